@@ -46,7 +46,7 @@ var exampleCmd = &cobra.Command{
 
 		// Collect
 		emit := func(d *processor.Document) error {
-			logger.Infof("emitted document: %+v", d)
+			logger.Debugf("emitted document: %+v", d)
 			return nil
 		}
 
@@ -0,0 +1,127 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/file"
+	"github.com/guacsec/guac/pkg/ingestor/service"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var filesFlags = struct {
+	ingestorAddr   string
+	ingestorTLS    bool
+	ingestorCAFile string
+	poll           bool
+	interval       time.Duration
+	archiveDepth   int
+	since          string
+}{}
+
+func init() {
+	filesCmd.PersistentFlags().StringVar(&filesFlags.ingestorAddr, "ingestor-addr", "localhost:2782", "address of the GUAC ingestion service")
+	filesCmd.PersistentFlags().BoolVar(&filesFlags.ingestorTLS, "ingestor-tls", false, "connect to the ingestion service over TLS, verifying its certificate against the system CAs; the connection is plaintext otherwise")
+	filesCmd.PersistentFlags().StringVar(&filesFlags.ingestorCAFile, "ingestor-ca-file", "", "path to the PEM encoded CA certificates the ingestion service certificate must chain to, instead of the system ones; implies --ingestor-tls")
+	filesCmd.PersistentFlags().BoolVar(&filesFlags.poll, "poll", false, "keep watching the folder and send new or modified documents")
+	filesCmd.PersistentFlags().DurationVar(&filesFlags.interval, "interval", 5*time.Second, "interval between each scan of the folder when polling")
+	filesCmd.PersistentFlags().StringVar(&filesFlags.since, "since", "", "only send the files modified after this time, given as an RFC 3339 timestamp (e.g. 2023-01-02T15:04:05Z) or a duration before now (e.g. 24h)")
+	filesCmd.PersistentFlags().IntVar(&filesFlags.archiveDepth, "archive-depth", file.DefaultArchiveDepth, "number of nested archive levels whose entries are sent as documents; 0 sends archives as regular files")
+}
+
+var filesCmd = &cobra.Command{
+	Use:   "files [flags] file_path",
+	Short: "collect a file, or a folder of files, and send them to a remote GUAC ingestor",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		since, err := collector.ParseSince(filesFlags.since, time.Now())
+		if err != nil {
+			logger.Errorf("invalid since: %v", err)
+			os.Exit(1)
+		}
+		fileCollector := file.NewFileCollector(ctx, args[0], filesFlags.poll, filesFlags.interval, filesFlags.archiveDepth, since, nil)
+		if err := collector.RegisterDocumentCollector(fileCollector, file.FileCollector); err != nil {
+			logger.Errorf("unable to register file collector: %v", err)
+			os.Exit(1)
+		}
+
+		creds, err := ingestorCredentials()
+		if err != nil {
+			logger.Errorf("invalid ingestor TLS configuration: %v", err)
+			os.Exit(1)
+		}
+		conn, err := grpc.DialContext(ctx, filesFlags.ingestorAddr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			logger.Errorf("unable to connect to ingestor: %v", err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+
+		errHandler := func(err error) bool {
+			if err == nil {
+				logger.Info("collector ended gracefully")
+				return true
+			}
+			logger.Errorf("collector ended with error: %v", err)
+			return false
+		}
+		docChan, wait := collector.CollectDocuments(ctx, errHandler)
+		summary, err := service.NewClient(conn).Ingest(ctx, docChan)
+		if err != nil {
+			logger.Fatalf("unable to send documents: %v", err)
+		}
+		if err := wait(); err != nil {
+			logger.Fatal(err)
+		}
+		fmt.Printf("sent %d documents, %d failed to be ingested\n", summary.GetReceived(), summary.GetFailed())
+	},
+}
+
+// ingestorCredentials returns the transport credentials of the connection to
+// the ingestion service given by the flags: TLS with --ingestor-tls or
+// --ingestor-ca-file, plaintext otherwise
+func ingestorCredentials() (credentials.TransportCredentials, error) {
+	if !filesFlags.ingestorTLS && filesFlags.ingestorCAFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if filesFlags.ingestorCAFile != "" {
+		data, err := os.ReadFile(filesFlags.ingestorCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM encoded certificate found in %s", filesFlags.ingestorCAFile)
+		}
+		config.RootCAs = pool
+	}
+	return credentials.NewTLS(config), nil
+}
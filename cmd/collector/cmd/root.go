@@ -19,16 +19,40 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+var (
+	maxDocumentSize int64
+	reconnectPolicy collector.ReconnectPolicy
+	logLevel        string
+)
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", string(logging.DefaultLevel), "minimum level of the log lines: debug, info, warn or error")
+	rootCmd.PersistentFlags().Int64Var(&maxDocumentSize, "max-document-size", processor.DefaultMaxDocumentSize, "maximum size in bytes of a document; larger documents are not read by the collectors. 0 disables the limit")
+	rootCmd.PersistentFlags().DurationVar(&reconnectPolicy.BaseDelay, "reconnect-delay", collector.DefaultReconnectPolicy.BaseDelay, "delay before the first attempt to open the stream to the ingestor again when it is unavailable; it doubles after each failed attempt")
+	rootCmd.PersistentFlags().DurationVar(&reconnectPolicy.MaxDelay, "reconnect-max-delay", collector.DefaultReconnectPolicy.MaxDelay, "maximum delay between the attempts to reconnect to the ingestor")
+	rootCmd.PersistentFlags().DurationVar(&reconnectPolicy.MaxRetryWindow, "reconnect-window", collector.DefaultReconnectPolicy.MaxRetryWindow, "how long the ingestor is retried before giving up with an error. 0 retries forever")
 	rootCmd.AddCommand(exampleCmd)
+	rootCmd.AddCommand(filesCmd)
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "collector",
 	Short: "collector is an collector cmdline for GUAC",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := logging.SetLevel(logging.Level(logLevel)); err != nil {
+			return err
+		}
+		if err := processor.SetMaxDocumentSize(maxDocumentSize); err != nil {
+			return err
+		}
+		return collector.SetReconnectPolicy(reconnectPolicy)
+	},
 }
 
 func Execute() {
@@ -0,0 +1,100 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// addBulkFlags adds the flags of bulk loads to the command
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&flags.bulk, "bulk", false, "stage the graphs of all the documents in --bulk-dir, then load them into neo4j in large batches; a failed load resumes when running again with the same --bulk-dir")
+	cmd.PersistentFlags().StringVar(&flags.bulkDir, "bulk-dir", "", "directory the graphs are staged in for --bulk")
+	cmd.PersistentFlags().IntVar(&flags.bulkRate, "bulk-rate", 0, "maximum number of nodes and edges loaded per second by --bulk; 0 disables the limit")
+}
+
+// validateBulkFlags sets the bulk load options. Documents are staged as
+// they are ingested, so the checkpoints and dedup files, which would skip
+// them when staging again, cannot be used.
+func validateBulkFlags(cmd *cobra.Command, opts *options) error {
+	if !flags.bulk {
+		return nil
+	}
+	if opts.backend != neo4jBackend {
+		return fmt.Errorf("bulk is only supported with the %s backend", neo4jBackend)
+	}
+	if opts.dryRun {
+		return fmt.Errorf("bulk and dry-run cannot be used together")
+	}
+	if opts.poll {
+		return fmt.Errorf("bulk and poll cannot be used together")
+	}
+	if flags.bulkDir == "" {
+		return fmt.Errorf("bulk-dir is required with bulk")
+	}
+	if opts.checkpointFile != "" || opts.dedupFile != "" {
+		return fmt.Errorf("checkpoint-file and dedup-file cannot be used with bulk, the load is checkpointed in bulk-dir")
+	}
+	if flags.bulkRate < 0 {
+		return fmt.Errorf("bulk-rate must not be negative")
+	}
+	opts.bulk = true
+	opts.bulkDir = flags.bulkDir
+	opts.bulkRate = flags.bulkRate
+	if !cmd.Flags().Changed("batch-size") {
+		opts.batchSize = assembler.DefaultBulkBatchSize
+	}
+	return nil
+}
+
+// loadBulk loads the graphs staged in the bulk directory into neo4j,
+// creating the indexes first so that nodes are merged quickly
+func loadBulk(ctx context.Context, opts options) error {
+	logger := logging.FromContext(ctx)
+	authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+	client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := createIndices(client); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	stats, err := assembler.LoadBulk(ctx, client, opts.bulkDir, assembler.BulkOptions{
+		BatchSize:     opts.batchSize,
+		RowsPerSecond: opts.bulkRate,
+		Retry:         opts.retry,
+	})
+	logger.Infow("bulk load",
+		"nodes", stats.Nodes,
+		"edges", stats.Edges,
+		"resumed_after_nodes", stats.SkippedNodes,
+		"resumed_after_edges", stats.SkippedEdges,
+		"elapsed", time.Since(start))
+	if err != nil {
+		return fmt.Errorf("%w; run again with the same bulk-dir to resume the load", err)
+	}
+	return nil
+}
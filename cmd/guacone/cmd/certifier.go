@@ -19,9 +19,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
-	"time"
 
+	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/assembler/graphdb"
 	"github.com/guacsec/guac/pkg/certifier"
 	"github.com/guacsec/guac/pkg/certifier/certify"
@@ -33,10 +32,9 @@ import (
 )
 
 func init() {
-	certifierCmd.PersistentFlags().StringVar(&flags.dbAddr, "db-addr", "neo4j://localhost:7687", "address to neo4j db")
-	certifierCmd.PersistentFlags().StringVar(&flags.creds, "creds", "", "credentials to access neo4j in 'user:pass' format")
-	certifierCmd.PersistentFlags().StringVar(&flags.realm, "realm", "neo4j", "realm to connecto graph db")
-	_ = certifierCmd.MarkPersistentFlagRequired("creds")
+	addDBFlags(certifierCmd)
+	addTLSFlags(certifierCmd)
+	addGraphCheckFlags(certifierCmd)
 }
 
 var certifierCmd = &cobra.Command{
@@ -54,24 +52,15 @@ var certifierCmd = &cobra.Command{
 		}
 
 		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
-		client, err := graphdb.NewGraphClient(opts.dbAddr, authToken)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
 		if err != nil {
 			logger.Errorf("error: %v", err)
 			os.Exit(1)
 		}
 
-		processorFunc, err := getProcessor(ctx)
-		if err != nil {
-			logger.Errorf("error: %v", err)
-			os.Exit(1)
-		}
-
-		ingestorFunc, err := getIngestor(ctx)
-		if err != nil {
-			logger.Errorf("error: %v", err)
-			os.Exit(1)
-		}
-		assemblerFunc, err := getAssembler(opts)
+		backend := assembler.NewNeo4jBackend(client, assembler.DefaultBatchSize, graphdb.DefaultRetryPolicy, nil)
+		defer backend.Close()
+		pipe, err := newPipeline(ctx, backend, opts)
 		if err != nil {
 			logger.Errorf("error: %v", err)
 			os.Exit(1)
@@ -88,28 +77,10 @@ var certifierCmd = &cobra.Command{
 		// Set emit function to go through the entire pipeline
 		emit := func(d *processor.Document) error {
 			totalNum += 1
-			start := time.Now()
-
-			docTree, err := processorFunc(d)
-			if err != nil {
+			if _, _, err := pipe.run(ctx, d); err != nil {
 				gotErr = true
-				return fmt.Errorf("unable to process doc: %v, fomat: %v, document: %v", err, d.Format, d.Type)
+				return err
 			}
-
-			graphs, err := ingestorFunc(docTree)
-			if err != nil {
-				gotErr = true
-				return fmt.Errorf("unable to ingest doc tree: %v", err)
-			}
-
-			err = assemblerFunc(graphs)
-			if err != nil {
-				gotErr = true
-				return fmt.Errorf("unable to assemble graphs: %v", err)
-			}
-			t := time.Now()
-			elapsed := t.Sub(start)
-			logger.Infof("[%v] completed doc %+v", elapsed, d.SourceInformation)
 			return nil
 		}
 
@@ -136,13 +107,22 @@ var certifierCmd = &cobra.Command{
 
 func validateCertifierFlags() (options, error) {
 	var opts options
-	credsSplit := strings.Split(flags.creds, ":")
-	if len(credsSplit) != 2 {
-		return opts, fmt.Errorf("creds flag not in correct format user:pass")
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
 	}
-	opts.user = credsSplit[0]
-	opts.pass = credsSplit[1]
+	opts.user = user
+	opts.pass = pass
 	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	if err := validateGraphCheckFlags(&opts); err != nil {
+		return opts, err
+	}
 
 	return opts, nil
 }
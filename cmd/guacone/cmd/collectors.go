@@ -0,0 +1,409 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
+	"github.com/guacsec/guac/pkg/handler/collector/file"
+	"github.com/guacsec/guac/pkg/handler/collector/gcs"
+	"github.com/guacsec/guac/pkg/handler/collector/kafka"
+	"github.com/guacsec/guac/pkg/handler/collector/oci"
+	"github.com/guacsec/guac/pkg/handler/collector/s3"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/spf13/cobra"
+)
+
+// bucketPrefix is a bucket and the prefix of the keys of the objects
+// collected from it, empty to collect all of them
+type bucketPrefix struct {
+	bucket string
+	prefix string
+}
+
+func (b bucketPrefix) String() string {
+	if b.prefix == "" {
+		return b.bucket
+	}
+	return b.bucket + "/" + b.prefix
+}
+
+// externalCollector is a collector created by a factory registered with
+// collector.RegisterCollectorFactory, and the config it is given
+type externalCollector struct {
+	name   string
+	config string
+}
+
+func (c externalCollector) String() string {
+	if c.config == "" {
+		return c.name
+	}
+	return c.name + "=" + c.config
+}
+
+// declaredType is the type and format of all the documents of a source, so
+// that they are not detected from their content
+type declaredType struct {
+	docType processor.DocumentType
+	format  processor.FormatType
+}
+
+// declarableFormats are the formats that can be declared for a source
+var declarableFormats = []processor.FormatType{
+	processor.FormatJSON,
+	processor.FormatJSONLines,
+	processor.FormatXML,
+	processor.FormatTagValue,
+}
+
+// addCollectorFlags adds the flags of the collectors run together with the
+// file collectors of the paths to the command
+func addCollectorFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&flags.ociRepos, "oci", nil, "OCI repositories or images (e.g. ghcr.io/org/app) whose attached signatures, attestations and SBOMs are collected; can be repeated")
+	cmd.PersistentFlags().StringSliceVar(&flags.registryCreds, "registry-creds", nil, "credentials of an OCI registry as REGISTRY=USER:PASS (e.g. ghcr.io=$GITHUB_ACTOR:$GITHUB_TOKEN), used when the docker config and its credential helpers have none; can be repeated")
+	cmd.PersistentFlags().StringSliceVar(&flags.s3Buckets, "s3", nil, "S3 buckets, optionally followed by a key prefix (e.g. sboms/releases/), whose objects are collected; can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&flags.s3Queues, "s3-queue", nil, "SQS queue receiving the S3 event notifications of a bucket given with --s3, as BUCKET[/PREFIX]=QUEUE_URL; the bucket is listed once, then its objects are collected as they are uploaded; can be repeated")
+	cmd.PersistentFlags().StringSliceVar(&flags.gcsBuckets, "gcs", nil, "GCS buckets, optionally followed by an object prefix (e.g. sboms/releases/), whose objects are collected; can be repeated")
+	cmd.PersistentFlags().StringSliceVar(&flags.kafkaBrokers, "kafka-brokers", nil, "addresses of the Kafka brokers (e.g. kafka:9092) the --kafka-topic topics are consumed from")
+	cmd.PersistentFlags().StringSliceVar(&flags.kafkaTopics, "kafka-topic", nil, "Kafka topic whose messages are collected as documents, until the command is interrupted; can be repeated")
+	cmd.PersistentFlags().StringVar(&flags.kafkaGroup, "kafka-group", "guac", "Kafka consumer group the topics are consumed as; collectors of the same group share the partitions")
+	cmd.PersistentFlags().DurationVar(&flags.reconnect.BaseDelay, "reconnect-delay", collector.DefaultReconnectPolicy.BaseDelay, "delay before the first reconnection attempt of streaming collectors (--kafka-topic, --s3-queue); it doubles after each failed attempt")
+	cmd.PersistentFlags().DurationVar(&flags.reconnect.MaxDelay, "reconnect-max-delay", collector.DefaultReconnectPolicy.MaxDelay, "maximum delay between reconnection attempts of streaming collectors")
+	cmd.PersistentFlags().DurationVar(&flags.reconnect.MaxRetryWindow, "reconnect-window", collector.DefaultReconnectPolicy.MaxRetryWindow, "how long streaming collectors keep reconnecting before giving up with an error. 0 retries forever")
+	cmd.PersistentFlags().IntVar(&flags.fetchConcurrency, "fetch-concurrency", collector.DefaultFetchConcurrency, "number of documents the collectors downloading many documents (--oci, --s3, --gcs) each fetch concurrently")
+	cmd.PersistentFlags().StringArrayVar(&flags.collectors, "collector", nil, "collector compiled into guacone from outside of GUAC to run, as NAME[=CONFIG] where the format of CONFIG is up to the collector; can be repeated")
+	cmd.PersistentFlags().StringSliceVar(&flags.documentTypes, "document-type", nil, "type, and optionally format, of all the documents of a path, OCI repository, s3:// or gs:// bucket, as SOURCE=TYPE[:FORMAT] (e.g. s3://sboms=SPDX:JSON), so that they are not detected from their content; can be repeated")
+}
+
+// validateCollectorFlags checks the flags of the collectors and sets them in
+// opts
+func validateCollectorFlags(opts *options) error {
+	for _, repo := range flags.ociRepos {
+		if strings.TrimSpace(repo) == "" {
+			return fmt.Errorf("oci repository must not be empty")
+		}
+		opts.ociRepos = append(opts.ociRepos, repo)
+	}
+	var err error
+	if opts.registryCreds, err = parseRegistryCreds(expandEnvAll(flags.registryCreds)); err != nil {
+		return err
+	}
+	if opts.s3Buckets, err = parseBuckets("s3", flags.s3Buckets); err != nil {
+		return err
+	}
+	if opts.s3Queues, err = parseS3Queues(flags.s3Queues, opts.s3Buckets); err != nil {
+		return err
+	}
+	if opts.gcsBuckets, err = parseBuckets("gcs", flags.gcsBuckets); err != nil {
+		return err
+	}
+	if len(flags.kafkaTopics) > 0 && len(flags.kafkaBrokers) == 0 {
+		return fmt.Errorf("kafka-brokers is required with kafka-topic")
+	}
+	if len(flags.kafkaBrokers) > 0 && len(flags.kafkaTopics) == 0 {
+		return fmt.Errorf("kafka-topic is required with kafka-brokers")
+	}
+	if len(flags.kafkaTopics) > 0 && flags.kafkaGroup == "" {
+		return fmt.Errorf("kafka-group must not be empty")
+	}
+	opts.kafkaBrokers = flags.kafkaBrokers
+	opts.kafkaTopics = flags.kafkaTopics
+	opts.kafkaGroup = flags.kafkaGroup
+	if err := collector.SetReconnectPolicy(flags.reconnect); err != nil {
+		return err
+	}
+	if err := collector.SetFetchConcurrency(flags.fetchConcurrency); err != nil {
+		return err
+	}
+	if opts.externalCollectors, err = parseExternalCollectors(flags.collectors); err != nil {
+		return err
+	}
+	if opts.documentTypes, err = parseDocumentTypes(flags.documentTypes); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseExternalCollectors parses the NAME[=CONFIG] of the collectors
+// registered with collector.RegisterCollectorFactory to run
+func parseExternalCollectors(specs []string) ([]externalCollector, error) {
+	factories := map[string]bool{}
+	for _, name := range collector.CollectorFactories() {
+		factories[name] = true
+	}
+	collectors := []externalCollector{}
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		name, config, _ := strings.Cut(spec, "=")
+		if !factories[name] {
+			return nil, fmt.Errorf("collector %q is not compiled in, available: %s", name, availableFactories())
+		}
+		c := externalCollector{name: name, config: config}
+		if seen[c.String()] {
+			return nil, fmt.Errorf("collector %s is given twice", c)
+		}
+		seen[c.String()] = true
+		collectors = append(collectors, c)
+	}
+	return collectors, nil
+}
+
+// availableFactories lists the names of the registered collector factories
+// for the error messages. The factories are registered by init functions, so
+// they are not all known yet when the flags are added.
+func availableFactories() string {
+	names := collector.CollectorFactories()
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseDocumentTypes parses the SOURCE=TYPE[:FORMAT] declarations of the
+// type of the documents of a source, and returns them by the key of their
+// source, see sourceKey. Types and formats are compared case insensitively.
+func parseDocumentTypes(declarations []string) (map[string]declaredType, error) {
+	types := map[string]declaredType{}
+	for _, d := range declarations {
+		i := strings.LastIndex(d, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("document type %q is not of the form SOURCE=TYPE[:FORMAT]", d)
+		}
+		source := d[:i]
+		typeName, formatName, _ := strings.Cut(d[i+1:], ":")
+
+		declared := declaredType{docType: processor.DocumentUnknown, format: processor.FormatUnknown}
+		for _, t := range process.DocumentTypes() {
+			if strings.EqualFold(typeName, string(t)) {
+				declared.docType = t
+			}
+		}
+		if declared.docType == processor.DocumentUnknown {
+			return nil, fmt.Errorf("document type %q of %s is not supported, expected one of %v", typeName, source, process.DocumentTypes())
+		}
+		if formatName != "" {
+			for _, f := range declarableFormats {
+				if strings.EqualFold(formatName, string(f)) {
+					declared.format = f
+				}
+			}
+			if declared.format == processor.FormatUnknown {
+				return nil, fmt.Errorf("document format %q of %s is not supported, expected one of %v", formatName, source, declarableFormats)
+			}
+		}
+
+		key, err := sourceKey(source)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := types[key]; ok {
+			return nil, fmt.Errorf("document type of %s is declared twice", source)
+		}
+		types[key] = declared
+	}
+	return types, nil
+}
+
+// sourceKey returns the key by which the type declared for a source is looked
+// up: the bucket and prefix for buckets, the cleaned path for the other
+// sources, which are either paths or OCI repositories
+func sourceKey(source string) (string, error) {
+	for scheme, name := range map[string]string{"s3://": "s3", "gs://": "gcs"} {
+		if strings.HasPrefix(source, scheme) {
+			buckets, err := parseBuckets(name, []string{source})
+			if err != nil {
+				return "", err
+			}
+			return scheme + buckets[0].String(), nil
+		}
+	}
+	return filepath.Clean(source), nil
+}
+
+// parseS3Queues parses the BUCKET[/PREFIX]=QUEUE_URL queues receiving the
+// event notifications of the buckets, and returns the URLs by bucket, see
+// bucketPrefix.String
+func parseS3Queues(values []string, buckets []bucketPrefix) (map[string]string, error) {
+	collected := map[string]bool{}
+	for _, b := range buckets {
+		collected[b.String()] = true
+	}
+	queues := map[string]string{}
+	for _, v := range values {
+		location, queueURL, ok := strings.Cut(v, "=")
+		if !ok || queueURL == "" {
+			return nil, fmt.Errorf("s3 queue %q is not of the form BUCKET[/PREFIX]=QUEUE_URL", v)
+		}
+		b, err := parseBuckets("s3", []string{location})
+		if err != nil {
+			return nil, err
+		}
+		bucket := b[0].String()
+		if !collected[bucket] {
+			return nil, fmt.Errorf("s3 queue given for %s, which is not a bucket given with --s3", bucket)
+		}
+		if _, ok := queues[bucket]; ok {
+			return nil, fmt.Errorf("s3 queue of %s is given twice", bucket)
+		}
+		queues[bucket] = queueURL
+	}
+	return queues, nil
+}
+
+// parseRegistryCreds parses the REGISTRY=USER:PASS credentials of the OCI
+// registries and returns them by registry
+func parseRegistryCreds(values []string) (map[string]authn.AuthConfig, error) {
+	creds := map[string]authn.AuthConfig{}
+	for _, v := range values {
+		registry, userPass, ok := strings.Cut(v, "=")
+		if !ok || registry == "" {
+			return nil, fmt.Errorf("registry credentials are not of the form REGISTRY=USER:PASS")
+		}
+		user, pass, ok := strings.Cut(userPass, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("credentials of registry %s are not of the form USER:PASS", registry)
+		}
+		if _, ok := creds[registry]; ok {
+			return nil, fmt.Errorf("credentials of registry %s are given twice", registry)
+		}
+		creds[registry] = authn.AuthConfig{Username: user, Password: pass}
+	}
+	return creds, nil
+}
+
+// parseBuckets splits each location into its bucket and prefix, accepting
+// the `s3://` and `gs://` URL forms too
+func parseBuckets(name string, locations []string) ([]bucketPrefix, error) {
+	buckets := []bucketPrefix{}
+	for _, l := range locations {
+		for _, scheme := range []string{"s3://", "gs://"} {
+			l = strings.TrimPrefix(l, scheme)
+		}
+		bucket, prefix, _ := strings.Cut(l, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("%s location %q has no bucket", name, l)
+		}
+		buckets = append(buckets, bucketPrefix{bucket: bucket, prefix: prefix})
+	}
+	return buckets, nil
+}
+
+// hasRemoteCollectors returns whether documents are collected from other
+// places than the paths
+func hasRemoteCollectors(opts options) bool {
+	return len(opts.ociRepos) > 0 || len(opts.s3Buckets) > 0 || len(opts.gcsBuckets) > 0 ||
+		len(opts.kafkaTopics) > 0 || len(opts.externalCollectors) > 0
+}
+
+// registerCollectors registers a collector for each path, OCI repository,
+// bucket, Kafka topic and external collector of the options. Their documents
+// all feed the same pipeline, and are told apart by the collector and source
+// of their SourceInformation. When polling, the remote collectors check for
+// new documents every interval too; the buckets with an event queue and the
+// Kafka topics are consumed until ctx is done.
+// The collectors of the sources with a declared type set it on their
+// documents.
+func registerCollectors(ctx context.Context, opts options, checkpoints checkpoint.Store) error {
+	declared := map[string]bool{}
+	register := func(c collector.Collector, name, source string) error {
+		key, err := sourceKey(source)
+		if err != nil {
+			return err
+		}
+		if t, ok := opts.documentTypes[key]; ok {
+			c = collector.WithDocumentType(c, t.docType, t.format)
+			declared[key] = true
+		}
+		return collector.RegisterDocumentCollector(c, name)
+	}
+
+	for _, path := range opts.paths {
+		fileCollector := file.NewFileCollector(ctx, path, opts.poll, opts.interval, opts.archiveDepth, opts.since, checkpoints)
+		if err := register(fileCollector, file.FileCollector+":"+path, path); err != nil {
+			return fmt.Errorf("unable to register file collector: %w", err)
+		}
+	}
+
+	var pollRate time.Duration
+	if opts.poll {
+		pollRate = opts.interval
+	}
+	keychain, err := oci.NewKeychain(opts.registryCreds)
+	if err != nil {
+		return err
+	}
+	for _, repo := range opts.ociRepos {
+		ociCollector := oci.NewOCICollector(ctx, repo, pollRate)
+		ociCollector.SetKeychain(keychain)
+		if err := register(ociCollector, oci.OCICollector+":"+repo, repo); err != nil {
+			return fmt.Errorf("unable to register OCI collector: %w", err)
+		}
+	}
+	for _, b := range opts.s3Buckets {
+		s3Collector, err := s3.NewS3Collector(ctx, b.bucket, b.prefix, pollRate, opts.s3Queues[b.String()])
+		if err != nil {
+			return fmt.Errorf("unable to create S3 collector for %s: %w", b, err)
+		}
+		s3Collector.SetSince(opts.since)
+		if err := register(s3Collector, s3.CollectorS3+":"+b.String(), "s3://"+b.String()); err != nil {
+			return fmt.Errorf("unable to register S3 collector: %w", err)
+		}
+	}
+	for _, b := range opts.gcsBuckets {
+		gcsCollector, err := gcs.NewGCSCollector(ctx, b.bucket, b.prefix, pollRate)
+		if err != nil {
+			return fmt.Errorf("unable to create GCS collector for %s: %w", b, err)
+		}
+		gcsCollector.SetSince(opts.since)
+		if err := register(gcsCollector, gcs.CollectorGCS+":"+b.String(), "gs://"+b.String()); err != nil {
+			return fmt.Errorf("unable to register GCS collector: %w", err)
+		}
+	}
+	for _, topic := range opts.kafkaTopics {
+		kafkaCollector, err := kafka.NewKafkaCollector(ctx, opts.kafkaBrokers, topic, opts.kafkaGroup)
+		if err != nil {
+			return fmt.Errorf("unable to create Kafka collector for %s: %w", topic, err)
+		}
+		if err := register(kafkaCollector, kafka.KafkaCollector+":"+topic, topic); err != nil {
+			return fmt.Errorf("unable to register Kafka collector: %w", err)
+		}
+	}
+	for _, c := range opts.externalCollectors {
+		external, err := collector.NewExternalCollector(ctx, c.name, c.config)
+		if err != nil {
+			return err
+		}
+		if err := register(external, external.Type()+":"+c.String(), c.name); err != nil {
+			return fmt.Errorf("unable to register %s collector: %w", c.name, err)
+		}
+	}
+
+	for key := range opts.documentTypes {
+		if !declared[key] {
+			return fmt.Errorf("document type declared for %s, which is not a collected path, OCI repository, bucket or collector", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	userEnv     = "NEO4J_USER"
+	passwordEnv = "NEO4J_PASSWORD"
+)
+
+// getCredentials returns the user and password to access the database. They
+// are read from the file given by --creds-file, then from the NEO4J_USER and
+// NEO4J_PASSWORD environment variables, and only if neither is set from the
+// --creds flag, which leaks the password into process listings.
+func getCredentials() (string, string, error) {
+	if flags.credsFile != "" {
+		b, err := os.ReadFile(flags.credsFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read creds file: %w", err)
+		}
+		return parseCredentials(strings.TrimSpace(string(b)), "creds file "+flags.credsFile)
+	}
+
+	user, userSet := os.LookupEnv(userEnv)
+	pass, passSet := os.LookupEnv(passwordEnv)
+	if userSet || passSet {
+		if !userSet || !passSet {
+			return "", "", fmt.Errorf("both %s and %s must be set", userEnv, passwordEnv)
+		}
+		return parseCredentials(user+":"+pass, userEnv+" and "+passwordEnv)
+	}
+
+	if flags.creds == "" {
+		return "", "", fmt.Errorf("no credentials given, use --creds-file, %s and %s, or --creds", userEnv, passwordEnv)
+	}
+	return parseCredentials(expandEnv(flags.creds), "creds flag")
+}
+
+// parseCredentials splits creds in 'user:pass' format. The password may
+// contain colons.
+func parseCredentials(creds string, source string) (string, string, error) {
+	user, pass, ok := strings.Cut(creds, ":")
+	if !ok || user == "" {
+		return "", "", fmt.Errorf("%s not in correct format user:pass", source)
+	}
+	return user, pass, nil
+}
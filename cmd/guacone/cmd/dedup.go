@@ -0,0 +1,85 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
+	"github.com/guacsec/guac/pkg/handler/dedup"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/spf13/cobra"
+)
+
+// addDedupFlags adds the flags deduplicating the documents to the command
+func addDedupFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&flags.dedup, "dedup", false, "skip the documents whose content is identical to a document already stored in this run")
+	cmd.PersistentFlags().StringVar(&flags.dedupFile, "dedup-file", "", "file recording the digests of the documents stored, so that identical documents are also skipped in later runs; implies --dedup")
+}
+
+// getDedupCache returns the cache given by the options, or nil when
+// deduplication is disabled. The digests are only persisted when the
+// documents are written to the database.
+func getDedupCache(opts options) (*dedup.Cache, error) {
+	if opts.dedupFile != "" && !opts.dryRun {
+		store, err := checkpoint.NewFileStore(opts.dedupFile)
+		if err != nil {
+			return nil, err
+		}
+		return dedup.NewCache(store), nil
+	}
+	if opts.dedup || opts.dedupFile != "" {
+		return dedup.NewCache(checkpoint.NewMemoryStore()), nil
+	}
+	return nil, nil
+}
+
+// dedupEmitter wraps emit to skip the documents whose content was already
+// stored, without processing them. Skipped documents are counted in deduped
+// and acknowledged as ingested. The content of the documents emitted
+// successfully is recorded in cache.
+func dedupEmitter(ctx context.Context, cache *dedup.Cache, deduped *int64, emit func(*processor.Document) error) func(*processor.Document) error {
+	logger := logging.FromContext(ctx)
+	if cache == nil {
+		return emit
+	}
+	return func(d *processor.Document) error {
+		digest := dedup.Digest(d.Blob)
+		first, ok, err := cache.Lookup(digest)
+		if err != nil {
+			logger.Warnf("unable to deduplicate document %s: %v", d.SourceInformation.Source, err)
+		}
+		if ok {
+			atomic.AddInt64(deduped, 1)
+			metrics.DocumentsDeduplicated.WithLabelValues(string(d.Type)).Inc()
+			logger.Debugw("skipping duplicate document",
+				"digest", digest,
+				"source", d.SourceInformation.Source,
+				"duplicate_of", first)
+			return nil
+		}
+		if err := emit(d); err != nil {
+			return err
+		}
+		if err := cache.Add(digest, d.SourceInformation.Source); err != nil {
+			logger.Warnf("unable to record document %s for deduplication: %v", d.SourceInformation.Source, err)
+		}
+		return nil
+	}
+}
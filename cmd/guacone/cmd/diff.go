@@ -0,0 +1,283 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+const (
+	changeAdded      = "added"
+	changeRemoved    = "removed"
+	changeVersion    = "changed"
+	changeIntroduced = "introduced"
+	changeResolved   = "resolved"
+)
+
+var diffFlags = struct {
+	depth  int
+	output string
+}{}
+
+type diffOptions struct {
+	options
+	from, to string
+	// maximum number of DependsOn edges to follow from each package
+	depth int
+	// output format: table or json
+	output string
+}
+
+// packageChange is a package whose versions differ between the two
+// dependency graphs. Packages are matched on their purl without version and
+// qualifiers, see `common.PurlName`. The JSON field names are stable, for
+// scripts to depend on them.
+type packageChange struct {
+	// Change is added, removed or changed
+	Change       string   `json:"change"`
+	Name         string   `json:"name"`
+	FromVersions []string `json:"from_versions"`
+	ToVersions   []string `json:"to_versions"`
+}
+
+// vulnerabilityChange is a vulnerability affecting only one of the two
+// dependency graphs, with the packages it affects in that graph
+type vulnerabilityChange struct {
+	// Change is introduced or resolved
+	Change   string   `json:"change"`
+	ID       string   `json:"id"`
+	Packages []string `json:"packages"`
+}
+
+type diffResult struct {
+	From            queriedPackage        `json:"from"`
+	To              queriedPackage        `json:"to"`
+	Packages        []packageChange       `json:"packages"`
+	Vulnerabilities []vulnerabilityChange `json:"vulnerabilities"`
+}
+
+func init() {
+	addDBFlags(diffCmd)
+	addTLSFlags(diffCmd)
+	diffCmd.PersistentFlags().IntVar(&diffFlags.depth, "depth", 10, "number of levels of transitive dependencies compared")
+	diffCmd.PersistentFlags().StringVar(&diffFlags.output, "output-format", "", "output format: table or json (default table when writing to a terminal, json otherwise)")
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags] from_purl to_purl",
+	Short: "compare the dependencies and vulnerabilities of two packages in the GUAC graph, e.g. two releases",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateDiffFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		from, err := queryPackage(client, queryOptions{purl: opts.from, depth: opts.depth})
+		if err != nil {
+			logger.Errorf("unable to query package: %v", err)
+			os.Exit(1)
+		}
+		to, err := queryPackage(client, queryOptions{purl: opts.to, depth: opts.depth})
+		if err != nil {
+			logger.Errorf("unable to query package: %v", err)
+			os.Exit(1)
+		}
+
+		result := diffPackages(from, to)
+		if opts.output == jsonOutput {
+			err = writeDiffJSON(os.Stdout, result)
+		} else {
+			err = writeDiffTable(os.Stdout, result)
+		}
+		if err != nil {
+			logger.Errorf("unable to write diff: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateDiffFlags(args []string) (diffOptions, error) {
+	var opts diffOptions
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+
+	if diffFlags.depth <= 0 {
+		return opts, fmt.Errorf("depth must be positive")
+	}
+	opts.depth = diffFlags.depth
+
+	opts.output, err = getOutputFormat(diffFlags.output)
+	if err != nil {
+		return opts, err
+	}
+	if opts.output == ndjsonOutput {
+		return opts, fmt.Errorf("output format %s is not supported by diff, expected %s or %s", ndjsonOutput, tableOutput, jsonOutput)
+	}
+
+	if len(args) != 2 {
+		return opts, fmt.Errorf("expected positional arguments for from_purl and to_purl")
+	}
+	// packages are stored with normalized purls
+	opts.from = common.NormalizePurl(args[0])
+	opts.to = common.NormalizePurl(args[1])
+
+	return opts, nil
+}
+
+// diffPackages compares the dependencies of the two query results, and the
+// vulnerabilities of the packages and their dependencies. The compared
+// packages themselves are not reported as changed.
+func diffPackages(from, to *queryResult) *diffResult {
+	result := &diffResult{
+		From:            from.Package,
+		To:              to.Package,
+		Packages:        []packageChange{},
+		Vulnerabilities: []vulnerabilityChange{},
+	}
+
+	fromVersions, toVersions := versionsByName(from.Dependencies), versionsByName(to.Dependencies)
+	for name, versions := range fromVersions {
+		if _, ok := toVersions[name]; !ok {
+			result.Packages = append(result.Packages, packageChange{Change: changeRemoved, Name: name, FromVersions: versions, ToVersions: []string{}})
+		}
+	}
+	for name, versions := range toVersions {
+		previous, ok := fromVersions[name]
+		switch {
+		case !ok:
+			result.Packages = append(result.Packages, packageChange{Change: changeAdded, Name: name, FromVersions: []string{}, ToVersions: versions})
+		case strings.Join(previous, "\x00") != strings.Join(versions, "\x00"):
+			result.Packages = append(result.Packages, packageChange{Change: changeVersion, Name: name, FromVersions: previous, ToVersions: versions})
+		}
+	}
+	sort.Slice(result.Packages, func(i, j int) bool {
+		return result.Packages[i].Name < result.Packages[j].Name
+	})
+
+	fromVulns, toVulns := vulnerablePackages(from), vulnerablePackages(to)
+	for id, purls := range fromVulns {
+		if _, ok := toVulns[id]; !ok {
+			result.Vulnerabilities = append(result.Vulnerabilities, vulnerabilityChange{Change: changeResolved, ID: id, Packages: purls})
+		}
+	}
+	for id, purls := range toVulns {
+		if _, ok := fromVulns[id]; !ok {
+			result.Vulnerabilities = append(result.Vulnerabilities, vulnerabilityChange{Change: changeIntroduced, ID: id, Packages: purls})
+		}
+	}
+	sort.Slice(result.Vulnerabilities, func(i, j int) bool {
+		a, b := result.Vulnerabilities[i], result.Vulnerabilities[j]
+		if a.Change != b.Change {
+			return a.Change == changeIntroduced
+		}
+		return a.ID < b.ID
+	})
+	return result
+}
+
+// versionsByName returns the sorted versions of the packages, by name
+func versionsByName(packages []queriedPackage) map[string][]string {
+	versions := map[string][]string{}
+	for _, p := range packages {
+		name, version := common.PurlName(p.Purl)
+		versions[name] = append(versions[name], version)
+	}
+	for name, vs := range versions {
+		sort.Strings(vs)
+		// different qualifiers give the same version several times
+		unique := vs[:0]
+		for i, v := range vs {
+			if i == 0 || v != vs[i-1] {
+				unique = append(unique, v)
+			}
+		}
+		versions[name] = unique
+	}
+	return versions
+}
+
+// vulnerablePackages returns the sorted purls of the packages of the query
+// result affected by each vulnerability
+func vulnerablePackages(result *queryResult) map[string][]string {
+	purls := map[string][]string{}
+	for _, p := range queriedPackages(result) {
+		for _, id := range p.Vulnerabilities {
+			purls[id] = append(purls[id], p.Purl)
+		}
+	}
+	for _, ps := range purls {
+		sort.Strings(ps)
+	}
+	return purls
+}
+
+func writeDiffTable(w io.Writer, result *diffResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tPACKAGE\tFROM\tTO")
+	for _, p := range result.Packages {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Change, p.Name, strings.Join(p.FromVersions, ","), strings.Join(p.ToVersions, ","))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CHANGE\tVULNERABILITY\tPACKAGES")
+	for _, v := range result.Vulnerabilities {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Change, v.ID, strings.Join(v.Packages, ","))
+	}
+	return tw.Flush()
+}
+
+// writeDiffJSON writes the diff as a single JSON document
+func writeDiffJSON(w io.Writer, result *diffResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
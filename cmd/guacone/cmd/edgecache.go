@@ -0,0 +1,52 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/spf13/cobra"
+)
+
+// addEdgeCacheFlags adds the flags of the cache skipping the neo4j writes of
+// edges identical to recent ones to the command
+func addEdgeCacheFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&flags.edgeCacheSize, "edge-cache-size", assembler.DefaultEdgeCacheSize, "number of edges written to neo4j remembered, so that identical edges from other documents are not written again; 0 disables the cache")
+	cmd.PersistentFlags().DurationVar(&flags.edgeCacheTTL, "edge-cache-ttl", assembler.DefaultEdgeCacheTTL, "time for which an edge written to neo4j is remembered")
+}
+
+// validateEdgeCacheFlags checks the edge cache flags and sets them in opts
+func validateEdgeCacheFlags(opts *options) error {
+	if flags.edgeCacheSize < 0 {
+		return fmt.Errorf("edge-cache-size must not be negative")
+	}
+	if flags.edgeCacheTTL <= 0 {
+		return fmt.Errorf("edge-cache-ttl must be positive")
+	}
+	opts.edgeCacheSize = flags.edgeCacheSize
+	opts.edgeCacheTTL = flags.edgeCacheTTL
+	return nil
+}
+
+// getEdgeCache returns the cache of the edges written to neo4j given by the
+// options, or nil when it is disabled
+func getEdgeCache(opts options) *assembler.EdgeCache {
+	if opts.edgeCacheSize == 0 {
+		return nil
+	}
+	return assembler.NewEdgeCache(opts.edgeCacheSize, opts.edgeCacheTTL)
+}
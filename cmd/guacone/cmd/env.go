@@ -0,0 +1,48 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// addExpandEnvFlag adds the flag enabling the expansion of environment
+// variables in the database address, the credentials and the paths given to
+// the command
+func addExpandEnvFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&flags.expandEnv, "expand-env", true, "expand $VAR and ${VAR} in --db-addr, --creds, --registry-creds and the paths from the environment (e.g. for exec form container entrypoints); disable it for values containing a literal $")
+}
+
+// expandEnv replaces $VAR and ${VAR} in s by the value of the environment
+// variable, unless the command disabled the expansion. Unset variables are
+// replaced by the empty string.
+func expandEnv(s string) string {
+	if !flags.expandEnv {
+		return s
+	}
+	return os.ExpandEnv(s)
+}
+
+// expandEnvAll applies expandEnv to each of values
+func expandEnvAll(values []string) []string {
+	expanded := make([]string, len(values))
+	for i, v := range values {
+		expanded[i] = expandEnv(v)
+	}
+	return expanded
+}
@@ -0,0 +1,346 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+const dotOutput = "dot"
+
+var exportFlags = struct {
+	depth           int
+	output          string
+	vulnerabilities bool
+}{}
+
+type exportOptions struct {
+	options
+	purl string
+	// maximum number of DependsOn edges to follow from the package
+	depth int
+	// output format: dot or json
+	output string
+	// also export the vulnerabilities of the packages
+	vulnerabilities bool
+}
+
+// exportedNode is a node of the exported subgraph. ID is only unique within
+// the export.
+type exportedNode struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Label      string                 `json:"label"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type exportedEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type exportedGraph struct {
+	Nodes []exportedNode `json:"nodes"`
+	Edges []exportedEdge `json:"edges"`
+}
+
+func init() {
+	addDBFlags(exportCmd)
+	addTLSFlags(exportCmd)
+	exportCmd.PersistentFlags().IntVar(&exportFlags.depth, "depth", 1, "number of levels of transitive dependencies to export")
+	exportCmd.PersistentFlags().StringVar(&exportFlags.output, "output", dotOutput, "output format: dot or json")
+	exportCmd.PersistentFlags().BoolVar(&exportFlags.vulnerabilities, "vulnerabilities", false, "also export the known vulnerabilities of the packages")
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [flags] purl",
+	Short: "export the dependency subgraph of a package in the GUAC graph as GraphViz DOT or JSON",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateExportFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		g, err := exportSubgraph(client, opts)
+		if err != nil {
+			logger.Errorf("unable to export package: %v", err)
+			os.Exit(1)
+		}
+
+		if opts.output == jsonOutput {
+			err = writeExportJSON(os.Stdout, g)
+		} else {
+			err = writeExportDOT(os.Stdout, g)
+		}
+		if err != nil {
+			logger.Errorf("unable to write export: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateExportFlags(args []string) (exportOptions, error) {
+	var opts exportOptions
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+
+	if exportFlags.depth <= 0 {
+		return opts, fmt.Errorf("depth must be positive")
+	}
+	opts.depth = exportFlags.depth
+
+	switch exportFlags.output {
+	case dotOutput, jsonOutput:
+		opts.output = exportFlags.output
+	default:
+		return opts, fmt.Errorf("unknown output %q, expected %s or %s", exportFlags.output, dotOutput, jsonOutput)
+	}
+	opts.vulnerabilities = exportFlags.vulnerabilities
+
+	if len(args) != 1 {
+		return opts, fmt.Errorf("expected positional argument for purl")
+	}
+	// packages are stored with normalized purls
+	opts.purl = common.NormalizePurl(args[0])
+
+	return opts, nil
+}
+
+// exportSubgraph returns the package with the given purl, the packages it
+// depends on up to `opts.depth` levels away and the DependsOn edges between
+// them, together with their vulnerabilities if requested
+func exportSubgraph(client graphdb.Client, opts exportOptions) (*exportedGraph, error) {
+	// variable length bounds cannot be query parameters, depth is validated
+	// to be a positive integer
+	query := fmt.Sprintf("MATCH (p:Package)-[:DependsOn*0..%d]->(d:Package) WHERE p.purl = $purl "+
+		"RETURN DISTINCT id(d), labels(d), properties(d)", opts.depth)
+	records, err := readRecords(client, query, map[string]interface{}{"purl": opts.purl})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("package %s not found", opts.purl)
+	}
+
+	g := &exportedGraph{Nodes: []exportedNode{}, Edges: []exportedEdge{}}
+	seen := map[int64]bool{}
+	ids := []int64{}
+	addNodes := func(records [][]interface{}) error {
+		for _, record := range records {
+			n, err := toExportedNode(record)
+			if err != nil {
+				return err
+			}
+			id := record[0].(int64)
+			if !seen[id] {
+				seen[id] = true
+				g.Nodes = append(g.Nodes, n)
+			}
+		}
+		return nil
+	}
+	if err := addNodes(records); err != nil {
+		return nil, err
+	}
+	for id := range seen {
+		ids = append(ids, id)
+	}
+
+	edgeQueries := []string{"MATCH (a:Package)-[e:DependsOn]->(b:Package) WHERE id(a) IN $ids AND id(b) IN $ids RETURN id(a), id(b), type(e)"}
+	if opts.vulnerabilities {
+		records, err := readRecords(client, "MATCH (p:Package)-[:VulnerableTo]->(v:Vulnerability) WHERE id(p) IN $ids "+
+			"RETURN DISTINCT id(v), labels(v), properties(v)", map[string]interface{}{"ids": ids})
+		if err != nil {
+			return nil, err
+		}
+		if err := addNodes(records); err != nil {
+			return nil, err
+		}
+		edgeQueries = append(edgeQueries, "MATCH (p:Package)-[e:VulnerableTo]->(v:Vulnerability) WHERE id(p) IN $ids RETURN id(p), id(v), type(e)")
+	}
+	for _, query := range edgeQueries {
+		records, err := readRecords(client, query, map[string]interface{}{"ids": ids})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			from, ok := record[0].(int64)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast node id to integer type")
+			}
+			to, ok := record[1].(int64)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast node id to integer type")
+			}
+			edgeType, ok := record[2].(string)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast edge type to string type")
+			}
+			g.Edges = append(g.Edges, exportedEdge{From: exportedID(from), To: exportedID(to), Type: edgeType})
+		}
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Type != g.Nodes[j].Type {
+			return g.Nodes[i].Type < g.Nodes[j].Type
+		}
+		return g.Nodes[i].Label < g.Nodes[j].Label
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Type < b.Type
+	})
+	return g, nil
+}
+
+// toExportedNode converts a record holding the id, labels and properties of
+// a node
+func toExportedNode(record []interface{}) (exportedNode, error) {
+	id, ok := record[0].(int64)
+	if !ok {
+		return exportedNode{}, fmt.Errorf("failed to cast node id to integer type")
+	}
+	labels, ok := record[1].([]interface{})
+	if !ok || len(labels) == 0 {
+		return exportedNode{}, fmt.Errorf("failed to cast node labels to list type")
+	}
+	nodeType, _ := labels[0].(string)
+	props, ok := record[2].(map[string]interface{})
+	if !ok {
+		return exportedNode{}, fmt.Errorf("failed to cast node properties to map type")
+	}
+	return exportedNode{
+		ID:         exportedID(id),
+		Type:       nodeType,
+		Label:      nodeLabel(nodeType, props),
+		Properties: props,
+	}, nil
+}
+
+func exportedID(id int64) string {
+	return fmt.Sprintf("n%d", id)
+}
+
+// nodeLabel returns the name and version of packages, the id of
+// vulnerabilities, and the most identifying property of other nodes
+func nodeLabel(nodeType string, props map[string]interface{}) string {
+	str := func(key string) string {
+		s, _ := props[key].(string)
+		return s
+	}
+	switch nodeType {
+	case "Package":
+		if name := str("name"); name != "" {
+			if version := str("version"); version != "" {
+				return name + "\n" + version
+			}
+			return name
+		}
+		return str("purl")
+	case "Vulnerability":
+		return str("id")
+	}
+	for _, key := range []string{"name", "purl", "id", "digest"} {
+		if s := str(key); s != "" {
+			return s
+		}
+	}
+	return nodeType
+}
+
+func writeExportJSON(w io.Writer, g *exportedGraph) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// writeExportDOT writes the graph in the GraphViz DOT language, e.g. to be
+// rendered with `dot -Tsvg`. Packages are boxes and vulnerabilities red
+// octagons; the full purl of a package is its tooltip.
+func writeExportDOT(w io.Writer, g *exportedGraph) error {
+	var sb strings.Builder
+	sb.WriteString("digraph guac {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Label)}
+		switch n.Type {
+		case "Package":
+			if purl, ok := n.Properties["purl"].(string); ok {
+				attrs = append(attrs, "tooltip="+dotQuote(purl))
+			}
+		case "Vulnerability":
+			attrs = append(attrs, "shape=octagon", "color=red")
+		}
+		fmt.Fprintf(&sb, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Type))
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotQuote returns s as a DOT quoted string. Line breaks become `\n`, which
+// GraphViz renders as centered line breaks in labels.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
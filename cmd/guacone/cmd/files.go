@@ -17,128 +17,517 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
-	"strings"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/assembler/postgresdb"
 	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
 	"github.com/guacsec/guac/pkg/handler/collector/file"
+	"github.com/guacsec/guac/pkg/handler/deadletter"
+	"github.com/guacsec/guac/pkg/handler/filter"
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/guacsec/guac/pkg/handler/progress"
+	"github.com/guacsec/guac/pkg/handler/timeout"
+	"github.com/guacsec/guac/pkg/health"
+	"github.com/guacsec/guac/pkg/ingestor/enricher"
+	"github.com/guacsec/guac/pkg/ingestor/key"
+	"github.com/guacsec/guac/pkg/ingestor/key/inmemory"
 	"github.com/guacsec/guac/pkg/ingestor/parser"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/ingestor/verifier"
+	"github.com/guacsec/guac/pkg/ingestor/verifier/rekor_verifier"
+	"github.com/guacsec/guac/pkg/ingestor/verifier/sigstore_verifier"
 	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/guacsec/guac/pkg/tracing"
+	// registers the database/sql driver of the postgres backend
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	neo4jBackend    = "neo4j"
+	postgresBackend = "postgres"
+	memoryBackend   = "memory"
+	fileBackend     = "file"
 )
 
 var flags = struct {
-	dbAddr string
-	creds  string
-	realm  string
+	backend        string
+	out            string
+	dbAddr         string
+	dbName         string
+	creds          string
+	credsFile      string
+	realm          string
+	dbCAFile       string
+	tlsClientCert  string
+	tlsClientKey   string
+	batchSize      int
+	dbRetries      int
+	dbRetryDelay   time.Duration
+	archiveDepth   int
+	poll           bool
+	interval       time.Duration
+	verifyKeys     []string
+	rekorURL       string
+	rekorKey       string
+	fulcioRoots    string
+	resolveTimeout time.Duration
+	resolveMaxSize int64
+	resolveBudget  time.Duration
+	dryRun         bool
+	expandEnv      bool
+	failOnError    bool
+	metricsAddr    string
+	otlpEndpoint   string
+	workers        int
+	schemaMode     string
+	cycleMode      string
+	digestMode     string
+	deadletterDir  string
+	checkpointFile string
+	bulk           bool
+	bulkDir        string
+	bulkRate       int
+
+	dbInsecureSkipVerify bool
+	progressInterval     time.Duration
+	processTimeout       time.Duration
+	ingestTimeout        time.Duration
+	assembleTimeout      time.Duration
+	includeTypes         []string
+	excludeTypes         []string
+	includeFormats       []string
+	excludeFormats       []string
+	includeSources       []string
+	excludeSources       []string
+	dedup                bool
+	dedupFile            string
+	edgeCacheSize        int
+	edgeCacheTTL         time.Duration
+	since                string
+	ociRepos             []string
+	s3Buckets            []string
+	s3Queues             []string
+	kafkaBrokers         []string
+	kafkaTopics          []string
+	kafkaGroup           string
+	reconnect            collector.ReconnectPolicy
+	fetchConcurrency     int
+	gcsBuckets           []string
+	documentTypes        []string
+	registryCreds        []string
+	purlCacheSize        int
+	collectors           []string
 }{}
 
 type options struct {
-	dbAddr string
-	user   string
-	pass   string
-	realm  string
+	backend string
+	dbAddr  string
+	dbName  string
+	user    string
+	pass    string
+	realm   string
+	// file the graph is written to by the file backend
+	out string
+	// encryption of the connection to neo4j
+	tls graphdb.TLSOptions
+	// number of nodes or edges written to neo4j in one query
+	batchSize int
+	// retry policy for the writes failing with transient neo4j errors
+	retry graphdb.RetryPolicy
+	// number of edges written to neo4j remembered to skip identical
+	// writes, each for edgeCacheTTL; 0 disables the cache
+	edgeCacheSize int
+	edgeCacheTTL  time.Duration
+	// number of normalized purls remembered by the parsers, 0 disables the
+	// cache
+	purlCacheSize int
 
-	// path to folder with documents to collect
-	path string
+	// paths to the files and folders with documents to collect
+	paths []string
+	// OCI repositories and S3 and GCS buckets collected together with the
+	// paths
+	ociRepos   []string
+	s3Buckets  []bucketPrefix
+	gcsBuckets []bucketPrefix
+	// SQS queues receiving the event notifications of some of the S3
+	// buckets, by bucket, see bucketPrefix.String
+	s3Queues map[string]string
+	// Kafka topics collected together with the paths, consumed from the
+	// brokers as a member of the consumer group
+	kafkaBrokers []string
+	kafkaTopics  []string
+	kafkaGroup   string
+	// collectors compiled in from outside of GUAC, see
+	// collector.RegisterCollectorFactory
+	externalCollectors []externalCollector
+	// credentials of the OCI registries, by host, used when the docker
+	// config has none
+	registryCreds map[string]authn.AuthConfig
+	// type and format declared for the documents of some of the paths,
+	// repositories and buckets, by source, see parseDocumentTypes
+	documentTypes map[string]declaredType
+	// poll the folder for new documents
+	poll bool
+	// interval between each scan of the folder when polling
+	interval time.Duration
+	// number of nested archive levels whose entries are ingested
+	archiveDepth int
+	// files modified before this time are skipped, the zero time ingests
+	// all of them
+	since time.Time
+	// PEM encoded public keys used to verify DSSE envelopes
+	verifyKeys []string
+	// Rekor instance keyless signed DSSE envelopes are looked up in, empty
+	// disables the lookup
+	rekorURL string
+	// path to the PEM encoded public key of the Rekor instance
+	rekorKey string
+	// path to the PEM encoded Fulcio root and intermediate certificates
+	fulcioRoots string
+	// timeout when fetching documents referenced by SBOMs, 0 disables fetching
+	resolveTimeout time.Duration
+	// maximum size of the referenced documents, and total time spent
+	// fetching those of a document tree
+	resolveMaxSize int64
+	resolveBudget  time.Duration
+	// process and ingest the documents without writing to the database
+	dryRun bool
+	// exit with an error status if any document failed
+	failOnError bool
+	// address to serve the gRPC ingestion service on
+	listenAddr string
+	// address to serve the Prometheus metrics on, empty disables them
+	metricsAddr string
+	// OTLP/HTTP endpoint the traces are exported to, empty disables tracing
+	otlpEndpoint string
+	// number of documents processed and ingested concurrently
+	workers int
+	// how SBOMs not matching the schema of their spec version are handled
+	schemaMode process.SchemaValidationMode
+	// how cycles among the dependencies of the stored graphs are handled
+	cycleMode assembler.CycleMode
+	// how artifacts whose digest contradicts their package are handled
+	digestMode assembler.DigestMismatchMode
+	// directory the documents failing the pipeline are written to, empty
+	// drops them
+	deadletterDir string
+	// file the ingested files are checkpointed in, empty disables
+	// checkpointing
+	checkpointFile string
+	// interval between progress reports, 0 disables them
+	progressInterval time.Duration
+	// timeouts of the stages of the pipeline
+	timeouts stageTimeouts
+	// documents to ingest, the others are skipped before being processed
+	filter filter.Filter
+	// skip the documents whose content was already stored in this run
+	dedup bool
+	// file the digests of the stored documents are recorded in, so that
+	// they are also skipped in later runs; empty keeps them in memory
+	dedupFile string
+	// stage the graphs in bulkDir, then load them in large batches
+	bulk    bool
+	bulkDir string
+	// maximum number of nodes and edges loaded per second, 0 is unlimited
+	bulkRate int
 }
 
 func init() {
-	exampleCmd.PersistentFlags().StringVar(&flags.dbAddr, "db-addr", "neo4j://localhost:7687", "address to neo4j db")
-	exampleCmd.PersistentFlags().StringVar(&flags.creds, "creds", "", "credentials to access neo4j in 'user:pass' format")
-	exampleCmd.PersistentFlags().StringVar(&flags.realm, "realm", "neo4j", "realm to connecto graph db")
-	_ = exampleCmd.MarkPersistentFlagRequired("creds")
+	exampleCmd.PersistentFlags().StringVar(&flags.backend, "backend", neo4jBackend, "database to store the graph in: neo4j, postgres, memory or file")
+	exampleCmd.PersistentFlags().StringVar(&flags.out, "out", "", "path to the JSON file the combined graph is written to by the file backend")
+	addDBFlags(exampleCmd)
+	addTLSFlags(exampleCmd)
+	addExpandEnvFlag(exampleCmd)
+	exampleCmd.PersistentFlags().IntVar(&flags.batchSize, "batch-size", assembler.DefaultBatchSize, "number of nodes or edges written to neo4j in one query")
+	exampleCmd.PersistentFlags().IntVar(&flags.dbRetries, "db-retries", graphdb.DefaultRetryPolicy.MaxRetries, "number of times a neo4j write failing with a transient error is retried")
+	exampleCmd.PersistentFlags().DurationVar(&flags.dbRetryDelay, "db-retry-delay", graphdb.DefaultRetryPolicy.BaseDelay, "base delay of the exponential backoff between neo4j write retries")
+	addEdgeCacheFlags(exampleCmd)
+	addPurlCacheFlag(exampleCmd)
+	addCollectorFlags(exampleCmd)
+	exampleCmd.PersistentFlags().BoolVar(&flags.poll, "poll", false, "keep watching the folder and ingest new or modified documents")
+	exampleCmd.PersistentFlags().DurationVar(&flags.interval, "interval", 5*time.Second, "interval between each scan of the folder when polling")
+	exampleCmd.PersistentFlags().StringVar(&flags.since, "since", "", "only ingest the files modified after this time, given as an RFC 3339 timestamp (e.g. 2023-01-02T15:04:05Z) or a duration before now (e.g. 24h)")
+	exampleCmd.PersistentFlags().IntVar(&flags.archiveDepth, "archive-depth", file.DefaultArchiveDepth, "number of nested archive levels whose entries are ingested as documents; 0 ingests archives as regular files")
+	exampleCmd.PersistentFlags().StringSliceVar(&flags.verifyKeys, "verify-keys", nil, "paths to PEM encoded public keys; when set, DSSE envelopes without a signature from one of these keys are rejected")
+	exampleCmd.PersistentFlags().StringVar(&flags.rekorURL, "rekor-url", "", "URL of the Rekor instance (e.g. "+rekor_verifier.DefaultRekorURL+"); when set, keyless signed DSSE envelopes without a valid entry in the log are rejected")
+	exampleCmd.PersistentFlags().StringVar(&flags.rekorKey, "rekor-key", "", "path to the PEM encoded public key of the Rekor instance")
+	exampleCmd.PersistentFlags().StringVar(&flags.fulcioRoots, "fulcio-roots", "", "path to the PEM encoded Fulcio root and intermediate certificates the signing certificates must chain to")
+	exampleCmd.PersistentFlags().BoolVar(&flags.dryRun, "dry-run", false, "process and ingest the documents, logging the nodes and edges instead of writing them to the database")
+	addResolveFlags(exampleCmd)
+	exampleCmd.PersistentFlags().StringVar(&flags.metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on at /metrics (e.g. :9090); empty disables them")
+	exampleCmd.PersistentFlags().IntVar(&flags.workers, "workers", 1, "number of documents processed and ingested concurrently")
+	exampleCmd.PersistentFlags().StringVar(&flags.deadletterDir, "deadletter-dir", "", "directory the documents failing the pipeline are written to, with the error, for auditing and reprocessing")
+	exampleCmd.PersistentFlags().DurationVar(&flags.progressInterval, "progress-interval", 10*time.Second, "interval between progress reports, drawn as a progress bar on a terminal and logged otherwise; 0 disables them")
+	exampleCmd.PersistentFlags().BoolVar(&flags.failOnError, "fail-on-error", false, "exit with status 1 if any document failed to be processed, ingested or stored; by default, the failures are only logged and counted in the summary")
+	exampleCmd.PersistentFlags().StringVar(&flags.checkpointFile, "checkpoint-file", "", "file recording the documents already ingested, so that they are skipped when restarting; empty ingests everything")
+	addTimeoutFlags(exampleCmd)
+	addFilterFlags(exampleCmd)
+	addDedupFlags(exampleCmd)
+	addBulkFlags(exampleCmd)
+	addTracingFlags(exampleCmd)
+	addGraphCheckFlags(exampleCmd)
+	exampleCmd.PersistentFlags().StringVar(&flags.schemaMode, "schema-validation", string(process.SchemaValidationWarn), "how CycloneDX and SPDX JSON documents not matching their schema are handled: warn, strict (reject them) or off")
 }
 
 var exampleCmd = &cobra.Command{
-	Use:   "files [flags] file_path",
-	Short: "take a folder of files and create a GUAC graph",
+	Use:     "files [flags] [file_path...]",
+	Aliases: []string{"collect"},
+	Short:   "take files or folders of files (or glob patterns matching them), OCI repositories, buckets and Kafka topics, and create a GUAC graph",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := logging.WithLogger(context.Background())
 		logger := logging.FromContext(ctx)
 
 		opts, err := validateFlags(args)
+		if err == nil {
+			err = validateBulkFlags(cmd, &opts)
+		}
 		if err != nil {
 			fmt.Printf("unable to validate flags: %v\n", err)
 			_ = cmd.Help()
 			os.Exit(1)
 		}
 
-		// Register collector
-		fileCollector := file.NewFileCollector(ctx, opts.path, false, time.Second)
-		err = collector.RegisterDocumentCollector(fileCollector, file.FileCollector)
-		if err != nil {
-			logger.Errorf("unable to register file collector: %v", err)
+		// A complete staging is only loaded, the documents are not collected
+		// again
+		if opts.bulk && assembler.BulkStaged(opts.bulkDir) {
+			logger.Infof("graphs already staged in %s, resuming their load", opts.bulkDir)
+			if err := loadBulk(ctx, opts); err != nil {
+				logger.Fatalf("unable to load the staged graphs: %v", err)
+			}
+			return
 		}
 
-		// Get pipeline of components
-		processorFunc, err := getProcessor(ctx)
+		if len(opts.verifyKeys) > 0 {
+			if err := registerVerifier(ctx, opts.verifyKeys); err != nil {
+				logger.Errorf("unable to register DSSE verifier: %v", err)
+				os.Exit(1)
+			}
+		}
+		if opts.rekorURL != "" {
+			if err := registerRekorVerifier(opts.rekorURL, opts.rekorKey, opts.fulcioRoots); err != nil {
+				logger.Errorf("unable to register Rekor verifier: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		if opts.metricsAddr != "" {
+			serveMetrics(ctx, opts.metricsAddr, nil)
+		}
+		shutdownTracing, err := setupTracing(ctx, opts.otlpEndpoint)
 		if err != nil {
 			logger.Errorf("error: %v", err)
 			os.Exit(1)
 		}
-		ingestorFunc, err := getIngestor(ctx)
+
+		setupDocumentResolver(opts)
+		common.SetPurlCacheSize(opts.purlCacheSize)
+		process.SetSchemaValidation(opts.schemaMode)
+
+		// Documents are not written in dry runs, so they are not checkpointed
+		var checkpoints checkpoint.Store
+		if opts.checkpointFile != "" && !opts.dryRun {
+			checkpoints, err = checkpoint.NewFileStore(opts.checkpointFile)
+			if err != nil {
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		cache, err := getDedupCache(opts)
 		if err != nil {
 			logger.Errorf("error: %v", err)
 			os.Exit(1)
 		}
-		assemblerFunc, err := getAssembler(opts)
-		if err != nil {
+
+		// Register a collector for each root, repository and bucket
+		if err := registerCollectors(ctx, opts, checkpoints); err != nil {
 			logger.Errorf("error: %v", err)
 			os.Exit(1)
 		}
 
-		totalNum := 0
-		gotErr := false
-		// Set emit function to go through the entire pipeline
-		emit := func(d *processor.Document) error {
-			totalNum += 1
-			start := time.Now()
-
-			docTree, err := processorFunc(d)
+		// Get pipeline of components
+		var backend assembler.Backend
+		var stager *assembler.BulkStager
+		var store assembler.Storer
+		if opts.bulk && !opts.dryRun {
+			stager, err = assembler.NewBulkStager(opts.bulkDir)
 			if err != nil {
-				gotErr = true
-				return fmt.Errorf("unable to process doc: %v, fomat: %v, document: %v", err, d.Format, d.Type)
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
 			}
-
-			graphs, err := ingestorFunc(docTree)
+			store = stager
+		} else if !opts.dryRun {
+			backend, err = getBackend(opts)
 			if err != nil {
-				gotErr = true
-				return fmt.Errorf("unable to ingest doc tree: %v", err)
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
 			}
+			store = backend
+		}
+		pipe, err := newPipeline(ctx, store, opts)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
 
-			err = assemblerFunc(graphs)
+		// emit and fail are called concurrently by the workers
+		var totalNum, errNum, filteredNum, dedupedNum int64
+		var sink deadletter.Sink
+		if opts.deadletterDir != "" {
+			sink, err = deadletter.NewDirectorySink(opts.deadletterDir)
 			if err != nil {
-				gotErr = true
-				return fmt.Errorf("unable to assemble graphs: %v", err)
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
+			}
+		}
+		// fail counts the failure and keeps the document in the deadletter
+		// sink, if any
+		fail := func(d *processor.Document, stage deadletter.Stage, err error) error {
+			atomic.AddInt64(&errNum, 1)
+			if sink != nil {
+				if sinkErr := sink.Write(d, stage, err); sinkErr != nil {
+					logger.Errorf("unable to write document %s to the deadletter directory: %v", d.SourceInformation.Source, sinkErr)
+				}
+			}
+			return err
+		}
+
+		// Set emit function to go through the entire pipeline
+		emit := func(d *processor.Document) error {
+			atomic.AddInt64(&totalNum, 1)
+			if _, stage, err := pipe.run(ctx, d); err != nil {
+				return fail(d, stage, err)
 			}
-			t := time.Now()
-			elapsed := t.Sub(start)
-			logger.Infof("[%v] completed doc %+v", elapsed, d.SourceInformation)
 			return nil
 		}
+		emit = dedupEmitter(ctx, cache, &dedupedNum, emit)
+		emit = filterEmitter(ctx, opts.filter, &filteredNum, emit)
 
-		// Collect
+		// Collect until the collectors are done or a termination signal is
+		// received. The documents already collected are still ingested with
+		// ctx, which is never canceled.
+		collectCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		cancelOnSignal(ctx, cancel)
 		errHandler := func(err error) bool {
 			if err == nil {
 				logger.Info("collector ended gracefully")
 				return true
 			}
+			if errors.Is(err, context.Canceled) && collectCtx.Err() != nil {
+				logger.Info("collector stopped on shutdown")
+				return true
+			}
 			logger.Errorf("collector ended with error: %v", err)
 			return false
 		}
-		if err := collector.Collect(ctx, emit, errHandler); err != nil {
-			logger.Fatal(err)
+
+		// Report the progress until all the documents are emitted
+		stopProgress := func() {}
+		if opts.progressInterval > 0 {
+			reporter := progress.NewReporter(collector.BufferedDocuments)
+			// the number of documents of the remote collectors is only
+			// known once they are listed
+			if !opts.poll && !hasRemoteCollectors(opts) {
+				reporter.SetTotal(countFiles(opts.paths))
+			}
+			emit = reporter.Wrap(emit)
+			progressCtx, cancelProgress := context.WithCancel(ctx)
+			progressDone := make(chan struct{})
+			go func() {
+				defer close(progressDone)
+				reporter.Run(progressCtx, opts.progressInterval, os.Stderr)
+			}()
+			stopProgress = func() {
+				cancelProgress()
+				<-progressDone
+			}
 		}
+		collectErr := collector.CollectWithWorkers(collectCtx, emit, errHandler, opts.workers)
+		stopProgress()
 
-		if gotErr {
-			logger.Fatalf("completed ingestion with errors")
+		// The staging is only complete when all the documents were collected
+		if stager != nil {
+			if collectErr == nil && collectCtx.Err() == nil {
+				err = stager.Finish()
+				if err == nil {
+					err = loadBulk(ctx, opts)
+				}
+				if err != nil {
+					logger.Errorf("unable to load the staged graphs: %v", err)
+					collectErr = err
+				}
+			} else {
+				logger.Warnf("staging in %s interrupted, the documents will be staged again on the next run", opts.bulkDir)
+			}
+			if err := stager.Close(); err != nil {
+				logger.Errorf("unable to close the staging files: %v", err)
+			}
+		}
+
+		if mb, ok := backend.(*assembler.MemoryBackend); ok {
+			g := mb.Graph()
+			logger.Infof("in-memory graph has %v nodes and %v edges", len(g.Nodes), len(g.Edges))
+		}
+		// Close explicitly, the deferred calls are skipped by logger.Fatal
+		if fb, ok := backend.(*assembler.FileBackend); ok {
+			g := fb.Graph()
+			if err := fb.Close(); err != nil {
+				logger.Errorf("unable to write the graph: %v", err)
+				collectErr = err
+			} else {
+				logger.Infof("wrote graph with %v nodes and %v edges to %s", len(g.Nodes), len(g.Edges), opts.out)
+			}
+		} else if backend != nil {
+			if err := backend.Close(); err != nil {
+				logger.Errorf("unable to close the database connection: %v", err)
+			}
+		}
+		if checkpoints != nil {
+			if err := checkpoints.Close(); err != nil {
+				logger.Errorf("unable to close the checkpoint file: %v", err)
+			}
+		}
+		if cache != nil {
+			if err := cache.Close(); err != nil {
+				logger.Errorf("unable to close the dedup file: %v", err)
+			}
+		}
+		shutdownTracing()
+		if collectErr != nil {
+			logger.Fatal(collectErr)
+		}
+		if filteredNum > 0 {
+			logger.Infof("skipped %v documents not matching the filters", filteredNum)
+		}
+		if dedupedNum > 0 {
+			logger.Infof("skipped %v duplicate documents", dedupedNum)
+		}
+		if errNum > 0 && opts.failOnError {
+			logger.Fatalf("completed ingestion with errors in %v of %v documents", errNum, totalNum)
+		} else if errNum > 0 {
+			logger.Warnf("completed ingestion with errors in %v of %v documents", errNum, totalNum)
 		} else {
 			logger.Infof("completed ingesting %v documents", totalNum)
 		}
@@ -147,82 +536,528 @@ var exampleCmd = &cobra.Command{
 
 func validateFlags(args []string) (options, error) {
 	var opts options
-	credsSplit := strings.Split(flags.creds, ":")
-	if len(credsSplit) != 2 {
-		return opts, fmt.Errorf("creds flag not in correct format user:pass")
+	if err := validateBackendFlags(&opts); err != nil {
+		return opts, err
+	}
+
+	opts.dryRun = flags.dryRun
+	opts.failOnError = flags.failOnError
+	opts.metricsAddr = flags.metricsAddr
+	opts.otlpEndpoint = flags.otlpEndpoint
+	if flags.workers < 1 {
+		return opts, fmt.Errorf("workers must be positive")
+	}
+	opts.workers = flags.workers
+	if flags.progressInterval < 0 {
+		return opts, fmt.Errorf("progress-interval must not be negative")
+	}
+	opts.progressInterval = flags.progressInterval
+	timeouts, err := getStageTimeouts()
+	if err != nil {
+		return opts, err
+	}
+	opts.timeouts = timeouts
+	opts.filter, err = getFilter()
+	if err != nil {
+		return opts, err
 	}
-	opts.user = credsSplit[0]
-	opts.pass = credsSplit[1]
-	opts.dbAddr = flags.dbAddr
 
-	if len(args) != 1 {
-		return opts, fmt.Errorf("expected positional argument for file_path")
+	// dry runs need no credentials
+	if needsCredentials(opts.backend) && !opts.dryRun {
+		user, pass, err := getCredentials()
+		if err != nil {
+			return opts, err
+		}
+		opts.user = user
+		opts.pass = pass
+	}
+	opts.dbAddr = expandEnv(flags.dbAddr)
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+	if flags.batchSize <= 0 {
+		return opts, fmt.Errorf("batch-size must be positive")
+	}
+	opts.batchSize = flags.batchSize
+	retry, err := getRetryPolicy()
+	if err != nil {
+		return opts, err
+	}
+	opts.retry = retry
+	if err := validateEdgeCacheFlags(&opts); err != nil {
+		return opts, err
+	}
+	if err := validatePurlCacheFlag(&opts); err != nil {
+		return opts, err
+	}
+
+	if err := validateCollectorFlags(&opts); err != nil {
+		return opts, err
+	}
+	if len(args) == 0 && !hasRemoteCollectors(opts) {
+		return opts, fmt.Errorf("expected positional arguments for file_path, or --oci, --s3, --gcs, --kafka-topic or --collector")
+	}
+	if len(args) > 0 {
+		paths, err := file.ResolvePaths(expandEnvAll(args))
+		if err != nil {
+			return opts, err
+		}
+		opts.paths = paths
+	}
+	opts.poll = flags.poll
+	opts.interval = flags.interval
+	if flags.archiveDepth < 0 {
+		return opts, fmt.Errorf("archive-depth must not be negative")
+	}
+	opts.archiveDepth = flags.archiveDepth
+	opts.since, err = collector.ParseSince(flags.since, time.Now())
+	if err != nil {
+		return opts, fmt.Errorf("invalid since: %w", err)
+	}
+	opts.verifyKeys = flags.verifyKeys
+	if flags.rekorURL != "" {
+		if len(flags.verifyKeys) > 0 {
+			return opts, fmt.Errorf("verify-keys and rekor-url cannot be used together")
+		}
+		if flags.rekorKey == "" || flags.fulcioRoots == "" {
+			return opts, fmt.Errorf("rekor-key and fulcio-roots are required with rekor-url")
+		}
+	}
+	opts.rekorURL = flags.rekorURL
+	opts.rekorKey = flags.rekorKey
+	opts.fulcioRoots = flags.fulcioRoots
+	if err := validateResolveFlags(&opts); err != nil {
+		return opts, err
+	}
+	opts.deadletterDir = flags.deadletterDir
+	opts.checkpointFile = flags.checkpointFile
+	opts.dedup = flags.dedup
+	opts.dedupFile = flags.dedupFile
+	opts.schemaMode, err = getSchemaValidationMode()
+	if err != nil {
+		return opts, err
+	}
+	if err := validateGraphCheckFlags(&opts); err != nil {
+		return opts, err
 	}
-	opts.path = args[0]
 
 	return opts, nil
 }
 
-func getProcessor(ctx context.Context) (func(*processor.Document) (processor.DocumentTree, error), error) {
-	return func(d *processor.Document) (processor.DocumentTree, error) {
-		return process.Process(ctx, d)
-	}, nil
+func getSchemaValidationMode() (process.SchemaValidationMode, error) {
+	switch mode := process.SchemaValidationMode(flags.schemaMode); mode {
+	case process.SchemaValidationWarn, process.SchemaValidationStrict, process.SchemaValidationOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown schema-validation mode %q, expected %s, %s or %s", flags.schemaMode,
+		process.SchemaValidationWarn, process.SchemaValidationStrict, process.SchemaValidationOff)
+}
+
+func getCycleMode() (assembler.CycleMode, error) {
+	switch mode := assembler.CycleMode(flags.cycleMode); mode {
+	case assembler.CycleWarn, assembler.CycleReject, assembler.CycleBreak:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown dependency-cycles mode %q, expected %s, %s or %s", flags.cycleMode,
+		assembler.CycleWarn, assembler.CycleReject, assembler.CycleBreak)
+}
+
+func getDigestMismatchMode() (assembler.DigestMismatchMode, error) {
+	switch mode := assembler.DigestMismatchMode(flags.digestMode); mode {
+	case assembler.DigestMismatchWarn, assembler.DigestMismatchMark, assembler.DigestMismatchReject:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown digest-mismatch mode %q, expected %s, %s or %s", flags.digestMode,
+		assembler.DigestMismatchWarn, assembler.DigestMismatchMark, assembler.DigestMismatchReject)
+}
+
+func getRetryPolicy() (graphdb.RetryPolicy, error) {
+	if flags.dbRetries < 0 {
+		return graphdb.RetryPolicy{}, fmt.Errorf("db-retries must not be negative")
+	}
+	if flags.dbRetryDelay <= 0 {
+		return graphdb.RetryPolicy{}, fmt.Errorf("db-retry-delay must be positive")
+	}
+	retry := graphdb.DefaultRetryPolicy
+	retry.MaxRetries = flags.dbRetries
+	retry.BaseDelay = flags.dbRetryDelay
+	if retry.MaxDelay < retry.BaseDelay {
+		retry.MaxDelay = retry.BaseDelay
+	}
+	return retry, nil
 }
-func getIngestor(ctx context.Context) (func(processor.DocumentTree) ([]assembler.Graph, error), error) {
-	return func(doc processor.DocumentTree) ([]assembler.Graph, error) {
-		inputs, err := parser.ParseDocumentTree(ctx, doc)
+
+// registerVerifier stores the public keys in an in-memory key provider, under
+// their DSSE key ID, and uses them to verify DSSE envelopes before ingestion
+func registerVerifier(ctx context.Context, keyPaths []string) error {
+	const keyProvider key.KeyProviderType = "inmemory"
+	if err := key.RegisterKeyProvider(inmemory.NewInmemoryProvider(), keyProvider); err != nil {
+		return err
+	}
+	for _, path := range keyPaths {
+		pemBytes, err := os.ReadFile(path)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("unable to read key %s: %w", path, err)
 		}
-		return inputs, nil
-	}, nil
+		pubKey, err := cryptoutils.UnmarshalPEMToPublicKey(pemBytes)
+		if err != nil {
+			return fmt.Errorf("unable to parse key %s: %w", path, err)
+		}
+		keyID, err := dsse.SHA256KeyID(pubKey)
+		if err != nil {
+			return err
+		}
+		if err := key.Store(ctx, keyID, pemBytes, keyProvider); err != nil {
+			return err
+		}
+	}
+
+	sigstoreVerifier := sigstore_verifier.NewSigstoreVerifier()
+	if err := verifier.RegisterVerifier(sigstoreVerifier, sigstoreVerifier.Type()); err != nil {
+		return err
+	}
+	return process.RegisterDSSEVerifier(sigstoreVerifier)
 }
 
-func getAssembler(opts options) (func([]assembler.Graph) error, error) {
-	authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
-	client, err := graphdb.NewGraphClient(opts.dbAddr, authToken)
+// registerRekorVerifier uses the Rekor instance and the Fulcio certificates
+// to verify keyless signed DSSE envelopes before ingestion
+func registerRekorVerifier(rekorURL string, rekorKeyPath string, fulcioRootsPath string) error {
+	rekorKey, err := os.ReadFile(rekorKeyPath)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("unable to read Rekor key %s: %w", rekorKeyPath, err)
 	}
-
-	err = createIndices(client)
+	fulcioRoots, err := os.ReadFile(fulcioRootsPath)
+	if err != nil {
+		return fmt.Errorf("unable to read Fulcio roots %s: %w", fulcioRootsPath, err)
+	}
+	rekorVerifier, err := rekor_verifier.NewRekorVerifier(rekorURL, rekorKey, fulcioRoots)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := verifier.RegisterVerifier(rekorVerifier, rekorVerifier.Type()); err != nil {
+		return err
 	}
+	return process.RegisterDSSEVerifier(rekorVerifier)
+}
 
-	return func(gs []assembler.Graph) error {
-		combined := assembler.Graph{
-			Nodes: []assembler.GuacNode{},
-			Edges: []assembler.GuacEdge{},
+// getProcessor returns the processor stage of the pipeline. Documents taking
+// longer than timeout to process are abandoned. The stage is traced as a
+// child of the span of ctx.
+func getProcessor(t time.Duration) (func(context.Context, *processor.Document) (processor.DocumentTree, error), error) {
+	return func(ctx context.Context, d *processor.Document) (processor.DocumentTree, error) {
+		ctx, span := tracing.Start(ctx, "process", documentAttributes(d)...)
+		defer span.End()
+		docTree, err := timeout.Run(ctx, "process", t, func(ctx context.Context) (processor.DocumentTree, error) {
+			return process.Process(ctx, d)
+		})
+		tracing.RecordError(span, err)
+		return docTree, err
+	}, nil
+}
+
+// getIngestor returns the ingestor stage of the pipeline. Document trees
+// taking longer than timeout to parse are abandoned. When only some
+// sub-documents fail to parse, the graphs of the others are returned with a
+// *parser.PartialError, see skipPartialFailure.
+func getIngestor(t time.Duration) (func(context.Context, processor.DocumentTree) ([]assembler.Graph, error), error) {
+	return func(ctx context.Context, doc processor.DocumentTree) ([]assembler.Graph, error) {
+		ctx, span := tracing.Start(ctx, "ingest", documentAttributes(doc.Document)...)
+		defer span.End()
+		graphs, err := timeout.Run(ctx, "ingest", t, func(ctx context.Context) ([]assembler.Graph, error) {
+			return parser.ParseDocumentTree(ctx, doc)
+		})
+		tracing.RecordError(span, err)
+		span.SetAttributes(attribute.Int("graphs", len(graphs)))
+		return graphs, err
+	}, nil
+}
+
+// skipPartialFailure logs the sub-documents of d that could not be parsed and
+// returns nil if err is a *parser.PartialError, so that the graphs of the
+// other documents are still assembled. Other errors are returned as is.
+func skipPartialFailure(ctx context.Context, d *processor.Document, err error) error {
+	var partial *parser.PartialError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	logging.FromContext(ctx).Warnw("ingesting document partially",
+		"source", d.SourceInformation.Source,
+		"parsed", partial.Parsed,
+		"failed", len(partial.Failures),
+		"error", err)
+	return nil
+}
+
+// validateBackendFlags sets the backend, and the file it writes to for the
+// file backend
+func validateBackendFlags(opts *options) error {
+	switch flags.backend {
+	case neo4jBackend, postgresBackend, memoryBackend, fileBackend:
+		opts.backend = flags.backend
+	default:
+		return fmt.Errorf("unknown backend %q, expected %s, %s, %s or %s", flags.backend, neo4jBackend, postgresBackend, memoryBackend, fileBackend)
+	}
+	if opts.backend == fileBackend {
+		if flags.out == "" {
+			return fmt.Errorf("out is required with the %s backend", fileBackend)
+		}
+		opts.out = flags.out
+	} else if flags.out != "" {
+		return fmt.Errorf("out is only supported with the %s backend", fileBackend)
+	}
+	if flags.dbName != "" && opts.backend != neo4jBackend {
+		return fmt.Errorf("db-name is only supported with the %s backend", neo4jBackend)
+	}
+	return nil
+}
+
+// needsCredentials returns whether the backend connects to a database
+func needsCredentials(backend string) bool {
+	return backend != memoryBackend && backend != fileBackend
+}
+
+func getBackend(opts options) (assembler.Backend, error) {
+	switch opts.backend {
+	case memoryBackend:
+		return assembler.NewMemoryBackend(), nil
+	case fileBackend:
+		return assembler.NewFileBackend(opts.out)
+	case postgresBackend:
+		dsn, err := url.Parse(opts.dbAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postgres connection URL: %w", err)
 		}
-		for _, g := range gs {
-			combined.AppendGraph(g)
+		dsn.User = url.UserPassword(opts.user, opts.pass)
+		client, err := postgresdb.NewPostgresClient(dsn.String())
+		if err != nil {
+			return nil, err
 		}
-		if err := assembler.StoreGraph(combined, client); err != nil {
-			return err
+		if err := postgresdb.Migrate(client); err != nil {
+			client.Close()
+			return nil, err
 		}
+		return assembler.NewPostgresBackend(client), nil
+	default:
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			return nil, err
+		}
+		if err := createIndices(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+		return assembler.NewNeo4jBackend(client, opts.batchSize, opts.retry, getEdgeCache(opts)), nil
+	}
+}
 
-		return nil
+// getAssembler returns an assembler writing the graphs to the store, once
+// enriched by the registered enrichers. It is safe for concurrent use: graphs
+// are merged concurrently, but written one at a time, as concurrent writes
+// merging the same nodes deadlock in Neo4j.
+//
+// Graphs not written within timeout, including the time spent waiting for the
+// other writes, are abandoned. The stores only stop between batches, so an
+// abandoned write still blocks the next ones until its current batch
+// completes.
+func getAssembler(store assembler.Storer, t time.Duration) (func(context.Context, []assembler.Graph) error, error) {
+	// a semaphore rather than a mutex, so that waiting can time out
+	sem := make(chan struct{}, 1)
+	return func(ctx context.Context, gs []assembler.Graph) error {
+		ctx, span := tracing.Start(ctx, "assemble")
+		defer span.End()
+		_, err := timeout.Run(ctx, "assemble", t, func(ctx context.Context) (struct{}, error) {
+			combined := assembler.Graph{
+				Nodes: []assembler.GuacNode{},
+				Edges: []assembler.GuacEdge{},
+			}
+			combined.Merge(gs...)
+			combined, err := enricher.EnrichGraph(ctx, combined)
+			if err != nil {
+				return struct{}{}, err
+			}
+			span.SetAttributes(attribute.Int("nodes", len(combined.Nodes)), attribute.Int("edges", len(combined.Edges)))
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return struct{}{}, ctx.Err()
+			}
+			defer func() { <-sem }()
+			start := time.Now()
+			defer func() {
+				metrics.AssemblerWriteSeconds.Observe(time.Since(start).Seconds())
+			}()
+			if err := store.Store(ctx, combined); err != nil {
+				return struct{}{}, err
+			}
+			metrics.NodesStored.Add(float64(len(combined.Nodes)))
+			metrics.EdgesStored.Add(float64(len(combined.Edges)))
+
+			return struct{}{}, nil
+		})
+		tracing.RecordError(span, err)
+		return err
 	}, nil
 }
 
-func createIndices(client graphdb.Client) error {
-	indices := map[string][]string{
-		"Artifact":      {"digest", "name"},
-		"Package":       {"purl", "name"},
-		"Metadata":      {"id"},
-		"Attestation":   {"digest"},
-		"Vulnerability": {"id"},
+// cycleStorer checks the cycles among the dependencies of the graphs before
+// storing them
+type cycleStorer struct {
+	assembler.Storer
+	mode assembler.CycleMode
+}
+
+// cycleChecking wraps store so that the dependency cycles of the graphs are
+// logged, and rejected or broken following mode
+func cycleChecking(store assembler.Storer, mode assembler.CycleMode) assembler.Storer {
+	return &cycleStorer{Storer: store, mode: mode}
+}
+
+func (s *cycleStorer) Store(ctx context.Context, g assembler.Graph) error {
+	logger := logging.FromContext(ctx)
+	g, cycles, err := assembler.CheckDependencyCycles(g, s.mode)
+	for _, c := range cycles {
+		logger.Warnw("dependency cycle", "cycle", c.String(), "mode", s.mode)
+	}
+	if err != nil {
+		return err
 	}
+	return s.Storer.Store(ctx, g)
+}
+
+// digestStorer checks the digests of the artifacts of the graphs against
+// the packages they are claimed to be before storing them
+type digestStorer struct {
+	assembler.Storer
+	mode   assembler.DigestMismatchMode
+	lookup assembler.DigestLookup
+}
 
-	for label, attributes := range indices {
-		for _, attribute := range attributes {
-			err := assembler.CreateIndexOn(client, label, attribute)
+// digestChecking wraps store so that the artifacts whose digest contradicts
+// their package are logged, and marked or rejected following mode. When store
+// can look up the digests of the packages it already stores, the artifacts
+// are also checked against them.
+func digestChecking(store assembler.Storer, mode assembler.DigestMismatchMode) assembler.Storer {
+	lookup, _ := store.(assembler.DigestLookup)
+	return &digestStorer{Storer: store, mode: mode, lookup: lookup}
+}
+
+func (s *digestStorer) Store(ctx context.Context, g assembler.Graph) error {
+	logger := logging.FromContext(ctx)
+	var stored map[string][]string
+	if s.lookup != nil {
+		if purls := assembler.PackagePurls(g); len(purls) > 0 {
+			var err error
+			stored, err = s.lookup.ExpectedDigests(purls)
 			if err != nil {
 				return err
 			}
 		}
 	}
+	g, mismatches, err := assembler.CheckDigestMismatches(g, stored, s.mode)
+	for _, m := range mismatches {
+		logger.Warnw("digest mismatch", "artifact", m.Artifact.Name, "digest", m.Artifact.Digest,
+			"package", m.Package.Purl, "expected", m.Expected, "mode", s.mode)
+	}
+	if err != nil {
+		return err
+	}
+	return s.Storer.Store(ctx, g)
+}
+
+// cancelOnSignal calls cancel on the first SIGINT or SIGTERM. The default
+// behavior is then restored, so that a second signal terminates the process
+// immediately.
+func cancelOnSignal(ctx context.Context, cancel context.CancelFunc) {
+	logger := logging.FromContext(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)
+		logger.Infof("received %v, stopping the collectors and ingesting the documents already collected", sig)
+		cancel()
+	}()
+}
+
+// serveMetrics serves the Prometheus metrics on addr in the background. In
+// server modes, ready is the readiness check of the server, and the health
+// probes are also served; nil serves no probes. A failure to serve is logged
+// but does not stop the ingestion.
+func serveMetrics(ctx context.Context, addr string, ready health.Check) {
+	logger := logging.FromContext(ctx)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if ready != nil {
+		health.Register(mux, ready)
+	}
+	go func() {
+		logger.Infof("serving metrics on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorf("unable to serve metrics: %v", err)
+		}
+	}()
+}
+
+// countFiles returns the number of files under the paths, as an estimate of
+// the number of documents to ingest. Archives count as one file.
+func countFiles(paths []string) int {
+	n := 0
+	for _, path := range paths {
+		_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				n++
+			}
+			return nil
+		})
+	}
+	return n
+}
+
+// getDryRunAssembler returns an assembler that only logs the number of nodes
+// and edges of each type that would have been written. Graphs with unknown
+// node or edge types still fail, as they would when writing.
+func getDryRunAssembler(ctx context.Context) func(context.Context, []assembler.Graph) error {
+	logger := logging.FromContext(ctx)
+	return func(ctx context.Context, gs []assembler.Graph) error {
+		_, span := tracing.Start(ctx, "assemble", attribute.Bool("dry_run", true))
+		defer span.End()
+		combined := assembler.Graph{
+			Nodes: []assembler.GuacNode{},
+			Edges: []assembler.GuacEdge{},
+		}
+		combined.Merge(gs...)
+		span.SetAttributes(attribute.Int("nodes", len(combined.Nodes)), attribute.Int("edges", len(combined.Edges)))
+		if err := assembler.ValidateGraph(combined); err != nil {
+			tracing.RecordError(span, err)
+			return err
+		}
+
+		nodeTypes := map[string]int{}
+		for _, n := range combined.Nodes {
+			nodeTypes[n.Type()]++
+		}
+		edgeTypes := map[string]int{}
+		for _, e := range combined.Edges {
+			edgeTypes[e.Type()]++
+		}
+		logger.Infow("dry run, skipping write",
+			"nodes", len(combined.Nodes),
+			"edges", len(combined.Edges),
+			"node_types", nodeTypes,
+			"edge_types", edgeTypes)
+		return nil
+	}
+}
 
-	return nil
+// createIndices creates the indexes of the GUAC graph that do not exist yet.
+// The ids are only indexed, `guacone init` constrains them.
+func createIndices(client graphdb.Client) error {
+	_, _, err := assembler.CreateSchema(client, false)
+	return err
 }
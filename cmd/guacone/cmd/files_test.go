@@ -0,0 +1,38 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/assembler/postgresdb"
+)
+
+func TestPostgresDriver(t *testing.T) {
+	db, err := sql.Open(postgresdb.DriverName, "postgres://localhost:5432/guac?sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open the postgres driver: %v", err)
+	}
+	db.Close()
+
+	// nothing listens on the port, so connecting must fail in the driver
+	_, err = getBackend(options{backend: postgresBackend, dbAddr: "postgres://127.0.0.1:1/guac?sslmode=disable", user: "guac", pass: "guac"})
+	if err == nil || strings.Contains(err.Error(), "unknown driver") {
+		t.Errorf("getBackend() error = %v, want a connection error", err)
+	}
+}
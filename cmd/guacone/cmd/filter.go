@@ -0,0 +1,85 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/filter"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/spf13/cobra"
+)
+
+// addFilterFlags adds the flags selecting the documents to ingest to the
+// command
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&flags.includeTypes, "include-types", nil, "only ingest the documents of these types (e.g. cyclonedx,spdx)")
+	cmd.PersistentFlags().StringSliceVar(&flags.excludeTypes, "exclude-types", nil, "skip the documents of these types (e.g. dsse)")
+	cmd.PersistentFlags().StringSliceVar(&flags.includeFormats, "include-formats", nil, "only ingest the documents in these formats (e.g. json)")
+	cmd.PersistentFlags().StringSliceVar(&flags.excludeFormats, "exclude-formats", nil, "skip the documents in these formats (e.g. xml)")
+	cmd.PersistentFlags().StringSliceVar(&flags.includeSources, "include-sources", nil, "only ingest the documents whose source matches one of these glob patterns (e.g. 'file:///sboms/*.json')")
+	cmd.PersistentFlags().StringSliceVar(&flags.excludeSources, "exclude-sources", nil, "skip the documents whose source matches one of these glob patterns")
+}
+
+// getFilter returns the filter given by the flags
+func getFilter() (filter.Filter, error) {
+	f := filter.Filter{
+		IncludeSources: flags.includeSources,
+		ExcludeSources: flags.excludeSources,
+	}
+	for _, t := range flags.includeTypes {
+		f.IncludeTypes = append(f.IncludeTypes, processor.DocumentType(t))
+	}
+	for _, t := range flags.excludeTypes {
+		f.ExcludeTypes = append(f.ExcludeTypes, processor.DocumentType(t))
+	}
+	for _, format := range flags.includeFormats {
+		f.IncludeFormats = append(f.IncludeFormats, processor.FormatType(format))
+	}
+	for _, format := range flags.excludeFormats {
+		f.ExcludeFormats = append(f.ExcludeFormats, processor.FormatType(format))
+	}
+	return f, f.Validate()
+}
+
+// filterEmitter wraps emit to skip the documents that do not pass the
+// filter, before they are processed. Skipped documents are counted in
+// filtered and acknowledged with filter.ErrFiltered, so that the files they
+// come from are not checkpointed and can be ingested by runs with other
+// filters.
+func filterEmitter(ctx context.Context, f filter.Filter, filtered *int64, emit func(*processor.Document) error) func(*processor.Document) error {
+	logger := logging.FromContext(ctx)
+	if f.Empty() {
+		return emit
+	}
+	return func(d *processor.Document) error {
+		if f.Apply(ctx, d) {
+			return emit(d)
+		}
+		atomic.AddInt64(filtered, 1)
+		metrics.DocumentsFiltered.WithLabelValues(string(d.Type)).Inc()
+		logger.Debugw("skipping filtered document",
+			"doc_type", d.Type,
+			"format", d.Format,
+			"source", d.SourceInformation.Source)
+		collector.Acknowledge(d, filter.ErrFiltered)
+		return nil
+	}
+}
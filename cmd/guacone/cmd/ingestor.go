@@ -0,0 +1,188 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/ingestor/service"
+	pb "github.com/guacsec/guac/pkg/ingestor/service/proto"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var ingestorFlags = struct {
+	listenAddr string
+}{}
+
+func init() {
+	ingestorCmd.PersistentFlags().StringVar(&flags.backend, "backend", neo4jBackend, "database to store the graph in: neo4j, postgres or memory")
+	addDBFlags(ingestorCmd)
+	addTLSFlags(ingestorCmd)
+	ingestorCmd.PersistentFlags().IntVar(&flags.batchSize, "batch-size", assembler.DefaultBatchSize, "number of nodes or edges written to neo4j in one query")
+	ingestorCmd.PersistentFlags().IntVar(&flags.dbRetries, "db-retries", graphdb.DefaultRetryPolicy.MaxRetries, "number of times a neo4j write failing with a transient error is retried")
+	ingestorCmd.PersistentFlags().DurationVar(&flags.dbRetryDelay, "db-retry-delay", graphdb.DefaultRetryPolicy.BaseDelay, "base delay of the exponential backoff between neo4j write retries")
+	addEdgeCacheFlags(ingestorCmd)
+	addPurlCacheFlag(ingestorCmd)
+	ingestorCmd.PersistentFlags().StringVar(&flags.metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on at /metrics (e.g. :9090), with the /healthz and /readyz probes; empty disables them")
+	addTimeoutFlags(ingestorCmd)
+	addGraphCheckFlags(ingestorCmd)
+	addFilterFlags(ingestorCmd)
+	addDedupFlags(ingestorCmd)
+	addTracingFlags(ingestorCmd)
+	ingestorCmd.PersistentFlags().StringVar(&ingestorFlags.listenAddr, "listen-addr", ":2782", "address to serve the gRPC ingestion service on")
+}
+
+var ingestorCmd = &cobra.Command{
+	Use:   "ingestor",
+	Short: "serve a gRPC service that ingests the documents sent by remote collectors into the GUAC graph",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateIngestorFlags()
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		shutdownTracing, err := setupTracing(ctx, opts.otlpEndpoint)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer shutdownTracing()
+		common.SetPurlCacheSize(opts.purlCacheSize)
+
+		// Get pipeline of components
+		backend, err := getBackend(opts)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer backend.Close()
+		if opts.metricsAddr != "" {
+			serveMetrics(ctx, opts.metricsAddr, backend.Ping)
+		}
+		pipe, err := newPipeline(ctx, backend, opts)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+
+		// Set emit function to go through the entire pipeline
+		emit := func(d *processor.Document) error {
+			_, _, err := pipe.run(ctx, d)
+			return err
+		}
+		cache, err := getDedupCache(opts)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		if cache != nil {
+			defer cache.Close()
+		}
+
+		// filtered and duplicate documents are only counted in the metrics
+		var filteredNum, dedupedNum int64
+		emit = dedupEmitter(ctx, cache, &dedupedNum, emit)
+		emit = filterEmitter(ctx, opts.filter, &filteredNum, emit)
+
+		listener, err := net.Listen("tcp", opts.listenAddr)
+		if err != nil {
+			logger.Errorf("unable to listen on %s: %v", opts.listenAddr, err)
+			os.Exit(1)
+		}
+		server := grpc.NewServer(service.ServerOptions()...)
+		pb.RegisterIngestorServiceServer(server, service.NewIngestorServer(ctx, emit))
+		logger.Infof("serving ingestion service on %s", listener.Addr())
+		if err := server.Serve(listener); err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func validateIngestorFlags() (options, error) {
+	var opts options
+	if err := validateBackendFlags(&opts); err != nil {
+		return opts, err
+	}
+	// the service runs until killed, the file would never be written
+	if opts.backend == fileBackend {
+		return opts, fmt.Errorf("the %s backend is not supported by the ingestor service", fileBackend)
+	}
+
+	if needsCredentials(opts.backend) {
+		user, pass, err := getCredentials()
+		if err != nil {
+			return opts, err
+		}
+		opts.user = user
+		opts.pass = pass
+	}
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+	if flags.batchSize <= 0 {
+		return opts, fmt.Errorf("batch-size must be positive")
+	}
+	opts.batchSize = flags.batchSize
+	retry, err := getRetryPolicy()
+	if err != nil {
+		return opts, err
+	}
+	opts.retry = retry
+	if err := validateEdgeCacheFlags(&opts); err != nil {
+		return opts, err
+	}
+	if err := validatePurlCacheFlag(&opts); err != nil {
+		return opts, err
+	}
+	timeouts, err := getStageTimeouts()
+	if err != nil {
+		return opts, err
+	}
+	opts.timeouts = timeouts
+	if err := validateGraphCheckFlags(&opts); err != nil {
+		return opts, err
+	}
+	opts.filter, err = getFilter()
+	if err != nil {
+		return opts, err
+	}
+	opts.dedup = flags.dedup
+	opts.dedupFile = flags.dedupFile
+	opts.listenAddr = ingestorFlags.listenAddr
+	opts.metricsAddr = flags.metricsAddr
+	opts.otlpEndpoint = flags.otlpEndpoint
+
+	return opts, nil
+}
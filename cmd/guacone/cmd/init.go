@@ -0,0 +1,111 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var initFlags = struct {
+	constraints bool
+}{}
+
+type initOptions struct {
+	options
+	// constrain the ids to be unique, instead of only indexing them
+	constraints bool
+}
+
+func init() {
+	addDBFlags(initCmd)
+	addTLSFlags(initCmd)
+	initCmd.PersistentFlags().BoolVar(&initFlags.constraints, "constraints", true, "constrain the id of each node type to be unique; false only indexes the ids, as ingestion does")
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init [flags]",
+	Short: "create the constraints and indexes of the GUAC graph in neo4j; safe to run again",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateInitFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		created, existing, err := assembler.CreateSchema(client, opts.constraints)
+		writeSchemaReport(os.Stdout, created, existing)
+		if err != nil {
+			logger.Errorf("unable to create the schema: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateInitFlags(args []string) (initOptions, error) {
+	var opts initOptions
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+	opts.constraints = initFlags.constraints
+
+	if len(args) != 0 {
+		return opts, fmt.Errorf("unexpected positional arguments")
+	}
+	return opts, nil
+}
+
+// writeSchemaReport lists the constraints and indexes that were created and
+// those that already existed, so that partial runs can be told apart
+func writeSchemaReport(w io.Writer, created, existing []assembler.SchemaObject) {
+	for _, o := range created {
+		fmt.Fprintf(w, "created %s\n", o)
+	}
+	for _, o := range existing {
+		fmt.Fprintf(w, "exists  %s\n", o)
+	}
+	fmt.Fprintf(w, "%d created, %d already existed\n", len(created), len(existing))
+}
@@ -0,0 +1,123 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/deadletter"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/guacsec/guac/pkg/tracing"
+	"github.com/spf13/cobra"
+)
+
+// pipeline processes, ingests and assembles documents. Every command
+// ingesting documents runs them through it, so that the graphs are checked
+// the same way and the same metrics and traces are reported whatever the
+// documents come from.
+type pipeline struct {
+	process  func(context.Context, *processor.Document) (processor.DocumentTree, error)
+	ingest   func(context.Context, processor.DocumentTree) ([]assembler.Graph, error)
+	assemble func(context.Context, []assembler.Graph) error
+}
+
+// addGraphCheckFlags adds the flags controlling the checks of the graphs
+// before they are stored to the command
+func addGraphCheckFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&flags.cycleMode, "dependency-cycles", string(assembler.CycleWarn), "how cycles among the dependencies of the ingested documents are handled: warn, reject (the documents) or break (drop the edge closing each cycle)")
+	cmd.PersistentFlags().StringVar(&flags.digestMode, "digest-mismatch", string(assembler.DigestMismatchMark), "how artifacts whose digest contradicts the expected digests of the package they are claimed to be are handled: warn, mark (with a DigestMismatch edge) or reject (the documents)")
+}
+
+// validateGraphCheckFlags checks the flags added by addGraphCheckFlags and
+// sets them in opts
+func validateGraphCheckFlags(opts *options) error {
+	var err error
+	if opts.cycleMode, err = getCycleMode(); err != nil {
+		return err
+	}
+	if opts.digestMode, err = getDigestMismatchMode(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newPipeline returns the pipeline storing the graphs of the documents in
+// store, once their digests and dependency cycles have been checked
+// following opts. With a nil store, the graphs are only validated and
+// logged, for dry runs.
+func newPipeline(ctx context.Context, store assembler.Storer, opts options) (*pipeline, error) {
+	processorFunc, err := getProcessor(opts.timeouts.process)
+	if err != nil {
+		return nil, err
+	}
+	ingestorFunc, err := getIngestor(opts.timeouts.ingest)
+	if err != nil {
+		return nil, err
+	}
+	var assemblerFunc func(context.Context, []assembler.Graph) error
+	if store == nil {
+		assemblerFunc = getDryRunAssembler(ctx)
+	} else {
+		assemblerFunc, err = getAssembler(cycleChecking(digestChecking(store, opts.digestMode), opts.cycleMode), opts.timeouts.assemble)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pipeline{process: processorFunc, ingest: ingestorFunc, assemble: assemblerFunc}, nil
+}
+
+// run runs the document through the pipeline and returns the graphs it was
+// ingested as. When the document fails, the stage it failed in is returned
+// together with the error.
+func (p *pipeline) run(ctx context.Context, d *processor.Document) (graphs []assembler.Graph, stage deadletter.Stage, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "document", documentAttributes(d)...)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	docTree, err := p.process(ctx, d)
+	if err != nil {
+		metrics.ParseFailures.WithLabelValues(string(d.Format)).Inc()
+		return nil, deadletter.StageProcess, fmt.Errorf("unable to process doc: %w, fomat: %v, document: %v", err, d.Format, d.Type)
+	}
+
+	graphs, err = p.ingest(ctx, docTree)
+	err = skipPartialFailure(ctx, d, err)
+	if err != nil {
+		metrics.ParseFailures.WithLabelValues(string(d.Format)).Inc()
+		return nil, deadletter.StageIngest, fmt.Errorf("unable to ingest doc tree: %w", err)
+	}
+
+	err = p.assemble(ctx, graphs)
+	if err != nil {
+		return nil, deadletter.StageAssemble, fmt.Errorf("unable to assemble graphs: %w", err)
+	}
+	metrics.DocumentsProcessed.WithLabelValues(string(d.Format)).Inc()
+	logging.FromContext(ctx).Debugw("completed doc",
+		"doc_type", d.Type,
+		"format", d.Format,
+		"source", d.SourceInformation.Source,
+		"collector", d.SourceInformation.Collector,
+		"elapsed", time.Since(start))
+	return graphs, "", nil
+}
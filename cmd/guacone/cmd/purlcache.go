@@ -0,0 +1,38 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/spf13/cobra"
+)
+
+// addPurlCacheFlag adds the flag of the cache of the normalized purls to the
+// command
+func addPurlCacheFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&flags.purlCacheSize, "purl-cache-size", common.DefaultPurlCacheSize, "number of purls whose normalized form is remembered, so that the packages found in many documents are only normalized once; 0 disables the cache")
+}
+
+// validatePurlCacheFlag checks the purl cache flag and sets it in opts
+func validatePurlCacheFlag(opts *options) error {
+	if flags.purlCacheSize < 0 {
+		return fmt.Errorf("purl-cache-size must not be negative")
+	}
+	opts.purlCacheSize = flags.purlCacheSize
+	return nil
+}
@@ -0,0 +1,527 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	jsonOutput   = "json"
+	tableOutput  = "table"
+	ndjsonOutput = "ndjson"
+)
+
+// queryOutputs are the output formats of the query and validate commands;
+// diff supports all but ndjson
+var queryOutputs = []string{tableOutput, jsonOutput, ndjsonOutput}
+
+var queryFlags = struct {
+	depth      int
+	output     string
+	dependents bool
+	stream     bool
+	fetchSize  int
+}{}
+
+type queryOptions struct {
+	options
+	purl string
+	// maximum number of DependsOn edges to follow from the package
+	depth int
+	// output format: table, json or ndjson
+	output string
+	// list the packages depending on the package instead of its dependencies
+	dependents bool
+	// write the packages, then the edges between them, as they are read
+	// from the database instead of buffering the whole subgraph
+	stream bool
+	// number of records pulled from the database at a time when streaming
+	fetchSize int
+}
+
+// queriedPackage is a package returned by the query command, together with
+// its distance to the queried package and its known vulnerabilities. The
+// JSON field names are stable, for scripts to depend on them.
+type queriedPackage struct {
+	// ID is the deterministic id of the node, see `assembler.NodeID`
+	ID    string `json:"id"`
+	Purl  string `json:"purl"`
+	Depth int64  `json:"depth"`
+	// EdgeType and Direction give the edges followed from the queried
+	// package to reach this one; they are empty for the queried package
+	EdgeType        string   `json:"edge_type,omitempty"`
+	Direction       string   `json:"direction,omitempty"`
+	Vulnerabilities []string `json:"vulnerabilities"`
+}
+
+// streamedNode is a line of the streamed output for a package
+type streamedNode struct {
+	Type string `json:"type"`
+	queriedPackage
+}
+
+// streamedEdge is a line of the streamed output for a DependsOn edge between
+// two of the returned packages
+type streamedEdge struct {
+	Type     string `json:"type"`
+	EdgeType string `json:"edge_type"`
+	FromID   string `json:"from_id"`
+	From     string `json:"from"`
+	ToID     string `json:"to_id"`
+	To       string `json:"to"`
+}
+
+type queryResult struct {
+	Package      queriedPackage   `json:"package"`
+	Dependencies []queriedPackage `json:"dependencies,omitempty"`
+	Dependents   []queriedPackage `json:"dependents,omitempty"`
+}
+
+func init() {
+	addDBFlags(queryCmd)
+	addTLSFlags(queryCmd)
+	queryCmd.PersistentFlags().IntVar(&queryFlags.depth, "depth", 1, "number of levels of transitive dependencies to return")
+	queryCmd.PersistentFlags().StringVar(&queryFlags.output, "output-format", "", "output format: table, json or ndjson (default table when writing to a terminal, json otherwise)")
+	queryCmd.PersistentFlags().StringVar(&queryFlags.output, "output", "", "output format")
+	_ = queryCmd.PersistentFlags().MarkDeprecated("output", "use --output-format instead")
+	queryCmd.PersistentFlags().BoolVar(&queryFlags.dependents, "dependents", false, "return the packages that depend on the package instead of its dependencies")
+	queryCmd.PersistentFlags().BoolVar(&queryFlags.stream, "stream", false, "stream the packages then the edges between them as ndjson, without holding the subgraph in memory")
+	queryCmd.PersistentFlags().IntVar(&queryFlags.fetchSize, "fetch-size", graphdb.DefaultFetchSize, "number of records to read from the database at a time with --stream")
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query [flags] purl",
+	Short: "query the dependencies and vulnerabilities of a package in the GUAC graph",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateQueryFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		if opts.stream {
+			if err := streamQueryPackage(client, opts, os.Stdout); err != nil {
+				logger.Errorf("unable to stream query result: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		result, err := queryPackage(client, opts)
+		if err != nil {
+			logger.Errorf("unable to query package: %v", err)
+			os.Exit(1)
+		}
+
+		switch opts.output {
+		case jsonOutput:
+			err = writeQueryJSON(os.Stdout, result)
+		case ndjsonOutput:
+			err = writeQueryNDJSON(os.Stdout, result)
+		default:
+			err = writeQueryTable(os.Stdout, result)
+		}
+		if err != nil {
+			logger.Errorf("unable to write query result: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateQueryFlags(args []string) (queryOptions, error) {
+	var opts queryOptions
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+
+	if queryFlags.depth <= 0 {
+		return opts, fmt.Errorf("depth must be positive")
+	}
+	opts.depth = queryFlags.depth
+
+	opts.output, err = getOutputFormat(queryFlags.output)
+	if err != nil {
+		return opts, err
+	}
+	opts.dependents = queryFlags.dependents
+
+	opts.stream = queryFlags.stream
+	if opts.stream {
+		if queryFlags.output != "" && queryFlags.output != ndjsonOutput {
+			return opts, fmt.Errorf("--stream only supports the ndjson output format")
+		}
+		opts.output = ndjsonOutput
+	}
+	if queryFlags.fetchSize <= 0 {
+		return opts, fmt.Errorf("fetch-size must be positive")
+	}
+	opts.fetchSize = queryFlags.fetchSize
+
+	if len(args) != 1 {
+		return opts, fmt.Errorf("expected positional argument for purl")
+	}
+	// packages are stored with normalized purls
+	opts.purl = common.NormalizePurl(args[0])
+
+	return opts, nil
+}
+
+// getOutputFormat validates the output format of the query, validate and diff
+// commands. It defaults to a table when writing to a terminal, and to JSON
+// otherwise, e.g. when piped to another tool.
+func getOutputFormat(output string) (string, error) {
+	if output == "" {
+		output = jsonOutput
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			output = tableOutput
+		}
+	}
+	for _, o := range queryOutputs {
+		if output == o {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of: %s", output, strings.Join(queryOutputs, ", "))
+}
+
+// queryPackage returns the package with the given purl, together with its
+// dependencies (or dependents) up to `opts.depth` levels away, and the
+// vulnerabilities of all of them
+func queryPackage(client graphdb.Client, opts queryOptions) (*queryResult, error) {
+	found, err := readRecords(client, "MATCH (p:Package) WHERE p.purl = $purl RETURN p."+assembler.IDProperty, map[string]interface{}{"purl": opts.purl})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("package %s not found", opts.purl)
+	}
+
+	// variable length bounds cannot be query parameters, depth is validated
+	// to be a positive integer
+	pattern := "(p:Package)-[:DependsOn*1..%d]->(d:Package)"
+	direction := "outgoing"
+	if opts.dependents {
+		pattern = "(d:Package)-[:DependsOn*1..%d]->(p:Package)"
+		direction = "incoming"
+	}
+	query := fmt.Sprintf("MATCH path = "+pattern+" WHERE p.purl = $purl AND d.purl <> $purl "+
+		"RETURN d.purl, min(length(path)) AS depth, d."+assembler.IDProperty+" ORDER BY depth, d.purl", opts.depth)
+	records, err := readRecords(client, query, map[string]interface{}{"purl": opts.purl})
+	if err != nil {
+		return nil, err
+	}
+
+	packages := []queriedPackage{}
+	purls := []string{opts.purl}
+	for _, record := range records {
+		purl, ok := record[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast purl property to string type")
+		}
+		depth, ok := record[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast depth to integer type")
+		}
+		// nodes ingested before ids were introduced have none
+		id, _ := record[2].(string)
+		packages = append(packages, queriedPackage{
+			ID:        id,
+			Purl:      purl,
+			Depth:     depth,
+			EdgeType:  assembler.EdgeTypeDependsOn,
+			Direction: direction,
+		})
+		purls = append(purls, purl)
+	}
+
+	vulns, err := queryVulnerabilities(client, purls)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := found[0][0].(string)
+	result := &queryResult{
+		Package: queriedPackage{ID: id, Purl: opts.purl, Vulnerabilities: vulnerabilitiesOf(vulns, opts.purl)},
+	}
+	for i := range packages {
+		packages[i].Vulnerabilities = vulnerabilitiesOf(vulns, packages[i].Purl)
+	}
+	if opts.dependents {
+		result.Dependents = packages
+	} else {
+		result.Dependencies = packages
+	}
+	return result, nil
+}
+
+// vulnerabilitiesProjection returns the IDs of the vulnerabilities of the
+// package bound to `node`, in the same record, for queries streaming packages
+func vulnerabilitiesProjection(node string) string {
+	return fmt.Sprintf("[(%[1]s)-[:VulnerableTo]->(v:Vulnerability) | v.id] + "+
+		"[(%[1]s)<-[:Attestation]-(:Attestation)-[:Vulnerable]->(v:Vulnerability) | v.id]", node)
+}
+
+// streamQueryPackage writes the same packages as queryPackage, one per line
+// as they are read from the database, then the DependsOn edges between them.
+// Neither the packages nor the edges are held in memory; the database sorts
+// and deduplicates them.
+func streamQueryPackage(client graphdb.Client, opts queryOptions, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	args := map[string]interface{}{"purl": opts.purl}
+
+	found := false
+	err := graphdb.StreamQuery(client, "MATCH (p:Package) WHERE p.purl = $purl "+
+		"RETURN p."+assembler.IDProperty+", "+vulnerabilitiesProjection("p")+" LIMIT 1", args, opts.fetchSize,
+		func(values []interface{}) error {
+			found = true
+			id, _ := values[0].(string)
+			vulns, err := vulnerabilityIDs(values[1])
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(streamedNode{Type: "node", queriedPackage: queriedPackage{ID: id, Purl: opts.purl, Vulnerabilities: vulns}})
+		})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("package %s not found", opts.purl)
+	}
+
+	// variable length bounds cannot be query parameters, depth is validated
+	// to be a positive integer
+	pattern := "(p:Package)-[:DependsOn*1..%d]->(d:Package)"
+	edgePattern := "(p:Package)-[:DependsOn*0..%d]->(a:Package)-[:DependsOn]->(b:Package)"
+	direction := "outgoing"
+	if opts.dependents {
+		pattern = "(d:Package)-[:DependsOn*1..%d]->(p:Package)"
+		edgePattern = "(a:Package)-[:DependsOn]->(b:Package)-[:DependsOn*0..%d]->(p:Package)"
+		direction = "incoming"
+	}
+
+	query := fmt.Sprintf("MATCH path = "+pattern+" WHERE p.purl = $purl AND d.purl <> $purl "+
+		"WITH d, min(length(path)) AS depth "+
+		"RETURN d.purl, depth, d."+assembler.IDProperty+", "+vulnerabilitiesProjection("d")+" ORDER BY depth, d.purl", opts.depth)
+	err = graphdb.StreamQuery(client, query, args, opts.fetchSize, func(values []interface{}) error {
+		purl, ok := values[0].(string)
+		if !ok {
+			return fmt.Errorf("failed to cast purl property to string type")
+		}
+		depth, ok := values[1].(int64)
+		if !ok {
+			return fmt.Errorf("failed to cast depth to integer type")
+		}
+		// nodes ingested before ids were introduced have none
+		id, _ := values[2].(string)
+		vulns, err := vulnerabilityIDs(values[3])
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(streamedNode{Type: "node", queriedPackage: queriedPackage{
+			ID:              id,
+			Purl:            purl,
+			Depth:           depth,
+			EdgeType:        assembler.EdgeTypeDependsOn,
+			Direction:       direction,
+			Vulnerabilities: vulns,
+		}})
+	})
+	if err != nil {
+		return err
+	}
+
+	// the edges between the returned packages are those leaving a package
+	// less than depth edges away from the queried one (or, for dependents,
+	// entering it)
+	query = fmt.Sprintf("MATCH "+edgePattern+" WHERE p.purl = $purl "+
+		"RETURN DISTINCT a.purl, a."+assembler.IDProperty+", b.purl, b."+assembler.IDProperty+" ORDER BY a.purl, b.purl", opts.depth-1)
+	return graphdb.StreamQuery(client, query, args, opts.fetchSize, func(values []interface{}) error {
+		from, ok := values[0].(string)
+		if !ok {
+			return fmt.Errorf("failed to cast purl property to string type")
+		}
+		to, ok := values[2].(string)
+		if !ok {
+			return fmt.Errorf("failed to cast purl property to string type")
+		}
+		fromID, _ := values[1].(string)
+		toID, _ := values[3].(string)
+		return encoder.Encode(streamedEdge{
+			Type:     "edge",
+			EdgeType: assembler.EdgeTypeDependsOn,
+			FromID:   fromID,
+			From:     from,
+			ToID:     toID,
+			To:       to,
+		})
+	})
+}
+
+// vulnerabilityIDs returns the sorted, deduplicated IDs of a list of
+// vulnerabilities returned by the database, never nil so that the JSON output
+// always has a list
+func vulnerabilityIDs(value interface{}) ([]string, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to cast vulnerabilities to list type")
+	}
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		id, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast id property to string type")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// queryVulnerabilities returns the IDs of the vulnerabilities of each package,
+// either reported by an SBOM or found by a certifier
+func queryVulnerabilities(client graphdb.Client, purls []string) (map[string][]string, error) {
+	query := "MATCH (p:Package)-[:VulnerableTo]->(v:Vulnerability) WHERE p.purl IN $purls RETURN p.purl AS purl, v.id AS id " +
+		"UNION MATCH (p:Package)<-[:Attestation]-(:Attestation)-[:Vulnerable]->(v:Vulnerability) WHERE p.purl IN $purls RETURN p.purl AS purl, v.id AS id"
+	records, err := readRecords(client, query, map[string]interface{}{"purls": purls})
+	if err != nil {
+		return nil, err
+	}
+
+	vulns := map[string][]string{}
+	for _, record := range records {
+		purl, ok := record[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast purl property to string type")
+		}
+		id, ok := record[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast id property to string type")
+		}
+		vulns[purl] = append(vulns[purl], id)
+	}
+	for _, ids := range vulns {
+		sort.Strings(ids)
+	}
+	return vulns, nil
+}
+
+// vulnerabilitiesOf returns the vulnerabilities of the package, never nil so
+// that the JSON output always has a list
+func vulnerabilitiesOf(vulns map[string][]string, purl string) []string {
+	if ids, ok := vulns[purl]; ok {
+		return ids
+	}
+	return []string{}
+}
+
+// readRecords runs a read query and returns the values of all the records
+func readRecords(client graphdb.Client, query string, args map[string]interface{}) ([][]interface{}, error) {
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	result, err := session.ReadTransaction(func(tx graphdb.Transaction) (interface{}, error) {
+		records, err := tx.Run(query, args)
+		if err != nil {
+			return nil, err
+		}
+		values := [][]interface{}{}
+		for records.Next() {
+			values = append(values, records.Record().Values)
+		}
+		return values, records.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([][]interface{}), nil
+}
+
+func writeQueryJSON(w io.Writer, result *queryResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// writeQueryNDJSON writes one JSON object per line, for the queried package
+// then for each of its dependencies (or dependents)
+func writeQueryNDJSON(w io.Writer, result *queryResult) error {
+	encoder := json.NewEncoder(w)
+	for _, p := range queriedPackages(result) {
+		if err := encoder.Encode(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queriedPackages returns the queried package followed by its dependencies
+// (or dependents)
+func queriedPackages(result *queryResult) []queriedPackage {
+	packages := append([]queriedPackage{result.Package}, result.Dependencies...)
+	return append(packages, result.Dependents...)
+}
+
+func writeQueryTable(w io.Writer, result *queryResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PURL\tDEPTH\tVULNERABILITIES")
+	for _, p := range queriedPackages(result) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", p.Purl, p.Depth, strings.Join(p.Vulnerabilities, ","))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,271 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/handler/deadletter"
+	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/ingestor/verifier/rekor_verifier"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var replayFlags = struct {
+	processedDir string
+}{}
+
+type replayOptions struct {
+	options
+	// deadletter directory the documents are replayed from
+	dir string
+	// directory the recovered documents are moved to, empty deletes them
+	processedDir string
+}
+
+func init() {
+	replayCmd.PersistentFlags().StringVar(&flags.backend, "backend", neo4jBackend, "database to store the graph in: neo4j, postgres, memory or file")
+	replayCmd.PersistentFlags().StringVar(&flags.out, "out", "", "path to the JSON file the combined graph is written to by the file backend")
+	addDBFlags(replayCmd)
+	addTLSFlags(replayCmd)
+	replayCmd.PersistentFlags().IntVar(&flags.batchSize, "batch-size", assembler.DefaultBatchSize, "number of nodes or edges written to neo4j in one query")
+	replayCmd.PersistentFlags().IntVar(&flags.dbRetries, "db-retries", graphdb.DefaultRetryPolicy.MaxRetries, "number of times a neo4j write failing with a transient error is retried")
+	replayCmd.PersistentFlags().DurationVar(&flags.dbRetryDelay, "db-retry-delay", graphdb.DefaultRetryPolicy.BaseDelay, "base delay of the exponential backoff between neo4j write retries")
+	addEdgeCacheFlags(replayCmd)
+	addPurlCacheFlag(replayCmd)
+	replayCmd.PersistentFlags().StringSliceVar(&flags.verifyKeys, "verify-keys", nil, "paths to PEM encoded public keys; when set, DSSE envelopes without a signature from one of these keys are rejected")
+	replayCmd.PersistentFlags().StringVar(&flags.rekorURL, "rekor-url", "", "URL of the Rekor instance (e.g. "+rekor_verifier.DefaultRekorURL+"); when set, keyless signed DSSE envelopes without a valid entry in the log are rejected")
+	replayCmd.PersistentFlags().StringVar(&flags.rekorKey, "rekor-key", "", "path to the PEM encoded public key of the Rekor instance")
+	replayCmd.PersistentFlags().StringVar(&flags.fulcioRoots, "fulcio-roots", "", "path to the PEM encoded Fulcio root and intermediate certificates the signing certificates must chain to")
+	addResolveFlags(replayCmd)
+	addTimeoutFlags(replayCmd)
+	addTracingFlags(replayCmd)
+	addGraphCheckFlags(replayCmd)
+	replayCmd.PersistentFlags().StringVar(&flags.schemaMode, "schema-validation", string(process.SchemaValidationWarn), "how CycloneDX and SPDX JSON documents not matching their schema are handled: warn, strict (reject them) or off")
+	replayCmd.PersistentFlags().StringVar(&replayFlags.processedDir, "processed-dir", "", "directory the recovered documents are moved to, with the same layout as the deadletter directory; empty deletes them")
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [flags] deadletter_dir",
+	Short: "ingest the documents of a deadletter directory again, removing those that succeed",
+	Long: `replay runs the documents kept by --deadletter-dir through the pipeline again,
+with their original source information, e.g. once the parser they failed in
+is fixed. The recovered documents are deleted from the directory, or moved to
+--processed-dir. The failure of the others is updated with their new error.
+
+It exits with status 1 if any document is still failing.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+		defer cancel()
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateReplayFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		dir, err := deadletter.OpenDirectory(opts.dir)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		entries, err := dir.Entries()
+		if err != nil {
+			logger.Errorf("unable to list the deadletter documents: %v", err)
+			os.Exit(1)
+		}
+		// failing documents are written back to the directory, replacing
+		// their previous failure
+		sink, err := deadletter.NewDirectorySink(opts.dir)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+
+		if len(opts.verifyKeys) > 0 {
+			if err := registerVerifier(ctx, opts.verifyKeys); err != nil {
+				logger.Errorf("unable to register DSSE verifier: %v", err)
+				os.Exit(1)
+			}
+		}
+		if opts.rekorURL != "" {
+			if err := registerRekorVerifier(opts.rekorURL, opts.rekorKey, opts.fulcioRoots); err != nil {
+				logger.Errorf("unable to register Rekor verifier: %v", err)
+				os.Exit(1)
+			}
+		}
+		shutdownTracing, err := setupTracing(ctx, opts.otlpEndpoint)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		setupDocumentResolver(opts.options)
+		common.SetPurlCacheSize(opts.purlCacheSize)
+		process.SetSchemaValidation(opts.schemaMode)
+
+		// Get pipeline of components
+		backend, err := getBackend(opts.options)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		pipe, err := newPipeline(ctx, backend, opts.options)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+
+		// Stop between documents on SIGINT or SIGTERM, the others stay in
+		// the directory
+		cancelOnSignal(ctx, cancel)
+		var recovered, failing int
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				break
+			}
+			d, err := dir.Document(e)
+			if err != nil {
+				logger.Errorf("%v", err)
+				failing++
+				continue
+			}
+
+			_, stage, err := pipe.run(ctx, d)
+			if err != nil {
+				failing++
+				logger.Errorw("document still failing", "source", e.Source, "stage", stage, "error", err)
+				if sinkErr := sink.Write(d, stage, err); sinkErr != nil {
+					logger.Errorf("unable to write document %s to the deadletter directory: %v", e.Source, sinkErr)
+					continue
+				}
+				// the processor may have decompressed the document, which
+				// is then kept under its new digest
+				if deadletter.Digest(d.Blob) != e.Digest {
+					if err := dir.Remove(e); err != nil {
+						logger.Errorf("unable to remove the previous failure of %s: %v", e.Source, err)
+					}
+				}
+				continue
+			}
+
+			recovered++
+			logger.Debugw("recovered doc", "source", e.Source, "doc_type", d.Type, "format", d.Format)
+			if opts.processedDir != "" {
+				err = dir.Move(e, opts.processedDir)
+			} else {
+				err = dir.Remove(e)
+			}
+			if err != nil {
+				logger.Errorf("unable to remove recovered document %s from the deadletter directory: %v", e.Source, err)
+			}
+		}
+
+		// Close explicitly, the deferred calls are skipped by logger.Fatal
+		if err := backend.Close(); err != nil {
+			logger.Errorf("unable to close the database connection: %v", err)
+		}
+		shutdownTracing()
+		skipped := len(entries) - recovered - failing
+		if skipped > 0 {
+			logger.Infof("replay interrupted, %v documents were not replayed", skipped)
+		}
+		if failing > 0 {
+			logger.Fatalf("recovered %v of %v documents, %v still failing", recovered, len(entries), failing)
+		}
+		logger.Infof("recovered %v of %v documents", recovered, len(entries))
+	},
+}
+
+func validateReplayFlags(args []string) (replayOptions, error) {
+	var opts replayOptions
+	if len(args) != 1 {
+		return opts, fmt.Errorf("expected the deadletter directory as positional argument")
+	}
+	opts.dir = args[0]
+	opts.processedDir = replayFlags.processedDir
+
+	if err := validateBackendFlags(&opts.options); err != nil {
+		return opts, err
+	}
+	if needsCredentials(opts.backend) {
+		user, pass, err := getCredentials()
+		if err != nil {
+			return opts, err
+		}
+		opts.user = user
+		opts.pass = pass
+	}
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+	if flags.batchSize <= 0 {
+		return opts, fmt.Errorf("batch-size must be positive")
+	}
+	opts.batchSize = flags.batchSize
+	opts.retry, err = getRetryPolicy()
+	if err != nil {
+		return opts, err
+	}
+	if err := validateEdgeCacheFlags(&opts.options); err != nil {
+		return opts, err
+	}
+	if err := validatePurlCacheFlag(&opts.options); err != nil {
+		return opts, err
+	}
+	opts.timeouts, err = getStageTimeouts()
+	if err != nil {
+		return opts, err
+	}
+
+	opts.verifyKeys = flags.verifyKeys
+	if flags.rekorURL != "" {
+		if len(flags.verifyKeys) > 0 {
+			return opts, fmt.Errorf("verify-keys and rekor-url cannot be used together")
+		}
+		if flags.rekorKey == "" || flags.fulcioRoots == "" {
+			return opts, fmt.Errorf("rekor-key and fulcio-roots are required with rekor-url")
+		}
+	}
+	opts.rekorURL = flags.rekorURL
+	opts.rekorKey = flags.rekorKey
+	opts.fulcioRoots = flags.fulcioRoots
+	if err := validateResolveFlags(&opts.options); err != nil {
+		return opts, err
+	}
+	opts.otlpEndpoint = flags.otlpEndpoint
+	opts.schemaMode, err = getSchemaValidationMode()
+	if err != nil {
+		return opts, err
+	}
+	if err := validateGraphCheckFlags(&opts.options); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,60 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/spf13/cobra"
+)
+
+// addResolveFlags adds the flags limiting the fetching of the documents
+// referenced by SBOMs to the command
+func addResolveFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVar(&flags.resolveTimeout, "resolve-timeout", process.DefaultResolveTimeout, "timeout of each request fetching a document referenced by SBOMs; 0 disables fetching them")
+	cmd.PersistentFlags().Int64Var(&flags.resolveMaxSize, "resolve-max-size", process.DefaultResolveMaxSize, "maximum size in bytes of a document referenced by SBOMs; 0 only applies --max-document-size")
+	cmd.PersistentFlags().DurationVar(&flags.resolveBudget, "resolve-budget", process.DefaultResolveBudget, "total time spent fetching the documents referenced by a document and its sub-documents, after which the remaining references are skipped; 0 disables the limit")
+}
+
+// validateResolveFlags checks the flags limiting the fetching of referenced
+// documents and sets them in opts
+func validateResolveFlags(opts *options) error {
+	if flags.resolveTimeout < 0 {
+		return fmt.Errorf("resolve-timeout must not be negative")
+	}
+	if flags.resolveMaxSize < 0 {
+		return fmt.Errorf("resolve-max-size must not be negative")
+	}
+	if flags.resolveBudget < 0 {
+		return fmt.Errorf("resolve-budget must not be negative")
+	}
+	opts.resolveTimeout = flags.resolveTimeout
+	opts.resolveMaxSize = flags.resolveMaxSize
+	opts.resolveBudget = flags.resolveBudget
+	return nil
+}
+
+// setupDocumentResolver sets the resolver fetching the documents referenced
+// by SBOMs, and its limits, given by the options
+func setupDocumentResolver(opts options) {
+	if opts.resolveTimeout == 0 {
+		process.SetDocumentResolver(nil)
+		return
+	}
+	process.SetDocumentResolver(process.NewHTTPResolver(opts.resolveTimeout, opts.resolveMaxSize))
+	process.SetResolveBudget(opts.resolveBudget)
+}
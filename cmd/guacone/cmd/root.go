@@ -19,17 +19,41 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+var (
+	maxDocumentSize int64
+	logLevel        string
+)
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", string(logging.DefaultLevel), "minimum level of the log lines: debug, info, warn or error")
+	rootCmd.PersistentFlags().Int64Var(&maxDocumentSize, "max-document-size", processor.DefaultMaxDocumentSize, "maximum size in bytes of a document; larger documents are not read by the collectors and are rejected by the processor. 0 disables the limit")
 	rootCmd.AddCommand(exampleCmd)
 	rootCmd.AddCommand(certifierCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(ingestorCmd)
+	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(replayCmd)
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "guacone",
 	Short: "guacone is an all in one flow cmdline for GUAC",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := logging.SetLevel(logging.Level(logLevel)); err != nil {
+			return err
+		}
+		return processor.SetMaxDocumentSize(maxDocumentSize)
+	},
 }
 
 func Execute() {
@@ -0,0 +1,155 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/graphql"
+	"github.com/guacsec/guac/pkg/handler/deadletter"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/guacsec/guac/pkg/health"
+	"github.com/guacsec/guac/pkg/ingestor/service"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var serverFlags = struct {
+	listenAddr string
+	ingest     bool
+}{}
+
+func init() {
+	addDBFlags(serverCmd)
+	addTLSFlags(serverCmd)
+	addGraphCheckFlags(serverCmd)
+	serverCmd.PersistentFlags().StringVar(&serverFlags.listenAddr, "listen-addr", ":8080", "address to serve the GraphQL API on")
+	serverCmd.PersistentFlags().BoolVar(&serverFlags.ingest, "ingest", false, "also serve POST /ingest, which ingests the uploaded documents into the graph")
+}
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "serve a GraphQL API to read the GUAC graph, and optionally an HTTP endpoint to ingest documents",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateServerFlags()
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		backend := assembler.NewNeo4jBackend(client, assembler.DefaultBatchSize, graphdb.DefaultRetryPolicy, nil)
+		schema, err := graphql.NewSchema(graphql.NewNeo4jStore(client))
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/query", graphql.NewHandler(schema))
+		logger.Infof("serving GraphQL API on %s/query", opts.listenAddr)
+		health.Register(mux, backend.Ping)
+		if serverFlags.ingest {
+			if err := createIndices(client); err != nil {
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
+			}
+			ingest, err := getHTTPIngestFunc(ctx, backend, opts)
+			if err != nil {
+				logger.Errorf("error: %v", err)
+				os.Exit(1)
+			}
+			mux.Handle("/ingest", service.NewHTTPHandler(ctx, ingest))
+			logger.Infof("serving ingestion endpoint on %s/ingest", opts.listenAddr)
+		}
+		if err := http.ListenAndServe(opts.listenAddr, mux); err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+// getHTTPIngestFunc returns the function running the documents uploaded to the
+// ingestion endpoint through the same pipeline as `guacone files`
+func getHTTPIngestFunc(ctx context.Context, backend assembler.Backend, opts options) (service.IngestFunc, error) {
+	pipe, err := newPipeline(ctx, backend, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d *processor.Document) (nodes int, edges int, err error) {
+		graphs, stage, err := pipe.run(ctx, d)
+		if err != nil {
+			if stage == deadletter.StageAssemble {
+				return 0, 0, err
+			}
+			return 0, 0, invalidDocument(err)
+		}
+		combined := assembler.Graph{}
+		combined.Merge(graphs...)
+		return len(combined.Nodes), len(combined.Edges), nil
+	}, nil
+}
+
+// invalidDocument wraps service.ErrInvalidDocument around the errors of the
+// documents that would fail again if submitted again: unsupported,
+// malformed or unverified documents. Other errors, e.g. timeouts, are
+// returned as is.
+func invalidDocument(err error) error {
+	if processor.ErrorKind(err) != nil || errors.Is(err, process.ErrUnverifiedEnvelope) {
+		return fmt.Errorf("%w: %v", service.ErrInvalidDocument, err)
+	}
+	return err
+}
+
+func validateServerFlags() (options, error) {
+	var opts options
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+	if err := validateGraphCheckFlags(&opts); err != nil {
+		return opts, err
+	}
+	opts.listenAddr = serverFlags.listenAddr
+	return opts, nil
+}
@@ -0,0 +1,263 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var statsFlags = struct {
+	top    int
+	output string
+}{}
+
+type statsOptions struct {
+	options
+	// number of most depended upon packages to list
+	top int
+	// output format: table or json
+	output string
+}
+
+// dependedOnPackage is a package with the number of packages and artifacts
+// depending on it directly
+type dependedOnPackage struct {
+	Purl       string `json:"purl"`
+	Dependents int64  `json:"dependents"`
+}
+
+// graphStats gives an overview of what the graph holds. The JSON field names
+// are stable, for scripts to depend on them.
+type graphStats struct {
+	// Nodes and Edges are the number of nodes by label and of edges by type
+	Nodes map[string]int64 `json:"nodes"`
+	Edges map[string]int64 `json:"edges"`
+	// Packages, Artifacts and Vulnerabilities are the number of distinct
+	// purls, digests and vulnerability ids
+	Packages        int64               `json:"packages"`
+	Artifacts       int64               `json:"artifacts"`
+	Vulnerabilities int64               `json:"vulnerabilities"`
+	MostDependedOn  []dependedOnPackage `json:"most_depended_on"`
+}
+
+func init() {
+	addDBFlags(statsCmd)
+	addTLSFlags(statsCmd)
+	statsCmd.PersistentFlags().IntVar(&statsFlags.top, "top", 10, "number of most depended upon packages to list")
+	statsCmd.PersistentFlags().StringVar(&statsFlags.output, "output-format", "", "output format: table or json (default table when writing to a terminal, json otherwise)")
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [flags]",
+	Short: "count the nodes and edges of the GUAC graph, e.g. to check that an ingestion landed",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := logging.WithLogger(context.Background())
+		logger := logging.FromContext(ctx)
+
+		opts, err := validateStatsFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		authToken := graphdb.CreateAuthTokenWithUsernameAndPassword(opts.user, opts.pass, opts.realm)
+		client, err := graphdb.NewGraphClientForDatabase(opts.dbAddr, authToken, opts.tls, opts.dbName)
+		if err != nil {
+			logger.Errorf("error: %v", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		stats, err := queryStats(client, opts.top)
+		if err != nil {
+			logger.Errorf("unable to query graph stats: %v", err)
+			os.Exit(1)
+		}
+
+		if opts.output == jsonOutput {
+			err = writeStatsJSON(os.Stdout, stats)
+		} else {
+			err = writeStatsTable(os.Stdout, stats)
+		}
+		if err != nil {
+			logger.Errorf("unable to write graph stats: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateStatsFlags(args []string) (statsOptions, error) {
+	var opts statsOptions
+	user, pass, err := getCredentials()
+	if err != nil {
+		return opts, err
+	}
+	opts.user = user
+	opts.pass = pass
+	opts.dbAddr = flags.dbAddr
+	opts.dbName = flags.dbName
+	tlsOptions, err := getTLSOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.tls = tlsOptions
+	opts.realm = flags.realm
+
+	if statsFlags.top < 0 {
+		return opts, fmt.Errorf("top must not be negative")
+	}
+	opts.top = statsFlags.top
+
+	opts.output, err = getOutputFormat(statsFlags.output)
+	if err != nil {
+		return opts, err
+	}
+	if opts.output == ndjsonOutput {
+		return opts, fmt.Errorf("output format %s is not supported by stats, expected %s or %s", ndjsonOutput, tableOutput, jsonOutput)
+	}
+
+	if len(args) != 0 {
+		return opts, fmt.Errorf("unexpected positional arguments")
+	}
+	return opts, nil
+}
+
+// queryStats counts the nodes and edges of the graph, and returns the top
+// packages with the most dependents
+func queryStats(client graphdb.Client, top int) (*graphStats, error) {
+	stats := &graphStats{MostDependedOn: []dependedOnPackage{}}
+	var err error
+
+	stats.Nodes, err = countsBy(client, "MATCH (n) UNWIND labels(n) AS label RETURN label, count(*)")
+	if err != nil {
+		return nil, err
+	}
+	stats.Edges, err = countsBy(client, "MATCH ()-[e]->() RETURN type(e), count(*)")
+	if err != nil {
+		return nil, err
+	}
+
+	distinct := []struct {
+		count *int64
+		query string
+	}{
+		{&stats.Packages, "MATCH (p:" + assembler.NodeTypePackage + ") RETURN count(DISTINCT p.purl)"},
+		{&stats.Artifacts, "MATCH (a:" + assembler.NodeTypeArtifact + ") RETURN count(DISTINCT a.digest)"},
+		{&stats.Vulnerabilities, "MATCH (v:" + assembler.NodeTypeVulnerability + ") RETURN count(DISTINCT v.id)"},
+	}
+	for _, d := range distinct {
+		records, err := readRecords(client, d.query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 1 {
+			*d.count, _ = records[0][0].(int64)
+		}
+	}
+
+	if top == 0 {
+		return stats, nil
+	}
+	records, err := readRecords(client, "MATCH (d)-[:"+assembler.EdgeTypeDependsOn+"]->(p:"+assembler.NodeTypePackage+") "+
+		"WITH p.purl AS purl, count(DISTINCT d) AS dependents RETURN purl, dependents ORDER BY dependents DESC, purl LIMIT $top",
+		map[string]interface{}{"top": top})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		purl, ok := record[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast purl property to string type")
+		}
+		dependents, ok := record[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast dependents to integer type")
+		}
+		stats.MostDependedOn = append(stats.MostDependedOn, dependedOnPackage{Purl: purl, Dependents: dependents})
+	}
+	return stats, nil
+}
+
+// countsBy runs a query returning a name and a count per record, and returns
+// the counts by name
+func countsBy(client graphdb.Client, query string) (map[string]int64, error) {
+	records, err := readRecords(client, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, record := range records {
+		name, ok := record[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast name to string type")
+		}
+		count, ok := record[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast count to integer type")
+		}
+		counts[name] = count
+	}
+	return counts, nil
+}
+
+func writeStatsJSON(w io.Writer, stats *graphStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+func writeStatsTable(w io.Writer, stats *graphStats) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE TYPE\tCOUNT")
+	writeCounts(tw, stats.Nodes)
+	fmt.Fprintln(tw, "\nEDGE TYPE\tCOUNT")
+	writeCounts(tw, stats.Edges)
+	fmt.Fprintln(tw, "\nDISTINCT\tCOUNT")
+	fmt.Fprintf(tw, "packages\t%d\n", stats.Packages)
+	fmt.Fprintf(tw, "artifacts\t%d\n", stats.Artifacts)
+	fmt.Fprintf(tw, "vulnerabilities\t%d\n", stats.Vulnerabilities)
+	if len(stats.MostDependedOn) > 0 {
+		fmt.Fprintln(tw, "\nMOST DEPENDED ON\tDEPENDENTS")
+		for _, p := range stats.MostDependedOn {
+			fmt.Fprintf(tw, "%s\t%d\n", p.Purl, p.Dependents)
+		}
+	}
+	return tw.Flush()
+}
+
+// writeCounts writes the counts sorted by name
+func writeCounts(w io.Writer, counts map[string]int64) {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%d\n", name, counts[name])
+	}
+}
@@ -0,0 +1,56 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultStageTimeout is long enough for the largest SBOMs, it only catches
+// documents stuck in a stage
+const defaultStageTimeout = 5 * time.Minute
+
+// stageTimeouts are the timeouts of the stages of the ingestion pipeline. A
+// document taking longer in a stage is abandoned. 0 disables the timeout.
+type stageTimeouts struct {
+	process  time.Duration
+	ingest   time.Duration
+	assemble time.Duration
+}
+
+// addTimeoutFlags adds the flags configuring the timeouts of the stages of
+// the ingestion pipeline to the command
+func addTimeoutFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVar(&flags.processTimeout, "process-timeout", defaultStageTimeout, "timeout to process a document, including fetching the documents it references; 0 disables it")
+	cmd.PersistentFlags().DurationVar(&flags.ingestTimeout, "ingest-timeout", defaultStageTimeout, "timeout to parse a processed document into graphs; 0 disables it")
+	cmd.PersistentFlags().DurationVar(&flags.assembleTimeout, "assemble-timeout", defaultStageTimeout, "timeout to write the graphs of a document to the database, including waiting for the writes of other documents; 0 disables it")
+}
+
+// getStageTimeouts returns the timeouts of the stages of the ingestion
+// pipeline given by the flags
+func getStageTimeouts() (stageTimeouts, error) {
+	if flags.processTimeout < 0 || flags.ingestTimeout < 0 || flags.assembleTimeout < 0 {
+		return stageTimeouts{}, fmt.Errorf("process-timeout, ingest-timeout and assemble-timeout must not be negative")
+	}
+	return stageTimeouts{
+		process:  flags.processTimeout,
+		ingest:   flags.ingestTimeout,
+		assemble: flags.assembleTimeout,
+	}, nil
+}
@@ -0,0 +1,68 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/spf13/cobra"
+)
+
+// addDBFlags adds the flags giving the database to connect to and its
+// credentials to the command. When the command has a --backend flag, which
+// must then be added first, they also apply to the PostgreSQL backend.
+func addDBFlags(cmd *cobra.Command) {
+	db, addr := "neo4j", "address to neo4j db"
+	if cmd.PersistentFlags().Lookup("backend") != nil {
+		db, addr = "the db", "address to neo4j db, or postgres connection URL (e.g. postgres://localhost:5432/guac)"
+	}
+	cmd.PersistentFlags().StringVar(&flags.dbAddr, "db-addr", "neo4j://localhost:7687", addr)
+	cmd.PersistentFlags().StringVar(&flags.dbName, "db-name", "", "name of the neo4j database to use, e.g. the database of a tenant; empty uses the default database of the server")
+	cmd.PersistentFlags().StringVar(&flags.creds, "creds", "", "credentials to access "+db+" in 'user:pass' format; prefer --creds-file or the NEO4J_USER and NEO4J_PASSWORD environment variables")
+	cmd.PersistentFlags().StringVar(&flags.credsFile, "creds-file", "", "path to a file holding the credentials to access "+db+" in 'user:pass' format")
+	cmd.PersistentFlags().StringVar(&flags.realm, "realm", "neo4j", "realm to connecto graph db")
+}
+
+// addTLSFlags adds the flags configuring the encryption of the connection to
+// neo4j to the command
+func addTLSFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&flags.dbCAFile, "db-ca-file", "", "path to the PEM encoded CA certificates the neo4j server certificate must chain to, instead of the system ones; needs a neo4j+s:// or bolt+s:// --db-addr")
+	cmd.PersistentFlags().BoolVar(&flags.dbInsecureSkipVerify, "db-insecure-skip-verify", false, "accept any neo4j server certificate, e.g. a self-signed one; only use for testing")
+	cmd.PersistentFlags().StringVar(&flags.tlsClientCert, "tls-client-cert", "", "path to a PEM encoded client certificate for mutual TLS with neo4j; not supported by the neo4j driver, so it is rejected")
+	cmd.PersistentFlags().StringVar(&flags.tlsClientKey, "tls-client-key", "", "path to the PEM encoded key of --tls-client-cert; not supported by the neo4j driver, so it is rejected")
+}
+
+// getTLSOptions returns the TLS options of the neo4j connection given by the
+// flags
+func getTLSOptions() (graphdb.TLSOptions, error) {
+	opts := graphdb.TLSOptions{
+		InsecureSkipVerify: flags.dbInsecureSkipVerify,
+		ClientCertFile:     flags.tlsClientCert,
+		ClientKeyFile:      flags.tlsClientKey,
+	}
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		return opts, fmt.Errorf("--tls-client-cert and --tls-client-key cannot be used: %w; terminate mutual TLS in a proxy in front of neo4j instead", graphdb.ErrClientCertificate)
+	}
+	if flags.dbCAFile != "" {
+		pool, err := graphdb.LoadCertPool(flags.dbCAFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.RootCAs = pool
+	}
+	return opts, nil
+}
@@ -0,0 +1,67 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/tracing"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracingShutdownTimeout bounds the time spent exporting the last spans when
+// the command exits
+const tracingShutdownTimeout = 10 * time.Second
+
+// addTracingFlags adds the flags configuring the export of the traces of the
+// pipeline to the command
+func addTracingFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&flags.otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint of an OpenTelemetry collector the traces of the pipeline are exported to (e.g. http://localhost:4318); empty disables tracing")
+}
+
+// setupTracing exports the spans to the OTLP endpoint, if any. The returned
+// function exports the remaining spans and must be called before exiting.
+func setupTracing(ctx context.Context, endpoint string) (func(), error) {
+	if endpoint == "" {
+		return func() {}, nil
+	}
+	shutdown, err := tracing.Setup(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Infof("exporting traces to %s", endpoint)
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(ctx, tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logging.FromContext(ctx).Errorf("unable to export the remaining spans: %v", err)
+		}
+	}, nil
+}
+
+// documentAttributes returns the span attributes identifying the document
+func documentAttributes(d *processor.Document) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("source", d.SourceInformation.Source),
+		attribute.String("collector", d.SourceInformation.Collector),
+		attribute.String("format", string(d.Format)),
+		attribute.String("type", string(d.Type)),
+	}
+}
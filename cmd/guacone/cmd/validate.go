@@ -0,0 +1,240 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/file"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/process"
+	"github.com/guacsec/guac/pkg/ingestor/parser"
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var validateCmdFlags = struct {
+	output string
+}{}
+
+type validateOptions struct {
+	// paths to the files and folders with documents to validate
+	paths []string
+	// number of nested archive levels whose entries are validated
+	archiveDepth int
+	// how SBOMs not matching the schema of their spec version are handled
+	schemaMode process.SchemaValidationMode
+	// output format: table, json or ndjson
+	output string
+}
+
+// validatedDocument is the result of validating a document. The JSON field
+// names are stable, for scripts to depend on them.
+type validatedDocument struct {
+	Source string                 `json:"source"`
+	Type   processor.DocumentType `json:"type"`
+	Format processor.FormatType   `json:"format"`
+	// Documents is the number of documents in the tree of the document,
+	// e.g. 2 for a DSSE envelope and its payload
+	Documents int `json:"documents"`
+	// Nodes and Edges are the numbers of nodes and edges the document
+	// would add to the graph
+	Nodes int    `json:"nodes"`
+	Edges int    `json:"edges"`
+	Error string `json:"error,omitempty"`
+}
+
+type validateResult struct {
+	Documents []validatedDocument `json:"documents"`
+	Failed    int                 `json:"failed"`
+}
+
+func init() {
+	validateCmd.PersistentFlags().IntVar(&flags.archiveDepth, "archive-depth", file.DefaultArchiveDepth, "number of nested archive levels whose entries are validated as documents; 0 validates archives as regular files")
+	validateCmd.PersistentFlags().StringVar(&flags.schemaMode, "schema-validation", string(process.SchemaValidationWarn), "how CycloneDX and SPDX JSON documents not matching their schema are handled: warn, strict (reject them) or off")
+	validateCmd.PersistentFlags().StringVar(&validateCmdFlags.output, "output-format", "", "output format: table, json or ndjson (default table when writing to a terminal, json otherwise)")
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [flags] file_path [file_path...]",
+	Short: "detect the format of the documents and parse them, without writing to a database",
+	Long: `validate processes and parses the documents like the files command, then
+prints the detected type and format of each document, the number of nodes and
+edges it would add to the graph, and the error if it cannot be ingested. No
+database or credentials are needed, signatures are not verified and the
+documents referenced by SBOMs are not fetched.
+
+It exits with status 1 if any document fails, e.g. to gate the SBOMs in CI.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+		defer cancel()
+		logger := logging.FromContext(ctx)
+		cancelOnSignal(ctx, cancel)
+
+		opts, err := validateValidateFlags(args)
+		if err != nil {
+			fmt.Printf("unable to validate flags: %v\n", err)
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+
+		process.SetDocumentResolver(nil)
+		process.SetSchemaValidation(opts.schemaMode)
+		for _, path := range opts.paths {
+			fileCollector := file.NewFileCollector(ctx, path, false, 0, opts.archiveDepth, time.Time{}, nil)
+			if err := collector.RegisterDocumentCollector(fileCollector, file.FileCollector+":"+path); err != nil {
+				logger.Errorf("unable to register file collector: %v", err)
+			}
+		}
+
+		result := validateResult{Documents: []validatedDocument{}}
+		docChan, wait := collector.CollectDocuments(ctx, func(err error) bool {
+			logger.Errorf("collector ended with error: %v", err)
+			return false
+		})
+		for d := range docChan {
+			v := validateDocument(ctx, d)
+			collector.Acknowledge(d, nil)
+			if v.Error != "" {
+				result.Failed++
+			}
+			result.Documents = append(result.Documents, v)
+		}
+		if err := wait(); err != nil {
+			os.Exit(1)
+		}
+
+		switch opts.output {
+		case jsonOutput:
+			err = writeValidateJSON(os.Stdout, result)
+		case ndjsonOutput:
+			err = writeValidateNDJSON(os.Stdout, result)
+		default:
+			err = writeValidateTable(os.Stdout, result)
+		}
+		if err != nil {
+			logger.Errorf("unable to write validation result: %v", err)
+			os.Exit(1)
+		}
+		if result.Failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func validateValidateFlags(args []string) (validateOptions, error) {
+	var opts validateOptions
+	if len(args) == 0 {
+		return opts, fmt.Errorf("expected positional arguments for file_path")
+	}
+	paths, err := file.ResolvePaths(args)
+	if err != nil {
+		return opts, err
+	}
+	opts.paths = paths
+	if flags.archiveDepth < 0 {
+		return opts, fmt.Errorf("archive-depth must not be negative")
+	}
+	opts.archiveDepth = flags.archiveDepth
+	opts.schemaMode, err = getSchemaValidationMode()
+	if err != nil {
+		return opts, err
+	}
+	opts.output, err = getOutputFormat(validateCmdFlags.output)
+	if err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// validateDocument processes and parses the document, and validates the
+// graph it would be stored as. Sub-documents failing to parse fail the
+// document, even though `guacone files` ingests the others.
+func validateDocument(ctx context.Context, d *processor.Document) validatedDocument {
+	v := validatedDocument{Source: d.SourceInformation.Source}
+	fail := func(err error) validatedDocument {
+		v.Type, v.Format = d.Type, d.Format
+		v.Error = err.Error()
+		return v
+	}
+
+	tree, err := process.Process(ctx, d)
+	if err != nil {
+		return fail(err)
+	}
+	v.Documents = countDocuments(tree)
+	graphs, err := parser.ParseDocumentTree(ctx, tree)
+	combined := assembler.Graph{
+		Nodes: []assembler.GuacNode{},
+		Edges: []assembler.GuacEdge{},
+	}
+	combined.Merge(graphs...)
+	v.Nodes, v.Edges = len(combined.Nodes), len(combined.Edges)
+	if err != nil {
+		return fail(err)
+	}
+	if err := assembler.ValidateGraph(combined); err != nil {
+		return fail(err)
+	}
+	v.Type, v.Format = d.Type, d.Format
+	return v
+}
+
+func countDocuments(n *processor.DocumentNode) int {
+	count := 1
+	for _, c := range n.Children {
+		count += countDocuments(c)
+	}
+	return count
+}
+
+func writeValidateJSON(w io.Writer, result validateResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// writeValidateNDJSON writes one JSON object per line for each document
+func writeValidateNDJSON(w io.Writer, result validateResult) error {
+	encoder := json.NewEncoder(w)
+	for _, d := range result.Documents {
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeValidateTable(w io.Writer, result validateResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tTYPE\tFORMAT\tNODES\tEDGES\tERROR")
+	for _, d := range result.Documents {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", d.Source, d.Type, d.Format, d.Nodes, d.Edges, d.Error)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d documents, %d failed\n", len(result.Documents), result.Failed)
+	return err
+}
@@ -85,7 +85,7 @@ var exampleCmd = &cobra.Command{
 				os.Exit(1)
 			}
 
-			g.AppendGraph(inputs...)
+			g.Merge(inputs...)
 		}
 		logger.Infof("graph nodes: %v, edges: %v", len(g.Nodes), len(g.Edges))
 
@@ -19,16 +19,23 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/guacsec/guac/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+var logLevel string
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", string(logging.DefaultLevel), "minimum level of the log lines: debug, info, warn or error")
 	rootCmd.AddCommand(exampleCmd)
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "ingestor",
 	Short: "ingestor is an ingestor cmdline for GUAC",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return logging.SetLevel(logging.Level(logLevel))
+	},
 }
 
 func Execute() {
@@ -0,0 +1,12 @@
+module github.com/example/app
+
+go 1.20
+
+require (
+	github.com/spf13/cobra v1.6.1
+	golang.org/x/text v0.3.7 // indirect
+)
+
+require github.com/spf13/pflag v1.0.5 // indirect
+
+replace golang.org/x/text => golang.org/x/text v0.3.8
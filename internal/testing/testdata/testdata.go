@@ -39,6 +39,16 @@ var (
 	//go:embed exampledata/alpine-small-spdx.json
 	SpdxExampleAlpine []byte
 
+	// SPDX 2.3 tag-value document with package checksums and
+	// GENERATED_FROM/STATIC_LINK relationships
+	//go:embed exampledata/small-spdx-2.3.spdx
+	SpdxTagValueExample []byte
+
+	// SPDX document describing the curl tarball that is the subject of
+	// Ite6SLSAV1Doc, with uppercase checksums
+	//go:embed exampledata/curl-spdx.json
+	SpdxExampleCurl []byte
+
 	// Invalid types for field spdxVersion
 	//go:embed exampledata/invalid-spdx.json
 	SpdxInvalidExample []byte
@@ -51,6 +61,108 @@ var (
 	//go:embed exampledata/invalid-scorecard.json
 	ScorecardInvalid []byte
 
+	//go:embed exampledata/osv-ghsa.json
+	OsvExample []byte
+
+	//go:embed exampledata/invalid-osv.json
+	OsvInvalid []byte
+
+	//go:embed exampledata/lifecycle-django.json
+	LifecycleExample []byte
+
+	//go:embed exampledata/invalid-lifecycle.json
+	LifecycleInvalid []byte
+
+	// ClearlyDefined definition of an npm package
+	//go:embed exampledata/clearlydefined-lodash.json
+	ClearlyDefinedExample []byte
+
+	// ClearlyDefined definition whose coordinates have no name
+	//go:embed exampledata/invalid-clearlydefined.json
+	ClearlyDefinedInvalid []byte
+
+	// SARIF log of a CodeQL analysis of a repository and of a scan of a
+	// binary
+	//go:embed exampledata/sarif-codeql.json
+	SARIFExample []byte
+
+	// SARIF log whose run does not name its tool
+	//go:embed exampledata/invalid-sarif.json
+	SARIFInvalid []byte
+
+	// Dependency snapshot of GitHub's dependency submission API, with an npm
+	// and a Go manifest
+	//go:embed exampledata/github-dependency-snapshot.json
+	DepSnapshotExample []byte
+
+	// Dependency snapshot whose detector has no name
+	//go:embed exampledata/invalid-github-dependency-snapshot.json
+	DepSnapshotInvalid []byte
+
+	// SLSA v0.2 provenance of a Tekton PipelineRun, generated by Tekton
+	// Chains
+	//go:embed exampledata/tekton-pipelinerun-provenance.json
+	TektonPipelineRunExample []byte
+
+	// Configuration of an image built on alpine, with its build history
+	//go:embed exampledata/oci-image-config.json
+	OCIImageConfigExample []byte
+
+	// Manifest of the image of OCIImageConfigExample, annotated with its
+	// base image
+	//go:embed exampledata/oci-image-manifest.json
+	OCIImageManifestExample []byte
+
+	// package-lock.json of a project with a nested dependency, a dev
+	// dependency and a missing optional dependency
+	//go:embed exampledata/npm-package-lock.json
+	NpmLockExample []byte
+
+	// go.mod and go.sum of a module with direct and indirect requirements
+	//go:embed exampledata/go-example.mod
+	GoModExample []byte
+
+	//go:embed exampledata/go-example.sum
+	GoSumExample []byte
+
+	// requirements.txt compiled by pip-compile, giving the dependencies in
+	// `# via` comments
+	//go:embed exampledata/requirements-example.txt
+	RequirementsExample []byte
+
+	//go:embed exampledata/cargo-example.lock
+	CargoLockExample []byte
+
+	//go:embed exampledata/openvex.json
+	OpenVEXExample []byte
+
+	//go:embed exampledata/openvex-v0.0.1.json
+	OpenVEXLegacyExample []byte
+
+	//go:embed exampledata/invalid-openvex.json
+	OpenVEXInvalid []byte
+
+	//go:embed exampledata/csaf-vex.json
+	CsafVEXExample []byte
+
+	//go:embed exampledata/invalid-csaf.json
+	CsafInvalid []byte
+
+	//go:embed exampledata/intoto-root.layout
+	InTotoLayoutExample []byte
+
+	//go:embed exampledata/intoto-write-code.link
+	InTotoWriteCodeLinkExample []byte
+
+	//go:embed exampledata/intoto-package.link
+	InTotoPackageLinkExample []byte
+
+	//go:embed exampledata/syft-alpine.json
+	SyftExample []byte
+
+	//go:embed exampledata/trivy-alpine.json
+	TrivyExample []byte
+
 	//go:embed exampledata/alpine-cyclonedx.json
 	CycloneDXExampleAlpine []byte
 
@@ -72,6 +184,14 @@ var (
 	//go:embed exampledata/big-mongo-cyclonedx.json
 	CycloneDXBigExample []byte
 
+	// CycloneDX 1.5 document with vulnerabilities
+	//go:embed exampledata/vuln-cyclonedx-1.5.json
+	CycloneDXVulnExample []byte
+
+	// CycloneDX 1.5 document with vulnerability analyses (embedded VEX)
+	//go:embed exampledata/vex-cyclonedx-1.5.json
+	CycloneDXVEXExample []byte
+
 	//go:embed exampledata/crev-review.json
 	ITE6CREVExample []byte
 
@@ -81,6 +201,11 @@ var (
 	//go:embed exampledata/certify-vuln.json
 	ITE6VulnExample []byte
 
+	// claims that a container image, its purl, a source commit and a go
+	// module identify the same software
+	//go:embed exampledata/ite6-same-as.json
+	ITE6SameAsExample []byte
+
 	// DSSE/SLSA Testdata
 
 	// Taken from: https://slsa.dev/provenance/v0.1#example
@@ -117,6 +242,38 @@ var (
 		}
 	}`
 
+	// Based on: https://slsa.dev/provenance/v1#example
+	ite6SLSAV1 = `
+	{
+		"_type": "https://in-toto.io/Statement/v1",
+		"subject": [{"name": "curl-7.72.0.tar.bz2", "digest": {"sha256": "ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef"}}],
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"predicate": {
+			"buildDefinition": {
+				"buildType": "https://slsa-framework.github.io/github-actions-buildtypes/workflow/v1",
+				"externalParameters": {
+					"workflow": {
+						"ref": "refs/heads/main",
+						"repository": "https://github.com/curl/curl-docker",
+						"path": ".github/workflows/release.yaml"
+					}
+				},
+				"resolvedDependencies": [
+					{
+						"uri": "git+https://github.com/curl/curl-docker@refs/heads/main",
+						"digest": { "gitCommit": "d6525c840a62b398424a78d792f457477135d0cf" }
+					}, {
+						"name": "github_hosted_vm:ubuntu-18.04:20210123.1"
+					}
+				]
+			},
+			"runDetails": {
+				"builder": { "id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.5.0" },
+				"metadata": { "invocationId": "https://github.com/curl/curl-docker/actions/runs/1234/attempts/1" }
+			}
+		}
+	}`
+
 	b64ITE6SLSA    = base64.StdEncoding.EncodeToString([]byte(ite6SLSA))
 	ite6Payload, _ = json.Marshal(dsse.Envelope{
 		PayloadType: "https://in-toto.io/Statement/v0.1",
@@ -145,6 +302,16 @@ var (
 		},
 	}
 
+	Ite6SLSAV1Doc = processor.Document{
+		Blob:   []byte(ite6SLSAV1),
+		Type:   processor.DocumentITE6SLSA,
+		Format: processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{
+			Collector: "TestCollector",
+			Source:    "TestSource",
+		},
+	}
+
 	art = assembler.ArtifactNode{
 		Name:   "helloworld",
 		Digest: "sha256:5678...",
@@ -200,6 +367,29 @@ var (
 		),
 	}
 
+	slsaSource = assembler.SourceNode{
+		URL: "https://github.com/curl/curl-docker",
+		VCS: "git",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	slsaCommit = assembler.CommitNode{
+		VCS:        "git",
+		Sha:        "d6525c840a62b398424a78d792f457477135d0cf",
+		Repository: "https://github.com/curl/curl-docker",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
 	EcdsaPubKey, pemBytes, _ = keyutil.GetECDSAPubKey()
 	keyHash, _               = dsse.SHA256KeyID(EcdsaPubKey)
 
@@ -220,7 +410,7 @@ var (
 	DsseNodes = []assembler.GuacNode{Ident}
 	DsseEdges = []assembler.GuacEdge{}
 
-	SlsaNodes = []assembler.GuacNode{art, att, mat1, mat2, build}
+	SlsaNodes = []assembler.GuacNode{art, att, mat1, mat2, build, slsaSource, slsaCommit}
 	SlsaEdges = []assembler.GuacEdge{
 		assembler.IdentityForEdge{
 			IdentityNode:    Ident,
@@ -234,13 +424,93 @@ var (
 			AttestationNode: att,
 			ForArtifact:     art,
 		},
-		assembler.DependsOnEdge{
-			ArtifactNode:       art,
-			ArtifactDependency: mat1,
+		assembler.BuiltFromEdge{
+			ArtifactNode: art,
+			MaterialNode: mat1,
 		},
-		assembler.DependsOnEdge{
-			ArtifactNode:       art,
-			ArtifactDependency: mat2,
+		assembler.BuiltFromEdge{
+			ArtifactNode: art,
+			MaterialNode: mat2,
+		},
+		assembler.BuiltFromSourceEdge{
+			ArtifactNode: art,
+			CommitNode:   slsaCommit,
+		},
+		assembler.HasCommitEdge{
+			SourceNode: slsaSource,
+			CommitNode: slsaCommit,
+		},
+	}
+
+	artV1 = assembler.ArtifactNode{
+		Name:   "curl-7.72.0.tar.bz2",
+		Digest: "sha256:ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	attV1 = assembler.AttestationNode{
+		FilePath: "TestSource",
+		Digest:   "sha256:1d7d9230bda15068c2e38f29b059eb57c74663d8def07972f5096397943835cd",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	matV1 = assembler.ArtifactNode{
+		Name:   "git+https://github.com/curl/curl-docker@refs/heads/main",
+		Digest: "gitcommit:d6525c840a62b398424a78d792f457477135d0cf",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	buildV1 = assembler.BuilderNode{
+		BuilderType: "https://slsa-framework.github.io/github-actions-buildtypes/workflow/v1",
+		BuilderId:   "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.5.0",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	SlsaV1Nodes = []assembler.GuacNode{artV1, attV1, matV1, buildV1, slsaSource, slsaCommit}
+	SlsaV1Edges = []assembler.GuacEdge{
+		assembler.IdentityForEdge{
+			IdentityNode:    Ident,
+			AttestationNode: attV1,
+		},
+		assembler.BuiltByEdge{
+			ArtifactNode: artV1,
+			BuilderNode:  buildV1,
+		},
+		assembler.AttestationForEdge{
+			AttestationNode: attV1,
+			ForArtifact:     artV1,
+		},
+		assembler.BuiltFromEdge{
+			ArtifactNode: artV1,
+			MaterialNode: matV1,
+		},
+		assembler.BuiltFromSourceEdge{
+			ArtifactNode: artV1,
+			CommitNode:   slsaCommit,
+		},
+		assembler.HasCommitEdge{
+			SourceNode: slsaSource,
+			CommitNode: slsaCommit,
 		},
 	}
 
@@ -263,7 +533,7 @@ var (
 	baselayoutPack = assembler.PackageNode{
 		Name:    "alpine-baselayout",
 		Digest:  nil,
-		Purl:    "pkg:alpine/alpine-baselayout@3.2.0-r22?arch=x86_64&upstream=alpine-baselayout&distro=alpine-3.16.2",
+		Purl:    "pkg:alpine/alpine-baselayout@3.2.0-r22?arch=x86_64&distro=alpine-3.16.2&upstream=alpine-baselayout",
 		Version: "3.2.0-r22",
 		CPEs: []string{
 			"cpe:2.3:a:alpine-baselayout:alpine-baselayout:3.2.0-r22:*:*:*:*:*:*:*",
@@ -280,7 +550,7 @@ var (
 	keysPack = assembler.PackageNode{
 		Name:    "alpine-keys",
 		Digest:  nil,
-		Purl:    "pkg:alpine/alpine-keys@2.4-r1?arch=x86_64&upstream=alpine-keys&distro=alpine-3.16.2",
+		Purl:    "pkg:alpine/alpine-keys@2.4-r1?arch=x86_64&distro=alpine-3.16.2&upstream=alpine-keys",
 		Version: "2.4-r1",
 		CPEs: []string{
 			"cpe:2.3:a:alpine-keys:alpine-keys:2.4-r1:*:*:*:*:*:*:*",
@@ -299,7 +569,7 @@ var (
 	baselayoutdataPack = assembler.PackageNode{
 		Name:    "alpine-baselayout-data",
 		Digest:  nil,
-		Purl:    "pkg:alpine/alpine-baselayout-data@3.2.0-r22?arch=x86_64&upstream=alpine-baselayout&distro=alpine-3.16.2",
+		Purl:    "pkg:alpine/alpine-baselayout-data@3.2.0-r22?arch=x86_64&distro=alpine-3.16.2&upstream=alpine-baselayout",
 		Version: "3.2.0-r22",
 		CPEs: []string{
 			"cpe:2.3:a:alpine-baselayout-data:alpine-baselayout-data:3.2.0-r22:*:*:*:*:*:*:*",
@@ -358,7 +628,26 @@ var (
 		),
 	}
 
-	SpdxNodes = []assembler.GuacNode{topLevelPack, baselayoutPack, baselayoutdataPack, rsaPubFile, keysPack, worldFile, rootFile, triggersFile}
+	gplLicense = assembler.LicenseNode{
+		Expression: "GPL-2.0-only",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+	mitLicense = assembler.LicenseNode{
+		Expression: "MIT",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	SpdxNodes = []assembler.GuacNode{topLevelPack, baselayoutPack, baselayoutdataPack, rsaPubFile, keysPack, worldFile, rootFile, triggersFile, gplLicense, mitLicense}
 	SpdxEdges = []assembler.GuacEdge{
 		assembler.DependsOnEdge{
 			PackageNode:       topLevelPack,
@@ -404,6 +693,144 @@ var (
 			PackageNode:       keysPack,
 			ContainedArtifact: rsaPubFile,
 		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: baselayoutPack,
+			LicenseNode: gplLicense,
+		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: baselayoutdataPack,
+			LicenseNode: gplLicense,
+		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: keysPack,
+			LicenseNode: mitLicense,
+		},
+	}
+	// SpdxCPEEdges are the edges linking the SPDX packages to their CPEs,
+	// added by the graph builder
+	SpdxCPEEdges = append(append(append([]assembler.GuacEdge{},
+		cpeEdges(baselayoutPack)...),
+		cpeEdges(baselayoutdataPack)...),
+		cpeEdges(keysPack)...)
+
+	// SPDX 2.3 tag-value Testdata
+
+	spdxHelloPack = assembler.PackageNode{
+		Name:    "hello",
+		Digest:  []string{"sha256:5415cfe5f88c0af38df3b7141a3f9bc6b8178e9cf72d700658091b8f5539c7b4"},
+		Purl:    "pkg:generic/hello@1.0.0",
+		Version: "1.0.0",
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxHelloArtifact = assembler.ArtifactNode{
+		Name:   "hello-1.0.0.tar.gz",
+		Digest: "sha256:5415cfe5f88c0af38df3b7141a3f9bc6b8178e9cf72d700658091b8f5539c7b4",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxLibgreetPack = assembler.PackageNode{
+		Name:    "libgreet",
+		Purl:    "pkg:generic/libgreet@2.1.0",
+		Version: "2.1.0",
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxHelloSrcPack = assembler.PackageNode{
+		Name:    "hello-src",
+		Purl:    "pkg:generic/hello-src@1.0.0",
+		Version: "1.0.0",
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxHelloSource = assembler.SourceNode{
+		URL: "https://github.com/example/hello",
+		VCS: "git",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxHelloCommit = assembler.CommitNode{
+		VCS:        "git",
+		Sha:        "0b4d3f6c27e2f7a1d1c5f5b1e9e4bd2f8a3e6c71",
+		Repository: "https://github.com/example/hello",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	spdxLibgreetSource = assembler.SourceNode{
+		URL: "https://github.com/example/libgreet",
+		VCS: "git",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	SpdxTagValueNodes = []assembler.GuacNode{spdxHelloPack, spdxHelloArtifact, spdxLibgreetPack, spdxHelloSrcPack,
+		spdxHelloSource, spdxHelloCommit, spdxLibgreetSource}
+	SpdxTagValueEdges = []assembler.GuacEdge{
+		assembler.ContainsEdge{
+			PackageNode:       spdxHelloPack,
+			ContainedArtifact: spdxHelloArtifact,
+		},
+		assembler.StaticLinkEdge{
+			PackageNode:   spdxHelloPack,
+			LinkedPackage: spdxLibgreetPack,
+		},
+		assembler.GeneratedFromEdge{
+			PackageNode:   spdxHelloPack,
+			PackageSource: spdxHelloSrcPack,
+		},
+		assembler.DependsOnEdge{
+			PackageNode:       spdxHelloPack,
+			PackageDependency: spdxLibgreetPack,
+		},
+		assembler.BuiltFromSourceEdge{
+			PackageNode: spdxHelloSrcPack,
+			CommitNode:  spdxHelloCommit,
+		},
+		assembler.HasCommitEdge{
+			SourceNode: spdxHelloSource,
+			CommitNode: spdxHelloCommit,
+		},
+		assembler.BuiltFromSourceEdge{
+			PackageNode: spdxLibgreetPack,
+			SourceNode:  spdxLibgreetSource,
+		},
 	}
 
 	// CycloneDX Testdata
@@ -468,7 +895,7 @@ var (
 		),
 	}
 
-	CycloneDXNodes = []assembler.GuacNode{cdxTopLevelPack, cdxBasefilesPack, cdxNetbasePack, cdxTzdataPack}
+	CycloneDXNodes = []assembler.GuacNode{cdxTopLevelPack, cdxBasefilesPack, cdxNetbasePack, cdxTzdataPack, gplLicense}
 	CyloneDXEdges  = []assembler.GuacEdge{
 		assembler.DependsOnEdge{
 			PackageDependency: cdxBasefilesPack,
@@ -482,13 +909,17 @@ var (
 			PackageDependency: cdxTzdataPack,
 			PackageNode:       cdxTopLevelPack,
 		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: cdxNetbasePack,
+			LicenseNode: gplLicense,
+		},
 	}
 
 	// CycloneDX Testdata with package dependencies
 	cdxTopQuarkusPack = assembler.PackageNode{
 		Name:    "getting-started",
 		Version: "1.0.0-SNAPSHOT",
-		Purl:    "pkg:maven/org.acme/getting-started@1.0.0-SNAPSHOT?type=jar",
+		Purl:    "pkg:maven/org.acme/getting-started@1.0.0-SNAPSHOT",
 		Tags:    []string{"library"},
 		CPEs:    nil,
 		NodeData: *assembler.NewObjectMetadata(
@@ -503,7 +934,7 @@ var (
 		Name:    "quarkus-resteasy-reactive",
 		Digest:  nil,
 		Version: "2.13.4.Final",
-		Purl:    "pkg:maven/io.quarkus/quarkus-resteasy-reactive@2.13.4.Final?type=jar",
+		Purl:    "pkg:maven/io.quarkus/quarkus-resteasy-reactive@2.13.4.Final",
 		CPEs:    nil,
 		NodeData: *assembler.NewObjectMetadata(
 			processor.SourceInformation{
@@ -517,7 +948,7 @@ var (
 		Name:    "quarkus-resteasy-reactive-common",
 		Digest:  nil,
 		Version: "2.13.4.Final",
-		Purl:    "pkg:maven/io.quarkus/quarkus-resteasy-reactive-common@2.13.4.Final?type=jar",
+		Purl:    "pkg:maven/io.quarkus/quarkus-resteasy-reactive-common@2.13.4.Final",
 		CPEs:    nil,
 		NodeData: *assembler.NewObjectMetadata(
 			processor.SourceInformation{
@@ -527,7 +958,28 @@ var (
 		),
 	}
 
-	CycloneDXQuarkusNodes = []assembler.GuacNode{cdxTopQuarkusPack, cdxResteasyPack, cdxReactiveCommonPack}
+	cdxQuarkusSource = assembler.SourceNode{
+		URL: "https://github.com/quarkusio/quarkus",
+		VCS: "git",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	apacheLicense = assembler.LicenseNode{
+		Expression: "Apache-2.0",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	CycloneDXQuarkusNodes = []assembler.GuacNode{cdxTopQuarkusPack, cdxResteasyPack, cdxReactiveCommonPack, cdxQuarkusSource, apacheLicense}
 	CyloneDXQuarkusEdges  = []assembler.GuacEdge{
 		assembler.DependsOnEdge{
 			PackageDependency: cdxResteasyPack,
@@ -541,6 +993,155 @@ var (
 			PackageDependency: cdxReactiveCommonPack,
 			PackageNode:       cdxResteasyPack,
 		},
+		assembler.BuiltFromSourceEdge{
+			PackageNode: cdxResteasyPack,
+			SourceNode:  cdxQuarkusSource,
+		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: cdxResteasyPack,
+			LicenseNode: apacheLicense,
+		},
+		assembler.DeclaredLicenseEdge{
+			PackageNode: cdxReactiveCommonPack,
+			LicenseNode: apacheLicense,
+		},
+	}
+
+	// CycloneDX Testdata with vulnerabilities
+	cdxVulnAppPack = assembler.PackageNode{
+		Name:    "vulnerable-app",
+		Digest:  nil,
+		Version: "1.0.0",
+		Purl:    "pkg:maven/org.acme/vulnerable-app@1.0.0",
+		Tags:    []string{"application"},
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	cdxVulnLog4jPack = assembler.PackageNode{
+		Name:    "log4j-core",
+		Digest:  nil,
+		Version: "2.14.1",
+		Purl:    "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1",
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	cdxVulnCommonsTextPack = assembler.PackageNode{
+		Name:    "commons-text",
+		Digest:  nil,
+		Version: "1.9",
+		Purl:    "pkg:maven/org.apache.commons/commons-text@1.9",
+		CPEs:    nil,
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	cdxLog4ShellVuln = assembler.VulnerabilityNode{
+		ID: "CVE-2021-44228",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	cdxText4ShellVuln = assembler.VulnerabilityNode{
+		ID: "CVE-2022-42889",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	CycloneDXVulnNodes = []assembler.GuacNode{cdxVulnAppPack, cdxVulnLog4jPack, cdxVulnCommonsTextPack, cdxLog4ShellVuln, cdxText4ShellVuln}
+	CycloneDXVulnEdges = []assembler.GuacEdge{
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnLog4jPack,
+			PackageNode:       cdxVulnAppPack,
+		},
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnCommonsTextPack,
+			PackageNode:       cdxVulnAppPack,
+		},
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnCommonsTextPack,
+			PackageNode:       cdxVulnLog4jPack,
+		},
+		assembler.VulnerableToEdge{
+			PackageNode:       cdxVulnLog4jPack,
+			VulnerabilityNode: cdxLog4ShellVuln,
+		},
+		assembler.VulnerableToEdge{
+			PackageNode:       cdxVulnCommonsTextPack,
+			VulnerabilityNode: cdxText4ShellVuln,
+		},
+	}
+
+	cdxLog4ShellBypassVuln = assembler.VulnerabilityNode{
+		ID: "CVE-2021-45046",
+		NodeData: *assembler.NewObjectMetadata(
+			processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		),
+	}
+
+	CycloneDXVEXNodes = []assembler.GuacNode{cdxVulnAppPack, cdxVulnLog4jPack, cdxVulnCommonsTextPack, cdxLog4ShellVuln, cdxText4ShellVuln, cdxLog4ShellBypassVuln}
+	CycloneDXVEXEdges = []assembler.GuacEdge{
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnLog4jPack,
+			PackageNode:       cdxVulnAppPack,
+		},
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnCommonsTextPack,
+			PackageNode:       cdxVulnAppPack,
+		},
+		assembler.DependsOnEdge{
+			PackageDependency: cdxVulnCommonsTextPack,
+			PackageNode:       cdxVulnLog4jPack,
+		},
+		assembler.VexStatementEdge{
+			VulnerabilityNode: cdxLog4ShellVuln,
+			PackageNode:       cdxVulnLog4jPack,
+			Status:            "not_affected",
+			Justification:     "code_not_reachable",
+			ImpactStatement:   "The JNDI lookup feature is disabled by the application.",
+		},
+		assembler.VexStatementEdge{
+			VulnerabilityNode: cdxText4ShellVuln,
+			PackageNode:       cdxVulnCommonsTextPack,
+			Status:            "affected",
+			ImpactStatement:   "User input reaches StringSubstitutor.",
+			ActionStatement:   "update, workaround_available",
+		},
+		assembler.VulnerableToEdge{
+			PackageNode:       cdxVulnCommonsTextPack,
+			VulnerabilityNode: cdxText4ShellVuln,
+		},
+		assembler.VexStatementEdge{
+			VulnerabilityNode: cdxLog4ShellBypassVuln,
+			PackageNode:       cdxVulnAppPack,
+			Status:            "fixed",
+		},
 	}
 
 	// ceritifer testdata
@@ -708,7 +1309,7 @@ var (
 	}
 
 	secondLevelPackage = assembler.PackageNode{
-		Purl:   "pkg:oci/vul-secondLevel-latest?repository_url=grc.io",
+		Purl:   "pkg:oci/vul-secondlevel-latest?repository_url=grc.io",
 		Digest: []string{"sha256:fe608dbc4894fc0b9c82908ece9ddddb63bb79083e5b25f2c02f87773bde1aa1"},
 	}
 
@@ -793,6 +1394,69 @@ func GuacNodeSliceEqual(slice1, slice2 []assembler.GuacNode) bool {
 						break
 					}
 				}
+			} else if node1.Type() == "Step" && node2.Type() == "Step" {
+				if node1.(assembler.StepNode).Name == node2.(assembler.StepNode).Name {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Layout" && node2.Type() == "Layout" {
+				if node1.(assembler.LayoutNode).Digest == node2.(assembler.LayoutNode).Digest {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "CPE" && node2.Type() == "CPE" {
+				if node1.(assembler.CPENode).CPE == node2.(assembler.CPENode).CPE {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Lifecycle" && node2.Type() == "Lifecycle" {
+				if node1.(assembler.LifecycleNode).Cycle == node2.(assembler.LifecycleNode).Cycle {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Metadata" && node2.Type() == "Metadata" {
+				if node1.(assembler.MetadataNode).ID == node2.(assembler.MetadataNode).ID {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "License" && node2.Type() == "License" {
+				if node1.(assembler.LicenseNode).Expression == node2.(assembler.LicenseNode).Expression {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Source" && node2.Type() == "Source" {
+				if node1.(assembler.SourceNode).URL == node2.(assembler.SourceNode).URL {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Commit" && node2.Type() == "Commit" {
+				if node1.(assembler.CommitNode).Sha == node2.(assembler.CommitNode).Sha {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
+			} else if node1.Type() == "Finding" && node2.Type() == "Finding" {
+				if node1.(assembler.FindingNode).RuleID == node2.(assembler.FindingNode).RuleID {
+					if reflect.DeepEqual(node1, node2) {
+						e = true
+						break
+					}
+				}
 			}
 		}
 		if !e {
@@ -842,6 +1506,116 @@ func GuacEdgeSliceEqual(slice1, slice2 []assembler.GuacEdge) bool {
 					e = true
 					break
 				}
+			} else if edge1.Type() == "VulnerableTo" && edge2.Type() == "VulnerableTo" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "GeneratedFrom" && edge2.Type() == "GeneratedFrom" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "StaticLink" && edge2.Type() == "StaticLink" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "BuiltFrom" && edge2.Type() == "BuiltFrom" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "AliasOf" && edge2.Type() == "AliasOf" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "Affects" && edge2.Type() == "Affects" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "VexStatement" && edge2.Type() == "VexStatement" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "Material" && edge2.Type() == "Material" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "Product" && edge2.Type() == "Product" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "ExpectsStep" && edge2.Type() == "ExpectsStep" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasCPE" && edge2.Type() == "HasCPE" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasLifecycle" && edge2.Type() == "HasLifecycle" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "MetadataFor" && edge2.Type() == "MetadataFor" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "DeclaredLicense" && edge2.Type() == "DeclaredLicense" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "DiscoveredLicense" && edge2.Type() == "DiscoveredLicense" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "SameAs" && edge2.Type() == "SameAs" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasCommit" && edge2.Type() == "HasCommit" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "BuiltFromSource" && edge2.Type() == "BuiltFromSource" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasFinding" && edge2.Type() == "HasFinding" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasLayer" && edge2.Type() == "HasLayer" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "HasConfig" && edge2.Type() == "HasConfig" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
+			} else if edge1.Type() == "DerivedFrom" && edge2.Type() == "DerivedFrom" {
+				if reflect.DeepEqual(edge1, edge2) {
+					e = true
+					break
+				}
 			}
 		}
 		if !e {
@@ -851,3 +1625,15 @@ func GuacEdgeSliceEqual(slice1, slice2 []assembler.GuacEdge) bool {
 	}
 	return result
 }
+
+// cpeEdges returns the edges linking the package to each of its CPEs
+func cpeEdges(pkg assembler.PackageNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, cpe := range pkg.CPEs {
+		edges = append(edges, assembler.HasCPEEdge{
+			PackageNode: assembler.PackageNode{Purl: pkg.Purl},
+			CPENode:     assembler.CPENode{CPE: cpe, NodeData: pkg.NodeData},
+		})
+	}
+	return edges
+}
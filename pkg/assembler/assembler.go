@@ -15,6 +15,11 @@
 
 package assembler
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
 type assembler struct{} //nolint: unused
 
 // NOTE: `GuacNode` and `GuacEdge` interfaces are very experimental and might
@@ -136,6 +141,146 @@ func (g *Graph) AppendGraph(gs ...Graph) {
 	}
 }
 
+// Merge appends the graph g with additional graphs, merging nodes and edges
+// that are already present.
+//
+// Two nodes are the same if they have the same type and the same values for
+// their `IdentifiablePropertyNames`. Two edges are the same if they have the
+// same type and the same endpoints. When duplicates are found, the first
+// occurrence is kept and completed with the properties of the others: unset
+// properties are filled in and list properties (e.g. digests, CPEs or tags)
+// are the union of all occurrences. Nodes that are missing values for their
+// identifiable properties cannot be compared, so they are always appended.
+func (g *Graph) Merge(gs ...Graph) {
+	nodeIndex := map[string]int{}
+	for i, n := range g.Nodes {
+		if id, err := nodeIdentity(n); err == nil {
+			if _, ok := nodeIndex[id]; !ok {
+				nodeIndex[id] = i
+			}
+		}
+	}
+	edgeIndex := map[string]int{}
+	for i, e := range g.Edges {
+		if id, err := edgeIdentity(e); err == nil {
+			if _, ok := edgeIndex[id]; !ok {
+				edgeIndex[id] = i
+			}
+		}
+	}
+
+	for _, add := range gs {
+		for _, n := range add.Nodes {
+			id, err := nodeIdentity(n)
+			if err == nil {
+				if i, ok := nodeIndex[id]; ok {
+					g.Nodes[i] = mergeProperties(g.Nodes[i], n)
+					continue
+				}
+				nodeIndex[id] = len(g.Nodes)
+			}
+			g.Nodes = append(g.Nodes, n)
+		}
+		for _, e := range add.Edges {
+			id, err := edgeIdentity(e)
+			if err == nil {
+				if i, ok := edgeIndex[id]; ok {
+					g.Edges[i] = mergeProperties(g.Edges[i], e)
+					continue
+				}
+				edgeIndex[id] = len(g.Edges)
+			}
+			g.Edges = append(g.Edges, e)
+		}
+	}
+}
+
+// CPEEdges returns a `HasCPEEdge` from each package of the graph (including
+// the endpoints of its edges) to each of its CPEs. Packages without a purl
+// cannot be identified, so they are skipped.
+func CPEEdges(g Graph) []GuacEdge {
+	edges := []GuacEdge{}
+	seen := map[[2]string]bool{}
+	add := func(n GuacNode) {
+		pkg, ok := n.(PackageNode)
+		if !ok || pkg.Purl == "" {
+			return
+		}
+		for _, cpe := range pkg.CPEs {
+			if cpe == "" || seen[[2]string{pkg.Purl, cpe}] {
+				continue
+			}
+			seen[[2]string{pkg.Purl, cpe}] = true
+			edges = append(edges, HasCPEEdge{
+				PackageNode: PackageNode{Purl: pkg.Purl},
+				CPENode:     CPENode{CPE: cpe, NodeData: pkg.NodeData},
+			})
+		}
+	}
+	for _, n := range g.Nodes {
+		add(n)
+	}
+	for _, e := range g.Edges {
+		a, b := e.Nodes()
+		add(a)
+		add(b)
+	}
+	return edges
+}
+
+// nodeIdentity returns a string that uniquely identifies the node
+func nodeIdentity(n GuacNode) (string, error) {
+	key, err := nodeKey(n)
+	if err != nil {
+		return "", err
+	}
+	return n.Type() + key, nil
+}
+
+// edgeIdentity returns a string that uniquely identifies the edge
+func edgeIdentity(e GuacEdge) (string, error) {
+	v, u := e.Nodes()
+	vID, err := nodeIdentity(v)
+	if err != nil {
+		return "", err
+	}
+	uID, err := nodeIdentity(u)
+	if err != nil {
+		return "", err
+	}
+	return e.Type() + "(" + vID + "," + uID + ")", nil
+}
+
+// IDProperty is the name of the property under which the backends store the
+// id returned by NodeID and EdgeID
+const IDProperty = "guac_id"
+
+// NodeID returns the deterministic id of the node: the hex encoded SHA-256
+// of its type and identifiable properties. The same node gets the same id in
+// every database and across runs, so external systems can reference it.
+func NodeID(n GuacNode) (string, error) {
+	id, err := nodeIdentity(n)
+	if err != nil {
+		return "", err
+	}
+	return hashID(id), nil
+}
+
+// EdgeID returns the deterministic id of the edge, derived from its type and
+// the identities of its endpoints
+func EdgeID(e GuacEdge) (string, error) {
+	id, err := edgeIdentity(e)
+	if err != nil {
+		return "", err
+	}
+	return hashID(id), nil
+}
+
+func hashID(identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:])
+}
+
 // TODO(mihaimaruseac): Write queries to write/read subgraphs from DB?
 
 // AssemblerInput represents the inputs to add to the graph
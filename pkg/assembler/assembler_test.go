@@ -0,0 +1,170 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraph_Merge(t *testing.T) {
+	a1 := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	a1Dup := ArtifactNode{Name: "other name", Digest: "SHA256:1"}
+	a2 := ArtifactNode{Name: "b", Digest: "sha256:2"}
+	b := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	noPurl := PackageNode{Name: "p"}
+
+	g := Graph{Nodes: []GuacNode{a1}}
+	g.Merge(Graph{
+		Nodes: []GuacNode{a1Dup, a2, b, noPurl},
+		Edges: []GuacEdge{BuiltByEdge{a1, b}},
+	}, Graph{
+		Nodes: []GuacNode{a2, noPurl},
+		Edges: []GuacEdge{BuiltByEdge{a1Dup, b}, BuiltByEdge{a2, b}},
+	})
+
+	wantNodes := []GuacNode{a1, a2, b, noPurl, noPurl}
+	if len(g.Nodes) != len(wantNodes) {
+		t.Fatalf("got %d nodes, want %d: %v", len(g.Nodes), len(wantNodes), g.Nodes)
+	}
+	for i := range wantNodes {
+		if g.Nodes[i].Type() != wantNodes[i].Type() {
+			t.Errorf("node %d: got %v, want %v", i, g.Nodes[i], wantNodes[i])
+		}
+	}
+	if g.Nodes[0].(ArtifactNode).Name != "a" {
+		t.Errorf("expected first occurrence of duplicate node to be kept, got %v", g.Nodes[0])
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("got %d edges, want 2: %v", len(g.Edges), g.Edges)
+	}
+}
+
+func TestGraph_Merge_Properties(t *testing.T) {
+	p := PackageNode{Name: "p", Purl: "pkg:golang/p", CPEs: []string{"cpe:1"}, Tags: []string{"a"}}
+	fromOther := PackageNode{Name: "other name", Purl: "pkg:golang/p", Version: "1.0", Digest: []string{"sha256:1"}, CPEs: []string{"cpe:2", "cpe:1"}, Tags: []string{"b"}}
+	v := VulnerabilityNode{ID: "CVE-1"}
+	affects := AffectsEdge{VulnerabilityNode: v, PackageNode: p, Ranges: []string{"<1.0"}}
+	affectsOther := AffectsEdge{VulnerabilityNode: v, PackageNode: fromOther, Ranges: []string{"<1.0", ">2.0"}}
+
+	g := Graph{Nodes: []GuacNode{p}, Edges: []GuacEdge{affects}}
+	g.Merge(Graph{Nodes: []GuacNode{fromOther, v}, Edges: []GuacEdge{affectsOther}})
+
+	wantNode := PackageNode{Name: "p", Purl: "pkg:golang/p", Version: "1.0", Digest: []string{"sha256:1"}, CPEs: []string{"cpe:1", "cpe:2"}, Tags: []string{"a", "b"}}
+	wantNodes := []GuacNode{wantNode, v}
+	if !reflect.DeepEqual(g.Nodes, wantNodes) {
+		t.Errorf("got nodes %v, want %v", g.Nodes, wantNodes)
+	}
+	wantEdges := []GuacEdge{AffectsEdge{VulnerabilityNode: v, PackageNode: wantNode, Ranges: []string{"<1.0", ">2.0"}}}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("got edges %v, want %v", g.Edges, wantEdges)
+	}
+	if !reflect.DeepEqual(p.CPEs, []string{"cpe:1"}) {
+		t.Errorf("Merge() modified the merged nodes: %v", p)
+	}
+}
+
+func TestNodeID(t *testing.T) {
+	p := PackageNode{Name: "p", Purl: "pkg:golang/p"}
+	id, err := NodeID(p)
+	if err != nil {
+		t.Fatalf("NodeID() error = %v", err)
+	}
+	// the id must not change across versions, as it is referenced by
+	// external systems
+	if want := "6335073956b310237cb0e5c4ca08040d98ca1b5a3a92b3181c14fe8819bc7c8a"; id != want {
+		t.Errorf("NodeID() = %v, want %v", id, want)
+	}
+	if other, _ := NodeID(PackageNode{Name: "other name", Purl: "pkg:golang/p"}); other != id {
+		t.Errorf("NodeID() depends on non identifiable properties: %v != %v", other, id)
+	}
+	if other, _ := NodeID(ArtifactNode{Name: "p", Digest: "pkg:golang/p"}); other == id {
+		t.Errorf("NodeID() does not depend on the node type")
+	}
+	if _, err := NodeID(PackageNode{Name: "p"}); err == nil {
+		t.Errorf("NodeID() expected error for node without identifiable properties")
+	}
+}
+
+func TestEdgeID(t *testing.T) {
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	b := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	p := PackageNode{Name: "p", Purl: "pkg:golang/p"}
+	q := PackageNode{Name: "q", Purl: "pkg:golang/q"}
+
+	id, err := EdgeID(BuiltByEdge{a, b})
+	if err != nil {
+		t.Fatalf("EdgeID() error = %v", err)
+	}
+	if other, _ := EdgeID(BuiltByEdge{ArtifactNode{Name: "other name", Digest: "sha256:1"}, b}); other != id {
+		t.Errorf("EdgeID() depends on non identifiable properties: %v != %v", other, id)
+	}
+	pq, _ := EdgeID(DependsOnEdge{PackageNode: p, PackageDependency: q})
+	qp, _ := EdgeID(DependsOnEdge{PackageNode: q, PackageDependency: p})
+	if pq == qp {
+		t.Errorf("EdgeID() does not depend on the direction of the edge")
+	}
+}
+
+func TestCPEEdges(t *testing.T) {
+	p := PackageNode{Purl: "pkg:golang/p", CPEs: []string{"cpe:2.3:a:p:p:1:*:*:*:*:*:*:*"}}
+	q := PackageNode{Purl: "pkg:golang/q", CPEs: []string{"cpe:2.3:a:q:q:1:*:*:*:*:*:*:*", "cpe:2.3:a:q:q2:1:*:*:*:*:*:*:*"}}
+	noPurl := PackageNode{Name: "r", CPEs: []string{"cpe:2.3:a:r:r:1:*:*:*:*:*:*:*"}}
+
+	edges := CPEEdges(Graph{
+		Nodes: []GuacNode{p, noPurl, ArtifactNode{Digest: "sha256:1"}},
+		Edges: []GuacEdge{DependsOnEdge{PackageNode: p, PackageDependency: q}},
+	})
+	want := []HasCPEEdge{
+		{PackageNode{Purl: p.Purl}, CPENode{CPE: p.CPEs[0]}},
+		{PackageNode{Purl: q.Purl}, CPENode{CPE: q.CPEs[0]}},
+		{PackageNode{Purl: q.Purl}, CPENode{CPE: q.CPEs[1]}},
+	}
+	if len(edges) != len(want) {
+		t.Fatalf("CPEEdges() = %v, want %v", edges, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(edges[i], want[i]) {
+			t.Errorf("CPEEdges()[%d] = %v, want %v", i, edges[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeCPE(t *testing.T) {
+	tests := []struct {
+		cpe  string
+		want string
+	}{{
+		cpe:  "cpe:2.3:a:OpenSSL:openssl:1.1.1k:*:*:*:*:*:*:*",
+		want: "cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*",
+	}, {
+		cpe:  "cpe:/a:openssl:openssl:1.1.1k",
+		want: "cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*",
+	}, {
+		cpe:  "cpe:/o:microsoft:windows_xp::sp2:pro",
+		want: "cpe:2.3:o:microsoft:windows_xp:*:sp2:pro:*:*:*:*:*",
+	}, {
+		cpe:  "cpe:/a:hp:insight_diagnostics:7.4.0.1570::~~online~win2003~x64~",
+		want: "cpe:2.3:a:hp:insight_diagnostics:7.4.0.1570:*:*:*:online:win2003:x64:*",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.cpe, func(t *testing.T) {
+			if got := normalizeCPE(tt.cpe); got != tt.want {
+				t.Errorf("normalizeCPE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
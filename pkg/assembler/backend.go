@@ -0,0 +1,134 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"context"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/guacsec/guac/pkg/assembler/postgresdb"
+)
+
+// Storer stores GUAC graphs. The assembler only depends on this interface,
+// so that the database can be swapped, or faked in tests.
+type Storer interface {
+	// Store writes the nodes of the graph, then its edges. The write stops
+	// early, with the error of ctx, when ctx is canceled; what was already
+	// written is kept.
+	Store(ctx context.Context, g Graph) error
+
+	// Close releases the connection to the database.
+	Close() error
+}
+
+// Backend is a database that can store the GUAC graph.
+type Backend interface {
+	Storer
+
+	// StoreNodes writes the nodes, merging them with existing nodes that
+	// have the same identifiable properties.
+	StoreNodes(nodes []GuacNode) error
+
+	// StoreEdges writes the edges, together with their endpoints.
+	StoreEdges(edges []GuacEdge) error
+
+	// Ping checks that the database is reachable, e.g. for the readiness
+	// probe of the server modes.
+	Ping(ctx context.Context) error
+}
+
+// storeGraph writes the nodes of the graph to the backend, then its edges,
+// unless ctx is canceled in between
+func storeGraph(ctx context.Context, b Backend, g Graph) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.StoreNodes(g.Nodes); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.StoreEdges(g.Edges)
+}
+
+type neo4jBackend struct {
+	client    graphdb.Client
+	batchSize int
+	retry     graphdb.RetryPolicy
+	edgeCache *EdgeCache
+}
+
+// NewNeo4jBackend returns a Backend that writes to Neo4j, batchSize nodes or
+// edges at a time. Batches failing with transient errors are retried
+// following the retry policy. Unless edgeCache is nil, Store skips the edges
+// identical to ones it wrote recently.
+func NewNeo4jBackend(client graphdb.Client, batchSize int, retry graphdb.RetryPolicy, edgeCache *EdgeCache) Backend {
+	return &neo4jBackend{client: client, batchSize: batchSize, retry: retry, edgeCache: edgeCache}
+}
+
+// Store writes the graph in batches, checking ctx before each batch
+func (b *neo4jBackend) Store(ctx context.Context, g Graph) error {
+	return storeGraphInBatches(ctx, g, b.client, b.batchSize, b.retry, b.edgeCache)
+}
+
+func (b *neo4jBackend) StoreNodes(nodes []GuacNode) error {
+	return StoreGraphInBatchesWithRetry(Graph{Nodes: nodes}, b.client, b.batchSize, b.retry)
+}
+
+func (b *neo4jBackend) StoreEdges(edges []GuacEdge) error {
+	return StoreGraphInBatchesWithRetry(Graph{Edges: edges}, b.client, b.batchSize, b.retry)
+}
+
+// Ping verifies the connectivity to Neo4j. The v4 driver does not take a
+// context, so the check is not canceled with ctx.
+func (b *neo4jBackend) Ping(ctx context.Context) error {
+	return b.client.VerifyConnectivity()
+}
+
+func (b *neo4jBackend) Close() error {
+	return b.client.Close()
+}
+
+type postgresBackend struct {
+	client postgresdb.Client
+}
+
+// NewPostgresBackend returns a Backend that writes to PostgreSQL. The tables
+// must have been created via `postgresdb.Migrate`.
+func NewPostgresBackend(client postgresdb.Client) Backend {
+	return &postgresBackend{client: client}
+}
+
+func (b *postgresBackend) Store(ctx context.Context, g Graph) error {
+	return storeGraph(ctx, b, g)
+}
+
+func (b *postgresBackend) StoreNodes(nodes []GuacNode) error {
+	return StoreGraphInPostgres(Graph{Nodes: nodes}, b.client)
+}
+
+func (b *postgresBackend) StoreEdges(edges []GuacEdge) error {
+	return StoreGraphInPostgres(Graph{Edges: edges}, b.client)
+}
+
+func (b *postgresBackend) Ping(ctx context.Context) error {
+	return b.client.PingContext(ctx)
+}
+
+func (b *postgresBackend) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,412 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// Bulk loads write the graphs of all the documents to staging files first,
+// then load the files into Neo4j in large batches. The batches are written
+// with the same `UNWIND` queries as StoreGraph, from the client, so that
+// neither the APOC plugin nor access to the import directory of the server
+// (for `LOAD CSV`) is needed. Each loaded batch is checkpointed, so a failed
+// load resumes after the last loaded batch.
+
+// DefaultBulkBatchSize is the number of nodes or edges written by a single
+// query of a bulk load
+const DefaultBulkBatchSize = 10000
+
+const (
+	bulkNodesFile      = "nodes.jsonl"
+	bulkEdgesFile      = "edges.jsonl"
+	bulkStagedFile     = "staged"
+	bulkCheckpointFile = "checkpoint.json"
+)
+
+// BulkStager is a Storer that writes the graphs to staging files in a
+// directory, to be loaded with LoadBulk. It is safe for concurrent use.
+type BulkStager struct {
+	dir   string
+	mu    sync.Mutex
+	files []*os.File
+	nodes *bufio.Writer
+	edges *bufio.Writer
+}
+
+// NewBulkStager returns a BulkStager writing to dir, which is created if
+// needed. Graphs previously staged in dir, and the checkpoint of their load,
+// are discarded.
+func NewBulkStager(dir string) (*BulkStager, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{bulkStagedFile, bulkCheckpointFile} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	s := &BulkStager{dir: dir}
+	for _, name := range []string{bulkNodesFile, bulkEdgesFile} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.files = append(s.files, f)
+	}
+	s.nodes = bufio.NewWriter(s.files[0])
+	s.edges = bufio.NewWriter(s.files[1])
+	return s, nil
+}
+
+// Store appends the nodes and edges of the graph to the staging files
+func (s *BulkStager) Store(ctx context.Context, g Graph) error {
+	if err := ValidateGraph(g); err != nil {
+		return err
+	}
+	// encode first, so that a failing graph is not partially staged
+	var nodes, edges bytes.Buffer
+	nodeEncoder, edgeEncoder := json.NewEncoder(&nodes), json.NewEncoder(&edges)
+	for _, n := range g.Nodes {
+		row, err := newNodeRow(n)
+		if err != nil {
+			return err
+		}
+		if err := nodeEncoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		row, err := newEdgeRow(e)
+		if err != nil {
+			return err
+		}
+		if err := edgeEncoder.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.nodes.Write(nodes.Bytes()); err != nil {
+		return err
+	}
+	_, err := s.edges.Write(edges.Bytes())
+	return err
+}
+
+// Finish flushes the staging files and marks the staging as complete, so
+// that BulkStaged reports it
+func (s *BulkStager) Finish() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range []*bufio.Writer{s.nodes, s.edges} {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := s.files[i].Sync(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(s.dir, bulkStagedFile), []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644)
+}
+
+// Close flushes and closes the staging files. Unless Finish was called, the
+// staging is incomplete and is not loaded.
+func (s *BulkStager) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for i, w := range []*bufio.Writer{s.nodes, s.edges} {
+		if w != nil {
+			errs = append(errs, w.Flush())
+		}
+		if i < len(s.files) {
+			errs = append(errs, s.files[i].Close())
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkStaged returns whether dir holds graphs whose staging completed, which
+// can be loaded (or whose load can be resumed) with LoadBulk
+func BulkStaged(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, bulkStagedFile))
+	return err == nil
+}
+
+// BulkOptions control a bulk load
+type BulkOptions struct {
+	// BatchSize is the number of nodes or edges written by a query
+	BatchSize int
+	// RowsPerSecond caps the number of nodes and edges loaded per second,
+	// to leave room for other clients of the database; 0 disables the limit
+	RowsPerSecond int
+	// Retry is the retry policy of the batches failing with transient errors
+	Retry graphdb.RetryPolicy
+}
+
+// BulkStats counts the nodes and edges loaded by LoadBulk, and those that
+// were already loaded by an earlier, failed, load
+type BulkStats struct {
+	Nodes, Edges               int64
+	SkippedNodes, SkippedEdges int64
+}
+
+// bulkCheckpoint is the number of nodes and edges loaded, from the start of
+// the staging files
+type bulkCheckpoint struct {
+	Nodes int64 `json:"nodes"`
+	Edges int64 `json:"edges"`
+}
+
+// LoadBulk loads the graphs staged in dir into Neo4j, nodes first, in
+// batches of opts.BatchSize. The position in the staging files is
+// checkpointed after each batch; when a previous load of dir failed, the
+// load resumes from its checkpoint. The indexes used to merge the nodes
+// should be created beforehand.
+func LoadBulk(ctx context.Context, client graphdb.Client, dir string, opts BulkOptions) (BulkStats, error) {
+	session := client.NewSession(neo4j.SessionConfig{})
+	defer session.Close()
+	return loadBulk(ctx, dir, opts, func(queries []string, params []map[string]interface{}) error {
+		return opts.Retry.WithRetry(func() error {
+			return runWriteQueries(session, queries, params)
+		})
+	})
+}
+
+// loadBulk is LoadBulk, running the queries of each batch with write
+func loadBulk(ctx context.Context, dir string, opts BulkOptions, write func([]string, []map[string]interface{}) error) (BulkStats, error) {
+	var stats BulkStats
+	if opts.BatchSize <= 0 {
+		return stats, fmt.Errorf("invalid batch size %d", opts.BatchSize)
+	}
+	if !BulkStaged(dir) {
+		return stats, fmt.Errorf("no complete staging in %s", dir)
+	}
+	cp, err := readBulkCheckpoint(dir)
+	if err != nil {
+		return stats, err
+	}
+	stats.SkippedNodes, stats.SkippedEdges = cp.Nodes, cp.Edges
+	limiter := newRateLimiter(opts.RowsPerSecond)
+
+	err = loadStagedRows(ctx, filepath.Join(dir, bulkNodesFile), cp.Nodes, opts.BatchSize, func(lines [][]byte) error {
+		rows := make([]nodeRow, len(lines))
+		for i, line := range lines {
+			if err := decodeStagedRow(line, &rows[i]); err != nil {
+				return err
+			}
+		}
+		queries, params := nodeRowQueries(rows)
+		if err := write(queries, params); err != nil {
+			return err
+		}
+		cp.Nodes += int64(len(rows))
+		stats.Nodes += int64(len(rows))
+		if err := writeBulkCheckpoint(dir, cp); err != nil {
+			return err
+		}
+		return limiter.wait(ctx, len(rows))
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = loadStagedRows(ctx, filepath.Join(dir, bulkEdgesFile), cp.Edges, opts.BatchSize, func(lines [][]byte) error {
+		rows := make([]edgeRow, len(lines))
+		for i, line := range lines {
+			if err := decodeStagedRow(line, &rows[i]); err != nil {
+				return err
+			}
+		}
+		queries, params := edgeRowQueries(rows)
+		if err := write(queries, params); err != nil {
+			return err
+		}
+		cp.Edges += int64(len(rows))
+		stats.Edges += int64(len(rows))
+		if err := writeBulkCheckpoint(dir, cp); err != nil {
+			return err
+		}
+		return limiter.wait(ctx, len(rows))
+	})
+	return stats, err
+}
+
+// loadStagedRows reads the lines of the staging file, skipping the first
+// skip ones, and calls load with batches of at most batchSize lines. It
+// stops before the next batch once ctx is canceled.
+func loadStagedRows(ctx context.Context, path string, skip int64, batchSize int, load func([][]byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var read int64
+	batch := [][]byte{}
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			read++
+			if read > skip {
+				batch = append(batch, line)
+			}
+		}
+		if len(batch) > 0 && (len(batch) == batchSize || err != nil) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := load(batch); err != nil {
+				return err
+			}
+			batch = [][]byte{}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// decodeStagedRow decodes a line of a staging file into a nodeRow or an
+// edgeRow. Numbers are decoded as int64 when they are integers, as they were
+// before staging, rather than as float64.
+func decodeStagedRow(line []byte, row interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(row); err != nil {
+		return fmt.Errorf("invalid staged row: %w", err)
+	}
+	switch row := row.(type) {
+	case *nodeRow:
+		stagedValue(row.Key)
+		stagedValue(row.Props)
+	case *edgeRow:
+		stagedValue(row.A)
+		stagedValue(row.B)
+		stagedValue(row.Props)
+	}
+	return nil
+}
+
+// stagedValue converts the json.Number values to int64 or float64, in place
+// for maps and slices
+func stagedValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = stagedValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stagedValue(item)
+		}
+	}
+	return v
+}
+
+func readBulkCheckpoint(dir string) (bulkCheckpoint, error) {
+	var cp bulkCheckpoint
+	b, err := os.ReadFile(filepath.Join(dir, bulkCheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return cp, fmt.Errorf("invalid bulk load checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// writeBulkCheckpoint replaces the checkpoint atomically, so that a crash
+// leaves either the previous or the new checkpoint
+func writeBulkCheckpoint(dir string, cp bulkCheckpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, bulkCheckpointFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, bulkCheckpointFile))
+}
+
+// rateLimiter spaces the batches so that the average rate since the start of
+// the load stays under rowsPerSecond
+type rateLimiter struct {
+	rowsPerSecond int
+	start         time.Time
+	rows          int64
+}
+
+func newRateLimiter(rowsPerSecond int) *rateLimiter {
+	return &rateLimiter{rowsPerSecond: rowsPerSecond, start: time.Now()}
+}
+
+// wait records that n rows were loaded, and sleeps until loading them fits
+// in the rate
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l.rowsPerSecond <= 0 {
+		return nil
+	}
+	l.rows += int64(n)
+	due := l.start.Add(time.Duration(float64(l.rows) / float64(l.rowsPerSecond) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
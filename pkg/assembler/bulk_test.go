@@ -0,0 +1,114 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBulkLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	b := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	p := PackageNode{Name: "p", Purl: "pkg:golang/p"}
+	meta := MetadataNode{MetadataType: "scorecard", ID: "p", Details: map[string]interface{}{"score": 8, "ratio": 0.5}}
+
+	stager, err := NewBulkStager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stager.Store(ctx, Graph{Nodes: []GuacNode{a, b}, Edges: []GuacEdge{BuiltByEdge{a, b}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stager.Store(ctx, Graph{Nodes: []GuacNode{p, meta}, Edges: []GuacEdge{MetadataForEdge{MetadataNode: meta, ForPackage: p}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stager.Store(ctx, Graph{Nodes: []GuacNode{typoNode{p}}}); err == nil {
+		t.Errorf("expected graphs with unknown node types to be rejected")
+	}
+	if BulkStaged(dir) {
+		t.Fatalf("expected the staging to be incomplete before Finish")
+	}
+	if _, err := loadBulk(ctx, dir, BulkOptions{BatchSize: 1}, nil); err == nil {
+		t.Fatalf("expected incomplete stagings not to be loaded")
+	}
+	if err := stager.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stager.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !BulkStaged(dir) {
+		t.Fatalf("expected the staging to be complete")
+	}
+
+	// the first load fails on its second batch
+	var rows []map[string]interface{}
+	writes := 0
+	write := func(queries []string, params []map[string]interface{}) error {
+		writes++
+		if writes == 2 {
+			return errors.New("connection lost")
+		}
+		for _, p := range params {
+			for _, row := range p["batch"].([]interface{}) {
+				rows = append(rows, row.(map[string]interface{}))
+			}
+		}
+		return nil
+	}
+	opts := BulkOptions{BatchSize: 2}
+	stats, err := loadBulk(ctx, dir, opts, write)
+	if err == nil {
+		t.Fatalf("expected the load to fail")
+	}
+	if stats.Nodes != 2 || stats.Edges != 0 {
+		t.Errorf("got %+v loaded before the failure, want 2 nodes", stats)
+	}
+
+	// the second load resumes after the first batch
+	stats, err = loadBulk(ctx, dir, opts, write)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := BulkStats{Nodes: 2, Edges: 2, SkippedNodes: 2}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+	if len(rows) != 6 {
+		t.Fatalf("got %d rows loaded, want 4 nodes and 2 edges", len(rows))
+	}
+	props := rows[3]["props"].(map[string]interface{})
+	if props["score"] != int64(8) || props["ratio"] != 0.5 {
+		t.Errorf("got metadata properties %v, want the integer score and float ratio", props)
+	}
+	if id, _ := NodeID(meta); rows[3]["n"].(map[string]interface{})[IDProperty] != id {
+		t.Errorf("got merge key %v, want the id %s", rows[3]["n"], id)
+	}
+
+	// a new staging discards the previous one
+	stager, err = NewBulkStager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stager.Close()
+	if BulkStaged(dir) {
+		t.Errorf("expected the new staging to be incomplete")
+	}
+}
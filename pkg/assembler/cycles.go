@@ -0,0 +1,224 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CycleMode controls how the cycles among the DependsOn edges of a graph are
+// handled. Cycles would make queries following the dependencies loop
+// forever.
+type CycleMode string
+
+const (
+	// CycleWarn reports the cycles and keeps the graph as is
+	CycleWarn CycleMode = "warn"
+	// CycleReject rejects the graphs containing cycles
+	CycleReject CycleMode = "reject"
+	// CycleBreak removes the edge closing each cycle
+	CycleBreak CycleMode = "break"
+)
+
+// ErrDependencyCycle is returned by CheckDependencyCycles when the graph has
+// cycles and the mode is CycleReject
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// DependencyCycle is a cycle of DependsOn edges: each node depends on the
+// next one, and the last node depends on the first one through Edge, the
+// edge closing the cycle.
+type DependencyCycle struct {
+	Nodes []GuacNode
+	Edge  GuacEdge
+}
+
+// String lists the nodes of the cycle, e.g. "pkg:npm/a@1 -> pkg:npm/b@1 ->
+// pkg:npm/a@1"
+func (c DependencyCycle) String() string {
+	names := make([]string, 0, len(c.Nodes)+1)
+	for _, n := range c.Nodes {
+		names = append(names, nodeName(n))
+	}
+	if len(c.Nodes) > 0 {
+		names = append(names, nodeName(c.Nodes[0]))
+	}
+	return strings.Join(names, " -> ")
+}
+
+// nodeName returns a human readable name for the node
+func nodeName(n GuacNode) string {
+	switch n := n.(type) {
+	case PackageNode:
+		if n.Purl != "" {
+			return n.Purl
+		}
+		if n.Version != "" {
+			return n.Name + "@" + n.Version
+		}
+		return n.Name
+	case ArtifactNode:
+		if n.Name != "" {
+			return n.Name
+		}
+		return n.Digest
+	}
+	if id, err := nodeIdentity(n); err == nil {
+		return id
+	}
+	return n.Type()
+}
+
+// FindDependencyCycles returns the cycles among the DependsOn edges of the
+// graph.
+//
+// The dependencies are walked depth first, starting from the nodes that no
+// other node depends on, in the order of the edges. Each edge leading back
+// to a node that is being walked closes a cycle. Removing these edges makes
+// the graph acyclic, so a node taking part in several cycles may be
+// reported more than once, but not every cycle through it is. Endpoints
+// that cannot be identified are skipped.
+func FindDependencyCycles(g Graph) []DependencyCycle {
+	type dependency struct {
+		edge int
+		to   string
+	}
+	nodes := map[string]GuacNode{}
+	order := []string{}
+	deps := map[string][]dependency{}
+	dependedOn := map[string]bool{}
+	add := func(n GuacNode) (string, bool) {
+		id, err := nodeIdentity(n)
+		if err != nil {
+			return "", false
+		}
+		if _, ok := nodes[id]; !ok {
+			nodes[id] = n
+			order = append(order, id)
+		}
+		return id, true
+	}
+	for i, e := range g.Edges {
+		if e.Type() != EdgeTypeDependsOn {
+			continue
+		}
+		v, u := e.Nodes()
+		from, ok := add(v)
+		if !ok {
+			continue
+		}
+		to, ok := add(u)
+		if !ok {
+			continue
+		}
+		deps[from] = append(deps[from], dependency{edge: i, to: to})
+		dependedOn[to] = true
+	}
+
+	const (
+		unvisited = iota
+		walking
+		done
+	)
+	state := map[string]int{}
+	path := []string{}
+	cycles := []DependencyCycle{}
+	var walk func(id string)
+	walk = func(id string) {
+		state[id] = walking
+		path = append(path, id)
+		for _, d := range deps[id] {
+			switch state[d.to] {
+			case unvisited:
+				walk(d.to)
+			case walking:
+				start := len(path) - 1
+				for path[start] != d.to {
+					start--
+				}
+				cycle := DependencyCycle{Edge: g.Edges[d.edge]}
+				for _, p := range path[start:] {
+					cycle.Nodes = append(cycle.Nodes, nodes[p])
+				}
+				cycles = append(cycles, cycle)
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+	}
+
+	// Roots first, so that the edges closing the cycles point back towards
+	// them. Nodes left unvisited only belong to cycles without roots.
+	for _, id := range order {
+		if !dependedOn[id] && state[id] == unvisited {
+			walk(id)
+		}
+	}
+	for _, id := range order {
+		if state[id] == unvisited {
+			walk(id)
+		}
+	}
+	return cycles
+}
+
+// BreakDependencyCycles returns a copy of the graph without the edges
+// closing the cycles found by FindDependencyCycles, together with these
+// cycles. The nodes are all kept.
+func BreakDependencyCycles(g Graph) (Graph, []DependencyCycle) {
+	cycles := FindDependencyCycles(g)
+	if len(cycles) == 0 {
+		return g, cycles
+	}
+	closing := map[string]bool{}
+	for _, c := range cycles {
+		if id, err := edgeIdentity(c.Edge); err == nil {
+			closing[id] = true
+		}
+	}
+	broken := Graph{Nodes: g.Nodes, Edges: make([]GuacEdge, 0, len(g.Edges)-len(closing))}
+	for _, e := range g.Edges {
+		if e.Type() == EdgeTypeDependsOn {
+			if id, err := edgeIdentity(e); err == nil && closing[id] {
+				continue
+			}
+		}
+		broken.Edges = append(broken.Edges, e)
+	}
+	return broken, cycles
+}
+
+// CheckDependencyCycles looks for cycles among the DependsOn edges of the
+// graph and handles them following mode. It returns the graph to store and
+// the cycles found, so that the caller can report them. With CycleReject,
+// an error wrapping ErrDependencyCycle is returned when there are cycles.
+func CheckDependencyCycles(g Graph, mode CycleMode) (Graph, []DependencyCycle, error) {
+	switch mode {
+	case CycleWarn:
+		return g, FindDependencyCycles(g), nil
+	case CycleReject:
+		cycles := FindDependencyCycles(g)
+		if len(cycles) > 0 {
+			return g, cycles, fmt.Errorf("%w: %s (%d cycles in total)", ErrDependencyCycle, cycles[0], len(cycles))
+		}
+		return g, cycles, nil
+	case CycleBreak:
+		g, cycles := BreakDependencyCycles(g)
+		return g, cycles, nil
+	}
+	return g, nil, fmt.Errorf("unknown dependency cycle mode %q", mode)
+}
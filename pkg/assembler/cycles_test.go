@@ -0,0 +1,107 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckDependencyCycles(t *testing.T) {
+	pkg := func(name string) PackageNode {
+		return PackageNode{Name: name, Purl: "pkg:npm/" + name + "@1"}
+	}
+	dep := func(from, to PackageNode) DependsOnEdge {
+		return DependsOnEdge{PackageNode: from, PackageDependency: to}
+	}
+	a, b, c, d := pkg("a"), pkg("b"), pkg("c"), pkg("d")
+	art := ArtifactNode{Name: "img", Digest: "sha256:1"}
+
+	tests := []struct {
+		name       string
+		edges      []GuacEdge
+		mode       CycleMode
+		wantCycles []string
+		wantEdges  int
+		wantErr    error
+	}{{
+		name:      "acyclic",
+		edges:     []GuacEdge{dep(a, b), dep(b, c), dep(a, c)},
+		mode:      CycleReject,
+		wantEdges: 3,
+	}, {
+		name:       "warn keeps the cycle",
+		edges:      []GuacEdge{dep(a, b), dep(b, c), dep(c, b)},
+		mode:       CycleWarn,
+		wantCycles: []string{"pkg:npm/b@1 -> pkg:npm/c@1 -> pkg:npm/b@1"},
+		wantEdges:  3,
+	}, {
+		name:       "reject",
+		edges:      []GuacEdge{dep(a, b), dep(b, c), dep(c, b)},
+		mode:       CycleReject,
+		wantCycles: []string{"pkg:npm/b@1 -> pkg:npm/c@1 -> pkg:npm/b@1"},
+		wantEdges:  3,
+		wantErr:    ErrDependencyCycle,
+	}, {
+		name:       "break removes the edge back towards the root",
+		edges:      []GuacEdge{dep(c, a), dep(a, b), dep(b, c), dep(d, a)},
+		mode:       CycleBreak,
+		wantCycles: []string{"pkg:npm/a@1 -> pkg:npm/b@1 -> pkg:npm/c@1 -> pkg:npm/a@1"},
+		wantEdges:  3,
+	}, {
+		name:       "self dependency",
+		edges:      []GuacEdge{dep(a, a), DependsOnEdge{ArtifactNode: art, PackageDependency: a}},
+		mode:       CycleBreak,
+		wantCycles: []string{"pkg:npm/a@1 -> pkg:npm/a@1"},
+		wantEdges:  1,
+	}, {
+		name:       "cycle without root",
+		edges:      []GuacEdge{dep(a, b), dep(b, a), dep(c, d)},
+		mode:       CycleBreak,
+		wantCycles: []string{"pkg:npm/a@1 -> pkg:npm/b@1 -> pkg:npm/a@1"},
+		wantEdges:  2,
+	}, {
+		name:      "other edges are ignored",
+		edges:     []GuacEdge{ContainsEdge{PackageNode: a, ContainedArtifact: art}, DependsOnEdge{ArtifactNode: art, PackageDependency: a}},
+		mode:      CycleReject,
+		wantEdges: 2,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, cycles, err := CheckDependencyCycles(Graph{Edges: tt.edges}, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckDependencyCycles() error = %v, want %v", err, tt.wantErr)
+			}
+			got := []string{}
+			for _, c := range cycles {
+				got = append(got, c.String())
+			}
+			if len(tt.wantCycles) == 0 {
+				tt.wantCycles = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.wantCycles) {
+				t.Errorf("CheckDependencyCycles() cycles = %v, want %v", got, tt.wantCycles)
+			}
+			if len(g.Edges) != tt.wantEdges {
+				t.Errorf("CheckDependencyCycles() kept %d edges, want %d", len(g.Edges), tt.wantEdges)
+			}
+			if tt.mode == CycleBreak && len(FindDependencyCycles(g)) != 0 {
+				t.Errorf("CheckDependencyCycles() left cycles in %v", g.Edges)
+			}
+		})
+	}
+}
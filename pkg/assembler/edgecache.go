@@ -0,0 +1,159 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/metrics"
+)
+
+const (
+	// DefaultEdgeCacheSize is the default number of edges remembered by an
+	// EdgeCache
+	DefaultEdgeCacheSize = 50000
+	// DefaultEdgeCacheTTL is the default time for which an EdgeCache
+	// remembers an edge
+	DefaultEdgeCacheTTL = 10 * time.Minute
+)
+
+// EdgeCache remembers the edges recently written to Neo4j, so that writing
+// an identical edge again, e.g. the same dependency declared by the SBOMs of
+// several documents, can be skipped.
+//
+// Edges are keyed on the digest of everything their MERGE writes: their
+// type, the merge keys of both endpoints and all their properties. An edge
+// is only skipped if the same write was committed at most ttl ago; an edge
+// differing in any property, even its source metadata, is written again. At
+// most size edges are remembered, the least recently written are forgotten
+// first.
+//
+// The cache assumes that nothing else deletes edges from the database within
+// ttl. It is safe for concurrent use.
+type EdgeCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the edgeCacheEntry values, most recently written first
+	order *list.List
+}
+
+type edgeCacheEntry struct {
+	key     string
+	written time.Time
+}
+
+// NewEdgeCache returns an empty cache remembering at most size edges, each
+// for ttl
+func NewEdgeCache(size int, ttl time.Duration) *EdgeCache {
+	return &EdgeCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Len returns the number of edges remembered, including the expired ones
+// not evicted yet
+func (c *EdgeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// contains tells whether the write of key was committed within ttl, counting
+// the hit or miss
+func (c *EdgeCache) contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		if c.now().Sub(el.Value.(*edgeCacheEntry).written) < c.ttl {
+			metrics.EdgeCacheHits.Inc()
+			return true
+		}
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	metrics.EdgeCacheMisses.Inc()
+	return false
+}
+
+// add records the writes of keys as committed now
+func (c *EdgeCache) add(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			el.Value.(*edgeCacheEntry).written = now
+			c.order.MoveToFront(el)
+			continue
+		}
+		c.entries[key] = c.order.PushFront(&edgeCacheEntry{key: key, written: now})
+		for c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*edgeCacheEntry).key)
+		}
+	}
+}
+
+// edgeRowKey returns the cache key of the write of the row. Since
+// `json.Marshal` sorts map keys, identical writes have the same key.
+func edgeRowKey(row edgeRow) (string, error) {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(b)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// uncachedEdgeRows returns the rows of the edges whose write is not in the
+// cache, together with their cache keys. All the rows are returned if cache
+// is nil.
+func uncachedEdgeRows(edges []GuacEdge, cache *EdgeCache) ([]edgeRow, []string, error) {
+	rows := make([]edgeRow, 0, len(edges))
+	keys := make([]string, 0, len(edges))
+	for _, e := range edges {
+		row, err := newEdgeRow(e)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cache != nil {
+			key, err := edgeRowKey(row)
+			if err != nil {
+				return nil, nil, err
+			}
+			if cache.contains(key) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		rows = append(rows, row)
+	}
+	return rows, keys, nil
+}
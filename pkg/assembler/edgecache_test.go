@@ -0,0 +1,93 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEdgeCache(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	c := NewEdgeCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.add([]string{"a", "b"})
+	if !c.contains("a") || !c.contains("b") {
+		t.Fatalf("expected the written edges to be cached")
+	}
+	if c.contains("c") {
+		t.Errorf("expected an edge not written to be missing")
+	}
+
+	// writing a again makes b the least recently written
+	c.add([]string{"a", "c"})
+	if c.Len() != 2 {
+		t.Errorf("cache holds %d edges, want 2", c.Len())
+	}
+	if c.contains("b") {
+		t.Errorf("expected the least recently written edge to be evicted")
+	}
+	if !c.contains("a") || !c.contains("c") {
+		t.Errorf("expected the recently written edges to be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if c.contains("a") {
+		t.Errorf("expected the edge written a ttl ago to be expired")
+	}
+	if c.Len() != 1 {
+		t.Errorf("cache holds %d edges, want the expired edge removed", c.Len())
+	}
+}
+
+func Test_uncachedEdgeRows(t *testing.T) {
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	b := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	other := BuilderNode{BuilderType: "type", BuilderId: "other"}
+	c := NewEdgeCache(10, time.Minute)
+
+	rows, keys, err := uncachedEdgeRows([]GuacEdge{BuiltByEdge{a, b}}, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || len(keys) != 1 {
+		t.Fatalf("expected the edge to be written, got %d rows", len(rows))
+	}
+
+	// the edge is only cached once its batch is committed
+	rows, _, _ = uncachedEdgeRows([]GuacEdge{BuiltByEdge{a, b}}, c)
+	if len(rows) != 1 {
+		t.Errorf("expected the edge not committed yet to be written again")
+	}
+	c.add(keys)
+
+	rows, keys, err = uncachedEdgeRows([]GuacEdge{BuiltByEdge{a, b}, BuiltByEdge{a, other}}, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || len(keys) != 1 {
+		t.Fatalf("expected only the new edge to be written, got %d rows", len(rows))
+	}
+	if rows[0].B[IDProperty] == nil || rows[0].B["id"] != "other" {
+		t.Errorf("got row %v, want the edge to the other builder", rows[0])
+	}
+
+	rows, keys, _ = uncachedEdgeRows([]GuacEdge{BuiltByEdge{a, b}}, nil)
+	if len(rows) != 1 || len(keys) != 0 {
+		t.Errorf("expected all the edges to be written without cache")
+	}
+}
@@ -0,0 +1,85 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// propertyNameRegex matches the property names that can be written without
+// quoting in the Cypher queries
+var propertyNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnrichedNode is a node with additional properties, e.g. the organizational
+// metadata added by enrichers. The properties of the wrapped node take
+// precedence, so that the identity of the node cannot change.
+type EnrichedNode struct {
+	GuacNode
+	Extra map[string]interface{}
+}
+
+// WithProperties returns n with the additional properties. Properties added
+// to an EnrichedNode are merged with its previous ones, the later values
+// taking precedence.
+func WithProperties(n GuacNode, properties map[string]interface{}) (GuacNode, error) {
+	for k := range properties {
+		if !propertyNameRegex.MatchString(k) || k == IDProperty {
+			return nil, fmt.Errorf("invalid property name %q for %s node", k, n.Type())
+		}
+	}
+	if len(properties) == 0 {
+		return n, nil
+	}
+	extra := map[string]interface{}{}
+	if en, ok := n.(EnrichedNode); ok {
+		n = en.GuacNode
+		for k, v := range en.Extra {
+			extra[k] = v
+		}
+	}
+	for k, v := range properties {
+		extra[k] = v
+	}
+	return EnrichedNode{GuacNode: n, Extra: extra}, nil
+}
+
+func (en EnrichedNode) Properties() map[string]interface{} {
+	properties := en.GuacNode.Properties()
+	for k, v := range en.Extra {
+		if _, ok := properties[k]; !ok {
+			properties[k] = v
+		}
+	}
+	return properties
+}
+
+func (en EnrichedNode) PropertyNames() []string {
+	fields := en.GuacNode.PropertyNames()
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f] = true
+	}
+	extra := []string{}
+	for k := range en.Extra {
+		if !known[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	return append(fields, extra...)
+}
@@ -0,0 +1,102 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithProperties(t *testing.T) {
+	pkg := PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+
+	testCases := []struct {
+		name      string
+		node      GuacNode
+		props     map[string]interface{}
+		wantProps map[string]interface{}
+		wantNames []string
+		wantErr   string
+	}{{
+		name:      "no properties",
+		node:      pkg,
+		wantProps: map[string]interface{}{"name": "app", "purl": "pkg:golang/app@v1"},
+		wantNames: pkg.PropertyNames(),
+	}, {
+		name:  "added properties",
+		node:  pkg,
+		props: map[string]interface{}{"team": "payments", "tier": 1},
+		wantProps: map[string]interface{}{
+			"name": "app", "purl": "pkg:golang/app@v1", "team": "payments", "tier": 1,
+		},
+		wantNames: append(pkg.PropertyNames(), "team", "tier"),
+	}, {
+		name:      "parser properties take precedence",
+		node:      pkg,
+		props:     map[string]interface{}{"purl": "pkg:golang/other@v1", "team": "payments"},
+		wantProps: map[string]interface{}{"name": "app", "purl": "pkg:golang/app@v1", "team": "payments"},
+		wantNames: append(pkg.PropertyNames(), "team"),
+	}, {
+		name:  "merged with earlier properties",
+		node:  EnrichedNode{GuacNode: pkg, Extra: map[string]interface{}{"team": "payments", "tier": 1}},
+		props: map[string]interface{}{"tier": 2},
+		wantProps: map[string]interface{}{
+			"name": "app", "purl": "pkg:golang/app@v1", "team": "payments", "tier": 2,
+		},
+		wantNames: append(pkg.PropertyNames(), "team", "tier"),
+	}, {
+		name:    "invalid name",
+		node:    pkg,
+		props:   map[string]interface{}{"team}) DETACH DELETE n //": "x"},
+		wantErr: "invalid property name",
+	}, {
+		name:    "reserved name",
+		node:    pkg,
+		props:   map[string]interface{}{IDProperty: "x"},
+		wantErr: "invalid property name",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WithProperties(tt.node, tt.props)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("WithProperties() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithProperties() unexpected error: %v", err)
+			}
+			if got.Type() != NodeTypePackage {
+				t.Errorf("Type() = %s, want %s", got.Type(), NodeTypePackage)
+			}
+			if !reflect.DeepEqual(got.Properties(), tt.wantProps) {
+				t.Errorf("Properties() = %v, want %v", got.Properties(), tt.wantProps)
+			}
+			if !reflect.DeepEqual(got.PropertyNames(), tt.wantNames) {
+				t.Errorf("PropertyNames() = %v, want %v", got.PropertyNames(), tt.wantNames)
+			}
+			gotID, err := NodeID(got)
+			if err != nil {
+				t.Fatalf("NodeID() unexpected error: %v", err)
+			}
+			if wantID, _ := NodeID(pkg); gotID != wantID {
+				t.Errorf("NodeID() = %s, want %s", gotID, wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// GraphFileVersion is the version of the format written by WriteGraph. It is
+// incremented on incompatible changes, so that ReadGraph can reject files it
+// does not understand.
+const GraphFileVersion = 1
+
+// graphFile is the JSON document written by WriteGraph. Nodes are listed
+// once, with their NodeID; edges reference their endpoints by these ids.
+type graphFile struct {
+	Version int             `json:"version"`
+	Nodes   []graphFileNode `json:"nodes"`
+	Edges   []graphFileEdge `json:"edges"`
+}
+
+type graphFileNode struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Identifiable []string               `json:"identifiable"`
+	Properties   map[string]interface{} `json:"properties"`
+}
+
+type graphFileEdge struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	From         string                 `json:"from"`
+	To           string                 `json:"to"`
+	Identifiable []string               `json:"identifiable"`
+	Properties   map[string]interface{} `json:"properties"`
+}
+
+// WriteGraph writes the graph to w as a JSON document that ReadGraph loads
+// back. The endpoints of the edges are written as nodes too, and nodes with
+// the same identity are written once, with the properties of the last one.
+func WriteGraph(w io.Writer, g Graph) error {
+	if err := ValidateGraph(g); err != nil {
+		return err
+	}
+	f := graphFile{Version: GraphFileVersion, Nodes: []graphFileNode{}, Edges: []graphFileEdge{}}
+	index := map[string]int{}
+	addNode := func(n GuacNode) (string, error) {
+		id, err := NodeID(n)
+		if err != nil {
+			return "", err
+		}
+		node := graphFileNode{
+			ID:           id,
+			Type:         n.Type(),
+			Identifiable: n.IdentifiablePropertyNames(),
+			Properties:   n.Properties(),
+		}
+		if i, ok := index[id]; ok {
+			f.Nodes[i] = node
+		} else {
+			index[id] = len(f.Nodes)
+			f.Nodes = append(f.Nodes, node)
+		}
+		return id, nil
+	}
+
+	for _, n := range g.Nodes {
+		if _, err := addNode(n); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		v, u := e.Nodes()
+		var from, to string
+		var err error
+		// keep the properties of the node in g.Nodes, which may be more
+		// complete than the endpoint
+		if from, err = NodeID(v); err != nil {
+			return err
+		}
+		if _, ok := index[from]; !ok {
+			if _, err := addNode(v); err != nil {
+				return err
+			}
+		}
+		if to, err = NodeID(u); err != nil {
+			return err
+		}
+		if _, ok := index[to]; !ok {
+			if _, err := addNode(u); err != nil {
+				return err
+			}
+		}
+		id, err := EdgeID(e)
+		if err != nil {
+			return err
+		}
+		f.Edges = append(f.Edges, graphFileEdge{
+			ID:           id,
+			Type:         e.Type(),
+			From:         from,
+			To:           to,
+			Identifiable: e.IdentifiablePropertyNames(),
+			Properties:   e.Properties(),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(f)
+}
+
+// ReadGraph loads a graph written by WriteGraph. The nodes and edges are
+// generic: they have the types and properties of the written ones, and the
+// same NodeID and EdgeID, but numbers in their properties are float64 and
+// lists are []interface{}, as decoded from JSON.
+func ReadGraph(r io.Reader) (Graph, error) {
+	var f graphFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return Graph{}, fmt.Errorf("unable to decode graph file: %w", err)
+	}
+	if f.Version != GraphFileVersion {
+		return Graph{}, fmt.Errorf("unsupported graph file version %d, expected %d", f.Version, GraphFileVersion)
+	}
+
+	g := Graph{
+		Nodes: make([]GuacNode, 0, len(f.Nodes)),
+		Edges: make([]GuacEdge, 0, len(f.Edges)),
+	}
+	nodes := map[string]GuacNode{}
+	for _, n := range f.Nodes {
+		node := fileNode{typ: n.Type, properties: n.Properties, identifiable: n.Identifiable}
+		nodes[n.ID] = node
+		g.Nodes = append(g.Nodes, node)
+	}
+	for _, e := range f.Edges {
+		from, ok := nodes[e.From]
+		if !ok {
+			return Graph{}, fmt.Errorf("edge %s references unknown node %s", e.ID, e.From)
+		}
+		to, ok := nodes[e.To]
+		if !ok {
+			return Graph{}, fmt.Errorf("edge %s references unknown node %s", e.ID, e.To)
+		}
+		g.Edges = append(g.Edges, fileEdge{
+			typ:          e.Type,
+			from:         from,
+			to:           to,
+			properties:   e.Properties,
+			identifiable: e.Identifiable,
+		})
+	}
+	if err := ValidateGraph(g); err != nil {
+		return Graph{}, err
+	}
+	return g, nil
+}
+
+// fileNode is a node loaded by ReadGraph
+type fileNode struct {
+	typ          string
+	properties   map[string]interface{}
+	identifiable []string
+}
+
+func (n fileNode) Type() string {
+	return n.typ
+}
+
+func (n fileNode) Properties() map[string]interface{} {
+	return n.properties
+}
+
+func (n fileNode) PropertyNames() []string {
+	return sortedKeys(n.properties)
+}
+
+func (n fileNode) IdentifiablePropertyNames() []string {
+	return n.identifiable
+}
+
+// fileEdge is an edge loaded by ReadGraph
+type fileEdge struct {
+	typ          string
+	from, to     GuacNode
+	properties   map[string]interface{}
+	identifiable []string
+}
+
+func (e fileEdge) Type() string {
+	return e.typ
+}
+
+func (e fileEdge) Nodes() (v, u GuacNode) {
+	return e.from, e.to
+}
+
+func (e fileEdge) Properties() map[string]interface{} {
+	return e.properties
+}
+
+func (e fileEdge) PropertyNames() []string {
+	return sortedKeys(e.properties)
+}
+
+func (e fileEdge) IdentifiablePropertyNames() []string {
+	return e.identifiable
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FileBackend is a Backend that collects the stored graphs in memory, then
+// writes the combined graph to a file with WriteGraph when closed. It needs
+// no database, so that the output of an ingestion can be kept as an
+// artifact, diffed across builds or loaded later with ReadGraph.
+type FileBackend struct {
+	*MemoryBackend
+	path string
+}
+
+// NewFileBackend returns a FileBackend writing to path. The directory of
+// path must exist; the file is only written by Close.
+func NewFileBackend(path string) (*FileBackend, error) {
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", filepath.Dir(path))
+	}
+	return &FileBackend{MemoryBackend: NewMemoryBackend(), path: path}, nil
+}
+
+// Close writes the combined graph to the file. The nodes are written with
+// their properties merged from all the times they were stored. The file is
+// replaced atomically, so a failed write keeps the previous one.
+func (b *FileBackend) Close() error {
+	g := b.Graph()
+	for i, n := range g.Nodes {
+		properties, _ := b.NodeProperties(n)
+		g.Nodes[i] = fileNode{typ: n.Type(), properties: properties, identifiable: n.IdentifiablePropertyNames()}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), "."+filepath.Base(b.path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := WriteGraph(tmp, g); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write graph to %s: %w", b.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
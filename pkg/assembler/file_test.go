@@ -0,0 +1,144 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteReadGraph(t *testing.T) {
+	app := PackageNode{Name: "app", Purl: "pkg:golang/app@v1", Tags: []string{"prod"}}
+	lib := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+	vuln := VulnerabilityNode{ID: "CVE-2022-1234"}
+	g := Graph{
+		Nodes: []GuacNode{app, lib},
+		Edges: []GuacEdge{
+			DependsOnEdge{PackageNode: app, PackageDependency: lib},
+			VulnerableToEdge{PackageNode: lib, VulnerabilityNode: vuln},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, g); err != nil {
+		t.Fatalf("WriteGraph() error = %v", err)
+	}
+	loaded, err := ReadGraph(&buf)
+	if err != nil {
+		t.Fatalf("ReadGraph() error = %v", err)
+	}
+
+	// the vulnerability is only an endpoint, it is written as a node too
+	if len(loaded.Nodes) != 3 || len(loaded.Edges) != 2 {
+		t.Fatalf("got %d nodes and %d edges, want 3 and 2", len(loaded.Nodes), len(loaded.Edges))
+	}
+	for i, n := range []GuacNode{app, lib, vuln} {
+		want, _ := NodeID(n)
+		got, err := NodeID(loaded.Nodes[i])
+		if err != nil || got != want {
+			t.Errorf("NodeID(loaded node %d) = %v, %v, want %v", i, got, err, want)
+		}
+		if loaded.Nodes[i].Type() != n.Type() {
+			t.Errorf("loaded node %d has type %v, want %v", i, loaded.Nodes[i].Type(), n.Type())
+		}
+	}
+	for i, e := range g.Edges {
+		want, _ := EdgeID(e)
+		got, err := EdgeID(loaded.Edges[i])
+		if err != nil || got != want {
+			t.Errorf("EdgeID(loaded edge %d) = %v, %v, want %v", i, got, err, want)
+		}
+	}
+	if tags := loaded.Nodes[0].Properties()["tags"]; len(tags.([]interface{})) != 1 {
+		t.Errorf("loaded tags = %v, want [prod]", tags)
+	}
+}
+
+func TestReadGraph_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{{
+		name:    "not json",
+		input:   "nodes:",
+		wantErr: "unable to decode",
+	}, {
+		name:    "unsupported version",
+		input:   `{"version": 2, "nodes": [], "edges": []}`,
+		wantErr: "unsupported graph file version 2",
+	}, {
+		name:    "dangling edge",
+		input:   `{"version": 1, "nodes": [], "edges": [{"id": "e", "type": "DependsOn", "from": "a", "to": "b"}]}`,
+		wantErr: "unknown node a",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadGraph(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadGraph() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	b, err := NewFileBackend(path)
+	if err != nil {
+		t.Fatalf("NewFileBackend() error = %v", err)
+	}
+	lib := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+	libVersion := PackageNode{Purl: "pkg:golang/lib@v1", Version: "v1"}
+	ctx := context.Background()
+	if err := b.Store(ctx, Graph{Nodes: []GuacNode{lib}}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := b.Store(ctx, Graph{Nodes: []GuacNode{libVersion}}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Errorf("graph file written before Close()")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := ReadGraph(f)
+	if err != nil {
+		t.Fatalf("ReadGraph() error = %v", err)
+	}
+	if len(g.Nodes) != 1 {
+		t.Fatalf("got %d nodes, want 1", len(g.Nodes))
+	}
+	props := g.Nodes[0].Properties()
+	if props["name"] != "lib" || props["version"] != "v1" {
+		t.Errorf("stored properties = %v, want them merged", props)
+	}
+
+	if _, err := NewFileBackend(filepath.Join(path, "missing", "graph.json")); err == nil {
+		t.Errorf("NewFileBackend() succeeded in a missing directory")
+	}
+}
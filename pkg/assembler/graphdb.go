@@ -16,7 +16,9 @@
 package assembler
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/guacsec/guac/pkg/assembler/graphdb"
@@ -25,8 +27,113 @@ import (
 
 // Note: This module is experimental and might change often!
 
-// StoreSubgraph stores a Graph to the graph database given by Client
+// DefaultBatchSize is the number of nodes or edges written by a single query
+// when calling StoreGraph.
+const DefaultBatchSize = 1000
+
+// StoreGraph stores a Graph to the graph database given by Client, writing
+// nodes and edges in batches of DefaultBatchSize. Graphs with node or edge
+// types that are not registered are rejected, see ValidateGraph.
 func StoreGraph(g Graph, client graphdb.Client) error {
+	return StoreGraphInBatches(g, client, DefaultBatchSize)
+}
+
+// StoreGraphInBatches stores a Graph to the graph database given by Client.
+//
+// Nodes (and then edges) are split in batches of at most batchSize elements.
+// Each batch is written in its own transaction, using one `UNWIND` query for
+// every group of elements that share the same shape (their type and the
+// identifiable properties of the nodes). Only the parameters of the current
+// batch are kept in memory.
+//
+// Nodes are merged on their identifiable properties and their NodeID is set
+// in the IDProperty property by the same query, so nodes written before the
+// id existed get it instead of being duplicated. Edges are merged on their
+// EdgeID, stored the same way.
+//
+// Batches that fail with transient errors are retried following
+// `graphdb.DefaultRetryPolicy`.
+func StoreGraphInBatches(g Graph, client graphdb.Client, batchSize int) error {
+	return StoreGraphInBatchesWithRetry(g, client, batchSize, graphdb.DefaultRetryPolicy)
+}
+
+// StoreGraphInBatchesWithRetry is like StoreGraphInBatches, but retries the
+// batches that fail with transient errors following the given policy. Since
+// nodes and edges are merged, writing a batch again is idempotent.
+func StoreGraphInBatchesWithRetry(g Graph, client graphdb.Client, batchSize int, retry graphdb.RetryPolicy) error {
+	return storeGraphInBatches(context.Background(), g, client, batchSize, retry, nil)
+}
+
+// storeGraphInBatches is StoreGraphInBatchesWithRetry, stopping before the
+// next batch once ctx is canceled. The edges found in cache are skipped, and
+// the edges of each committed batch are added to it, unless cache is nil.
+func storeGraphInBatches(ctx context.Context, g Graph, client graphdb.Client, batchSize int, retry graphdb.RetryPolicy, cache *EdgeCache) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	if err := ValidateGraph(g); err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{})
+	defer session.Close()
+
+	for start := 0; start < len(g.Nodes); start += batchSize {
+		end := start + batchSize
+		if end > len(g.Nodes) {
+			end = len(g.Nodes)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		queries, params, err := nodeBatchQueries(g.Nodes[start:end])
+		if err != nil {
+			return err
+		}
+		err = retry.WithRetry(func() error {
+			return runWriteQueries(session, queries, params)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(g.Edges); start += batchSize {
+		end := start + batchSize
+		if end > len(g.Edges) {
+			end = len(g.Edges)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows, keys, err := uncachedEdgeRows(g.Edges[start:end], cache)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		queries, params := edgeRowQueries(rows)
+		err = retry.WithRetry(func() error {
+			return runWriteQueries(session, queries, params)
+		})
+		if err != nil {
+			return err
+		}
+		if cache != nil {
+			cache.add(keys)
+		}
+	}
+
+	return nil
+}
+
+// StoreGraphUnbatched stores a Graph to the graph database given by Client,
+// using one query per node and per edge, all in a single transaction.
+func StoreGraphUnbatched(g Graph, client graphdb.Client) error {
+	if err := ValidateGraph(g); err != nil {
+		return err
+	}
 	session := client.NewSession(neo4j.SessionConfig{})
 	defer session.Close()
 
@@ -39,8 +146,13 @@ func StoreGraph(g Graph, client graphdb.Client) error {
 		}
 		queryPartForNodeAttributes(&sb, true, n, "n")
 		queryPartForNodeAttributes(&sb, false, n, "n")
+		queryPartForNodeID(&sb, "n")
+		id, err := NodeID(n)
+		if err != nil {
+			return err
+		}
 		node_queries[i] = sb.String()
-		node_dicts[i] = map[string]interface{}{}
+		node_dicts[i] = map[string]interface{}{"n_" + IDProperty: id}
 		for k, v := range n.Properties() {
 			node_dicts[i]["n_"+k] = v
 		}
@@ -54,12 +166,30 @@ func StoreGraph(g Graph, client graphdb.Client) error {
 		if err := queryPartForMergeNode(&sb, a, "a"); err != nil {
 			return err
 		}
+		queryPartForNodeID(&sb, "a")
 		if err := queryPartForMergeNode(&sb, b, "b"); err != nil {
 			return err
 		}
+		queryPartForNodeID(&sb, "b")
 		queryPartForEdgeConnection(&sb, e)
+		aID, err := NodeID(a)
+		if err != nil {
+			return err
+		}
+		bID, err := NodeID(b)
+		if err != nil {
+			return err
+		}
+		id, err := EdgeID(e)
+		if err != nil {
+			return err
+		}
 		edge_queries[i] = sb.String()
-		edge_dicts[i] = map[string]interface{}{}
+		edge_dicts[i] = map[string]interface{}{
+			"a_" + IDProperty: aID,
+			"b_" + IDProperty: bID,
+			"e_" + IDProperty: id,
+		}
 		for k, v := range a.Properties() {
 			edge_dicts[i]["a_"+k] = v
 		}
@@ -73,6 +203,11 @@ func StoreGraph(g Graph, client graphdb.Client) error {
 
 	queries := append(node_queries, edge_queries...)
 	params := append(node_dicts, edge_dicts...)
+	return runWriteQueries(session, queries, params)
+}
+
+// runWriteQueries runs all queries, with matching params, in one transaction
+func runWriteQueries(session neo4j.Session, queries []string, params []map[string]interface{}) error {
 	_, err := session.WriteTransaction(
 		func(tx graphdb.Transaction) (interface{}, error) {
 			for i, query := range queries {
@@ -91,6 +226,235 @@ func StoreGraph(g Graph, client graphdb.Client) error {
 	return err
 }
 
+// nodeRow is a node as written by the batch queries: its type, the key it is
+// merged on and its properties. Bulk loads stage the nodes in this form.
+type nodeRow struct {
+	Type  string                 `json:"type"`
+	Key   map[string]interface{} `json:"key"`
+	Props map[string]interface{} `json:"props"`
+}
+
+// edgeRow is an edge as written by the batch queries, with the types and
+// merge keys of its endpoints
+type edgeRow struct {
+	Type  string                 `json:"type"`
+	AType string                 `json:"a_type"`
+	BType string                 `json:"b_type"`
+	A     map[string]interface{} `json:"a"`
+	B     map[string]interface{} `json:"b"`
+	Props map[string]interface{} `json:"props"`
+}
+
+func newNodeRow(n GuacNode) (nodeRow, error) {
+	key, err := mergeKey(n)
+	if err != nil {
+		return nodeRow{}, err
+	}
+	return nodeRow{Type: n.Type(), Key: key, Props: n.Properties()}, nil
+}
+
+func newEdgeRow(e GuacEdge) (edgeRow, error) {
+	a, b := e.Nodes()
+	aKey, err := mergeKey(a)
+	if err != nil {
+		return edgeRow{}, err
+	}
+	bKey, err := mergeKey(b)
+	if err != nil {
+		return edgeRow{}, err
+	}
+	id, err := EdgeID(e)
+	if err != nil {
+		return edgeRow{}, err
+	}
+	return edgeRow{
+		Type:  e.Type(),
+		AType: a.Type(),
+		BType: b.Type(),
+		A:     aKey,
+		B:     bKey,
+		Props: withID(e.Properties(), id),
+	}, nil
+}
+
+// nodeBatchQueries groups the nodes by shape and creates one
+// "UNWIND $batch AS row MERGE (n:${NODE_TYPE} {${ATTR}:row.n.${ATTR}, ...})
+// SET n += row.props, n.guac_id = row.n.guac_id" query for each group.
+func nodeBatchQueries(nodes []GuacNode) ([]string, []map[string]interface{}, error) {
+	rows := make([]nodeRow, 0, len(nodes))
+	for _, n := range nodes {
+		row, err := newNodeRow(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+	queries, params := nodeRowQueries(rows)
+	return queries, params, nil
+}
+
+// nodeRowQueries creates the queries of nodeBatchQueries for the rows
+func nodeRowQueries(rows []nodeRow) ([]string, []map[string]interface{}) {
+	var queries []string
+	var batches [][]interface{}
+	groups := map[string]int{}
+
+	for _, row := range rows {
+		names := identifiableNames(row.Key)
+		shape := row.Type + "|" + strings.Join(names, ",")
+		ix, ok := groups[shape]
+		if !ok {
+			var sb strings.Builder
+			sb.WriteString("UNWIND $batch AS row\n")
+			queryPartForUnwindMergeNode(&sb, row.Type, names, "n")
+			sb.WriteString("SET n += row.props, n." + IDProperty + " = row.n." + IDProperty + "\n")
+			ix = len(queries)
+			groups[shape] = ix
+			queries = append(queries, sb.String())
+			batches = append(batches, []interface{}{})
+		}
+		batches[ix] = append(batches[ix], map[string]interface{}{
+			"n":     row.Key,
+			"props": row.Props,
+		})
+	}
+
+	return queries, batchParams(batches)
+}
+
+// edgeBatchQueries groups the edges by shape (edge type and shape of both
+// endpoints) and creates one "UNWIND $batch AS row MERGE (a) MERGE (b)
+// MERGE (a) -[e:${EDGE_TYPE} {guac_id:row.props.guac_id}]-> (b)
+// SET e += row.props" query for each group. Endpoints that do not exist yet
+// are created with their identifiable properties and id only.
+//
+// The EdgeID is the idempotency key of the edge: it only depends on the
+// content of the edge, so writing a batch again, e.g. when retrying after a
+// transient error whose transaction was committed, merges onto the same
+// edges instead of creating duplicates.
+func edgeBatchQueries(edges []GuacEdge) ([]string, []map[string]interface{}, error) {
+	rows, _, err := uncachedEdgeRows(edges, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	queries, params := edgeRowQueries(rows)
+	return queries, params, nil
+}
+
+// edgeRowQueries creates the queries of edgeBatchQueries for the rows
+func edgeRowQueries(rows []edgeRow) ([]string, []map[string]interface{}) {
+	var queries []string
+	var batches [][]interface{}
+	groups := map[string]int{}
+
+	for _, row := range rows {
+		aNames := identifiableNames(row.A)
+		bNames := identifiableNames(row.B)
+		shape := strings.Join([]string{
+			row.Type,
+			row.AType, strings.Join(aNames, ","),
+			row.BType, strings.Join(bNames, ","),
+		}, "|")
+		ix, ok := groups[shape]
+		if !ok {
+			var sb strings.Builder
+			sb.WriteString("UNWIND $batch AS row\n")
+			queryPartForUnwindMergeNode(&sb, row.AType, aNames, "a")
+			sb.WriteString("SET a." + IDProperty + " = row.a." + IDProperty + "\n")
+			queryPartForUnwindMergeNode(&sb, row.BType, bNames, "b")
+			sb.WriteString("SET b." + IDProperty + " = row.b." + IDProperty + "\n")
+			sb.WriteString("MERGE (a) -[e:")
+			sb.WriteString(row.Type) // not user controlled
+			sb.WriteString(" {" + IDProperty + ":row.props." + IDProperty + "}]-> (b)\n")
+			sb.WriteString("SET e += row.props\n")
+			ix = len(queries)
+			groups[shape] = ix
+			queries = append(queries, sb.String())
+			batches = append(batches, []interface{}{})
+		}
+		batches[ix] = append(batches[ix], map[string]interface{}{
+			"a":     row.A,
+			"b":     row.B,
+			"props": row.Props,
+		})
+	}
+
+	return queries, batchParams(batches)
+}
+
+func batchParams(batches [][]interface{}) []map[string]interface{} {
+	params := make([]map[string]interface{}, len(batches))
+	for i, batch := range batches {
+		params[i] = map[string]interface{}{"batch": batch}
+	}
+	return params
+}
+
+// identifiableProperties returns the values of the properties that uniquely
+// identify the node
+func identifiableProperties(n GuacNode) (map[string]interface{}, error) {
+	node_data := n.Properties()
+	key := map[string]interface{}{}
+	for _, name := range n.IdentifiablePropertyNames() {
+		v, ok := node_data[name]
+		if !ok {
+			return nil, fmt.Errorf("Node %v has no value for property %v", n, name)
+		}
+		key[name] = v
+	}
+	return key, nil
+}
+
+// mergeKey returns the identifiable properties of the node together with its
+// id, under IDProperty. Nodes are merged on the identifiable properties and
+// the id is set on the merged node.
+func mergeKey(n GuacNode) (map[string]interface{}, error) {
+	key, err := identifiableProperties(n)
+	if err != nil {
+		return nil, err
+	}
+	id, err := NodeID(n)
+	if err != nil {
+		return nil, err
+	}
+	key[IDProperty] = id
+	return key, nil
+}
+
+// identifiableNames returns the sorted names of the identifiable properties
+// in a merge key, leaving out the id
+func identifiableNames(key map[string]interface{}) []string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		if name != IDProperty {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Creates the "MERGE (n:${NODE_TYPE} {${ATTR}:row.n.${ATTR}, ...})" part of
+// the query, reading values from the current `UNWIND` row
+func queryPartForUnwindMergeNode(sb *strings.Builder, nodeType string, names []string, label string) {
+	sb.WriteString("MERGE (")
+	sb.WriteString(label) // not user controlled
+	sb.WriteString(":")
+	sb.WriteString(nodeType) // not user controlled
+	sb.WriteString(" {")
+	for ix, key := range names {
+		if ix != 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(key) // not user controlled
+		sb.WriteString(":row.")
+		sb.WriteString(label) // not user controlled
+		sb.WriteString(".")
+		sb.WriteString(key) // not user controlled
+	}
+	sb.WriteString("})\n")
+}
+
 // CreateIndexOn creates database indixes in the graph database given by Client
 // to optimize performance.
 func CreateIndexOn(client graphdb.Client, nodeLabel string, nodeAttribute string) error {
@@ -131,6 +495,13 @@ func queryPartForMergeNode(sb *strings.Builder, n GuacNode, label string) error
 	return nil
 }
 
+// Creates the "SET n.guac_id=${VALUE}" part of the query
+func queryPartForNodeID(sb *strings.Builder, label string) {
+	sb.WriteString("SET ")
+	writeKeyValToQuery(sb, IDProperty, label, true, true)
+	sb.WriteString("\n")
+}
+
 // Creates the "ON CREATE SET ${ATTR}=${VALUE}, ..." part of the query
 // Creates the "ON MATCH SET ${ATTR}=${VALUE}, ..." part of the query
 func queryPartForNodeAttributes(sb *strings.Builder, onCreate bool, n GuacNode, label string) {
@@ -148,18 +519,22 @@ func queryPartForNodeAttributes(sb *strings.Builder, onCreate bool, n GuacNode,
 	sb.WriteString("\n")
 }
 
-// Creates the "(a) -[e:${EDGE_TYPE}] -> (b)" part of the query and sets the edge attributes
+// Creates the "(a) -[e:${EDGE_TYPE} {guac_id:${VALUE}}] -> (b)" part of the
+// query and sets the edge attributes
 func queryPartForEdgeConnection(sb *strings.Builder, e GuacEdge) {
 	sb.WriteString("MERGE (a) -[e:")
 	sb.WriteString(e.Type()) // not user controlled
-	sb.WriteString("]-> (b)")
-	if edge_data := e.Properties(); len(edge_data) > 0 {
-		sb.WriteString("\nSET ")
-		first := true
-		for key := range edge_data {
-			writeKeyValToQuery(sb, key, "e", true, first)
-			first = false
-		}
+	sb.WriteString(" {")
+	writeKeyValToQuery(sb, IDProperty, "e", false, true)
+	sb.WriteString("}]-> (b)\n")
+	if len(e.Properties()) == 0 {
+		return
+	}
+	sb.WriteString("SET ")
+	first := true
+	for key := range e.Properties() {
+		writeKeyValToQuery(sb, key, "e", true, first)
+		first = false
 	}
 	sb.WriteString("\n")
 }
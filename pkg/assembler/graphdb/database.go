@@ -0,0 +1,81 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// NewGraphClientForDatabase is like NewGraphClientWithTLS, but the sessions of
+// the returned client run against the database named dbName instead of the
+// default database of the server, e.g. to keep the graph of each tenant in
+// its own database of a shared Neo4j instance. An empty dbName uses the
+// default database.
+func NewGraphClientForDatabase(uri string, authToken AuthToken, tlsOptions TLSOptions, dbName string) (Client, error) {
+	driver, err := NewGraphClientWithTLS(uri, authToken, tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+	if dbName == "" {
+		return driver, nil
+	}
+
+	client := WithDatabase(driver, dbName)
+	// VerifyConnectivity does not check the database, so fail early
+	// rather than on the first write if it does not exist
+	if err := checkDatabase(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to use database %q: %w", dbName, err)
+	}
+	return client, nil
+}
+
+// WithDatabase returns a client whose sessions run against the database named
+// dbName, unless their configuration names another database.
+func WithDatabase(client Client, dbName string) Client {
+	return &databaseClient{Driver: client, dbName: dbName}
+}
+
+type databaseClient struct {
+	neo4j.Driver
+	dbName string
+}
+
+func (c *databaseClient) NewSession(config neo4j.SessionConfig) neo4j.Session {
+	if config.DatabaseName == "" {
+		config.DatabaseName = c.dbName
+	}
+	return c.Driver.NewSession(config)
+}
+
+func (c *databaseClient) Session(accessMode neo4j.AccessMode, bookmarks ...string) (neo4j.Session, error) {
+	return c.NewSession(neo4j.SessionConfig{AccessMode: accessMode, Bookmarks: bookmarks}), nil
+}
+
+// checkDatabase runs a trivial query against the database of the client
+func checkDatabase(client Client) error {
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	result, err := session.Run("RETURN 1", nil)
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume()
+	return err
+}
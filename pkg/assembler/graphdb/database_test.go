@@ -0,0 +1,78 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// sessionRecorder is a driver recording the configuration of the sessions
+// created from it
+type sessionRecorder struct {
+	neo4j.Driver
+	configs []neo4j.SessionConfig
+}
+
+func (r *sessionRecorder) NewSession(config neo4j.SessionConfig) neo4j.Session {
+	r.configs = append(r.configs, config)
+	return nil
+}
+
+func TestWithDatabase(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config neo4j.SessionConfig
+		want   neo4j.SessionConfig
+	}{{
+		name:   "default database",
+		config: neo4j.SessionConfig{},
+		want:   neo4j.SessionConfig{DatabaseName: "team-a"},
+	}, {
+		name:   "access mode is kept",
+		config: neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead},
+		want:   neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead, DatabaseName: "team-a"},
+	}, {
+		name:   "explicit database",
+		config: neo4j.SessionConfig{DatabaseName: "system"},
+		want:   neo4j.SessionConfig{DatabaseName: "system"},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &sessionRecorder{}
+			WithDatabase(recorder, "team-a").NewSession(tt.config)
+			if len(recorder.configs) != 1 {
+				t.Fatalf("got %d sessions, expected 1", len(recorder.configs))
+			}
+			got := recorder.configs[0]
+			if got.DatabaseName != tt.want.DatabaseName || got.AccessMode != tt.want.AccessMode {
+				t.Errorf("got session config %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDatabase_DeprecatedSession(t *testing.T) {
+	recorder := &sessionRecorder{}
+	if _, err := WithDatabase(recorder, "team-a").Session(neo4j.AccessModeWrite, "bookmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := recorder.configs[0]
+	if got.DatabaseName != "team-a" || got.AccessMode != neo4j.AccessModeWrite || len(got.Bookmarks) != 1 {
+		t.Errorf("got session config %+v, expected the team-a database in write mode", got)
+	}
+}
@@ -40,15 +40,33 @@ type Client = neo4j.Driver
 // NewGraphClient creates a new connection to the graph database given by
 // `uri`, performing authentication via `authToken`.
 func NewGraphClient(uri string, authToken AuthToken) (Client, error) {
+	return NewGraphClientWithTLS(uri, authToken, TLSOptions{})
+}
+
+// NewGraphClientWithTLS is like NewGraphClient, but encrypts the connection
+// following tlsOptions. The `uri` must then use an encrypted scheme
+// (`neo4j+s` or `bolt+s`). If the connection fails because of the TLS
+// handshake, the returned error explains why the certificate was rejected.
+func NewGraphClientWithTLS(uri string, authToken AuthToken, tlsOptions TLSOptions) (Client, error) {
+	target, err := tlsOptions.target(uri)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO(mihaimaruseac): Allow configuration to control internal
 	// attributes of the connection (e.g., max connection pool size, etc.)
-	driver, err := neo4j.NewDriver(uri, authToken)
+	driver, err := neo4j.NewDriver(target.String(), authToken, func(c *neo4j.Config) {
+		c.RootCAs = tlsOptions.RootCAs
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	if err = driver.VerifyConnectivity(); err != nil {
 		driver.Close()
+		if tlsErr := checkTLS(target, tlsOptions); tlsErr != nil {
+			return nil, tlsErr
+		}
 		return nil, err
 	}
 	return driver, nil
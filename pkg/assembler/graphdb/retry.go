@@ -0,0 +1,107 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// RetryPolicy controls how failed transactions are retried. The delay before
+// retry `i` (counting from 0) is drawn uniformly from
+// [0, min(MaxDelay, BaseDelay * 2^i)], so that clients retrying at the same
+// time spread out.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero
+	// disables retries.
+	MaxRetries int
+	// BaseDelay is the upper bound of the delay before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the upper bound of the delay between retries
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries 5 times, waiting up to 200ms, 400ms, 800ms,
+// 1.6s and 3.2s between attempts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// WithRetry runs work, retrying it according to the policy while it fails
+// with a retriable error (see `IsRetriable`). The work must be idempotent.
+func (p RetryPolicy) WithRetry(work func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = work()
+		if err == nil || !IsRetriable(err) || attempt >= p.MaxRetries {
+			return err
+		}
+		sleep(p.delay(attempt))
+	}
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	upper := p.BaseDelay
+	for i := 0; i < attempt && upper < p.MaxDelay; i++ {
+		upper *= 2
+	}
+	if p.MaxDelay > 0 && upper > p.MaxDelay {
+		upper = p.MaxDelay
+	}
+	if upper <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(upper) + 1))
+}
+
+// IsRetriable returns whether err is a transient error after which the
+// transaction can be attempted again: connection failures, transient database
+// errors and cluster leadership changes. Client errors, such as constraint
+// violations, are not retriable.
+func IsRetriable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// the driver returns this error when its own retries ran out, check the
+	// error of the last attempt
+	var limit *neo4j.TransactionExecutionLimit
+	if errors.As(err, &limit) {
+		if len(limit.Errors) == 0 {
+			return false
+		}
+		return IsRetriable(limit.Errors[len(limit.Errors)-1])
+	}
+
+	var connectivityErr *neo4j.ConnectivityError
+	if errors.As(err, &connectivityErr) {
+		return true
+	}
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) {
+		return neo4jErr.IsRetriableTransient() || neo4jErr.IsRetriableCluster()
+	}
+	return false
+}
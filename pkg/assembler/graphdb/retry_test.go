@@ -0,0 +1,165 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+func TestIsRetriable(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{{
+		name: "transient error",
+		err:  &neo4j.Neo4jError{Code: "Neo.TransientError.General.DatabaseUnavailable"},
+		want: true,
+	}, {
+		name: "leader changed",
+		err:  &neo4j.Neo4jError{Code: "Neo.ClientError.Cluster.NotALeader"},
+		want: true,
+	}, {
+		name: "wrapped transient error",
+		err:  fmt.Errorf("batch failed: %w", &neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.DeadlockDetected"}),
+		want: true,
+	}, {
+		name: "connectivity error",
+		err:  &neo4j.ConnectivityError{},
+		want: true,
+	}, {
+		name: "constraint violation",
+		err:  &neo4j.Neo4jError{Code: "Neo.ClientError.Schema.ConstraintValidationFailed"},
+		want: false,
+	}, {
+		name: "terminated transaction",
+		err:  &neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.Terminated"},
+		want: false,
+	}, {
+		name: "driver retries ran out",
+		err: &neo4j.TransactionExecutionLimit{Errors: []error{
+			&neo4j.Neo4jError{Code: "Neo.ClientError.Schema.ConstraintValidationFailed"},
+			&neo4j.Neo4jError{Code: "Neo.ClientError.Cluster.NotALeader"},
+		}},
+		want: true,
+	}, {
+		name: "canceled",
+		err:  context.Canceled,
+		want: false,
+	}, {
+		name: "other error",
+		err:  errors.New("boom"),
+		want: false,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetriable(tt.err); got != tt.want {
+				t.Errorf("IsRetriable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_WithRetry(t *testing.T) {
+	transient := &neo4j.Neo4jError{Code: "Neo.TransientError.General.DatabaseUnavailable"}
+	constraint := &neo4j.Neo4jError{Code: "Neo.ClientError.Schema.ConstraintValidationFailed"}
+
+	testCases := []struct {
+		name         string
+		maxRetries   int
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{{
+		name:         "success",
+		maxRetries:   3,
+		wantAttempts: 1,
+	}, {
+		name:         "success after transient errors",
+		maxRetries:   3,
+		errs:         []error{transient, transient},
+		wantAttempts: 3,
+	}, {
+		name:         "retries exhausted",
+		maxRetries:   2,
+		errs:         []error{transient, transient, transient, transient},
+		wantErr:      transient,
+		wantAttempts: 3,
+	}, {
+		name:         "not retriable",
+		maxRetries:   3,
+		errs:         []error{constraint},
+		wantErr:      constraint,
+		wantAttempts: 1,
+	}, {
+		name:         "retries disabled",
+		maxRetries:   0,
+		errs:         []error{transient},
+		wantErr:      transient,
+		wantAttempts: 1,
+	}}
+
+	defer func(s func(time.Duration)) { sleep = s }(sleep)
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var slept []time.Duration
+			sleep = func(d time.Duration) { slept = append(slept, d) }
+
+			p := RetryPolicy{MaxRetries: tt.maxRetries, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+			attempts := 0
+			err := p.WithRetry(func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("WithRetry() error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("WithRetry() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(slept) != attempts-1 {
+				t.Errorf("WithRetry() slept %d times, want %d", len(slept), attempts-1)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, upper := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(attempt); d < 0 || d > upper {
+				t.Fatalf("delay(%d) = %v, want in [0, %v]", attempt, d, upper)
+			}
+		}
+	}
+}
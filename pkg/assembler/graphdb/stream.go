@@ -0,0 +1,49 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// DefaultFetchSize is the number of records pulled from the server at a time
+// by StreamQuery, unless configured otherwise
+const DefaultFetchSize = 1000
+
+// StreamQuery runs a read query and calls fn with the values of each record,
+// as the records are pulled from the server fetchSize at a time, so that the
+// whole result is never held in memory. It stops at the first error of fn.
+//
+// The query runs in an auto-commit transaction, which is not retried: fn may
+// already have seen some records when the query fails.
+func StreamQuery(client Client, query string, args map[string]interface{}, fetchSize int, fn func(values []interface{}) error) error {
+	if fetchSize <= 0 {
+		fetchSize = DefaultFetchSize
+	}
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead, FetchSize: fetchSize})
+	defer session.Close()
+
+	result, err := session.Run(query, args)
+	if err != nil {
+		return err
+	}
+	for result.Next() {
+		if err := fn(result.Record().Values); err != nil {
+			return err
+		}
+	}
+	return result.Err()
+}
@@ -0,0 +1,147 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// fakeStreamDriver is a driver whose sessions return the records one by one,
+// counting how many were pulled
+type fakeStreamDriver struct {
+	neo4j.Driver
+	records [][]interface{}
+	err     error
+	config  neo4j.SessionConfig
+	pulled  int
+	closed  bool
+}
+
+func (d *fakeStreamDriver) NewSession(config neo4j.SessionConfig) neo4j.Session {
+	d.config = config
+	return &fakeStreamSession{driver: d}
+}
+
+type fakeStreamSession struct {
+	neo4j.Session
+	driver *fakeStreamDriver
+}
+
+func (s *fakeStreamSession) Run(string, map[string]interface{}, ...func(*neo4j.TransactionConfig)) (neo4j.Result, error) {
+	return &fakeStreamResult{driver: s.driver}, nil
+}
+
+func (s *fakeStreamSession) Close() error {
+	s.driver.closed = true
+	return nil
+}
+
+type fakeStreamResult struct {
+	neo4j.Result
+	driver *fakeStreamDriver
+	record *neo4j.Record
+}
+
+func (r *fakeStreamResult) Next() bool {
+	if r.driver.pulled == len(r.driver.records) {
+		return false
+	}
+	r.record = &neo4j.Record{Values: r.driver.records[r.driver.pulled]}
+	r.driver.pulled++
+	return true
+}
+
+func (r *fakeStreamResult) Record() *neo4j.Record {
+	return r.record
+}
+
+func (r *fakeStreamResult) Err() error {
+	return r.driver.err
+}
+
+func TestStreamQuery(t *testing.T) {
+	errStop := errors.New("stop")
+	errQuery := errors.New("query failed")
+	records := [][]interface{}{{"a", int64(1)}, {"b", int64(2)}, {"c", int64(3)}}
+
+	testCases := []struct {
+		name       string
+		fetchSize  int
+		stopAt     int
+		queryErr   error
+		wantSize   int
+		wantValues [][]interface{}
+		wantPulled int
+		wantErr    error
+	}{{
+		name:       "all records",
+		fetchSize:  2,
+		wantSize:   2,
+		wantValues: records,
+		wantPulled: 3,
+	}, {
+		name:       "default fetch size",
+		wantSize:   DefaultFetchSize,
+		wantValues: records,
+		wantPulled: 3,
+	}, {
+		name:       "stops at the first error of fn",
+		stopAt:     2,
+		wantSize:   DefaultFetchSize,
+		wantValues: records[:2],
+		wantPulled: 2,
+		wantErr:    errStop,
+	}, {
+		name:       "error of the query",
+		queryErr:   errQuery,
+		wantSize:   DefaultFetchSize,
+		wantValues: records,
+		wantPulled: 3,
+		wantErr:    errQuery,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &fakeStreamDriver{records: records, err: tt.queryErr}
+			var got [][]interface{}
+			err := StreamQuery(driver, "MATCH (n) RETURN n", nil, tt.fetchSize, func(values []interface{}) error {
+				got = append(got, values)
+				if len(got) == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, expected %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.wantValues) {
+				t.Errorf("got values %v, expected %v", got, tt.wantValues)
+			}
+			if driver.pulled != tt.wantPulled {
+				t.Errorf("pulled %d records, expected %d", driver.pulled, tt.wantPulled)
+			}
+			if driver.config.FetchSize != tt.wantSize || driver.config.AccessMode != neo4j.AccessModeRead {
+				t.Errorf("got session config %+v, expected a read session fetching %d records at a time", driver.config, tt.wantSize)
+			}
+			if !driver.closed {
+				t.Errorf("session was not closed")
+			}
+		})
+	}
+}
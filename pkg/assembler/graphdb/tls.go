@@ -0,0 +1,136 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultPort is the port of the Bolt protocol, used when the URI has none
+const defaultPort = "7687"
+
+// tlsCheckTimeout bounds the TLS handshake done to explain a failed connection
+const tlsCheckTimeout = 10 * time.Second
+
+// ErrClientCertificate is returned when TLSOptions has a client certificate:
+// the v4 Neo4j driver cannot present one, so mutual TLS is not supported.
+var ErrClientCertificate = errors.New("client certificates (mutual TLS) are not supported by the Neo4j driver")
+
+// TLSOptions configures the encryption of the connection to the graph
+// database. The zero value trusts the system root certificates.
+type TLSOptions struct {
+	// RootCAs are the certificate authorities the certificate of the server
+	// must chain to, nil uses the system root certificates
+	RootCAs *x509.CertPool
+	// InsecureSkipVerify accepts any certificate, e.g. a self-signed one.
+	// This should only be used for testing.
+	InsecureSkipVerify bool
+	// ClientCertFile and ClientKeyFile are the paths to the PEM encoded
+	// client certificate and key for mutual TLS. Setting either makes
+	// connecting fail with ErrClientCertificate.
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// LoadCertPool returns the pool of the PEM encoded certificates in the file
+// at path, to be used as `TLSOptions.RootCAs`.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %s", path)
+	}
+	return pool, nil
+}
+
+// target returns the URI to connect to. The Neo4j driver only skips the
+// verification of the certificate for the `+ssc` schemes.
+func (o TLSOptions) target(uri string) (*url.URL, error) {
+	target, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URI: %w", err)
+	}
+	if o.ClientCertFile != "" || o.ClientKeyFile != "" {
+		return nil, ErrClientCertificate
+	}
+	if o == (TLSOptions{}) {
+		return target, nil
+	}
+	if !encrypted(target) {
+		return nil, fmt.Errorf("TLS options need an encrypted URI scheme (neo4j+s or bolt+s), got %s", target.Scheme)
+	}
+	if o.InsecureSkipVerify && strings.HasSuffix(target.Scheme, "+s") {
+		target.Scheme += "sc"
+	}
+	return target, nil
+}
+
+func encrypted(target *url.URL) bool {
+	return strings.HasSuffix(target.Scheme, "+s") || strings.HasSuffix(target.Scheme, "+ssc")
+}
+
+// checkTLS returns an error explaining why the TLS handshake with the server
+// fails, if it does. The Neo4j driver reports these failures as generic
+// connectivity errors, without saying which certificate was rejected.
+func checkTLS(target *url.URL, o TLSOptions) error {
+	if !encrypted(target) {
+		return nil
+	}
+	address := target.Host
+	if target.Port() == "" {
+		address = net.JoinHostPort(target.Hostname(), defaultPort)
+	}
+	config := &tls.Config{
+		RootCAs:            o.RootCAs,
+		InsecureSkipVerify: o.InsecureSkipVerify || strings.HasSuffix(target.Scheme, "+ssc"),
+		ServerName:         target.Hostname(),
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: tlsCheckTimeout}, "tcp", address, config)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		header           tls.RecordHeaderError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("the certificate of the database at %s is not signed by a trusted CA, configure the CA certificate that signed it: %w", address, err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("the certificate of the database at %s is not valid for host %s: %w", address, target.Hostname(), err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("the certificate of the database at %s is invalid: %w", address, err)
+	case errors.As(err, &header):
+		return fmt.Errorf("the database at %s does not accept TLS connections, use the neo4j:// or bolt:// scheme: %w", address, err)
+	}
+	// not a TLS problem, e.g. the server is down
+	return nil
+}
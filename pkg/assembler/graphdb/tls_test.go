@@ -0,0 +1,179 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphdb
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLSOptions_target(t *testing.T) {
+	testCases := []struct {
+		name    string
+		uri     string
+		options TLSOptions
+		want    string
+		wantErr bool
+	}{{
+		name: "no TLS options",
+		uri:  "neo4j://localhost:7687",
+		want: "neo4j://localhost:7687",
+	}, {
+		name:    "root CAs",
+		uri:     "neo4j+s://db.example.com",
+		options: TLSOptions{RootCAs: x509.NewCertPool()},
+		want:    "neo4j+s://db.example.com",
+	}, {
+		name:    "skip verify",
+		uri:     "neo4j+s://db.example.com:7687",
+		options: TLSOptions{InsecureSkipVerify: true},
+		want:    "neo4j+ssc://db.example.com:7687",
+	}, {
+		name:    "skip verify with self-signed scheme",
+		uri:     "bolt+ssc://db.example.com",
+		options: TLSOptions{InsecureSkipVerify: true},
+		want:    "bolt+ssc://db.example.com",
+	}, {
+		name:    "unencrypted scheme",
+		uri:     "neo4j://db.example.com",
+		options: TLSOptions{RootCAs: x509.NewCertPool()},
+		wantErr: true,
+	}, {
+		name:    "client certificate",
+		uri:     "neo4j+s://db.example.com",
+		options: TLSOptions{ClientCertFile: "client.pem", ClientKeyFile: "client-key.pem"},
+		wantErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.target(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("target() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("target() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	// the test certificate is valid for 127.0.0.1 and example.com
+	_, port, _ := net.SplitHostPort(tlsServer.Listener.Addr().String())
+	trusted := x509.NewCertPool()
+	trusted.AddCert(tlsServer.Certificate())
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	testCases := []struct {
+		name    string
+		uri     string
+		options TLSOptions
+		wantErr string
+	}{{
+		name:    "trusted certificate",
+		uri:     "neo4j+s://" + tlsServer.Listener.Addr().String(),
+		options: TLSOptions{RootCAs: trusted},
+	}, {
+		name:    "unknown authority",
+		uri:     "neo4j+s://" + tlsServer.Listener.Addr().String(),
+		wantErr: "not signed by a trusted CA",
+	}, {
+		name:    "wrong host",
+		uri:     "bolt+s://localhost:" + port,
+		options: TLSOptions{RootCAs: trusted},
+		wantErr: "not valid for host localhost",
+	}, {
+		name: "self-signed scheme",
+		uri:  "neo4j+ssc://localhost:" + port,
+	}, {
+		name:    "server without TLS",
+		uri:     "neo4j+s://" + plainServer.Listener.Addr().String(),
+		wantErr: "does not accept TLS connections",
+	}, {
+		name: "server down",
+		uri:  "neo4j+s://" + closedAddr,
+	}, {
+		name: "unencrypted scheme",
+		uri:  "neo4j://" + plainServer.Listener.Addr().String(),
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := tt.options.target(tt.uri)
+			if err != nil {
+				t.Fatalf("target() error = %v", err)
+			}
+			err = checkTLS(target, tt.options)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkTLS() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkTLS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := LoadCertPool(caFile)
+	if err != nil {
+		t.Fatalf("LoadCertPool() error = %v", err)
+	}
+	if _, err := server.Certificate().Verify(x509.VerifyOptions{Roots: pool, DNSName: "example.com"}); err != nil {
+		t.Errorf("certificate not trusted by the loaded pool: %v", err)
+	}
+	if _, err := LoadCertPool(invalidFile); err == nil {
+		t.Errorf("LoadCertPool() of an invalid file did not fail")
+	}
+	if _, err := LoadCertPool(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Errorf("LoadCertPool() of a missing file did not fail")
+	}
+}
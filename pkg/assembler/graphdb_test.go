@@ -0,0 +1,240 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+func Test_nodeBatchQueries(t *testing.T) {
+	nodes := []GuacNode{
+		ArtifactNode{Name: "a", Digest: "sha256:1"},
+		PackageNode{Name: "p", Purl: "pkg:golang/p"},
+		ArtifactNode{Name: "b", Digest: "sha256:2"},
+	}
+	queries, params, err := nodeBatchQueries(nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queries) != 2 || len(params) != 2 {
+		t.Fatalf("expected one query per node shape, got %d queries", len(queries))
+	}
+	want := "UNWIND $batch AS row\nMERGE (n:Artifact {digest:row.n.digest})\nSET n += row.props, n.guac_id = row.n.guac_id\n"
+	if queries[0] != want {
+		t.Errorf("got query %q, want %q", queries[0], want)
+	}
+	row := params[0]["batch"].([]interface{})[0].(map[string]interface{})
+	key := row["n"].(map[string]interface{})
+	if id, _ := NodeID(nodes[0]); key[IDProperty] != id || key["digest"] != "sha256:1" {
+		t.Errorf("got merge key %v, want the id %v and the digest", key, id)
+	}
+	if got := len(params[0]["batch"].([]interface{})); got != 2 {
+		t.Errorf("expected 2 artifacts in the first batch, got %d", got)
+	}
+	if got := len(params[1]["batch"].([]interface{})); got != 1 {
+		t.Errorf("expected 1 package in the second batch, got %d", got)
+	}
+}
+
+func Test_edgeBatchQueries(t *testing.T) {
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	b := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	queries, params, err := edgeBatchQueries([]GuacEdge{BuiltByEdge{a, b}, BuiltByEdge{a, b}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "UNWIND $batch AS row\n" +
+		"MERGE (a:Artifact {digest:row.a.digest})\nSET a.guac_id = row.a.guac_id\n" +
+		"MERGE (b:Builder {id:row.b.id, type:row.b.type})\nSET b.guac_id = row.b.guac_id\n" +
+		"MERGE (a) -[e:BuiltBy {guac_id:row.props.guac_id}]-> (b)\nSET e += row.props\n"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("got queries %q, want [%q]", queries, want)
+	}
+	if got := len(params[0]["batch"].([]interface{})); got != 2 {
+		t.Errorf("expected 2 edges in the batch, got %d", got)
+	}
+	row := params[0]["batch"].([]interface{})[0].(map[string]interface{})
+	props := row["props"].(map[string]interface{})
+	if id, _ := EdgeID(BuiltByEdge{a, b}); props[IDProperty] != id {
+		t.Errorf("got edge properties %v, want the id %v", props, id)
+	}
+}
+
+func Test_nodeBatchQueriesMissingProperty(t *testing.T) {
+	if _, _, err := nodeBatchQueries([]GuacNode{PackageNode{Name: "p"}}); err == nil {
+		t.Errorf("expected error for node without identifiable properties")
+	}
+}
+
+// fakeNeo4j is a driver applying the MERGE of the batch queries to an in
+// memory graph, keeping the guac_id set on each node. The first
+// `lostCommits` transactions are committed, but fail with a transient error
+// as if the acknowledgement was lost.
+type fakeNeo4j struct {
+	neo4j.Driver
+	lostCommits  int
+	transactions int
+	nodes        map[string]interface{}
+	edges        map[string]bool
+}
+
+var (
+	mergeNodePattern = regexp.MustCompile(`MERGE \((\w+):(\w+) \{([^}]*)\}\)`)
+	mergeKeyPattern  = regexp.MustCompile(`(\w+):row\.(\w+)\.(\w+)`)
+	mergeEdgePattern = regexp.MustCompile(`MERGE \(a\) -\[e:(\w+)( \{guac_id:row\.props\.guac_id\})?\]-> \(b\)`)
+)
+
+func (f *fakeNeo4j) NewSession(config neo4j.SessionConfig) neo4j.Session {
+	return fakeSession{db: f}
+}
+
+type fakeSession struct {
+	neo4j.Session
+	db *fakeNeo4j
+}
+
+func (s fakeSession) WriteTransaction(work neo4j.TransactionWork, configurers ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	s.db.transactions++
+	res, err := work(fakeTransaction{db: s.db})
+	if err != nil {
+		return nil, err
+	}
+	if s.db.lostCommits > 0 {
+		s.db.lostCommits--
+		return nil, &neo4j.ConnectivityError{}
+	}
+	return res, nil
+}
+
+func (s fakeSession) Close() error {
+	return nil
+}
+
+type fakeTransaction struct {
+	neo4j.Transaction
+	db *fakeNeo4j
+}
+
+func (tx fakeTransaction) Run(cypher string, params map[string]interface{}) (neo4j.Result, error) {
+	for _, r := range params["batch"].([]interface{}) {
+		row := r.(map[string]interface{})
+		ids := map[string]string{}
+		for _, m := range mergeNodePattern.FindAllStringSubmatch(cypher, -1) {
+			key := row[m[1]].(map[string]interface{})
+			id := m[2]
+			for _, p := range mergeKeyPattern.FindAllStringSubmatch(m[3], -1) {
+				id += fmt.Sprintf("/%s=%v", p[1], key[p[3]])
+			}
+			ids[m[1]] = id
+			if _, ok := tx.db.nodes[id]; !ok {
+				tx.db.nodes[id] = nil
+			}
+			if strings.Contains(cypher, m[1]+".guac_id = row."+m[1]+".guac_id") {
+				tx.db.nodes[id] = key[IDProperty]
+			}
+		}
+		if m := mergeEdgePattern.FindStringSubmatch(cypher); m != nil {
+			// a MERGE without properties only matches on the endpoints
+			key := fmt.Sprintf("%s/%v/%v", m[1], ids["a"], ids["b"])
+			if m[2] != "" {
+				key += fmt.Sprintf("/%v", row["props"].(map[string]interface{})[IDProperty])
+			}
+			tx.db.edges[key] = true
+		}
+	}
+	return fakeResult{}, nil
+}
+
+type fakeResult struct {
+	neo4j.Result
+}
+
+func (fakeResult) Consume() (neo4j.ResultSummary, error) {
+	return nil, nil
+}
+
+func TestStoreGraphInBatchesWithRetry_NoDuplicates(t *testing.T) {
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	b := ArtifactNode{Name: "b", Digest: "sha256:2"}
+	builder := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	g := Graph{
+		Nodes: []GuacNode{a, b, builder},
+		Edges: []GuacEdge{BuiltByEdge{a, builder}, BuiltByEdge{b, builder}, BuiltByEdge{a, builder}},
+	}
+
+	db := &fakeNeo4j{lostCommits: 2, nodes: map[string]interface{}{}, edges: map[string]bool{}}
+	retry := graphdb.RetryPolicy{MaxRetries: 2}
+	if err := StoreGraphInBatchesWithRetry(g, db, 2, retry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 2 node batches and 2 edge batches, the first one being retried twice
+	if db.transactions != 6 {
+		t.Errorf("got %d transactions, expected 6", db.transactions)
+	}
+	if len(db.nodes) != 3 {
+		t.Errorf("got %d nodes, expected 3: %v", len(db.nodes), db.nodes)
+	}
+	if len(db.edges) != 2 {
+		t.Errorf("got %d edges, expected 2: %v", len(db.edges), db.edges)
+	}
+
+	// ingesting the same graph again is idempotent too
+	if err := StoreGraphInBatchesWithRetry(g, db, 2, retry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(db.nodes) != 3 || len(db.edges) != 2 {
+		t.Errorf("got %d nodes and %d edges after ingesting again, expected 3 and 2", len(db.nodes), len(db.edges))
+	}
+}
+
+func TestStoreGraphInBatches_NodesWithoutID(t *testing.T) {
+	a := ArtifactNode{Name: "a", Digest: "sha256:1"}
+	builder := BuilderNode{BuilderType: "type", BuilderId: "id"}
+	g := Graph{
+		Nodes: []GuacNode{a, builder},
+		Edges: []GuacEdge{BuiltByEdge{a, builder}},
+	}
+
+	// nodes written before guac_id existed only have their identifiable
+	// properties
+	db := &fakeNeo4j{
+		nodes: map[string]interface{}{
+			"Artifact/digest=sha256:1": nil,
+			"Builder/id=id/type=type":  nil,
+		},
+		edges: map[string]bool{},
+	}
+	if err := StoreGraphInBatches(g, db, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(db.nodes) != 2 {
+		t.Errorf("got %d nodes, expected the 2 existing ones: %v", len(db.nodes), db.nodes)
+	}
+	aID, _ := NodeID(a)
+	builderID, _ := NodeID(builder)
+	if got := db.nodes["Artifact/digest=sha256:1"]; got != aID {
+		t.Errorf("got guac_id %v for the artifact, want %v", got, aID)
+	}
+	if got := db.nodes["Builder/id=id/type=type"]; got != builderID {
+		t.Errorf("got guac_id %v for the builder, want %v", got, builderID)
+	}
+}
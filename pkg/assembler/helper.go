@@ -28,13 +28,19 @@ type objectMetadata struct {
 	sourceInfo string
 	// collectorInfo is the collector from which the file that created the node came from
 	collectorInfo string
+	// rekorLogIndex is the transparency log entry the file was verified against
+	rekorLogIndex *int64
+	// signerIdentity is the verified signer of the file
+	signerIdentity string
 }
 
 // NewObjectMetadata creates a new instance to add metadata to nodes
 func NewObjectMetadata(s processor.SourceInformation) *objectMetadata {
 	return &objectMetadata{
-		sourceInfo:    s.Source,
-		collectorInfo: s.Collector,
+		sourceInfo:     s.Source,
+		collectorInfo:  s.Collector,
+		rekorLogIndex:  s.RekorLogIndex,
+		signerIdentity: s.SignerIdentity,
 	}
 }
 
@@ -45,10 +51,16 @@ func (o *objectMetadata) addProperties(prop map[string]interface{}) {
 	if len(o.collectorInfo) > 0 {
 		prop["collector"] = o.collectorInfo
 	}
+	if o.rekorLogIndex != nil {
+		prop["rekor_log_index"] = *o.rekorLogIndex
+	}
+	if len(o.signerIdentity) > 0 {
+		prop["signer_identity"] = o.signerIdentity
+	}
 }
 
 func (o *objectMetadata) getProperties() []string {
-	return []string{"source", "collector"}
+	return []string{"source", "collector", "rekor_log_index", "signer_identity"}
 }
 
 func isDefined(v interface{}) bool {
@@ -62,3 +74,86 @@ func toLower(v ...string) []string {
 	}
 	return lowerVals
 }
+
+// normalizeCPE returns the CPE as a lower case CPE 2.3 formatted string, so
+// that the CPE 2.2 URIs used by some advisories (e.g.
+// `cpe:/a:openssl:openssl:1.1.1k`) match the formatted strings of SBOMs
+// (e.g. `cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*`). Components
+// missing from a URI match any value.
+func normalizeCPE(cpe string) string {
+	cpe = strings.ToLower(cpe)
+	if !strings.HasPrefix(cpe, "cpe:/") {
+		return cpe
+	}
+	components := strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+	// the edition of a URI packs the extended attributes of CPE 2.3 as
+	// ~edition~sw_edition~target_sw~target_hw~other
+	if len(components) > 5 && strings.HasPrefix(components[5], "~") {
+		packed := strings.Split(strings.TrimPrefix(components[5], "~"), "~")
+		language := components[6:]
+		components = append(append(components[:5:5], packed[0]), language...)
+		if len(components) < 7 {
+			components = append(components, "")
+		}
+		components = append(components, packed[1:]...)
+	}
+	formatted := make([]string, 11)
+	for i := range formatted {
+		formatted[i] = "*"
+		if i < len(components) && components[i] != "" {
+			formatted[i] = components[i]
+		}
+	}
+	return "cpe:2.3:" + strings.Join(formatted, ":")
+}
+
+// mergeProperties returns a copy of the node or edge a completed with the
+// properties of b, which must describe the same object: fields of a which
+// are not set are taken from b, list fields are the union of both and the
+// endpoints of edges are merged in the same way. Objects of different types
+// cannot be merged, so a is returned unchanged.
+func mergeProperties[T any](a, b T) T {
+	mergeValue(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	return a
+}
+
+func mergeValue(dst, src reflect.Value) {
+	if !dst.CanSet() || dst.Type() != src.Type() || src.IsZero() {
+		return
+	}
+	if dst.IsZero() {
+		dst.Set(src)
+		return
+	}
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.Elem().Type() != src.Elem().Type() {
+			return
+		}
+		merged := reflect.New(dst.Elem().Type()).Elem()
+		merged.Set(dst.Elem())
+		mergeValue(merged, src.Elem())
+		dst.Set(merged)
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.Slice:
+		union := reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst)
+		for i := 0; i < src.Len(); i++ {
+			if !containsValue(union, src.Index(i)) {
+				union = reflect.Append(union, src.Index(i))
+			}
+		}
+		dst.Set(union)
+	}
+}
+
+func containsValue(list, v reflect.Value) bool {
+	for i := 0; i < list.Len(); i++ {
+		if reflect.DeepEqual(list.Index(i).Interface(), v.Interface()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+)
+
+// DigestMismatchMode controls how the artifacts whose digest contradicts
+// the digests expected of the package they are claimed to be are handled.
+type DigestMismatchMode string
+
+const (
+	// DigestMismatchWarn reports the mismatches and keeps the graph as is
+	DigestMismatchWarn DigestMismatchMode = "warn"
+	// DigestMismatchMark also adds a DigestMismatch edge from each
+	// mismatching artifact to the package
+	DigestMismatchMark DigestMismatchMode = "mark"
+	// DigestMismatchReject rejects the graphs containing mismatches
+	DigestMismatchReject DigestMismatchMode = "reject"
+)
+
+// ErrDigestMismatch is returned by CheckDigestMismatches when the graph has
+// mismatches and the mode is DigestMismatchReject
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// DigestMismatch is an artifact claimed, by a SameAs edge, to be a package,
+// while its digest is not among the digests of the same algorithm expected
+// of the package.
+type DigestMismatch struct {
+	Artifact ArtifactNode
+	Package  PackageNode
+	Expected []string
+}
+
+// String gives both digests, e.g. "artifact ghcr.io/app has digest
+// sha256:12..., package pkg:oci/app expects sha256:34..."
+func (m DigestMismatch) String() string {
+	return fmt.Sprintf("artifact %s has digest %s, package %s expects %s", nodeName(m.Artifact),
+		strings.ToLower(m.Artifact.Digest), nodeName(m.Package), strings.Join(m.Expected, ", "))
+}
+
+// Edge returns the DigestMismatch edge marking the mismatch
+func (m DigestMismatch) Edge() DigestMismatchEdge {
+	return DigestMismatchEdge{ArtifactNode: m.Artifact, PackageNode: m.Package, Expected: m.Expected}
+}
+
+// DigestLookup is implemented by the backends that can tell the digests
+// expected of the packages they already store, so that the artifacts of new
+// documents are also checked against the packages of earlier ones.
+type DigestLookup interface {
+	// ExpectedDigests returns the digests of the stored packages with the
+	// given purls, by purl. Packages without digests are left out.
+	ExpectedDigests(purls []string) (map[string][]string, error)
+}
+
+// PackagePurls returns the purls of the packages claimed to be the same as
+// an artifact by the SameAs edges of the graph, i.e. the packages to look up
+// with a DigestLookup before calling FindDigestMismatches.
+func PackagePurls(g Graph) []string {
+	seen := map[string]bool{}
+	purls := []string{}
+	for _, e := range g.Edges {
+		if _, p, ok := sameArtifactAndPackage(e); ok && p.Purl != "" && !seen[p.Purl] {
+			seen[p.Purl] = true
+			purls = append(purls, p.Purl)
+		}
+	}
+	sort.Strings(purls)
+	return purls
+}
+
+// FindDigestMismatches returns the artifacts of the graph whose digest
+// contradicts the package they are claimed to be by a SameAs edge.
+//
+// The digests expected of a package are those of its nodes in the graph,
+// together with stored, the digests of the packages already in the
+// database, by purl (nil when the database cannot be queried). An artifact
+// is a mismatch when the package expects digests of the algorithm of the
+// artifact digest, but not this one. A package expecting no digest of that
+// algorithm says nothing about the artifact, so there is no mismatch.
+func FindDigestMismatches(g Graph, stored map[string][]string) []DigestMismatch {
+	expected := map[string]map[string]bool{}
+	expect := func(p PackageNode, digests []string) {
+		key := nodeName(p)
+		if expected[key] == nil {
+			expected[key] = map[string]bool{}
+		}
+		for _, d := range digests {
+			expected[key][strings.ToLower(d)] = true
+		}
+	}
+	for _, n := range g.Nodes {
+		if p, ok := n.(PackageNode); ok {
+			expect(p, p.Digest)
+		}
+	}
+	for _, e := range g.Edges {
+		if _, p, ok := sameArtifactAndPackage(e); ok {
+			expect(p, p.Digest)
+			if p.Purl != "" {
+				expect(p, stored[p.Purl])
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	mismatches := []DigestMismatch{}
+	for _, e := range g.Edges {
+		a, p, ok := sameArtifactAndPackage(e)
+		if !ok {
+			continue
+		}
+		digest := strings.ToLower(a.Digest)
+		algorithm, _, ok := strings.Cut(digest, ":")
+		if !ok {
+			continue
+		}
+		digests := expected[nodeName(p)]
+		if digests[digest] {
+			continue
+		}
+		sameAlgorithm := []string{}
+		for d := range digests {
+			if strings.HasPrefix(d, algorithm+":") {
+				sameAlgorithm = append(sameAlgorithm, d)
+			}
+		}
+		if len(sameAlgorithm) == 0 {
+			continue
+		}
+		key := digest + " " + nodeName(p)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sort.Strings(sameAlgorithm)
+		mismatches = append(mismatches, DigestMismatch{Artifact: a, Package: p, Expected: sameAlgorithm})
+	}
+	return mismatches
+}
+
+// CheckDigestMismatches looks for the artifacts of the graph whose digest
+// contradicts their package, as FindDigestMismatches does, and handles them
+// following mode. It returns the graph to store and the mismatches found,
+// so that the caller can report them. With DigestMismatchReject, an error
+// wrapping ErrDigestMismatch is returned when there are mismatches.
+func CheckDigestMismatches(g Graph, stored map[string][]string, mode DigestMismatchMode) (Graph, []DigestMismatch, error) {
+	switch mode {
+	case DigestMismatchWarn:
+		return g, FindDigestMismatches(g, stored), nil
+	case DigestMismatchReject:
+		mismatches := FindDigestMismatches(g, stored)
+		if len(mismatches) > 0 {
+			return g, mismatches, fmt.Errorf("%w: %s (%d mismatches in total)", ErrDigestMismatch, mismatches[0], len(mismatches))
+		}
+		return g, mismatches, nil
+	case DigestMismatchMark:
+		mismatches := FindDigestMismatches(g, stored)
+		if len(mismatches) == 0 {
+			return g, mismatches, nil
+		}
+		marked := Graph{Nodes: g.Nodes, Edges: make([]GuacEdge, 0, len(g.Edges)+len(mismatches))}
+		marked.Edges = append(marked.Edges, g.Edges...)
+		for _, m := range mismatches {
+			marked.Edges = append(marked.Edges, m.Edge())
+		}
+		return marked, mismatches, nil
+	}
+	return g, nil, fmt.Errorf("unknown digest mismatch mode %q", mode)
+}
+
+// sameArtifactAndPackage returns the artifact and the package linked by e,
+// if e is a SameAs edge between an artifact and a package, in either
+// direction
+func sameArtifactAndPackage(e GuacEdge) (ArtifactNode, PackageNode, bool) {
+	if e.Type() != EdgeTypeSameAs {
+		return ArtifactNode{}, PackageNode{}, false
+	}
+	v, u := e.Nodes()
+	if a, ok := v.(ArtifactNode); ok {
+		p, ok := u.(PackageNode)
+		return a, p, ok
+	}
+	if a, ok := u.(ArtifactNode); ok {
+		p, ok := v.(PackageNode)
+		return a, p, ok
+	}
+	return ArtifactNode{}, PackageNode{}, false
+}
+
+// ExpectedDigests returns the digests of the Package nodes with the given
+// purls
+func (b *neo4jBackend) ExpectedDigests(purls []string) (map[string][]string, error) {
+	digests := map[string][]string{}
+	if len(purls) == 0 {
+		return digests, nil
+	}
+	err := graphdb.StreamQuery(b.client, "MATCH (p:Package) WHERE p.purl IN $purls AND p.digest IS NOT NULL "+
+		"RETURN p.purl, p.digest", map[string]interface{}{"purls": purls}, 0, func(values []interface{}) error {
+		purl, _ := values[0].(string)
+		digests[purl] = append(digests[purl], stringList(values[1])...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up the digests of the packages: %w", err)
+	}
+	return digests, nil
+}
+
+// ExpectedDigests returns the digests of the stored packages with the given
+// purls
+func (b *MemoryBackend) ExpectedDigests(purls []string) (map[string][]string, error) {
+	wanted := map[string]bool{}
+	for _, p := range purls {
+		wanted[p] = true
+	}
+	digests := map[string][]string{}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, id := range b.nodeOrder {
+		n := b.nodes[id]
+		if n.node.Type() != NodeTypePackage {
+			continue
+		}
+		purl, _ := n.properties["purl"].(string)
+		if !wanted[purl] {
+			continue
+		}
+		if d := stringList(n.properties["digest"]); len(d) > 0 {
+			digests[purl] = append(digests[purl], d...)
+		}
+	}
+	return digests, nil
+}
+
+// stringList returns the strings of a list property, as written by the
+// nodes ([]string) or read back from Neo4j ([]interface{})
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case string:
+		return []string{v}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckDigestMismatches(t *testing.T) {
+	app := PackageNode{Name: "app", Purl: "pkg:oci/app", Digest: []string{"sha256:aa", "sha1:bb"}}
+	lib := PackageNode{Name: "lib", Purl: "pkg:npm/lib@1"}
+	good := ArtifactNode{Name: "ghcr.io/app", Digest: "SHA256:AA"}
+	bad := ArtifactNode{Name: "ghcr.io/app", Digest: "sha256:cc"}
+	other := ArtifactNode{Name: "app.tar", Digest: "sha512:dd"}
+
+	tests := []struct {
+		name           string
+		nodes          []GuacNode
+		edges          []GuacEdge
+		stored         map[string][]string
+		mode           DigestMismatchMode
+		wantMismatches []string
+		wantEdges      int
+		wantErr        error
+	}{{
+		name:      "matching digest",
+		edges:     []GuacEdge{SameAsEdge{PackageNode: app, ArtifactEquivalent: good}},
+		mode:      DigestMismatchReject,
+		wantEdges: 1,
+	}, {
+		name:           "warn keeps the graph",
+		edges:          []GuacEdge{SameAsEdge{PackageNode: app, ArtifactEquivalent: bad}},
+		mode:           DigestMismatchWarn,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:oci/app expects sha256:aa"},
+		wantEdges:      1,
+	}, {
+		name:           "mark adds an edge",
+		edges:          []GuacEdge{SameAsEdge{ArtifactNode: bad, PackageEquivalent: app}},
+		mode:           DigestMismatchMark,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:oci/app expects sha256:aa"},
+		wantEdges:      2,
+	}, {
+		name:           "reject",
+		edges:          []GuacEdge{SameAsEdge{PackageNode: app, ArtifactEquivalent: bad}},
+		mode:           DigestMismatchReject,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:oci/app expects sha256:aa"},
+		wantEdges:      1,
+		wantErr:        ErrDigestMismatch,
+	}, {
+		name:      "other algorithm",
+		edges:     []GuacEdge{SameAsEdge{PackageNode: app, ArtifactEquivalent: other}},
+		mode:      DigestMismatchReject,
+		wantEdges: 1,
+	}, {
+		name:      "package without digest",
+		edges:     []GuacEdge{SameAsEdge{PackageNode: lib, ArtifactEquivalent: bad}},
+		mode:      DigestMismatchReject,
+		wantEdges: 1,
+	}, {
+		name:           "digest of the package node",
+		nodes:          []GuacNode{PackageNode{Purl: lib.Purl, Digest: []string{"sha256:ee"}}},
+		edges:          []GuacEdge{SameAsEdge{PackageNode: lib, ArtifactEquivalent: bad}},
+		mode:           DigestMismatchMark,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:npm/lib@1 expects sha256:ee"},
+		wantEdges:      2,
+	}, {
+		name:           "stored digest",
+		edges:          []GuacEdge{SameAsEdge{PackageNode: lib, ArtifactEquivalent: bad}},
+		stored:         map[string][]string{lib.Purl: {"SHA256:FF"}},
+		mode:           DigestMismatchMark,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:npm/lib@1 expects sha256:ff"},
+		wantEdges:      2,
+	}, {
+		name: "reported once",
+		edges: []GuacEdge{
+			SameAsEdge{PackageNode: app, ArtifactEquivalent: bad},
+			SameAsEdge{ArtifactNode: bad, PackageEquivalent: app},
+		},
+		mode:           DigestMismatchMark,
+		wantMismatches: []string{"artifact ghcr.io/app has digest sha256:cc, package pkg:oci/app expects sha256:aa"},
+		wantEdges:      3,
+	}, {
+		name:      "other edges are ignored",
+		edges:     []GuacEdge{ContainsEdge{PackageNode: app, ContainedArtifact: bad}},
+		mode:      DigestMismatchReject,
+		wantEdges: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, mismatches, err := CheckDigestMismatches(Graph{Nodes: tt.nodes, Edges: tt.edges}, tt.stored, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckDigestMismatches() error = %v, want %v", err, tt.wantErr)
+			}
+			got := []string{}
+			for _, m := range mismatches {
+				got = append(got, m.String())
+			}
+			if len(tt.wantMismatches) == 0 {
+				tt.wantMismatches = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.wantMismatches) {
+				t.Errorf("CheckDigestMismatches() mismatches = %v, want %v", got, tt.wantMismatches)
+			}
+			if len(g.Edges) != tt.wantEdges {
+				t.Errorf("CheckDigestMismatches() returned %d edges, want %d", len(g.Edges), tt.wantEdges)
+			}
+			if err := ValidateGraph(g); err != nil {
+				t.Errorf("CheckDigestMismatches() returned an invalid graph: %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryBackend_ExpectedDigests(t *testing.T) {
+	b := NewMemoryBackend()
+	g := Graph{Nodes: []GuacNode{
+		PackageNode{Purl: "pkg:oci/app", Digest: []string{"SHA256:AA"}},
+		PackageNode{Purl: "pkg:npm/lib@1"},
+		PackageNode{Purl: "pkg:npm/other@1", Digest: []string{"sha256:bb"}},
+	}}
+	if err := b.Store(context.Background(), g); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	got, err := b.ExpectedDigests([]string{"pkg:oci/app", "pkg:npm/lib@1", "pkg:npm/missing@1"})
+	if err != nil {
+		t.Fatalf("ExpectedDigests() error = %v", err)
+	}
+	want := map[string][]string{"pkg:oci/app": {"sha256:aa"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpectedDigests() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MemoryBackend is a Backend that keeps the graph in memory. It needs no
+// external database, so it is meant for tests and demos.
+//
+// Nodes and edges are deduplicated like in the other backends: storing a node
+// that has the same type and identifiable properties as an existing node
+// replaces it and merges its properties.
+type MemoryBackend struct {
+	mu sync.RWMutex
+	// nodes and edges are indexed by `nodeIdentity` and `edgeIdentity`
+	nodes map[string]*memoryNode
+	edges map[string]GuacEdge
+	// nodeOrder and edgeOrder keep the insertion order, so that results are
+	// deterministic
+	nodeOrder []string
+	edgeOrder []string
+	// out and in map a node identity to the identities of its outgoing and
+	// incoming edges
+	out map[string][]string
+	in  map[string][]string
+}
+
+type memoryNode struct {
+	node       GuacNode
+	properties map[string]interface{}
+}
+
+// NewMemoryBackend returns an empty MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		nodes: map[string]*memoryNode{},
+		edges: map[string]GuacEdge{},
+		out:   map[string][]string{},
+		in:    map[string][]string{},
+	}
+}
+
+func (b *MemoryBackend) Store(ctx context.Context, g Graph) error {
+	return storeGraph(ctx, b, g)
+}
+
+func (b *MemoryBackend) StoreNodes(nodes []GuacNode) error {
+	if err := ValidateGraph(Graph{Nodes: nodes}); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, n := range nodes {
+		if _, err := b.storeNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) StoreEdges(edges []GuacEdge) error {
+	if err := ValidateGraph(Graph{Edges: edges}); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range edges {
+		v, u := e.Nodes()
+		vID, err := b.storeNode(v)
+		if err != nil {
+			return err
+		}
+		uID, err := b.storeNode(u)
+		if err != nil {
+			return err
+		}
+		id, err := edgeIdentity(e)
+		if err != nil {
+			return err
+		}
+		if _, ok := b.edges[id]; !ok {
+			b.edgeOrder = append(b.edgeOrder, id)
+			b.out[vID] = append(b.out[vID], id)
+			b.in[uID] = append(b.in[uID], id)
+		}
+		b.edges[id] = e
+	}
+	return nil
+}
+
+// Ping always succeeds, there is no database to reach
+func (b *MemoryBackend) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
+
+// storeNode writes the node and returns its identity. Must be called with the
+// lock held.
+func (b *MemoryBackend) storeNode(n GuacNode) (string, error) {
+	id, err := nodeIdentity(n)
+	if err != nil {
+		return "", err
+	}
+	existing, ok := b.nodes[id]
+	if !ok {
+		existing = &memoryNode{properties: map[string]interface{}{}}
+		b.nodes[id] = existing
+		b.nodeOrder = append(b.nodeOrder, id)
+	}
+	existing.node = n
+	for k, v := range n.Properties() {
+		existing.properties[k] = v
+	}
+	return id, nil
+}
+
+// Graph returns all the nodes and edges that have been stored
+func (b *MemoryBackend) Graph() Graph {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	g := Graph{
+		Nodes: make([]GuacNode, 0, len(b.nodeOrder)),
+		Edges: make([]GuacEdge, 0, len(b.edgeOrder)),
+	}
+	for _, id := range b.nodeOrder {
+		g.Nodes = append(g.Nodes, b.nodes[id].node)
+	}
+	for _, id := range b.edgeOrder {
+		g.Edges = append(g.Edges, b.edges[id])
+	}
+	return g
+}
+
+// GetNode returns the node of the given type whose identifiable properties
+// have the values in key, e.g. `GetNode("Package", map[string]interface{}{"purl": purl})`
+func (b *MemoryBackend) GetNode(nodeType string, key map[string]interface{}) (GuacNode, bool) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if n, ok := b.nodes[nodeType+string(k)]; ok {
+		return n.node, true
+	}
+	return nil, false
+}
+
+// NodeProperties returns the properties of the stored node, merged from all
+// the times it has been stored
+func (b *MemoryBackend) NodeProperties(n GuacNode) (map[string]interface{}, bool) {
+	id, err := nodeIdentity(n)
+	if err != nil {
+		return nil, false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stored, ok := b.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	properties := make(map[string]interface{}, len(stored.properties))
+	for k, v := range stored.properties {
+		properties[k] = v
+	}
+	return properties, true
+}
+
+// Neighbors returns the nodes u for which there is an edge n-[edgeType]->u.
+// If edgeType is empty, edges of all types are followed.
+func (b *MemoryBackend) Neighbors(n GuacNode, edgeType string) []GuacNode {
+	return b.neighbors(n, edgeType, b.out, func(e GuacEdge) GuacNode {
+		_, u := e.Nodes()
+		return u
+	})
+}
+
+// ReverseNeighbors returns the nodes v for which there is an edge
+// v-[edgeType]->n. If edgeType is empty, edges of all types are followed.
+func (b *MemoryBackend) ReverseNeighbors(n GuacNode, edgeType string) []GuacNode {
+	return b.neighbors(n, edgeType, b.in, func(e GuacEdge) GuacNode {
+		v, _ := e.Nodes()
+		return v
+	})
+}
+
+func (b *MemoryBackend) neighbors(n GuacNode, edgeType string, index map[string][]string, other func(GuacEdge) GuacNode) []GuacNode {
+	id, err := nodeIdentity(n)
+	if err != nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	result := []GuacNode{}
+	for _, edgeID := range index[id] {
+		e := b.edges[edgeID]
+		if edgeType != "" && e.Type() != edgeType {
+			continue
+		}
+		// return the stored node, which may be more recent than the edge
+		otherID, err := nodeIdentity(other(e))
+		if err != nil {
+			continue
+		}
+		result = append(result, b.nodes[otherID].node)
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	app := PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+	lib := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+	libDup := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1", Version: "v1"}
+	vuln := VulnerabilityNode{ID: "CVE-2022-1234"}
+
+	b := NewMemoryBackend()
+	if err := b.StoreNodes([]GuacNode{app, lib}); err != nil {
+		t.Fatalf("StoreNodes() error = %v", err)
+	}
+	err := b.StoreEdges([]GuacEdge{
+		DependsOnEdge{PackageNode: app, PackageDependency: lib},
+		DependsOnEdge{PackageNode: app, PackageDependency: libDup},
+		VulnerableToEdge{PackageNode: libDup, VulnerabilityNode: vuln},
+	})
+	if err != nil {
+		t.Fatalf("StoreEdges() error = %v", err)
+	}
+
+	g := b.Graph()
+	if len(g.Nodes) != 3 || len(g.Edges) != 2 {
+		t.Fatalf("got %d nodes and %d edges, want 3 and 2", len(g.Nodes), len(g.Edges))
+	}
+
+	n, ok := b.GetNode("Package", map[string]interface{}{"purl": "pkg:golang/lib@v1"})
+	if !ok {
+		t.Fatalf("GetNode() did not find the package")
+	}
+	if !reflect.DeepEqual(n, libDup) {
+		t.Errorf("GetNode() = %v, want %v", n, libDup)
+	}
+	if _, ok := b.GetNode("Package", map[string]interface{}{"purl": "pkg:golang/missing@v1"}); ok {
+		t.Errorf("GetNode() found a missing package")
+	}
+
+	props, ok := b.NodeProperties(lib)
+	if !ok || props["version"] != "v1" {
+		t.Errorf("NodeProperties() = %v, want version v1", props)
+	}
+
+	if got := b.Neighbors(app, "DependsOn"); !reflect.DeepEqual(got, []GuacNode{libDup}) {
+		t.Errorf("Neighbors(app, DependsOn) = %v, want %v", got, []GuacNode{libDup})
+	}
+	if got := b.Neighbors(app, "VulnerableTo"); len(got) != 0 {
+		t.Errorf("Neighbors(app, VulnerableTo) = %v, want none", got)
+	}
+	if got := b.Neighbors(lib, ""); !reflect.DeepEqual(got, []GuacNode{vuln}) {
+		t.Errorf("Neighbors(lib) = %v, want %v", got, []GuacNode{vuln})
+	}
+	if got := b.ReverseNeighbors(lib, "DependsOn"); !reflect.DeepEqual(got, []GuacNode{app}) {
+		t.Errorf("ReverseNeighbors(lib, DependsOn) = %v, want %v", got, []GuacNode{app})
+	}
+}
+
+func TestMemoryBackend_Store(t *testing.T) {
+	app := PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+	lib := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+	g := Graph{
+		Nodes: []GuacNode{app},
+		Edges: []GuacEdge{DependsOnEdge{PackageNode: app, PackageDependency: lib}},
+	}
+
+	var s Storer = NewMemoryBackend()
+	if err := s.Store(context.Background(), g); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	got := s.(*MemoryBackend).Graph()
+	if len(got.Nodes) != 2 || len(got.Edges) != 1 {
+		t.Errorf("got %d nodes and %d edges, want 2 and 1", len(got.Nodes), len(got.Edges))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := NewMemoryBackend()
+	if err := b.Store(ctx, g); !errors.Is(err, context.Canceled) {
+		t.Errorf("Store() error = %v, want %v", err, context.Canceled)
+	}
+	if got := b.Graph(); len(got.Nodes) != 0 {
+		t.Errorf("got %d nodes after canceling, want none", len(got.Nodes))
+	}
+}
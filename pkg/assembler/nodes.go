@@ -26,7 +26,7 @@ type ArtifactNode struct {
 }
 
 func (an ArtifactNode) Type() string {
-	return "Artifact"
+	return NodeTypeArtifact
 }
 
 func (an ArtifactNode) Properties() map[string]interface{} {
@@ -61,7 +61,7 @@ type PackageNode struct {
 }
 
 func (pn PackageNode) Type() string {
-	return "Package"
+	return NodeTypePackage
 }
 
 func (pn PackageNode) Properties() map[string]interface{} {
@@ -110,7 +110,7 @@ type IdentityNode struct {
 }
 
 func (in IdentityNode) Type() string {
-	return "Identity"
+	return NodeTypeIdentity
 }
 
 func (in IdentityNode) Properties() map[string]interface{} {
@@ -146,7 +146,7 @@ type AttestationNode struct {
 }
 
 func (an AttestationNode) Type() string {
-	return "Attestation"
+	return NodeTypeAttestation
 }
 
 func (an AttestationNode) Properties() map[string]interface{} {
@@ -183,7 +183,7 @@ type BuilderNode struct {
 }
 
 func (bn BuilderNode) Type() string {
-	return "Builder"
+	return NodeTypeBuilder
 }
 
 func (bn BuilderNode) Properties() map[string]interface{} {
@@ -213,7 +213,7 @@ type MetadataNode struct {
 }
 
 func (mn MetadataNode) Type() string {
-	return "Metadata"
+	return NodeTypeMetadata
 }
 
 func (mn MetadataNode) Properties() map[string]interface{} {
@@ -248,7 +248,7 @@ type VulnerabilityNode struct {
 }
 
 func (vn VulnerabilityNode) Type() string {
-	return "Vulnerability"
+	return NodeTypeVulnerability
 }
 
 func (vn VulnerabilityNode) Properties() map[string]interface{} {
@@ -269,6 +269,331 @@ func (vn VulnerabilityNode) IdentifiablePropertyNames() []string {
 	return []string{"id"}
 }
 
+// StepNode is a node that represents a step of an in-toto supply chain, as
+// performed by the functionary holding the key with ID `KeyID`. Steps are
+// created both from layouts, which only know the expected steps, and from
+// links, which also record the command that was run.
+type StepNode struct {
+	Name     string
+	KeyID    string
+	Command  []string
+	Link     string
+	NodeData objectMetadata
+}
+
+func (sn StepNode) Type() string {
+	return NodeTypeStep
+}
+
+func (sn StepNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["name"] = sn.Name
+	properties["keyid"] = sn.KeyID
+	// Only set by links, so that storing the step of a layout does not
+	// erase what was recorded by the link
+	if sn.Link != "" {
+		properties["link"] = strings.ToLower(sn.Link)
+	}
+	if len(sn.Command) > 0 {
+		properties["command"] = sn.Command
+	}
+	sn.NodeData.addProperties(properties)
+	return properties
+}
+
+func (sn StepNode) PropertyNames() []string {
+	fields := []string{"name", "keyid", "link", "command"}
+	fields = append(fields, sn.NodeData.getProperties()...)
+	return fields
+}
+
+func (sn StepNode) IdentifiablePropertyNames() []string {
+	// The same step performed by different functionaries counts towards
+	// the threshold of the step, so they must be different nodes
+	return []string{"name", "keyid"}
+}
+
+// LayoutNode is a node that represents an in-toto layout, defining the
+// steps of a supply chain
+type LayoutNode struct {
+	Digest   string
+	Expires  string
+	Readme   string
+	Steps    []string
+	NodeData objectMetadata
+}
+
+func (ln LayoutNode) Type() string {
+	return NodeTypeLayout
+}
+
+func (ln LayoutNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["digest"] = strings.ToLower(ln.Digest)
+	properties["expires"] = ln.Expires
+	properties["readme"] = ln.Readme
+	properties["steps"] = ln.Steps
+	ln.NodeData.addProperties(properties)
+	return properties
+}
+
+func (ln LayoutNode) PropertyNames() []string {
+	fields := []string{"digest", "expires", "readme", "steps"}
+	fields = append(fields, ln.NodeData.getProperties()...)
+	return fields
+}
+
+func (ln LayoutNode) IdentifiablePropertyNames() []string {
+	return []string{"digest"}
+}
+
+// CPENode is a node that represents a CPE (Common Platform Enumeration)
+// name, e.g. `cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*`. Packages are
+// linked to their CPEs via `HasCPEEdge`, so that advisories that only know
+// the CPEs of the affected software (e.g. NVD) can reach them.
+type CPENode struct {
+	CPE      string
+	NodeData objectMetadata
+}
+
+func (cn CPENode) Type() string {
+	return NodeTypeCPE
+}
+
+func (cn CPENode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["cpe"] = normalizeCPE(cn.CPE)
+	cn.NodeData.addProperties(properties)
+	return properties
+}
+
+func (cn CPENode) PropertyNames() []string {
+	fields := []string{"cpe"}
+	fields = append(fields, cn.NodeData.getProperties()...)
+	return fields
+}
+
+func (cn CPENode) IdentifiablePropertyNames() []string {
+	return []string{"cpe"}
+}
+
+// LifecycleNode is a node that represents a release cycle of the package
+// with the purl (without version), e.g. the `3.11` cycle of
+// `pkg:generic/python`. EOL and Support are the dates at which the cycle
+// reaches its end of life and of active support; when the date of the end of
+// life is unknown, EOLReached tells whether it is reached. Packages are
+// linked to their cycles via `HasLifecycleEdge`.
+type LifecycleNode struct {
+	Purl        string
+	Cycle       string
+	Product     string
+	ReleaseDate string
+	EOL         string
+	EOLReached  bool
+	Support     string
+	LTS         bool
+	Latest      string
+	NodeData    objectMetadata
+}
+
+func (ln LifecycleNode) Type() string {
+	return NodeTypeLifecycle
+}
+
+func (ln LifecycleNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["purl"] = ln.Purl
+	properties["cycle"] = ln.Cycle
+	properties["eol_reached"] = ln.EOLReached
+	properties["lts"] = ln.LTS
+	if len(ln.Product) > 0 {
+		properties["product"] = ln.Product
+	}
+	if len(ln.ReleaseDate) > 0 {
+		properties["release_date"] = ln.ReleaseDate
+	}
+	if len(ln.EOL) > 0 {
+		properties["eol"] = ln.EOL
+	}
+	if len(ln.Support) > 0 {
+		properties["support"] = ln.Support
+	}
+	if len(ln.Latest) > 0 {
+		properties["latest"] = ln.Latest
+	}
+	ln.NodeData.addProperties(properties)
+	return properties
+}
+
+func (ln LifecycleNode) PropertyNames() []string {
+	fields := []string{"purl", "cycle", "product", "release_date", "eol", "eol_reached", "support", "lts", "latest"}
+	fields = append(fields, ln.NodeData.getProperties()...)
+	return fields
+}
+
+func (ln LifecycleNode) IdentifiablePropertyNames() []string {
+	return []string{"purl", "cycle"}
+}
+
+// LicenseNode is a node that represents a license, given by its SPDX license
+// expression, e.g. `MIT OR Apache-2.0`. Packages are linked to the license
+// they declare via `DeclaredLicenseEdge`, and to the licenses found in their
+// files via `DiscoveredLicenseEdge`.
+//
+// Operator is the top-level operator of compound expressions (AND, OR or
+// WITH), whose operands are linked via `LicenseOperandEdge`; it is empty for
+// a single license or exception.
+type LicenseNode struct {
+	Expression string
+	Operator   string
+	NodeData   objectMetadata
+}
+
+func (ln LicenseNode) Type() string {
+	return NodeTypeLicense
+}
+
+func (ln LicenseNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["expression"] = ln.Expression
+	if ln.Operator != "" {
+		properties["operator"] = ln.Operator
+	}
+	ln.NodeData.addProperties(properties)
+	return properties
+}
+
+func (ln LicenseNode) PropertyNames() []string {
+	fields := []string{"expression", "operator"}
+	fields = append(fields, ln.NodeData.getProperties()...)
+	return fields
+}
+
+func (ln LicenseNode) IdentifiablePropertyNames() []string {
+	return []string{"expression"}
+}
+
+// SourceNode is a node that represents a source repository, identified by
+// its URL (e.g. "https://github.com/guacsec/guac"). VCS is the version
+// control system of the repository, e.g. "git".
+type SourceNode struct {
+	URL      string
+	VCS      string
+	NodeData objectMetadata
+}
+
+func (sn SourceNode) Type() string {
+	return NodeTypeSource
+}
+
+func (sn SourceNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["url"] = sn.URL
+	properties["vcs"] = sn.VCS
+	sn.NodeData.addProperties(properties)
+	return properties
+}
+
+func (sn SourceNode) PropertyNames() []string {
+	fields := []string{"url", "vcs"}
+	fields = append(fields, sn.NodeData.getProperties()...)
+	return fields
+}
+
+func (sn SourceNode) IdentifiablePropertyNames() []string {
+	return []string{"url"}
+}
+
+// CommitNode is a node that represents a commit, identified by the version
+// control system and the commit id (the sha1 of a git commit). Since a commit
+// can be found in several repositories (e.g. forks), the repository the
+// commit was referenced from is not identifiable.
+type CommitNode struct {
+	VCS        string
+	Sha        string
+	Repository string
+	NodeData   objectMetadata
+}
+
+func (cn CommitNode) Type() string {
+	return NodeTypeCommit
+}
+
+func (cn CommitNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["vcs"] = cn.VCS
+	properties["sha"] = cn.Sha
+	properties["repository"] = cn.Repository
+	cn.NodeData.addProperties(properties)
+	return properties
+}
+
+func (cn CommitNode) PropertyNames() []string {
+	fields := []string{"vcs", "sha", "repository"}
+	fields = append(fields, cn.NodeData.getProperties()...)
+	return fields
+}
+
+func (cn CommitNode) IdentifiablePropertyNames() []string {
+	return []string{"vcs", "sha"}
+}
+
+// FindingNode is a node that represents a result of a static analysis tool,
+// e.g. a SARIF result: the rule RuleID of Tool matched at Location (the path
+// of the file, followed by the line and column when known) of Subject, the
+// commit, repository or artifact analyzed (its sha, URL or digest). Level is
+// the SARIF level (`error`, `warning`, `note` or `none`) and Severity the
+// security severity of the rule (`critical`, `high`, `medium` or `low`), if
+// the tool gives one. Findings are linked to their subject via
+// `HasFindingEdge`.
+type FindingNode struct {
+	Subject     string
+	Tool        string
+	RuleID      string
+	Location    string
+	Level       string
+	Severity    string
+	Message     string
+	Fingerprint string
+	NodeData    objectMetadata
+}
+
+func (fn FindingNode) Type() string {
+	return NodeTypeFinding
+}
+
+func (fn FindingNode) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["subject"] = fn.Subject
+	properties["tool"] = fn.Tool
+	properties["rule_id"] = fn.RuleID
+	properties["location"] = fn.Location
+	if len(fn.Level) > 0 {
+		properties["level"] = fn.Level
+	}
+	if len(fn.Severity) > 0 {
+		properties["severity"] = fn.Severity
+	}
+	if len(fn.Message) > 0 {
+		properties["message"] = fn.Message
+	}
+	if len(fn.Fingerprint) > 0 {
+		properties["fingerprint"] = fn.Fingerprint
+	}
+	fn.NodeData.addProperties(properties)
+	return properties
+}
+
+func (fn FindingNode) PropertyNames() []string {
+	fields := []string{"subject", "tool", "rule_id", "location", "level", "severity", "message", "fingerprint"}
+	fields = append(fields, fn.NodeData.getProperties()...)
+	return fields
+}
+
+func (fn FindingNode) IdentifiablePropertyNames() []string {
+	return []string{"subject", "tool", "rule_id", "location"}
+}
+
 // IdentityForEdge is an edge that represents the fact that an
 // `IdentityNode` is an identity for an `AttestationNode`.
 type IdentityForEdge struct {
@@ -277,7 +602,7 @@ type IdentityForEdge struct {
 }
 
 func (e IdentityForEdge) Type() string {
-	return "Identity"
+	return EdgeTypeIdentityFor
 }
 
 func (e IdentityForEdge) Nodes() (v, u GuacNode) {
@@ -306,7 +631,7 @@ type AttestationForEdge struct {
 }
 
 func (e AttestationForEdge) Type() string {
-	return "Attestation"
+	return EdgeTypeAttestationFor
 }
 
 func (e AttestationForEdge) Nodes() (v, u GuacNode) {
@@ -345,7 +670,7 @@ type BuiltByEdge struct {
 }
 
 func (e BuiltByEdge) Type() string {
-	return "BuiltBy"
+	return EdgeTypeBuiltBy
 }
 
 func (e BuiltByEdge) Nodes() (v, u GuacNode) {
@@ -364,18 +689,55 @@ func (e BuiltByEdge) IdentifiablePropertyNames() []string {
 	return []string{}
 }
 
+// BuiltFromEdge is an edge that represents the fact that an
+// `ArtifactNode` has been built from a material `ArtifactNode`, as
+// recorded in its build provenance
+type BuiltFromEdge struct {
+	ArtifactNode ArtifactNode
+	MaterialNode ArtifactNode
+}
+
+func (e BuiltFromEdge) Type() string {
+	return EdgeTypeBuiltFrom
+}
+
+func (e BuiltFromEdge) Nodes() (v, u GuacNode) {
+	return e.ArtifactNode, e.MaterialNode
+}
+
+func (e BuiltFromEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e BuiltFromEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e BuiltFromEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
 // DependsOnEdge is an edge that represents the fact that an
 // `ArtifactNode/PackageNode` depends on another `ArtifactNode/PackageNode`
 // Only one of each side of the edge should be defined.
+// Manifest and Detector are set when the dependency was reported by a
+// dependency detector (e.g. via GitHub's dependency submission API) rather
+// than an SBOM: the path of the manifest file it was found in and the name
+// of the detector. Relationship is `direct` or `indirect` when the source
+// tells whether a project needs the dependency itself or only for its
+// dependencies (e.g. `// indirect` requirements of go.mod).
 type DependsOnEdge struct {
 	ArtifactNode       ArtifactNode
 	PackageNode        PackageNode
 	ArtifactDependency ArtifactNode
 	PackageDependency  PackageNode
+	Manifest           string
+	Detector           string
+	Relationship       string
 }
 
 func (e DependsOnEdge) Type() string {
-	return "DependsOn"
+	return EdgeTypeDependsOn
 }
 
 func (e DependsOnEdge) Nodes() (v, u GuacNode) {
@@ -405,11 +767,21 @@ func (e DependsOnEdge) Nodes() (v, u GuacNode) {
 }
 
 func (e DependsOnEdge) Properties() map[string]interface{} {
-	return map[string]interface{}{}
+	properties := make(map[string]interface{})
+	if e.Manifest != "" {
+		properties["manifest"] = e.Manifest
+	}
+	if e.Detector != "" {
+		properties["detector"] = e.Detector
+	}
+	if e.Relationship != "" {
+		properties["relationship"] = e.Relationship
+	}
+	return properties
 }
 
 func (e DependsOnEdge) PropertyNames() []string {
-	return []string{}
+	return []string{"manifest", "detector", "relationship"}
 }
 
 func (e DependsOnEdge) IdentifiablePropertyNames() []string {
@@ -424,7 +796,7 @@ type ContainsEdge struct {
 }
 
 func (e ContainsEdge) Type() string {
-	return "Contains"
+	return EdgeTypeContains
 }
 
 func (e ContainsEdge) Nodes() (v, u GuacNode) {
@@ -455,7 +827,7 @@ type MetadataForEdge struct {
 }
 
 func (e MetadataForEdge) Type() string {
-	return "MetadataFor"
+	return EdgeTypeMetadataFor
 }
 
 func (e MetadataForEdge) Nodes() (v, u GuacNode) {
@@ -495,7 +867,7 @@ type VulnerableEdge struct {
 }
 
 func (e VulnerableEdge) Type() string {
-	return "Vulnerable"
+	return EdgeTypeVulnerable
 }
 
 func (e VulnerableEdge) Nodes() (v, u GuacNode) {
@@ -513,3 +885,834 @@ func (e VulnerableEdge) PropertyNames() []string {
 func (e VulnerableEdge) IdentifiablePropertyNames() []string {
 	return []string{}
 }
+
+// VulnerableToEdge is an edge that represents the fact that a
+// `PackageNode` is affected by a `VulnerabilityNode`, as reported
+// by an SBOM
+type VulnerableToEdge struct {
+	PackageNode       PackageNode
+	VulnerabilityNode VulnerabilityNode
+}
+
+func (e VulnerableToEdge) Type() string {
+	return EdgeTypeVulnerableTo
+}
+
+func (e VulnerableToEdge) Nodes() (v, u GuacNode) {
+	return e.PackageNode, e.VulnerabilityNode
+}
+
+func (e VulnerableToEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e VulnerableToEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e VulnerableToEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// GeneratedFromEdge is an edge that represents the fact that an
+// `ArtifactNode/PackageNode` has been generated from another
+// `ArtifactNode/PackageNode` (e.g., a binary from its sources).
+// Only one of each side of the edge should be defined.
+type GeneratedFromEdge struct {
+	ArtifactNode   ArtifactNode
+	PackageNode    PackageNode
+	ArtifactSource ArtifactNode
+	PackageSource  PackageNode
+}
+
+func (e GeneratedFromEdge) Type() string {
+	return EdgeTypeGeneratedFrom
+}
+
+func (e GeneratedFromEdge) Nodes() (v, u GuacNode) {
+	vA, vP := isDefined(e.ArtifactNode), isDefined(e.PackageNode)
+	uA, uP := isDefined(e.ArtifactSource), isDefined(e.PackageSource)
+	if vA == vP {
+		panic("only one of package and artifact node defined for GeneratedFrom relationship")
+	}
+
+	if uA == uP {
+		panic("only one of package and artifact source node defined for GeneratedFrom relationship")
+	}
+
+	if vA {
+		v = e.ArtifactNode
+	} else {
+		v = e.PackageNode
+	}
+
+	if uA {
+		u = e.ArtifactSource
+	} else {
+		u = e.PackageSource
+	}
+
+	return v, u
+}
+
+func (e GeneratedFromEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e GeneratedFromEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e GeneratedFromEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// StaticLinkEdge is an edge that represents the fact that an
+// `ArtifactNode/PackageNode` statically links another
+// `ArtifactNode/PackageNode`.
+// Only one of each side of the edge should be defined.
+type StaticLinkEdge struct {
+	ArtifactNode   ArtifactNode
+	PackageNode    PackageNode
+	LinkedArtifact ArtifactNode
+	LinkedPackage  PackageNode
+}
+
+func (e StaticLinkEdge) Type() string {
+	return EdgeTypeStaticLink
+}
+
+func (e StaticLinkEdge) Nodes() (v, u GuacNode) {
+	vA, vP := isDefined(e.ArtifactNode), isDefined(e.PackageNode)
+	uA, uP := isDefined(e.LinkedArtifact), isDefined(e.LinkedPackage)
+	if vA == vP {
+		panic("only one of package and artifact node defined for StaticLink relationship")
+	}
+
+	if uA == uP {
+		panic("only one of package and artifact linked node defined for StaticLink relationship")
+	}
+
+	if vA {
+		v = e.ArtifactNode
+	} else {
+		v = e.PackageNode
+	}
+
+	if uA {
+		u = e.LinkedArtifact
+	} else {
+		u = e.LinkedPackage
+	}
+
+	return v, u
+}
+
+func (e StaticLinkEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e StaticLinkEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e StaticLinkEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// AliasOfEdge is an edge that represents the fact that a
+// `VulnerabilityNode` is also known under the ID of another
+// `VulnerabilityNode` (e.g., a GHSA advisory and its CVE).
+type AliasOfEdge struct {
+	VulnerabilityNode VulnerabilityNode
+	AliasNode         VulnerabilityNode
+}
+
+func (e AliasOfEdge) Type() string {
+	return EdgeTypeAliasOf
+}
+
+func (e AliasOfEdge) Nodes() (v, u GuacNode) {
+	return e.VulnerabilityNode, e.AliasNode
+}
+
+func (e AliasOfEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e AliasOfEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e AliasOfEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// AffectsEdge is an edge that represents the fact that a
+// `VulnerabilityNode` affects some versions of a `PackageNode`, as
+// reported by an advisory. The affected versions are given by the
+// explicit Versions and by the Ranges, each being the JSON encoding of an
+// OSV range (e.g. `{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"1.2.3"}]}`).
+type AffectsEdge struct {
+	VulnerabilityNode VulnerabilityNode
+	PackageNode       PackageNode
+	Ranges            []string
+	Versions          []string
+}
+
+func (e AffectsEdge) Type() string {
+	return EdgeTypeAffects
+}
+
+func (e AffectsEdge) Nodes() (v, u GuacNode) {
+	return e.VulnerabilityNode, e.PackageNode
+}
+
+func (e AffectsEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if len(e.Ranges) > 0 {
+		properties["ranges"] = e.Ranges
+	}
+	if len(e.Versions) > 0 {
+		properties["versions"] = e.Versions
+	}
+	return properties
+}
+
+func (e AffectsEdge) PropertyNames() []string {
+	return []string{"ranges", "versions"}
+}
+
+func (e AffectsEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasCPEEdge is an edge that represents the fact that a `PackageNode` is
+// identified by a `CPENode`, as given by an SBOM
+type HasCPEEdge struct {
+	PackageNode PackageNode
+	CPENode     CPENode
+}
+
+func (e HasCPEEdge) Type() string {
+	return EdgeTypeHasCPE
+}
+
+func (e HasCPEEdge) Nodes() (v, u GuacNode) {
+	return e.PackageNode, e.CPENode
+}
+
+func (e HasCPEEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e HasCPEEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e HasCPEEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasLifecycleEdge is an edge that represents the fact that a `PackageNode`
+// (without version) has the release cycle given by a `LifecycleNode`. The
+// versions of the package in the cycle are found by matching the cycle
+// against their version, see `lifecycle.MatchCycle`.
+type HasLifecycleEdge struct {
+	PackageNode   PackageNode
+	LifecycleNode LifecycleNode
+}
+
+func (e HasLifecycleEdge) Type() string {
+	return EdgeTypeHasLifecycle
+}
+
+func (e HasLifecycleEdge) Nodes() (v, u GuacNode) {
+	return e.PackageNode, e.LifecycleNode
+}
+
+func (e HasLifecycleEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e HasLifecycleEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e HasLifecycleEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// DeclaredLicenseEdge is an edge that represents the fact that a
+// `PackageNode` declares the license given by a `LicenseNode`, e.g. in the
+// metadata of the package registry
+type DeclaredLicenseEdge struct {
+	PackageNode PackageNode
+	LicenseNode LicenseNode
+}
+
+func (e DeclaredLicenseEdge) Type() string {
+	return EdgeTypeDeclaredLicense
+}
+
+func (e DeclaredLicenseEdge) Nodes() (v, u GuacNode) {
+	return e.PackageNode, e.LicenseNode
+}
+
+func (e DeclaredLicenseEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e DeclaredLicenseEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e DeclaredLicenseEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// DiscoveredLicenseEdge is an edge that represents the fact that the license
+// given by a `LicenseNode` was found in the files of a `PackageNode`
+type DiscoveredLicenseEdge struct {
+	PackageNode PackageNode
+	LicenseNode LicenseNode
+}
+
+func (e DiscoveredLicenseEdge) Type() string {
+	return EdgeTypeDiscoveredLicense
+}
+
+func (e DiscoveredLicenseEdge) Nodes() (v, u GuacNode) {
+	return e.PackageNode, e.LicenseNode
+}
+
+func (e DiscoveredLicenseEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e DiscoveredLicenseEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e DiscoveredLicenseEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// LicenseOperandEdge is an edge that represents the fact that the license
+// given by `OperandNode` is an operand of the compound license expression
+// given by `LicenseNode`. The operands of a WITH expression are the license
+// and its exception.
+type LicenseOperandEdge struct {
+	LicenseNode LicenseNode
+	OperandNode LicenseNode
+}
+
+func (e LicenseOperandEdge) Type() string {
+	return EdgeTypeLicenseOperand
+}
+
+func (e LicenseOperandEdge) Nodes() (v, u GuacNode) {
+	return e.LicenseNode, e.OperandNode
+}
+
+func (e LicenseOperandEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e LicenseOperandEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e LicenseOperandEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// CPEAffectsEdge is like `AffectsEdge`, for advisories that identify the
+// affected software by its CPE rather than its purl. The packages it affects
+// are those linked to the `CPENode` via `HasCPEEdge`.
+type CPEAffectsEdge struct {
+	VulnerabilityNode VulnerabilityNode
+	CPENode           CPENode
+	Ranges            []string
+	Versions          []string
+}
+
+func (e CPEAffectsEdge) Type() string {
+	return EdgeTypeAffects
+}
+
+func (e CPEAffectsEdge) Nodes() (v, u GuacNode) {
+	return e.VulnerabilityNode, e.CPENode
+}
+
+func (e CPEAffectsEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if len(e.Ranges) > 0 {
+		properties["ranges"] = e.Ranges
+	}
+	if len(e.Versions) > 0 {
+		properties["versions"] = e.Versions
+	}
+	return properties
+}
+
+func (e CPEAffectsEdge) PropertyNames() []string {
+	return []string{"ranges", "versions"}
+}
+
+func (e CPEAffectsEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// VexStatementEdge is an edge that represents the fact that a VEX (Vulnerability
+// Exploitability eXchange) document states whether a `VulnerabilityNode`
+// affects a `PackageNode`. Status is one of `not_affected`, `affected`,
+// `fixed` or `under_investigation`; Justification explains a `not_affected`
+// status (e.g. `vulnerable_code_not_in_execute_path`).
+type VexStatementEdge struct {
+	VulnerabilityNode VulnerabilityNode
+	PackageNode       PackageNode
+	Status            string
+	Justification     string
+	ImpactStatement   string
+	ActionStatement   string
+}
+
+func (e VexStatementEdge) Type() string {
+	return EdgeTypeVexStatement
+}
+
+func (e VexStatementEdge) Nodes() (v, u GuacNode) {
+	return e.VulnerabilityNode, e.PackageNode
+}
+
+func (e VexStatementEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["status"] = e.Status
+	if e.Justification != "" {
+		properties["justification"] = e.Justification
+	}
+	if e.ImpactStatement != "" {
+		properties["impact_statement"] = e.ImpactStatement
+	}
+	if e.ActionStatement != "" {
+		properties["action_statement"] = e.ActionStatement
+	}
+	return properties
+}
+
+func (e VexStatementEdge) PropertyNames() []string {
+	return []string{"status", "justification", "impact_statement", "action_statement"}
+}
+
+func (e VexStatementEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// MaterialEdge is an edge that represents the fact that an `ArtifactNode`
+// was used as a material by a `StepNode`.
+type MaterialEdge struct {
+	StepNode     StepNode
+	ArtifactNode ArtifactNode
+}
+
+func (e MaterialEdge) Type() string {
+	return EdgeTypeMaterial
+}
+
+func (e MaterialEdge) Nodes() (v, u GuacNode) {
+	return e.StepNode, e.ArtifactNode
+}
+
+func (e MaterialEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e MaterialEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e MaterialEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// ProductEdge is an edge that represents the fact that an `ArtifactNode`
+// was produced by a `StepNode`.
+type ProductEdge struct {
+	StepNode     StepNode
+	ArtifactNode ArtifactNode
+}
+
+func (e ProductEdge) Type() string {
+	return EdgeTypeProduct
+}
+
+func (e ProductEdge) Nodes() (v, u GuacNode) {
+	return e.StepNode, e.ArtifactNode
+}
+
+func (e ProductEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e ProductEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e ProductEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// ExpectsStepEdge is an edge that represents the fact that a `LayoutNode`
+// expects a link for a `StepNode`. Steps that can be performed by several
+// functionaries have one edge per functionary, and need links from
+// `Threshold` of them.
+type ExpectsStepEdge struct {
+	LayoutNode LayoutNode
+	StepNode   StepNode
+	Threshold  int
+}
+
+func (e ExpectsStepEdge) Type() string {
+	return EdgeTypeExpectsStep
+}
+
+func (e ExpectsStepEdge) Nodes() (v, u GuacNode) {
+	return e.LayoutNode, e.StepNode
+}
+
+func (e ExpectsStepEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	properties["threshold"] = e.Threshold
+	return properties
+}
+
+func (e ExpectsStepEdge) PropertyNames() []string {
+	return []string{"threshold"}
+}
+
+func (e ExpectsStepEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// SameAsEdge is an edge that represents the claim that an
+// `ArtifactNode/PackageNode` identifies the same software as another
+// `ArtifactNode/PackageNode` under a different identity scheme, e.g. a
+// source commit and the digest of the artifact it produced. The claim holds
+// both ways. Only one of each side of the edge should be defined.
+type SameAsEdge struct {
+	ArtifactNode       ArtifactNode
+	PackageNode        PackageNode
+	ArtifactEquivalent ArtifactNode
+	PackageEquivalent  PackageNode
+	Justification      string
+}
+
+func (e SameAsEdge) Type() string {
+	return EdgeTypeSameAs
+}
+
+func (e SameAsEdge) Nodes() (v, u GuacNode) {
+	vA, vP := isDefined(e.ArtifactNode), isDefined(e.PackageNode)
+	uA, uP := isDefined(e.ArtifactEquivalent), isDefined(e.PackageEquivalent)
+	if vA == vP {
+		panic("only one of package and artifact node defined for SameAs relationship")
+	}
+
+	if uA == uP {
+		panic("only one of package and artifact equivalent node defined for SameAs relationship")
+	}
+
+	if vA {
+		v = e.ArtifactNode
+	} else {
+		v = e.PackageNode
+	}
+
+	if uA {
+		u = e.ArtifactEquivalent
+	} else {
+		u = e.PackageEquivalent
+	}
+
+	return v, u
+}
+
+func (e SameAsEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if e.Justification != "" {
+		properties["justification"] = e.Justification
+	}
+	return properties
+}
+
+func (e SameAsEdge) PropertyNames() []string {
+	return []string{"justification"}
+}
+
+func (e SameAsEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasCommitEdge is an edge that represents the fact that the commit given by
+// a `CommitNode` belongs to the repository given by a `SourceNode`
+type HasCommitEdge struct {
+	SourceNode SourceNode
+	CommitNode CommitNode
+}
+
+func (e HasCommitEdge) Type() string {
+	return EdgeTypeHasCommit
+}
+
+func (e HasCommitEdge) Nodes() (v, u GuacNode) {
+	return e.SourceNode, e.CommitNode
+}
+
+func (e HasCommitEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e HasCommitEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e HasCommitEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// BuiltFromSourceEdge is an edge that represents the fact that an
+// `ArtifactNode/PackageNode` was built from the source given by a
+// `CommitNode/SourceNode`. The source is a commit when the revision is
+// known, otherwise the repository. Only one of each side of the edge should
+// be defined.
+type BuiltFromSourceEdge struct {
+	ArtifactNode ArtifactNode
+	PackageNode  PackageNode
+	CommitNode   CommitNode
+	SourceNode   SourceNode
+}
+
+func (e BuiltFromSourceEdge) Type() string {
+	return EdgeTypeBuiltFromSource
+}
+
+func (e BuiltFromSourceEdge) Nodes() (v, u GuacNode) {
+	vA, vP := isDefined(e.ArtifactNode), isDefined(e.PackageNode)
+	uC, uS := isDefined(e.CommitNode), isDefined(e.SourceNode)
+	if vA == vP {
+		panic("only one of package and artifact node defined for BuiltFromSource relationship")
+	}
+
+	if uC == uS {
+		panic("only one of commit and source node defined for BuiltFromSource relationship")
+	}
+
+	if vA {
+		v = e.ArtifactNode
+	} else {
+		v = e.PackageNode
+	}
+
+	if uC {
+		u = e.CommitNode
+	} else {
+		u = e.SourceNode
+	}
+
+	return v, u
+}
+
+func (e BuiltFromSourceEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e BuiltFromSourceEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e BuiltFromSourceEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasFindingEdge is an edge that represents the fact that the static
+// analysis of the `ArtifactNode/CommitNode/SourceNode` reported the finding
+// given by a `FindingNode`. Only one of the analyzed nodes should be defined.
+type HasFindingEdge struct {
+	ArtifactNode ArtifactNode
+	CommitNode   CommitNode
+	SourceNode   SourceNode
+	FindingNode  FindingNode
+}
+
+func (e HasFindingEdge) Type() string {
+	return EdgeTypeHasFinding
+}
+
+func (e HasFindingEdge) Nodes() (v, u GuacNode) {
+	vA, vC, vS := isDefined(e.ArtifactNode), isDefined(e.CommitNode), isDefined(e.SourceNode)
+	switch {
+	case vA && !vC && !vS:
+		v = e.ArtifactNode
+	case vC && !vA && !vS:
+		v = e.CommitNode
+	case vS && !vA && !vC:
+		v = e.SourceNode
+	default:
+		panic("only one of artifact, commit and source node defined for HasFinding relationship")
+	}
+	return v, e.FindingNode
+}
+
+func (e HasFindingEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e HasFindingEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e HasFindingEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasLayerEdge is an edge that represents the fact that the container image
+// given by `ImageNode` is made of the layer given by `LayerNode`. Index is the
+// position of the layer, from the bottom of the image, and CreatedBy the
+// build instruction that created it, when known.
+type HasLayerEdge struct {
+	ImageNode ArtifactNode
+	LayerNode ArtifactNode
+	Index     int
+	CreatedBy string
+}
+
+func (e HasLayerEdge) Type() string {
+	return EdgeTypeHasLayer
+}
+
+func (e HasLayerEdge) Nodes() (v, u GuacNode) {
+	return e.ImageNode, e.LayerNode
+}
+
+func (e HasLayerEdge) Properties() map[string]interface{} {
+	properties := map[string]interface{}{"index": e.Index}
+	if e.CreatedBy != "" {
+		properties["created_by"] = e.CreatedBy
+	}
+	return properties
+}
+
+func (e HasLayerEdge) PropertyNames() []string {
+	return []string{"index", "created_by"}
+}
+
+func (e HasLayerEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// HasConfigEdge is an edge that represents the fact that the manifest of the
+// container image given by `ImageNode` references the image configuration
+// given by `ConfigNode`, whose digest is the image ID.
+type HasConfigEdge struct {
+	ImageNode  ArtifactNode
+	ConfigNode ArtifactNode
+}
+
+func (e HasConfigEdge) Type() string {
+	return EdgeTypeHasConfig
+}
+
+func (e HasConfigEdge) Nodes() (v, u GuacNode) {
+	return e.ImageNode, e.ConfigNode
+}
+
+func (e HasConfigEdge) Properties() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e HasConfigEdge) PropertyNames() []string {
+	return []string{}
+}
+
+func (e HasConfigEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// DerivedFromEdge is an edge that represents the fact that the container
+// image given by `ImageNode` was built on top of a base image, given either
+// by its digest as an `ArtifactNode` or by its name as a `PackageNode`. Only
+// one of the base nodes should be defined. Detection tells how the base
+// image was found: "annotation", "label" or "history".
+type DerivedFromEdge struct {
+	ImageNode    ArtifactNode
+	BaseArtifact ArtifactNode
+	BasePackage  PackageNode
+	Detection    string
+}
+
+func (e DerivedFromEdge) Type() string {
+	return EdgeTypeDerivedFrom
+}
+
+func (e DerivedFromEdge) Nodes() (v, u GuacNode) {
+	uA, uP := isDefined(e.BaseArtifact), isDefined(e.BasePackage)
+	if uA == uP {
+		panic("only one of base artifact and package node defined for DerivedFrom relationship")
+	}
+	if uA {
+		return e.ImageNode, e.BaseArtifact
+	}
+	return e.ImageNode, e.BasePackage
+}
+
+func (e DerivedFromEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if e.Detection != "" {
+		properties["detection"] = e.Detection
+	}
+	return properties
+}
+
+func (e DerivedFromEdge) PropertyNames() []string {
+	return []string{"detection"}
+}
+
+func (e DerivedFromEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
+
+// DigestMismatchEdge is an edge that marks the artifact given by
+// `ArtifactNode` as claimed to be the package given by `PackageNode`, while
+// its digest is not among the digests of the same algorithm expected of the
+// package. Expected lists these digests, so that the conflict can be reviewed
+// without looking up the package.
+type DigestMismatchEdge struct {
+	ArtifactNode ArtifactNode
+	PackageNode  PackageNode
+	Expected     []string
+}
+
+func (e DigestMismatchEdge) Type() string {
+	return EdgeTypeDigestMismatch
+}
+
+func (e DigestMismatchEdge) Nodes() (v, u GuacNode) {
+	return e.ArtifactNode, e.PackageNode
+}
+
+func (e DigestMismatchEdge) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if len(e.Expected) > 0 {
+		properties["expected"] = toLower(e.Expected...)
+	}
+	return properties
+}
+
+func (e DigestMismatchEdge) PropertyNames() []string {
+	return []string{"expected"}
+}
+
+func (e DigestMismatchEdge) IdentifiablePropertyNames() []string {
+	return []string{}
+}
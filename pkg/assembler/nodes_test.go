@@ -123,6 +123,11 @@ func (e MockEdge) IdentifiablePropertyNames() []string {
 	return []string{}
 }
 
+func init() {
+	_ = RegisterNodeType("MockNode")
+	_ = RegisterEdgeType("MockEdge")
+}
+
 func Test_MockNodes(t *testing.T) {
 	client, err := graphdb.EmptyClientForTesting(dbUri)
 	if err != nil {
@@ -177,6 +182,11 @@ func Test_MockNodes(t *testing.T) {
 		t.Errorf("Could not store graph: %v", err)
 	}
 
+	err = StoreGraphUnbatched(graph, client)
+	if err != nil {
+		t.Errorf("Could not store graph unbatched: %v", err)
+	}
+
 	// TODO: retrieve nodes from DB using the last identifiable attribute
 
 }
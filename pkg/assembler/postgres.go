@@ -0,0 +1,129 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"encoding/json"
+
+	"github.com/guacsec/guac/pkg/assembler/postgresdb"
+)
+
+// Note: This module is experimental and might change often!
+
+const (
+	upsertNodeQuery = `INSERT INTO guac_nodes (node_type, node_key, properties)
+VALUES ($1, $2, $3)
+ON CONFLICT (node_type, node_key)
+DO UPDATE SET properties = guac_nodes.properties || EXCLUDED.properties
+RETURNING id`
+
+	upsertEdgeQuery = `INSERT INTO guac_edges (edge_type, from_id, to_id, properties)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (edge_type, from_id, to_id)
+DO UPDATE SET properties = guac_edges.properties || EXCLUDED.properties`
+)
+
+// StoreGraphInPostgres stores a Graph to the PostgreSQL database given by
+// Client. Nodes and edges are upserted, so storing the same graph twice is
+// idempotent.
+func StoreGraphInPostgres(g Graph, client postgresdb.Client) error {
+	if err := ValidateGraph(g); err != nil {
+		return err
+	}
+	return postgresdb.WithTransaction(client, func(tx postgresdb.Transaction) error {
+		for _, n := range g.Nodes {
+			if _, err := upsertPostgresNode(tx, n); err != nil {
+				return err
+			}
+		}
+		for _, e := range g.Edges {
+			if err := upsertPostgresEdge(tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// upsertPostgresNode writes the node and returns its row id
+func upsertPostgresNode(tx postgresdb.Transaction, n GuacNode) (int64, error) {
+	key, err := nodeKey(n)
+	if err != nil {
+		return 0, err
+	}
+	guacID, err := NodeID(n)
+	if err != nil {
+		return 0, err
+	}
+	props, err := json.Marshal(withID(n.Properties(), guacID))
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := tx.QueryRow(upsertNodeQuery, n.Type(), key, string(props)).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// upsertPostgresEdge writes both endpoints of the edge, then the edge itself
+func upsertPostgresEdge(tx postgresdb.Transaction, e GuacEdge) error {
+	a, b := e.Nodes()
+	from, err := upsertPostgresNode(tx, a)
+	if err != nil {
+		return err
+	}
+	to, err := upsertPostgresNode(tx, b)
+	if err != nil {
+		return err
+	}
+	id, err := EdgeID(e)
+	if err != nil {
+		return err
+	}
+	props, err := json.Marshal(withID(e.Properties(), id))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(upsertEdgeQuery, e.Type(), from, to, string(props))
+	return err
+}
+
+// nodeKey returns the JSON object built from the identifiable properties of
+// the node. Since `json.Marshal` sorts map keys, the result is canonical and
+// can be used as the unique key of the node.
+func nodeKey(n GuacNode) (string, error) {
+	key, err := identifiableProperties(n)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// withID returns a copy of the properties, with the id under IDProperty
+func withID(properties map[string]interface{}, id string) map[string]interface{} {
+	props := map[string]interface{}{IDProperty: id}
+	for k, v := range properties {
+		props[k] = v
+	}
+	return props
+}
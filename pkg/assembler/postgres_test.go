@@ -0,0 +1,52 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"testing"
+)
+
+func Test_nodeKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    GuacNode
+		want    string
+		wantErr bool
+	}{{
+		name: "artifact identified by digest",
+		node: ArtifactNode{Name: "foo", Digest: "SHA256:ABC"},
+		want: `{"digest":"sha256:abc"}`,
+	}, {
+		name: "same key regardless of non identifiable properties",
+		node: ArtifactNode{Name: "bar", Digest: "sha256:abc"},
+		want: `{"digest":"sha256:abc"}`,
+	}, {
+		name:    "missing identifiable property",
+		node:    PackageNode{Name: "foo"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nodeKey(tt.node)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nodeKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("nodeKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+-- Copyright 2022 The GUAC Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- Nodes are identified by their type and the JSON object built from their
+-- identifiable properties. All properties (including the identifiable ones)
+-- are stored in `properties`.
+CREATE TABLE IF NOT EXISTS guac_nodes (
+    id         BIGSERIAL PRIMARY KEY,
+    node_type  TEXT  NOT NULL,
+    node_key   JSONB NOT NULL,
+    properties JSONB NOT NULL DEFAULT '{}'::jsonb,
+    CONSTRAINT guac_nodes_type_key UNIQUE (node_type, node_key)
+);
+
+CREATE INDEX IF NOT EXISTS guac_nodes_properties_idx ON guac_nodes USING GIN (properties);
+
+-- Edges are identified by their type and their two endpoints.
+CREATE TABLE IF NOT EXISTS guac_edges (
+    id         BIGSERIAL PRIMARY KEY,
+    edge_type  TEXT   NOT NULL,
+    from_id    BIGINT NOT NULL REFERENCES guac_nodes (id) ON DELETE CASCADE,
+    to_id      BIGINT NOT NULL REFERENCES guac_nodes (id) ON DELETE CASCADE,
+    properties JSONB  NOT NULL DEFAULT '{}'::jsonb,
+    CONSTRAINT guac_edges_type_endpoints UNIQUE (edge_type, from_id, to_id)
+);
+
+CREATE INDEX IF NOT EXISTS guac_edges_to_idx ON guac_edges (to_id);
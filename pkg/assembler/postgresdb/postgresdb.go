@@ -0,0 +1,118 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Note: All this code here is temporary and will change often. This module
+// must be a leaf in the dependency tree!
+
+package postgresdb
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// DriverName is the name under which the PostgreSQL `database/sql` driver
+// must be registered. The binary using this package is responsible for
+// linking in the driver (e.g. by importing `github.com/lib/pq`).
+const DriverName = "postgres"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Client represents a client to the PostgreSQL database.
+type Client = *sql.DB
+
+// NewPostgresClient creates a new connection pool to the PostgreSQL database
+// given by the `dsn` connection string.
+func NewPostgresClient(dsn string) (Client, error) {
+	db, err := sql.Open(DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Transaction is a transaction in the database
+type Transaction = *sql.Tx
+
+// WithTransaction runs `work` inside a transaction, committing if `work`
+// succeeds and rolling back otherwise.
+func WithTransaction(client Client, work func(tx Transaction) error) error {
+	tx, err := client.Begin()
+	if err != nil {
+		return err
+	}
+	if err := work(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Migrate creates (or upgrades) the tables and indexes needed to store the
+// GUAC graph. Migrations that were already applied are skipped, so it is safe
+// to call this every time a client is created.
+func Migrate(client Client) error {
+	_, err := client.Exec(`CREATE TABLE IF NOT EXISTS guac_schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("unable to create migrations table: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+		query, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		err = WithTransaction(client, func(tx Transaction) error {
+			var applied bool
+			row := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM guac_schema_migrations WHERE version = $1)", version)
+			if err := row.Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+			if _, err := tx.Exec(string(query)); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO guac_schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to apply migration %s: %w", version, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// SchemaObject is a constraint or an index of the GUAC graph in Neo4j, on one
+// property of the nodes of one type
+type SchemaObject struct {
+	Label    string
+	Property string
+	// Unique is true for uniqueness constraints, false for indexes
+	Unique bool
+}
+
+// Name returns the name under which the object is created
+func (o SchemaObject) Name() string {
+	kind := "index"
+	if o.Unique {
+		kind = "unique"
+	}
+	return fmt.Sprintf("guac_%s_%s_%s", strings.ToLower(o.Label), o.Property, kind)
+}
+
+func (o SchemaObject) String() string {
+	kind := "index"
+	if o.Unique {
+		kind = "uniqueness constraint"
+	}
+	return fmt.Sprintf("%s on :%s(%s)", kind, o.Label, o.Property)
+}
+
+// indexedProperties are the properties, by node type, on which the queries
+// look nodes up
+var indexedProperties = map[string][]string{
+	NodeTypeArtifact:      {"digest", "name"},
+	NodeTypePackage:       {"purl", "name"},
+	NodeTypeMetadata:      {"id", "metadata_type"},
+	NodeTypeAttestation:   {"digest"},
+	NodeTypeVulnerability: {"id"},
+	NodeTypeStep:          {"name"},
+	NodeTypeLayout:        {"digest"},
+	NodeTypeCPE:           {"cpe"},
+	NodeTypeLifecycle:     {"purl"},
+	NodeTypeLicense:       {"expression"},
+	NodeTypeSource:        {"url"},
+	NodeTypeCommit:        {"sha"},
+	NodeTypeFinding:       {"rule_id", "subject"},
+}
+
+// Schema returns the constraints and indexes of the GUAC graph: the
+// uniqueness of the id of each node type, set on every merged node, then the
+// indexes on the properties queries look nodes up by
+func Schema() []SchemaObject {
+	objects := []SchemaObject{}
+	for _, label := range NodeTypes() {
+		objects = append(objects, SchemaObject{Label: label, Property: IDProperty, Unique: true})
+	}
+	for _, label := range NodeTypes() {
+		for _, property := range indexedProperties[label] {
+			objects = append(objects, SchemaObject{Label: label, Property: property})
+		}
+	}
+	return objects
+}
+
+// schemaKey is the node type and property an index is on
+type schemaKey struct {
+	label    string
+	property string
+}
+
+// existingIndex is an index found in the database
+type existingIndex struct {
+	name string
+	// constraint is true for the indexes backing a constraint
+	constraint bool
+}
+
+// CreateSchema creates the objects of Schema that do not exist yet, and
+// returns those it created and those that already existed. It is
+// idempotent, and objects created by other tools with another name count as
+// existing.
+//
+// Unless constraints is true, the ids are indexed instead of being
+// constrained, which is enough to merge nodes quickly. Creating a constraint
+// replaces the index of the id, and fails if nodes with the same id were
+// already stored.
+func CreateSchema(client graphdb.Client, constraints bool) (created, existing []SchemaObject, err error) {
+	session := client.NewSession(neo4j.SessionConfig{})
+	defer session.Close()
+
+	indexes, err := existingIndexes(session)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list the indexes: %w", err)
+	}
+
+	for _, o := range Schema() {
+		o.Unique = o.Unique && constraints
+		index, ok := indexes[schemaKey{o.Label, o.Property}]
+		if ok && (index.constraint || !o.Unique) {
+			existing = append(existing, o)
+			continue
+		}
+
+		if ok {
+			// a constraint cannot be created over an index of the same
+			// property, it creates its own
+			if _, err := runSchemaQuery(session, "DROP INDEX "+quoteName(index.name)+" IF EXISTS"); err != nil {
+				return created, existing, fmt.Errorf("unable to drop index %s to replace it with a constraint: %w", index.name, err)
+			}
+		}
+		var query string
+		if o.Unique {
+			query = fmt.Sprintf("CREATE CONSTRAINT %s IF NOT EXISTS FOR (n:%s) REQUIRE n.%s IS UNIQUE", o.Name(), o.Label, o.Property) // not user controlled
+		} else {
+			query = fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (n:%s) ON (n.%s)", o.Name(), o.Label, o.Property) // not user controlled
+		}
+		added, err := runSchemaQuery(session, query)
+		if err != nil {
+			if o.Unique {
+				return created, existing, fmt.Errorf("unable to create %s, duplicate %s nodes may have been stored: %w", o, o.Label, err)
+			}
+			return created, existing, fmt.Errorf("unable to create %s: %w", o, err)
+		}
+		if added {
+			created = append(created, o)
+		} else {
+			existing = append(existing, o)
+		}
+	}
+	return created, existing, nil
+}
+
+// existingIndexes returns the indexes on a single property of a single node
+// type, including those backing constraints
+func existingIndexes(session neo4j.Session) (map[schemaKey]existingIndex, error) {
+	result, err := session.Run("SHOW INDEXES YIELD name, labelsOrTypes, properties, owningConstraint", nil)
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[schemaKey]existingIndex{}
+	for result.Next() {
+		record := result.Record()
+		name, _ := record.Get("name")
+		labels, _ := record.Get("labelsOrTypes")
+		properties, _ := record.Get("properties")
+		owner, _ := record.Get("owningConstraint")
+
+		l, _ := labels.([]interface{})
+		p, _ := properties.([]interface{})
+		if len(l) != 1 || len(p) != 1 {
+			continue
+		}
+		label, _ := l[0].(string)
+		property, _ := p[0].(string)
+		n, _ := name.(string)
+		indexes[schemaKey{label, property}] = existingIndex{name: n, constraint: owner != nil}
+	}
+	return indexes, result.Err()
+}
+
+// runSchemaQuery runs a schema query and returns whether it added an index or
+// a constraint
+func runSchemaQuery(session neo4j.Session, query string) (bool, error) {
+	result, err := session.Run(query, nil)
+	if err != nil {
+		return false, err
+	}
+	summary, err := result.Consume()
+	if err != nil {
+		return false, err
+	}
+	counters := summary.Counters()
+	return counters.IndexesAdded() > 0 || counters.ConstraintsAdded() > 0, nil
+}
+
+// quoteName quotes the name of a schema object read from the database
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,222 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// fakeSchemaNeo4j is a driver keeping the indexes and constraints created by
+// the schema queries, by the node type and property they are on
+type fakeSchemaNeo4j struct {
+	neo4j.Driver
+	indexes map[schemaKey]existingIndex
+	// duplicates are the node types whose ids cannot be constrained
+	duplicates map[string]bool
+}
+
+var (
+	createSchemaPattern = regexp.MustCompile(`^CREATE (INDEX|CONSTRAINT) (\w+) IF NOT EXISTS FOR \(n:(\w+)\) (?:ON \(n\.(\w+)\)|REQUIRE n\.(\w+) IS UNIQUE)$`)
+	dropIndexPattern    = regexp.MustCompile("^DROP INDEX `(\\w+)` IF EXISTS$")
+)
+
+func (f *fakeSchemaNeo4j) NewSession(config neo4j.SessionConfig) neo4j.Session {
+	return fakeSchemaSession{db: f}
+}
+
+type fakeSchemaSession struct {
+	neo4j.Session
+	db *fakeSchemaNeo4j
+}
+
+func (s fakeSchemaSession) Run(cypher string, params map[string]interface{}, configurers ...func(*neo4j.TransactionConfig)) (neo4j.Result, error) {
+	if cypher == "SHOW INDEXES YIELD name, labelsOrTypes, properties, owningConstraint" {
+		result := &fakeSchemaResult{}
+		for key, index := range s.db.indexes {
+			var owner interface{}
+			if index.constraint {
+				owner = index.name
+			}
+			result.records = append(result.records, &neo4j.Record{
+				Keys:   []string{"name", "labelsOrTypes", "properties", "owningConstraint"},
+				Values: []interface{}{index.name, []interface{}{key.label}, []interface{}{key.property}, owner},
+			})
+		}
+		return result, nil
+	}
+	if m := dropIndexPattern.FindStringSubmatch(cypher); m != nil {
+		for key, index := range s.db.indexes {
+			if index.name == m[1] && !index.constraint {
+				delete(s.db.indexes, key)
+			}
+		}
+		return &fakeSchemaResult{}, nil
+	}
+	m := createSchemaPattern.FindStringSubmatch(cypher)
+	if m == nil {
+		return nil, fmt.Errorf("unexpected query %q", cypher)
+	}
+	key := schemaKey{label: m[3], property: m[4] + m[5]}
+	constraint := m[1] == "CONSTRAINT"
+	if index, ok := s.db.indexes[key]; ok {
+		if constraint && !index.constraint {
+			return nil, errors.New("there already exists an index on the property")
+		}
+		return &fakeSchemaResult{}, nil
+	}
+	if constraint && s.db.duplicates[key.label] {
+		return nil, errors.New("both nodes have the same id")
+	}
+	s.db.indexes[key] = existingIndex{name: m[2], constraint: constraint}
+	return &fakeSchemaResult{added: 1}, nil
+}
+
+func (s fakeSchemaSession) Close() error {
+	return nil
+}
+
+type fakeSchemaResult struct {
+	neo4j.Result
+	records []*neo4j.Record
+	current *neo4j.Record
+	added   int
+}
+
+func (r *fakeSchemaResult) Next() bool {
+	if len(r.records) == 0 {
+		return false
+	}
+	r.current, r.records = r.records[0], r.records[1:]
+	return true
+}
+
+func (r *fakeSchemaResult) Record() *neo4j.Record {
+	return r.current
+}
+
+func (r *fakeSchemaResult) Err() error {
+	return nil
+}
+
+func (r *fakeSchemaResult) Consume() (neo4j.ResultSummary, error) {
+	return fakeSummary{added: r.added}, nil
+}
+
+type fakeSummary struct {
+	neo4j.ResultSummary
+	added int
+}
+
+func (s fakeSummary) Counters() neo4j.Counters {
+	return fakeCounters{added: s.added}
+}
+
+type fakeCounters struct {
+	neo4j.Counters
+	added int
+}
+
+func (c fakeCounters) IndexesAdded() int {
+	return c.added
+}
+
+func (c fakeCounters) ConstraintsAdded() int {
+	return 0
+}
+
+func TestCreateSchema(t *testing.T) {
+	packageID := schemaKey{label: NodeTypePackage, property: IDProperty}
+	tests := []struct {
+		name         string
+		indexes      map[schemaKey]existingIndex
+		duplicates   map[string]bool
+		constraints  bool
+		wantCreated  int
+		wantExisting int
+		wantErr      bool
+		// wantConstraint is whether the id of the packages must end up
+		// constrained
+		wantConstraint bool
+	}{{
+		name:           "empty database",
+		indexes:        map[schemaKey]existingIndex{},
+		constraints:    true,
+		wantCreated:    len(Schema()),
+		wantConstraint: true,
+	}, {
+		name:        "indexes only",
+		indexes:     map[schemaKey]existingIndex{},
+		constraints: false,
+		wantCreated: len(Schema()),
+	}, {
+		name: "index of the id created by ingestion is replaced",
+		indexes: map[schemaKey]existingIndex{
+			packageID: {name: "index_1"},
+			{label: NodeTypePackage, property: "purl"}: {name: "index_2"},
+		},
+		constraints:    true,
+		wantCreated:    len(Schema()) - 1,
+		wantExisting:   1,
+		wantConstraint: true,
+	}, {
+		name: "constraint counts as the index of the id",
+		indexes: map[schemaKey]existingIndex{
+			packageID: {name: "constraint_1", constraint: true},
+		},
+		constraints:    false,
+		wantCreated:    len(Schema()) - 1,
+		wantExisting:   1,
+		wantConstraint: true,
+	}, {
+		name:        "duplicate nodes",
+		indexes:     map[schemaKey]existingIndex{},
+		duplicates:  map[string]bool{NodeTypePackage: true},
+		constraints: true,
+		wantErr:     true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeSchemaNeo4j{indexes: tt.indexes, duplicates: tt.duplicates}
+			created, existing, err := CreateSchema(db, tt.constraints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(created) != tt.wantCreated || len(existing) != tt.wantExisting {
+				t.Errorf("CreateSchema() created %d and found %d, want %d and %d", len(created), len(existing), tt.wantCreated, tt.wantExisting)
+			}
+			if got := db.indexes[packageID].constraint; got != tt.wantConstraint {
+				t.Errorf("id of the packages constrained = %v, want %v", got, tt.wantConstraint)
+			}
+
+			// running again finds everything
+			created, existing, err = CreateSchema(db, tt.constraints)
+			if err != nil {
+				t.Fatalf("CreateSchema() again error = %v", err)
+			}
+			if len(created) != 0 || len(existing) != len(Schema()) {
+				t.Errorf("CreateSchema() again created %d and found %d, want 0 and %d", len(created), len(existing), len(Schema()))
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Canonical names of the node types, returned by `GuacNode.Type()`. These are
+// the labels of the nodes in the graph database.
+const (
+	NodeTypeArtifact      = "Artifact"
+	NodeTypePackage       = "Package"
+	NodeTypeIdentity      = "Identity"
+	NodeTypeAttestation   = "Attestation"
+	NodeTypeBuilder       = "Builder"
+	NodeTypeMetadata      = "Metadata"
+	NodeTypeVulnerability = "Vulnerability"
+	NodeTypeStep          = "Step"
+	NodeTypeLayout        = "Layout"
+	NodeTypeCPE           = "CPE"
+	NodeTypeLifecycle     = "Lifecycle"
+	NodeTypeLicense       = "License"
+	NodeTypeSource        = "Source"
+	NodeTypeCommit        = "Commit"
+	NodeTypeFinding       = "Finding"
+)
+
+// Canonical names of the edge types, returned by `GuacEdge.Type()`. These are
+// the relationship types in the graph database.
+const (
+	EdgeTypeIdentityFor       = "Identity"
+	EdgeTypeAttestationFor    = "Attestation"
+	EdgeTypeBuiltBy           = "BuiltBy"
+	EdgeTypeBuiltFrom         = "BuiltFrom"
+	EdgeTypeDependsOn         = "DependsOn"
+	EdgeTypeContains          = "Contains"
+	EdgeTypeMetadataFor       = "MetadataFor"
+	EdgeTypeVulnerable        = "Vulnerable"
+	EdgeTypeVulnerableTo      = "VulnerableTo"
+	EdgeTypeGeneratedFrom     = "GeneratedFrom"
+	EdgeTypeStaticLink        = "StaticLink"
+	EdgeTypeAliasOf           = "AliasOf"
+	EdgeTypeAffects           = "Affects"
+	EdgeTypeVexStatement      = "VexStatement"
+	EdgeTypeMaterial          = "Material"
+	EdgeTypeProduct           = "Product"
+	EdgeTypeExpectsStep       = "ExpectsStep"
+	EdgeTypeHasCPE            = "HasCPE"
+	EdgeTypeHasLifecycle      = "HasLifecycle"
+	EdgeTypeDeclaredLicense   = "DeclaredLicense"
+	EdgeTypeDiscoveredLicense = "DiscoveredLicense"
+	EdgeTypeLicenseOperand    = "LicenseOperand"
+	EdgeTypeSameAs            = "SameAs"
+	EdgeTypeHasCommit         = "HasCommit"
+	EdgeTypeBuiltFromSource   = "BuiltFromSource"
+	EdgeTypeHasFinding        = "HasFinding"
+	EdgeTypeHasLayer          = "HasLayer"
+	EdgeTypeHasConfig         = "HasConfig"
+	EdgeTypeDerivedFrom       = "DerivedFrom"
+	EdgeTypeDigestMismatch    = "DigestMismatch"
+)
+
+var (
+	nodeTypes = map[string]bool{
+		NodeTypeArtifact:      true,
+		NodeTypePackage:       true,
+		NodeTypeIdentity:      true,
+		NodeTypeAttestation:   true,
+		NodeTypeBuilder:       true,
+		NodeTypeMetadata:      true,
+		NodeTypeVulnerability: true,
+		NodeTypeStep:          true,
+		NodeTypeLayout:        true,
+		NodeTypeCPE:           true,
+		NodeTypeLifecycle:     true,
+		NodeTypeLicense:       true,
+		NodeTypeSource:        true,
+		NodeTypeCommit:        true,
+		NodeTypeFinding:       true,
+	}
+	edgeTypes = map[string]bool{
+		EdgeTypeIdentityFor:       true,
+		EdgeTypeAttestationFor:    true,
+		EdgeTypeBuiltBy:           true,
+		EdgeTypeBuiltFrom:         true,
+		EdgeTypeDependsOn:         true,
+		EdgeTypeContains:          true,
+		EdgeTypeMetadataFor:       true,
+		EdgeTypeVulnerable:        true,
+		EdgeTypeVulnerableTo:      true,
+		EdgeTypeGeneratedFrom:     true,
+		EdgeTypeStaticLink:        true,
+		EdgeTypeAliasOf:           true,
+		EdgeTypeAffects:           true,
+		EdgeTypeVexStatement:      true,
+		EdgeTypeMaterial:          true,
+		EdgeTypeProduct:           true,
+		EdgeTypeExpectsStep:       true,
+		EdgeTypeHasCPE:            true,
+		EdgeTypeHasLifecycle:      true,
+		EdgeTypeDeclaredLicense:   true,
+		EdgeTypeDiscoveredLicense: true,
+		EdgeTypeLicenseOperand:    true,
+		EdgeTypeSameAs:            true,
+		EdgeTypeHasCommit:         true,
+		EdgeTypeBuiltFromSource:   true,
+		EdgeTypeHasFinding:        true,
+		EdgeTypeHasLayer:          true,
+		EdgeTypeHasConfig:         true,
+		EdgeTypeDerivedFrom:       true,
+		EdgeTypeDigestMismatch:    true,
+	}
+)
+
+// RegisterNodeType registers a node type in addition to the canonical ones,
+// so that graphs with nodes of this type can be stored. Like the other
+// registries, it must be called before ingestion starts.
+func RegisterNodeType(nodeType string) error {
+	if _, ok := nodeTypes[nodeType]; ok {
+		return fmt.Errorf("the node type is being overwritten: %s", nodeType)
+	}
+	nodeTypes[nodeType] = true
+	return nil
+}
+
+// RegisterEdgeType registers an edge type in addition to the canonical ones,
+// so that graphs with edges of this type can be stored. Like the other
+// registries, it must be called before ingestion starts.
+func RegisterEdgeType(edgeType string) error {
+	if _, ok := edgeTypes[edgeType]; ok {
+		return fmt.Errorf("the edge type is being overwritten: %s", edgeType)
+	}
+	edgeTypes[edgeType] = true
+	return nil
+}
+
+// NodeTypes returns the registered node types, sorted
+func NodeTypes() []string {
+	types := make([]string, 0, len(nodeTypes))
+	for t := range nodeTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidateGraph returns an error if a node (including the endpoints of the
+// edges) or an edge of the graph has a type that is not registered. This
+// catches typos in the parsers before they create orphaned node types in the
+// database.
+func ValidateGraph(g Graph) error {
+	for _, n := range g.Nodes {
+		if err := validateNodeType(n); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if err := validateEdgeType(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNodeType(n GuacNode) error {
+	if !nodeTypes[n.Type()] {
+		return fmt.Errorf("unknown node type %q", n.Type())
+	}
+	return nil
+}
+
+func validateEdgeType(e GuacEdge) error {
+	if !edgeTypes[e.Type()] {
+		return fmt.Errorf("unknown edge type %q", e.Type())
+	}
+	a, b := e.Nodes()
+	if err := validateNodeType(a); err != nil {
+		return fmt.Errorf("invalid %s edge: %w", e.Type(), err)
+	}
+	if err := validateNodeType(b); err != nil {
+		return fmt.Errorf("invalid %s edge: %w", e.Type(), err)
+	}
+	return nil
+}
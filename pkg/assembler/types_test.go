@@ -0,0 +1,114 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assembler
+
+import (
+	"strings"
+	"testing"
+)
+
+// typoNode is a package node with a misspelled type
+type typoNode struct {
+	PackageNode
+}
+
+func (n typoNode) Type() string {
+	return "package"
+}
+
+// testEdge is an edge of the given type between any two nodes
+type testEdge struct {
+	edgeType string
+	v, u     GuacNode
+}
+
+func (e testEdge) Type() string                        { return e.edgeType }
+func (e testEdge) Nodes() (v, u GuacNode)              { return e.v, e.u }
+func (e testEdge) Properties() map[string]interface{}  { return nil }
+func (e testEdge) PropertyNames() []string             { return nil }
+func (e testEdge) IdentifiablePropertyNames() []string { return nil }
+
+func TestValidateGraph(t *testing.T) {
+	app := PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+	lib := PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+
+	testCases := []struct {
+		name    string
+		graph   Graph
+		wantErr string
+	}{{
+		name: "canonical types",
+		graph: Graph{
+			Nodes: []GuacNode{app, lib, VulnerabilityNode{ID: "CVE-2022-1234"}},
+			Edges: []GuacEdge{DependsOnEdge{PackageNode: app, PackageDependency: lib}},
+		},
+	}, {
+		name:    "unknown node type",
+		graph:   Graph{Nodes: []GuacNode{app, typoNode{lib}}},
+		wantErr: `unknown node type "package"`,
+	}, {
+		name:    "unknown edge type",
+		graph:   Graph{Edges: []GuacEdge{testEdge{"DEPENDS_ON", app, lib}}},
+		wantErr: `unknown edge type "DEPENDS_ON"`,
+	}, {
+		name:    "unknown endpoint type",
+		graph:   Graph{Edges: []GuacEdge{testEdge{EdgeTypeDependsOn, app, typoNode{lib}}}},
+		wantErr: `invalid DependsOn edge: unknown node type "package"`,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGraph(tt.graph)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateGraph() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateGraph() error = %v, want %q", err, tt.wantErr)
+			}
+			if err := NewMemoryBackend().StoreNodes(tt.graph.Nodes); len(tt.graph.Nodes) > 0 && err == nil {
+				t.Errorf("MemoryBackend.StoreNodes() stored an invalid graph")
+			}
+		})
+	}
+}
+
+func TestRegisterTypes(t *testing.T) {
+	defer func() {
+		delete(nodeTypes, "package")
+		delete(edgeTypes, "DEPENDS_ON")
+	}()
+
+	if err := RegisterNodeType(NodeTypePackage); err == nil {
+		t.Errorf("RegisterNodeType() of a canonical type did not fail")
+	}
+	if err := RegisterNodeType("package"); err != nil {
+		t.Fatalf("RegisterNodeType() error = %v", err)
+	}
+	if err := RegisterEdgeType("DEPENDS_ON"); err != nil {
+		t.Fatalf("RegisterEdgeType() error = %v", err)
+	}
+	if types := NodeTypes(); types[len(types)-1] != "package" {
+		t.Errorf("NodeTypes() = %v, want the registered type last", types)
+	}
+	app := PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+	g := Graph{Edges: []GuacEdge{testEdge{"DEPENDS_ON", app, typoNode{app}}}}
+	if err := ValidateGraph(g); err != nil {
+		t.Errorf("ValidateGraph() error = %v", err)
+	}
+}
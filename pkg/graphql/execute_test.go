@@ -0,0 +1,456 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	gql "github.com/graphql-go/graphql"
+)
+
+type fakeNode struct {
+	label string
+	props map[string]interface{}
+}
+
+type fakeEdge struct {
+	edgeType string
+	from, to int64
+	props    map[string]interface{}
+}
+
+// fakeStore is a Store over a graph given as lists of nodes and edges, the
+// index of each node being its ID
+type fakeStore struct {
+	nodes []fakeNode
+	edges []fakeEdge
+}
+
+func (s *fakeStore) FindNode(ctx context.Context, label string, key string, value interface{}) (*Node, error) {
+	for i, n := range s.nodes {
+		if n.label == label && n.props[key] == value {
+			return &Node{ID: int64(i), Properties: n.props}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) Related(ctx context.Context, id int64, edgeType string, dir Direction, depth int, to string) ([]Relation, error) {
+	seen := map[int64]bool{id: true}
+	relations := []Relation{}
+	frontier := []int64{id}
+	for level := 1; level <= depth; level++ {
+		next := []int64{}
+		for _, from := range frontier {
+			for _, e := range s.edges {
+				v, u := e.from, e.to
+				if dir == Incoming {
+					v, u = e.to, e.from
+				}
+				if e.edgeType != edgeType || v != from || seen[u] {
+					continue
+				}
+				seen[u] = true
+				next = append(next, u)
+				if s.nodes[u].label == to {
+					rel := Relation{Node: Node{ID: u, Properties: s.nodes[u].props}}
+					if depth == 1 {
+						rel.EdgeProperties = e.props
+					}
+					relations = append(relations, rel)
+				}
+			}
+		}
+		frontier = next
+	}
+	return relations, nil
+}
+
+func testStore() *fakeStore {
+	return &fakeStore{
+		nodes: []fakeNode{
+			/* 0 */ {"Package", map[string]interface{}{"purl": "pkg:npm/app@1.0.0", "name": "app", "version": "1.0.0"}},
+			/* 1 */ {"Package", map[string]interface{}{"purl": "pkg:npm/lib@2.0.0", "name": "lib", "version": "2.0.0"}},
+			/* 2 */ {"Package", map[string]interface{}{"purl": "pkg:npm/semver@7.0.0", "name": "semver", "version": "7.0.0"}},
+			/* 3 */ {"Vulnerability", map[string]interface{}{"id": "GHSA-c2qf-rxjj-qqgw"}},
+			/* 4 */ {"Vulnerability", map[string]interface{}{"id": "CVE-2022-25883"}},
+			/* 5 */ {"Artifact", map[string]interface{}{"name": "app.tgz", "digest": "sha256:abc"}},
+			/* 6 */ {"Attestation", map[string]interface{}{"digest": "sha256:att", "attestation_type": "SLSA"}},
+			/* 7 */ {"Builder", map[string]interface{}{"id": "https://github.com/actions", "type": "gha"}},
+			/* 8 */ {"Artifact", map[string]interface{}{"name": "git+https://github.com/app", "digest": "sha1:def"}},
+			/* 9 */ {"CPE", map[string]interface{}{"cpe": "cpe:2.3:a:example:app:1.0.0:*:*:*:*:*:*:*"}},
+			/* 10 */ {"Vulnerability", map[string]interface{}{"id": "CVE-2023-1234"}},
+			/* 11 */ {"Package", map[string]interface{}{"purl": "pkg:npm/lib"}},
+			/* 12 */ {"Lifecycle", map[string]interface{}{"purl": "pkg:npm/lib", "cycle": "2", "eol": "2000-01-01", "eol_reached": false, "lts": false}},
+			/* 13 */ {"Lifecycle", map[string]interface{}{"purl": "pkg:npm/lib", "cycle": "1", "eol_reached": true, "lts": false}},
+			/* 14 */ {"Package", map[string]interface{}{"purl": "pkg:npm/semver"}},
+			/* 15 */ {"Lifecycle", map[string]interface{}{"purl": "pkg:npm/semver", "cycle": "7", "eol": "2999-01-01", "eol_reached": false, "lts": true}},
+			/* 16 */ {"License", map[string]interface{}{"expression": "MIT"}},
+			/* 17 */ {"License", map[string]interface{}{"expression": "GPL-3.0-only"}},
+			/* 18 */ {"Metadata", map[string]interface{}{"metadata_type": "clearlydefined", "id": "npm/npmjs/-/lib/2.0.0", "copyrights": []interface{}{"Copyright Lib Authors"}}},
+			/* 19 */ {"Source", map[string]interface{}{"url": "https://github.com/app", "vcs": "git"}},
+			/* 20 */ {"Commit", map[string]interface{}{"sha": "d6525c840a62b398424a78d792f457477135d0cf", "vcs": "git", "repository": "https://github.com/app"}},
+			/* 21 */ {"Package", map[string]interface{}{"purl": "pkg:deb/debian/openssl@1.1.1n-0+deb11u4?arch=amd64", "name": "openssl", "version": "1.1.1n-0+deb11u4"}},
+			/* 22 */ {"Package", map[string]interface{}{"purl": "pkg:deb/debian/openssl"}},
+			/* 23 */ {"Vulnerability", map[string]interface{}{"id": "DSA-5343-1"}},
+			/* 24 */ {"Vulnerability", map[string]interface{}{"id": "DLA-0000-1"}},
+			/* 25 */ {"Vulnerability", map[string]interface{}{"id": "DSA-5169-1"}},
+		},
+		edges: []fakeEdge{
+			{edgeType: "DependsOn", from: 0, to: 1},
+			{edgeType: "DependsOn", from: 1, to: 2},
+			{edgeType: "VulnerableTo", from: 2, to: 3},
+			{edgeType: "AliasOf", from: 3, to: 4},
+			{edgeType: "Affects", from: 3, to: 2, props: map[string]interface{}{
+				"ranges": []interface{}{`{"type":"SEMVER","events":[{"introduced":"7.0.0"},{"fixed":"7.5.2"}]}`},
+			}},
+			{edgeType: "VexStatement", from: 4, to: 2, props: map[string]interface{}{
+				"status":        "not_affected",
+				"justification": "vulnerable_code_not_in_execute_path",
+			}},
+			{edgeType: "Contains", from: 0, to: 5},
+			{edgeType: "Attestation", from: 6, to: 5},
+			{edgeType: "BuiltBy", from: 5, to: 7},
+			{edgeType: "BuiltFrom", from: 5, to: 8},
+			{edgeType: "HasCPE", from: 0, to: 9},
+			{edgeType: "Affects", from: 10, to: 9, props: map[string]interface{}{
+				"versions": []interface{}{"1.0.0"},
+			}},
+			{edgeType: "HasLifecycle", from: 11, to: 12},
+			{edgeType: "HasLifecycle", from: 11, to: 13},
+			{edgeType: "HasLifecycle", from: 14, to: 15},
+			{edgeType: "DeclaredLicense", from: 1, to: 16},
+			{edgeType: "DeclaredLicense", from: 2, to: 17},
+			{edgeType: "DiscoveredLicense", from: 2, to: 16},
+			{edgeType: "MetadataFor", from: 18, to: 1},
+			{edgeType: "SameAs", from: 0, to: 5},
+			{edgeType: "SameAs", from: 5, to: 8, props: map[string]interface{}{"justification": "built from"}},
+			{edgeType: "HasCommit", from: 19, to: 20},
+			{edgeType: "BuiltFromSource", from: 5, to: 20},
+			{edgeType: "BuiltFromSource", from: 0, to: 19},
+			{edgeType: "Affects", from: 23, to: 22, props: map[string]interface{}{
+				"ranges": []interface{}{`{"type":"ECOSYSTEM","events":[{"introduced":"0"},{"fixed":"1.1.1n-0+deb11u4"}]}`},
+			}},
+			{edgeType: "Affects", from: 24, to: 22, props: map[string]interface{}{
+				"ranges": []interface{}{`{"type":"ECOSYSTEM","events":[{"introduced":"0"},{"fixed":"1:1.0.0-1"}]}`},
+			}},
+			{edgeType: "Affects", from: 25, to: 22, props: map[string]interface{}{
+				"versions": []interface{}{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u4"},
+			}},
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	schema, err := NewSchema(testStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]interface{}
+		want      string
+	}{{
+		name:  "package",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") { name version __typename } }`,
+		want:  `{"data":{"package":{"name":"app","version":"1.0.0","__typename":"Package"}}}`,
+	}, {
+		name:  "unknown package",
+		query: `{ package(purl: "pkg:npm/unknown") { name } }`,
+		want:  `{"data":{"package":null}}`,
+	}, {
+		name: "dependencies with depth and vulnerabilities",
+		query: `query Deps($purl: String!, $depth: Int) {
+			package(purl: $purl) {
+				direct: dependencies { name }
+				all: dependencies(depth: $depth) {
+					name
+					vulnerabilities { id aliases { id } }
+				}
+			}
+		}`,
+		variables: map[string]interface{}{"purl": "pkg:npm/app@1.0.0", "depth": float64(2)},
+		want: `{"data":{"package":{` +
+			`"direct":[{"name":"lib"}],` +
+			`"all":[{"name":"lib","vulnerabilities":[]},` +
+			`{"name":"semver","vulnerabilities":[{"id":"GHSA-c2qf-rxjj-qqgw","aliases":[{"id":"CVE-2022-25883"}]}]}]}}}`,
+	}, {
+		name: "dependents",
+		query: `{ package(purl: "pkg:npm/semver@7.0.0") {
+			dependents(depth: 5) { ...Name }
+		} }
+		fragment Name on Package { name }`,
+		want: `{"data":{"package":{"dependents":[{"name":"lib"},{"name":"app"}]}}}`,
+	}, {
+		name: "provenance",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") {
+			artifacts {
+				digest
+				provenance {
+					attestations { type }
+					builders { id }
+					sources { name }
+				}
+			}
+		} }`,
+		want: `{"data":{"package":{"artifacts":[{"digest":"sha256:abc","provenance":{` +
+			`"attestations":[{"type":"SLSA"}],` +
+			`"builders":[{"id":"https://github.com/actions"}],` +
+			`"sources":[{"name":"git+https://github.com/app"}]}}]}}}`,
+	}, {
+		name: "vulnerability",
+		query: `{ vulnerability(id: "CVE-2022-25883") {
+			aliases {
+				id
+				affected { ranges package { purl } }
+			}
+		} }`,
+		want: `{"data":{"vulnerability":{"aliases":[{"id":"GHSA-c2qf-rxjj-qqgw","affected":[{` +
+			`"ranges":["{\"type\":\"SEMVER\",\"events\":[{\"introduced\":\"7.0.0\"},{\"fixed\":\"7.5.2\"}]}"],` +
+			`"package":{"purl":"pkg:npm/semver@7.0.0"}}]}]}}}`,
+	}, {
+		name: "vulnerability affecting a CPE",
+		query: `{
+			vulnerability(id: "CVE-2023-1234") { affected { versions package { purl } } }
+			package(purl: "pkg:npm/app@1.0.0") { vulnerabilities { id } }
+		}`,
+		want: `{"data":{` +
+			`"vulnerability":{"affected":[{"versions":["1.0.0"],"package":{"purl":"pkg:npm/app@1.0.0"}}]},` +
+			`"package":{"vulnerabilities":[{"id":"CVE-2023-1234"}]}}}`,
+	}, {
+		name:  "advisories for the package without version",
+		query: `{ package(purl: "pkg:deb/debian/openssl@1.1.1n-0+deb11u4?arch=amd64") { vulnerabilities { id } } }`,
+		want:  `{"data":{"package":{"vulnerabilities":[{"id":"DLA-0000-1"},{"id":"DSA-5169-1"}]}}}`,
+	}, {
+		name: "VEX statements",
+		query: `{ vulnerability(id: "CVE-2022-25883") {
+			statements { status justification impactStatement package { purl } }
+		} }`,
+		want: `{"data":{"vulnerability":{"statements":[{"status":"not_affected",` +
+			`"justification":"vulnerable_code_not_in_execute_path","impactStatement":null,` +
+			`"package":{"purl":"pkg:npm/semver@7.0.0"}}]}}}`,
+	}, {
+		name: "suppress not affected vulnerabilities",
+		query: `{ package(purl: "pkg:npm/semver@7.0.0") {
+			all: vulnerabilities { id }
+			affecting: vulnerabilities(suppressNotAffected: true) { id }
+		} }`,
+		want: `{"data":{"package":{"all":[{"id":"GHSA-c2qf-rxjj-qqgw"}],"affecting":[]}}}`,
+	}, {
+		name: "lifecycle",
+		query: `{
+			lib: package(purl: "pkg:npm/lib@2.0.0") { lifecycle { cycle eol lts pastEOL } }
+			semver: package(purl: "pkg:npm/semver@7.0.0") { lifecycle { cycle eol lts pastEOL } }
+			app: package(purl: "pkg:npm/app@1.0.0") { lifecycle { cycle } }
+		}`,
+		want: `{"data":{` +
+			`"lib":{"lifecycle":{"cycle":"2","eol":"2000-01-01","lts":false,"pastEOL":true}},` +
+			`"semver":{"lifecycle":{"cycle":"7","eol":"2999-01-01","lts":true,"pastEOL":false}},` +
+			`"app":{"lifecycle":null}}}`,
+	}, {
+		name: "dependencies past end of life",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") {
+			eolDependencies(depth: 2) { name lifecycle { cycle } }
+		} }`,
+		want: `{"data":{"package":{"eolDependencies":[{"name":"lib","lifecycle":{"cycle":"2"}}]}}}`,
+	}, {
+		name: "licenses",
+		query: `{
+			lib: package(purl: "pkg:npm/lib@2.0.0") { licenses { expression kind } copyrights }
+			semver: package(purl: "pkg:npm/semver@7.0.0") { licenses { expression kind } copyrights }
+		}`,
+		want: `{"data":{` +
+			`"lib":{"licenses":[{"expression":"MIT","kind":"declared"}],"copyrights":["Copyright Lib Authors"]},` +
+			`"semver":{"licenses":[{"expression":"GPL-3.0-only","kind":"declared"},{"expression":"MIT","kind":"discovered"}],"copyrights":[]}}}`,
+	}, {
+		name: "dependencies with unapproved licenses",
+		query: `query ($approved: [String!]!) { package(purl: "pkg:npm/app@1.0.0") {
+			variable: unapprovedLicenseDependencies(approved: $approved, depth: 2) { name }
+			literal: unapprovedLicenseDependencies(approved: ["mit", "GPL-3.0-only"], depth: 2) { name }
+			single: unapprovedLicenseDependencies(approved: "MIT", depth: 2) { name }
+		} }`,
+		variables: map[string]interface{}{"approved": []interface{}{"MIT"}},
+		want: `{"data":{"package":{` +
+			`"variable":[{"name":"semver"}],` +
+			`"literal":[],` +
+			`"single":[{"name":"semver"}]}}}`,
+	}, {
+		name: "equivalents",
+		query: `{
+			package(purl: "pkg:npm/app@1.0.0") { equivalentPackages { name } equivalentArtifacts { name } }
+			artifact(digest: "sha1:def") { equivalentPackages { name } equivalentArtifacts { name } }
+		}`,
+		want: `{"data":{` +
+			`"package":{"equivalentPackages":[],"equivalentArtifacts":[{"name":"app.tgz"},{"name":"git+https://github.com/app"}]},` +
+			`"artifact":{"equivalentPackages":[{"name":"app"}],"equivalentArtifacts":[{"name":"app.tgz"}]}}}`,
+	}, {
+		name: "commits and sources",
+		query: `{
+			commit(sha: "d6525c840a62b398424a78d792f457477135d0cf") {
+				vcs
+				repository { url }
+				artifacts { name }
+			}
+			source(url: "https://github.com/app") {
+				commits { sha }
+				artifacts { name }
+				packages { name }
+			}
+			artifact(digest: "sha256:abc") { provenance { commits { sha } repositories { url } } }
+		}`,
+		want: `{"data":{` +
+			`"commit":{"vcs":"git","repository":{"url":"https://github.com/app"},"artifacts":[{"name":"app.tgz"}]},` +
+			`"source":{"commits":[{"sha":"d6525c840a62b398424a78d792f457477135d0cf"}],"artifacts":[{"name":"app.tgz"}],"packages":[{"name":"app"}]},` +
+			`"artifact":{"provenance":{"commits":[{"sha":"d6525c840a62b398424a78d792f457477135d0cf"}],"repositories":[{"url":"https://github.com/app"}]}}}}`,
+	}, {
+		name: "skip and include",
+		query: `query ($yes: Boolean = true) {
+			package(purl: "pkg:npm/app@1.0.0") {
+				name @skip(if: $yes)
+				version @include(if: $yes)
+			}
+		}`,
+		want: `{"data":{"package":{"version":"1.0.0"}}}`,
+	}, {
+		name:      "operation name",
+		query:     `query A { package(purl: "pkg:npm/app@1.0.0") { name } } query B { package(purl: "pkg:npm/lib@2.0.0") { name } }`,
+		operation: "B",
+		want:      `{"data":{"package":{"name":"lib"}}}`,
+	}, {
+		name:  "field errors",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") { name dependencies(depth: 100) { name } } }`,
+		want: `{"data":{"package":{"name":"app","dependencies":null}},"errors":[` +
+			`{"message":"depth must be between 1 and 10","locations":[{"line":1,"column":45}],"path":["package","dependencies"]}]}`,
+	}, {
+		name:  "unknown field",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") { name unknown } }`,
+		want:  `{"data":null,"errors":[{"message":"Cannot query field \"unknown\" on type \"Package\".","locations":[{"line":1,"column":45}]}]}`,
+	}, {
+		name:  "missing required argument",
+		query: `{ package { name } }`,
+		want:  `{"data":null,"errors":[{"message":"Field \"package\" argument \"purl\" of type \"String!\" is required but not provided.","locations":[{"line":1,"column":3}]}]}`,
+	}, {
+		name:  "missing subfields",
+		query: `{ package(purl: "pkg:npm/app@1.0.0") }`,
+		want:  `{"data":null,"errors":[{"message":"Field \"package\" of type \"Package\" must have a sub selection.","locations":[{"line":1,"column":3}]}]}`,
+	}, {
+		name:  "missing required variable",
+		query: `query ($purl: String!) { package(purl: $purl) { name } }`,
+		want:  `{"data":null,"errors":[{"message":"Variable \"$purl\" of required type \"String!\" was not provided.","locations":[{"line":1,"column":8}]}]}`,
+	}, {
+		name:  "ambiguous operation",
+		query: `query A { package(purl: "a") { name } } query B { package(purl: "b") { name } }`,
+		want:  `{"data":null,"errors":[{"message":"Must provide operation name if query contains multiple operations.","locations":[]}]}`,
+	}, {
+		name:  "syntax error",
+		query: `{ package(purl: "a" { name } }`,
+		want: `{"data":null,"errors":[{"message":` +
+			`"Syntax Error GraphQL request (1:21) Expected Name, found {\n\n1: { package(purl: \"a\" { name } }\n                       ^\n",` +
+			`"locations":[{"line":1,"column":21}]}]}`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := gql.Do(gql.Params{
+				Schema:         schema,
+				RequestString:  tt.query,
+				OperationName:  tt.operation,
+				VariableValues: tt.variables,
+				Context:        context.Background(),
+			})
+			got, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, got, tt.want) {
+				t.Errorf("Do() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	schema, err := NewSchema(testStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewHandler(schema))
+	defer server.Close()
+
+	const want = `{"data":{"package":{"name":"app"}}}` + "\n"
+	query := `query ($purl: String!) { package(purl: $purl) { name } }`
+
+	body := `{"query": "query ($purl: String!) { package(purl: $purl) { name } }", "variables": {"purl": "pkg:npm/app@1.0.0"}}`
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, resp, http.StatusOK, want)
+
+	params := url.Values{"query": {query}, "variables": {`{"purl": "pkg:npm/app@1.0.0"}`}}
+	resp, err = http.Get(server.URL + "?" + params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponse(t, resp, http.StatusOK, want)
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader("not json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid body returned status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func checkResponse(t *testing.T, resp *http.Response, wantStatus int, want string) {
+	t.Helper()
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Errorf("got status %d, want %d", resp.StatusCode, wantStatus)
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(t, got, want) {
+		t.Errorf("got response %s, want %s", got, want)
+	}
+}
+
+// jsonEqual returns whether got is the JSON document want, as objects are
+// returned as maps whose keys are not in the order of the query
+func jsonEqual(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
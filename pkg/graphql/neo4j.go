@@ -0,0 +1,104 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler/graphdb"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+type neo4jStore struct {
+	client graphdb.Client
+}
+
+// NewNeo4jStore returns a Store reading the graph from Neo4j
+func NewNeo4jStore(client graphdb.Client) Store {
+	return &neo4jStore{client: client}
+}
+
+func (s *neo4jStore) FindNode(ctx context.Context, label string, key string, value interface{}) (*Node, error) {
+	query := fmt.Sprintf("MATCH (n:%s {%s: $value}) RETURN n LIMIT 1", label, key)
+	records, err := s.read(query, map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	n := toNode(records[0][0])
+	return &n, nil
+}
+
+func (s *neo4jStore) Related(ctx context.Context, id int64, edgeType string, dir Direction, depth int, to string) ([]Relation, error) {
+	left, right := "-", "->"
+	if dir == Incoming {
+		left, right = "<-", "-"
+	}
+	var query string
+	if depth == 1 {
+		query = fmt.Sprintf("MATCH (n)%s[r:%s]%s(m:%s) WHERE id(n) = $id RETURN m, r ORDER BY id(m)",
+			left, edgeType, right, to)
+	} else {
+		query = fmt.Sprintf("MATCH (n)%s[:%s*1..%d]%s(m:%s) WHERE id(n) = $id AND m <> n RETURN DISTINCT m ORDER BY id(m)",
+			left, edgeType, depth, right, to)
+	}
+	records, err := s.read(query, map[string]interface{}{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	relations := []Relation{}
+	for _, record := range records {
+		rel := Relation{Node: toNode(record[0])}
+		if len(record) > 1 {
+			if r, ok := record[1].(neo4j.Relationship); ok {
+				rel.EdgeProperties = r.Props
+			}
+		}
+		relations = append(relations, rel)
+	}
+	return relations, nil
+}
+
+// read runs a read query and returns the values of all the records
+func (s *neo4jStore) read(query string, args map[string]interface{}) ([][]interface{}, error) {
+	session := s.client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	result, err := session.ReadTransaction(func(tx graphdb.Transaction) (interface{}, error) {
+		records, err := tx.Run(query, args)
+		if err != nil {
+			return nil, err
+		}
+		values := [][]interface{}{}
+		for records.Next() {
+			values = append(values, records.Record().Values)
+		}
+		return values, records.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([][]interface{}), nil
+}
+
+func toNode(value interface{}) Node {
+	n, _ := value.(neo4j.Node)
+	return Node{ID: n.Id, Properties: n.Props}
+}
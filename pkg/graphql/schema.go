@@ -0,0 +1,855 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/guacsec/guac/pkg/handler/processor/clearlydefined"
+	"github.com/guacsec/guac/pkg/handler/processor/lifecycle"
+	"github.com/guacsec/guac/pkg/handler/processor/osv"
+)
+
+// MaxDepth is the maximum number of edges followed when querying the
+// transitive dependencies of a package or artifact
+const MaxDepth = 10
+
+// Node is a node of the GUAC graph
+type Node struct {
+	ID         int64
+	Properties map[string]interface{}
+}
+
+// Relation is a node reached from another node. EdgeProperties are the
+// properties of the edge between them, only set when the edge is direct.
+type Relation struct {
+	Node
+	EdgeProperties map[string]interface{}
+}
+
+// Direction is the direction in which edges are followed
+type Direction int
+
+const (
+	Outgoing Direction = iota
+	Incoming
+)
+
+// Store reads the GUAC graph
+type Store interface {
+	// FindNode returns the node with the label whose property key equals
+	// value, or nil if there is none
+	FindNode(ctx context.Context, label string, key string, value interface{}) (*Node, error)
+
+	// Related returns the distinct nodes with label `to` reached from the
+	// node with the given id by following between 1 and depth edges of
+	// type edgeType in the direction dir
+	Related(ctx context.Context, id int64, edgeType string, dir Direction, depth int, to string) ([]Relation, error)
+}
+
+// NewSchema returns the schema of the GUAC graph, resolving the fields with
+// the store:
+//
+//	type Query {
+//	  package(purl: String!): Package
+//	  artifact(digest: String!): Artifact
+//	  vulnerability(id: String!): Vulnerability
+//	  commit(sha: String!): Commit
+//	  source(url: String!): Source
+//	}
+//	type Package {
+//	  purl: String, name: String, version: String
+//	  digest: [String], cpes: [String], tags: [String]
+//	  dependencies(depth: Int = 1): [Package]
+//	  dependents(depth: Int = 1): [Package]
+//	  artifacts: [Artifact]
+//	  vulnerabilities(suppressNotAffected: Boolean = false): [Vulnerability]
+//	  provenance: Provenance
+//	  lifecycle: Lifecycle
+//	  eolDependencies(depth: Int = 1): [Package]
+//	  licenses: [License]
+//	  copyrights: [String]
+//	  unapprovedLicenseDependencies(approved: [String!]!, depth: Int = 1): [Package]
+//	  equivalentPackages: [Package]
+//	  equivalentArtifacts: [Artifact]
+//	}
+//	type Artifact {
+//	  name: String, digest: String, tags: [String]
+//	  dependencies(depth: Int = 1): [Artifact]
+//	  vulnerabilities: [Vulnerability]
+//	  provenance: Provenance
+//	  equivalentPackages: [Package]
+//	  equivalentArtifacts: [Artifact]
+//	}
+//	type Vulnerability {
+//	  id: String
+//	  aliases: [Vulnerability]
+//	  affected: [Affected]
+//	  packages: [Package]
+//	  statements: [VexStatement]
+//	}
+//	type Affected { package: Package, ranges: [String], versions: [String] }
+//	type VexStatement {
+//	  package: Package, status: String, justification: String
+//	  impactStatement: String, actionStatement: String
+//	}
+//	type Provenance {
+//	  attestations: [Attestation], builders: [Builder], sources: [Artifact]
+//	  commits: [Commit], repositories: [Source]
+//	}
+//	type Attestation { digest: String, type: String, filepath: String }
+//	type Builder { id: String, type: String }
+//	type Lifecycle {
+//	  purl: String, product: String, cycle: String, releaseDate: String
+//	  eol: String, support: String, lts: Boolean, latest: String
+//	  pastEOL: Boolean
+//	}
+//	type License { expression: String, kind: String }
+//	type Commit {
+//	  sha: String, vcs: String, repository: Source
+//	  artifacts: [Artifact], packages: [Package]
+//	}
+//	type Source {
+//	  url: String, vcs: String, commits: [Commit]
+//	  artifacts: [Artifact], packages: [Package]
+//	}
+func NewSchema(store Store) (gql.Schema, error) {
+	r := &resolver{store: store, now: time.Now}
+
+	var pkg, artifact, vuln, affected, statement, provenance, attestation, builder, cycle, license, commit, source *gql.Object
+
+	depthArg := gql.FieldConfigArgument{"depth": {Type: gql.Int, DefaultValue: 1}}
+	suppressArg := gql.FieldConfigArgument{"suppressNotAffected": {Type: gql.Boolean, DefaultValue: false}}
+	approvedArgs := gql.FieldConfigArgument{
+		"approved": {Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(gql.String)))},
+		"depth":    {Type: gql.Int, DefaultValue: 1},
+	}
+
+	pkg = object("Package", func() gql.Fields {
+		return gql.Fields{
+			"purl":                          property("purl", gql.String),
+			"name":                          property("name", gql.String),
+			"version":                       property("version", gql.String),
+			"digest":                        property("digest", stringsType),
+			"cpes":                          property("cpes", stringsType),
+			"tags":                          property("tags", stringsType),
+			"dependencies":                  field(gql.NewList(pkg), depthArg, r.related("DependsOn", Outgoing, "Package")),
+			"dependents":                    field(gql.NewList(pkg), depthArg, r.related("DependsOn", Incoming, "Package")),
+			"artifacts":                     field(gql.NewList(artifact), nil, r.related("Contains", Outgoing, "Artifact")),
+			"vulnerabilities":               field(gql.NewList(vuln), suppressArg, r.vulnerabilities),
+			"provenance":                    field(provenance, nil, self),
+			"lifecycle":                     field(cycle, nil, r.lifecycle),
+			"eolDependencies":               field(gql.NewList(pkg), depthArg, r.eolDependencies),
+			"licenses":                      field(gql.NewList(license), nil, r.licenses),
+			"copyrights":                    field(stringsType, nil, r.copyrights),
+			"unapprovedLicenseDependencies": field(gql.NewList(pkg), approvedArgs, r.unapprovedLicenseDependencies),
+			"equivalentPackages":            field(gql.NewList(pkg), nil, r.equivalents("Package")),
+			"equivalentArtifacts":           field(gql.NewList(artifact), nil, r.equivalents("Artifact")),
+		}
+	})
+	artifact = object("Artifact", func() gql.Fields {
+		return gql.Fields{
+			"name":                property("name", gql.String),
+			"digest":              property("digest", gql.String),
+			"tags":                property("tags", stringsType),
+			"dependencies":        field(gql.NewList(artifact), depthArg, r.related("DependsOn", Outgoing, "Artifact")),
+			"vulnerabilities":     field(gql.NewList(vuln), nil, r.vulnerabilities),
+			"provenance":          field(provenance, nil, self),
+			"equivalentPackages":  field(gql.NewList(pkg), nil, r.equivalents("Package")),
+			"equivalentArtifacts": field(gql.NewList(artifact), nil, r.equivalents("Artifact")),
+		}
+	})
+	vuln = object("Vulnerability", func() gql.Fields {
+		return gql.Fields{
+			"id":         property("id", gql.String),
+			"aliases":    field(gql.NewList(vuln), nil, r.aliases),
+			"affected":   field(gql.NewList(affected), nil, r.affected),
+			"packages":   field(gql.NewList(pkg), nil, r.related("VulnerableTo", Incoming, "Package")),
+			"statements": field(gql.NewList(statement), nil, r.related("VexStatement", Outgoing, "Package")),
+		}
+	})
+	affected = object("Affected", func() gql.Fields {
+		return gql.Fields{
+			"package":  field(pkg, nil, self),
+			"ranges":   field(stringsType, nil, edgeProperty("ranges")),
+			"versions": field(stringsType, nil, edgeProperty("versions")),
+		}
+	})
+	statement = object("VexStatement", func() gql.Fields {
+		return gql.Fields{
+			"package":         field(pkg, nil, self),
+			"status":          field(gql.String, nil, edgeProperty("status")),
+			"justification":   field(gql.String, nil, edgeProperty("justification")),
+			"impactStatement": field(gql.String, nil, edgeProperty("impact_statement")),
+			"actionStatement": field(gql.String, nil, edgeProperty("action_statement")),
+		}
+	})
+	provenance = object("Provenance", func() gql.Fields {
+		return gql.Fields{
+			"attestations": field(gql.NewList(attestation), nil, r.related("Attestation", Incoming, "Attestation")),
+			"builders":     field(gql.NewList(builder), nil, r.related("BuiltBy", Outgoing, "Builder")),
+			"sources":      field(gql.NewList(artifact), nil, r.related("BuiltFrom", Outgoing, "Artifact")),
+			"commits":      field(gql.NewList(commit), nil, r.related("BuiltFromSource", Outgoing, "Commit")),
+			"repositories": field(gql.NewList(source), nil, r.repositories),
+		}
+	})
+	attestation = object("Attestation", func() gql.Fields {
+		return gql.Fields{
+			"digest":   property("digest", gql.String),
+			"type":     property("attestation_type", gql.String),
+			"filepath": property("filepath", gql.String),
+		}
+	})
+	builder = object("Builder", func() gql.Fields {
+		return gql.Fields{
+			"id":   property("id", gql.String),
+			"type": property("type", gql.String),
+		}
+	})
+	cycle = object("Lifecycle", func() gql.Fields {
+		return gql.Fields{
+			"purl":        property("purl", gql.String),
+			"product":     property("product", gql.String),
+			"cycle":       property("cycle", gql.String),
+			"releaseDate": property("release_date", gql.String),
+			"eol":         property("eol", gql.String),
+			"support":     property("support", gql.String),
+			"lts":         property("lts", gql.Boolean),
+			"latest":      property("latest", gql.String),
+			"pastEOL":     field(gql.Boolean, nil, r.pastEOL),
+		}
+	})
+	license = object("License", func() gql.Fields {
+		return gql.Fields{
+			"expression": property("expression", gql.String),
+			"operator":   property("operator", gql.String),
+			"operands":   field(gql.NewList(license), nil, r.related("LicenseOperand", Outgoing, "License")),
+			"kind":       field(gql.String, nil, edgeProperty("kind")),
+		}
+	})
+	commit = object("Commit", func() gql.Fields {
+		return gql.Fields{
+			"sha":        property("sha", gql.String),
+			"vcs":        property("vcs", gql.String),
+			"repository": field(source, nil, r.repository),
+			"artifacts":  field(gql.NewList(artifact), nil, r.related("BuiltFromSource", Incoming, "Artifact")),
+			"packages":   field(gql.NewList(pkg), nil, r.related("BuiltFromSource", Incoming, "Package")),
+		}
+	})
+	source = object("Source", func() gql.Fields {
+		return gql.Fields{
+			"url":       property("url", gql.String),
+			"vcs":       property("vcs", gql.String),
+			"commits":   field(gql.NewList(commit), nil, r.related("HasCommit", Outgoing, "Commit")),
+			"artifacts": field(gql.NewList(artifact), nil, r.builtFromSource("Artifact")),
+			"packages":  field(gql.NewList(pkg), nil, r.builtFromSource("Package")),
+		}
+	})
+
+	query := gql.NewObject(gql.ObjectConfig{Name: "Query", Fields: gql.Fields{
+		"package":       field(pkg, requiredArg("purl"), r.find("Package", "purl")),
+		"artifact":      field(artifact, requiredArg("digest"), r.find("Artifact", "digest")),
+		"vulnerability": field(vuln, requiredArg("id"), r.find("Vulnerability", "id")),
+		"commit":        field(commit, requiredArg("sha"), r.find("Commit", "sha")),
+		"source":        field(source, requiredArg("url"), r.find("Source", "url")),
+	}})
+	return gql.NewSchema(gql.SchemaConfig{Query: query})
+}
+
+// stringsType is the type of the fields listing strings
+var stringsType = gql.NewList(gql.String)
+
+// resolveFunc resolves a field of the source node
+type resolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// object returns an object type whose fields are built once all the types
+// are, as they reference each other
+func object(name string, fields func() gql.Fields) *gql.Object {
+	return gql.NewObject(gql.ObjectConfig{Name: name, Fields: gql.FieldsThunk(fields)})
+}
+
+func field(typ gql.Output, args gql.FieldConfigArgument, resolve resolveFunc) *gql.Field {
+	return &gql.Field{Type: typ, Args: args, Resolve: func(p gql.ResolveParams) (interface{}, error) {
+		return resolve(p.Context, p.Source, p.Args)
+	}}
+}
+
+func requiredArg(name string) gql.FieldConfigArgument {
+	return gql.FieldConfigArgument{name: {Type: gql.NewNonNull(gql.String)}}
+}
+
+// node returns the node a field is resolved on
+func node(source interface{}) Node {
+	switch n := source.(type) {
+	case Node:
+		return n
+	case Relation:
+		return n.Node
+	}
+	panic(fmt.Sprintf("unexpected source %T", source))
+}
+
+func property(name string, typ gql.Output) *gql.Field {
+	return field(typ, nil, func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		return node(source).Properties[name], nil
+	})
+}
+
+func edgeProperty(name string) resolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		if rel, ok := source.(Relation); ok {
+			return rel.EdgeProperties[name], nil
+		}
+		return nil, nil
+	}
+}
+
+// self resolves fields grouping other fields of the same node (e.g. the
+// provenance of a package)
+func self(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return source, nil
+}
+
+type resolver struct {
+	store Store
+	now   func() time.Time
+}
+
+func (r *resolver) find(label string, key string) resolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		n, err := r.store.FindNode(ctx, label, key, args[key])
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return *n, nil
+	}
+}
+
+func (r *resolver) related(edgeType string, dir Direction, to string) resolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		depth := 1
+		if d, ok := args["depth"].(int); ok {
+			depth = d
+		}
+		if depth < 1 || depth > MaxDepth {
+			return nil, fmt.Errorf("depth must be between 1 and %d", MaxDepth)
+		}
+		return r.store.Related(ctx, node(source).ID, edgeType, dir, depth, to)
+	}
+}
+
+// vulnerabilities returns the vulnerabilities of a package or artifact:
+// those reported by SBOMs, by vulnerability certifications and by advisories,
+// including the advisories for the package without version whose ranges
+// cover its version.
+// With `suppressNotAffected`, the vulnerabilities that a VEX statement marks
+// as not affecting the package are left out.
+func (r *resolver) vulnerabilities(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	n := node(source)
+	seen := map[int64]bool{}
+	vulns := []Relation{}
+	add := func(rels []Relation) {
+		for _, rel := range rels {
+			if !seen[rel.ID] {
+				seen[rel.ID] = true
+				vulns = append(vulns, Relation{Node: rel.Node})
+			}
+		}
+	}
+
+	reported, err := r.store.Related(ctx, n.ID, "VulnerableTo", Outgoing, 1, "Vulnerability")
+	if err != nil {
+		return nil, err
+	}
+	add(reported)
+
+	attestations, err := r.store.Related(ctx, n.ID, "Attestation", Incoming, 1, "Attestation")
+	if err != nil {
+		return nil, err
+	}
+	for _, att := range attestations {
+		certified, err := r.store.Related(ctx, att.ID, "Vulnerable", Outgoing, 1, "Vulnerability")
+		if err != nil {
+			return nil, err
+		}
+		add(certified)
+	}
+
+	advisories, err := r.store.Related(ctx, n.ID, "Affects", Incoming, 1, "Vulnerability")
+	if err != nil {
+		return nil, err
+	}
+	add(advisories)
+
+	advisories, err = r.versionAdvisories(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	add(advisories)
+
+	cpes, err := r.store.Related(ctx, n.ID, "HasCPE", Outgoing, 1, "CPE")
+	if err != nil {
+		return nil, err
+	}
+	for _, cpe := range cpes {
+		advisories, err := r.store.Related(ctx, cpe.ID, "Affects", Incoming, 1, "Vulnerability")
+		if err != nil {
+			return nil, err
+		}
+		add(advisories)
+	}
+
+	if suppress, _ := args["suppressNotAffected"].(bool); !suppress {
+		return vulns, nil
+	}
+	affecting := []Relation{}
+	for _, v := range vulns {
+		notAffected, err := r.notAffected(ctx, v.Node, n)
+		if err != nil {
+			return nil, err
+		}
+		if !notAffected {
+			affecting = append(affecting, v)
+		}
+	}
+	return affecting, nil
+}
+
+// versionAdvisories returns the advisories for the package without version
+// (which is how advisories identify packages) that affect the version of the
+// package, comparing versions following the semantics of its purl type
+func (r *resolver) versionAdvisories(ctx context.Context, n Node) ([]Relation, error) {
+	purl, _ := n.Properties["purl"].(string)
+	base, version := lifecycle.BasePurl(purl)
+	if version == "" {
+		return nil, nil
+	}
+	// versions with characters such as `^` are escaped in purls
+	if unescaped, err := url.PathUnescape(version); err == nil {
+		version = unescaped
+	}
+	pkg, err := r.store.FindNode(ctx, "Package", "purl", base)
+	if err != nil || pkg == nil || pkg.ID == n.ID {
+		return nil, err
+	}
+	advisories, err := r.store.Related(ctx, pkg.ID, "Affects", Incoming, 1, "Vulnerability")
+	if err != nil {
+		return nil, err
+	}
+
+	purlType, _, _ := strings.Cut(strings.TrimPrefix(base, "pkg:"), "/")
+	affecting := []Relation{}
+	for _, a := range advisories {
+		ranges := []osv.Range{}
+		for _, encoded := range stringList(a.EdgeProperties["ranges"]) {
+			var r osv.Range
+			if err := json.Unmarshal([]byte(encoded), &r); err != nil {
+				return nil, fmt.Errorf("invalid range of advisory %v: %w", a.Properties["id"], err)
+			}
+			ranges = append(ranges, r)
+		}
+		if osv.AffectsVersion(purlType, version, ranges, stringList(a.EdgeProperties["versions"])) {
+			affecting = append(affecting, a)
+		}
+	}
+	return affecting, nil
+}
+
+// stringList returns the strings of a list property, as returned by the
+// store
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		strs := []string{}
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// affected returns the packages affected by a vulnerability, whether the
+// advisory gives them by purl or by CPE. Packages reached through a CPE get
+// the ranges and versions of the advisory for that CPE.
+func (r *resolver) affected(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	n := node(source)
+	packages, err := r.store.Related(ctx, n.ID, "Affects", Outgoing, 1, "Package")
+	if err != nil {
+		return nil, err
+	}
+	cpes, err := r.store.Related(ctx, n.ID, "Affects", Outgoing, 1, "CPE")
+	if err != nil {
+		return nil, err
+	}
+	for _, cpe := range cpes {
+		identified, err := r.store.Related(ctx, cpe.ID, "HasCPE", Incoming, 1, "Package")
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range identified {
+			packages = append(packages, Relation{Node: pkg.Node, EdgeProperties: cpe.EdgeProperties})
+		}
+	}
+	return packages, nil
+}
+
+// notAffected returns whether a VEX statement on the vulnerability, or on
+// one of its aliases, marks the package as not affected
+func (r *resolver) notAffected(ctx context.Context, vuln Node, pkg Node) (bool, error) {
+	aliases, err := r.aliases(ctx, vuln, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range append([]Relation{{Node: vuln}}, aliases.([]Relation)...) {
+		statements, err := r.store.Related(ctx, v.ID, "VexStatement", Outgoing, 1, "Package")
+		if err != nil {
+			return false, err
+		}
+		for _, s := range statements {
+			if s.ID == pkg.ID && s.EdgeProperties["status"] == "not_affected" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// aliases returns the vulnerabilities linked by AliasOf edges in either
+// direction
+func (r *resolver) aliases(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	n := node(source)
+	aliases, err := r.store.Related(ctx, n.ID, "AliasOf", Outgoing, 1, "Vulnerability")
+	if err != nil {
+		return nil, err
+	}
+	aliasOf, err := r.store.Related(ctx, n.ID, "AliasOf", Incoming, 1, "Vulnerability")
+	if err != nil {
+		return nil, err
+	}
+	return append(aliases, aliasOf...), nil
+}
+
+// lifecycle returns the release cycle of the package, found by matching its
+// version against the cycles linked to the package without version
+func (r *resolver) lifecycle(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	n := node(source)
+	purl, _ := n.Properties["purl"].(string)
+	base, version := lifecycle.BasePurl(purl)
+	if version == "" {
+		version, _ = n.Properties["version"].(string)
+	}
+	if version == "" {
+		return nil, nil
+	}
+	pkg, err := r.store.FindNode(ctx, "Package", "purl", base)
+	if err != nil || pkg == nil {
+		return nil, err
+	}
+	cycles, err := r.store.Related(ctx, pkg.ID, "HasLifecycle", Outgoing, 1, "Lifecycle")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, c := range cycles {
+		if name, ok := c.Properties["cycle"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	match, ok := lifecycle.MatchCycle(version, names)
+	if !ok {
+		return nil, nil
+	}
+	for _, c := range cycles {
+		if c.Properties["cycle"] == match {
+			return c.Node, nil
+		}
+	}
+	return nil, nil
+}
+
+// pastEOL returns whether the release cycle reached its end of life
+func (r *resolver) pastEOL(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	props := node(source).Properties
+	eol, _ := props["eol"].(string)
+	reached, _ := props["eol_reached"].(bool)
+	return lifecycle.Milestone{Date: eol, Reached: reached}.ReachedAt(r.now()), nil
+}
+
+// eolDependencies returns the dependencies of the package, up to depth, whose
+// release cycle reached its end of life
+func (r *resolver) eolDependencies(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	deps, err := r.related("DependsOn", Outgoing, "Package")(ctx, source, args)
+	if err != nil {
+		return nil, err
+	}
+	eol := []Relation{}
+	for _, dep := range deps.([]Relation) {
+		cycle, err := r.lifecycle(ctx, dep, nil)
+		if err != nil {
+			return nil, err
+		}
+		if cycle == nil {
+			continue
+		}
+		if past, _ := r.pastEOL(ctx, cycle, nil); past.(bool) {
+			eol = append(eol, Relation{Node: dep.Node})
+		}
+	}
+	return eol, nil
+}
+
+// licensedPackages returns the nodes holding the license data of the
+// package: the package itself and, when its purl has qualifiers or a subpath,
+// the package without them, which is the one ClearlyDefined data is attached
+// to
+func (r *resolver) licensedPackages(ctx context.Context, n Node) ([]Node, error) {
+	nodes := []Node{n}
+	purl, _ := n.Properties["purl"].(string)
+	i := strings.IndexAny(purl, "?#")
+	if i < 0 {
+		return nodes, nil
+	}
+	pkg, err := r.store.FindNode(ctx, "Package", "purl", purl[:i])
+	if err != nil {
+		return nil, err
+	}
+	if pkg != nil && pkg.ID != n.ID {
+		nodes = append(nodes, *pkg)
+	}
+	return nodes, nil
+}
+
+// licenses returns the licenses declared by the package, then those found in
+// its files, the kind of each being "declared" or "discovered"
+func (r *resolver) licenses(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	pkgs, err := r.licensedPackages(ctx, node(source))
+	if err != nil {
+		return nil, err
+	}
+	licenses := []Relation{}
+	for _, k := range []struct{ edgeType, kind string }{
+		{"DeclaredLicense", "declared"},
+		{"DiscoveredLicense", "discovered"},
+	} {
+		for _, pkg := range pkgs {
+			rels, err := r.store.Related(ctx, pkg.ID, k.edgeType, Outgoing, 1, "License")
+			if err != nil {
+				return nil, err
+			}
+			for _, rel := range rels {
+				licenses = append(licenses, Relation{Node: rel.Node, EdgeProperties: map[string]interface{}{"kind": k.kind}})
+			}
+		}
+	}
+	return licenses, nil
+}
+
+// copyrights returns the copyrights found in the files of the package by
+// ClearlyDefined
+func (r *resolver) copyrights(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	pkgs, err := r.licensedPackages(ctx, node(source))
+	if err != nil {
+		return nil, err
+	}
+	copyrights := []interface{}{}
+	for _, pkg := range pkgs {
+		metadata, err := r.store.Related(ctx, pkg.ID, "MetadataFor", Incoming, 1, "Metadata")
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range metadata {
+			if m.Properties["metadata_type"] != "clearlydefined" {
+				continue
+			}
+			switch parties := m.Properties["copyrights"].(type) {
+			case []interface{}:
+				copyrights = append(copyrights, parties...)
+			case []string:
+				for _, party := range parties {
+					copyrights = append(copyrights, party)
+				}
+			}
+		}
+	}
+	return copyrights, nil
+}
+
+// unapprovedLicenseDependencies returns the dependencies of the package, up
+// to depth, that cannot be used complying only with the approved licenses.
+// The declared license of a dependency is checked if there is one, otherwise
+// all the licenses found in its files are. Invalid license expressions are
+// not approved; dependencies without license data are left out.
+func (r *resolver) unapprovedLicenseDependencies(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	approved := []string{}
+	for _, a := range args["approved"].([]interface{}) {
+		approved = append(approved, a.(string))
+	}
+	deps, err := r.related("DependsOn", Outgoing, "Package")(ctx, source, args)
+	if err != nil {
+		return nil, err
+	}
+	unapproved := []Relation{}
+	for _, dep := range deps.([]Relation) {
+		licenses, err := r.licenses(ctx, dep, nil)
+		if err != nil {
+			return nil, err
+		}
+		declared, discovered := []string{}, []string{}
+		for _, l := range licenses.([]Relation) {
+			expression, _ := l.Properties["expression"].(string)
+			if l.EdgeProperties["kind"] == "declared" {
+				declared = append(declared, expression)
+			} else {
+				discovered = append(discovered, expression)
+			}
+		}
+		checked := declared
+		if len(checked) == 0 {
+			checked = discovered
+		}
+		for _, expression := range checked {
+			if ok, err := clearlydefined.Satisfied(expression, approved); err != nil || !ok {
+				unapproved = append(unapproved, Relation{Node: dep.Node})
+				break
+			}
+		}
+	}
+	return unapproved, nil
+}
+
+// equivalents returns the nodes with label `to` identifying the same software
+// as the package or artifact: those reached by following SameAs edges, in
+// either direction and through packages and artifacts alike, up to MaxDepth
+// edges away
+func (r *resolver) equivalents(to string) resolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		n := node(source)
+		seen := map[int64]bool{n.ID: true}
+		equivalents := []Relation{}
+		frontier := []Node{n}
+		for level := 0; level < MaxDepth && len(frontier) > 0; level++ {
+			next := []Node{}
+			for _, f := range frontier {
+				for _, label := range []string{"Package", "Artifact"} {
+					for _, dir := range []Direction{Outgoing, Incoming} {
+						rels, err := r.store.Related(ctx, f.ID, "SameAs", dir, 1, label)
+						if err != nil {
+							return nil, err
+						}
+						for _, rel := range rels {
+							if seen[rel.ID] {
+								continue
+							}
+							seen[rel.ID] = true
+							next = append(next, rel.Node)
+							if label == to {
+								equivalents = append(equivalents, Relation{Node: rel.Node})
+							}
+						}
+					}
+				}
+			}
+			frontier = next
+		}
+		return equivalents, nil
+	}
+}
+
+// repository returns the repository of a commit, or nil if it is unknown
+func (r *resolver) repository(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	rels, err := r.store.Related(ctx, node(source).ID, "HasCommit", Incoming, 1, "Source")
+	if err != nil || len(rels) == 0 {
+		return nil, err
+	}
+	return rels[0].Node, nil
+}
+
+// repositories returns the repositories a package or artifact is built from:
+// those it is linked to directly, when the commit is unknown, and those of
+// the commits it is built from
+func (r *resolver) repositories(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	n := node(source)
+	repositories, err := r.store.Related(ctx, n.ID, "BuiltFromSource", Outgoing, 1, "Source")
+	if err != nil {
+		return nil, err
+	}
+	commits, err := r.store.Related(ctx, n.ID, "BuiltFromSource", Outgoing, 1, "Commit")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int64]bool{}
+	for _, repo := range repositories {
+		seen[repo.ID] = true
+	}
+	for _, c := range commits {
+		rels, err := r.store.Related(ctx, c.ID, "HasCommit", Incoming, 1, "Source")
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range rels {
+			if !seen[repo.ID] {
+				seen[repo.ID] = true
+				repositories = append(repositories, Relation{Node: repo.Node})
+			}
+		}
+	}
+	return repositories, nil
+}
+
+// builtFromSource returns the nodes with label `to` built from the
+// repository: those linked to it directly and those built from one of its
+// commits
+func (r *resolver) builtFromSource(to string) resolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		n := node(source)
+		built, err := r.store.Related(ctx, n.ID, "BuiltFromSource", Incoming, 1, to)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := r.store.Related(ctx, n.ID, "HasCommit", Outgoing, 1, "Commit")
+		if err != nil {
+			return nil, err
+		}
+		seen := map[int64]bool{}
+		for _, b := range built {
+			seen[b.ID] = true
+		}
+		for _, c := range commits {
+			rels, err := r.store.Related(ctx, c.ID, "BuiltFromSource", Incoming, 1, to)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range rels {
+				if !seen[b.ID] {
+					seen[b.ID] = true
+					built = append(built, Relation{Node: b.Node})
+				}
+			}
+		}
+		return built, nil
+	}
+}
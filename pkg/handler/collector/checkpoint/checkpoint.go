@@ -0,0 +1,170 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists the progress of the collectors, so that they resume from
+// where they stopped when GUAC restarts instead of collecting everything
+// again. Collectors record a checkpoint once the documents it covers have
+// been ingested (see collector.SetAcknowledger), under keys prefixed with
+// their type. The value is the high-water mark of the collector, e.g. the
+// modification time of a file, the generation of an object or an offset.
+type Store interface {
+	// Get returns the checkpoint recorded under key, and whether there is
+	// one
+	Get(key string) (string, bool, error)
+	// Set records the checkpoint under key, replacing the previous one
+	Set(key string, value string) error
+	// Close releases the resources of the store
+	Close() error
+}
+
+type memoryStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+// NewMemoryStore returns a Store that keeps the checkpoints in memory, so they
+// are lost when the process exits
+func NewMemoryStore() Store {
+	return &memoryStore{checkpoints: map[string]string{}}
+}
+
+func (s *memoryStore) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.checkpoints[key]
+	return value, ok, nil
+}
+
+func (s *memoryStore) Set(key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[key] = value
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// entry is a line of the log of a file store
+type entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type fileStore struct {
+	memoryStore
+	file *os.File
+}
+
+// NewFileStore returns a Store that persists the checkpoints to the file at
+// path, creating it if needed. Checkpoints are appended to the file as JSON
+// lines, so recording one is cheap, and the file is compacted when it is
+// opened. A line truncated by a crash is ignored.
+func NewFileStore(path string) (Store, error) {
+	checkpoints := map[string]string{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read checkpoints: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		checkpoints[e.Key] = e.Value
+	}
+
+	if err := compact(path, checkpoints); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open checkpoints: %w", err)
+	}
+	return &fileStore{memoryStore: memoryStore{checkpoints: checkpoints}, file: file}, nil
+}
+
+// compact atomically replaces the file with one line per checkpoint
+func compact(path string, checkpoints map[string]string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("unable to write checkpoints: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for key, value := range checkpoints {
+		if err := writeEntry(w, key, value); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to write checkpoints: %w", err)
+	}
+	return nil
+}
+
+// writeEntry writes the line with a single call, so that a crash truncates
+// at most the last line of the file
+func writeEntry(w io.Writer, key string, value string) error {
+	line, err := json.Marshal(entry{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *fileStore) Set(key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.checkpoints[key]; ok && current == value {
+		return nil
+	}
+	if err := writeEntry(s.file, key, value); err != nil {
+		return err
+	}
+	s.checkpoints[key] = value
+	return nil
+}
+
+func (s *fileStore) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	newFileStore := func(t *testing.T) Store {
+		s, err := NewFileStore(filepath.Join(t.TempDir(), "checkpoints"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return s
+	}
+	testCases := []struct {
+		name     string
+		newStore func(t *testing.T) Store
+	}{{
+		name:     "memory",
+		newStore: func(t *testing.T) Store { return NewMemoryStore() },
+	}, {
+		name:     "file",
+		newStore: newFileStore,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.newStore(t)
+			defer s.Close()
+
+			if _, ok, err := s.Get("a"); err != nil || ok {
+				t.Errorf("Get() of a missing key = %v, %v, want false, nil", ok, err)
+			}
+			for _, value := range []string{"1", "2"} {
+				if err := s.Set("a", value); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got, ok, err := s.Get("a")
+				if err != nil || !ok || got != value {
+					t.Errorf("Get() = %q, %v, %v, want %q, true, nil", got, ok, err, value)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStore_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "1"}, {"a", "2"}, {"a", "2"}} {
+		if err := s.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// simulate a crash while appending a checkpoint
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"key":"b","val`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	for key, want := range map[string]string{"a": "2", "b": "1"} {
+		got, ok, err := s.Get(key)
+		if err != nil || !ok || got != want {
+			t.Errorf("Get(%q) = %q, %v, %v, want %q, true, nil", key, got, ok, err, want)
+		}
+	}
+
+	// the file is compacted to one line per checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("got %d lines in the checkpoint file, want 2:\n%s", lines, data)
+	}
+}
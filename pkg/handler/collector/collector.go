@@ -18,15 +18,28 @@ package collector
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/logging"
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/guacsec/guac/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	BufferChannelSize int = 1000
 )
 
+// Collector retrieves documents from a source and emits them to be processed
+// and ingested. It is all a collector needs to implement, including the
+// collectors added outside of GUAC, see RegisterCollectorFactory.
+//
+// Each emitted document should have its SourceInformation set, with the Type
+// of the collector as Collector and where the document was found as Source.
+// Type and Format can be left as DocumentUnknown and FormatUnknown to have
+// them detected from the content of the Blob.
 type Collector interface {
 	// RetrieveArtifacts collects the documents from the collector. It emits each collected
 	// document through the channel to be collected and processed by the upstream processor.
@@ -48,6 +61,16 @@ type ErrHandler func(error) bool
 
 var (
 	documentCollectors = map[string]Collector{}
+
+	// acknowledgers holds the functions to call with the result of emitting
+	// each document, see SetAcknowledger
+	acknowledgersMu sync.Mutex
+	acknowledgers   = map[*processor.Document]func(error){}
+
+	// channels holds the document channels being drained by
+	// CollectWithWorkers, see BufferedDocuments
+	channelsMu sync.Mutex
+	channels   = map[<-chan *processor.Document]bool{}
 )
 
 func RegisterDocumentCollector(c Collector, collectorType string) error {
@@ -59,43 +82,166 @@ func RegisterDocumentCollector(c Collector, collectorType string) error {
 	return nil
 }
 
+// ParseSince parses the threshold before which collectors skip documents,
+// given either as an RFC 3339 timestamp (e.g. "2023-01-02T15:04:05Z") or as
+// a duration before now (e.g. "24h"). The empty string gives the zero time,
+// which collects all the documents.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected an RFC 3339 timestamp or a positive duration", s)
+	}
+	return now.Add(-d), nil
+}
+
+// SetAcknowledger registers ack to be called with the result of emitting the
+// document, before the document is sent to the channel of the collector.
+// Collectors reading from a stream use it to only commit their position once
+// their documents have been ingested.
+func SetAcknowledger(d *processor.Document, ack func(error)) {
+	acknowledgersMu.Lock()
+	defer acknowledgersMu.Unlock()
+	acknowledgers[d] = ack
+}
+
+// Acknowledge calls the function registered for the document with the result
+// of emitting it, if there is one. Collect acknowledges every document it
+// emits; consumers of CollectDocuments should do the same.
+func Acknowledge(d *processor.Document, err error) {
+	acknowledgersMu.Lock()
+	ack, ok := acknowledgers[d]
+	delete(acknowledgers, d)
+	acknowledgersMu.Unlock()
+	if ok {
+		ack(err)
+	}
+}
+
 // Collect takes all the collectors and starts collecting artifacts
 // after Collect is called, no calls to RegisterDocumentCollector should happen.
+//
+// Every collected document is passed to emitter. Collect returns once all the
+// collectors are done and all their documents have been emitted, or with the
+// first collector error that handleErr could not handle.
 func Collect(ctx context.Context, emitter Emitter, handleErr ErrHandler) error {
-	// docChan to collect artifacts
-	docChan := make(chan *processor.Document, BufferChannelSize)
-	// errChan to receive error from collectors
-	errChan := make(chan error, len(documentCollectors))
-	// logger
+	return CollectWithWorkers(ctx, emitter, handleErr, 1)
+}
+
+// CollectWithWorkers is like Collect, but emits the documents from workers
+// goroutines draining the channel of the collectors, so emitter must be safe
+// for concurrent use. Documents are emitted in no particular order, each one
+// exactly once, including those still buffered when the collectors return.
+func CollectWithWorkers(ctx context.Context, emitter Emitter, handleErr ErrHandler, workers int) error {
 	logger := logging.FromContext(ctx)
+	if workers < 1 {
+		return fmt.Errorf("the number of workers must be positive, got %d", workers)
+	}
 
-	for _, collector := range documentCollectors {
-		c := collector
+	docChan, wait := CollectDocuments(ctx, handleErr)
+	channelsMu.Lock()
+	channels[docChan] = true
+	channelsMu.Unlock()
+	defer func() {
+		channelsMu.Lock()
+		delete(channels, docChan)
+		channelsMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
 		go func() {
-			errChan <- c.RetrieveArtifacts(ctx, docChan)
+			defer wg.Done()
+			for d := range docChan {
+				metrics.DocumentsCollected.WithLabelValues(d.SourceInformation.Collector).Inc()
+				err := emitter(d)
+				if err != nil {
+					logger.Errorf("emit error: %v", err)
+				}
+				Acknowledge(d, err)
+			}
 		}()
 	}
+	wg.Wait()
+	return wait()
+}
 
-	numCollectors := len(documentCollectors)
-	collectorsDone := 0
-	for collectorsDone < numCollectors {
-		select {
-		case d := <-docChan:
-			if err := emitter(d); err != nil {
-				logger.Errorf("emit error: %v", err)
-			}
-		case err := <-errChan:
+// BufferedDocuments returns the number of documents that were collected, but
+// are still waiting in the channel of a running Collect to be emitted
+func BufferedDocuments() int {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	n := 0
+	for c := range channels {
+		n += len(c)
+	}
+	return n
+}
+
+// CollectDocuments starts all the collectors and returns the channel through
+// which they emit the collected documents. After CollectDocuments is called,
+// no calls to RegisterDocumentCollector should happen.
+//
+// Collectors block when the channel is full, so no document is dropped when
+// the consumer is slower than the collectors. The channel is closed exactly
+// once, after all the collectors have returned, so it can be consumed with a
+// `range` loop.
+//
+// The error returned by each collector is passed to handleErr. If handleErr
+// cannot handle it, the context of the remaining collectors is canceled. The
+// returned function blocks until the channel is closed and returns the first
+// error that could not be handled.
+func CollectDocuments(ctx context.Context, handleErr ErrHandler) (<-chan *processor.Document, func() error) {
+	ctx, cancel := context.WithCancel(ctx)
+	docChan := make(chan *processor.Document, BufferChannelSize)
+	done := make(chan struct{})
+
+	var (
+		wg sync.WaitGroup
+		// mu serializes the calls to handleErr and guards collectErr
+		mu         sync.Mutex
+		collectErr error
+	)
+	for collectorType, collector := range documentCollectors {
+		c := collector
+		collectorType := collectorType
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, span := tracing.Start(ctx, "collect", attribute.String("collector", collectorType))
+			err := c.RetrieveArtifacts(ctx, docChan)
+			tracing.RecordError(span, err)
+			span.End()
+
+			mu.Lock()
+			defer mu.Unlock()
 			if !handleErr(err) {
-				return err
+				if collectErr == nil {
+					collectErr = err
+				}
+				cancel()
 			}
-			collectorsDone += 1
-		}
+		}()
 	}
-	for len(docChan) > 0 {
-		d := <-docChan
-		if err := emitter(d); err != nil {
-			logger.Errorf("emit error: %v", err)
-		}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(docChan)
+		close(done)
+	}()
+
+	wait := func() error {
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		return collectErr
 	}
-	return nil
+	return docChan, wait
 }
@@ -17,16 +17,37 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/guacsec/guac/pkg/handler/collector/file"
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/logging"
 )
 
-func TestCollect(t *testing.T) {
+type countCollector struct {
+	name  string
+	count int
+	err   error
+}
+
+func (c *countCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	for i := 0; i < c.count; i++ {
+		docChannel <- &processor.Document{
+			Blob: []byte(fmt.Sprintf("%s-%d", c.name, i)),
+		}
+	}
+	return c.err
+}
+
+func (c *countCollector) Type() string {
+	return c.name
+}
+
+func TestCollectDocuments(t *testing.T) {
 	ctx := logging.WithLogger(context.Background())
 
 	errHandler := func(err error) bool {
@@ -34,49 +55,322 @@ func TestCollect(t *testing.T) {
 	}
 
 	tests := []struct {
-		name          string
-		collectorType string
-		collector     Collector
-		wantErr       bool
-		want          []*processor.Document
+		name       string
+		collectors []Collector
+		wantDocs   int
+		wantErr    bool
 	}{{
-		name:      "file collector file",
-		collector: file.NewFileCollector(ctx, "./testdata", false, time.Second),
-		want: []*processor.Document{{
-			Blob:   []byte("hello\n"),
-			Type:   processor.DocumentUnknown,
-			Format: processor.FormatUnknown,
-			SourceInformation: processor.SourceInformation{
-				Collector: string(file.FileCollector),
-				Source:    "file:///testdata/hello",
-			}},
+		name: "more documents than the channel buffer",
+		collectors: []Collector{
+			&countCollector{name: "a", count: BufferChannelSize},
+			&countCollector{name: "b", count: BufferChannelSize},
+			&countCollector{name: "c", count: 10},
 		},
-		wantErr: false,
-	},
-	}
+		wantDocs: 2*BufferChannelSize + 10,
+	}, {
+		name: "collector error",
+		collectors: []Collector{
+			&countCollector{name: "a", count: 10},
+			&countCollector{name: "b", count: 10, err: errors.New("failed")},
+		},
+		wantDocs: 20,
+		wantErr:  true,
+	}, {
+		name:       "no collectors",
+		collectors: []Collector{},
+		wantDocs:   0,
+	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var collectedDoc []*processor.Document
 			documentCollectors = map[string]Collector{}
+			for _, c := range tt.collectors {
+				if err := RegisterDocumentCollector(c, c.Type()); err != nil {
+					t.Fatal(err)
+				}
+			}
 
-			err := RegisterDocumentCollector(tt.collector, tt.collector.Type())
-			if err != nil {
-				t.Error(err)
+			docChan, wait := CollectDocuments(ctx, errHandler)
+			seen := map[string]bool{}
+			for d := range docChan {
+				seen[string(d.Blob)] = true
 			}
+			err := wait()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CollectDocuments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(seen) != tt.wantDocs {
+				t.Errorf("CollectDocuments() emitted %d documents, want %d", len(seen), tt.wantDocs)
+			}
+		})
+	}
+}
 
+// ackCollector records the result of emitting each of its documents
+type ackCollector struct {
+	count int
+	acks  []error
+}
+
+func (c *ackCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	for i := 0; i < c.count; i++ {
+		d := &processor.Document{Blob: []byte(fmt.Sprintf("ack-%d", i))}
+		SetAcknowledger(d, func(err error) {
+			c.acks = append(c.acks, err)
+		})
+		docChannel <- d
+	}
+	return nil
+}
+
+func (c *ackCollector) Type() string {
+	return "ack"
+}
+
+func TestCollect_Acknowledge(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	documentCollectors = map[string]Collector{}
+
+	c := &ackCollector{count: 3}
+	if err := RegisterDocumentCollector(c, c.Type()); err != nil {
+		t.Fatal(err)
+	}
+	errEmit := errors.New("emit failed")
+	emit := func(d *processor.Document) error {
+		if string(d.Blob) == "ack-1" {
+			return errEmit
+		}
+		return nil
+	}
+	if err := Collect(ctx, emit, func(err error) bool { return err == nil }); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	want := []error{nil, errEmit, nil}
+	if !reflect.DeepEqual(c.acks, want) {
+		t.Errorf("acknowledged %v, want %v", c.acks, want)
+	}
+	if len(acknowledgers) != 0 {
+		t.Errorf("%d acknowledgers were not released", len(acknowledgers))
+	}
+}
+
+func TestCollectWithWorkers(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+
+	tests := []struct {
+		name     string
+		workers  int
+		wantDocs int
+		wantErr  bool
+	}{{
+		name:     "single worker",
+		workers:  1,
+		wantDocs: BufferChannelSize + 10,
+	}, {
+		name:     "worker pool",
+		workers:  8,
+		wantDocs: BufferChannelSize + 10,
+	}, {
+		name:    "no workers",
+		workers: 0,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documentCollectors = map[string]Collector{}
+			for _, c := range []Collector{
+				&countCollector{name: "a", count: BufferChannelSize},
+				&countCollector{name: "b", count: 10},
+			} {
+				if err := RegisterDocumentCollector(c, c.Type()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var mu sync.Mutex
+			emitted := map[string]int{}
 			emit := func(d *processor.Document) error {
-				collectedDoc = append(collectedDoc, d)
+				// slow emitter, so that documents are still buffered when
+				// the collectors return
+				time.Sleep(10 * time.Microsecond)
+				mu.Lock()
+				defer mu.Unlock()
+				emitted[string(d.Blob)]++
 				return nil
 			}
-			err = Collect(ctx, emit, errHandler)
+			err := CollectWithWorkers(ctx, emit, func(err error) bool { return err == nil }, tt.workers)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Collect() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("CollectWithWorkers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(emitted) != tt.wantDocs {
+				t.Errorf("CollectWithWorkers() emitted %d documents, want %d", len(emitted), tt.wantDocs)
 			}
-			if err == nil {
-				if !reflect.DeepEqual(collectedDoc, tt.want) {
-					t.Errorf("Collect() = %v, want %v", collectedDoc, tt.want)
+			for doc, n := range emitted {
+				if n != 1 {
+					t.Errorf("document %s emitted %d times", doc, n)
 				}
 			}
 		})
 	}
 }
+
+func TestBufferedDocuments(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	documentCollectors = map[string]Collector{}
+
+	c := &countCollector{name: "buffered", count: 5}
+	if err := RegisterDocumentCollector(c, c.Type()); err != nil {
+		t.Fatal(err)
+	}
+	// the first document blocks the emitter until the others are buffered
+	emitting := make(chan struct{})
+	release := make(chan struct{})
+	emit := func(d *processor.Document) error {
+		if string(d.Blob) == "buffered-0" {
+			close(emitting)
+			<-release
+		}
+		return nil
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- Collect(ctx, emit, func(err error) bool { return err == nil })
+	}()
+
+	<-emitting
+	deadline := time.Now().Add(5 * time.Second)
+	for BufferedDocuments() != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("BufferedDocuments() = %d, want 4", BufferedDocuments())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if err := <-errChan; err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if got := BufferedDocuments(); got != 0 {
+		t.Errorf("BufferedDocuments() = %d after Collect returned, want 0", got)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since   string
+		want    time.Time
+		wantErr bool
+	}{{
+		since: "",
+		want:  time.Time{},
+	}, {
+		since: "2023-01-01T10:00:00Z",
+		want:  time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+	}, {
+		since: "24h",
+		want:  time.Date(2023, 1, 1, 15, 0, 0, 0, time.UTC),
+	}, {
+		since:   "-1h",
+		wantErr: true,
+	}, {
+		since:   "yesterday",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.since, func(t *testing.T) {
+			got, err := ParseSince(tt.since, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// typesCollector emits one document of each of the types, with the format
+// left unknown
+type typesCollector struct {
+	types []processor.DocumentType
+}
+
+func (c *typesCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	for i, t := range c.types {
+		docChannel <- &processor.Document{
+			Blob:   []byte(fmt.Sprintf("doc-%d", i)),
+			Type:   t,
+			Format: processor.FormatUnknown,
+		}
+	}
+	return nil
+}
+
+func (c *typesCollector) Type() string {
+	return "types"
+}
+
+func TestWithDocumentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		types       []processor.DocumentType
+		docType     processor.DocumentType
+		format      processor.FormatType
+		wantTypes   []processor.DocumentType
+		wantFormats []processor.FormatType
+	}{{
+		name:        "declared type and format",
+		types:       []processor.DocumentType{processor.DocumentUnknown, ""},
+		docType:     processor.DocumentSPDX,
+		format:      processor.FormatJSON,
+		wantTypes:   []processor.DocumentType{processor.DocumentSPDX, processor.DocumentSPDX},
+		wantFormats: []processor.FormatType{processor.FormatJSON, processor.FormatJSON},
+	}, {
+		name:        "type set by the collector is kept",
+		types:       []processor.DocumentType{processor.DocumentGoMod, processor.DocumentUnknown},
+		docType:     processor.DocumentCycloneDX,
+		format:      processor.FormatUnknown,
+		wantTypes:   []processor.DocumentType{processor.DocumentGoMod, processor.DocumentCycloneDX},
+		wantFormats: []processor.FormatType{processor.FormatUnknown, processor.FormatUnknown},
+	}, {
+		name:        "declared format only",
+		types:       []processor.DocumentType{""},
+		docType:     processor.DocumentUnknown,
+		format:      processor.FormatXML,
+		wantTypes:   []processor.DocumentType{processor.DocumentUnknown},
+		wantFormats: []processor.FormatType{processor.FormatXML},
+	}, {
+		name:        "nothing declared",
+		types:       []processor.DocumentType{processor.DocumentUnknown},
+		docType:     processor.DocumentUnknown,
+		format:      processor.FormatUnknown,
+		wantTypes:   []processor.DocumentType{processor.DocumentUnknown},
+		wantFormats: []processor.FormatType{processor.FormatUnknown},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := WithDocumentType(&typesCollector{types: tt.types}, tt.docType, tt.format)
+			if c.Type() != "types" {
+				t.Errorf("Type() = %q, want the type of the wrapped collector", c.Type())
+			}
+
+			docChan := make(chan *processor.Document, len(tt.types))
+			if err := c.RetrieveArtifacts(context.Background(), docChan); err != nil {
+				t.Fatal(err)
+			}
+			close(docChan)
+			var gotTypes []processor.DocumentType
+			var gotFormats []processor.FormatType
+			for d := range docChan {
+				gotTypes = append(gotTypes, d.Type)
+				gotFormats = append(gotFormats, d.Format)
+			}
+			if !reflect.DeepEqual(gotTypes, tt.wantTypes) {
+				t.Errorf("types = %v, want %v", gotTypes, tt.wantTypes)
+			}
+			if !reflect.DeepEqual(gotFormats, tt.wantFormats) {
+				t.Errorf("formats = %v, want %v", gotFormats, tt.wantFormats)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Factory creates a collector that is compiled into the binary without being
+// part of GUAC, e.g. a collector for an internal system added by a fork.
+// config is the value given to the collector on the command line, if any; its
+// format is up to the collector.
+type Factory func(ctx context.Context, config string) (Collector, error)
+
+var factories = map[string]Factory{}
+
+// RegisterCollectorFactory makes the collector created by factory available
+// under name, to be run with `guacone files --collector name[=config]`.
+//
+// It is meant to be called from the init function of a file linked into the
+// binary, e.g. a file of cmd/guacone/cmd guarded by a build tag, so that a
+// fork only adds that file and its collector package:
+//
+//	//go:build acme
+//
+//	func init() {
+//		_ = collector.RegisterCollectorFactory("acme", acme.NewCollector)
+//	}
+func RegisterCollectorFactory(name string, factory Factory) error {
+	if _, ok := factories[name]; ok {
+		return fmt.Errorf("the collector factory is being overwritten: %s", name)
+	}
+	factories[name] = factory
+	return nil
+}
+
+// CollectorFactories returns the sorted names of the registered collector
+// factories
+func CollectorFactories() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewExternalCollector creates the collector of the factory registered under
+// name, with config
+func NewExternalCollector(ctx context.Context, name, config string) (Collector, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("no collector factory registered for %s, expected one of %v", name, CollectorFactories())
+	}
+	c, err := factory(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the %s collector: %w", name, err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("the %s collector factory returned no collector", name)
+	}
+	return c, nil
+}
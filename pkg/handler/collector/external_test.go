@@ -0,0 +1,112 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// inventoryCollector is the skeleton of a collector added outside of GUAC:
+// it implements Collector, and newInventoryCollector is registered as its
+// Factory. It stands for a collector of an internal inventory, configured
+// with the comma separated names of the documents to fetch from it.
+type inventoryCollector struct {
+	names []string
+}
+
+func newInventoryCollector(ctx context.Context, config string) (Collector, error) {
+	if config == "" {
+		return nil, errors.New("expected the names of the documents to collect")
+	}
+	return &inventoryCollector{names: strings.Split(config, ",")}, nil
+}
+
+func (c *inventoryCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	for _, name := range c.names {
+		doc := &processor.Document{
+			Blob:   []byte(`{"name": "` + name + `"}`),
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: c.Type(),
+				Source:    "inventory://" + name,
+			},
+		}
+		select {
+		case docChannel <- doc:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *inventoryCollector) Type() string {
+	return "inventory"
+}
+
+func TestNewExternalCollector(t *testing.T) {
+	ResetDocumentCollectors()
+	defer ResetCollectorFactories()
+	defer ResetDocumentCollectors()
+	ctx := logging.WithLogger(context.Background())
+
+	if err := RegisterCollectorFactory("inventory", newInventoryCollector); err != nil {
+		t.Fatalf("RegisterCollectorFactory() error = %v", err)
+	}
+	if err := RegisterCollectorFactory("inventory", newInventoryCollector); err == nil {
+		t.Errorf("RegisterCollectorFactory() overwrote the factory")
+	}
+	if got, want := CollectorFactories(), []string{"inventory"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectorFactories() = %v, want %v", got, want)
+	}
+
+	if _, err := NewExternalCollector(ctx, "unknown", ""); err == nil {
+		t.Errorf("NewExternalCollector() created an unregistered collector")
+	}
+	if _, err := NewExternalCollector(ctx, "inventory", ""); err == nil {
+		t.Errorf("NewExternalCollector() ignored the error of the factory")
+	}
+
+	c, err := NewExternalCollector(ctx, "inventory", "sbom-a,sbom-b")
+	if err != nil {
+		t.Fatalf("NewExternalCollector() error = %v", err)
+	}
+	if err := RegisterDocumentCollector(c, "inventory"); err != nil {
+		t.Fatalf("RegisterDocumentCollector() error = %v", err)
+	}
+	var sources []string
+	emit := func(d *processor.Document) error {
+		if d.SourceInformation.Collector != "inventory" {
+			t.Errorf("collector = %q, want inventory", d.SourceInformation.Collector)
+		}
+		sources = append(sources, d.SourceInformation.Source)
+		return nil
+	}
+	if err := Collect(ctx, emit, func(err error) bool { return err == nil }); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if want := []string{"inventory://sbom-a", "inventory://sbom-b"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+}
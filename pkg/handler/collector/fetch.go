@@ -0,0 +1,75 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultFetchConcurrency is the number of documents a collector downloads
+// concurrently, unless set with SetFetchConcurrency
+const DefaultFetchConcurrency = 4
+
+var fetchConcurrency int32 = DefaultFetchConcurrency
+
+// SetFetchConcurrency sets the number of documents each collector fanning out
+// downloads (e.g. the objects of a bucket) fetches concurrently. It is
+// independent of the number of workers emitting the documents, so that
+// fetching and processing can be tuned separately.
+func SetFetchConcurrency(n int) error {
+	if n < 1 {
+		return fmt.Errorf("the fetch concurrency must be positive, got %d", n)
+	}
+	atomic.StoreInt32(&fetchConcurrency, int32(n))
+	return nil
+}
+
+// FetchConcurrency returns the number of documents each collector downloads
+// concurrently
+func FetchConcurrency() int {
+	return int(atomic.LoadInt32(&fetchConcurrency))
+}
+
+// Fetch calls fetch for each of the n items, from at most FetchConcurrency()
+// goroutines at a time, and waits for all the calls to return. Calls are
+// made in order but may complete in any order, so fetch must be safe for
+// concurrent use. Once ctx is done, the remaining items are skipped and the
+// error of ctx is returned.
+func Fetch(ctx context.Context, n int, fetch func(ctx context.Context, i int)) error {
+	sem := make(chan struct{}, FetchConcurrency())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fetch(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
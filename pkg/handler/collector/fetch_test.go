@@ -0,0 +1,93 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	if err := SetFetchConcurrency(3); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetFetchConcurrency(DefaultFetchConcurrency) }()
+
+	var running, maxRunning int32
+	var mu sync.Mutex
+	fetched := map[int]bool{}
+	err := Fetch(context.Background(), 20, func(ctx context.Context, i int) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		if n > maxRunning {
+			maxRunning = n
+		}
+		fetched[i] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+	})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(fetched) != 20 {
+		t.Errorf("Fetch() fetched %d items, want 20", len(fetched))
+	}
+	if maxRunning > 3 {
+		t.Errorf("Fetch() ran %d fetches concurrently, want at most 3", maxRunning)
+	}
+}
+
+func TestFetchCanceled(t *testing.T) {
+	if err := SetFetchConcurrency(1); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetFetchConcurrency(DefaultFetchConcurrency) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var fetched int32
+	err := Fetch(ctx, 10, func(ctx context.Context, i int) {
+		if atomic.AddInt32(&fetched, 1) == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Fetch() error = %v, want %v", err, context.Canceled)
+	}
+	if fetched != 2 {
+		t.Errorf("Fetch() fetched %d items after the cancellation, want 2", fetched)
+	}
+}
+
+func TestSetFetchConcurrency(t *testing.T) {
+	defer func() { _ = SetFetchConcurrency(DefaultFetchConcurrency) }()
+	if err := SetFetchConcurrency(0); err == nil {
+		t.Errorf("SetFetchConcurrency(0) succeeded, want error")
+	}
+	if got := FetchConcurrency(); got != DefaultFetchConcurrency {
+		t.Errorf("FetchConcurrency() = %d, want %d", got, DefaultFetchConcurrency)
+	}
+	if err := SetFetchConcurrency(8); err != nil {
+		t.Fatalf("SetFetchConcurrency(8) error = %v", err)
+	}
+	if got := FetchConcurrency(); got != 8 {
+		t.Errorf("FetchConcurrency() = %d, want 8", got)
+	}
+}
@@ -0,0 +1,210 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// DefaultArchiveDepth is the default number of nested archive levels that are
+// opened when collecting documents
+const DefaultArchiveDepth = 3
+
+// archiveSeparator separates the path of an archive from the name of one of
+// its entries in the source of the collected documents, e.g.
+// `file:///bundle.tar.gz!/sboms/alpine.spdx.json`
+const archiveSeparator = "!/"
+
+type archiveKind int
+
+const (
+	notArchive archiveKind = iota
+	gzipArchive
+	tarArchive
+	zipArchive
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	tarMagic  = []byte("ustar")
+)
+
+// tarMagicOffset is the offset of the magic bytes in the header of the first
+// entry of POSIX and GNU tar archives
+const tarMagicOffset = 257
+
+// detectArchive returns the kind of archive in blob, looking at the magic
+// bytes first and falling back to the extension of name for tar archives
+// without magic bytes.
+func detectArchive(name string, blob []byte) archiveKind {
+	switch {
+	case bytes.HasPrefix(blob, gzipMagic):
+		return gzipArchive
+	case bytes.HasPrefix(blob, zipMagic):
+		return zipArchive
+	case len(blob) >= tarMagicOffset+len(tarMagic) &&
+		bytes.Equal(blob[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return tarArchive
+	case strings.HasSuffix(name, ".tar"):
+		return tarArchive
+	}
+	return notArchive
+}
+
+// readDocuments calls emit with the source and contents of each document in
+// blob. Documents that are not archives are emitted as is. The entries of
+// archives are read recursively, opening at most depth levels of nested
+// archives; deeper archives, directories and empty entries are skipped.
+// Compression (e.g. the gzip layer of a `.tar.gz`) does not count as a level.
+func readDocuments(ctx context.Context, name string, source string, blob []byte, depth int, emit func(source string, blob []byte)) error {
+	logger := logging.FromContext(ctx)
+
+	kind := detectArchive(name, blob)
+	if kind == notArchive {
+		if len(blob) == 0 {
+			logger.Debugf("skipping empty entry %s", source)
+			return nil
+		}
+		emit(source, blob)
+		return nil
+	}
+
+	if kind == gzipArchive {
+		r, err := gzip.NewReader(bytes.NewReader(blob))
+		if err != nil {
+			return fmt.Errorf("unable to read gzip archive %s: %w", source, err)
+		}
+		defer r.Close()
+		contents, err := processor.ReadDocument(r, source)
+		if err != nil {
+			return fmt.Errorf("unable to read gzip archive %s: %w", source, err)
+		}
+		return readDocuments(ctx, gunzippedName(name), source, contents, depth, emit)
+	}
+
+	if depth <= 0 {
+		logger.Debugf("skipping archive %s nested deeper than the maximum depth", source)
+		return nil
+	}
+
+	entry := func(entryName string, contents []byte) error {
+		entrySource := source + archiveSeparator + strings.TrimPrefix(entryName, "/")
+		return readDocuments(ctx, entryName, entrySource, contents, depth-1, emit)
+	}
+	switch kind {
+	case tarArchive:
+		return readTar(ctx, source, blob, entry)
+	case zipArchive:
+		return readZip(ctx, source, blob, entry)
+	}
+	return nil
+}
+
+func readTar(ctx context.Context, source string, blob []byte, entry func(name string, contents []byte) error) error {
+	logger := logging.FromContext(ctx)
+
+	r := tar.NewReader(bytes.NewReader(blob))
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read tar archive %s: %w", source, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			logger.Debugf("skipping entry %s of %s, not a regular file", header.Name, source)
+			continue
+		}
+		contents, err := processor.ReadDocument(r, source+archiveSeparator+header.Name)
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			logger.Warnf("skipping entry %s of %s: %v", header.Name, source, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read entry %s of tar archive %s: %w", header.Name, source, err)
+		}
+		if err := entry(header.Name, contents); err != nil {
+			return err
+		}
+	}
+}
+
+func readZip(ctx context.Context, source string, blob []byte, entry func(name string, contents []byte) error) error {
+	logger := logging.FromContext(ctx)
+
+	r, err := zip.NewReader(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		return fmt.Errorf("unable to read zip archive %s: %w", source, err)
+	}
+	for _, file := range r.File {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !file.Mode().IsRegular() {
+			logger.Debugf("skipping entry %s of %s, not a regular file", file.Name, source)
+			continue
+		}
+		contents, err := readZipEntry(file, source+archiveSeparator+file.Name)
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			logger.Warnf("skipping entry %s of %s: %v", file.Name, source, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read entry %s of zip archive %s: %w", file.Name, source, err)
+		}
+		if err := entry(file.Name, contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipEntry(file *zip.File, source string) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return processor.ReadDocument(rc, source)
+}
+
+// gunzippedName returns the name of the file compressed in the gzip file name
+func gunzippedName(name string) string {
+	switch ext := path.Ext(name); ext {
+	case ".tgz":
+		return strings.TrimSuffix(name, ext) + ".tar"
+	case ".gz":
+		return strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,218 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"reflect"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	contents []byte
+	dir      bool
+}
+
+func tarBlob(t *testing.T, entries ...entry) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.contents)), Typeflag: tar.TypeReg}
+		if e.dir {
+			header = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipBlob(t *testing.T, entries ...entry) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		f, err := w.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(e.contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBlob(t *testing.T, blob []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(blob); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func Test_readDocuments(t *testing.T) {
+	spdx := []byte(`{"spdxVersion": "SPDX-2.2"}`)
+	cdx := []byte(`{"bomFormat": "CycloneDX"}`)
+
+	tests := []struct {
+		name    string
+		file    string
+		blob    []byte
+		depth   int
+		want    map[string]string
+		wantErr bool
+	}{{
+		name:  "plain document",
+		file:  "sbom.json",
+		blob:  spdx,
+		depth: 1,
+		want:  map[string]string{"file:///sbom.json": string(spdx)},
+	}, {
+		name: "tar.gz",
+		file: "bundle.tar.gz",
+		blob: gzipBlob(t, tarBlob(t,
+			entry{name: "sboms/", dir: true},
+			entry{name: "sboms/spdx.json", contents: spdx},
+			entry{name: "sboms/cdx.json", contents: cdx},
+			entry{name: "sboms/empty"},
+		)),
+		depth: 1,
+		want: map[string]string{
+			"file:///bundle.tar.gz!/sboms/spdx.json": string(spdx),
+			"file:///bundle.tar.gz!/sboms/cdx.json":  string(cdx),
+		},
+	}, {
+		name:  "zip",
+		file:  "bundle.zip",
+		blob:  zipBlob(t, entry{name: "spdx.json", contents: spdx}),
+		depth: 1,
+		want:  map[string]string{"file:///bundle.zip!/spdx.json": string(spdx)},
+	}, {
+		name:  "gzipped document",
+		file:  "sbom.json.gz",
+		blob:  gzipBlob(t, spdx),
+		depth: 1,
+		want:  map[string]string{"file:///sbom.json.gz": string(spdx)},
+	}, {
+		name: "nested archive",
+		file: "bundle.tgz",
+		blob: gzipBlob(t, tarBlob(t,
+			entry{name: "spdx.json", contents: spdx},
+			entry{name: "inner.zip", contents: zipBlob(t, entry{name: "cdx.json", contents: cdx})},
+		)),
+		depth: 2,
+		want: map[string]string{
+			"file:///bundle.tgz!/spdx.json":           string(spdx),
+			"file:///bundle.tgz!/inner.zip!/cdx.json": string(cdx),
+		},
+	}, {
+		name: "nested archive deeper than max depth",
+		file: "bundle.tgz",
+		blob: gzipBlob(t, tarBlob(t,
+			entry{name: "spdx.json", contents: spdx},
+			entry{name: "inner.zip", contents: zipBlob(t, entry{name: "cdx.json", contents: cdx})},
+		)),
+		depth: 1,
+		want:  map[string]string{"file:///bundle.tgz!/spdx.json": string(spdx)},
+	}, {
+		name:    "corrupt zip",
+		file:    "bundle.zip",
+		blob:    []byte("PK\x03\x04garbage"),
+		depth:   1,
+		want:    map[string]string{},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			emit := func(source string, blob []byte) {
+				got[source] = string(blob)
+			}
+			err := readDocuments(context.Background(), tt.file, "file:///"+tt.file, tt.blob, tt.depth, emit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readDocuments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readDocuments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_detectArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		blob []byte
+		want archiveKind
+	}{{
+		name: "gzip",
+		file: "bundle",
+		blob: gzipBlob(t, []byte("hello")),
+		want: gzipArchive,
+	}, {
+		name: "zip",
+		file: "bundle",
+		blob: zipBlob(t, entry{name: "hello", contents: []byte("hello")}),
+		want: zipArchive,
+	}, {
+		name: "tar",
+		file: "bundle",
+		blob: tarBlob(t, entry{name: "hello", contents: []byte("hello")}),
+		want: tarArchive,
+	}, {
+		name: "tar by extension",
+		file: "bundle.tar",
+		blob: []byte("hello"),
+		want: tarArchive,
+	}, {
+		name: "zip extension without magic bytes",
+		file: "bundle.zip",
+		blob: []byte("hello"),
+		want: notArchive,
+	}, {
+		name: "document",
+		file: "sbom.json",
+		blob: []byte(`{"spdxVersion": "SPDX-2.2"}`),
+		want: notArchive,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectArchive(tt.file, tt.blob); got != tt.want {
+				t.Errorf("detectArchive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
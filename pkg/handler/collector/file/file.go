@@ -20,12 +20,16 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
 	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/lockfile"
+	"github.com/guacsec/guac/pkg/logging"
 )
 
 const (
@@ -37,13 +41,35 @@ type fileCollector struct {
 	lastChecked time.Time
 	poll        bool
 	interval    time.Duration
+	// archiveDepth is the number of nested archive levels whose entries are
+	// emitted as documents, 0 emits archives as regular files
+	archiveDepth int
+	// since is the time before which the files were modified are skipped,
+	// the zero time collects all of them
+	since time.Time
+	// emitted tracks the modification time of the files that have already
+	// been emitted so that polling only emits new or modified files
+	emitted map[string]time.Time
+	// checkpoints records the modification time of the files whose documents
+	// have all been ingested, so that they are skipped after a restart
+	checkpoints checkpoint.Store
 }
 
-func NewFileCollector(ctx context.Context, path string, poll bool, interval time.Duration) *fileCollector {
+// NewFileCollector returns a collector emitting the files under path. The
+// entries of `.tar`, `.tar.gz`, `.tgz` and `.zip` archives are emitted as
+// individual documents, opening up to archiveDepth levels of nested archives.
+// Files modified before since are skipped, the zero time collects all of
+// them. Files are checkpointed in checkpoints once ingested, a nil store
+// disables checkpointing.
+func NewFileCollector(ctx context.Context, path string, poll bool, interval time.Duration, archiveDepth int, since time.Time, checkpoints checkpoint.Store) *fileCollector {
 	return &fileCollector{
-		path:     path,
-		poll:     poll,
-		interval: interval,
+		path:         path,
+		poll:         poll,
+		interval:     interval,
+		archiveDepth: archiveDepth,
+		since:        since,
+		emitted:      map[string]time.Time{},
+		checkpoints:  checkpoints,
 	}
 }
 
@@ -54,9 +80,20 @@ func NewFileCollector(ctx context.Context, path string, poll bool, interval time
 // for new artifacts as they are being uploaded by polling on an interval or run once and
 // grab all the artifacts and end.
 func (f *fileCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
-	if _, err := os.Stat(f.path); os.IsExist(err) {
+	rootInfo, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("path: %s does not exist", f.path)
 	}
+	if err != nil {
+		return err
+	}
+	if !rootInfo.IsDir() && !rootInfo.Mode().IsRegular() {
+		return fmt.Errorf("path: %s is neither a file nor a directory", f.path)
+	}
+	if f.emitted == nil {
+		f.emitted = map[string]time.Time{}
+	}
+	logger := logging.FromContext(ctx)
 
 	readFunc := func(path string, dirEntry fs.DirEntry, err error) error {
 		// If the context has been canceled it contains an err which we can throw.
@@ -73,53 +110,198 @@ func (f *fileCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<-
 		if dirEntry.IsDir() {
 			return nil
 		}
-		if info, err := dirEntry.Info(); !info.ModTime().After(f.lastChecked) || err != nil {
+		info, err := dirEntry.Info()
+		if err != nil {
 			return err
 		}
+		if !info.ModTime().After(f.lastChecked) {
+			return nil
+		}
+		if info.ModTime().Before(f.since) {
+			return nil
+		}
+		if modTime, ok := f.emitted[path]; ok && modTime.Equal(info.ModTime()) {
+			return nil
+		}
 
-		blob, err := ioutil.ReadFile(path)
+		ack, err := f.checkpoint(ctx, path, info.ModTime())
 		if err != nil {
 			return err
 		}
+		if ack == nil {
+			f.emitted[path] = info.ModTime()
+			return nil
+		}
 
-		doc := &processor.Document{
-			Blob:   blob,
-			Type:   processor.DocumentUnknown,
-			Format: processor.FormatUnknown,
-			SourceInformation: processor.SourceInformation{
-				Collector: string(FileCollector),
-				Source:    fmt.Sprintf("file:///%s", path),
-			},
+		// oversized files are skipped without being read, but not
+		// checkpointed
+		err = processor.CheckDocumentSize(path, info.Size())
+		var blob []byte
+		if err == nil {
+			blob, err = readFile(path)
+		}
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			logger.Errorf("skipping %s: %v", path, err)
+			ack.done(err)
+			f.emitted[path] = info.ModTime()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		emit := func(source string, blob []byte) {
+			// lockfiles cannot be told apart by their content, only by
+			// their name
+			d := &processor.Document{
+				Blob:   blob,
+				Type:   lockfile.TypeOf(source),
+				Format: processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{
+					Collector: string(FileCollector),
+					Source:    source,
+				},
+			}
+			ack.add(d)
+			docChannel <- d
 		}
 
-		docChannel <- doc
+		source := fmt.Sprintf("file:///%s", path)
+		if f.archiveDepth > 0 {
+			if err := readDocuments(ctx, path, source, blob, f.archiveDepth, emit); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				logger.Warnf("skipping %s: %v", path, err)
+			}
+		} else {
+			emit(source, blob)
+		}
+		ack.done(nil)
+		f.emitted[path] = info.ModTime()
 
 		return nil
 	}
 
+	// A regular file is read on its own. filepath.WalkDir would also visit
+	// it, but does not follow a symbolic link to it.
+	walk := func() error {
+		if rootInfo.IsDir() {
+			return filepath.WalkDir(f.path, readFunc)
+		}
+		path := filepath.Clean(f.path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return readFunc(path, nil, err)
+		}
+		return readFunc(path, fs.FileInfoToDirEntry(info), nil)
+	}
+
 	if f.poll {
 		for {
-			err := filepath.WalkDir(f.path, readFunc)
+			err := walk()
 			if err != nil {
 				if errors.Is(err, ctx.Err()) {
 					return nil
 				}
 				return err
 			}
-			f.lastChecked = time.Now()
-			time.Sleep(f.interval)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(f.interval):
+			}
 		}
 	} else {
-		err := filepath.WalkDir(f.path, readFunc)
+		err := walk()
 		if err != nil {
 			return err
 		}
-		f.lastChecked = time.Now()
 	}
 
 	return nil
 }
 
+// readFile reads the file, without reading beyond the maximum document size
+// if it grew since it was listed
+func readFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return processor.ReadDocument(file, path)
+}
+
+// checkpoint returns the acknowledger recording the checkpoint of the file
+// once all of its documents have been ingested, or nil if the file was
+// already checkpointed with the same modification time
+func (f *fileCollector) checkpoint(ctx context.Context, path string, modTime time.Time) (*fileAck, error) {
+	logger := logging.FromContext(ctx)
+	ack := &fileAck{remaining: 1}
+	if f.checkpoints == nil {
+		return ack, nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	key := FileCollector + ":" + absPath
+	value := modTime.UTC().Format(time.RFC3339Nano)
+	current, ok, err := f.checkpoints.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get checkpoint of %s: %w", path, err)
+	}
+	if ok && current == value {
+		logger.Debugf("skipping %s: already ingested", path)
+		return nil, nil
+	}
+	ack.onIngested = func() {
+		if err := f.checkpoints.Set(key, value); err != nil {
+			logger.Warnf("unable to checkpoint %s: %v", path, err)
+		}
+	}
+	return ack, nil
+}
+
+// fileAck counts the documents of a file that remain to be ingested. It
+// starts at one for the file itself, which is done once all of its documents
+// have been emitted.
+type fileAck struct {
+	mu         sync.Mutex
+	remaining  int
+	failed     bool
+	onIngested func()
+}
+
+// add registers the document as one of the documents of the file. Documents
+// are only acknowledged when the file is checkpointed.
+func (a *fileAck) add(d *processor.Document) {
+	if a.onIngested == nil {
+		return
+	}
+	a.mu.Lock()
+	a.remaining++
+	a.mu.Unlock()
+	collector.SetAcknowledger(d, a.done)
+}
+
+// done records the result of ingesting one of the documents, calling
+// onIngested once all of them have been ingested without error
+func (a *fileAck) done(err error) {
+	a.mu.Lock()
+	a.remaining--
+	if err != nil {
+		a.failed = true
+	}
+	ingested := a.remaining == 0 && !a.failed
+	a.mu.Unlock()
+	if ingested && a.onIngested != nil {
+		a.onIngested()
+	}
+}
+
 // Type returns the collector type
 func (f *fileCollector) Type() string {
 	return FileCollector
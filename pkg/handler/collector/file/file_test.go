@@ -17,10 +17,15 @@ package file
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
 	"github.com/guacsec/guac/pkg/handler/processor"
 )
 
@@ -64,6 +69,34 @@ func Test_fileCollector_RetrieveArtifacts(t *testing.T) {
 			}},
 		},
 		wantErr: false,
+	}, {
+		name: "single file",
+		fields: fields{
+			path:        "./testdata/hello",
+			lastChecked: time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC),
+			poll:        false,
+			interval:    0,
+		},
+		want: []*processor.Document{{
+			Blob:   []byte("hello\n"),
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: string(FileCollector),
+				Source:    "file:///testdata/hello",
+			}},
+		},
+		wantErr: false,
+	}, {
+		name: "neither file nor directory",
+		fields: fields{
+			path:        os.DevNull,
+			lastChecked: time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC),
+			poll:        false,
+			interval:    0,
+		},
+		want:    []*processor.Document{},
+		wantErr: true,
 	}, {
 		name: "with canceled poll",
 		fields: fields{
@@ -141,3 +174,227 @@ func Test_fileCollector_RetrieveArtifacts(t *testing.T) {
 		})
 	}
 }
+
+func Test_fileCollector_PollNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	if err := os.WriteFile(first, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewFileCollector(ctx, dir, true, 10*time.Millisecond, DefaultArchiveDepth, time.Time{}, nil)
+	docChan := make(chan *processor.Document, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- f.RetrieveArtifacts(ctx, docChan)
+	}()
+
+	next := func() string {
+		select {
+		case d := <-docChan:
+			return string(d.Blob)
+		case err := <-errChan:
+			t.Fatalf("fileCollector.RetrieveArtifacts() returned while polling: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for document")
+		}
+		return ""
+	}
+
+	if got := next(); got != "first" {
+		t.Errorf("got document %q, want %q", got, "first")
+	}
+
+	// a new file is emitted on the next poll
+	if err := os.WriteFile(second, []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(); got != "second" {
+		t.Errorf("got document %q, want %q", got, "second")
+	}
+
+	// a modified file is emitted again
+	if err := os.WriteFile(first, []byte("first modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(first, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(); got != "first modified" {
+		t.Errorf("got document %q, want %q", got, "first modified")
+	}
+
+	// nothing else is emitted for files that did not change
+	select {
+	case d := <-docChan:
+		t.Errorf("unexpected document emitted: %s", d.Blob)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-errChan; err != nil {
+		t.Errorf("fileCollector.RetrieveArtifacts() error = %v", err)
+	}
+}
+
+func Test_fileCollector_Since(t *testing.T) {
+	dir := t.TempDir()
+	since := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	for name, modTime := range map[string]time.Time{
+		"old": since.Add(-time.Hour),
+		"new": since.Add(time.Hour),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	f := NewFileCollector(ctx, dir, false, time.Second, DefaultArchiveDepth, since, nil)
+	docChan := make(chan *processor.Document, 10)
+	if err := f.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("fileCollector.RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	got := []string{}
+	for d := range docChan {
+		got = append(got, string(d.Blob))
+	}
+	if want := []string{"new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fileCollector.RetrieveArtifacts() = %v, want %v", got, want)
+	}
+}
+
+func Test_fileCollector_MaxDocumentSize(t *testing.T) {
+	if err := processor.SetMaxDocumentSize(5); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = processor.SetMaxDocumentSize(processor.DefaultMaxDocumentSize) }()
+
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"small": "small",
+		"large": "larger than the limit",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	f := NewFileCollector(ctx, dir, false, time.Second, DefaultArchiveDepth, time.Time{}, nil)
+	docChan := make(chan *processor.Document, 10)
+	if err := f.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("fileCollector.RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	got := []string{}
+	for d := range docChan {
+		got = append(got, string(d.Blob))
+	}
+	if want := []string{"small"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fileCollector.RetrieveArtifacts() = %v, want %v", got, want)
+	}
+}
+
+func Test_fileCollector_Lockfiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"go.sum", "requirements-dev.txt", "sbom.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	f := NewFileCollector(ctx, dir, false, time.Second, DefaultArchiveDepth, time.Time{}, nil)
+	docChan := make(chan *processor.Document, 10)
+	if err := f.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("fileCollector.RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	got := map[string]processor.DocumentType{}
+	for d := range docChan {
+		got[string(d.Blob)] = d.Type
+	}
+	want := map[string]processor.DocumentType{
+		"go.sum":               processor.DocumentGoSum,
+		"requirements-dev.txt": processor.DocumentRequirements,
+		"sbom.json":            processor.DocumentUnknown,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fileCollector.RetrieveArtifacts() types = %v, want %v", got, want)
+	}
+}
+
+func Test_fileCollector_SymlinkToFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "sbom.json")
+	if err := os.WriteFile(target, []byte("sbom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "latest.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("unable to create symlink: %v", err)
+	}
+
+	ctx := context.Background()
+	f := NewFileCollector(ctx, link, false, time.Second, DefaultArchiveDepth, time.Time{}, nil)
+	docChan := make(chan *processor.Document, 10)
+	if err := f.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("fileCollector.RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	got := []string{}
+	for d := range docChan {
+		got = append(got, d.SourceInformation.Source+" "+string(d.Blob))
+	}
+	if want := []string{"file:///" + link + " sbom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fileCollector.RetrieveArtifacts() = %v, want %v", got, want)
+	}
+}
+
+func Test_fileCollector_Checkpoints(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ingested"), []byte("ingested"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "failed"), []byte("failed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := checkpoint.NewMemoryStore()
+
+	run := func() []string {
+		ctx := context.Background()
+		f := NewFileCollector(ctx, dir, false, time.Second, DefaultArchiveDepth, time.Time{}, checkpoints)
+		docChan := make(chan *processor.Document, 10)
+		if err := f.RetrieveArtifacts(ctx, docChan); err != nil {
+			t.Fatalf("fileCollector.RetrieveArtifacts() error = %v", err)
+		}
+		close(docChan)
+		var got []string
+		for d := range docChan {
+			got = append(got, string(d.Blob))
+			var err error
+			if string(d.Blob) == "failed" {
+				err = errors.New("ingestion failed")
+			}
+			collector.Acknowledge(d, err)
+		}
+		return got
+	}
+
+	if got, want := run(), []string{"failed", "ingested"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first run emitted %v, want %v", got, want)
+	}
+	// a restarted collector skips the ingested file, but not the failed one
+	if got, want := run(), []string{"failed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second run emitted %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ResolvePaths expands the glob patterns (as understood by `filepath.Glob`)
+// among paths and returns the resulting roots to collect from, sorted. Roots
+// contained in another root are dropped, so that every file is collected
+// only once. Patterns are only expanded once: when polling, files created
+// later are only collected if they are under one of the roots. Each path, or
+// match of a pattern, must be a regular file or a directory.
+func ResolvePaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no path given")
+	}
+
+	// resolved maps the absolute path of each match to its path as given
+	resolved := map[string]string{}
+	for _, p := range paths {
+		matches := []string{p}
+		if hasMeta(p) {
+			var err error
+			matches, err = filepath.Glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("pattern %q matched no files", p)
+			}
+		}
+		for _, m := range matches {
+			if err := checkPath(m); err != nil {
+				return nil, err
+			}
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := resolved[abs]; !ok {
+				resolved[abs] = filepath.Clean(m)
+			}
+		}
+	}
+
+	roots := []string{}
+	for abs, p := range resolved {
+		if !underRoot(abs, resolved) {
+			roots = append(roots, p)
+		}
+	}
+	sort.Strings(roots)
+	return roots, nil
+}
+
+// checkPath returns an error if path, following symbolic links, is not a
+// regular file or a directory
+func checkPath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("path %q does not exist", path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to access path %q: %w", path, err)
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return fmt.Errorf("path %q is neither a file nor a directory", path)
+	}
+	return nil
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+// underRoot returns whether one of the parent directories of the absolute
+// path is one of the roots
+func underRoot(path string, roots map[string]string) bool {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, ok := roots[dir]; ok {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,92 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolvePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a/sbom.json", "b/sbom.json", "b/other.json", "c/nested/sbom.json"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := func(elems ...string) string {
+		return filepath.Join(append([]string{dir}, elems...)...)
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{{
+		name:  "single directory",
+		paths: []string{join("a")},
+		want:  []string{join("a")},
+	}, {
+		name:  "glob",
+		paths: []string{join("*", "sbom.json")},
+		want:  []string{join("a", "sbom.json"), join("b", "sbom.json")},
+	}, {
+		name:  "overlapping paths",
+		paths: []string{join("b", "sbom.json"), join("*"), join("c", "nested")},
+		want:  []string{join("a"), join("b"), join("c")},
+	}, {
+		name:  "duplicate paths",
+		paths: []string{join("a"), join("a") + "/", join("b", "..", "a")},
+		want:  []string{join("a")},
+	}, {
+		name:    "pattern matching nothing",
+		paths:   []string{join("a"), join("*", "missing.json")},
+		wantErr: true,
+	}, {
+		name:  "single file",
+		paths: []string{join("c", "nested", "sbom.json")},
+		want:  []string{join("c", "nested", "sbom.json")},
+	}, {
+		name:    "neither file nor directory",
+		paths:   []string{os.DevNull},
+		wantErr: true,
+	}, {
+		name:    "missing path",
+		paths:   []string{join("missing")},
+		wantErr: true,
+	}, {
+		name:    "no path",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePaths(tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolvePaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -20,24 +20,31 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
+	"github.com/guacsec/guac/pkg/handler/collector"
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/logging"
 )
 
 type gcs struct {
-	bucket       string
-	reader       gcsReader
-	lastDownload time.Time
-	poll         bool
-	interval     time.Duration
+	bucket   string
+	prefix   string
+	reader   gcsReader
+	poll     bool
+	interval time.Duration
+	// since is the time before which the objects were last updated are
+	// skipped, the zero time collects all of them
+	since time.Time
+	// generations holds the generation of each object last emitted, so that
+	// only new or overwritten objects are emitted again when polling
+	generations map[string]int64
 }
 
 const (
@@ -77,14 +84,48 @@ func NewGCSClient(ctx context.Context, poll bool, interval time.Duration) (*gcs,
 	}
 	bucket := getBucketPath()
 	gstore := &gcs{
-		bucket:   getBucketPath(),
-		reader:   &reader{client: client, bucket: bucket},
-		poll:     poll,
-		interval: interval,
+		bucket:      getBucketPath(),
+		reader:      &reader{client: client, bucket: bucket},
+		poll:        poll,
+		interval:    interval,
+		generations: map[string]int64{},
 	}
 	return gstore, nil
 }
 
+// NewGCSCollector initializes a collector emitting the objects of the bucket
+// whose name starts with prefix (all of them if prefix is empty). If
+// pollRate is positive, the bucket is listed again every pollRate and only
+// the objects that were added or overwritten since are emitted; otherwise
+// the bucket is listed once.
+//
+// The client authenticates with the application default credentials, see
+// https://cloud.google.com/docs/authentication/application-default-credentials.
+func NewGCSCollector(ctx context.Context, bucket, prefix string, pollRate time.Duration) (*gcs, error) {
+	if bucket == "" {
+		return nil, errors.New("gcs bucket not specified")
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcs{
+		bucket:      bucket,
+		prefix:      prefix,
+		reader:      &reader{client: client, bucket: bucket, prefix: prefix},
+		poll:        pollRate > 0,
+		interval:    pollRate,
+		generations: map[string]int64{},
+	}, nil
+}
+
+// SetSince makes the collector skip the objects last updated before since,
+// without downloading them. The zero time, the default, collects all of
+// them. It must be called before RetrieveArtifacts.
+func (g *gcs) SetSince(since time.Time) {
+	g.since = since
+}
+
 // Type is the collector type of the collector
 func (g *gcs) Type() string {
 	return CollectorGCS
@@ -92,53 +133,63 @@ func (g *gcs) Type() string {
 
 type gcsReader interface {
 	getIterator(ctx context.Context) (*storage.ObjectIterator, error)
-	getReader(ctx context.Context, object string) (io.ReadCloser, error)
+	getReader(ctx context.Context, object string, generation int64) (io.ReadCloser, error)
 }
 
 type reader struct {
 	client *storage.Client
 	bucket string
+	prefix string
 }
 
 func (r *reader) getIterator(ctx context.Context) (*storage.ObjectIterator, error) {
 	q := &storage.Query{
+		Prefix:     r.prefix,
 		Projection: storage.ProjectionNoACL,
 	}
-	// set query to return only the Name and Updated attributes
-	err := q.SetAttrSelection([]string{"Name", "Updated"})
+	// set query to return only the attributes needed to detect changes
+	err := q.SetAttrSelection([]string{"Name", "Generation", "Updated"})
 	if err != nil {
 		return nil, err
 	}
 	return r.client.Bucket(r.bucket).Objects(ctx, q), nil
 }
 
-func (r *reader) getReader(ctx context.Context, object string) (io.ReadCloser, error) {
-	return r.client.Bucket(r.bucket).Object(object).NewReader(ctx)
+func (r *reader) getReader(ctx context.Context, object string, generation int64) (io.ReadCloser, error) {
+	o := r.client.Bucket(r.bucket).Object(object)
+	if generation != 0 {
+		// read the listed generation, even if the object was overwritten since
+		o = o.Generation(generation)
+	}
+	return o.NewReader(ctx)
 }
 
 // RetrieveArtifacts get the artifacts from the collector source based on polling or one time
 func (g *gcs) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
-
 	if g.reader == nil {
 		return errors.New("gcs not initialized")
 	}
-	if g.poll {
-		for {
-			time.Sleep(g.interval)
-			err := g.getArtifacts(ctx, docChannel)
-			if err != nil {
+	if g.generations == nil {
+		g.generations = map[string]int64{}
+	}
+	if err := g.getArtifacts(ctx, docChannel); err != nil {
+		return err
+	}
+	if !g.poll {
+		return nil
+	}
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := g.getArtifacts(ctx, docChannel); err != nil {
 				return err
 			}
-			g.lastDownload = time.Now()
-		}
-	} else {
-		err := g.getArtifacts(ctx, docChannel)
-		if err != nil {
-			return err
 		}
-		g.lastDownload = time.Now()
 	}
-	return nil
 }
 
 func (g *gcs) getArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
@@ -147,6 +198,8 @@ func (g *gcs) getArtifacts(ctx context.Context, docChannel chan<- *processor.Doc
 	if err != nil {
 		return fmt.Errorf("failed to get reader for object for bucket: %s, error: %w", g.bucket, err)
 	}
+	listed := map[string]bool{}
+	pending := []*storage.ObjectAttrs{}
 	for {
 		attrs, err := it.Next()
 		if err == iterator.Done {
@@ -155,45 +208,74 @@ func (g *gcs) getArtifacts(ctx context.Context, docChannel chan<- *processor.Doc
 		if err != nil {
 			return fmt.Errorf("failed to retrieve object attribute from bucket: %s, error: %w", g.bucket, err)
 		}
-		payload := []byte{}
-		if g.lastDownload.IsZero() {
-			payload, err = g.getObject(ctx, attrs.Name)
-			if err != nil {
-				logger.Warnf("failed to retrieve object: %s from bucket: %s", attrs.Name, g.bucket)
-				continue
-			}
-		} else if attrs.Updated.After(g.lastDownload) {
-			payload, err = g.getObject(ctx, attrs.Name)
-			if err != nil {
-				logger.Warnf("failed to retrieve object: %s from bucket: %s", attrs.Name, g.bucket)
-				continue
-			}
+		listed[attrs.Name] = true
+		if generation, ok := g.generations[attrs.Name]; ok && generation == attrs.Generation {
+			continue
 		}
-		if len(payload) > 0 {
-			doc := &processor.Document{
-				Blob:   payload,
-				Type:   processor.DocumentUnknown,
-				Format: processor.FormatUnknown,
-				SourceInformation: processor.SourceInformation{
-					Collector: string(CollectorGCS),
-					Source:    g.bucket + "/" + attrs.Name,
-				},
-			}
-			docChannel <- doc
+		if attrs.Updated.Before(g.since) {
+			logger.Debugf("skipping object %s of bucket %s: last updated at %v", attrs.Name, g.bucket, attrs.Updated)
+			continue
+		}
+		pending = append(pending, attrs)
+	}
+	// mu guards g.generations while the objects are downloaded
+	var mu sync.Mutex
+	err = collector.Fetch(ctx, len(pending), func(ctx context.Context, i int) {
+		attrs := pending[i]
+		err := processor.CheckDocumentSize(g.bucket+"/"+attrs.Name, attrs.Size)
+		var payload []byte
+		if err == nil {
+			payload, err = g.getObject(ctx, attrs.Name, attrs.Generation)
+		}
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			// skipped until the object is replaced
+			logger.Errorf("skipping object %s of bucket %s: %v", attrs.Name, g.bucket, err)
+			mu.Lock()
+			g.generations[attrs.Name] = attrs.Generation
+			mu.Unlock()
+			return
+		}
+		if err != nil {
+			logger.Warnf("failed to retrieve object: %s from bucket: %s", attrs.Name, g.bucket)
+			return
+		}
+		mu.Lock()
+		g.generations[attrs.Name] = attrs.Generation
+		mu.Unlock()
+		if len(payload) == 0 {
+			return
+		}
+		doc := &processor.Document{
+			Blob:   payload,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: string(CollectorGCS),
+				Source:    g.bucket + "/" + attrs.Name,
+			},
+		}
+		select {
+		case docChannel <- doc:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// forget the deleted objects
+	for name := range g.generations {
+		if !listed[name] {
+			delete(g.generations, name)
 		}
 	}
 	return nil
 }
 
-func (g *gcs) getObject(ctx context.Context, object string) ([]byte, error) {
-	reader, err := g.reader.getReader(ctx, object)
+func (g *gcs) getObject(ctx context.Context, object string, generation int64) ([]byte, error) {
+	reader, err := g.reader.getReader(ctx, object, generation)
 	if err != nil {
 		return nil, err
 	}
 	defer reader.Close()
-	payload, err := ioutil.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
-	return payload, nil
+	return processor.ReadDocument(reader, g.bucket+"/"+object)
 }
@@ -51,12 +51,18 @@ func TestGCS_RetrieveArtifacts(t *testing.T) {
 			Source:    getBucketPath() + "/some/object/file.txt",
 		},
 	}
+	attrs, err := client.Bucket("some-bucket").Object("some/object/file.txt").Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	type fields struct {
-		bucket       string
-		reader       gcsReader
-		lastDownload time.Time
-		poll         bool
+		bucket      string
+		prefix      string
+		reader      gcsReader
+		poll        bool
+		since       time.Time
+		generations map[string]int64
 	}
 	tests := []struct {
 		name     string
@@ -78,33 +84,75 @@ func TestGCS_RetrieveArtifacts(t *testing.T) {
 		wantErr:  false,
 		wantDone: true,
 	}, {
-		name: "last download time the same",
+		name: "object under prefix",
+		fields: fields{
+			bucket: getBucketPath(),
+			prefix: "some/",
+			reader: &reader{client: client, bucket: getBucketPath(), prefix: "some/"},
+		},
+		want:     doc,
+		wantErr:  false,
+		wantDone: true,
+	}, {
+		name: "object outside prefix",
 		fields: fields{
-			bucket:       getBucketPath(),
-			reader:       &reader{client: client, bucket: getBucketPath()},
-			lastDownload: time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC),
+			bucket: getBucketPath(),
+			prefix: "other/",
+			reader: &reader{client: client, bucket: getBucketPath(), prefix: "other/"},
 		},
 		want:     nil,
 		wantErr:  false,
 		wantDone: true,
 	}, {
-		name: "last download time set before",
+		name: "generation already emitted",
 		fields: fields{
-			bucket:       getBucketPath(),
-			reader:       &reader{client: client, bucket: getBucketPath()},
-			lastDownload: time.Date(2009, 10, 17, 20, 34, 58, 651387237, time.UTC),
+			bucket:      getBucketPath(),
+			reader:      &reader{client: client, bucket: getBucketPath()},
+			generations: map[string]int64{"some/object/file.txt": attrs.Generation},
+		},
+		want:     nil,
+		wantErr:  false,
+		wantDone: true,
+	}, {
+		name: "older generation emitted",
+		fields: fields{
+			bucket:      getBucketPath(),
+			reader:      &reader{client: client, bucket: getBucketPath()},
+			generations: map[string]int64{"some/object/file.txt": attrs.Generation - 1},
+		},
+		want:     doc,
+		wantErr:  false,
+		wantDone: true,
+	}, {
+		name: "updated after since",
+		fields: fields{
+			bucket: getBucketPath(),
+			reader: &reader{client: client, bucket: getBucketPath()},
+			since:  time.Date(2009, 11, 1, 0, 0, 0, 0, time.UTC),
 		},
 		want:     doc,
 		wantErr:  false,
 		wantDone: true,
+	}, {
+		name: "updated before since",
+		fields: fields{
+			bucket: getBucketPath(),
+			reader: &reader{client: client, bucket: getBucketPath()},
+			since:  time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		want:     nil,
+		wantErr:  false,
+		wantDone: true,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &gcs{
-				bucket:       tt.fields.bucket,
-				reader:       tt.fields.reader,
-				lastDownload: tt.fields.lastDownload,
-				poll:         tt.fields.poll,
+				bucket:      tt.fields.bucket,
+				prefix:      tt.fields.prefix,
+				reader:      tt.fields.reader,
+				poll:        tt.fields.poll,
+				since:       tt.fields.since,
+				generations: tt.fields.generations,
 			}
 			docChan := make(chan *processor.Document, 1)
 			errChan := make(chan error, 1)
@@ -139,3 +187,62 @@ func TestGCS_RetrieveArtifacts(t *testing.T) {
 		})
 	}
 }
+
+func TestGCS_Poll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := fakestorage.NewServer([]fakestorage.Object{{
+		ObjectAttrs: fakestorage.ObjectAttrs{BucketName: "sboms", Name: "app/sbom.json"},
+		Content:     []byte("v1"),
+	}, {
+		ObjectAttrs: fakestorage.ObjectAttrs{BucketName: "sboms", Name: "other/sbom.json"},
+		Content:     []byte("other"),
+	}})
+	defer server.Stop()
+
+	g := &gcs{
+		bucket:   "sboms",
+		prefix:   "app/",
+		reader:   &reader{client: server.Client(), bucket: "sboms", prefix: "app/"},
+		poll:     true,
+		interval: 10 * time.Millisecond,
+	}
+	docChan := make(chan *processor.Document, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- g.RetrieveArtifacts(ctx, docChan)
+	}()
+
+	next := func() string {
+		select {
+		case d := <-docChan:
+			if d.SourceInformation.Source != "sboms/app/sbom.json" {
+				t.Errorf("unexpected source %s", d.SourceInformation.Source)
+			}
+			return string(d.Blob)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a document")
+			return ""
+		}
+	}
+	if got := next(); got != "v1" {
+		t.Errorf("first document = %s, want v1", got)
+	}
+	// overwriting the object creates a new generation, emitted once
+	server.CreateObject(fakestorage.Object{
+		ObjectAttrs: fakestorage.ObjectAttrs{BucketName: "sboms", Name: "app/sbom.json"},
+		Content:     []byte("v2"),
+	})
+	if got := next(); got != "v2" {
+		t.Errorf("second document = %s, want v2", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(docChan) != 0 {
+		t.Errorf("unchanged objects were emitted again: %d documents", len(docChan))
+	}
+
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("g.RetrieveArtifacts() error = %v, want %v", err, context.Canceled)
+	}
+}
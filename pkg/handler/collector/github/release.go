@@ -0,0 +1,346 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+const (
+	CollectorGitHubRelease = "GitHubRelease"
+
+	// maxRetries is the number of times a request failing because of a
+	// rate limit or a server error is retried
+	maxRetries = 5
+	// baseRetryDelay is the delay before the first retry, when GitHub does
+	// not say how long to wait. It doubles with each retry.
+	baseRetryDelay = 2 * time.Second
+	// maxRetryDelay bounds the time waited before a retry
+	maxRetryDelay = time.Hour
+)
+
+// DefaultAssetPatterns match the names of the release assets that usually
+// hold SBOMs and attestations
+var DefaultAssetPatterns = []string{
+	"*.spdx",
+	"*.spdx.json",
+	"*.cdx.json",
+	"*.bom.json",
+	"*.intoto.jsonl",
+	"*.intoto.json",
+	"*.sbom.json",
+}
+
+type releaseCollector struct {
+	owner    string
+	repo     string
+	patterns []string
+	pollRate time.Duration
+	// since is the time before which the releases were published are
+	// skipped, the zero time collects all of them
+	since  time.Time
+	client *github.Client
+	// downloadClient fetches the assets from the storage GitHub redirects
+	// to, without the GitHub token
+	downloadClient *http.Client
+	// emitted holds the ids of the assets already emitted, so that polling
+	// only emits newly published assets
+	emitted map[int64]bool
+	// wait sleeps before retrying a request, it is replaced in tests
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// NewReleaseCollector initializes a collector emitting the assets of the
+// published releases of the GitHub repository owner/repo whose name matches
+// one of the patterns (DefaultAssetPatterns if none is given), using the
+// syntax of `path.Match`. If pollRate is positive, the releases are listed
+// again every pollRate and only the newly published assets are emitted.
+//
+// The GITHUB_TOKEN (or GH_TOKEN) environment variable is used to
+// authenticate, which is needed for private repositories and raises the rate
+// limit. Requests hitting a rate limit are retried once it is reset.
+func NewReleaseCollector(ctx context.Context, owner, repo string, pollRate time.Duration, patterns ...string) *releaseCollector {
+	if len(patterns) == 0 {
+		patterns = DefaultAssetPatterns
+	}
+	httpClient := &http.Client{Timeout: time.Minute}
+	if token := githubToken(); token != "" {
+		httpClient.Transport = &tokenTransport{token: token, base: http.DefaultTransport}
+	}
+	return &releaseCollector{
+		owner:          owner,
+		repo:           repo,
+		patterns:       patterns,
+		pollRate:       pollRate,
+		client:         github.NewClient(httpClient),
+		downloadClient: &http.Client{Timeout: 10 * time.Minute},
+		emitted:        map[int64]bool{},
+		wait:           sleep,
+	}
+}
+
+// SetSince makes the collector skip the assets of the releases published
+// before since, without downloading them. The zero time, the default,
+// collects all of them. It must be called before RetrieveArtifacts.
+func (r *releaseCollector) SetSince(since time.Time) {
+	r.since = since
+}
+
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// tokenTransport authenticates the requests to the GitHub API
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// RetrieveArtifacts collects the documents from the collector. It emits each collected
+// document through the channel to be collected and processed by the upstream processor.
+// The function should block until all the artifacts are collected and return a nil error
+// or return an error from the collector crashing. This function can keep running and check
+// for new artifacts as they are being uploaded by polling on an interval or run once and
+// grab all the artifacts and end.
+func (r *releaseCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	if err := r.getArtifacts(ctx, docChannel); err != nil {
+		return err
+	}
+	if r.pollRate <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(r.pollRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.getArtifacts(ctx, docChannel); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Type returns the collector type
+func (r *releaseCollector) Type() string {
+	return CollectorGitHubRelease
+}
+
+// getArtifacts emits the matching assets of the published releases that were
+// not emitted yet
+func (r *releaseCollector) getArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	releases, err := r.listReleases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list releases of %s/%s: %w", r.owner, r.repo, err)
+	}
+	type pendingAsset struct {
+		tag   string
+		asset *github.ReleaseAsset
+	}
+	pending := []pendingAsset{}
+	for _, release := range releases {
+		if release.GetDraft() {
+			continue
+		}
+		if release.GetPublishedAt().Before(r.since) {
+			logger.Debugf("skipping release %s of %s/%s: published at %v", release.GetTagName(), r.owner, r.repo, release.GetPublishedAt())
+			continue
+		}
+		for _, asset := range release.Assets {
+			if r.emitted[asset.GetID()] || !r.matches(asset.GetName()) {
+				continue
+			}
+			pending = append(pending, pendingAsset{tag: release.GetTagName(), asset: asset})
+		}
+	}
+	// mu guards r.emitted while the assets are downloaded
+	var mu sync.Mutex
+	return collector.Fetch(ctx, len(pending), func(ctx context.Context, i int) {
+		tag, asset := pending[i].tag, pending[i].asset
+		source := assetSource(r.owner, r.repo, tag, asset.GetName())
+		err := processor.CheckDocumentSize(source, int64(asset.GetSize()))
+		var payload []byte
+		if err == nil {
+			payload, err = r.downloadAsset(ctx, asset.GetID(), source)
+		}
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			logger.Errorf("skipping asset %s of release %s of %s/%s: %v",
+				asset.GetName(), tag, r.owner, r.repo, err)
+			mu.Lock()
+			r.emitted[asset.GetID()] = true
+			mu.Unlock()
+			return
+		}
+		if err != nil {
+			logger.Warnf("failed to download asset %s of release %s of %s/%s: %v",
+				asset.GetName(), tag, r.owner, r.repo, err)
+			return
+		}
+		mu.Lock()
+		r.emitted[asset.GetID()] = true
+		mu.Unlock()
+		doc := &processor.Document{
+			Blob:   payload,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: CollectorGitHubRelease,
+				Source:    source,
+			},
+		}
+		select {
+		case docChannel <- doc:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// assetSource identifies the asset by its download URL, which holds the tag
+// of the release and the name of the asset
+func assetSource(owner, repo, tag, name string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", owner, repo, tag, name)
+}
+
+func (r *releaseCollector) matches(name string) bool {
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *releaseCollector) listReleases(ctx context.Context) ([]*github.RepositoryRelease, error) {
+	releases := []*github.RepositoryRelease{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		var page []*github.RepositoryRelease
+		var resp *github.Response
+		err := r.withRetry(ctx, func() error {
+			var err error
+			page, resp, err = r.client.Repositories.ListReleases(ctx, r.owner, r.repo, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page...)
+		if resp.NextPage == 0 {
+			return releases, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (r *releaseCollector) downloadAsset(ctx context.Context, id int64, source string) ([]byte, error) {
+	var payload []byte
+	err := r.withRetry(ctx, func() error {
+		rc, _, err := r.client.Repositories.DownloadReleaseAsset(ctx, r.owner, r.repo, id, r.downloadClient)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		payload, err = processor.ReadDocument(rc, source)
+		return err
+	})
+	return payload, err
+}
+
+// withRetry calls fn until it succeeds, fails with an error that is not
+// transient or maxRetries retries have been made. After hitting a rate limit,
+// it waits until the limit is reset, or as long as GitHub asks to; otherwise
+// it backs off exponentially.
+func (r *releaseCollector) withRetry(ctx context.Context, fn func() error) error {
+	logger := logging.FromContext(ctx)
+	delay := baseRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		wait, ok := retryDelay(err, delay)
+		if !ok || attempt == maxRetries {
+			return err
+		}
+		if wait > maxRetryDelay {
+			wait = maxRetryDelay
+		}
+		logger.Infof("GitHub request failed, retrying in %v: %v", wait, err)
+		if err := r.wait(ctx, wait); err != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// retryDelay returns how long to wait before retrying the request that
+// failed with err, and whether it should be retried at all
+func retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		if wait := time.Until(rateErr.Rate.Reset.Time); wait > 0 {
+			return wait, true
+		}
+		return backoff, true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return backoff, true
+	}
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.StatusCode >= http.StatusInternalServerError {
+		return backoff, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
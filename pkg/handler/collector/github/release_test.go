@@ -0,0 +1,325 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+type fakeAsset struct {
+	id      int64
+	name    string
+	content string
+}
+
+type fakeRelease struct {
+	tag       string
+	draft     bool
+	published time.Time
+	assets    []fakeAsset
+}
+
+// fakeGitHub serves the releases API of the repository o/r, one release per
+// page. Asset downloads are redirected to /storage, like GitHub does.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	releases []fakeRelease
+	// rateLimited is the number of requests to fail with a rate limit
+	// error before serving them
+	rateLimited int
+	auth        []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/storage/") {
+		// the token must not be sent to the storage
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/storage/"), 10, 64)
+		for _, rel := range f.releases {
+			for _, a := range rel.assets {
+				if a.id == id {
+					fmt.Fprint(w, a.content)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		// already reset when the request is retried
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/repos/o/r/releases":
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			page, _ = strconv.Atoi(p)
+		}
+		if page < len(f.releases) {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/o/r/releases?page=%d>; rel="next"`, r.Host, page+1))
+		}
+		if page > len(f.releases) {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		rel := f.releases[page-1]
+		assets := []string{}
+		for _, a := range rel.assets {
+			assets = append(assets, fmt.Sprintf(`{"id": %d, "name": %q}`, a.id, a.name))
+		}
+		published := "null"
+		if !rel.published.IsZero() {
+			published = strconv.Quote(rel.published.Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, `[{"tag_name": %q, "draft": %t, "published_at": %s, "assets": [%s]}]`, rel.tag, rel.draft, published, strings.Join(assets, ","))
+	case strings.HasPrefix(r.URL.Path, "/repos/o/r/releases/assets/"):
+		http.Redirect(w, r, "/storage/"+strings.TrimPrefix(r.URL.Path, "/repos/o/r/releases/assets/"), http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitHub) addRelease(rel fakeRelease) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.releases = append(f.releases, rel)
+}
+
+func newTestCollector(t *testing.T, f *fakeGitHub, pollRate time.Duration, waits *[]time.Duration) *releaseCollector {
+	t.Setenv("GITHUB_TOKEN", "s3cr3t")
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	c := NewReleaseCollector(context.Background(), "o", "r", pollRate)
+	baseURL, _ := url.Parse(server.URL + "/")
+	c.client.BaseURL = baseURL
+	c.wait = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return c
+}
+
+func sources(docs []*processor.Document) []string {
+	s := []string{}
+	for _, d := range docs {
+		s = append(s, d.SourceInformation.Source+"="+string(d.Blob))
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestReleaseCollector_RetrieveArtifacts(t *testing.T) {
+	f := &fakeGitHub{
+		releases: []fakeRelease{{
+			tag: "v1.0.0",
+			assets: []fakeAsset{
+				{id: 1, name: "app.spdx.json", content: "sbom-1"},
+				{id: 2, name: "app.tar.gz", content: "binary"},
+				{id: 3, name: "multiple.intoto.jsonl", content: "provenance-1"},
+			},
+		}, {
+			tag:    "v2.0.0-rc",
+			draft:  true,
+			assets: []fakeAsset{{id: 4, name: "app.spdx.json", content: "draft"}},
+		}},
+		rateLimited: 1,
+	}
+	var waits []time.Duration
+	c := newTestCollector(t, f, 0, &waits)
+
+	docChan := make(chan *processor.Document, 10)
+	if err := c.RetrieveArtifacts(context.Background(), docChan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(docChan)
+	docs := []*processor.Document{}
+	for d := range docChan {
+		if d.SourceInformation.Collector != CollectorGitHubRelease || d.Type != processor.DocumentUnknown {
+			t.Errorf("unexpected document %+v", d)
+		}
+		docs = append(docs, d)
+	}
+
+	want := []string{
+		"https://github.com/o/r/releases/download/v1.0.0/app.spdx.json=sbom-1",
+		"https://github.com/o/r/releases/download/v1.0.0/multiple.intoto.jsonl=provenance-1",
+	}
+	if got := sources(docs); !reflect.DeepEqual(got, want) {
+		t.Errorf("got documents %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(waits, []time.Duration{baseRetryDelay}) {
+		t.Errorf("got waits %v, want one retry after %v", waits, baseRetryDelay)
+	}
+	for _, a := range f.auth {
+		if a != "Bearer s3cr3t" {
+			t.Errorf("got Authorization %q, want the token", a)
+		}
+	}
+}
+
+func TestReleaseCollector_Since(t *testing.T) {
+	since := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	f := &fakeGitHub{
+		releases: []fakeRelease{{
+			tag:       "v2.0.0",
+			published: since.Add(time.Hour),
+			assets:    []fakeAsset{{id: 1, name: "app.spdx.json", content: "sbom-2"}},
+		}, {
+			tag:       "v1.0.0",
+			published: since.Add(-time.Hour),
+			assets:    []fakeAsset{{id: 2, name: "app.spdx.json", content: "sbom-1"}},
+		}},
+	}
+	var waits []time.Duration
+	c := newTestCollector(t, f, 0, &waits)
+	c.SetSince(since)
+
+	docChan := make(chan *processor.Document, 10)
+	if err := c.RetrieveArtifacts(context.Background(), docChan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(docChan)
+	docs := []*processor.Document{}
+	for d := range docChan {
+		docs = append(docs, d)
+	}
+	want := []string{"https://github.com/o/r/releases/download/v2.0.0/app.spdx.json=sbom-2"}
+	if got := sources(docs); !reflect.DeepEqual(got, want) {
+		t.Errorf("got documents %v, want %v", got, want)
+	}
+}
+
+func TestReleaseCollector_Poll(t *testing.T) {
+	f := &fakeGitHub{
+		releases: []fakeRelease{{
+			tag:    "v1.0.0",
+			assets: []fakeAsset{{id: 1, name: "app.spdx.json", content: "sbom-1"}},
+		}},
+	}
+	var waits []time.Duration
+	c := newTestCollector(t, f, 10*time.Millisecond, &waits)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	docChan := make(chan *processor.Document, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- c.RetrieveArtifacts(ctx, docChan)
+	}()
+
+	first := <-docChan
+	f.addRelease(fakeRelease{
+		tag:    "v1.1.0",
+		assets: []fakeAsset{{id: 2, name: "app.cdx.json", content: "sbom-2"}},
+	})
+	second := <-docChan
+
+	want := []string{
+		"https://github.com/o/r/releases/download/v1.0.0/app.spdx.json=sbom-1",
+		"https://github.com/o/r/releases/download/v1.1.0/app.cdx.json=sbom-2",
+	}
+	if got := sources([]*processor.Document{first, second}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got documents %v, want %v", got, want)
+	}
+
+	// let a few more polls happen, no document is emitted again
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Errorf("RetrieveArtifacts() error = %v, want %v", err, context.Canceled)
+	}
+	if len(docChan) != 0 {
+		t.Errorf("got %d documents emitted again", len(docChan))
+	}
+}
+
+func Test_retryDelay(t *testing.T) {
+	retryAfter := 30 * time.Second
+	testCases := []struct {
+		name      string
+		err       error
+		want      time.Duration
+		wantRetry bool
+	}{{
+		name:      "rate limit",
+		err:       &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}},
+		want:      time.Hour,
+		wantRetry: true,
+	}, {
+		name:      "rate limit already reset",
+		err:       &github.RateLimitError{},
+		want:      time.Second,
+		wantRetry: true,
+	}, {
+		name:      "secondary rate limit",
+		err:       &github.AbuseRateLimitError{RetryAfter: &retryAfter},
+		want:      retryAfter,
+		wantRetry: true,
+	}, {
+		name:      "server error",
+		err:       &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}},
+		want:      time.Second,
+		wantRetry: true,
+	}, {
+		name: "not found",
+		err:  &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}},
+	}, {
+		name: "other error",
+		err:  fmt.Errorf("connection refused"),
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, retry := retryDelay(tt.err, time.Second)
+			// the wait until a rate limit reset is computed from the
+			// current time
+			if got > tt.want || got < tt.want-time.Minute || retry != tt.wantRetry {
+				t.Errorf("retryDelay() = %v, %v, want %v, %v", got, retry, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
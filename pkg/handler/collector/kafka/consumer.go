@@ -0,0 +1,199 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+const (
+	// sessionTimeout is the time after which the coordinator removes a
+	// member that stopped sending heartbeats from the group
+	sessionTimeout = 30 * time.Second
+	// rebalanceTimeout is the time the coordinator waits for all the members
+	// to rejoin the group during a rebalance
+	rebalanceTimeout = 60 * time.Second
+	// heartbeatInterval is the interval between heartbeats, well below the
+	// session timeout
+	heartbeatInterval = 3 * time.Second
+
+	// fetchMaxWait is the time the broker waits for new records before
+	// answering a fetch request
+	fetchMaxWait = time.Second
+	// fetchMaxBytes and fetchPartitionMaxBytes bound the size of a fetch
+	// response. Larger records are still returned, one at a time.
+	fetchMaxBytes          = 50 << 20
+	fetchPartitionMaxBytes = 1 << 20
+)
+
+// message is a record read from a partition of the topic
+type message struct {
+	partition int32
+	offset    int64
+	key       []byte
+	value     []byte
+	// generation is the generation of the group when the message was read.
+	// Its offset can only be committed within the same generation.
+	generation int32
+}
+
+// groupConsumer reads a topic as a member of a consumer group. The partitions
+// of the topic are shared among the members of the group, and the offsets
+// committed by a member are used by the next member assigned the partition.
+type groupConsumer struct {
+	client *kgo.Client
+	topic  string
+	group  string
+
+	// mu guards generation, which is incremented by the client when
+	// partitions are revoked from the member or lost
+	mu         sync.Mutex
+	generation int32
+}
+
+func newGroupConsumer(brokers []string, topic string, group string) (*groupConsumer, error) {
+	g := &groupConsumer{topic: topic, group: group}
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(topic),
+		// the offsets are committed once their documents are ingested
+		kgo.DisableAutoCommit(),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		// compatible with the default strategy of the Java consumer
+		kgo.Balancers(kgo.RangeBalancer()),
+		kgo.SessionTimeout(sessionTimeout),
+		kgo.RebalanceTimeout(rebalanceTimeout),
+		kgo.HeartbeatInterval(heartbeatInterval),
+		kgo.FetchMaxWait(fetchMaxWait),
+		kgo.FetchMaxBytes(fetchMaxBytes),
+		kgo.FetchMaxPartitionBytes(fetchPartitionMaxBytes),
+		// the messages of a poll are stamped with the generation before the
+		// partitions can be revoked
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsRevoked(g.rebalanced),
+		kgo.OnPartitionsLost(g.rebalanced),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kafka client: %w", err)
+	}
+	g.client = client
+	return g, nil
+}
+
+// rebalanced starts a new generation, as the partitions may have been
+// assigned to other members
+func (g *groupConsumer) rebalanced(context.Context, *kgo.Client, map[string][]int32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.generation++
+}
+
+// fetch returns the next records of the partitions assigned to the member,
+// joining the group first if needed. Fetch errors are only returned when no
+// record could be fetched; the client retries the failed partitions.
+func (g *groupConsumer) fetch(ctx context.Context) ([]message, error) {
+	fetches := g.client.PollFetches(ctx)
+	defer g.client.AllowRebalance()
+	if fetches.IsClientClosed() {
+		return nil, kgo.ErrClientClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	generation := g.generation
+	g.mu.Unlock()
+
+	msgs := []message{}
+	fetches.EachRecord(func(r *kgo.Record) {
+		msgs = append(msgs, message{
+			partition:  r.Partition,
+			offset:     r.Offset,
+			key:        r.Key,
+			value:      r.Value,
+			generation: generation,
+		})
+	})
+	var errs []error
+	fetches.EachError(func(topic string, partition int32, err error) {
+		errs = append(errs, fmt.Errorf("unable to fetch partition %d of topic %s: %w", partition, topic, err))
+	})
+	if len(errs) > 0 && len(msgs) == 0 {
+		return nil, errs[0]
+	}
+	for _, err := range errs {
+		logging.FromContext(ctx).Warnf("%v", err)
+	}
+	return msgs, nil
+}
+
+// commit commits the offsets of the next records to read from the
+// partitions, if the group is still in the given generation
+func (g *groupConsumer) commit(ctx context.Context, generation int32, offsets map[int32]int64) error {
+	g.mu.Lock()
+	current := g.generation
+	g.mu.Unlock()
+	if generation != current {
+		return fmt.Errorf("group %s was rebalanced, offsets of generation %d are not committed", g.group, generation)
+	}
+
+	partitions := map[int32]kgo.EpochOffset{}
+	for p, o := range offsets {
+		partitions[p] = kgo.EpochOffset{Epoch: -1, Offset: o}
+	}
+	var commitErr error
+	g.client.CommitOffsetsSync(ctx, map[string]map[int32]kgo.EpochOffset{g.topic: partitions},
+		func(_ *kgo.Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+			if err != nil {
+				commitErr = err
+				return
+			}
+			for _, t := range resp.Topics {
+				for _, p := range t.Partitions {
+					if err := kerr.ErrorForCode(p.ErrorCode); err != nil && commitErr == nil {
+						commitErr = fmt.Errorf("unable to commit partition %d: %w", p.Partition, err)
+					}
+				}
+			}
+		})
+	return commitErr
+}
+
+// close leaves the group, so that its partitions are reassigned right away,
+// and closes the connections
+func (g *groupConsumer) close(ctx context.Context) error {
+	closed := make(chan struct{})
+	go func() {
+		g.client.CloseAllowingRebalance()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,267 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+const (
+	KafkaCollector = "KafkaCollector"
+
+	// commitInterval is the interval between the commits of the offsets of
+	// the ingested documents
+	commitInterval = time.Second
+	// closeTimeout bounds the final commit and leaving the group
+	closeTimeout = 10 * time.Second
+)
+
+// consumer reads the records of a topic and commits the offsets of the
+// ingested ones
+type consumer interface {
+	fetch(ctx context.Context) ([]message, error)
+	commit(ctx context.Context, generation int32, offsets map[int32]int64) error
+	close(ctx context.Context) error
+}
+
+type kafkaCollector struct {
+	topic    string
+	consumer consumer
+	tracker  *offsetTracker
+}
+
+// NewKafkaCollector initializes the kafka collector, consuming the messages
+// of the topic as a member of the consumer group. Running several collectors
+// with the same group shares the partitions of the topic among them.
+//
+// The offset of a message is only committed once its document has been
+// emitted without error by collector.Collect, so that the documents that
+// failed are read again after a restart.
+func NewKafkaCollector(ctx context.Context, brokers []string, topic string, group string) (*kafkaCollector, error) {
+	consumer, err := newGroupConsumer(brokers, topic, group)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaCollector{
+		topic:    topic,
+		consumer: consumer,
+		tracker:  newOffsetTracker(),
+	}, nil
+}
+
+// RetrieveArtifacts collects the documents from the collector. It emits each collected
+// document through the channel to be collected and processed by the upstream processor.
+// The function should block until all the artifacts are collected and return a nil error
+// or return an error from the collector crashing. This function can keep running and check
+// for new artifacts as they are being uploaded by polling on an interval or run once and
+// grab all the artifacts and end.
+//
+// When the brokers cannot be reached, it keeps reconnecting following the
+// collector.ReconnectPolicy, and only returns an error once the retry window
+// is exceeded.
+func (k *kafkaCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	var wg sync.WaitGroup
+	commitCtx, stopCommits := context.WithCancel(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		k.commitLoop(commitCtx)
+	}()
+	defer func() {
+		stopCommits()
+		wg.Wait()
+		// Commit what was ingested so far and leave the group, even though
+		// ctx is done
+		closeCtx, cancel := context.WithTimeout(logging.WithLogger(context.Background()), closeTimeout)
+		defer cancel()
+		k.commit(closeCtx)
+		if err := k.consumer.close(closeCtx); err != nil {
+			logger.Warnf("unable to leave consumer group: %v", err)
+		}
+	}()
+
+	// The consumer reconnects to the brokers on the next fetch, after a
+	// delay growing with the consecutive failures
+	reconnector := collector.NewReconnector()
+	for {
+		msgs, err := k.consumer.fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Warnf("unable to fetch messages from topic %s (attempt %d): %v", k.topic, reconnector.Attempt()+1, err)
+			if err := reconnector.Wait(ctx, err); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("unable to fetch messages from topic %s: %w", k.topic, err)
+			}
+			continue
+		}
+		reconnector.Reset()
+
+		for _, m := range msgs {
+			doc := &processor.Document{
+				Blob:   m.value,
+				Type:   processor.DocumentUnknown,
+				Format: processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{
+					Collector: KafkaCollector,
+					Source:    fmt.Sprintf("%s/%d/%d", k.topic, m.partition, m.offset),
+				},
+			}
+			k.tracker.emitted(m)
+			m := m
+			collector.SetAcknowledger(doc, func(err error) {
+				if k.tracker.ack(m, err) {
+					logger.Warnf("document at offset %d of partition %d failed, the partition is not committed past it until the next rebalance", m.offset, m.partition)
+				}
+			})
+			select {
+			case docChannel <- doc:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// Type returns the collector type
+func (k *kafkaCollector) Type() string {
+	return KafkaCollector
+}
+
+func (k *kafkaCollector) commitLoop(ctx context.Context) {
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			k.commit(ctx)
+		}
+	}
+}
+
+func (k *kafkaCollector) commit(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	for generation, offsets := range k.tracker.take() {
+		if err := k.consumer.commit(ctx, generation, offsets); err != nil {
+			logger.Warnf("unable to commit offsets: %v", err)
+		}
+	}
+}
+
+// partitionOffsets tracks the messages of a partition emitted in a
+// generation of the group
+type partitionOffsets struct {
+	generation int32
+	// pending are the offsets of the emitted messages that cannot be
+	// committed yet, in order
+	pending []int64
+	// ingested are the pending offsets whose documents were ingested
+	ingested map[int64]bool
+	// failed is set once a document failed. The later offsets are not
+	// tracked anymore, as they must not be committed.
+	failed bool
+}
+
+// offsetTracker computes the offsets that can be committed: the offset
+// following a run of messages that were all ingested
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int32]*partitionOffsets
+	// commits are the offsets to commit, by generation then partition
+	commits map[int32]map[int32]int64
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: map[int32]*partitionOffsets{},
+		commits:    map[int32]map[int32]int64{},
+	}
+}
+
+// emitted records that the message is being emitted. Messages of a partition
+// must be emitted in order.
+func (t *offsetTracker) emitted(m message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.partitions[m.partition]
+	if !ok || p.generation != m.generation {
+		// The partition was (re)assigned, the messages of the previous
+		// generation will be read again
+		p = &partitionOffsets{generation: m.generation, ingested: map[int64]bool{}}
+		t.partitions[m.partition] = p
+	}
+	if p.failed {
+		return
+	}
+	p.pending = append(p.pending, m.offset)
+}
+
+// ack records the result of emitting the message. It returns true if the
+// message is the first of its partition to fail in the generation.
+func (t *offsetTracker) ack(m message, err error) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.partitions[m.partition]
+	if !ok || p.generation != m.generation || p.failed {
+		return false
+	}
+	if err != nil {
+		p.failed = true
+		p.pending = nil
+		p.ingested = nil
+		return true
+	}
+
+	p.ingested[m.offset] = true
+	committed := false
+	var next int64
+	for len(p.pending) > 0 && p.ingested[p.pending[0]] {
+		delete(p.ingested, p.pending[0])
+		next = p.pending[0] + 1
+		p.pending = p.pending[1:]
+		committed = true
+	}
+	if committed {
+		if t.commits[m.generation] == nil {
+			t.commits[m.generation] = map[int32]int64{}
+		}
+		t.commits[m.generation][m.partition] = next
+	}
+	return false
+}
+
+// take returns the offsets to commit and forgets them
+func (t *offsetTracker) take() map[int32]map[int32]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	commits := t.commits
+	t.commits = map[int32]map[int32]int64{}
+	return commits
+}
@@ -0,0 +1,257 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// fakeConsumer returns msgs on the first fetch, then blocks until the
+// context is done
+type fakeConsumer struct {
+	msgs []message
+
+	mu      sync.Mutex
+	fetched bool
+	commits map[int32]int64
+	closed  bool
+}
+
+func (f *fakeConsumer) fetch(ctx context.Context) ([]message, error) {
+	f.mu.Lock()
+	fetched := f.fetched
+	f.fetched = true
+	f.mu.Unlock()
+	if !fetched {
+		return f.msgs, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *fakeConsumer) commit(ctx context.Context, generation int32, offsets map[int32]int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for p, o := range offsets {
+		f.commits[p] = o
+	}
+	return nil
+}
+
+func (f *fakeConsumer) close(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestKafkaCollector_RetrieveArtifacts(t *testing.T) {
+	msgs := []message{
+		{partition: 0, offset: 0, value: []byte("p0-0")},
+		{partition: 1, offset: 5, value: []byte("p1-5")},
+		{partition: 0, offset: 1, value: []byte("p0-1")},
+		{partition: 0, offset: 2, value: []byte("p0-2")},
+		{partition: 1, offset: 6, value: []byte("p1-6")},
+	}
+	tests := []struct {
+		name        string
+		failed      map[string]bool
+		wantCommits map[int32]int64
+	}{{
+		name:        "all documents ingested",
+		wantCommits: map[int32]int64{0: 3, 1: 7},
+	}, {
+		name:        "failed document is not committed",
+		failed:      map[string]bool{"p0-1": true},
+		wantCommits: map[int32]int64{0: 1, 1: 7},
+	}, {
+		name:        "nothing committed before the first failure",
+		failed:      map[string]bool{"p1-5": true},
+		wantCommits: map[int32]int64{0: 3},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+			defer cancel()
+
+			fake := &fakeConsumer{msgs: msgs, commits: map[int32]int64{}}
+			k := &kafkaCollector{topic: "sboms", consumer: fake, tracker: newOffsetTracker()}
+
+			docChan := make(chan *processor.Document, len(msgs))
+			done := make(chan error)
+			go func() {
+				done <- k.RetrieveArtifacts(ctx, docChan)
+			}()
+
+			sources := []string{}
+			for range msgs {
+				d := <-docChan
+				sources = append(sources, d.SourceInformation.Source)
+				var err error
+				if tt.failed[string(d.Blob)] {
+					err = errors.New("ingestion failed")
+				}
+				collector.Acknowledge(d, err)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatalf("RetrieveArtifacts() error = %v", err)
+			}
+
+			wantSources := []string{"sboms/0/0", "sboms/1/5", "sboms/0/1", "sboms/0/2", "sboms/1/6"}
+			if !reflect.DeepEqual(sources, wantSources) {
+				t.Errorf("sources = %v, want %v", sources, wantSources)
+			}
+			if !reflect.DeepEqual(fake.commits, tt.wantCommits) {
+				t.Errorf("committed %v, want %v", fake.commits, tt.wantCommits)
+			}
+			if !fake.closed {
+				t.Errorf("consumer was not closed")
+			}
+		})
+	}
+}
+
+// flakyConsumer fails to fetch until failures is exhausted (forever if it is
+// negative), then behaves like fakeConsumer
+type flakyConsumer struct {
+	*fakeConsumer
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyConsumer) fetch(ctx context.Context) ([]message, error) {
+	f.mu.Lock()
+	failing := f.failures != 0
+	if f.failures > 0 {
+		f.failures--
+	}
+	f.mu.Unlock()
+	if failing {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	return f.fakeConsumer.fetch(ctx)
+}
+
+func TestKafkaCollector_Reconnect(t *testing.T) {
+	if err := collector.SetReconnectPolicy(collector.ReconnectPolicy{
+		BaseDelay:      time.Millisecond,
+		MaxDelay:       time.Millisecond,
+		MaxRetryWindow: 50 * time.Millisecond,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = collector.SetReconnectPolicy(collector.DefaultReconnectPolicy) }()
+	msgs := []message{{partition: 0, offset: 0, value: []byte("p0-0")}}
+
+	t.Run("reconnects after transient failures", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+		defer cancel()
+		fake := &flakyConsumer{fakeConsumer: &fakeConsumer{msgs: msgs, commits: map[int32]int64{}}, failures: 3}
+		k := &kafkaCollector{topic: "sboms", consumer: fake, tracker: newOffsetTracker()}
+
+		docChan := make(chan *processor.Document, len(msgs))
+		done := make(chan error)
+		go func() {
+			done <- k.RetrieveArtifacts(ctx, docChan)
+		}()
+		select {
+		case d := <-docChan:
+			collector.Acknowledge(d, nil)
+		case err := <-done:
+			t.Fatalf("RetrieveArtifacts() returned before reconnecting: %v", err)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("RetrieveArtifacts() error = %v", err)
+		}
+	})
+
+	t.Run("gives up after the retry window", func(t *testing.T) {
+		ctx := logging.WithLogger(context.Background())
+		fake := &flakyConsumer{fakeConsumer: &fakeConsumer{commits: map[int32]int64{}}, failures: -1}
+		k := &kafkaCollector{topic: "sboms", consumer: fake, tracker: newOffsetTracker()}
+
+		err := k.RetrieveArtifacts(ctx, make(chan *processor.Document))
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("RetrieveArtifacts() error = %v, want to give up with the fetch error", err)
+		}
+		if !fake.closed {
+			t.Errorf("consumer was not closed")
+		}
+	})
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tr := newOffsetTracker()
+	m := func(gen int32, offset int64) message {
+		return message{partition: 0, offset: offset, generation: gen}
+	}
+
+	for _, o := range []int64{0, 1, 2} {
+		tr.emitted(m(1, o))
+	}
+	tr.ack(m(1, 1), nil)
+	if got := tr.take(); len(got) != 0 {
+		t.Errorf("out of order ack committed %v", got)
+	}
+	tr.ack(m(1, 0), nil)
+	if got, want := tr.take(), map[int32]map[int32]int64{1: {0: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("take() = %v, want %v", got, want)
+	}
+
+	// After a rebalance, the acks of the previous generation are ignored
+	tr.emitted(m(2, 2))
+	tr.ack(m(1, 2), nil)
+	if got := tr.take(); len(got) != 0 {
+		t.Errorf("ack of previous generation committed %v", got)
+	}
+	tr.ack(m(2, 2), nil)
+	if got, want := tr.take(), map[int32]map[int32]int64{2: {0: 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("take() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupConsumer_Unreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(logging.WithLogger(context.Background()), 100*time.Millisecond)
+	defer cancel()
+	g, err := newGroupConsumer([]string{"127.0.0.1:1"}, "sboms", "guac")
+	if err != nil {
+		t.Fatalf("newGroupConsumer() error = %v", err)
+	}
+	if _, err := g.fetch(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetch() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := g.commit(ctx, g.generation+1, map[int32]int64{0: 1}); err == nil {
+		t.Errorf("commit() of another generation succeeded")
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := g.close(closeCtx); err != nil {
+		t.Errorf("close() error = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ErrUnauthorized is wrapped by the errors of the registries rejecting the
+// credentials, or requiring some when none were found
+var ErrUnauthorized = errors.New("unauthorized")
+
+// registryCredentials is a keychain of credentials by registry host
+type registryCredentials map[string]authn.AuthConfig
+
+func (c registryCredentials) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if cfg, ok := c[target.RegistryStr()]; ok {
+		return authn.FromConfig(cfg), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// NewKeychain returns the keychain the credentials of a registry are looked
+// up in: first the docker config file (`~/.docker/config.json`, or the
+// directory of $DOCKER_CONFIG) and the credential helpers it configures, as
+// cosign and crane do, then creds, by registry host (e.g. "ghcr.io"). The
+// registries found in neither are pulled from anonymously.
+func NewKeychain(creds map[string]authn.AuthConfig) (authn.Keychain, error) {
+	byRegistry := registryCredentials{}
+	for host, cfg := range creds {
+		// normalizes the aliases, e.g. docker.io to index.docker.io
+		reg, err := name.NewRegistry(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q: %w", host, err)
+		}
+		byRegistry[reg.RegistryStr()] = cfg
+	}
+	return authn.NewMultiKeychain(authn.DefaultKeychain, byRegistry), nil
+}
+
+// registryError returns err, wrapping ErrUnauthorized with the registry when
+// the registry rejected the request for lack of valid credentials. Some
+// registries answer that a manifest is unknown instead, which cannot be told
+// apart from a missing manifest.
+func registryError(registry string, err error) error {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return err
+	}
+	unauthorized := terr.StatusCode == http.StatusUnauthorized || terr.StatusCode == http.StatusForbidden
+	for _, d := range terr.Errors {
+		if d.Code == transport.UnauthorizedErrorCode || d.Code == transport.DeniedErrorCode {
+			unauthorized = true
+		}
+	}
+	if !unauthorized {
+		return err
+	}
+	return fmt.Errorf("%w for registry %s: %v", ErrUnauthorized, registry, err)
+}
@@ -0,0 +1,345 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+const (
+	OCICollector = "OCICollector"
+)
+
+// cosignTagSuffixes are the tag suffixes used by cosign to attach signatures,
+// attestations and SBOMs to an image as `sha256-<digest>.<suffix>`
+var cosignTagSuffixes = []string{"sig", "att", "sbom"}
+
+// documentMediaTypes are the media types of layers that contain documents
+// that can be ingested by GUAC
+var documentMediaTypes = []string{
+	"application/vnd.dev.cosign",
+	"application/vnd.dsse.envelope.v1+json",
+	"application/vnd.in-toto+json",
+	"application/spdx+json",
+	"text/spdx+json",
+	"application/vnd.cyclonedx+json",
+}
+
+type ociCollector struct {
+	repoRef  string
+	poll     bool
+	interval time.Duration
+	// keychain resolves the credentials of the registry
+	keychain authn.Keychain
+	// checkedDigests holds the digests of the artifact manifests that have
+	// already been emitted so polling only emits new artifacts. It is
+	// guarded by mu, as the images are checked concurrently.
+	mu             sync.Mutex
+	checkedDigests map[string]bool
+}
+
+// NewOCICollector initializes the oci collector for the given repository or
+// image reference. If pollRate is greater than zero, the collector keeps
+// checking the registry for new artifacts on that interval. The credentials
+// of the registry are looked up in authn.DefaultKeychain, unless SetKeychain
+// is called.
+func NewOCICollector(ctx context.Context, repoRef string, pollRate time.Duration) *ociCollector {
+	return &ociCollector{
+		repoRef:        repoRef,
+		poll:           pollRate > 0,
+		interval:       pollRate,
+		keychain:       authn.DefaultKeychain,
+		checkedDigests: map[string]bool{},
+	}
+}
+
+// SetKeychain makes the collector look the credentials of the registry up
+// in keychain, see NewKeychain. It must be called before RetrieveArtifacts.
+func (o *ociCollector) SetKeychain(keychain authn.Keychain) {
+	o.keychain = keychain
+}
+
+// RetrieveArtifacts collects the documents from the collector. It emits each collected
+// document through the channel to be collected and processed by the upstream processor.
+// The function should block until all the artifacts are collected and return a nil error
+// or return an error from the collector crashing. This function can keep running and check
+// for new artifacts as they are being uploaded by polling on an interval or run once and
+// grab all the artifacts and end.
+func (o *ociCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	if o.poll {
+		for {
+			if err := o.getArtifacts(ctx, docChannel); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(o.interval):
+			}
+		}
+	}
+	return o.getArtifacts(ctx, docChannel)
+}
+
+// Type returns the collector type
+func (o *ociCollector) Type() string {
+	return OCICollector
+}
+
+func (o *ociCollector) getArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	refs, err := o.getImageReferences(ctx)
+	if err != nil {
+		return err
+	}
+	// the images are fetched concurrently, their artifacts one at a time
+	err = collector.Fetch(ctx, len(refs), func(ctx context.Context, i int) {
+		ref := refs[i]
+		desc, err := remote.Head(ref, o.remoteOptions(ctx)...)
+		if err != nil {
+			logger.Warnf("failed to retrieve image descriptor for %s: %v", ref, registryError(ref.Context().RegistryStr(), err))
+			return
+		}
+		subject := ref.Context().Digest(desc.Digest.String())
+		artifacts, err := o.getAttachedArtifacts(ctx, subject)
+		if err != nil {
+			logger.Warnf("failed to retrieve artifacts attached to %s: %v", subject, err)
+			return
+		}
+		for _, artifact := range artifacts {
+			if err := o.emitArtifact(ctx, subject, artifact, docChannel); err != nil {
+				logger.Warnf("failed to retrieve artifact %s for %s: %v", artifact, subject, err)
+			}
+		}
+	})
+	if err != nil && o.poll {
+		// polling stops without error once the context is canceled
+		return nil
+	}
+	return err
+}
+
+// getImageReferences returns the image references to check for attached
+// artifacts. If the collector was given a repository rather than an image
+// reference, all the tags of the repository are returned, skipping over the
+// tags that cosign uses to store artifacts.
+func (o *ociCollector) getImageReferences(ctx context.Context) ([]name.Reference, error) {
+	repo, err := name.NewRepository(o.repoRef)
+	if err != nil {
+		ref, err := name.ParseReference(o.repoRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reference %s: %w", o.repoRef, err)
+		}
+		return []name.Reference{ref}, nil
+	}
+
+	tags, err := remote.List(repo, o.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for repository %s: %w", o.repoRef, registryError(repo.RegistryStr(), err))
+	}
+	refs := []name.Reference{}
+	for _, tag := range tags {
+		if isCosignTag(tag) {
+			continue
+		}
+		refs = append(refs, repo.Tag(tag))
+	}
+	return refs, nil
+}
+
+func isCosignTag(tag string) bool {
+	if !strings.HasPrefix(tag, "sha256-") {
+		return false
+	}
+	for _, suffix := range cosignTagSuffixes {
+		if strings.HasSuffix(tag, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAttachedArtifacts returns the references to the artifacts attached to the
+// subject image. It first uses the OCI referrers API and falls back on the
+// cosign tag convention if the registry does not support it.
+func (o *ociCollector) getAttachedArtifacts(ctx context.Context, subject name.Digest) ([]name.Reference, error) {
+	refs, err := o.getReferrers(ctx, subject)
+	if err == nil {
+		return refs, nil
+	}
+	if !errors.Is(err, errReferrersUnsupported) {
+		return nil, err
+	}
+
+	refs = []name.Reference{}
+	for _, suffix := range cosignTagSuffixes {
+		tag := subject.Context().Tag(strings.Replace(subject.DigestStr(), ":", "-", 1) + "." + suffix)
+		if _, err := remote.Head(tag, o.remoteOptions(ctx)...); err != nil {
+			var terr *transport.Error
+			if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, registryError(subject.RegistryStr(), err)
+		}
+		refs = append(refs, tag)
+	}
+	return refs, nil
+}
+
+var errReferrersUnsupported = errors.New("referrers API not supported by registry")
+
+// getReferrers queries `/v2/<name>/referrers/<digest>` as defined by the OCI
+// distribution spec to list the artifacts that have the subject image.
+func (o *ociCollector) getReferrers(ctx context.Context, subject name.Digest) ([]name.Reference, error) {
+	repo := subject.Context()
+	auth, err := o.keychain.Resolve(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the credentials of registry %s: %w", repo.RegistryStr(), err)
+	}
+	tr, err := transport.NewWithContext(ctx, repo.Registry, auth, http.DefaultTransport, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, registryError(repo.RegistryStr(), err)
+	}
+
+	url := fmt.Sprintf("%s://%s/v2/%s/referrers/%s", repo.Registry.Scheme(), repo.RegistryStr(), repo.RepositoryStr(), subject.DigestStr())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.oci.image.index.v1+json")
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, errReferrersUnsupported
+	}
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, registryError(repo.RegistryStr(), err)
+	}
+
+	index := v1.IndexManifest{}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode referrers response: %w", err)
+	}
+	refs := []name.Reference{}
+	for _, m := range index.Manifests {
+		refs = append(refs, repo.Digest(m.Digest.String()))
+	}
+	return refs, nil
+}
+
+// emitArtifact fetches the artifact manifest and emits each of the layers
+// that contain a document
+func (o *ociCollector) emitArtifact(ctx context.Context, subject name.Digest, artifact name.Reference, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	img, err := remote.Image(artifact, o.remoteOptions(ctx)...)
+	if err != nil {
+		return registryError(artifact.Context().RegistryStr(), err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	if o.isChecked(digest.String()) {
+		return nil
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return err
+		}
+		if !isDocumentMediaType(string(mediaType)) {
+			continue
+		}
+		source := fmt.Sprintf("%s/%s@%s", subject.RegistryStr(), subject.RepositoryStr(), subject.DigestStr())
+		size, err := layer.Size()
+		if err != nil {
+			return err
+		}
+		if err := processor.CheckDocumentSize(source, size); err != nil {
+			logger.Errorf("skipping layer of %s: %v", artifact, err)
+			continue
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		blob, err := processor.ReadDocument(rc, source)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+
+		doc := &processor.Document{
+			Blob:   blob,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: string(OCICollector),
+				Source:    source,
+			},
+		}
+		docChannel <- doc
+	}
+	o.mu.Lock()
+	o.checkedDigests[digest.String()] = true
+	o.mu.Unlock()
+	return nil
+}
+
+// remoteOptions returns the options of the requests to the registry
+func (o *ociCollector) remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(o.keychain)}
+}
+
+func (o *ociCollector) isChecked(digest string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.checkedDigests[digest]
+}
+
+func isDocumentMediaType(mediaType string) bool {
+	for _, mt := range documentMediaTypes {
+		if strings.HasPrefix(mediaType, mt) {
+			return true
+		}
+	}
+	return false
+}
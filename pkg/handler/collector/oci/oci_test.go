@@ -0,0 +1,333 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+var (
+	attestationBlob = []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30=","signatures":[]}`)
+	sbomBlob        = []byte(`{"spdxVersion":"SPDX-2.2"}`)
+)
+
+type testRegistry struct {
+	host     string
+	subject  name.Digest
+	artifact v1.Hash
+}
+
+// setupRegistry pushes an image tagged `v1` and a cosign style attestation for
+// that image. If withReferrers is set the registry also answers the OCI
+// referrers API with the attestation manifest. Unless creds is nil, the
+// registry requires them.
+func setupRegistry(t *testing.T, withReferrers bool, creds *authn.Basic) (*testRegistry, func()) {
+	reg := &testRegistry{}
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if creds != nil {
+			if user, pass, ok := r.BasicAuth(); !ok || user != creds.Username || pass != creds.Password {
+				w.Header().Set("WWW-Authenticate", `Basic realm="guac"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`))
+				return
+			}
+		}
+		if strings.Contains(r.URL.Path, "/referrers/") {
+			if !withReferrers {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+			_ = json.NewEncoder(w).Encode(v1.IndexManifest{
+				SchemaVersion: 2,
+				MediaType:     types.OCIImageIndex,
+				Manifests:     []v1.Descriptor{{Digest: reg.artifact, MediaType: types.OCIManifestSchema1}},
+			})
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.host = u.Host
+	opts := []remote.Option{}
+	if creds != nil {
+		opts = append(opts, remote.WithAuth(creds))
+	}
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := name.NewTag(reg.host + "/guac/image:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(tag, img, opts...); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.subject = tag.Context().Digest(digest.String())
+
+	att, err := mutate.AppendLayers(empty.Image,
+		static.NewLayer(attestationBlob, types.MediaType("application/vnd.dsse.envelope.v1+json")),
+		static.NewLayer(sbomBlob, types.MediaType("text/spdx+json")),
+		static.NewLayer([]byte("not a document"), types.MediaType("application/octet-stream")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attTag := tag.Context().Tag(strings.Replace(digest.String(), ":", "-", 1) + ".att")
+	if err := remote.Write(attTag, att, opts...); err != nil {
+		t.Fatal(err)
+	}
+	reg.artifact, err = att.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reg, server.Close
+}
+
+func collect(t *testing.T, o *ociCollector) []*processor.Document {
+	docChan := make(chan *processor.Document, 10)
+	if err := o.RetrieveArtifacts(context.Background(), docChan); err != nil {
+		t.Fatalf("ociCollector.RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	docs := []*processor.Document{}
+	for d := range docChan {
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+func Test_ociCollector_RetrieveArtifacts(t *testing.T) {
+	tests := []struct {
+		name          string
+		withReferrers bool
+		ref           func(reg *testRegistry) string
+	}{{
+		name: "repository with cosign tags",
+		ref: func(reg *testRegistry) string {
+			return reg.host + "/guac/image"
+		},
+	}, {
+		name: "image tag with cosign tags",
+		ref: func(reg *testRegistry) string {
+			return reg.host + "/guac/image:v1"
+		},
+	}, {
+		name: "image digest with cosign tags",
+		ref: func(reg *testRegistry) string {
+			return reg.subject.String()
+		},
+	}, {
+		name:          "repository with referrers API",
+		withReferrers: true,
+		ref: func(reg *testRegistry) string {
+			return reg.host + "/guac/image"
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, stop := setupRegistry(t, tt.withReferrers, nil)
+			defer stop()
+
+			source := reg.host + "/guac/image@" + reg.subject.DigestStr()
+			want := []*processor.Document{{
+				Blob:   attestationBlob,
+				Type:   processor.DocumentUnknown,
+				Format: processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{
+					Collector: OCICollector,
+					Source:    source,
+				},
+			}, {
+				Blob:   sbomBlob,
+				Type:   processor.DocumentUnknown,
+				Format: processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{
+					Collector: OCICollector,
+					Source:    source,
+				},
+			}}
+
+			o := NewOCICollector(context.Background(), tt.ref(reg), 0)
+			if got := collect(t, o); !reflect.DeepEqual(got, want) {
+				t.Errorf("ociCollector.RetrieveArtifacts() = %v, want %v", got, want)
+			}
+			// artifacts already emitted are not emitted again
+			if got := collect(t, o); len(got) != 0 {
+				t.Errorf("ociCollector.RetrieveArtifacts() emitted %d documents again, want 0", len(got))
+			}
+			if o.Type() != OCICollector {
+				t.Errorf("ociCollector.Type() = %s, want %s", o.Type(), OCICollector)
+			}
+		})
+	}
+}
+
+func Test_ociCollector_Poll(t *testing.T) {
+	reg, stop := setupRegistry(t, false, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	o := NewOCICollector(ctx, reg.host+"/guac/image", 10*time.Millisecond)
+	docChan := make(chan *processor.Document, 10)
+	if err := o.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("ociCollector.RetrieveArtifacts() error = %v", err)
+	}
+	if len(docChan) != 2 {
+		t.Errorf("ociCollector.RetrieveArtifacts() emitted %d documents, want 2", len(docChan))
+	}
+}
+
+func Test_ociCollector_Auth(t *testing.T) {
+	creds := &authn.Basic{Username: "guac", Password: "s3cr3t"}
+	tests := []struct {
+		name string
+		// dockerConfig is the docker config file, given the registry host
+		dockerConfig func(host string) string
+		// flagCreds are the credentials given to NewKeychain
+		flagCreds        func(host string) map[string]authn.AuthConfig
+		wantUnauthorized bool
+	}{{
+		name: "credentials of the docker config",
+		dockerConfig: func(host string) string {
+			auth := base64.StdEncoding.EncodeToString([]byte("guac:s3cr3t"))
+			return fmt.Sprintf(`{"auths":{%q:{"auth":%q}}}`, host, auth)
+		},
+	}, {
+		name: "credentials given to the keychain",
+		flagCreds: func(host string) map[string]authn.AuthConfig {
+			return map[string]authn.AuthConfig{host: {Username: "guac", Password: "s3cr3t"}}
+		},
+	}, {
+		name: "docker config before the credentials given to the keychain",
+		dockerConfig: func(host string) string {
+			auth := base64.StdEncoding.EncodeToString([]byte("guac:s3cr3t"))
+			return fmt.Sprintf(`{"auths":{%q:{"auth":%q}}}`, host, auth)
+		},
+		flagCreds: func(host string) map[string]authn.AuthConfig {
+			return map[string]authn.AuthConfig{host: {Username: "guac", Password: "wrong"}}
+		},
+	}, {
+		name: "wrong credentials",
+		flagCreds: func(host string) map[string]authn.AuthConfig {
+			return map[string]authn.AuthConfig{host: {Username: "guac", Password: "wrong"}}
+		},
+		wantUnauthorized: true,
+	}, {
+		name:             "anonymous",
+		wantUnauthorized: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, stop := setupRegistry(t, false, creds)
+			defer stop()
+
+			// only the docker config of the test is found
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("DOCKER_CONFIG", "")
+			t.Setenv("XDG_RUNTIME_DIR", home)
+			if tt.dockerConfig != nil {
+				dir := filepath.Join(home, ".docker")
+				if err := os.MkdirAll(dir, 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(tt.dockerConfig(reg.host)), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("DOCKER_CONFIG", dir)
+			}
+			var flagCreds map[string]authn.AuthConfig
+			if tt.flagCreds != nil {
+				flagCreds = tt.flagCreds(reg.host)
+			}
+			keychain, err := NewKeychain(flagCreds)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o := NewOCICollector(context.Background(), reg.host+"/guac/image", 0)
+			o.SetKeychain(keychain)
+			docChan := make(chan *processor.Document, 10)
+			err = o.RetrieveArtifacts(context.Background(), docChan)
+			if tt.wantUnauthorized {
+				if !errors.Is(err, ErrUnauthorized) || !strings.Contains(err.Error(), "unauthorized for registry "+reg.host) {
+					t.Errorf("ociCollector.RetrieveArtifacts() error = %v, want unauthorized for registry %s", err, reg.host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ociCollector.RetrieveArtifacts() error = %v", err)
+			}
+			if len(docChan) != 2 {
+				t.Errorf("ociCollector.RetrieveArtifacts() emitted %d documents, want 2", len(docChan))
+			}
+		})
+	}
+}
+
+func Test_isCosignTag(t *testing.T) {
+	tests := map[string]bool{
+		"v1":                  false,
+		"latest":              false,
+		"sha256-abcdef.sig":   true,
+		"sha256-abcdef.att":   true,
+		"sha256-abcdef.sbom":  true,
+		"sha256-abcdef.other": false,
+	}
+	for tag, want := range tests {
+		if got := isCosignTag(tag); got != want {
+			t.Errorf("isCosignTag(%s) = %v, want %v", tag, got, want)
+		}
+	}
+}
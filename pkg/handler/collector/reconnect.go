@@ -0,0 +1,145 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ReconnectPolicy controls how streaming collectors, which consume a broker
+// or a queue continuously (e.g. Kafka or the S3 event notifications),
+// reconnect after failing to receive. The delay before attempt i (counting
+// from 0) is drawn uniformly from [d/2, d], with
+// d = min(MaxDelay, BaseDelay * 2^i), so that collectors losing the same
+// broker do not reconnect in lockstep.
+type ReconnectPolicy struct {
+	// BaseDelay is the upper bound of the delay before the first attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the upper bound of the delay between attempts
+	MaxDelay time.Duration
+	// MaxRetryWindow is how long a collector keeps reconnecting after its
+	// first failed attempt before giving up and returning an error. Zero
+	// reconnects forever.
+	MaxRetryWindow time.Duration
+}
+
+// DefaultReconnectPolicy reconnects forever, waiting up to 1s, 2s, 4s... and
+// at most 1 minute between attempts
+var DefaultReconnectPolicy = ReconnectPolicy{
+	BaseDelay: time.Second,
+	MaxDelay:  time.Minute,
+}
+
+var (
+	reconnectMu     sync.Mutex
+	reconnectPolicy = DefaultReconnectPolicy
+)
+
+// SetReconnectPolicy sets how the streaming collectors reconnect. It applies
+// to the collectors started afterwards.
+func SetReconnectPolicy(p ReconnectPolicy) error {
+	if p.BaseDelay <= 0 {
+		return fmt.Errorf("the reconnect delay must be positive, got %v", p.BaseDelay)
+	}
+	if p.MaxDelay < p.BaseDelay {
+		return fmt.Errorf("the maximum reconnect delay %v must not be less than the reconnect delay %v", p.MaxDelay, p.BaseDelay)
+	}
+	if p.MaxRetryWindow < 0 {
+		return fmt.Errorf("the reconnect window must not be negative, got %v", p.MaxRetryWindow)
+	}
+	reconnectMu.Lock()
+	defer reconnectMu.Unlock()
+	reconnectPolicy = p
+	return nil
+}
+
+// GetReconnectPolicy returns how the streaming collectors reconnect
+func GetReconnectPolicy() ReconnectPolicy {
+	reconnectMu.Lock()
+	defer reconnectMu.Unlock()
+	return reconnectPolicy
+}
+
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	upper := p.BaseDelay
+	for i := 0; i < attempt && upper < p.MaxDelay; i++ {
+		upper *= 2
+	}
+	if upper > p.MaxDelay {
+		upper = p.MaxDelay
+	}
+	if upper <= 0 {
+		return 0
+	}
+	return upper/2 + time.Duration(rand.Int63n(int64(upper-upper/2)+1))
+}
+
+// Reconnector tracks the consecutive failures of a streaming collector to
+// receive, following the reconnect policy. It is not safe for concurrent
+// use.
+type Reconnector struct {
+	policy       ReconnectPolicy
+	attempt      int
+	failingSince time.Time
+	now          func() time.Time
+}
+
+// NewReconnector returns a Reconnector following the current reconnect
+// policy, see SetReconnectPolicy
+func NewReconnector() *Reconnector {
+	return &Reconnector{policy: GetReconnectPolicy(), now: time.Now}
+}
+
+// Wait waits before reconnecting after the failure err, longer after each
+// consecutive failure. It returns an error wrapping err, without waiting,
+// once the collector has been failing for longer than the retry window, and
+// the error of ctx once ctx is done. While it waits, the collector must not
+// return, so that it is not reported as done.
+func (r *Reconnector) Wait(ctx context.Context, err error) error {
+	now := r.now()
+	if r.attempt == 0 {
+		r.failingSince = now
+	}
+	if w := r.policy.MaxRetryWindow; w > 0 && now.Sub(r.failingSince) >= w {
+		return fmt.Errorf("giving up reconnecting after failing for %v: %w", now.Sub(r.failingSince).Round(time.Second), err)
+	}
+	d := r.policy.delay(r.attempt)
+	r.attempt++
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reset records that the collector received successfully, so that the next
+// failure waits the base delay again
+func (r *Reconnector) Reset() {
+	r.attempt = 0
+}
+
+// Attempt returns the number of consecutive failures so far
+func (r *Reconnector) Attempt() int {
+	return r.attempt
+}
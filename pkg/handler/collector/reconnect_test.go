@@ -0,0 +1,91 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicy_delay(t *testing.T) {
+	p := ReconnectPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempt, upper := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.delay(attempt); d < upper/2 || d > upper {
+				t.Fatalf("delay(%d) = %v, want between %v and %v", attempt, d, upper/2, upper)
+			}
+		}
+	}
+}
+
+func TestSetReconnectPolicy(t *testing.T) {
+	defer func() { _ = SetReconnectPolicy(DefaultReconnectPolicy) }()
+	for _, p := range []ReconnectPolicy{
+		{BaseDelay: 0, MaxDelay: time.Second},
+		{BaseDelay: time.Second, MaxDelay: time.Millisecond},
+		{BaseDelay: time.Second, MaxDelay: time.Second, MaxRetryWindow: -time.Second},
+	} {
+		if err := SetReconnectPolicy(p); err == nil {
+			t.Errorf("SetReconnectPolicy(%+v) expected an error", p)
+		}
+	}
+	p := ReconnectPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Second, MaxRetryWindow: time.Minute}
+	if err := SetReconnectPolicy(p); err != nil {
+		t.Fatalf("SetReconnectPolicy() unexpected error: %v", err)
+	}
+	if got := GetReconnectPolicy(); got != p {
+		t.Errorf("GetReconnectPolicy() = %+v, want %+v", got, p)
+	}
+}
+
+func TestReconnector_Wait(t *testing.T) {
+	errDisconnected := errors.New("connection reset by peer")
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	r := &Reconnector{
+		policy: ReconnectPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetryWindow: time.Minute},
+		now:    func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := r.Wait(ctx, errDisconnected); err != nil {
+			t.Fatalf("Wait() unexpected error within the retry window: %v", err)
+		}
+		now = now.Add(20 * time.Second)
+	}
+	if r.Attempt() != 3 {
+		t.Errorf("Attempt() = %d, want 3", r.Attempt())
+	}
+	err := r.Wait(ctx, errDisconnected)
+	if !errors.Is(err, errDisconnected) {
+		t.Fatalf("Wait() error = %v, want to give up with %v", err, errDisconnected)
+	}
+
+	// a successful receive starts a new window
+	r.Reset()
+	if err := r.Wait(ctx, errDisconnected); err != nil {
+		t.Errorf("Wait() unexpected error after Reset(): %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	r.policy.BaseDelay, r.policy.MaxDelay = time.Hour, time.Hour
+	if err := r.Wait(canceled, errDisconnected); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+}
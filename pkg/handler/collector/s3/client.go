@@ -0,0 +1,176 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+const (
+	// receiveWaitSeconds is the long polling duration of the SQS receives
+	receiveWaitSeconds = 20
+	// maxReceivedMessages is the maximum number of messages of a receive
+	maxReceivedMessages = 10
+)
+
+// isNotFound returns whether the error reports a missing object
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &noSuchKey) || (errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound)
+}
+
+// object is an object listed in a bucket
+type object struct {
+	Key          string
+	ETag         string
+	Size         int64
+	LastModified time.Time
+}
+
+// s3API lists and downloads the objects of a bucket
+type s3API struct {
+	client *s3.Client
+	bucket string
+}
+
+// newS3API returns the API of the bucket, served by endpoint if set rather
+// than by AWS
+func newS3API(cfg aws.Config, endpoint string, bucket string) *s3API {
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint == "" {
+			// bucket names with dots break the TLS certificate of the
+			// virtual-hosted style
+			o.UsePathStyle = strings.Contains(bucket, ".")
+			return
+		}
+		o.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+		// most S3 compatible servers need the bucket in the path
+		o.UsePathStyle = true
+	})
+	return &s3API{client: client, bucket: bucket}
+}
+
+// listObjects returns all the objects whose key starts with prefix
+func (s *s3API) listObjects(ctx context.Context, prefix string) ([]object, error) {
+	objects := []object{}
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range page.Contents {
+			objects = append(objects, object{
+				Key:          aws.ToString(o.Key),
+				ETag:         aws.ToString(o.ETag),
+				Size:         o.Size,
+				LastModified: aws.ToTime(o.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// getObject returns the content of the object
+func (s *s3API) getObject(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return processor.ReadDocument(out.Body, s.bucket+"/"+key)
+}
+
+// sqsMessage is a message received from an SQS queue
+type sqsMessage struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string
+}
+
+// sqsAPI receives and deletes the messages of a queue
+type sqsAPI struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+func newSQSAPI(cfg aws.Config, queueURL string) (*sqsAPI, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid SQS queue URL %s", queueURL)
+	}
+	client := sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		// queue URLs are like https://sqs.<region>.amazonaws.com/<account>/<queue>
+		if parts := strings.Split(u.Hostname(), "."); len(parts) == 4 && parts[0] == "sqs" && parts[2] == "amazonaws" {
+			o.Region = parts[1]
+			return
+		}
+		// queues of SQS compatible servers are served by the host of
+		// their URL
+		o.EndpointResolver = sqs.EndpointResolverFromURL(u.Scheme + "://" + u.Host)
+	})
+	return &sqsAPI{client: client, queueURL: queueURL}, nil
+}
+
+// receive long polls the queue for messages
+func (q *sqsAPI) receive(ctx context.Context) ([]sqsMessage, error) {
+	out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.queueURL),
+		MaxNumberOfMessages: maxReceivedMessages,
+		WaitTimeSeconds:     receiveWaitSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]sqsMessage, len(out.Messages))
+	for i, m := range out.Messages {
+		messages[i] = sqsMessage{
+			MessageID:     aws.ToString(m.MessageId),
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+			Body:          aws.ToString(m.Body),
+		}
+	}
+	return messages, nil
+}
+
+// delete removes the message from the queue, once it has been handled
+func (q *sqsAPI) delete(ctx context.Context, receiptHandle string) error {
+	_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	return err
+}
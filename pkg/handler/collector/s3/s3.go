@@ -0,0 +1,411 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+const (
+	CollectorS3 = "S3"
+
+	// requestTimeout bounds each request to S3 and SQS, including the long
+	// polling of the queue
+	requestTimeout = time.Minute
+)
+
+// objectStore lists and downloads the objects of a bucket
+type objectStore interface {
+	listObjects(ctx context.Context, prefix string) ([]object, error)
+	getObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// eventQueue receives the S3 event notifications of a bucket
+type eventQueue interface {
+	receive(ctx context.Context) ([]sqsMessage, error)
+	delete(ctx context.Context, receiptHandle string) error
+}
+
+type s3Collector struct {
+	bucket   string
+	prefix   string
+	store    objectStore
+	queue    eventQueue
+	pollRate time.Duration
+	// since is the time before which the objects were last modified are
+	// skipped, the zero time collects all of them
+	since time.Time
+	// etags holds the ETag of each object last emitted, so that only new or
+	// overwritten objects are emitted again when polling
+	etags map[string]string
+}
+
+// NewS3Collector initializes a collector emitting the objects of the bucket
+// whose key starts with prefix (all of them if prefix is empty).
+//
+// If queueURL is set, the bucket is listed once, then the objects are
+// emitted as soon as they are uploaded, as reported by the S3
+// `ObjectCreated` event notifications sent to the SQS queue (directly or
+// through SNS). Messages are deleted from the queue once their objects have
+// been ingested. Otherwise, if pollRate is positive, the bucket is listed
+// again every pollRate and only the objects that were added or overwritten
+// since are emitted; if not, the bucket is listed once.
+//
+// The credentials and the region are loaded by the default configuration of
+// the AWS SDK: environment variables, shared config and credentials files,
+// web identity token, container and instance metadata.
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL point to S3 compatible servers.
+func NewS3Collector(ctx context.Context, bucket, prefix string, pollRate time.Duration, queueURL string) (*s3Collector, error) {
+	if bucket == "" {
+		return nil, errors.New("s3 bucket not specified")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(requestTimeout)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("AWS region not specified, set AWS_REGION")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	c := &s3Collector{
+		bucket:   bucket,
+		prefix:   prefix,
+		store:    newS3API(cfg, endpoint, bucket),
+		pollRate: pollRate,
+		etags:    map[string]string{},
+	}
+	if queueURL != "" {
+		queue, err := newSQSAPI(cfg, queueURL)
+		if err != nil {
+			return nil, err
+		}
+		c.queue = queue
+	}
+	return c, nil
+}
+
+// SetSince makes the collector skip the objects last modified before since
+// when listing the bucket, without downloading them. The zero time, the
+// default, collects all of them. It must be called before RetrieveArtifacts.
+func (s *s3Collector) SetSince(since time.Time) {
+	s.since = since
+}
+
+// RetrieveArtifacts collects the documents from the collector. It emits each collected
+// document through the channel to be collected and processed by the upstream processor.
+// The function should block until all the artifacts are collected and return a nil error
+// or return an error from the collector crashing. This function can keep running and check
+// for new artifacts as they are being uploaded by polling on an interval or run once and
+// grab all the artifacts and end.
+func (s *s3Collector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	if s.etags == nil {
+		s.etags = map[string]string{}
+	}
+	if err := s.getArtifacts(ctx, docChannel); err != nil {
+		return err
+	}
+	if s.queue != nil {
+		return s.consumeEvents(ctx, docChannel)
+	}
+	if s.pollRate <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(s.pollRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := s.getArtifacts(ctx, docChannel); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// getArtifacts emits the objects listed under the prefix that were not
+// emitted yet, or were overwritten since, and were last modified after
+// s.since
+func (s *s3Collector) getArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	objects, err := s.store.listObjects(ctx, s.prefix)
+	if err != nil {
+		return err
+	}
+	listed := map[string]bool{}
+	pending := []object{}
+	for _, o := range objects {
+		listed[o.Key] = true
+		// skip the "folders" created by the console
+		if strings.HasSuffix(o.Key, "/") || s.etags[o.Key] == o.ETag {
+			continue
+		}
+		if o.LastModified.Before(s.since) {
+			logger.Debugf("skipping object %s of bucket %s: last modified at %v", o.Key, s.bucket, o.LastModified)
+			continue
+		}
+		pending = append(pending, o)
+	}
+	// mu guards s.etags while the objects are downloaded
+	var mu sync.Mutex
+	err = collector.Fetch(ctx, len(pending), func(ctx context.Context, i int) {
+		o := pending[i]
+		err := processor.CheckDocumentSize(s.bucket+"/"+o.Key, o.Size)
+		var payload []byte
+		if err == nil {
+			payload, err = s.store.getObject(ctx, o.Key)
+		}
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			// skipped until the object is replaced
+			logger.Errorf("skipping object %s of bucket %s: %v", o.Key, s.bucket, err)
+			mu.Lock()
+			s.etags[o.Key] = o.ETag
+			mu.Unlock()
+			return
+		}
+		if err != nil {
+			logger.Warnf("failed to retrieve object: %s from bucket: %s: %v", o.Key, s.bucket, err)
+			return
+		}
+		mu.Lock()
+		s.etags[o.Key] = o.ETag
+		mu.Unlock()
+		if len(payload) == 0 {
+			return
+		}
+		select {
+		case docChannel <- s.document(o.Key, payload):
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// forget the deleted objects
+	for key := range s.etags {
+		if !listed[key] {
+			delete(s.etags, key)
+		}
+	}
+	return nil
+}
+
+func (s *s3Collector) document(key string, payload []byte) *processor.Document {
+	return &processor.Document{
+		Blob:   payload,
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+		SourceInformation: processor.SourceInformation{
+			Collector: CollectorS3,
+			Source:    s.bucket + "/" + key,
+		},
+	}
+}
+
+// consumeEvents emits the objects reported by the event notifications of the
+// queue until the context is canceled. Failures to receive are retried
+// following the collector.ReconnectPolicy.
+func (s *s3Collector) consumeEvents(ctx context.Context, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	reconnector := collector.NewReconnector()
+	for {
+		messages, err := s.queue.receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Warnf("failed to receive S3 events (attempt %d): %v", reconnector.Attempt()+1, err)
+			if err := reconnector.Wait(ctx, err); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("failed to receive S3 events: %w", err)
+			}
+			continue
+		}
+		reconnector.Reset()
+		for _, m := range messages {
+			if err := s.handleMessage(ctx, m, docChannel); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleMessage emits the objects created under the prefix reported by the
+// message. The message is deleted once all of them have been emitted without
+// error, so that it is received again otherwise.
+func (s *s3Collector) handleMessage(ctx context.Context, m sqsMessage, docChannel chan<- *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	keys, err := s.createdKeys(m.Body)
+	if err != nil {
+		logger.Warnf("ignoring invalid S3 event notification %s: %v", m.MessageID, err)
+	}
+	payloads := make([][]byte, len(keys))
+	var failed int32
+	err = collector.Fetch(ctx, len(keys), func(ctx context.Context, i int) {
+		payload, err := s.store.getObject(ctx, keys[i])
+		if isNotFound(err) {
+			logger.Debugf("object %s of bucket %s was deleted before being collected", keys[i], s.bucket)
+			return
+		}
+		if errors.Is(err, processor.ErrDocumentTooLarge) {
+			logger.Errorf("skipping object %s of bucket %s: %v", keys[i], s.bucket, err)
+			return
+		}
+		if err != nil {
+			logger.Warnf("failed to retrieve object: %s from bucket: %s: %v", keys[i], s.bucket, err)
+			atomic.StoreInt32(&failed, 1)
+			return
+		}
+		payloads[i] = payload
+	})
+	if err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&failed) != 0 {
+		// the message becomes visible again after its visibility timeout
+		return nil
+	}
+	docs := []*processor.Document{}
+	for i, payload := range payloads {
+		if len(payload) > 0 {
+			docs = append(docs, s.document(keys[i], payload))
+		}
+	}
+
+	deleteMessage := func() {
+		if err := s.queue.delete(ctx, m.ReceiptHandle); err != nil {
+			logger.Warnf("failed to delete S3 event notification %s: %v", m.MessageID, err)
+		}
+	}
+	if len(docs) == 0 {
+		deleteMessage()
+		return nil
+	}
+	ack := &messageAck{remaining: len(docs)}
+	for _, d := range docs {
+		collector.SetAcknowledger(d, func(err error) {
+			if ack.done(err) {
+				deleteMessage()
+			}
+		})
+	}
+	for _, d := range docs {
+		select {
+		case docChannel <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// messageAck counts the documents of a message that remain to be ingested
+type messageAck struct {
+	mu        sync.Mutex
+	remaining int
+	failed    bool
+}
+
+// done records the result of ingesting one of the documents, and returns
+// whether all of them have been ingested without error
+func (a *messageAck) done(err error) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.remaining--
+	if err != nil {
+		a.failed = true
+	}
+	return a.remaining == 0 && !a.failed
+}
+
+// s3Event is an S3 event notification, see
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// snsNotification is the envelope of the messages sent to the queue through
+// an SNS topic
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// createdKeys returns the keys of the objects created under the prefix of the
+// bucket, as reported by the body of the message
+func (s *s3Collector) createdKeys(body string) ([]string, error) {
+	var sns snsNotification
+	if err := json.Unmarshal([]byte(body), &sns); err == nil && sns.Type == "Notification" {
+		body = sns.Message
+	}
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, r := range event.Records {
+		if !strings.HasPrefix(r.EventName, "ObjectCreated:") || r.S3.Bucket.Name != s.bucket {
+			continue
+		}
+		// keys are URL encoded, with spaces as `+`
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(key, s.prefix) && !strings.HasSuffix(key, "/") {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Type returns the collector type
+func (s *s3Collector) Type() string {
+	return CollectorS3
+}
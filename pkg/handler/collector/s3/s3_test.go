@@ -0,0 +1,340 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// fakeAWS serves the S3 bucket `sboms` and the SQS queue `/queue`
+type fakeAWS struct {
+	mu      sync.Mutex
+	objects map[string]string
+	// unlisted objects can be downloaded but are not listed yet
+	unlisted map[string]string
+	messages []sqsMessage
+	deleted  []string
+	// modified holds the last modification time of the objects that have one
+	modified map[string]time.Time
+	// downloaded holds the keys of the objects downloaded
+	downloaded []string
+}
+
+const pageSize = 2
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost:
+		// SQS query API, served by the host of the queue URL
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Form.Get("Action") {
+		case "ReceiveMessage":
+			fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult>")
+			for _, m := range f.messages {
+				fmt.Fprintf(w, "<Message><MessageId>%s</MessageId><ReceiptHandle>%s</ReceiptHandle><Body>", m.MessageID, m.ReceiptHandle)
+				_ = xml.EscapeText(w, []byte(m.Body))
+				fmt.Fprint(w, "</Body></Message>")
+			}
+			fmt.Fprint(w, "</ReceiveMessageResult></ReceiveMessageResponse>")
+			f.messages = nil
+		case "DeleteMessage":
+			f.deleted = append(f.deleted, r.Form.Get("ReceiptHandle"))
+			fmt.Fprint(w, "<DeleteMessageResponse></DeleteMessageResponse>")
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>InvalidAction</Code></Error></ErrorResponse>")
+		}
+	case r.URL.Path == "/sboms":
+		prefix := r.URL.Query().Get("prefix")
+		start, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+		keys := []string{}
+		for k := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprint(w, "<ListBucketResult>")
+		end := start + pageSize
+		if end < len(keys) {
+			fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+		} else {
+			end = len(keys)
+		}
+		for _, k := range keys[start:end] {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><ETag>&quot;%x&quot;</ETag>", k, f.objects[k])
+			if modified, ok := f.modified[k]; ok {
+				fmt.Fprintf(w, "<LastModified>%s</LastModified>", modified.Format("2006-01-02T15:04:05.000Z"))
+			}
+			fmt.Fprint(w, "</Contents>")
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case strings.HasPrefix(r.URL.Path, "/sboms/"):
+		key := strings.TrimPrefix(r.URL.Path, "/sboms/")
+		content, ok := f.objects[key]
+		if !ok {
+			content, ok = f.unlisted[key]
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		f.downloaded = append(f.downloaded, key)
+		fmt.Fprint(w, content)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestCollector(t *testing.T, f *fakeAWS, prefix string, withQueue bool) *s3Collector {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+		HTTPClient:  server.Client(),
+		Retryer:     func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	store := newS3API(cfg, server.URL, "sboms")
+	c := &s3Collector{bucket: "sboms", prefix: prefix, store: store}
+	if withQueue {
+		queue, err := newSQSAPI(cfg, server.URL+"/queue")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.queue = queue
+	}
+	return c
+}
+
+func sources(docs []*processor.Document) []string {
+	s := []string{}
+	for _, d := range docs {
+		s = append(s, d.SourceInformation.Source+"="+string(d.Blob))
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestS3Collector_RetrieveArtifacts(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	f := &fakeAWS{objects: map[string]string{
+		"app/a.json":         "a",
+		"app/b c.json":       "b",
+		"app/dir/":           "",
+		"app/dir/d.json":     "d",
+		"other/ignored.json": "x",
+	}}
+	c := newTestCollector(t, f, "app/", false)
+
+	docChan := make(chan *processor.Document, 10)
+	if err := c.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	docs := []*processor.Document{}
+	for d := range docChan {
+		docs = append(docs, d)
+	}
+	want := []string{"sboms/app/a.json=a", "sboms/app/b c.json=b", "sboms/app/dir/d.json=d"}
+	if got := sources(docs); !reflect.DeepEqual(got, want) {
+		t.Errorf("RetrieveArtifacts() = %v, want %v", got, want)
+	}
+	if docs[0].SourceInformation.Collector != CollectorS3 || docs[0].Type != processor.DocumentUnknown {
+		t.Errorf("unexpected document %+v", docs[0])
+	}
+}
+
+func TestS3Collector_Since(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	since := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	f := &fakeAWS{
+		objects: map[string]string{"old.json": "o", "new.json": "n", "same.json": "s"},
+		modified: map[string]time.Time{
+			"old.json":  since.Add(-time.Hour),
+			"new.json":  since.Add(time.Hour),
+			"same.json": since,
+		},
+	}
+	c := newTestCollector(t, f, "", false)
+	c.SetSince(since)
+
+	docChan := make(chan *processor.Document, 10)
+	if err := c.RetrieveArtifacts(ctx, docChan); err != nil {
+		t.Fatalf("RetrieveArtifacts() error = %v", err)
+	}
+	close(docChan)
+	docs := []*processor.Document{}
+	for d := range docChan {
+		docs = append(docs, d)
+	}
+	if got, want := sources(docs), []string{"sboms/new.json=n", "sboms/same.json=s"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RetrieveArtifacts() = %v, want %v", got, want)
+	}
+	for _, key := range f.downloaded {
+		if key == "old.json" {
+			t.Errorf("object modified before since was downloaded")
+		}
+	}
+}
+
+func TestS3Collector_Poll(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	f := &fakeAWS{objects: map[string]string{"a.json": "a1", "b.json": "b1"}}
+	c := newTestCollector(t, f, "", false)
+	c.etags = map[string]string{}
+
+	collect := func() []string {
+		docChan := make(chan *processor.Document, 10)
+		if err := c.getArtifacts(ctx, docChan); err != nil {
+			t.Fatalf("getArtifacts() error = %v", err)
+		}
+		close(docChan)
+		docs := []*processor.Document{}
+		for d := range docChan {
+			docs = append(docs, d)
+		}
+		return sources(docs)
+	}
+
+	if got, want := collect(), []string{"sboms/a.json=a1", "sboms/b.json=b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first listing = %v, want %v", got, want)
+	}
+	if got := collect(); len(got) != 0 {
+		t.Errorf("unchanged objects emitted again: %v", got)
+	}
+	f.mu.Lock()
+	f.objects["a.json"] = "a2"
+	f.objects["c.json"] = "c1"
+	delete(f.objects, "b.json")
+	f.mu.Unlock()
+	if got, want := collect(), []string{"sboms/a.json=a2", "sboms/c.json=c1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listing after changes = %v, want %v", got, want)
+	}
+	if _, ok := c.etags["b.json"]; ok {
+		t.Errorf("deleted object still tracked")
+	}
+}
+
+func TestS3Collector_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+	defer cancel()
+	event := func(name, bucket, key string) string {
+		return fmt.Sprintf(`{"Records":[{"eventName":%q,"s3":{"bucket":{"name":%q},"object":{"key":%q}}}]}`, name, bucket, key)
+	}
+	f := &fakeAWS{
+		objects: map[string]string{"app/old.json": "old"},
+		messages: []sqsMessage{
+			{MessageID: "1", ReceiptHandle: "created", Body: event("ObjectCreated:Put", "sboms", "app/new+file.json")},
+			{MessageID: "2", ReceiptHandle: "test-event", Body: `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"sboms"}`},
+			{MessageID: "3", ReceiptHandle: "other-prefix", Body: event("ObjectCreated:Put", "sboms", "other/x.json")},
+			{MessageID: "4", ReceiptHandle: "removed", Body: event("ObjectRemoved:Delete", "sboms", "app/old.json")},
+			{MessageID: "5", ReceiptHandle: "sns", Body: fmt.Sprintf(`{"Type":"Notification","Message":%q}`,
+				event("ObjectCreated:CompleteMultipartUpload", "sboms", "app/sns.json"))},
+			{MessageID: "6", ReceiptHandle: "failed", Body: event("ObjectCreated:Copy", "sboms", "app/failed.json")},
+		},
+	}
+	f.unlisted = map[string]string{
+		"app/new file.json": "new",
+		"app/sns.json":      "sns",
+		"app/failed.json":   "failed",
+	}
+	c := newTestCollector(t, f, "app/", true)
+
+	docChan := make(chan *processor.Document, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- c.RetrieveArtifacts(ctx, docChan)
+	}()
+
+	// the initial listing emits the existing objects, then the events the
+	// created ones
+	docs := []*processor.Document{}
+	for len(docs) < 4 {
+		select {
+		case d := <-docChan:
+			docs = append(docs, d)
+			var err error
+			if d.SourceInformation.Source == "sboms/app/failed.json" {
+				err = fmt.Errorf("ingestion failed")
+			}
+			collector.Acknowledge(d, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out, got %v", sources(docs))
+		}
+	}
+	want := []string{
+		"sboms/app/failed.json=failed",
+		"sboms/app/new file.json=new",
+		"sboms/app/old.json=old",
+		"sboms/app/sns.json=sns",
+	}
+	if got := sources(docs); !reflect.DeepEqual(got, want) {
+		t.Errorf("RetrieveArtifacts() = %v, want %v", got, want)
+	}
+
+	// the messages without objects to ingest are deleted in the background,
+	// the one of the failed object is kept
+	wantDeleted := []string{"created", "other-prefix", "removed", "sns", "test-event"}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		deleted := append([]string{}, f.deleted...)
+		f.mu.Unlock()
+		sort.Strings(deleted)
+		if reflect.DeepEqual(deleted, wantDeleted) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deleted messages = %v, want %v", deleted, wantDeleted)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("RetrieveArtifacts() error = %v, want %v", err, context.Canceled)
+	}
+}
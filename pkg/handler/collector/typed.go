@@ -0,0 +1,78 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// typedCollector sets the type and format declared for the documents of a
+// collector, see WithDocumentType
+type typedCollector struct {
+	Collector
+	docType processor.DocumentType
+	format  processor.FormatType
+}
+
+// WithDocumentType wraps a collector whose source only holds one type of
+// document, so that its documents are emitted with the given type and format
+// and the processor does not have to detect them from their content. Either
+// can be DocumentUnknown or FormatUnknown to keep detecting it. The type and
+// format the collector sets itself on a document take precedence.
+func WithDocumentType(c Collector, docType processor.DocumentType, format processor.FormatType) Collector {
+	if unknownType(docType) && unknownFormat(format) {
+		return c
+	}
+	return &typedCollector{Collector: c, docType: docType, format: format}
+}
+
+func (c *typedCollector) RetrieveArtifacts(ctx context.Context, docChannel chan<- *processor.Document) error {
+	docs := make(chan *processor.Document)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for d := range docs {
+			if unknownType(d.Type) {
+				d.Type = c.docType
+			}
+			if unknownFormat(d.Format) {
+				d.Format = c.format
+			}
+			docChannel <- d
+		}
+	}()
+
+	err := c.Collector.RetrieveArtifacts(ctx, docs)
+	close(docs)
+	wg.Wait()
+	return err
+}
+
+// unknownType returns whether the type is left for the processor to detect,
+// collectors leaving the type empty included
+func unknownType(t processor.DocumentType) bool {
+	return t == "" || t == processor.DocumentUnknown
+}
+
+// unknownFormat returns whether the format is left for the processor to
+// detect, collectors leaving the format empty included
+func unknownFormat(f processor.FormatType) bool {
+	return f == "" || f == processor.FormatUnknown
+}
@@ -0,0 +1,159 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deadletter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Stage is the step of the pipeline in which a document failed
+type Stage string
+
+// Stage* is the enumerables of Stage
+const (
+	StageProcess  Stage = "process"
+	StageIngest   Stage = "ingest"
+	StageAssemble Stage = "assemble"
+)
+
+const (
+	// DocumentsDir is the subdirectory of the sink holding the raw
+	// documents. It can be collected again once the failure is fixed.
+	DocumentsDir = "documents"
+	// MetadataDir is the subdirectory of the sink holding the metadata of
+	// each failure, as JSON
+	MetadataDir = "metadata"
+)
+
+// Sink stores the documents that failed the pipeline, so that they can be
+// audited and reprocessed
+type Sink interface {
+	// Write stores the document together with the stage it failed in and
+	// the error
+	Write(d *processor.Document, stage Stage, err error) error
+}
+
+// errorKinds names the kinds of processor errors in the metadata
+var errorKinds = map[error]string{
+	processor.ErrUnsupportedFormat: "unsupported_format",
+	processor.ErrMalformedDocument: "malformed_document",
+	processor.ErrPartial:           "partial",
+	processor.ErrDocumentTooLarge:  "document_too_large",
+}
+
+// Metadata describes the failure of a document
+type Metadata struct {
+	Stage Stage  `json:"stage"`
+	Error string `json:"error"`
+	// ErrorKind classifies the error: "unsupported_format",
+	// "malformed_document", "partial" or "document_too_large" for the
+	// documents that will fail again until GUAC, its configuration or the
+	// document is fixed; empty for the others, which may be ingested when
+	// collected again
+	ErrorKind      string                 `json:"error_kind,omitempty"`
+	FailedAt       time.Time              `json:"failed_at"`
+	Digest         string                 `json:"digest"`
+	DocumentType   processor.DocumentType `json:"document_type"`
+	Format         processor.FormatType   `json:"format"`
+	Collector      string                 `json:"collector"`
+	Source         string                 `json:"source"`
+	RekorLogIndex  *int64                 `json:"rekor_log_index,omitempty"`
+	SignerIdentity string                 `json:"signer_identity,omitempty"`
+	Decompressed   bool                   `json:"decompressed,omitempty"`
+}
+
+type dirSink struct {
+	dir string
+	now func() time.Time
+}
+
+// NewDirectorySink returns a Sink writing each document to the documents
+// subdirectory of dir and its Metadata to the metadata subdirectory. Both
+// files are named after the SHA-256 digest of the document, so a document
+// failing again replaces its previous failure.
+func NewDirectorySink(dir string) (Sink, error) {
+	for _, sub := range []string{DocumentsDir, MetadataDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create deadletter directory: %w", err)
+		}
+	}
+	return &dirSink{dir: dir, now: time.Now}, nil
+}
+
+func (s *dirSink) Write(d *processor.Document, stage Stage, err error) error {
+	name := documentName(d.Blob)
+
+	metadata, mErr := json.MarshalIndent(Metadata{
+		Stage:          stage,
+		Error:          err.Error(),
+		ErrorKind:      errorKinds[processor.ErrorKind(err)],
+		FailedAt:       s.now().UTC(),
+		Digest:         Digest(d.Blob),
+		DocumentType:   d.Type,
+		Format:         d.Format,
+		Collector:      d.SourceInformation.Collector,
+		Source:         d.SourceInformation.Source,
+		RekorLogIndex:  d.SourceInformation.RekorLogIndex,
+		SignerIdentity: d.SourceInformation.SignerIdentity,
+		Decompressed:   d.SourceInformation.Decompressed,
+	}, "", "  ")
+	if mErr != nil {
+		return mErr
+	}
+
+	// The document is written first, so that every metadata file refers to
+	// an existing document
+	if err := writeFile(filepath.Join(s.dir, DocumentsDir), name, d.Blob); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.dir, MetadataDir), name+".json", metadata)
+}
+
+// documentName returns the name of the files of the document, the hex encoded
+// SHA-256 digest of its content
+func documentName(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFile atomically replaces the file, since concurrent workers may write
+// the same document
+func writeFile(dir string, name string, data []byte) error {
+	f, err := os.CreateTemp(dir, "."+name+"-*")
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", name, err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write %s: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write %s: %w", name, err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("unable to write %s: %w", name, err)
+	}
+	return nil
+}
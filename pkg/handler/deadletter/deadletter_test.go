@@ -0,0 +1,123 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestDirectorySink_Write(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewDirectorySink(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failedAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	sink.(*dirSink).now = func() time.Time { return failedAt }
+
+	logIndex := int64(42)
+	doc := &processor.Document{
+		Blob:   []byte("hello"),
+		Type:   processor.DocumentSPDX,
+		Format: processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{
+			Collector:     "FileCollector",
+			Source:        "file:///tmp/hello.json",
+			RekorLogIndex: &logIndex,
+		},
+	}
+	// sha256 of "hello"
+	name := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	testCases := []struct {
+		name     string
+		stage    Stage
+		err      error
+		wantKind string
+	}{{
+		name:     "process failure",
+		stage:    StageProcess,
+		err:      fmt.Errorf("unable to process doc: %w", processor.MalformedDocumentError(errors.New("invalid JSON document"))),
+		wantKind: "malformed_document",
+	}, {
+		name:     "oversized document",
+		stage:    StageProcess,
+		err:      processor.DocumentTooLargeError("file:///tmp/hello.json", 4),
+		wantKind: "document_too_large",
+	}, {
+		name:  "failing again replaces the failure",
+		stage: StageAssemble,
+		err:   errors.New("connection refused"),
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sink.Write(doc, tt.stage, tt.err); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			blob, err := os.ReadFile(filepath.Join(dir, DocumentsDir, name))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(blob) != "hello" {
+				t.Errorf("got document %q, want %q", blob, "hello")
+			}
+
+			b, err := os.ReadFile(filepath.Join(dir, MetadataDir, name+".json"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got Metadata
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := Metadata{
+				Stage:         tt.stage,
+				Error:         tt.err.Error(),
+				ErrorKind:     tt.wantKind,
+				FailedAt:      failedAt,
+				Digest:        "sha256:" + name,
+				DocumentType:  processor.DocumentSPDX,
+				Format:        processor.FormatJSON,
+				Collector:     "FileCollector",
+				Source:        "file:///tmp/hello.json",
+				RekorLogIndex: &logIndex,
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got metadata %+v, want %+v", got, want)
+			}
+
+			for _, sub := range []string{DocumentsDir, MetadataDir} {
+				entries, err := os.ReadDir(filepath.Join(dir, sub))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(entries) != 1 {
+					t.Errorf("got %d files in %s, want 1", len(entries), sub)
+				}
+			}
+		})
+	}
+}
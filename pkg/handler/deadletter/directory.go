@@ -0,0 +1,144 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Entry is a document kept in a deadletter directory, with the metadata of
+// its failure
+type Entry struct {
+	Metadata
+	// name is the name of the files of the entry, the hex encoded SHA-256
+	// digest of the document
+	name string
+}
+
+// Directory reads the entries written by the sink of NewDirectorySink, e.g.
+// to replay them once the failure is fixed
+type Directory struct {
+	dir string
+}
+
+// OpenDirectory opens the deadletter directory dir
+func OpenDirectory(dir string) (*Directory, error) {
+	for _, sub := range []string{DocumentsDir, MetadataDir} {
+		info, err := os.Stat(filepath.Join(dir, sub))
+		if err != nil {
+			return nil, fmt.Errorf("unable to open deadletter directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("unable to open deadletter directory: %s is not a directory", filepath.Join(dir, sub))
+		}
+	}
+	return &Directory{dir: dir}, nil
+}
+
+// Digest returns the digest of the document, as recorded in its Metadata
+func Digest(blob []byte) string {
+	return "sha256:" + documentName(blob)
+}
+
+// Entries returns the entries of the directory, sorted by name
+func (d *Directory) Entries() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(d.dir, MetadataDir))
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, f := range files {
+		// skip the temporary files of the writes in progress
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.dir, MetadataDir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		e := Entry{name: strings.TrimSuffix(f.Name(), ".json")}
+		if err := json.Unmarshal(data, &e.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata %s: %w", f.Name(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Document returns the document of the entry as it was when it failed. The
+// type and format of the documents that failed to be processed are reset to
+// unknown, so that they are guessed again.
+func (d *Directory) Document(e Entry) (*processor.Document, error) {
+	blob, err := os.ReadFile(filepath.Join(d.dir, DocumentsDir, e.name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read document of %s: %w", e.Source, err)
+	}
+	doc := &processor.Document{
+		Blob:   blob,
+		Type:   e.DocumentType,
+		Format: e.Format,
+		SourceInformation: processor.SourceInformation{
+			Collector:      e.Collector,
+			Source:         e.Source,
+			RekorLogIndex:  e.RekorLogIndex,
+			SignerIdentity: e.SignerIdentity,
+			Decompressed:   e.Decompressed,
+		},
+	}
+	if e.Stage == StageProcess || doc.Type == "" || doc.Format == "" {
+		doc.Type = processor.DocumentUnknown
+		doc.Format = processor.FormatUnknown
+	}
+	return doc, nil
+}
+
+// Remove deletes the entry. The metadata is deleted first, so that every
+// metadata file refers to an existing document.
+func (d *Directory) Remove(e Entry) error {
+	if err := os.Remove(filepath.Join(d.dir, MetadataDir, e.name+".json")); err != nil {
+		return fmt.Errorf("unable to remove %s: %w", e.name, err)
+	}
+	if err := os.Remove(filepath.Join(d.dir, DocumentsDir, e.name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove %s: %w", e.name, err)
+	}
+	return nil
+}
+
+// Move moves the entry to the directory dest, with the same layout, e.g. to
+// keep the documents that were replayed. dest must be on the same file
+// system.
+func (d *Directory) Move(e Entry, dest string) error {
+	for _, sub := range []string{DocumentsDir, MetadataDir} {
+		if err := os.MkdirAll(filepath.Join(dest, sub), 0o755); err != nil {
+			return fmt.Errorf("unable to create directory: %w", err)
+		}
+	}
+	// The document is moved first, so that every metadata file of dest
+	// refers to an existing document
+	if err := os.Rename(filepath.Join(d.dir, DocumentsDir, e.name), filepath.Join(dest, DocumentsDir, e.name)); err != nil {
+		return fmt.Errorf("unable to move %s: %w", e.name, err)
+	}
+	if err := os.Rename(filepath.Join(d.dir, MetadataDir, e.name+".json"), filepath.Join(dest, MetadataDir, e.name+".json")); err != nil {
+		return fmt.Errorf("unable to move %s: %w", e.name, err)
+	}
+	return nil
+}
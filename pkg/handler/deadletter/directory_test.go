@@ -0,0 +1,114 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deadletter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewDirectorySink(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	processFailure := &processor.Document{
+		Blob:              []byte("hello"),
+		Type:              processor.DocumentUnknown,
+		Format:            processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{Collector: "FileCollector", Source: "file:///tmp/hello.json"},
+	}
+	ingestFailure := &processor.Document{
+		Blob:              []byte("world"),
+		Type:              processor.DocumentSPDX,
+		Format:            processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{Collector: "FileCollector", Source: "file:///tmp/world.json"},
+	}
+	if err := sink.Write(processFailure, StageProcess, processor.UnsupportedFormatError(errors.New("unknown document"))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sink.Write(ingestFailure, StageIngest, errors.New("invalid SPDX")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d, err := OpenDirectory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := d.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// sorted by the digests of "hello" (2cf24d...) and "world" (486ea4...)
+	if len(entries) != 2 || entries[0].Source != "file:///tmp/hello.json" || entries[1].Source != "file:///tmp/world.json" {
+		t.Fatalf("Entries() = %+v, want the entries of hello.json and world.json", entries)
+	}
+	if entries[0].Digest != Digest(processFailure.Blob) || entries[0].ErrorKind != "unsupported_format" {
+		t.Errorf("Entries()[0] = %+v, want the digest of hello and the unsupported_format kind", entries[0])
+	}
+
+	wantDocs := []*processor.Document{{
+		Blob:              processFailure.Blob,
+		Type:              processor.DocumentUnknown,
+		Format:            processor.FormatUnknown,
+		SourceInformation: processFailure.SourceInformation,
+	}, ingestFailure}
+	for i, e := range entries {
+		got, err := d.Document(e)
+		if err != nil {
+			t.Fatalf("Document() unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, wantDocs[i]) {
+			t.Errorf("Document() = %+v, want %+v", got, wantDocs[i])
+		}
+	}
+
+	processed := filepath.Join(t.TempDir(), "processed")
+	if err := d.Move(entries[0], processed); err != nil {
+		t.Fatalf("Move() unexpected error: %v", err)
+	}
+	if err := d.Remove(entries[1]); err != nil {
+		t.Fatalf("Remove() unexpected error: %v", err)
+	}
+	if entries, err := d.Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("Entries() = %v, %v, want no entries", entries, err)
+	}
+	for _, sub := range []string{DocumentsDir, MetadataDir} {
+		files, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil || len(files) != 0 {
+			t.Errorf("%s holds %v, %v, want no files", sub, files, err)
+		}
+	}
+	moved, err := OpenDirectory(processed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries, err := moved.Entries(); err != nil || len(entries) != 1 || entries[0].Source != "file:///tmp/hello.json" {
+		t.Errorf("Entries() of the moved entries = %+v, %v, want the entry of hello.json", entries, err)
+	}
+}
+
+func TestOpenDirectory_Missing(t *testing.T) {
+	if _, err := OpenDirectory(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("OpenDirectory() expected an error for a missing directory")
+	}
+}
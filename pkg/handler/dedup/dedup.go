@@ -0,0 +1,82 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
+)
+
+// keyPrefix is prepended to the digests in the store, so that the store can
+// be shared with the checkpoints of the collectors
+const keyPrefix = "dedup:"
+
+// Cache remembers the content of the documents that went through the whole
+// pipeline, so that identical documents collected again (e.g. the SBOM of a
+// base image attached to many artifacts) are not processed, ingested and
+// assembled a second time. Documents are identified by the SHA-256 digest of
+// their blob, regardless of their source.
+//
+// A Cache is safe for concurrent use. Documents with the same content that
+// are in the pipeline at the same time are all processed, since they are
+// only recorded once they have been stored.
+type Cache struct {
+	store checkpoint.Store
+}
+
+// NewCache returns a Cache recording the digests in store. With a
+// checkpoint.NewMemoryStore, documents are only deduplicated within a run;
+// with a checkpoint.NewFileStore, they are also deduplicated across runs.
+func NewCache(store checkpoint.Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Digest returns the key under which a document with the given blob is
+// cached
+func Digest(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Lookup returns the source of the first document with the given digest
+// that was recorded, and whether there is one
+func (c *Cache) Lookup(digest string) (string, bool, error) {
+	source, ok, err := c.store.Get(keyPrefix + digest)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to look up document %s: %w", digest, err)
+	}
+	return source, ok, nil
+}
+
+// Add records that the document with the given digest, collected from
+// source, was stored. Documents already recorded keep their first source.
+func (c *Cache) Add(digest string, source string) error {
+	if _, ok, err := c.Lookup(digest); err != nil || ok {
+		return err
+	}
+	if err := c.store.Set(keyPrefix+digest, source); err != nil {
+		return fmt.Errorf("unable to record document %s: %w", digest, err)
+	}
+	return nil
+}
+
+// Close releases the store of the cache
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
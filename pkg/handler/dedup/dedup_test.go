@@ -0,0 +1,85 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/collector/checkpoint"
+)
+
+func TestDigest(t *testing.T) {
+	if Digest([]byte("a")) != Digest([]byte("a")) {
+		t.Errorf("Digest() of identical blobs differ")
+	}
+	if Digest([]byte("a")) == Digest([]byte("b")) {
+		t.Errorf("Digest() of different blobs are equal")
+	}
+	// sha256 of the empty string
+	want := "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := Digest(nil); got != want {
+		t.Errorf("Digest(nil) = %v, want %v", got, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(checkpoint.NewMemoryStore())
+	defer c.Close()
+	digest := Digest([]byte("sbom"))
+
+	if _, ok, err := c.Lookup(digest); err != nil || ok {
+		t.Errorf("Lookup() of a new document = %v, %v, want false, nil", ok, err)
+	}
+	for _, source := range []string{"file:///a.json", "file:///b.json"} {
+		if err := c.Add(digest, source); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	source, ok, err := c.Lookup(digest)
+	if err != nil || !ok || source != "file:///a.json" {
+		t.Errorf("Lookup() = %q, %v, %v, want the first source", source, ok, err)
+	}
+	if _, ok, _ := c.Lookup(Digest([]byte("other"))); ok {
+		t.Errorf("Lookup() of another document = true, want false")
+	}
+}
+
+func TestCache_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+	store, err := checkpoint.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := NewCache(store)
+	digest := Digest([]byte("sbom"))
+	if err := c.Add(digest, "file:///a.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store, err = checkpoint.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c = NewCache(store)
+	defer c.Close()
+	if source, ok, err := c.Lookup(digest); err != nil || !ok || source != "file:///a.json" {
+		t.Errorf("Lookup() after restart = %q, %v, %v, want file:///a.json, true, nil", source, ok, err)
+	}
+}
@@ -0,0 +1,134 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/guesser"
+)
+
+// ErrFiltered is the result with which the documents that are filtered out
+// are acknowledged, so that collectors do not record them as ingested
+var ErrFiltered = errors.New("document filtered out")
+
+// Filter selects the documents to ingest by type, format and source. A
+// document passes the filter if it matches at least one of the Include
+// criteria of each kind (an empty list matches everything) and none of the
+// Exclude criteria.
+//
+// Types and formats are compared case insensitively, so "cyclonedx" matches
+// processor.DocumentCycloneDX.
+type Filter struct {
+	IncludeTypes   []processor.DocumentType
+	ExcludeTypes   []processor.DocumentType
+	IncludeFormats []processor.FormatType
+	ExcludeFormats []processor.FormatType
+	// IncludeSources and ExcludeSources are glob patterns, as accepted by
+	// `path.Match`, matched against the source of the document (e.g.
+	// "file:///sboms/*.json")
+	IncludeSources []string
+	ExcludeSources []string
+}
+
+// Validate returns an error if one of the source patterns is malformed
+func (f *Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.IncludeSources...), f.ExcludeSources...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid source pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Empty returns true if the filter lets every document through
+func (f *Filter) Empty() bool {
+	return len(f.IncludeTypes) == 0 && len(f.ExcludeTypes) == 0 &&
+		len(f.IncludeFormats) == 0 && len(f.ExcludeFormats) == 0 &&
+		len(f.IncludeSources) == 0 && len(f.ExcludeSources) == 0
+}
+
+// Apply returns true if the document passes the filter. When the filter
+// selects on the type or format of the document and they are unknown, they
+// are guessed from the content first, and set on the document so the
+// processor does not guess them again. Documents whose type cannot be
+// guessed pass the filter, so that their failure is reported by the
+// processor.
+func (f *Filter) Apply(ctx context.Context, d *processor.Document) bool {
+	if !matchSource(d.SourceInformation.Source, f.IncludeSources, f.ExcludeSources) {
+		return false
+	}
+	filtersTypes := len(f.IncludeTypes) > 0 || len(f.ExcludeTypes) > 0
+	filtersFormats := len(f.IncludeFormats) > 0 || len(f.ExcludeFormats) > 0
+	if !filtersTypes && !filtersFormats {
+		return true
+	}
+
+	if d.Type == processor.DocumentUnknown || d.Format == processor.FormatUnknown {
+		docType, format, err := guesser.GuessDocument(ctx, d)
+		if err != nil || docType == processor.DocumentUnknown {
+			return true
+		}
+		d.Type = docType
+		d.Format = format
+	}
+
+	return match(string(d.Type), f.IncludeTypes, f.ExcludeTypes) &&
+		match(string(d.Format), f.IncludeFormats, f.ExcludeFormats)
+}
+
+// match compares value case insensitively with the included and excluded
+// values
+func match[T ~string](value string, include, exclude []T) bool {
+	for _, e := range exclude {
+		if strings.EqualFold(value, string(e)) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, i := range include {
+		if strings.EqualFold(value, string(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSource matches the source with the included and excluded patterns.
+// The patterns have been validated, so errors are ignored.
+func matchSource(source string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := path.Match(pattern, source); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := path.Match(pattern, source); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	sbom := func() *processor.Document {
+		return &processor.Document{
+			Blob:   testdata.CycloneDXBusyboxExample,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: "file",
+				Source:    "file:///sboms/busybox.json",
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		doc      *processor.Document
+		want     bool
+		wantType processor.DocumentType
+	}{{
+		name:     "empty filter",
+		doc:      sbom(),
+		want:     true,
+		wantType: processor.DocumentUnknown,
+	}, {
+		name:     "included type, case insensitive",
+		filter:   Filter{IncludeTypes: []processor.DocumentType{"cyclonedx"}},
+		doc:      sbom(),
+		want:     true,
+		wantType: processor.DocumentCycloneDX,
+	}, {
+		name:     "not included type",
+		filter:   Filter{IncludeTypes: []processor.DocumentType{processor.DocumentDSSE, processor.DocumentSPDX}},
+		doc:      sbom(),
+		want:     false,
+		wantType: processor.DocumentCycloneDX,
+	}, {
+		name:     "excluded format",
+		filter:   Filter{ExcludeFormats: []processor.FormatType{"json"}},
+		doc:      sbom(),
+		want:     false,
+		wantType: processor.DocumentCycloneDX,
+	}, {
+		name:     "included source",
+		filter:   Filter{IncludeSources: []string{"file:///sboms/*.json"}},
+		doc:      sbom(),
+		want:     true,
+		wantType: processor.DocumentUnknown,
+	}, {
+		name:     "excluded source wins",
+		filter:   Filter{IncludeSources: []string{"file:///sboms/*"}, ExcludeSources: []string{"file:///*/busybox.json"}},
+		doc:      sbom(),
+		want:     false,
+		wantType: processor.DocumentUnknown,
+	}, {
+		name:   "unknown type passes",
+		filter: Filter{IncludeTypes: []processor.DocumentType{processor.DocumentCycloneDX}},
+		doc: &processor.Document{
+			Blob:   []byte(`{"unknown": "document"}`),
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+		},
+		want:     true,
+		wantType: processor.DocumentUnknown,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Apply(ctx, tt.doc); got != tt.want {
+				t.Errorf("Filter.Apply() = %v, want %v", got, tt.want)
+			}
+			if tt.doc.Type != tt.wantType {
+				t.Errorf("document type = %v, want %v", tt.doc.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	if err := (&Filter{IncludeSources: []string{"file:///*.json"}}).Validate(); err != nil {
+		t.Errorf("Filter.Validate() error = %v", err)
+	}
+	if err := (&Filter{ExcludeSources: []string{"file:///[.json"}}).Validate(); err == nil {
+		t.Errorf("Filter.Validate() expected error for malformed pattern")
+	}
+}
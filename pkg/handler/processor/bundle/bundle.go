@@ -0,0 +1,206 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bundle processes Sigstore bundles, as written by `cosign
+// attest-blob --new-bundle-format --bundle`: a DSSE envelope
+// together with the material needed to verify it offline, i.e. the signing
+// certificate (or key hint) and the Rekor transparency log entry recording
+// the signature, with its signed entry timestamp and inclusion proof.
+package bundle
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// MediaTypePrefix is the prefix of the media type of all the versions of
+// the Sigstore bundle (e.g.
+// `application/vnd.dev.sigstore.bundle+json;version=0.1` or
+// `application/vnd.dev.sigstore.bundle.v0.3+json`)
+const MediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+
+// Bundle is a Sigstore bundle. Only the fields needed to verify and unpack
+// DSSE envelopes are decoded.
+type Bundle struct {
+	MediaType            string               `json:"mediaType"`
+	VerificationMaterial VerificationMaterial `json:"verificationMaterial"`
+	// DSSEEnvelope is the raw JSON of the envelope, which is what its
+	// signatures are verified against
+	DSSEEnvelope json.RawMessage `json:"dsseEnvelope,omitempty"`
+	// MessageSignature is set by bundles signing a blob instead of an
+	// attestation
+	MessageSignature json.RawMessage `json:"messageSignature,omitempty"`
+}
+
+// VerificationMaterial is the material needed to verify the bundle
+type VerificationMaterial struct {
+	// Certificate is the signing certificate of v0.3 bundles
+	Certificate *Certificate `json:"certificate,omitempty"`
+	// X509CertificateChain holds the signing certificate first, in earlier
+	// bundles
+	X509CertificateChain *struct {
+		Certificates []Certificate `json:"certificates"`
+	} `json:"x509CertificateChain,omitempty"`
+	PublicKey *struct {
+		Hint string `json:"hint"`
+	} `json:"publicKey,omitempty"`
+	TlogEntries []TlogEntry `json:"tlogEntries"`
+}
+
+// Certificate is a DER encoded X.509 certificate
+type Certificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// TlogEntry is an entry of the Rekor log. Binary fields are base64 encoded
+// by the JSON encoding, integers may be strings.
+type TlogEntry struct {
+	LogIndex Int64 `json:"logIndex"`
+	LogID    struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+	KindVersion struct {
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+	} `json:"kindVersion"`
+	IntegratedTime   Int64 `json:"integratedTime"`
+	InclusionPromise *struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise,omitempty"`
+	InclusionProof *struct {
+		LogIndex   Int64    `json:"logIndex"`
+		RootHash   []byte   `json:"rootHash"`
+		TreeSize   Int64    `json:"treeSize"`
+		Hashes     [][]byte `json:"hashes"`
+		Checkpoint struct {
+			Envelope string `json:"envelope"`
+		} `json:"checkpoint"`
+	} `json:"inclusionProof,omitempty"`
+	CanonicalizedBody []byte `json:"canonicalizedBody"`
+}
+
+// Int64 is an integer encoded either as a JSON number or, as protobuf JSON
+// encodes 64-bit integers, as a string
+type Int64 int64
+
+func (i *Int64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", b)
+	}
+	*i = Int64(v)
+	return nil
+}
+
+// envelope is the part of the DSSE envelope needed to unpack its payload
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// ParseBundle decodes a Sigstore bundle holding a DSSE envelope
+func ParseBundle(b []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(bundle.MediaType, MediaTypePrefix) {
+		return nil, fmt.Errorf("unexpected media type %q for a Sigstore bundle", bundle.MediaType)
+	}
+	if len(bundle.DSSEEnvelope) == 0 {
+		if len(bundle.MessageSignature) > 0 {
+			return nil, errors.New("bundle signs a blob, not an attestation, there is no DSSE envelope to ingest")
+		}
+		return nil, errors.New("bundle has no DSSE envelope")
+	}
+	return &bundle, nil
+}
+
+// Envelope returns the payload type and the decoded payload of the DSSE
+// envelope of the bundle
+func (b *Bundle) Envelope() (string, []byte, error) {
+	var e envelope
+	if err := json.Unmarshal(b.DSSEEnvelope, &e); err != nil {
+		return "", nil, fmt.Errorf("unable to parse DSSE envelope: %w", err)
+	}
+	if len(e.Signatures) == 0 {
+		return "", nil, errors.New("DSSE envelope has no signature")
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to decode envelope payload: %w", err)
+	}
+	return e.PayloadType, payload, nil
+}
+
+// SigningCertificate returns the DER encoded signing certificate of the
+// bundle, or nil if it is signed with a key
+func (b *Bundle) SigningCertificate() []byte {
+	m := b.VerificationMaterial
+	switch {
+	case m.Certificate != nil:
+		return m.Certificate.RawBytes
+	case m.X509CertificateChain != nil && len(m.X509CertificateChain.Certificates) > 0:
+		return m.X509CertificateChain.Certificates[0].RawBytes
+	}
+	return nil
+}
+
+type BundleProcessor struct{}
+
+func (p *BundleProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentSigstoreBundle {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSigstoreBundle, d.Type)
+	}
+	bundle, err := ParseBundle(d.Blob)
+	if err != nil {
+		return err
+	}
+	_, _, err = bundle.Envelope()
+	return err
+}
+
+// Unpack returns the payload of the DSSE envelope of the bundle. Its type is
+// left unknown, so that it is guessed and routed to the parser of the
+// attestation, like the payloads of standalone DSSE envelopes.
+func (p *BundleProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentSigstoreBundle {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSigstoreBundle, d.Type)
+	}
+	bundle, err := ParseBundle(d.Blob)
+	if err != nil {
+		return nil, err
+	}
+	_, payload, err := bundle.Envelope()
+	if err != nil {
+		return nil, err
+	}
+	return []*processor.Document{{
+		Blob:              payload,
+		Type:              processor.DocumentUnknown,
+		Format:            processor.FormatUnknown,
+		SourceInformation: d.SourceInformation,
+	}}, nil
+}
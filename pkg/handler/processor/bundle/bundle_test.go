@@ -0,0 +1,173 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+var (
+	ite6Bundle = processor.Document{
+		Blob: []byte(`
+		{
+			"mediaType": "application/vnd.dev.sigstore.bundle+json;version=0.2",
+			"verificationMaterial": {
+				"x509CertificateChain": {"certificates": [{"rawBytes": "MIIB"}]},
+				"tlogEntries": [{
+					"logIndex": "25579",
+					"logId": {"keyId": "wNI9atQGlz+VWfO6LRygH4QUfY/8W4RFwiT5i5WRgB0="},
+					"kindVersion": {"kind": "dsse", "version": "0.0.1"},
+					"integratedTime": "1700000000",
+					"inclusionPromise": {"signedEntryTimestamp": "c2V0"},
+					"canonicalizedBody": "e30="
+				}]
+			},
+			"dsseEnvelope": {
+				"payload": "eyJfdHlwZSI6Imh0dHBzOi8vaW4tdG90by5pby9TdGF0ZW1lbnQvdjAuMSJ9",
+				"payloadType": "application/vnd.in-toto+json",
+				"signatures": [{"sig": "c2ln"}]
+			}
+		}`),
+		Type:   processor.DocumentSigstoreBundle,
+		Format: processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{
+			Collector: "TestCollector",
+			Source:    "TestSource",
+		},
+	}
+	unpackedITE6Bundle = processor.Document{
+		Blob:   []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+		SourceInformation: processor.SourceInformation{
+			Collector: "TestCollector",
+			Source:    "TestSource",
+		},
+	}
+	messageSignatureBundle = processor.Document{
+		Blob: []byte(`
+		{
+			"mediaType": "application/vnd.dev.sigstore.bundle+json;version=0.2",
+			"verificationMaterial": {"tlogEntries": []},
+			"messageSignature": {"messageDigest": {"algorithm": "SHA2_256", "digest": "aGVsbG8="}, "signature": "c2ln"}
+		}`),
+		Type:   processor.DocumentSigstoreBundle,
+		Format: processor.FormatJSON,
+	}
+	unsignedEnvelopeBundle = processor.Document{
+		Blob: []byte(`
+		{
+			"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+			"verificationMaterial": {"tlogEntries": []},
+			"dsseEnvelope": {"payload": "e30=", "payloadType": "application/vnd.in-toto+json", "signatures": []}
+		}`),
+		Type:   processor.DocumentSigstoreBundle,
+		Format: processor.FormatJSON,
+	}
+	incorrectTypeDoc = processor.Document{
+		Blob:   []byte(`{"mediaType": "application/json"}`),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatJSON,
+	}
+)
+
+func TestParseBundle(t *testing.T) {
+	b, err := ParseBundle(ite6Bundle.Blob)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	entries := b.VerificationMaterial.TlogEntries
+	if len(entries) != 1 {
+		t.Fatalf("ParseBundle() returned %d log entries, want 1", len(entries))
+	}
+	if entries[0].LogIndex != 25579 || entries[0].IntegratedTime != 1700000000 {
+		t.Errorf("ParseBundle() log index = %d, integrated time = %d", entries[0].LogIndex, entries[0].IntegratedTime)
+	}
+	if string(entries[0].CanonicalizedBody) != "{}" {
+		t.Errorf("ParseBundle() body = %s, want {}", entries[0].CanonicalizedBody)
+	}
+	if !reflect.DeepEqual(b.SigningCertificate(), []byte{0x30, 0x82, 0x01}) {
+		t.Errorf("ParseBundle() certificate = %x", b.SigningCertificate())
+	}
+}
+
+func TestBundleProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name:     "bundle with ITE6",
+		doc:      ite6Bundle,
+		expected: []*processor.Document{&unpackedITE6Bundle},
+	}, {
+		name:      "bundle of a blob signature",
+		doc:       messageSignatureBundle,
+		expectErr: true,
+	}, {
+		name:      "incorrect type",
+		doc:       incorrectTypeDoc,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			p := BundleProcessor{}
+			actual, err := p.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("BundleProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("BundleProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBundleProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expectErr bool
+	}{{
+		name: "valid bundle",
+		doc:  ite6Bundle,
+	}, {
+		name:      "bundle of a blob signature",
+		doc:       messageSignatureBundle,
+		expectErr: true,
+	}, {
+		name:      "envelope without signature",
+		doc:       unsignedEnvelopeBundle,
+		expectErr: true,
+	}, {
+		name:      "incorrect type",
+		doc:       incorrectTypeDoc,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			p := BundleProcessor{}
+			err := p.ValidateSchema(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("BundleProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clearlydefined
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Definition is the definition of a component, as returned by the
+// ClearlyDefined API (`https://api.clearlydefined.io/definitions/<coordinates>`).
+// Only the fields ingested by GUAC are decoded.
+type Definition struct {
+	Coordinates Coordinates `json:"coordinates"`
+	Described   Described   `json:"described"`
+	Licensed    Licensed    `json:"licensed"`
+	Scores      Scores      `json:"scores"`
+	Meta        Meta        `json:"_meta"`
+}
+
+// Coordinates identify a component in ClearlyDefined, e.g. the type `npm`,
+// provider `npmjs`, namespace `@babel`, name `core` and revision `7.0.0`
+type Coordinates struct {
+	Type      string `json:"type"`
+	Provider  string `json:"provider"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Revision  string `json:"revision,omitempty"`
+}
+
+// Described holds the descriptive data of the component
+type Described struct {
+	ReleaseDate    string          `json:"releaseDate,omitempty"`
+	SourceLocation *SourceLocation `json:"sourceLocation,omitempty"`
+}
+
+// SourceLocation gives where the sources of the component are
+type SourceLocation struct {
+	Coordinates
+	URL string `json:"url,omitempty"`
+}
+
+// Licensed holds the licensing data of the component: the license declared
+// by the package, and the licenses and copyrights found in the files of its
+// core facet (i.e. not in tests, docs, etc.)
+type Licensed struct {
+	Declared string `json:"declared,omitempty"`
+	Facets   struct {
+		Core Facet `json:"core"`
+	} `json:"facets"`
+}
+
+// Facet holds the licensing data found in a group of files
+type Facet struct {
+	Attribution struct {
+		Parties []string `json:"parties,omitempty"`
+	} `json:"attribution"`
+	Discovered struct {
+		Expressions []string `json:"expressions,omitempty"`
+	} `json:"discovered"`
+	Files int `json:"files,omitempty"`
+}
+
+// Scores are the scores (0 to 100) of the quality of the licensing data
+type Scores struct {
+	Effective int `json:"effective"`
+	Tool      int `json:"tool"`
+}
+
+// Meta holds the metadata of the definition
+type Meta struct {
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+	Updated       string `json:"updated,omitempty"`
+}
+
+// String returns the coordinates in the form used by the ClearlyDefined API,
+// `type/provider/namespace/name/revision`, with `-` for an empty namespace
+func (c Coordinates) String() string {
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = "-"
+	}
+	s := strings.Join([]string{c.Type, c.Provider, namespace, c.Name}, "/")
+	if c.Revision != "" {
+		s += "/" + c.Revision
+	}
+	return s
+}
+
+// purlTypes maps the ClearlyDefined types to purl types. The types of
+// source archives and git repositories depend on their provider, see Purl.
+var purlTypes = map[string]string{
+	"composer": "composer",
+	"crate":    "cargo",
+	"deb":      "deb",
+	"debsrc":   "deb",
+	"gem":      "gem",
+	"go":       "golang",
+	"maven":    "maven",
+	"npm":      "npm",
+	"nuget":    "nuget",
+	"pod":      "cocoapods",
+	"pypi":     "pypi",
+}
+
+// sourcePurlTypes maps the providers of git repositories and source archives
+// to purl types
+var sourcePurlTypes = map[string]string{
+	"github":       "github",
+	"gitlab":       "gitlab",
+	"mavencentral": "maven",
+	"mavengoogle":  "maven",
+}
+
+// Purl returns the purl of the package with the coordinates, without
+// qualifiers: those that ClearlyDefined encodes in the revision (e.g. the
+// architecture of Debian packages) do not change the licensing. It fails for
+// types without purl equivalent.
+func (c Coordinates) Purl() (string, error) {
+	if c.Type == "" || c.Name == "" {
+		return "", fmt.Errorf("incomplete coordinates %q", c.String())
+	}
+	typ, ok := purlTypes[strings.ToLower(c.Type)]
+	if t := strings.ToLower(c.Type); t == "git" || t == "sourcearchive" {
+		typ, ok = sourcePurlTypes[strings.ToLower(c.Provider)]
+	}
+	if !ok {
+		return "", fmt.Errorf("unsupported coordinates %q", c.String())
+	}
+
+	namespace := c.Namespace
+	if namespace == "-" {
+		namespace = ""
+	}
+	// Go namespaces are module paths with escaped slashes, e.g.
+	// `github.com%2fgorilla`
+	if unescaped, err := url.PathUnescape(namespace); err == nil {
+		namespace = unescaped
+	}
+	version := c.Revision
+	if typ == "deb" {
+		namespace = "debian"
+		// Debian revisions end with the architecture, e.g. `1.2-3_amd64`,
+		// and versions cannot contain underscores
+		if i := strings.LastIndex(version, "_"); i >= 0 {
+			version = version[:i]
+		}
+	}
+
+	segments := []string{}
+	for _, s := range strings.Split(namespace, "/") {
+		if s != "" {
+			segments = append(segments, escapeSegment(s))
+		}
+	}
+	segments = append(segments, escapeSegment(c.Name))
+	purl := "pkg:" + typ + "/" + strings.Join(segments, "/")
+	if version != "" {
+		purl += "@" + escapeSegment(version)
+	}
+	return purl, nil
+}
+
+// escapeSegment escapes a segment of a purl, including the `@` of npm scopes
+func escapeSegment(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
+
+// ClearlyDefinedProcessor processes ClearlyDefined definitions.
+// Currently only supports JSON documents
+type ClearlyDefinedProcessor struct {
+}
+
+func (p *ClearlyDefinedProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentClearlyDefined {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentClearlyDefined, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var def Definition
+		if err := json.Unmarshal(d.Blob, &def); err != nil {
+			return err
+		}
+		if def.Coordinates.Type == "" || def.Coordinates.Provider == "" || def.Coordinates.Name == "" {
+			return fmt.Errorf("missing required ClearlyDefined coordinates")
+		}
+		_, err := def.Coordinates.Purl()
+		return err
+	}
+
+	return fmt.Errorf("unable to support parsing of ClearlyDefined document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *ClearlyDefinedProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentClearlyDefined {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentClearlyDefined, d.Type)
+	}
+
+	// ClearlyDefined definitions don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
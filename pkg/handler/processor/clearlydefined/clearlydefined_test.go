@@ -0,0 +1,179 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clearlydefined
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestClearlyDefinedProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "ClearlyDefined document",
+		doc: processor.Document{
+			Blob:              testdata.ClearlyDefinedExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentClearlyDefined,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.ClearlyDefinedExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := ClearlyDefinedProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ClearlyDefinedProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("ClearlyDefinedProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClearlyDefinedProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		format    processor.FormatType
+		expectErr bool
+	}{{
+		name:      "valid ClearlyDefined document",
+		blob:      testdata.ClearlyDefinedExample,
+		format:    processor.FormatJSON,
+		expectErr: false,
+	}, {
+		name:      "coordinates without name",
+		blob:      testdata.ClearlyDefinedInvalid,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "unsupported coordinates",
+		blob:      []byte(`{"coordinates": {"type": "conda", "provider": "conda-forge", "name": "numpy", "revision": "linux-64:1.26.0"}}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid format supported",
+		blob:      testdata.ClearlyDefinedExample,
+		format:    processor.FormatUnknown,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := ClearlyDefinedProcessor{}
+			err := d.ValidateSchema(&processor.Document{
+				Blob:   tt.blob,
+				Format: tt.format,
+				Type:   processor.DocumentClearlyDefined,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ClearlyDefinedProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestCoordinates_Purl(t *testing.T) {
+	testCases := []struct {
+		coordinates Coordinates
+		expected    string
+		expectErr   bool
+	}{
+		{coordinates: Coordinates{Type: "npm", Provider: "npmjs", Name: "lodash", Revision: "4.17.21"}, expected: "pkg:npm/lodash@4.17.21"},
+		{coordinates: Coordinates{Type: "npm", Provider: "npmjs", Namespace: "@babel", Name: "core", Revision: "7.0.0"}, expected: "pkg:npm/%40babel/core@7.0.0"},
+		{coordinates: Coordinates{Type: "npm", Provider: "npmjs", Namespace: "-", Name: "lodash"}, expected: "pkg:npm/lodash"},
+		{coordinates: Coordinates{Type: "maven", Provider: "mavencentral", Namespace: "org.apache.commons", Name: "commons-lang3", Revision: "3.12.0"}, expected: "pkg:maven/org.apache.commons/commons-lang3@3.12.0"},
+		{coordinates: Coordinates{Type: "sourcearchive", Provider: "mavencentral", Namespace: "org.apache.commons", Name: "commons-lang3", Revision: "3.12.0"}, expected: "pkg:maven/org.apache.commons/commons-lang3@3.12.0"},
+		{coordinates: Coordinates{Type: "pypi", Provider: "pypi", Name: "Django", Revision: "4.2.1"}, expected: "pkg:pypi/Django@4.2.1"},
+		{coordinates: Coordinates{Type: "crate", Provider: "cratesio", Name: "serde", Revision: "1.0.188"}, expected: "pkg:cargo/serde@1.0.188"},
+		{coordinates: Coordinates{Type: "go", Provider: "golang", Namespace: "github.com%2fgorilla", Name: "mux", Revision: "v1.8.0"}, expected: "pkg:golang/github.com/gorilla/mux@v1.8.0"},
+		{coordinates: Coordinates{Type: "git", Provider: "github", Namespace: "lodash", Name: "lodash", Revision: "c6e281b"}, expected: "pkg:github/lodash/lodash@c6e281b"},
+		{coordinates: Coordinates{Type: "deb", Provider: "debian", Name: "openssl", Revision: "3.0.11-1~deb12u2_amd64"}, expected: "pkg:deb/debian/openssl@3.0.11-1~deb12u2"},
+		{coordinates: Coordinates{Type: "pod", Provider: "cocoapods", Name: "Alamofire", Revision: "5.8.0"}, expected: "pkg:cocoapods/Alamofire@5.8.0"},
+		{coordinates: Coordinates{Type: "conda", Provider: "conda-forge", Name: "numpy"}, expectErr: true},
+		{coordinates: Coordinates{Type: "git", Provider: "bitbucket", Namespace: "a", Name: "b"}, expectErr: true},
+		{coordinates: Coordinates{Type: "npm", Provider: "npmjs"}, expectErr: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.coordinates.String(), func(t *testing.T) {
+			got, err := tt.coordinates.Purl()
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Coordinates.Purl() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Coordinates.Purl() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSatisfied(t *testing.T) {
+	approved := []string{"MIT", "apache-2.0", "GPL-2.0 WITH Classpath-exception-2.0", "LGPL-2.1"}
+	testCases := []struct {
+		expression string
+		expected   bool
+		expectErr  bool
+	}{
+		{expression: "MIT", expected: true},
+		{expression: "mit", expected: true},
+		{expression: "BSD-3-Clause", expected: false},
+		{expression: "MIT OR GPL-3.0", expected: true},
+		{expression: "MIT AND GPL-3.0", expected: false},
+		{expression: "(MIT OR GPL-3.0) AND Apache-2.0", expected: true},
+		{expression: "MIT AND GPL-3.0 OR Apache-2.0", expected: true},
+		{expression: "GPL-2.0 WITH Classpath-exception-2.0", expected: true},
+		{expression: "GPL-2.0", expected: false},
+		{expression: "MIT WITH some-exception", expected: true},
+		{expression: "LGPL-2.1+", expected: true},
+		{expression: "NOASSERTION", expected: false},
+		{expression: "", expectErr: true},
+		{expression: "MIT OR", expectErr: true},
+		{expression: "(MIT", expectErr: true},
+		{expression: "MIT Apache-2.0", expectErr: true},
+		{expression: "GPL-2.0 WITH", expectErr: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Satisfied(tt.expression, approved)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Satisfied() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Satisfied() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
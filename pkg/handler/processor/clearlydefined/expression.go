@@ -0,0 +1,144 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clearlydefined
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Satisfied returns whether the SPDX license expression can be complied with
+// using only the approved licenses: all the operands of AND must be
+// satisfied, and one of the operands of OR. A license with an exception
+// (`GPL-2.0 WITH Classpath-exception-2.0`) is satisfied when it is approved
+// with or without the exception, and `GPL-2.0+` when it is approved with or
+// without the `+`. Licenses are matched case insensitively.
+func Satisfied(expression string, approved []string) (bool, error) {
+	p := &expressionParser{
+		tokens:   strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)),
+		approved: map[string]bool{},
+	}
+	for _, a := range approved {
+		p.approved[normalizeLicense(a)] = true
+	}
+	ok, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if tok := p.next(); tok != "" {
+		return false, fmt.Errorf("unexpected %q in license expression %q", tok, expression)
+	}
+	return ok, nil
+}
+
+func normalizeLicense(license string) string {
+	return strings.ToLower(strings.Join(strings.Fields(license), " "))
+}
+
+// expressionParser evaluates a license expression, parsing it fully even
+// when the result is known, so that invalid expressions are reported
+type expressionParser struct {
+	tokens   []string
+	approved map[string]bool
+}
+
+func (p *expressionParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *expressionParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+func (p *expressionParser) or() (bool, error) {
+	ok, err := p.and()
+	if err != nil {
+		return false, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		operand, err := p.and()
+		if err != nil {
+			return false, err
+		}
+		ok = ok || operand
+	}
+	return ok, nil
+}
+
+func (p *expressionParser) and() (bool, error) {
+	ok, err := p.license()
+	if err != nil {
+		return false, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		operand, err := p.license()
+		if err != nil {
+			return false, err
+		}
+		ok = ok && operand
+	}
+	return ok, nil
+}
+
+func (p *expressionParser) license() (bool, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return false, fmt.Errorf("unexpected end of license expression")
+	case tok == "(":
+		ok, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if p.next() != ")" {
+			return false, fmt.Errorf("missing closing parenthesis in license expression")
+		}
+		return ok, nil
+	case !isLicense(tok):
+		return false, fmt.Errorf("unexpected %q in license expression", tok)
+	}
+
+	id := strings.ToLower(tok)
+	ok := p.approved[id] || (strings.HasSuffix(id, "+") && p.approved[strings.TrimSuffix(id, "+")])
+	if strings.EqualFold(p.peek(), "WITH") {
+		p.next()
+		exception := p.next()
+		if !isLicense(exception) {
+			return false, fmt.Errorf("missing exception after WITH in license expression")
+		}
+		ok = ok || p.approved[id+" with "+strings.ToLower(exception)]
+	}
+	return ok, nil
+}
+
+// isLicense returns whether the token is a license (or exception) id,
+// rather than an operator or a parenthesis
+func isLicense(tok string) bool {
+	switch strings.ToUpper(tok) {
+	case "", "(", ")", "AND", "OR", "WITH":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csaf
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// CategoryVEX is the document category of CSAF VEX documents
+const CategoryVEX = "csaf_vex"
+
+// Document is a CSAF document, as described at
+// https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html. Only the fields
+// used by GUAC are decoded.
+type Document struct {
+	Document        DocumentMetadata `json:"document"`
+	ProductTree     ProductTree      `json:"product_tree"`
+	Vulnerabilities []Vulnerability  `json:"vulnerabilities"`
+}
+
+// DocumentMetadata is the metadata of the document
+type DocumentMetadata struct {
+	Category    string   `json:"category"`
+	CSAFVersion string   `json:"csaf_version"`
+	Title       string   `json:"title"`
+	Tracking    Tracking `json:"tracking"`
+}
+
+// Tracking identifies the document
+type Tracking struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// ProductTree lists the products referenced by the vulnerabilities
+type ProductTree struct {
+	Branches         []Branch       `json:"branches,omitempty"`
+	FullProductNames []FullProduct  `json:"full_product_names,omitempty"`
+	Relationships    []Relationship `json:"relationships,omitempty"`
+}
+
+// Branch is a node of the product tree, its leaves are products
+type Branch struct {
+	Category string       `json:"category"`
+	Name     string       `json:"name"`
+	Branches []Branch     `json:"branches,omitempty"`
+	Product  *FullProduct `json:"product,omitempty"`
+}
+
+// FullProduct is a product, identified in the document by its ProductID
+type FullProduct struct {
+	Name                        string                       `json:"name"`
+	ProductID                   string                       `json:"product_id"`
+	ProductIdentificationHelper *ProductIdentificationHelper `json:"product_identification_helper,omitempty"`
+}
+
+// ProductIdentificationHelper gives the software identifiers of a product
+type ProductIdentificationHelper struct {
+	CPE  string `json:"cpe,omitempty"`
+	Purl string `json:"purl,omitempty"`
+}
+
+// Relationship defines a product made of other products (e.g. a package
+// installed in a container image)
+type Relationship struct {
+	Category                  string      `json:"category"`
+	FullProductName           FullProduct `json:"full_product_name"`
+	ProductReference          string      `json:"product_reference"`
+	RelatesToProductReference string      `json:"relates_to_product_reference"`
+}
+
+// Vulnerability gives the status of a vulnerability for the products
+type Vulnerability struct {
+	CVE           string        `json:"cve,omitempty"`
+	IDs           []ID          `json:"ids,omitempty"`
+	ProductStatus ProductStatus `json:"product_status"`
+	Flags         []Flag        `json:"flags,omitempty"`
+	Threats       []Threat      `json:"threats,omitempty"`
+	Remediations  []Remediation `json:"remediations,omitempty"`
+}
+
+// ID is an identifier of the vulnerability in another system (e.g. a GHSA)
+type ID struct {
+	SystemName string `json:"system_name"`
+	Text       string `json:"text"`
+}
+
+// ProductStatus lists the products for each status of the vulnerability
+type ProductStatus struct {
+	FirstAffected      []string `json:"first_affected,omitempty"`
+	FirstFixed         []string `json:"first_fixed,omitempty"`
+	Fixed              []string `json:"fixed,omitempty"`
+	KnownAffected      []string `json:"known_affected,omitempty"`
+	KnownNotAffected   []string `json:"known_not_affected,omitempty"`
+	LastAffected       []string `json:"last_affected,omitempty"`
+	Recommended        []string `json:"recommended,omitempty"`
+	UnderInvestigation []string `json:"under_investigation,omitempty"`
+}
+
+// Flag justifies why products are not affected, its label being one of the
+// VEX justifications (e.g. `vulnerable_code_not_present`)
+type Flag struct {
+	Label      string   `json:"label"`
+	ProductIDs []string `json:"product_ids,omitempty"`
+}
+
+// Threat describes the impact of the vulnerability on products
+type Threat struct {
+	Category   string   `json:"category"`
+	Details    string   `json:"details"`
+	ProductIDs []string `json:"product_ids,omitempty"`
+}
+
+// Remediation describes how to remediate the vulnerability on products
+type Remediation struct {
+	Category   string   `json:"category"`
+	Details    string   `json:"details"`
+	ProductIDs []string `json:"product_ids,omitempty"`
+}
+
+// CSAFProcessor processes CSAF documents.
+// Currently only supports JSON documents
+type CSAFProcessor struct {
+}
+
+func (p *CSAFProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentCSAF {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentCSAF, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var doc Document
+		if err := json.Unmarshal(d.Blob, &doc); err != nil {
+			return err
+		}
+		if doc.Document.CSAFVersion == "" || doc.Document.Category == "" || doc.Document.Tracking.ID == "" {
+			return fmt.Errorf("missing required CSAF fields")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of CSAF document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *CSAFProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentCSAF {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentCSAF, d.Type)
+	}
+
+	// CSAF documents don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
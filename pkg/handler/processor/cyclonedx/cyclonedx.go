@@ -17,6 +17,7 @@ package cyclonedx
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	cdx "github.com/CycloneDX/cyclonedx-go"
@@ -24,7 +25,7 @@ import (
 )
 
 // CycloneDXProcessor processes CycloneDXProcessor documents.
-// Currently only supports CycloneDX-JSON documents
+// Currently only supports CycloneDX-JSON documents, up to version 1.5
 type CycloneDXProcessor struct {
 }
 
@@ -35,10 +36,7 @@ func (p *CycloneDXProcessor) ValidateSchema(d *processor.Document) error {
 
 	switch d.Format {
 	case processor.FormatJSON:
-		reader := bytes.NewReader(d.Blob)
-		bom := new(cdx.BOM)
-		decoder := cdx.NewBOMDecoder(reader, cdx.BOMFileFormatJSON)
-		err := decoder.Decode(bom)
+		_, err := DecodeBOM(d.Blob)
 		return err
 	}
 
@@ -51,3 +49,86 @@ func (p *CycloneDXProcessor) Unpack(d *processor.Document) ([]*processor.Documen
 	}
 	return []*processor.Document{}, nil
 }
+
+// ExternalReferences returns the URLs of the BOMs referenced by the document
+// or by any of its components, through external references of type `bom`
+func (p *CycloneDXProcessor) ExternalReferences(d *processor.Document) ([]string, error) {
+	if d.Type != processor.DocumentCycloneDX {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentCycloneDX, d.Type)
+	}
+
+	bom, err := DecodeBOM(d.Blob)
+	if err != nil {
+		return nil, err
+	}
+	urls := bomReferences(bom.ExternalReferences)
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		urls = append(urls, bomReferences(bom.Metadata.Component.ExternalReferences)...)
+	}
+	if bom.Components != nil {
+		urls = append(urls, componentBOMReferences(*bom.Components)...)
+	}
+	return urls, nil
+}
+
+func componentBOMReferences(components []cdx.Component) []string {
+	urls := []string{}
+	for _, c := range components {
+		urls = append(urls, bomReferences(c.ExternalReferences)...)
+		if c.Components != nil {
+			urls = append(urls, componentBOMReferences(*c.Components)...)
+		}
+	}
+	return urls
+}
+
+func bomReferences(refs *[]cdx.ExternalReference) []string {
+	urls := []string{}
+	if refs == nil {
+		return urls
+	}
+	for _, ref := range *refs {
+		if ref.Type == cdx.ERTypeBOM && ref.URL != "" {
+			urls = append(urls, ref.URL)
+		}
+	}
+	return urls
+}
+
+// DecodeBOM decodes a CycloneDX-JSON document.
+//
+// The CycloneDX library rejects specification versions it does not know
+// about, so the `specVersion` of CycloneDX 1.5 documents is rewritten to 1.4
+// before decoding. CycloneDX 1.5 only adds fields on top of 1.4, which are
+// ignored.
+func DecodeBOM(blob []byte) (*cdx.BOM, error) {
+	blob, err := downgradeSpecVersion(blob)
+	if err != nil {
+		return nil, err
+	}
+	bom := new(cdx.BOM)
+	decoder := cdx.NewBOMDecoder(bytes.NewReader(blob), cdx.BOMFileFormatJSON)
+	if err := decoder.Decode(bom); err != nil {
+		return nil, err
+	}
+	return bom, nil
+}
+
+func downgradeSpecVersion(blob []byte) ([]byte, error) {
+	var header struct {
+		SpecVersion string `json:"specVersion"`
+	}
+	if err := json.Unmarshal(blob, &header); err != nil {
+		return nil, err
+	}
+	if header.SpecVersion != "1.5" {
+		return blob, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &raw); err != nil {
+		return nil, err
+	}
+	raw["specVersion"] = json.RawMessage(`"1.4"`)
+	return json.Marshal(raw)
+}
@@ -98,6 +98,15 @@ func TestCycloneDXProcessor_ValidateSchema(t *testing.T) {
 			SourceInformation: processor.SourceInformation{},
 		},
 		expectErr: false,
+	}, {
+		name: "valid CycloneDX 1.5 document",
+		doc: processor.Document{
+			Blob:              testdata.CycloneDXVulnExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentCycloneDX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: false,
 	}, {
 		name: "invalid CycloneDX document",
 		doc: processor.Document{
@@ -127,3 +136,55 @@ func TestCycloneDXProcessor_ValidateSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestCycloneDXProcessor_ExternalReferences(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		expected  []string
+		expectErr bool
+	}{{
+		name:     "no references",
+		blob:     testdata.CycloneDXBusyboxExample,
+		expected: []string{},
+	}, {
+		name: "bom references",
+		blob: []byte(`{
+			"bomFormat": "CycloneDX",
+			"specVersion": "1.4",
+			"version": 1,
+			"externalReferences": [{"type": "bom", "url": "https://example.com/top.json"}],
+			"metadata": {"component": {"type": "application", "name": "app",
+				"externalReferences": [{"type": "website", "url": "https://example.com"}]}},
+			"components": [{"type": "library", "name": "lib",
+				"externalReferences": [{"type": "bom", "url": "https://example.com/lib.json"}],
+				"components": [{"type": "library", "name": "nested",
+					"externalReferences": [{"type": "bom", "url": "https://example.com/nested.json"}]}]}]
+		}`),
+		expected: []string{
+			"https://example.com/top.json",
+			"https://example.com/lib.json",
+			"https://example.com/nested.json",
+		},
+	}, {
+		name:      "invalid document",
+		blob:      []byte(`not json`),
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := CycloneDXProcessor{}
+			actual, err := d.ExternalReferences(&processor.Document{
+				Blob:   tt.blob,
+				Format: processor.FormatJSON,
+				Type:   processor.DocumentCycloneDX,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("CycloneDXProcessor.ExternalReferences() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err == nil && !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("CycloneDXProcessor.ExternalReferences() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
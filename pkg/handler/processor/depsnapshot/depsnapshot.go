@@ -0,0 +1,153 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsnapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Snapshot is a snapshot of the dependencies of a repository at a commit, in
+// the format of GitHub's dependency submission API
+// (https://docs.github.com/en/rest/dependency-graph/dependency-submission).
+// The repository is given by the URL the snapshot is submitted to, not by
+// the snapshot itself.
+type Snapshot struct {
+	Version   int                 `json:"version"`
+	Job       Job                 `json:"job"`
+	Sha       string              `json:"sha"`
+	Ref       string              `json:"ref"`
+	Detector  Detector            `json:"detector"`
+	Scanned   string              `json:"scanned,omitempty"`
+	Manifests map[string]Manifest `json:"manifests,omitempty"`
+}
+
+// Job is the CI job that submitted the snapshot
+type Job struct {
+	Correlator string `json:"correlator"`
+	ID         string `json:"id"`
+	HTMLURL    string `json:"html_url,omitempty"`
+}
+
+// Detector is the tool that found the dependencies
+type Detector struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// Manifest is a manifest file (e.g. `package-lock.json`) and the
+// dependencies resolved from it, keyed by an identifier unique within the
+// manifest (usually the name of the package)
+type Manifest struct {
+	Name     string                `json:"name"`
+	File     *File                 `json:"file,omitempty"`
+	Resolved map[string]Dependency `json:"resolved,omitempty"`
+}
+
+// File is the location of a manifest in the repository
+type File struct {
+	SourceLocation string `json:"source_location,omitempty"`
+}
+
+// Dependency is a resolved dependency of a manifest. Relationship is
+// `direct` or `indirect`, Scope is `runtime` or `development`.
+// Dependencies lists the dependencies of the package, either by their key in
+// the resolved dependencies of the manifest or by their purl.
+type Dependency struct {
+	PackageURL   string   `json:"package_url,omitempty"`
+	Relationship string   `json:"relationship,omitempty"`
+	Scope        string   `json:"scope,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// Path returns the path of the manifest in the repository, or its name (or
+// its key in the snapshot) when the location of its file is unknown
+func (m Manifest) Path(key string) string {
+	if m.File != nil && m.File.SourceLocation != "" {
+		return m.File.SourceLocation
+	}
+	if m.Name != "" {
+		return m.Name
+	}
+	return key
+}
+
+// Lookup returns the purl of a dependency listed by a resolved dependency of
+// the manifest, given either by its key or by its purl, or false if it is
+// not resolved by the manifest and not a purl
+func (m Manifest) Lookup(ref string) (string, bool) {
+	if d, ok := m.Resolved[ref]; ok {
+		return d.PackageURL, d.PackageURL != ""
+	}
+	if isPurl(ref) {
+		return ref, true
+	}
+	return "", false
+}
+
+func isPurl(s string) bool {
+	return len(s) > len("pkg:") && strings.EqualFold(s[:len("pkg:")], "pkg:")
+}
+
+// DepSnapshotProcessor processes dependency snapshots.
+// Currently only supports JSON documents
+type DepSnapshotProcessor struct {
+}
+
+func (p *DepSnapshotProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentDepSnapshot {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentDepSnapshot, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var snapshot Snapshot
+		if err := json.Unmarshal(d.Blob, &snapshot); err != nil {
+			return err
+		}
+		if snapshot.Detector.Name == "" || snapshot.Sha == "" || snapshot.Ref == "" {
+			return fmt.Errorf("missing required dependency snapshot fields")
+		}
+		for key, m := range snapshot.Manifests {
+			for name, dep := range m.Resolved {
+				if dep.PackageURL != "" && !isPurl(dep.PackageURL) {
+					return fmt.Errorf("invalid purl %q of dependency %s of manifest %s", dep.PackageURL, name, m.Path(key))
+				}
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of dependency snapshot format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *DepSnapshotProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentDepSnapshot {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentDepSnapshot, d.Type)
+	}
+
+	// Dependency snapshots don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
@@ -0,0 +1,143 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsnapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestDepSnapshotProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "dependency snapshot",
+		doc: processor.Document{
+			Blob:              testdata.DepSnapshotExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentDepSnapshot,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.DepSnapshotExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DepSnapshotProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("DepSnapshotProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("DepSnapshotProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDepSnapshotProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		format    processor.FormatType
+		expectErr bool
+	}{{
+		name:      "valid dependency snapshot",
+		blob:      testdata.DepSnapshotExample,
+		format:    processor.FormatJSON,
+		expectErr: false,
+	}, {
+		name:      "detector without name",
+		blob:      testdata.DepSnapshotInvalid,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid purl",
+		blob:      []byte(`{"sha": "ce58745", "ref": "refs/heads/main", "detector": {"name": "d"}, "manifests": {"go.mod": {"resolved": {"cobra": {"package_url": "golang/github.com/spf13/cobra"}}}}}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid format supported",
+		blob:      testdata.DepSnapshotExample,
+		format:    processor.FormatUnknown,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DepSnapshotProcessor{}
+			err := d.ValidateSchema(&processor.Document{
+				Blob:   tt.blob,
+				Format: tt.format,
+				Type:   processor.DocumentDepSnapshot,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("DepSnapshotProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	m := Manifest{
+		Name: "package-lock.json",
+		Resolved: map[string]Dependency{
+			"tunnel": {PackageURL: "pkg:npm/tunnel@0.0.6"},
+			"local":  {},
+		},
+	}
+	if got := m.Path("key"); got != "package-lock.json" {
+		t.Errorf("Path() = %q, expected the name of the manifest", got)
+	}
+	m.File = &File{SourceLocation: "web/package-lock.json"}
+	if got := m.Path("key"); got != "web/package-lock.json" {
+		t.Errorf("Path() = %q, expected the location of the file", got)
+	}
+
+	testCases := []struct {
+		ref      string
+		expected string
+		found    bool
+	}{
+		{ref: "tunnel", expected: "pkg:npm/tunnel@0.0.6", found: true},
+		{ref: "pkg:npm/semver@7.0.0", expected: "pkg:npm/semver@7.0.0", found: true},
+		{ref: "local", found: false},
+		{ref: "unknown", found: false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, found := m.Lookup(tt.ref)
+			if got != tt.expected || found != tt.found {
+				t.Errorf("Lookup() = %q, %v, expected %q, %v", got, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import "errors"
+
+// Kinds of the errors returned by the processor and parser packages for
+// documents that cannot be ingested. Callers branch on them with errors.Is,
+// e.g. to keep malformed documents aside but retry the others: an error of
+// none of these kinds (I/O, timeout, ...) may succeed when retried.
+var (
+	// ErrUnsupportedFormat is the kind of the errors for documents whose
+	// type, format or spec version GUAC cannot ingest
+	ErrUnsupportedFormat = errors.New("unsupported document format")
+	// ErrMalformedDocument is the kind of the errors for documents that do
+	// not match their type and format
+	ErrMalformedDocument = errors.New("malformed document")
+	// ErrPartial is the kind of the errors for document trees of which only
+	// some documents could be ingested
+	ErrPartial = errors.New("partially ingested document")
+	// ErrDocumentTooLarge is the kind of the errors for documents larger
+	// than MaxDocumentSize
+	ErrDocumentTooLarge = errors.New("document too large")
+)
+
+// Error is an error of kind Kind, one of ErrUnsupportedFormat,
+// ErrMalformedDocument, ErrPartial or ErrDocumentTooLarge, caused by Err. Both errors.Is(err,
+// Kind) and errors.Is(err, Err) hold.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// UnsupportedFormatError returns err with the kind ErrUnsupportedFormat, or
+// nil if err is nil. Errors that already have a kind keep it.
+func UnsupportedFormatError(err error) error {
+	return withKind(ErrUnsupportedFormat, err)
+}
+
+// MalformedDocumentError returns err with the kind ErrMalformedDocument, or
+// nil if err is nil. Errors that already have a kind keep it.
+func MalformedDocumentError(err error) error {
+	return withKind(ErrMalformedDocument, err)
+}
+
+func withKind(kind error, err error) error {
+	if err == nil || ErrorKind(err) != nil {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// ErrorKind returns the kind of err, ErrUnsupportedFormat,
+// ErrMalformedDocument, ErrPartial or ErrDocumentTooLarge, or nil if it has
+// none
+func ErrorKind(err error) error {
+	for _, kind := range []error{ErrUnsupportedFormat, ErrMalformedDocument, ErrPartial, ErrDocumentTooLarge} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ func init() {
 	_ = RegisterDocumentFormatGuesser(&jsonFormatGuesser{}, "json")
 	_ = RegisterDocumentFormatGuesser(&jsonLinesFormatGuesser{}, "json-lines")
 	_ = RegisterDocumentFormatGuesser(&xmlFormatGuesser{}, "xml")
+	_ = RegisterDocumentFormatGuesser(&tagValueFormatGuesser{}, "tag-value")
 }
 
 // DocumentFormatGuesser guesses the format of the document given a blob
@@ -24,15 +24,38 @@ import (
 
 type jsonLinesFormatGuesser struct{}
 
+// GuessFormat detects JSON Lines documents (e.g. `.intoto.jsonl` files, with
+// one DSSE envelope per line) as those with several lines, most of which are
+// JSON objects. A few malformed lines are tolerated, so that they only fail
+// themselves once unpacked, while indented JSON documents, whose lines are
+// mostly not valid JSON on their own, are not matched.
 func (_ *jsonLinesFormatGuesser) GuessFormat(blob []byte) processor.FormatType {
-	lines := strings.Split(strings.TrimSpace(string(blob)), "\n")
+	lines := []string{}
+	for _, line := range strings.Split(string(blob), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	switch len(lines) {
+	case 0:
+		return processor.FormatUnknown
+	case 1:
+		if json.Valid([]byte(lines[0])) {
+			return processor.FormatJSON
+		}
+		return processor.FormatUnknown
+	}
+	if json.Valid(blob) {
+		return processor.FormatUnknown
+	}
+	objects := 0
 	for _, line := range lines {
-		if !json.Valid([]byte(strings.TrimSpace(line))) {
-			return processor.FormatUnknown
+		if strings.HasPrefix(line, "{") && json.Valid([]byte(line)) {
+			objects++
 		}
 	}
-	if len(lines) == 1 {
-		return processor.FormatJSON
+	if objects*2 <= len(lines) {
+		return processor.FormatUnknown
 	}
 	return processor.FormatJSONLines
 }
@@ -48,6 +48,19 @@ func Test_JsonLinesGuesser(t *testing.T) {
 			{ "abc": "def"}
 		`),
 		expected: processor.FormatJSONLines,
+	}, {
+		name: "JSON Lines with a malformed line",
+		blob: []byte(`{ "abc": "def"}
+			{ "abc":
+			{ "abc": "def"}`),
+		expected: processor.FormatJSONLines,
+	}, {
+		name: "indented JSON with object lines",
+		blob: []byte(`{
+			"a": {},
+			"b": {}
+		}`),
+		expected: processor.FormatUnknown,
 	}, {
 		name:     "invalid JSON Lines",
 		blob:     []byte(`"abc": "def"`),
@@ -0,0 +1,43 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+type tagValueFormatGuesser struct{}
+
+// GuessFormat expects the first line that is neither empty nor a comment to
+// be the `SPDXVersion` tag, which starts every SPDX tag-value document
+func (_ *tagValueFormatGuesser) GuessFormat(blob []byte) processor.FormatType {
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "SPDXVersion:") {
+			return processor.FormatTagValue
+		}
+		break
+	}
+	return processor.FormatUnknown
+}
@@ -17,35 +17,98 @@ package guesser
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/logging"
 )
 
+// AmbiguousDocumentError is returned when the content of a document matches
+// several formats or document types, so that none can be picked safely
+type AmbiguousDocumentError struct {
+	// Kind is what is ambiguous, "format" or "type"
+	Kind string
+	// Candidates are the matching formats or types, each followed by the
+	// guessers that matched it
+	Candidates []string
+}
+
+func (e *AmbiguousDocumentError) Error() string {
+	return fmt.Sprintf("ambiguous document %s, the content matches %s; the collector must set the document %s",
+		e.Kind, strings.Join(e.Candidates, ", "), e.Kind)
+}
+
+// GuessDocument detects the format and the type of the document from its
+// content, when they are unknown. Every guesser is run, so a document that
+// matches several formats or types is rejected with an
+// AmbiguousDocumentError instead of depending on the order of the guessers.
 func GuessDocument(ctx context.Context, d *processor.Document) (processor.DocumentType, processor.FormatType, error) {
 	logger := logging.FromContext(ctx)
 	format := d.Format
 
 	if format == processor.FormatUnknown {
-		for name, g := range documentFormatGuessers {
-			if f := g.GuessFormat(d.Blob); f != processor.FormatUnknown {
-				format = f
+		guesses := map[string][]string{}
+		for _, name := range sortedNames(documentFormatGuessers) {
+			if f := documentFormatGuessers[name].GuessFormat(d.Blob); f != processor.FormatUnknown {
+				guesses[string(f)] = append(guesses[string(f)], name)
 				logger.Debugf("Format guesser %v guessed document format %v", name, f)
-				break
 			}
 		}
+		f, err := pickGuess("format", guesses)
+		if err != nil {
+			return processor.DocumentUnknown, processor.FormatUnknown, err
+		}
+		if f != "" {
+			format = processor.FormatType(f)
+		}
 	}
 
 	documentType := d.Type
 	if documentType == processor.DocumentUnknown {
-		for name, g := range documentTypeGuessers {
-			if t := g.GuessDocumentType(d.Blob, format); t != processor.DocumentUnknown {
-				documentType = t
-				logger.Debugf("DocumentType guesser %v guessed document format %v", name, t)
-				break
+		guesses := map[string][]string{}
+		for _, name := range sortedNames(documentTypeGuessers) {
+			if t := documentTypeGuessers[name].GuessDocumentType(d.Blob, format); t != processor.DocumentUnknown {
+				guesses[string(t)] = append(guesses[string(t)], name)
+				logger.Debugf("DocumentType guesser %v guessed document type %v", name, t)
 			}
 		}
+		t, err := pickGuess("type", guesses)
+		if err != nil {
+			return processor.DocumentUnknown, format, err
+		}
+		if t != "" {
+			documentType = processor.DocumentType(t)
+		}
 	}
 
 	return documentType, format, nil
 }
+
+// pickGuess returns the only guess, or an empty string if there is none. The
+// guesses map each guessed value to the guessers that returned it.
+func pickGuess(kind string, guesses map[string][]string) (string, error) {
+	switch len(guesses) {
+	case 0:
+		return "", nil
+	case 1:
+		for g := range guesses {
+			return g, nil
+		}
+	}
+	candidates := []string{}
+	for _, g := range sortedNames(guesses) {
+		candidates = append(candidates, fmt.Sprintf("%s (guessed by %s)", g, strings.Join(guesses[g], ", ")))
+	}
+	return "", &AmbiguousDocumentError{Kind: kind, Candidates: candidates}
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
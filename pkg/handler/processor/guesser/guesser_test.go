@@ -17,6 +17,7 @@ package guesser
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/guacsec/guac/internal/testing/testdata"
@@ -139,6 +140,96 @@ func Test_GuessDocument(t *testing.T) {
 		},
 		expectedType:   processor.DocumentScorecard,
 		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid OSV Document",
+		document: &processor.Document{
+			Blob:              testdata.OsvExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentOSV,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid lifecycle Document",
+		document: &processor.Document{
+			Blob:              testdata.LifecycleExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentLifecycle,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid SARIF Document",
+		document: &processor.Document{
+			Blob:              testdata.SARIFExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentSARIF,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid dependency snapshot",
+		document: &processor.Document{
+			Blob:              testdata.DepSnapshotExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentDepSnapshot,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid image configuration",
+		document: &processor.Document{
+			Blob:              testdata.OCIImageConfigExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentImageConfig,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid image manifest",
+		document: &processor.Document{
+			Blob:              testdata.OCIImageManifestExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentImageManifest,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid ClearlyDefined Document",
+		document: &processor.Document{
+			Blob:              testdata.ClearlyDefinedExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentClearlyDefined,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid OpenVEX Document",
+		document: &processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentOpenVEX,
+		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid CSAF VEX Document",
+		document: &processor.Document{
+			Blob:              testdata.CsafVEXExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentCSAF,
+		expectedFormat: processor.FormatJSON,
 	}, {
 		name: "valid big cyclonedx Document",
 		document: &processor.Document{
@@ -189,6 +280,16 @@ func Test_GuessDocument(t *testing.T) {
 		},
 		expectedType:   processor.DocumentITE6Vul,
 		expectedFormat: processor.FormatJSON,
+	}, {
+		name: "valid SameAs ITE6 Document",
+		document: &processor.Document{
+			Blob:              testdata.ITE6SameAsExample,
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectedType:   processor.DocumentITE6SameAs,
+		expectedFormat: processor.FormatJSON,
 	}}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -201,3 +302,55 @@ func Test_GuessDocument(t *testing.T) {
 		})
 	}
 }
+
+func Test_GuessDocument_Ambiguous(t *testing.T) {
+	// Both an OSV entry and a scorecard result
+	blob := []byte(`{
+		"id": "GHSA-xxxx-xxxx-xxxx",
+		"modified": "2022-10-01T00:00:00Z",
+		"schema_version": "1.3.0",
+		"scorecard": {"version": "v4.8.0", "commit": "abc"}
+	}`)
+	testCases := []struct {
+		name           string
+		document       *processor.Document
+		wantErr        string
+		expectedType   processor.DocumentType
+		expectedFormat processor.FormatType
+	}{{
+		name: "content matching several types",
+		document: &processor.Document{
+			Blob:   blob,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+		},
+		wantErr: "ambiguous document type, the content matches OSV (guessed by osv), SCORECARD (guessed by scorecard); the collector must set the document type",
+	}, {
+		name: "type set by the collector",
+		document: &processor.Document{
+			Blob:   blob,
+			Type:   processor.DocumentOSV,
+			Format: processor.FormatUnknown,
+		},
+		expectedType:   processor.DocumentOSV,
+		expectedFormat: processor.FormatJSON,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			documentType, documentFormat, err := GuessDocument(context.TODO(), tt.document)
+			if tt.wantErr != "" {
+				var ambiguous *AmbiguousDocumentError
+				if !errors.As(err, &ambiguous) || err.Error() != tt.wantErr {
+					t.Fatalf("GuessDocument() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if documentType != tt.expectedType || documentFormat != tt.expectedFormat {
+				t.Errorf("document type, format: got %v, %v, expected %v, %v", documentType, documentFormat, tt.expectedType, tt.expectedFormat)
+			}
+		})
+	}
+}
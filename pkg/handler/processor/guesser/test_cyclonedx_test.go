@@ -49,6 +49,10 @@ func Test_cyclonedxTypeGuesser_GuessDocumentType(t *testing.T) {
 		name:     "valid alpine cyclonedx Document",
 		blob:     testdata.CycloneDXExampleAlpine,
 		expected: processor.DocumentCycloneDX,
+	}, {
+		name:     "valid cyclonedx 1.5 Document",
+		blob:     testdata.CycloneDXVulnExample,
+		expected: processor.DocumentCycloneDX,
 	}}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -16,9 +16,8 @@
 package guesser
 
 import (
-	"bytes"
+	"encoding/json"
 
-	cdx "github.com/CycloneDX/cyclonedx-go"
 	"github.com/guacsec/guac/pkg/handler/processor"
 )
 
@@ -28,16 +27,26 @@ const (
 	cycloneDXFormat = "CycloneDX"
 )
 
+// cycloneDXSpecVersions are the CycloneDX specification versions that have a
+// JSON representation
+var cycloneDXSpecVersions = map[string]bool{
+	"1.2": true,
+	"1.3": true,
+	"1.4": true,
+	"1.5": true,
+}
+
 func (_ *cycloneDXTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
-	reader := bytes.NewReader(blob)
 	switch format {
 	case processor.FormatJSON:
-		// Decode the BOM
-		bom := new(cdx.BOM)
-		decoder := cdx.NewBOMDecoder(reader, cdx.BOMFileFormatJSON)
-		err := decoder.Decode(bom)
+		// Only decode the header, the full BOM is decoded by the parser
+		var header struct {
+			BOMFormat   string `json:"bomFormat"`
+			SpecVersion string `json:"specVersion"`
+		}
+		err := json.Unmarshal(blob, &header)
 		if err == nil {
-			if bom.BOMFormat == cycloneDXFormat {
+			if header.BOMFormat == cycloneDXFormat && cycloneDXSpecVersions[header.SpecVersion] {
 				return processor.DocumentCycloneDX
 			}
 		}
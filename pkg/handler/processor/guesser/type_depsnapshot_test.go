@@ -0,0 +1,62 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func Test_depSnapshotTypeGuesser_GuessDocumentType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		blob     []byte
+		expected processor.DocumentType
+	}{{
+		name: "invalid dependency snapshot",
+		blob: []byte(`{
+			"abc": "def"
+		}`),
+		expected: processor.DocumentUnknown,
+	}, {
+		name:     "dependency snapshot without detector name",
+		blob:     testdata.DepSnapshotInvalid,
+		expected: processor.DocumentUnknown,
+	}, {
+		name:     "dependency snapshot without manifests",
+		blob:     []byte(`{"sha": "ce587453ced02b1526dfb4cb910479d431683101", "detector": {"name": "component-detection"}}`),
+		expected: processor.DocumentUnknown,
+	}, {
+		name:     "OSV Document",
+		blob:     testdata.OsvExample,
+		expected: processor.DocumentUnknown,
+	}, {
+		name:     "valid dependency snapshot",
+		blob:     testdata.DepSnapshotExample,
+		expected: processor.DocumentDepSnapshot,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			guesser := &depSnapshotTypeGuesser{}
+			f := guesser.GuessDocumentType(tt.blob, processor.FormatJSON)
+			if f != tt.expected {
+				t.Errorf("got the wrong format, got %v, expected %v", f, tt.expected)
+			}
+		})
+	}
+}
@@ -24,9 +24,22 @@ import (
 func init() {
 	_ = RegisterDocumentTypeGuesser(&ite6TypeGuesser{}, "ite6")
 	_ = RegisterDocumentTypeGuesser(&dsseTypeGuesser{}, "dsse")
+	_ = RegisterDocumentTypeGuesser(&sigstoreBundleTypeGuesser{}, "sigstore_bundle")
 	_ = RegisterDocumentTypeGuesser(&spdxTypeGuesser{}, "spdx")
 	_ = RegisterDocumentTypeGuesser(&scorecardTypeGuesser{}, "scorecard")
 	_ = RegisterDocumentTypeGuesser(&cycloneDXTypeGuesser{}, "cyclonedx")
+	_ = RegisterDocumentTypeGuesser(&osvTypeGuesser{}, "osv")
+	_ = RegisterDocumentTypeGuesser(&openVEXTypeGuesser{}, "openvex")
+	_ = RegisterDocumentTypeGuesser(&csafTypeGuesser{}, "csaf")
+	_ = RegisterDocumentTypeGuesser(&inTotoTypeGuesser{}, "intoto")
+	_ = RegisterDocumentTypeGuesser(&syftTypeGuesser{}, "syft")
+	_ = RegisterDocumentTypeGuesser(&trivyTypeGuesser{}, "trivy")
+	_ = RegisterDocumentTypeGuesser(&lifecycleTypeGuesser{}, "lifecycle")
+	_ = RegisterDocumentTypeGuesser(&clearlyDefinedTypeGuesser{}, "clearlydefined")
+	_ = RegisterDocumentTypeGuesser(&sarifTypeGuesser{}, "sarif")
+	_ = RegisterDocumentTypeGuesser(&depSnapshotTypeGuesser{}, "depsnapshot")
+	_ = RegisterDocumentTypeGuesser(&ociImageTypeGuesser{}, "ociimage")
+	_ = RegisterDocumentTypeGuesser(&jsonLinesTypeGuesser{}, "jsonlines")
 }
 
 // DocumentTypeGuesser guesses the document type based on the blob and format given
@@ -47,3 +60,9 @@ func RegisterDocumentTypeGuesser(g DocumentTypeGuesser, name string) error {
 	documentTypeGuessers[name] = g
 	return nil
 }
+
+// DocumentTypeGuessers returns the names of the registered document type
+// guessers, in order
+func DocumentTypeGuessers() []string {
+	return sortedNames(documentTypeGuessers)
+}
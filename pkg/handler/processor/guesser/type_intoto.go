@@ -0,0 +1,49 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"encoding/json"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/intoto"
+)
+
+type inTotoTypeGuesser struct{}
+
+func (_ *inTotoTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
+	switch format {
+	case processor.FormatJSON:
+		// Links and layouts are both wrapped in a metablock, only decode
+		// the type of the signed part
+		var metablock struct {
+			Signed struct {
+				Type string `json:"_type"`
+			} `json:"signed"`
+			Signatures json.RawMessage `json:"signatures"`
+		}
+		if json.Unmarshal(blob, &metablock) != nil || metablock.Signatures == nil {
+			return processor.DocumentUnknown
+		}
+		switch metablock.Signed.Type {
+		case intoto.TypeLink:
+			return processor.DocumentInTotoLink
+		case intoto.TypeLayout:
+			return processor.DocumentInTotoLayout
+		}
+	}
+	return processor.DocumentUnknown
+}
@@ -37,6 +37,8 @@ func (_ *ite6TypeGuesser) GuessDocumentType(blob []byte, format processor.Format
 				return processor.DocumentITE6Generic
 			} else if strings.HasPrefix(statement.PredicateType, "https://in-toto.io/attestation/vuln/v0.1") {
 				return processor.DocumentITE6Vul
+			} else if strings.HasPrefix(statement.PredicateType, "https://in-toto.io/attestation/same-as/v0.1") {
+				return processor.DocumentITE6SameAs
 			}
 			return processor.DocumentITE6Generic
 		}
@@ -43,6 +43,14 @@ func Test_Ite6TypeGuesser(t *testing.T) {
 		name:     "valid SLSA ITE6 Document with different versions",
 		blob:     []byte(`{"_type": "https://in-toto.io/Statement/v1.1", "predicateType": "https://slsa.dev/provenance/v1.0"}`),
 		expected: processor.DocumentITE6SLSA,
+	}, {
+		name:     "valid SLSA v1.0 ITE6 Document",
+		blob:     testdata.Ite6SLSAV1Doc.Blob,
+		expected: processor.DocumentITE6SLSA,
+	}, {
+		name:     "valid Tekton Chains SLSA ITE6 Document",
+		blob:     testdata.TektonPipelineRunExample,
+		expected: processor.DocumentITE6SLSA,
 	}, {
 		name:     "valid CREV ITE6 Document",
 		blob:     testdata.ITE6CREVExample,
@@ -55,6 +63,10 @@ func Test_Ite6TypeGuesser(t *testing.T) {
 		name:     "valid Vuln ITE6 Document",
 		blob:     testdata.ITE6VulnExample,
 		expected: processor.DocumentITE6Vul,
+	}, {
+		name:     "valid SameAs ITE6 Document",
+		blob:     testdata.ITE6SameAsExample,
+		expected: processor.DocumentITE6SameAs,
 	}}
 
 	for _, tt := range testCases {
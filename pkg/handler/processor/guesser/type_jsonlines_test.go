@@ -0,0 +1,52 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func Test_jsonLinesTypeGuesser_GuessDocumentType(t *testing.T) {
+	blob := []byte("{\"abc\": \"def\"}\n{\"abc\": \"def\"}")
+	testCases := []struct {
+		name     string
+		format   processor.FormatType
+		expected processor.DocumentType
+	}{{
+		name:     "JSON Lines",
+		format:   processor.FormatJSONLines,
+		expected: processor.DocumentJsonLines,
+	}, {
+		name:     "JSON",
+		format:   processor.FormatJSON,
+		expected: processor.DocumentUnknown,
+	}, {
+		name:     "unknown format",
+		format:   processor.FormatUnknown,
+		expected: processor.DocumentUnknown,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			guesser := &jsonLinesTypeGuesser{}
+			f := guesser.GuessDocumentType(blob, tt.format)
+			if f != tt.expected {
+				t.Errorf("got the wrong format, got %v, expected %v", f, tt.expected)
+			}
+		})
+	}
+}
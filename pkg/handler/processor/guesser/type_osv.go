@@ -0,0 +1,35 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"encoding/json"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/osv"
+)
+
+type osvTypeGuesser struct{}
+
+func (_ *osvTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
+	var entry osv.Entry
+	if json.Unmarshal(blob, &entry) == nil && format == processor.FormatJSON {
+		if entry.ID != "" && entry.Modified != "" && (len(entry.Affected) > 0 || entry.SchemaVersion != "") {
+			return processor.DocumentOSV
+		}
+	}
+	return processor.DocumentUnknown
+}
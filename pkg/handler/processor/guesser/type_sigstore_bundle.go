@@ -0,0 +1,39 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/bundle"
+)
+
+type sigstoreBundleTypeGuesser struct{}
+
+// GuessDocumentType recognizes the Sigstore bundles holding a DSSE envelope,
+// as written by `cosign attest-blob --new-bundle-format --bundle`
+func (_ *sigstoreBundleTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
+	if format != processor.FormatJSON {
+		return processor.DocumentUnknown
+	}
+	var b bundle.Bundle
+	if json.Unmarshal(blob, &b) == nil && strings.HasPrefix(b.MediaType, bundle.MediaTypePrefix) && len(b.DSSEEnvelope) > 0 {
+		return processor.DocumentSigstoreBundle
+	}
+	return processor.DocumentUnknown
+}
@@ -26,6 +26,7 @@ func Test_spdxTypeGuesser_GuessDocumentType(t *testing.T) {
 	testCases := []struct {
 		name     string
 		blob     []byte
+		format   processor.FormatType
 		expected processor.DocumentType
 	}{{
 		name: "invalid spdx Document",
@@ -45,11 +46,20 @@ func Test_spdxTypeGuesser_GuessDocumentType(t *testing.T) {
 		name:     "valid big spdx Document",
 		blob:     testdata.SpdxExampleBig,
 		expected: processor.DocumentSPDX,
+	}, {
+		name:     "valid tag-value spdx 2.3 Document",
+		blob:     testdata.SpdxTagValueExample,
+		format:   processor.FormatTagValue,
+		expected: processor.DocumentSPDX,
 	}}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			if format == "" {
+				format = processor.FormatJSON
+			}
 			guesser := &spdxTypeGuesser{}
-			f := guesser.GuessDocumentType(tt.blob, processor.FormatJSON)
+			f := guesser.GuessDocumentType(tt.blob, format)
 			if f != tt.expected {
 				t.Errorf("got the wrong format, got %v, expected %v", f, tt.expected)
 			}
@@ -0,0 +1,42 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"encoding/json"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/syft"
+)
+
+type syftTypeGuesser struct{}
+
+func (_ *syftTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
+	switch format {
+	case processor.FormatJSON:
+		// Only decode the header, the packages are decoded by the parser
+		var header struct {
+			Artifacts  json.RawMessage `json:"artifacts"`
+			Descriptor struct {
+				Name string `json:"name"`
+			} `json:"descriptor"`
+		}
+		if json.Unmarshal(blob, &header) == nil && header.Descriptor.Name == syft.DescriptorName && header.Artifacts != nil {
+			return processor.DocumentSyft
+		}
+	}
+	return processor.DocumentUnknown
+}
@@ -0,0 +1,40 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesser
+
+import (
+	"encoding/json"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+type trivyTypeGuesser struct{}
+
+func (_ *trivyTypeGuesser) GuessDocumentType(blob []byte, format processor.FormatType) processor.DocumentType {
+	switch format {
+	case processor.FormatJSON:
+		// Only decode the header, the results are decoded by the parser
+		var header struct {
+			SchemaVersion int    `json:"SchemaVersion"`
+			ArtifactName  string `json:"ArtifactName"`
+			ArtifactType  string `json:"ArtifactType"`
+		}
+		if json.Unmarshal(blob, &header) == nil && header.SchemaVersion > 0 && header.ArtifactName != "" && header.ArtifactType != "" {
+			return processor.DocumentTrivy
+		}
+	}
+	return processor.DocumentUnknown
+}
@@ -0,0 +1,162 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// Values of the `_type` field of the signed part of in-toto metadata
+const (
+	TypeLink   = "link"
+	TypeLayout = "layout"
+)
+
+// LinkMetablock is a signed in-toto link, which records the materials and
+// the products of a step of the supply chain, as described at
+// https://github.com/in-toto/docs/blob/master/in-toto-spec.md
+type LinkMetablock struct {
+	Signed     in_toto.Link        `json:"signed"`
+	Signatures []in_toto.Signature `json:"signatures"`
+}
+
+// LayoutMetablock is a signed in-toto layout, which defines the steps of the
+// supply chain and the functionaries allowed to perform them
+type LayoutMetablock struct {
+	Signed     in_toto.Layout      `json:"signed"`
+	Signatures []in_toto.Signature `json:"signatures"`
+}
+
+// ParseLink decodes the in-toto link in the blob
+func ParseLink(blob []byte) (*LinkMetablock, error) {
+	var mb LinkMetablock
+	if err := json.Unmarshal(blob, &mb); err != nil {
+		return nil, err
+	}
+	if mb.Signed.Type != TypeLink {
+		return nil, fmt.Errorf("expected in-toto metadata of type %q, got %q", TypeLink, mb.Signed.Type)
+	}
+	return &mb, nil
+}
+
+// ParseLayout decodes the in-toto layout in the blob
+func ParseLayout(blob []byte) (*LayoutMetablock, error) {
+	var mb LayoutMetablock
+	if err := json.Unmarshal(blob, &mb); err != nil {
+		return nil, err
+	}
+	if mb.Signed.Type != TypeLayout {
+		return nil, fmt.Errorf("expected in-toto metadata of type %q, got %q", TypeLayout, mb.Signed.Type)
+	}
+	return &mb, nil
+}
+
+// ArtifactDigests returns the digest sets of the materials or products of a
+// link, keyed by artifact path. Each digest set maps the hash algorithm to
+// the hex value of the hash.
+func ArtifactDigests(artifacts map[string]interface{}) (map[string]map[string]string, error) {
+	digests := map[string]map[string]string{}
+	for path, artifact := range artifacts {
+		hashes, ok := artifact.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("artifact %s has no digest set", path)
+		}
+		digests[path] = map[string]string{}
+		for alg, value := range hashes {
+			v, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("artifact %s has an invalid %s hash", path, alg)
+			}
+			digests[path][alg] = v
+		}
+	}
+	return digests, nil
+}
+
+// InTotoProcessor processes in-toto links and layouts.
+// Currently only supports JSON documents
+type InTotoProcessor struct {
+}
+
+func (p *InTotoProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentInTotoLink && d.Type != processor.DocumentInTotoLayout {
+		return fmt.Errorf("expected in-toto document type, actual document type: %v", d.Type)
+	}
+	if d.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of in-toto document format: %v", d.Format)
+	}
+
+	if d.Type == processor.DocumentInTotoLink {
+		return validateLink(d.Blob)
+	}
+	return validateLayout(d.Blob)
+}
+
+func validateLink(blob []byte) error {
+	mb, err := ParseLink(blob)
+	if err != nil {
+		return err
+	}
+	if mb.Signed.Name == "" {
+		return fmt.Errorf("in-toto link has no step name")
+	}
+	if _, err := ArtifactDigests(mb.Signed.Materials); err != nil {
+		return fmt.Errorf("invalid materials: %w", err)
+	}
+	if _, err := ArtifactDigests(mb.Signed.Products); err != nil {
+		return fmt.Errorf("invalid products: %w", err)
+	}
+	return nil
+}
+
+func validateLayout(blob []byte) error {
+	mb, err := ParseLayout(blob)
+	if err != nil {
+		return err
+	}
+	if mb.Signed.Expires == "" {
+		return fmt.Errorf("in-toto layout has no expiration date")
+	}
+	names := map[string]bool{}
+	for i, s := range mb.Signed.Steps {
+		if s.Name == "" {
+			return fmt.Errorf("step %d of in-toto layout has no name", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("in-toto layout has duplicate step %s", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *InTotoProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentInTotoLink && d.Type != processor.DocumentInTotoLayout {
+		return nil, fmt.Errorf("expected in-toto document type, actual document type: %v", d.Type)
+	}
+
+	// Links and layouts don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
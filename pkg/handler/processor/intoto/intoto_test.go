@@ -0,0 +1,149 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestInTotoProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "in-toto link",
+		doc: processor.Document{
+			Blob:              testdata.InTotoPackageLinkExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentInTotoLink,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "in-toto layout",
+		doc: processor.Document{
+			Blob:              testdata.InTotoLayoutExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentInTotoLayout,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.InTotoPackageLinkExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := InTotoProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("InTotoProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("InTotoProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInTotoProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expectErr bool
+	}{{
+		name: "valid link",
+		doc: processor.Document{
+			Blob:   testdata.InTotoPackageLinkExample,
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLink,
+		},
+		expectErr: false,
+	}, {
+		name: "valid layout",
+		doc: processor.Document{
+			Blob:   testdata.InTotoLayoutExample,
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLayout,
+		},
+		expectErr: false,
+	}, {
+		name: "layout given as link",
+		doc: processor.Document{
+			Blob:   testdata.InTotoLayoutExample,
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLink,
+		},
+		expectErr: true,
+	}, {
+		name: "link without name",
+		doc: processor.Document{
+			Blob:   []byte(`{"signed": {"_type": "link", "materials": {}, "products": {}}, "signatures": []}`),
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLink,
+		},
+		expectErr: true,
+	}, {
+		name: "link with invalid digest set",
+		doc: processor.Document{
+			Blob:   []byte(`{"signed": {"_type": "link", "name": "build", "materials": {"foo.py": "74dc"}}, "signatures": []}`),
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLink,
+		},
+		expectErr: true,
+	}, {
+		name: "layout with duplicate steps",
+		doc: processor.Document{
+			Blob: []byte(`{"signed": {"_type": "layout", "expires": "2030-11-18T16:06:36Z",
+				"steps": [{"_type": "step", "name": "build"}, {"_type": "step", "name": "build"}]}, "signatures": []}`),
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentInTotoLayout,
+		},
+		expectErr: true,
+	}, {
+		name: "invalid format supported",
+		doc: processor.Document{
+			Blob:   testdata.InTotoPackageLinkExample,
+			Format: processor.FormatUnknown,
+			Type:   processor.DocumentInTotoLink,
+		},
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := InTotoProcessor{}
+			err := d.ValidateSchema(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("InTotoProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -28,7 +28,7 @@ type ITE6Processor struct {
 
 // ValidateSchema ensures that the document blob can be parsed into a valid data structure
 func (e *ITE6Processor) ValidateSchema(i *processor.Document) error {
-	if i.Type != processor.DocumentITE6Generic && i.Type != processor.DocumentITE6SLSA && i.Type != processor.DocumentITE6Vul {
+	if i.Type != processor.DocumentITE6Generic && i.Type != processor.DocumentITE6SLSA && i.Type != processor.DocumentITE6Vul && i.Type != processor.DocumentITE6SameAs {
 		return fmt.Errorf("expected ITE6 document type, actual document type: %v", i.Type)
 	}
 
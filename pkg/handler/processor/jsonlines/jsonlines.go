@@ -16,21 +16,25 @@
 package jsonlines
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
 )
 
+// JsonLinesProcessor unpacks JSON Lines documents (e.g. `.intoto.jsonl`
+// files) into one document per non-empty line. Lines are not validated here,
+// so that a malformed line only fails its own document.
 type JsonLinesProcessor struct{}
 
 func (d *JsonLinesProcessor) ValidateSchema(i *processor.Document) error {
 	if i.Type != processor.DocumentJsonLines {
 		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentJsonLines, i.Type)
 	}
-	_, err := parseJsonLines(i.Blob)
-	return err
+	if len(splitLines(i.Blob)) == 0 {
+		return fmt.Errorf("unable to parse JSON Lines file: no lines")
+	}
+	return nil
 }
 
 // Unpack takes in the document and tries to unpack it
@@ -39,37 +43,41 @@ func (d *JsonLinesProcessor) ValidateSchema(i *processor.Document) error {
 // For example, a DSSE envelope or a tarball
 // Returns empty list and nil error if nothing to unpack
 // Returns unpacked list and nil error if successfully unpacked
+//
+// Each line is unpacked with its line number in the SourceInformation.
 func (d *JsonLinesProcessor) Unpack(i *processor.Document) ([]*processor.Document, error) {
 	if i.Type != processor.DocumentJsonLines {
 		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentJsonLines, i.Type)
 	}
 
-	lines, err := parseJsonLines(i.Blob)
-	if err != nil {
-		return nil, err
-	}
-
+	lines := splitLines(i.Blob)
 	documents := make([]*processor.Document, len(lines))
 	for idx, line := range lines {
+		source := i.SourceInformation
+		source.Line = line.number
 		documents[idx] = &processor.Document{
-			Blob:              line,
+			Blob:              line.blob,
 			Type:              processor.DocumentUnknown,
 			Format:            processor.FormatJSON,
-			SourceInformation: i.SourceInformation,
+			SourceInformation: source,
 		}
 	}
 	return documents, nil
 }
 
-func parseJsonLines(b []byte) ([][]byte, error) {
-	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
-	blines := [][]byte{}
-	for idx, line := range lines {
-		bline := []byte(strings.TrimSpace(line))
-		if !json.Valid(bline) {
-			return nil, fmt.Errorf("unable to parse JSON Lines file. line %d is invalid json", idx)
+type line struct {
+	// number is the line number, starting at 1
+	number int
+	blob   []byte
+}
+
+// splitLines returns the non-empty lines of b, without surrounding spaces
+func splitLines(b []byte) []line {
+	lines := []line{}
+	for idx, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, line{number: idx + 1, blob: []byte(l)})
 		}
-		blines = append(blines, bline)
 	}
-	return blines, nil
+	return lines
 }
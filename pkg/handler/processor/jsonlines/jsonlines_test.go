@@ -44,18 +44,18 @@ var (
 			Source:    "TestSource",
 		},
 	}
-	unpackedJsonLinesUnknownDSSEDoc = processor.Document{
-		Blob:   []byte(singleLineDSSE),
-		Type:   processor.DocumentUnknown,
-		Format: processor.FormatJSON,
+	singleLineJson  = `{"a": "b"}`
+	jsonLinesSimple = processor.Document{
+		Blob:   []byte(fmt.Sprintf("%s\n%s", singleLineJson, singleLineJson)),
+		Type:   processor.DocumentJsonLines,
+		Format: processor.FormatJSONLines,
 		SourceInformation: processor.SourceInformation{
 			Collector: "TestCollector",
 			Source:    "TestSource",
 		},
 	}
-	singleLineJson  = `{"a": "b"}`
-	jsonLinesSimple = processor.Document{
-		Blob:   []byte(fmt.Sprintf("%s\n%s", singleLineJson, singleLineJson)),
+	jsonLinesMalformed = processor.Document{
+		Blob:   []byte(fmt.Sprintf("%s\n\nnot valid JSON\n%s\n", singleLineJson, singleLineJson)),
 		Type:   processor.DocumentJsonLines,
 		Format: processor.FormatJSONLines,
 		SourceInformation: processor.SourceInformation{
@@ -63,10 +63,10 @@ var (
 			Source:    "TestSource",
 		},
 	}
-	unpackedJsonLinesSimple = processor.Document{
-		Blob:   []byte(singleLineJson),
-		Type:   processor.DocumentUnknown,
-		Format: processor.FormatJSON,
+	jsonLinesEmpty = processor.Document{
+		Blob:   []byte("\n  \n"),
+		Type:   processor.DocumentJsonLines,
+		Format: processor.FormatJSONLines,
 		SourceInformation: processor.SourceInformation{
 			Collector: "TestCollector",
 			Source:    "TestSource",
@@ -83,6 +83,20 @@ var (
 	}
 )
 
+// unpacked returns the document unpacked from the line of a test document
+func unpacked(blob string, line int) *processor.Document {
+	return &processor.Document{
+		Blob:   []byte(blob),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{
+			Collector: "TestCollector",
+			Source:    "TestSource",
+			Line:      line,
+		},
+	}
+}
+
 func TestJsonLinesProcessor_Unpack(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -92,12 +106,17 @@ func TestJsonLinesProcessor_Unpack(t *testing.T) {
 	}{{
 		name:      "JSON Lines with DSSE Unknown Payload entries",
 		doc:       jsonLinesUnknownDSSEDoc,
-		expected:  []*processor.Document{&unpackedJsonLinesUnknownDSSEDoc, &unpackedJsonLinesUnknownDSSEDoc},
+		expected:  []*processor.Document{unpacked(singleLineDSSE, 1), unpacked(singleLineDSSE, 2)},
 		expectErr: false,
 	}, {
 		name:      "JSON Lines with random json entries",
 		doc:       jsonLinesSimple,
-		expected:  []*processor.Document{&unpackedJsonLinesSimple, &unpackedJsonLinesSimple},
+		expected:  []*processor.Document{unpacked(singleLineJson, 1), unpacked(singleLineJson, 2)},
+		expectErr: false,
+	}, {
+		name:      "malformed lines are unpacked, empty lines are skipped",
+		doc:       jsonLinesMalformed,
+		expected:  []*processor.Document{unpacked(singleLineJson, 1), unpacked("not valid JSON", 3), unpacked(singleLineJson, 4)},
 		expectErr: false,
 	}, {
 		name:      "Incorrect type",
@@ -128,6 +147,14 @@ func TestDSSEProcessor_ValidateSchema(t *testing.T) {
 		name:      "Valid JSON Lines",
 		doc:       jsonLinesUnknownDSSEDoc,
 		expectErr: false,
+	}, {
+		name:      "Malformed lines",
+		doc:       jsonLinesMalformed,
+		expectErr: false,
+	}, {
+		name:      "No lines",
+		doc:       jsonLinesEmpty,
+		expectErr: true,
 	}, {
 		name:      "Invalid JSON Lines",
 		doc:       incorrectTypeDoc,
@@ -0,0 +1,206 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// DateLayout is the layout of the dates of lifecycle documents
+const DateLayout = "2006-01-02"
+
+// Document gives the release cycles of a product, as published by
+// https://endoflife.date, together with the purls (without version) of the
+// packages distributing the product. The cycles are those returned by the
+// endoflife.date API (`https://endoflife.date/api/<product>.json`), so they
+// can be copied as is.
+type Document struct {
+	Product string   `json:"product,omitempty"`
+	Purls   []string `json:"purls"`
+	Cycles  []Cycle  `json:"cycles"`
+}
+
+// Cycle is a release cycle of the product, covering the versions starting
+// with the cycle (e.g. cycle `3.11` covers the versions `3.11.0`, `3.11.4`...)
+type Cycle struct {
+	Cycle       string    `json:"cycle"`
+	ReleaseDate string    `json:"releaseDate,omitempty"`
+	EOL         Milestone `json:"eol"`
+	Support     Milestone `json:"support,omitempty"`
+	LTS         Milestone `json:"lts,omitempty"`
+	Latest      string    `json:"latest,omitempty"`
+}
+
+// Milestone is the value of the endoflife.date fields that are either the
+// date of the milestone, or a boolean telling whether the milestone is
+// reached when the date is unknown (e.g. `"eol": false`).
+type Milestone struct {
+	Date    string
+	Reached bool
+}
+
+func (m *Milestone) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*m = Milestone{}
+		return nil
+	}
+	var date string
+	if err := json.Unmarshal(b, &date); err == nil {
+		*m = Milestone{Date: date}
+		return nil
+	}
+	var reached bool
+	if err := json.Unmarshal(b, &reached); err != nil {
+		return fmt.Errorf("expected a date or a boolean, got %s", b)
+	}
+	*m = Milestone{Reached: reached}
+	return nil
+}
+
+// ReachedAt returns whether the milestone is reached at the given time
+func (m Milestone) ReachedAt(t time.Time) bool {
+	if m.Date == "" {
+		return m.Reached
+	}
+	date, err := time.Parse(DateLayout, m.Date)
+	if err != nil {
+		return false
+	}
+	return !t.Before(date)
+}
+
+// BasePurl returns the purl without version, qualifiers and subpath, which
+// is the purl lifecycle documents give their cycles for, and the version
+func BasePurl(purl string) (string, string) {
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, _, _ = strings.Cut(purl, "?")
+	if i := strings.LastIndex(purl, "@"); i > strings.LastIndex(purl, "/") {
+		return purl[:i], purl[i+1:]
+	}
+	return purl, ""
+}
+
+// MatchCycle returns the cycle covering the version, or false if there is
+// none. When cycles are nested (e.g. `3` and `3.11`), the most specific one
+// wins.
+func MatchCycle(version string, cycles []string) (string, bool) {
+	match, found := "", false
+	for _, c := range cycles {
+		if InCycle(version, c) && (!found || len(strings.Split(c, ".")) > len(strings.Split(match, "."))) {
+			match, found = c, true
+		}
+	}
+	return match, found
+}
+
+// InCycle returns whether the version belongs to the release cycle, i.e.
+// whether the dot-separated components of the cycle are the first ones of the
+// version. A leading `v` and the epoch of distribution versions (`1:`) are
+// ignored, and the last component of the cycle may be followed by a suffix
+// in the version (e.g. `18.17-r0` is in cycle `18.17`, `18.170` is not).
+func InCycle(version string, cycle string) bool {
+	if version == "" || cycle == "" {
+		return false
+	}
+	if i := strings.Index(version, ":"); i >= 0 {
+		version = version[i+1:]
+	}
+	version = strings.TrimPrefix(version, "v")
+	cycle = strings.TrimPrefix(cycle, "v")
+
+	versionParts := strings.Split(version, ".")
+	cycleParts := strings.Split(cycle, ".")
+	if len(cycleParts) > len(versionParts) {
+		return false
+	}
+	for i, c := range cycleParts {
+		v := versionParts[i]
+		if v == c {
+			continue
+		}
+		if i == len(cycleParts)-1 && strings.HasPrefix(v, c) && !isDigit(v[len(c)]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// LifecycleProcessor processes lifecycle documents.
+// Currently only supports JSON documents
+type LifecycleProcessor struct {
+}
+
+func (p *LifecycleProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentLifecycle {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentLifecycle, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var doc Document
+		if err := json.Unmarshal(d.Blob, &doc); err != nil {
+			return err
+		}
+		if len(doc.Purls) == 0 || len(doc.Cycles) == 0 {
+			return fmt.Errorf("missing required lifecycle fields")
+		}
+		for _, purl := range doc.Purls {
+			if !strings.HasPrefix(purl, "pkg:") {
+				return fmt.Errorf("invalid purl %q", purl)
+			}
+		}
+		for _, c := range doc.Cycles {
+			if c.Cycle == "" {
+				return fmt.Errorf("missing cycle of release cycle")
+			}
+			for _, date := range []string{c.ReleaseDate, c.EOL.Date, c.Support.Date, c.LTS.Date} {
+				if date == "" {
+					continue
+				}
+				if _, err := time.Parse(DateLayout, date); err != nil {
+					return fmt.Errorf("invalid date in cycle %s: %w", c.Cycle, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of lifecycle document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *LifecycleProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentLifecycle {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentLifecycle, d.Type)
+	}
+
+	// Lifecycle documents don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
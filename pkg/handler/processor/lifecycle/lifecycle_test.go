@@ -0,0 +1,231 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestLifecycleProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "lifecycle document",
+		doc: processor.Document{
+			Blob:              testdata.LifecycleExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentLifecycle,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.LifecycleExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := LifecycleProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("LifecycleProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("LifecycleProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLifecycleProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		format    processor.FormatType
+		expectErr bool
+	}{{
+		name:      "valid lifecycle document",
+		blob:      testdata.LifecycleExample,
+		format:    processor.FormatJSON,
+		expectErr: false,
+	}, {
+		name:      "cycle without name",
+		blob:      testdata.LifecycleInvalid,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "bare endoflife.date cycles",
+		blob:      []byte(`[{"cycle": "4.2", "eol": "2026-04-30"}]`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid purl",
+		blob:      []byte(`{"purls": ["pypi/django"], "cycles": [{"cycle": "4.2", "eol": "2026-04-30"}]}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid date",
+		blob:      []byte(`{"purls": ["pkg:pypi/django"], "cycles": [{"cycle": "4.2", "eol": "April 2026"}]}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid format supported",
+		blob:      testdata.LifecycleExample,
+		format:    processor.FormatUnknown,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := LifecycleProcessor{}
+			err := d.ValidateSchema(&processor.Document{
+				Blob:   tt.blob,
+				Format: tt.format,
+				Type:   processor.DocumentLifecycle,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("LifecycleProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestMilestone(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name         string
+		json         string
+		expected     Milestone
+		reachedAtNow bool
+		expectErr    bool
+	}{{
+		name:         "past date",
+		json:         `"2024-04-30"`,
+		expected:     Milestone{Date: "2024-04-30"},
+		reachedAtNow: true,
+	}, {
+		name:         "same day",
+		json:         `"2024-06-01"`,
+		expected:     Milestone{Date: "2024-06-01"},
+		reachedAtNow: true,
+	}, {
+		name:     "future date",
+		json:     `"2026-04-30"`,
+		expected: Milestone{Date: "2026-04-30"},
+	}, {
+		name:         "reached",
+		json:         `true`,
+		expected:     Milestone{Reached: true},
+		reachedAtNow: true,
+	}, {
+		name:     "not reached",
+		json:     `false`,
+		expected: Milestone{},
+	}, {
+		name:     "null",
+		json:     `null`,
+		expected: Milestone{},
+	}, {
+		name:      "number",
+		json:      `2026`,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Milestone
+			err := json.Unmarshal([]byte(tt.json), &m)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("json.Unmarshal() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expectErr {
+				return
+			}
+			if m != tt.expected {
+				t.Errorf("json.Unmarshal() = %+v, expected %+v", m, tt.expected)
+			}
+			if got := m.ReachedAt(now); got != tt.reachedAtNow {
+				t.Errorf("Milestone.ReachedAt() = %v, expected %v", got, tt.reachedAtNow)
+			}
+		})
+	}
+}
+
+func TestMatchCycle(t *testing.T) {
+	cycles := []string{"3", "3.11", "3.1", "18", "5.0"}
+	testCases := []struct {
+		version  string
+		expected string
+		found    bool
+	}{
+		{version: "3.11.4", expected: "3.11", found: true},
+		{version: "3.11", expected: "3.11", found: true},
+		{version: "3.10.2", expected: "3", found: true},
+		{version: "3.1.2", expected: "3.1", found: true},
+		{version: "v3.1.2", expected: "3.1", found: true},
+		{version: "18.17.1-r0", expected: "18", found: true},
+		{version: "1:18.17.1", expected: "18", found: true},
+		{version: "5.0-rc1", expected: "5.0", found: true},
+		{version: "5.01", found: false},
+		{version: "5", found: false},
+		{version: "30.1", found: false},
+		{version: "", found: false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.version, func(t *testing.T) {
+			got, found := MatchCycle(tt.version, cycles)
+			if got != tt.expected || found != tt.found {
+				t.Errorf("MatchCycle() = %q, %v, expected %q, %v", got, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+func TestBasePurl(t *testing.T) {
+	testCases := []struct {
+		purl    string
+		base    string
+		version string
+	}{
+		{purl: "pkg:pypi/django@4.2.1", base: "pkg:pypi/django", version: "4.2.1"},
+		{purl: "pkg:pypi/django", base: "pkg:pypi/django"},
+		{purl: "pkg:npm/%40babel/core@7.0.0", base: "pkg:npm/%40babel/core", version: "7.0.0"},
+		{purl: "pkg:deb/debian/openssl@3.0.11-1?arch=amd64&distro=debian-12", base: "pkg:deb/debian/openssl", version: "3.0.11-1"},
+		{purl: "pkg:golang/github.com/a/b@v1.2.0#sub/dir", base: "pkg:golang/github.com/a/b", version: "v1.2.0"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.purl, func(t *testing.T) {
+			base, version := BasePurl(tt.purl)
+			if base != tt.base || version != tt.version {
+				t.Errorf("BasePurl() = %q, %q, expected %q, %q", base, version, tt.base, tt.version)
+			}
+		})
+	}
+}
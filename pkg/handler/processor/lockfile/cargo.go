@@ -0,0 +1,153 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// cargoPackage is a `[[package]]` of a Cargo.lock
+type cargoPackage struct {
+	name, version, source string
+	dependencies          []string
+}
+
+// parseCargoLock returns the packages of the Cargo.lock. The packages without
+// source are the members of the workspace; when there is only one, it is the
+// root. Cargo.lock is TOML, but is written by cargo in a fixed layout, which
+// is all that is parsed.
+func parseCargoLock(blob []byte) (*Lockfile, error) {
+	packages := []*cargoPackage{}
+	var current *cargoPackage
+	inDependencies := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if inDependencies {
+			if strings.HasPrefix(line, "]") {
+				inDependencies = false
+				continue
+			}
+			current.dependencies = append(current.dependencies, unquoteTOML(strings.TrimSuffix(line, ",")))
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			current = nil
+			if line == "[[package]]" {
+				current = &cargoPackage{}
+				packages = append(packages, current)
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid Cargo.lock line: %q", line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "name":
+			current.name = unquoteTOML(value)
+		case "version":
+			current.version = unquoteTOML(value)
+		case "source":
+			current.source = unquoteTOML(value)
+		case "dependencies":
+			if !strings.HasPrefix(value, "[") {
+				return nil, fmt.Errorf("invalid Cargo.lock dependencies: %q", line)
+			}
+			value = strings.TrimPrefix(value, "[")
+			if strings.HasSuffix(value, "]") {
+				for _, d := range strings.Split(strings.TrimSuffix(value, "]"), ",") {
+					if d = strings.TrimSpace(d); d != "" {
+						current.dependencies = append(current.dependencies, unquoteTOML(d))
+					}
+				}
+			} else {
+				inDependencies = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("missing packages in Cargo.lock")
+	}
+
+	// dependencies are given by name when only one version of the package
+	// is locked, and by name and version (and source) otherwise
+	byName := map[string][]*cargoPackage{}
+	members := []*cargoPackage{}
+	for _, p := range packages {
+		if p.name == "" || p.version == "" {
+			return nil, fmt.Errorf("missing name or version of a Cargo.lock package")
+		}
+		byName[p.name] = append(byName[p.name], p)
+		if p.source == "" {
+			members = append(members, p)
+		}
+	}
+
+	lockfile := &Lockfile{}
+	set := &packageSet{}
+	for _, p := range packages {
+		deps := []Dependency{}
+		for _, d := range p.dependencies {
+			fields := strings.Fields(d)
+			if len(fields) == 0 {
+				continue
+			}
+			candidates := byName[fields[0]]
+			if len(fields) > 1 {
+				candidates = nil
+				for _, c := range byName[fields[0]] {
+					if c.version == fields[1] {
+						candidates = append(candidates, c)
+					}
+				}
+			}
+			if len(candidates) == 0 {
+				return nil, fmt.Errorf("dependency %q of %s %s is not locked", d, p.name, p.version)
+			}
+			deps = append(deps, Dependency{Name: candidates[0].name, Version: candidates[0].version})
+		}
+		if len(members) == 1 && p == members[0] {
+			lockfile.Root = &Package{Name: p.name, Version: p.version, Dependencies: deps}
+			continue
+		}
+		i := set.add(p.name, p.version)
+		for _, d := range deps {
+			set.depend(i, d)
+		}
+	}
+	lockfile.Packages = set.packages
+	return lockfile, nil
+}
+
+// unquoteTOML returns the value of a TOML basic string, as written by cargo
+func unquoteTOML(s string) string {
+	return strings.Trim(strings.TrimSpace(s), "\"")
+}
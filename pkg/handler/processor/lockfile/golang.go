@@ -0,0 +1,141 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// parseGoMod returns the module as the root, depending on the required
+// modules, indirectly for those marked `// indirect`. Replacements by another
+// module version apply; replacements by a local directory are ignored.
+func parseGoMod(blob []byte) (*Lockfile, error) {
+	type require struct {
+		path, version string
+		indirect      bool
+	}
+	var module string
+	requires := []require{}
+	replaces := map[string][2]string{}
+
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	for scanner.Scan() {
+		line, comment, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		indirect := strings.TrimSpace(comment) == "indirect" || strings.HasPrefix(strings.TrimSpace(comment), "indirect;")
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" && fields[0] == ")" {
+			block = ""
+			continue
+		}
+		directive := block
+		if directive == "" {
+			directive, fields = fields[0], fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				block = directive
+				continue
+			}
+		}
+		switch directive {
+		case "module":
+			if len(fields) > 0 {
+				module = unquote(fields[0])
+			}
+		case "require":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid require directive: %q", scanner.Text())
+			}
+			requires = append(requires, require{path: unquote(fields[0]), version: unquote(fields[1]), indirect: indirect})
+		case "replace":
+			// `old [version] => new [version]`
+			i := indexOf(fields, "=>")
+			if i < 1 || i == len(fields)-1 {
+				return nil, fmt.Errorf("invalid replace directive: %q", scanner.Text())
+			}
+			if len(fields)-i-1 == 2 {
+				old := unquote(fields[0])
+				if i == 2 {
+					old += "@" + unquote(fields[1])
+				}
+				replaces[old] = [2]string{unquote(fields[i+1]), unquote(fields[i+2])}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if module == "" {
+		return nil, fmt.Errorf("missing module directive")
+	}
+
+	set := &packageSet{}
+	root := &Package{Name: module, Dependencies: []Dependency{}}
+	for _, r := range requires {
+		path, version := r.path, r.version
+		if to, ok := replaces[path+"@"+version]; ok {
+			path, version = to[0], to[1]
+		} else if to, ok := replaces[path]; ok {
+			path, version = to[0], to[1]
+		}
+		set.add(path, version)
+		root.Dependencies = append(root.Dependencies, Dependency{Name: path, Version: version, Indirect: r.indirect})
+	}
+	return &Lockfile{Root: root, Packages: set.packages}, nil
+}
+
+// parseGoSum returns the module versions whose content is checksummed by
+// go.sum. The versions only listed for their go.mod file are left out, their
+// code is not needed by the build. go.sum does not give the dependencies.
+func parseGoSum(blob []byte) (*Lockfile, error) {
+	set := &packageSet{}
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], "h1:") {
+			return nil, fmt.Errorf("invalid go.sum line: %q", scanner.Text())
+		}
+		if strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		set.add(fields[0], fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &Lockfile{Packages: set.packages}, nil
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, "\"`")
+}
+
+func indexOf(fields []string, s string) int {
+	for i, f := range fields {
+		if f == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,159 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Lockfile is the content of a lockfile (or a manifest pinning versions):
+// the project, when the lockfile names it, and the packages it resolves.
+type Lockfile struct {
+	// Root is the project the lockfile resolves the dependencies of, nil
+	// when the lockfile does not name it. Its dependencies are the direct
+	// dependencies of the project.
+	Root *Package
+	// Packages are the resolved packages, each once, in the order of the
+	// lockfile
+	Packages []Package
+}
+
+// Package is a package of a lockfile
+type Package struct {
+	Name    string
+	Version string
+	// Dependencies are the packages this package depends on, nil when the
+	// lockfile does not give them
+	Dependencies []Dependency
+}
+
+// Dependency is a reference to a package of the lockfile. Indirect is set
+// when the lockfile marks a dependency of the project as only needed by its
+// dependencies (e.g. `// indirect` in go.mod).
+type Dependency struct {
+	Name     string
+	Version  string
+	Indirect bool
+}
+
+// fileTypes maps the names of the lockfiles to their document type
+var fileTypes = map[string]processor.DocumentType{
+	"package-lock.json":   processor.DocumentNpmLock,
+	"npm-shrinkwrap.json": processor.DocumentNpmLock,
+	"go.mod":              processor.DocumentGoMod,
+	"go.sum":              processor.DocumentGoSum,
+	"Cargo.lock":          processor.DocumentCargoLock,
+}
+
+// TypeOf returns the type of the lockfile given by the name of the file at
+// path (which may be a URI), or processor.DocumentUnknown if the file is not
+// a known lockfile. Besides the fixed names, `requirements*.txt` files are
+// pip requirements.
+func TypeOf(p string) processor.DocumentType {
+	name := path.Base(strings.ReplaceAll(p, "\\", "/"))
+	if t, ok := fileTypes[name]; ok {
+		return t
+	}
+	if strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt") {
+		return processor.DocumentRequirements
+	}
+	return processor.DocumentUnknown
+}
+
+// Parse returns the content of the lockfile of the given type
+func Parse(docType processor.DocumentType, blob []byte) (*Lockfile, error) {
+	switch docType {
+	case processor.DocumentNpmLock:
+		return parseNpmLock(blob)
+	case processor.DocumentGoMod:
+		return parseGoMod(blob)
+	case processor.DocumentGoSum:
+		return parseGoSum(blob)
+	case processor.DocumentRequirements:
+		return parseRequirements(blob)
+	case processor.DocumentCargoLock:
+		return parseCargoLock(blob)
+	}
+	return nil, fmt.Errorf("unsupported lockfile type: %v", docType)
+}
+
+// LockfileProcessor processes lockfiles. Since lockfiles cannot be told apart
+// by their content, collectors set their type, e.g. via `TypeOf`.
+type LockfileProcessor struct {
+}
+
+func (p *LockfileProcessor) ValidateSchema(d *processor.Document) error {
+	_, err := Parse(d.Type, d.Blob)
+	return err
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *LockfileProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if _, ok := supportedTypes[d.Type]; !ok {
+		return nil, fmt.Errorf("unsupported lockfile type: %v", d.Type)
+	}
+
+	// lockfiles don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
+
+// supportedTypes are the document types of lockfiles
+var supportedTypes = map[processor.DocumentType]bool{
+	processor.DocumentNpmLock:      true,
+	processor.DocumentGoMod:        true,
+	processor.DocumentGoSum:        true,
+	processor.DocumentRequirements: true,
+	processor.DocumentCargoLock:    true,
+}
+
+// packageSet collects the packages of a lockfile, each once, keeping their
+// order
+type packageSet struct {
+	packages []Package
+	index    map[[2]string]int
+}
+
+// add adds the package unless it was already added, and returns its index
+func (s *packageSet) add(name, version string) int {
+	if s.index == nil {
+		s.index = map[[2]string]int{}
+	}
+	key := [2]string{name, version}
+	if i, ok := s.index[key]; ok {
+		return i
+	}
+	s.index[key] = len(s.packages)
+	s.packages = append(s.packages, Package{Name: name, Version: version})
+	return len(s.packages) - 1
+}
+
+// depend records the dependency of the package at index i, once
+func (s *packageSet) depend(i int, dep Dependency) {
+	for _, d := range s.packages[i].Dependencies {
+		if d.Name == dep.Name && d.Version == dep.Version {
+			return
+		}
+	}
+	s.packages[i].Dependencies = append(s.packages[i].Dependencies, dep)
+}
@@ -0,0 +1,222 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestTypeOf(t *testing.T) {
+	testCases := []struct {
+		path string
+		want processor.DocumentType
+	}{
+		{"package-lock.json", processor.DocumentNpmLock},
+		{"/src/web/npm-shrinkwrap.json", processor.DocumentNpmLock},
+		{"file:///src/go.mod", processor.DocumentGoMod},
+		{"file:///src/app.tar.gz!/app/go.sum", processor.DocumentGoSum},
+		{"requirements.txt", processor.DocumentRequirements},
+		{"C:\\src\\requirements-dev.txt", processor.DocumentRequirements},
+		{"/src/Cargo.lock", processor.DocumentCargoLock},
+		{"/src/package.json", processor.DocumentUnknown},
+		{"/src/go.mod.orig", processor.DocumentUnknown},
+		{"/src/requirements.in", processor.DocumentUnknown},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := TypeOf(tt.path); got != tt.want {
+				t.Errorf("TypeOf(%q) = %v, expected %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		docType processor.DocumentType
+		blob    []byte
+		want    *Lockfile
+		wantErr bool
+	}{{
+		name:    "package-lock.json",
+		docType: processor.DocumentNpmLock,
+		blob:    testdata.NpmLockExample,
+		want: &Lockfile{
+			Root: &Package{Name: "web", Version: "1.0.0", Dependencies: []Dependency{
+				{Name: "@actions/core", Version: "1.10.0"},
+				{Name: "ms", Version: "2.1.3"},
+				{Name: "uuid", Version: "9.0.0"},
+			}},
+			Packages: []Package{
+				{Name: "@actions/core", Version: "1.10.0", Dependencies: []Dependency{
+					{Name: "@actions/http-client", Version: "2.1.0"},
+					{Name: "uuid", Version: "8.3.2"},
+				}},
+				{Name: "@actions/http-client", Version: "2.1.0", Dependencies: []Dependency{{Name: "tunnel", Version: "0.0.6"}}},
+				{Name: "tunnel", Version: "0.0.6"},
+				{Name: "uuid", Version: "8.3.2"},
+				{Name: "ms", Version: "2.1.3"},
+				{Name: "uuid", Version: "9.0.0"},
+			},
+		},
+	}, {
+		name:    "package-lock.json with workspaces",
+		docType: processor.DocumentNpmLock,
+		blob: []byte(`{"lockfileVersion": 2, "packages": {
+			"": {"workspaces": ["packages/lib"]},
+			"node_modules/lib": {"resolved": "packages/lib", "link": true},
+			"packages/lib": {"name": "lib", "version": "0.1.0", "dependencies": {"ms": "^2.1.3"}},
+			"node_modules/ms": {"version": "2.1.3"}
+		}}`),
+		want: &Lockfile{
+			Packages: []Package{
+				{Name: "ms", Version: "2.1.3"},
+				{Name: "lib", Version: "0.1.0", Dependencies: []Dependency{{Name: "ms", Version: "2.1.3"}}},
+			},
+		},
+	}, {
+		name:    "package-lock.json version 1",
+		docType: processor.DocumentNpmLock,
+		blob:    []byte(`{"name": "web", "lockfileVersion": 1, "dependencies": {"ms": {"version": "2.1.3"}}}`),
+		wantErr: true,
+	}, {
+		name:    "go.mod",
+		docType: processor.DocumentGoMod,
+		blob:    testdata.GoModExample,
+		want: &Lockfile{
+			Root: &Package{Name: "github.com/example/app", Dependencies: []Dependency{
+				{Name: "github.com/spf13/cobra", Version: "v1.6.1"},
+				{Name: "golang.org/x/text", Version: "v0.3.8", Indirect: true},
+				{Name: "github.com/spf13/pflag", Version: "v1.0.5", Indirect: true},
+			}},
+			Packages: []Package{
+				{Name: "github.com/spf13/cobra", Version: "v1.6.1"},
+				{Name: "golang.org/x/text", Version: "v0.3.8"},
+				{Name: "github.com/spf13/pflag", Version: "v1.0.5"},
+			},
+		},
+	}, {
+		name:    "go.mod replaced by a local directory",
+		docType: processor.DocumentGoMod,
+		blob:    []byte("module example.com/app\nrequire example.com/lib v1.0.0\nreplace example.com/lib => ../lib\n"),
+		want: &Lockfile{
+			Root:     &Package{Name: "example.com/app", Dependencies: []Dependency{{Name: "example.com/lib", Version: "v1.0.0"}}},
+			Packages: []Package{{Name: "example.com/lib", Version: "v1.0.0"}},
+		},
+	}, {
+		name:    "go.mod without module",
+		docType: processor.DocumentGoMod,
+		blob:    []byte("go 1.20\n"),
+		wantErr: true,
+	}, {
+		name:    "go.sum",
+		docType: processor.DocumentGoSum,
+		blob:    testdata.GoSumExample,
+		want: &Lockfile{
+			Packages: []Package{
+				{Name: "github.com/spf13/cobra", Version: "v1.6.1"},
+				{Name: "github.com/spf13/pflag", Version: "v1.0.5"},
+			},
+		},
+	}, {
+		name:    "invalid go.sum",
+		docType: processor.DocumentGoSum,
+		blob:    []byte("github.com/spf13/cobra v1.6.1\n"),
+		wantErr: true,
+	}, {
+		name:    "requirements.txt",
+		docType: processor.DocumentRequirements,
+		blob:    testdata.RequirementsExample,
+		want: &Lockfile{
+			Packages: []Package{
+				{Name: "certifi", Version: "2023.7.22"},
+				{Name: "charset-normalizer", Version: "3.2.0"},
+				{Name: "idna", Version: "3.4"},
+				{Name: "pyyaml", Version: "6.0.1"},
+				{Name: "requests", Version: "2.31.0", Dependencies: []Dependency{
+					{Name: "certifi", Version: "2023.7.22"},
+					{Name: "charset-normalizer", Version: "3.2.0"},
+					{Name: "idna", Version: "3.4"},
+					{Name: "urllib3", Version: "2.0.4"},
+				}},
+				{Name: "urllib3", Version: "2.0.4"},
+			},
+		},
+	}, {
+		name:    "requirements.txt not pinned",
+		docType: processor.DocumentRequirements,
+		blob:    []byte("-r base.txt\nflask>=2.0\nrequests[socks] == 2.31.0 ; python_version >= \"3.7\"  # pinned\n-e .\n"),
+		want: &Lockfile{
+			Packages: []Package{{Name: "requests", Version: "2.31.0"}},
+		},
+	}, {
+		name:    "Cargo.lock",
+		docType: processor.DocumentCargoLock,
+		blob:    testdata.CargoLockExample,
+		want: &Lockfile{
+			Root: &Package{Name: "app", Version: "0.1.0", Dependencies: []Dependency{
+				{Name: "anyhow", Version: "1.0.75"},
+				{Name: "serde", Version: "1.0.188"},
+			}},
+			Packages: []Package{
+				{Name: "anyhow", Version: "1.0.75"},
+				{Name: "serde", Version: "1.0.188", Dependencies: []Dependency{{Name: "serde_derive", Version: "1.0.188"}}},
+				{Name: "serde_derive", Version: "1.0.188"},
+			},
+		},
+	}, {
+		name:    "Cargo.lock with a dependency that is not locked",
+		docType: processor.DocumentCargoLock,
+		blob:    []byte("[[package]]\nname = \"app\"\nversion = \"0.1.0\"\ndependencies = [\"anyhow\"]\n"),
+		wantErr: true,
+	}, {
+		name:    "not a lockfile",
+		docType: processor.DocumentSPDX,
+		blob:    testdata.SpdxExampleSmall,
+		wantErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.docType, tt.blob)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockfileProcessor(t *testing.T) {
+	p := &LockfileProcessor{}
+	doc := &processor.Document{Blob: testdata.CargoLockExample, Type: processor.DocumentCargoLock}
+	if err := p.ValidateSchema(doc); err != nil {
+		t.Errorf("ValidateSchema() error = %v", err)
+	}
+	if docs, err := p.Unpack(doc); err != nil || len(docs) != 0 {
+		t.Errorf("Unpack() = %v, %v, expected no documents", docs, err)
+	}
+	doc = &processor.Document{Blob: testdata.CargoLockExample, Type: processor.DocumentGoSum}
+	if err := p.ValidateSchema(doc); err == nil {
+		t.Errorf("ValidateSchema() of a Cargo.lock as a go.sum succeeded")
+	}
+}
@@ -0,0 +1,180 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// npmLock is a package-lock.json (or npm-shrinkwrap.json) of lockfile
+// version 2 or 3, see https://docs.npmjs.com/cli/configuring-npm/package-lock-json
+type npmLock struct {
+	Name            string                `json:"name"`
+	Version         string                `json:"version"`
+	LockfileVersion int                   `json:"lockfileVersion"`
+	Packages        map[string]npmPackage `json:"packages"`
+}
+
+// npmPackage is an entry of the packages of a package-lock.json, keyed by its
+// location (e.g. `node_modules/a/node_modules/b`); the root project has the
+// empty key
+type npmPackage struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Resolved             string            `json:"resolved"`
+	Link                 bool              `json:"link"`
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+}
+
+// parseNpmLock resolves the dependencies of each package of the lockfile the
+// way node does: from the node_modules directory of the package, then from
+// those of its parents. Dependencies that are not installed (e.g. optional
+// dependencies for other platforms) are left out.
+func parseNpmLock(blob []byte) (*Lockfile, error) {
+	var lock npmLock
+	if err := json.Unmarshal(blob, &lock); err != nil {
+		return nil, err
+	}
+	if lock.LockfileVersion < 2 || lock.Packages == nil {
+		return nil, fmt.Errorf("unsupported package-lock.json lockfile version %d, expected version 2 or 3 (npm 7 or later)", lock.LockfileVersion)
+	}
+
+	// resolve follows links (e.g. to workspaces) to the installed package
+	resolve := func(location string) (string, npmPackage, bool) {
+		pkg, ok := lock.Packages[location]
+		for i := 0; ok && pkg.Link && i < len(lock.Packages); i++ {
+			location = pkg.Resolved
+			pkg, ok = lock.Packages[location]
+		}
+		return location, pkg, ok
+	}
+	// lookup returns the location of the package installed for a dependency
+	// of the package at location
+	lookup := func(location string, name string) (string, npmPackage, bool) {
+		for {
+			candidate := "node_modules/" + name
+			if location != "" {
+				candidate = location + "/" + candidate
+			}
+			if found, pkg, ok := resolve(candidate); ok {
+				return found, pkg, true
+			}
+			if location == "" {
+				return "", npmPackage{}, false
+			}
+			i := strings.LastIndex(location, "node_modules/")
+			if i < 0 {
+				location = ""
+			} else {
+				location = strings.TrimSuffix(location[:i], "/")
+			}
+		}
+	}
+
+	set := &packageSet{}
+	indexes := map[string]int{}
+	var add func(location string, pkg npmPackage) int
+	add = func(location string, pkg npmPackage) int {
+		if i, ok := indexes[location]; ok {
+			return i
+		}
+		i := set.add(npmName(location, pkg), pkg.Version)
+		indexes[location] = i
+		for _, name := range sortedKeys(pkg.Dependencies, pkg.OptionalDependencies, pkg.PeerDependencies) {
+			depLocation, dep, ok := lookup(location, name)
+			if !ok {
+				continue
+			}
+			j := add(depLocation, dep)
+			set.depend(i, Dependency{Name: set.packages[j].Name, Version: set.packages[j].Version})
+		}
+		return i
+	}
+
+	root := lock.Packages[""]
+	lockfile := &Lockfile{}
+	rootDeps := []Dependency{}
+	for _, name := range sortedKeys(root.Dependencies, root.OptionalDependencies, root.PeerDependencies, root.DevDependencies) {
+		location, pkg, ok := lookup("", name)
+		if !ok {
+			continue
+		}
+		j := add(location, pkg)
+		rootDeps = append(rootDeps, Dependency{Name: set.packages[j].Name, Version: set.packages[j].Version})
+	}
+	// packages that are installed but not reachable from the project, e.g.
+	// the dependencies of workspaces
+	locations := []string{}
+	for location := range lock.Packages {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	for _, location := range locations {
+		pkg := lock.Packages[location]
+		if location == "" || pkg.Link || pkg.Version == "" {
+			continue
+		}
+		add(location, pkg)
+	}
+	lockfile.Packages = set.packages
+
+	name := root.Name
+	if name == "" {
+		name = lock.Name
+	}
+	version := root.Version
+	if version == "" {
+		version = lock.Version
+	}
+	if name != "" {
+		lockfile.Root = &Package{Name: name, Version: version, Dependencies: rootDeps}
+	}
+	return lockfile, nil
+}
+
+// npmName returns the name of the package at location, which is the path
+// after the last node_modules directory unless the package names itself
+func npmName(location string, pkg npmPackage) string {
+	if pkg.Name != "" {
+		return pkg.Name
+	}
+	if i := strings.LastIndex(location, "node_modules/"); i >= 0 {
+		return location[i+len("node_modules/"):]
+	}
+	return location
+}
+
+// sortedKeys returns the keys of the maps, each once, in order
+func sortedKeys(maps ...map[string]string) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
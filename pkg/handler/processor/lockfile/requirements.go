@@ -0,0 +1,109 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// requirementPattern matches a requirement pinned to a version, e.g.
+// `requests[security]==2.31.0 ; python_version >= "3.7"`
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*===?\s*([^\s;#\\]+)`)
+
+// parseRequirements returns the requirements pinned to a version (with `==`
+// or `===`); the others cannot be resolved without an index and are left
+// out. Files compiled by pip-compile give the dependencies in the `# via`
+// comments following each requirement: a requirement is needed by the
+// packages listed there. requirements.txt does not name the project.
+func parseRequirements(blob []byte) (*Lockfile, error) {
+	set := &packageSet{}
+	// via maps the index of each package to the names of the packages
+	// depending on it
+	via := map[int][]string{}
+	current := -1
+	inVia := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(joinContinuations(blob)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if comment := strings.TrimPrefix(line, "#"); comment != line {
+			comment = strings.TrimSpace(comment)
+			if rest := strings.TrimPrefix(comment, "via"); rest != comment && (rest == "" || rest[0] == ' ') {
+				inVia = true
+				comment = strings.TrimSpace(rest)
+			}
+			if inVia && current >= 0 && comment != "" {
+				for _, name := range strings.Split(comment, ",") {
+					name = strings.TrimSpace(name)
+					// `-r requirements.in` and `-c constraints.txt` are
+					// the files the requirement comes from
+					if name != "" && !strings.HasPrefix(name, "-") {
+						via[current] = append(via[current], normalizePyPIName(name))
+					}
+				}
+			}
+			continue
+		}
+		inVia = false
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			// options, e.g. `-r other.txt` or `-e .`
+			current = -1
+			continue
+		}
+		m := requirementPattern.FindStringSubmatch(line)
+		if m == nil {
+			current = -1
+			continue
+		}
+		current = set.add(normalizePyPIName(m[1]), m[2])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	byName := map[string]int{}
+	for i, p := range set.packages {
+		byName[p.Name] = i
+	}
+	for i := range set.packages {
+		for _, name := range via[i] {
+			if j, ok := byName[name]; ok {
+				set.depend(j, Dependency{Name: set.packages[i].Name, Version: set.packages[i].Version})
+			}
+		}
+	}
+	return &Lockfile{Packages: set.packages}, nil
+}
+
+// joinContinuations joins the lines ending with a backslash with the next
+// one, e.g. requirements followed by `--hash` options
+func joinContinuations(blob []byte) []byte {
+	return bytes.ReplaceAll(bytes.ReplaceAll(blob, []byte("\\\r\n"), []byte(" ")), []byte("\\\n"), []byte(" "))
+}
+
+// normalizePyPIName returns the normalized name of a Python package, see
+// https://packaging.python.org/en/latest/specifications/name-normalization/
+func normalizePyPIName(name string) string {
+	return strings.ToLower(pypiSeparators.ReplaceAllString(name, "-"))
+}
+
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
@@ -0,0 +1,110 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ociimage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Annotations (in manifests) and labels (in configurations) naming the base
+// image of an image, see
+// https://github.com/opencontainers/image-spec/blob/main/annotations.md
+const (
+	BaseNameAnnotation   = "org.opencontainers.image.base.name"
+	BaseDigestAnnotation = "org.opencontainers.image.base.digest"
+)
+
+// ParseConfig returns the image configuration (the JSON document whose
+// digest is the image ID, as printed by `crane config`), or an error if blob
+// is not one
+func ParseConfig(blob []byte) (*v1.ConfigFile, error) {
+	var config v1.ConfigFile
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, err
+	}
+	if config.RootFS.Type != "layers" || config.OS == "" {
+		return nil, fmt.Errorf("missing required image configuration fields")
+	}
+	return &config, nil
+}
+
+// ParseManifest returns the image manifest (as printed by `crane manifest`),
+// or an error if blob is not the manifest of an image. Indexes and the
+// manifests of other artifacts (e.g. signatures) are rejected.
+func ParseManifest(blob []byte) (*v1.Manifest, error) {
+	var manifest v1.Manifest
+	if err := json.Unmarshal(blob, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.SchemaVersion != 2 || manifest.Config.Digest.Hex == "" {
+		return nil, fmt.Errorf("missing required image manifest fields")
+	}
+	switch manifest.MediaType {
+	case "", types.OCIManifestSchema1, types.DockerManifestSchema2:
+	default:
+		return nil, fmt.Errorf("unsupported manifest media type %s", manifest.MediaType)
+	}
+	switch manifest.Config.MediaType {
+	case types.OCIConfigJSON, types.DockerConfigJSON:
+	default:
+		return nil, fmt.Errorf("unsupported image configuration media type %s", manifest.Config.MediaType)
+	}
+	return &manifest, nil
+}
+
+// OCIImageProcessor processes the configurations and manifests of container
+// images. Currently only supports JSON documents
+type OCIImageProcessor struct {
+}
+
+func (p *OCIImageProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentImageConfig && d.Type != processor.DocumentImageManifest {
+		return fmt.Errorf("expected document type: %v or %v, actual document type: %v", processor.DocumentImageConfig, processor.DocumentImageManifest, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var err error
+		if d.Type == processor.DocumentImageConfig {
+			_, err = ParseConfig(d.Blob)
+		} else {
+			_, err = ParseManifest(d.Blob)
+		}
+		return err
+	}
+
+	return fmt.Errorf("unable to support parsing of image document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *OCIImageProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentImageConfig && d.Type != processor.DocumentImageManifest {
+		return nil, fmt.Errorf("expected document type: %v or %v, actual document type: %v", processor.DocumentImageConfig, processor.DocumentImageManifest, d.Type)
+	}
+
+	// Image documents don't unpack into additional documents, the layers
+	// are referenced by digest only.
+	return []*processor.Document{}, nil
+}
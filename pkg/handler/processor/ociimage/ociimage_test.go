@@ -0,0 +1,148 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ociimage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestOCIImageProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "image configuration",
+		doc: processor.Document{
+			Blob:              testdata.OCIImageConfigExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentImageConfig,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "image manifest",
+		doc: processor.Document{
+			Blob:              testdata.OCIImageManifestExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentImageManifest,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.OCIImageConfigExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := OCIImageProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("OCIImageProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("OCIImageProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOCIImageProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		docType   processor.DocumentType
+		format    processor.FormatType
+		expectErr bool
+	}{{
+		name:      "valid image configuration",
+		blob:      testdata.OCIImageConfigExample,
+		docType:   processor.DocumentImageConfig,
+		format:    processor.FormatJSON,
+		expectErr: false,
+	}, {
+		name:      "valid image manifest",
+		blob:      testdata.OCIImageManifestExample,
+		docType:   processor.DocumentImageManifest,
+		format:    processor.FormatJSON,
+		expectErr: false,
+	}, {
+		name:      "manifest as configuration",
+		blob:      testdata.OCIImageManifestExample,
+		docType:   processor.DocumentImageConfig,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "configuration without rootfs",
+		blob:      []byte(`{"architecture": "amd64", "os": "linux"}`),
+		docType:   processor.DocumentImageConfig,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid layer digest",
+		blob:      []byte(`{"os": "linux", "rootfs": {"type": "layers", "diff_ids": ["abc"]}}`),
+		docType:   processor.DocumentImageConfig,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name: "image index",
+		blob: []byte(`{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [],
+			"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:d13e0ce4c60e0d05869f5253e8a44363ce5aca77e414f9615cf04c8a13e361c5"}}`),
+		docType:   processor.DocumentImageManifest,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name: "signature manifest",
+		blob: []byte(`{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"config": {"mediaType": "application/vnd.dev.cosign.artifact.sig.v1+json", "digest": "sha256:d13e0ce4c60e0d05869f5253e8a44363ce5aca77e414f9615cf04c8a13e361c5"}}`),
+		docType:   processor.DocumentImageManifest,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "invalid format supported",
+		blob:      testdata.OCIImageConfigExample,
+		docType:   processor.DocumentImageConfig,
+		format:    processor.FormatUnknown,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := OCIImageProcessor{}
+			err := d.ValidateSchema(&processor.Document{
+				Blob:   tt.blob,
+				Format: tt.format,
+				Type:   tt.docType,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("OCIImageProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openvex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// ContextPrefix is the prefix of the `@context` of OpenVEX documents, which
+// is followed by the version of the specification (e.g. `/v0.2.0`)
+const ContextPrefix = "https://openvex.dev/ns"
+
+// Status is the status of a vulnerability for a product
+type Status string
+
+// Status* are the statuses defined by the VEX minimum requirements, shared
+// by OpenVEX and CSAF
+const (
+	StatusNotAffected        Status = "not_affected"
+	StatusAffected           Status = "affected"
+	StatusFixed              Status = "fixed"
+	StatusUnderInvestigation Status = "under_investigation"
+)
+
+// Valid returns whether the status is one of the defined statuses
+func (s Status) Valid() bool {
+	switch s {
+	case StatusNotAffected, StatusAffected, StatusFixed, StatusUnderInvestigation:
+		return true
+	}
+	return false
+}
+
+// Document is an OpenVEX document, as described at
+// https://github.com/openvex/spec. Only the fields used by GUAC are decoded.
+type Document struct {
+	Context    string      `json:"@context"`
+	ID         string      `json:"@id"`
+	Author     string      `json:"author"`
+	Timestamp  string      `json:"timestamp"`
+	Statements []Statement `json:"statements"`
+}
+
+// Statement is the status of a vulnerability for a set of products
+type Statement struct {
+	Vulnerability   Vulnerability `json:"vulnerability"`
+	Products        []Product     `json:"products"`
+	Status          Status        `json:"status"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	ActionStatement string        `json:"action_statement,omitempty"`
+}
+
+// Vulnerability identifies the vulnerability of a statement. Up to v0.0.1 of
+// the specification, it is given as a plain string (its name).
+type Vulnerability struct {
+	ID      string   `json:"@id,omitempty"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+func (v *Vulnerability) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &v.Name); err == nil {
+		return nil
+	}
+	type vulnerability Vulnerability
+	return json.Unmarshal(b, (*vulnerability)(v))
+}
+
+// Product identifies a product of a statement. Up to v0.0.1 of the
+// specification, it is given as a plain string (its `@id`, usually a purl).
+type Product struct {
+	ID          string      `json:"@id"`
+	Identifiers Identifiers `json:"identifiers,omitempty"`
+}
+
+// Identifiers are the software identifiers of a product
+type Identifiers struct {
+	Purl string `json:"purl,omitempty"`
+}
+
+func (p *Product) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &p.ID); err == nil {
+		return nil
+	}
+	type product Product
+	return json.Unmarshal(b, (*product)(p))
+}
+
+// Purl returns the purl of the product, or the empty string if the product
+// is not identified by a purl
+func (p Product) Purl() string {
+	if p.Identifiers.Purl != "" {
+		return p.Identifiers.Purl
+	}
+	if strings.HasPrefix(p.ID, "pkg:") {
+		return p.ID
+	}
+	return ""
+}
+
+// OpenVEXProcessor processes OpenVEX documents.
+// Currently only supports JSON documents
+type OpenVEXProcessor struct {
+}
+
+func (p *OpenVEXProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentOpenVEX {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOpenVEX, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var doc Document
+		if err := json.Unmarshal(d.Blob, &doc); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(doc.Context, ContextPrefix) || doc.ID == "" {
+			return fmt.Errorf("missing required OpenVEX fields")
+		}
+		for i, s := range doc.Statements {
+			if s.Vulnerability.Name == "" {
+				return fmt.Errorf("statement %d has no vulnerability", i)
+			}
+			if !s.Status.Valid() {
+				return fmt.Errorf("statement %d has invalid status %q", i, s.Status)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of OpenVEX document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *OpenVEXProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentOpenVEX {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOpenVEX, d.Type)
+	}
+
+	// OpenVEX documents don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
@@ -0,0 +1,160 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openvex
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestOpenVEXProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "OpenVEX document",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentOpenVEX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := OpenVEXProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("OpenVEXProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("OpenVEXProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOpenVEXProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expectErr bool
+	}{{
+		name: "valid OpenVEX document",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentOpenVEX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: false,
+	}, {
+		name: "valid legacy OpenVEX document",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXLegacyExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentOpenVEX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: false,
+	}, {
+		name: "invalid OpenVEX document",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXInvalid,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentOpenVEX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: true,
+	}, {
+		name: "invalid format supported",
+		doc: processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentOpenVEX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := OpenVEXProcessor{}
+			err := d.ValidateSchema(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("OpenVEXProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestStatement_Unmarshal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		blob     string
+		expected Statement
+	}{{
+		name: "v0.0.1 statement",
+		blob: `{"vulnerability": "CVE-2023-1234", "products": ["pkg:npm/lib@2.0.0"], "status": "fixed"}`,
+		expected: Statement{
+			Vulnerability: Vulnerability{Name: "CVE-2023-1234"},
+			Products:      []Product{{ID: "pkg:npm/lib@2.0.0"}},
+			Status:        StatusFixed,
+		},
+	}, {
+		name: "v0.2.0 statement",
+		blob: `{"vulnerability": {"name": "CVE-2023-1234", "aliases": ["GHSA-xxxx"]},
+			"products": [{"@id": "https://example.com/lib", "identifiers": {"purl": "pkg:npm/lib@2.0.0"}}],
+			"status": "not_affected", "justification": "component_not_present"}`,
+		expected: Statement{
+			Vulnerability: Vulnerability{Name: "CVE-2023-1234", Aliases: []string{"GHSA-xxxx"}},
+			Products:      []Product{{ID: "https://example.com/lib", Identifiers: Identifiers{Purl: "pkg:npm/lib@2.0.0"}}},
+			Status:        StatusNotAffected,
+			Justification: "component_not_present",
+		},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual Statement
+			if err := json.Unmarshal([]byte(tt.blob), &actual); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Statement = %+v, expected %+v", actual, tt.expected)
+			}
+			if purl := actual.Products[0].Purl(); purl != "pkg:npm/lib@2.0.0" {
+				t.Errorf("Product.Purl() = %v, expected pkg:npm/lib@2.0.0", purl)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Entry is a vulnerability entry in the OSV format, as described at
+// https://ossf.github.io/osv-schema/. Only the fields used by GUAC are
+// decoded.
+type Entry struct {
+	SchemaVersion string     `json:"schema_version,omitempty"`
+	ID            string     `json:"id"`
+	Modified      string     `json:"modified"`
+	Published     string     `json:"published,omitempty"`
+	Withdrawn     string     `json:"withdrawn,omitempty"`
+	Aliases       []string   `json:"aliases,omitempty"`
+	Summary       string     `json:"summary,omitempty"`
+	Details       string     `json:"details,omitempty"`
+	Affected      []Affected `json:"affected,omitempty"`
+}
+
+// Affected describes the versions of a package affected by the vulnerability
+type Affected struct {
+	Package  Package  `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Package identifies the affected package
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl,omitempty"`
+}
+
+// Range is a range of affected versions, given by the events at which the
+// vulnerability is introduced and fixed
+type Range struct {
+	Type   string  `json:"type"`
+	Repo   string  `json:"repo,omitempty"`
+	Events []Event `json:"events"`
+}
+
+// Event is a version at which the vulnerability status changes. Only one of
+// the fields is set.
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// OSVProcessor processes OSV vulnerability entries.
+// Currently only supports JSON entries
+type OSVProcessor struct {
+}
+
+func (p *OSVProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentOSV {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOSV, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var entry Entry
+		if err := json.Unmarshal(d.Blob, &entry); err != nil {
+			return err
+		}
+		if entry.ID == "" || entry.Modified == "" {
+			return fmt.Errorf("missing required OSV fields")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of OSV document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *OSVProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentOSV {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOSV, d.Type)
+	}
+
+	// OSV entries don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
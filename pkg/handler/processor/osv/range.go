@@ -0,0 +1,109 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osv
+
+import (
+	"sort"
+
+	"github.com/guacsec/guac/pkg/version"
+)
+
+// Types of ranges
+const (
+	RangeSemver    = "SEMVER"
+	RangeEcosystem = "ECOSYSTEM"
+	RangeGit       = "GIT"
+)
+
+// AffectsVersion returns whether the version of a package of the purl type
+// (e.g. `deb`) is affected, being one of the affected versions or in one of
+// the affected ranges
+func AffectsVersion(purlType string, v string, ranges []Range, versions []string) bool {
+	compare := version.ComparatorFor(purlType)
+	for _, affected := range versions {
+		if compare(v, affected) == 0 {
+			return true
+		}
+	}
+	for _, r := range ranges {
+		if r.Contains(purlType, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns whether the version of a package of the purl type is in
+// the range, following the evaluation given by the OSV schema: the events
+// are sorted by version, and the last one at or below the version decides.
+// Versions of ECOSYSTEM ranges are compared following the semantics of the
+// ecosystem; GIT ranges give commits, not versions, and contain none.
+func (r Range) Contains(purlType string, v string) bool {
+	var compare version.Comparator
+	switch r.Type {
+	case RangeSemver:
+		compare = version.CompareSemver
+	case RangeEcosystem:
+		compare = version.ComparatorFor(purlType)
+	default:
+		return false
+	}
+
+	events := append([]Event{}, r.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i].version(), events[j].version()
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return compare(a, b) < 0
+	})
+
+	affected := false
+	for _, e := range events {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || compare(v, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if compare(v, e.Fixed) >= 0 {
+				affected = false
+			}
+		case e.LastAffected != "":
+			if compare(v, e.LastAffected) > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if compare(v, e.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// version returns the version at which the event happens
+func (e Event) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	}
+	return e.Limit
+}
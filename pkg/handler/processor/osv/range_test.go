@@ -0,0 +1,157 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osv
+
+import (
+	"testing"
+)
+
+func TestAffectsVersion(t *testing.T) {
+	semverRange := Range{Type: RangeSemver, Events: []Event{{Introduced: "7.0.0"}, {Fixed: "7.5.2"}}}
+	testCases := []struct {
+		name     string
+		purlType string
+		version  string
+		ranges   []Range
+		versions []string
+		want     bool
+	}{{
+		name:     "in semver range",
+		purlType: "npm",
+		version:  "7.0.0",
+		ranges:   []Range{semverRange},
+		want:     true,
+	}, {
+		name:     "fixed version",
+		purlType: "npm",
+		version:  "7.5.2",
+		ranges:   []Range{semverRange},
+	}, {
+		name:     "before the introduced version",
+		purlType: "npm",
+		version:  "7.0.0-rc.1",
+		ranges:   []Range{semverRange},
+	}, {
+		name:     "introduced at 0",
+		purlType: "npm",
+		version:  "0.0.1",
+		ranges:   []Range{{Type: RangeSemver, Events: []Event{{Introduced: "0"}, {Fixed: "1.0.0"}}}},
+		want:     true,
+	}, {
+		name:     "between two ranges of events",
+		purlType: "golang",
+		version:  "v1.7.0",
+		ranges: []Range{{Type: RangeSemver, Events: []Event{
+			{Introduced: "2.0.0"}, {Fixed: "2.5.0"}, {Introduced: "1.0.0"}, {Fixed: "1.5.0"},
+		}}},
+	}, {
+		name:     "in second range of unsorted events",
+		purlType: "golang",
+		version:  "v2.1.0",
+		ranges: []Range{{Type: RangeSemver, Events: []Event{
+			{Introduced: "2.0.0"}, {Fixed: "2.5.0"}, {Introduced: "1.0.0"}, {Fixed: "1.5.0"},
+		}}},
+		want: true,
+	}, {
+		name:     "last affected version",
+		purlType: "maven",
+		version:  "2.14.1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "2.0-beta9"}, {LastAffected: "2.14.1"}}}},
+		want:     true,
+	}, {
+		name:     "after last affected version",
+		purlType: "maven",
+		version:  "2.15.0",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "2.0-beta9"}, {LastAffected: "2.14.1"}}}},
+	}, {
+		name:     "maven pre-release of the introduced version",
+		purlType: "maven",
+		version:  "2.0-beta8",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "2.0-beta9"}, {LastAffected: "2.14.1"}}}},
+	}, {
+		name:     "limit",
+		purlType: "npm",
+		version:  "3.0.0",
+		ranges:   []Range{{Type: RangeSemver, Events: []Event{{Introduced: "0"}, {Limit: "3.0.0"}}}},
+	}, {
+		name:     "debian epoch above the fixed version",
+		purlType: "deb",
+		version:  "1:1.0-1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "0"}, {Fixed: "2.0-1"}}}},
+	}, {
+		name:     "debian tilde below the fixed version",
+		purlType: "deb",
+		version:  "2.0~rc1-1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "0"}, {Fixed: "2.0-1"}}}},
+		want:     true,
+	}, {
+		name:     "debian backport below the fixed version",
+		purlType: "deb",
+		version:  "2.30-1~bpo10+1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "0"}, {Fixed: "2.30-1"}}}},
+		want:     true,
+	}, {
+		name:     "rpm tilde below the fixed version",
+		purlType: "rpm",
+		version:  "1.0~rc2-1.el8",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "0"}, {Fixed: "1.0-1.el8"}}}},
+		want:     true,
+	}, {
+		name:     "rpm caret above the fixed version",
+		purlType: "rpm",
+		version:  "1.0^git1-1.el8",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "0"}, {Fixed: "1.0-1.el8"}}}},
+	}, {
+		name:     "pep 440 release candidate below the fixed version",
+		purlType: "pypi",
+		version:  "2.0rc1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "1.0"}, {Fixed: "2.0"}}}},
+		want:     true,
+	}, {
+		name:     "pep 440 post-release above the fixed version",
+		purlType: "pypi",
+		version:  "2.0.post1",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "1.0"}, {Fixed: "2.0"}}}},
+	}, {
+		name:     "pep 440 development release below the introduced version",
+		purlType: "pypi",
+		version:  "1.0.dev3",
+		ranges:   []Range{{Type: RangeEcosystem, Events: []Event{{Introduced: "1.0"}, {Fixed: "2.0"}}}},
+	}, {
+		name:     "git ranges contain no version",
+		purlType: "golang",
+		version:  "v1.0.0",
+		ranges:   []Range{{Type: RangeGit, Repo: "https://github.com/example/repo", Events: []Event{{Introduced: "0"}}}},
+	}, {
+		name:     "listed version",
+		purlType: "pypi",
+		version:  "1.0.0",
+		versions: []string{"0.9", "1.0"},
+		want:     true,
+	}, {
+		name:     "unlisted version",
+		purlType: "pypi",
+		version:  "1.1",
+		versions: []string{"0.9", "1.0"},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AffectsVersion(tt.purlType, tt.version, tt.ranges, tt.versions); got != tt.want {
+				t.Errorf("AffectsVersion(%q, %q) = %v, expected %v", tt.purlType, tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package process
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// maxDecompressedSize bounds the size of decompressed documents when
+// processor.MaxDocumentSize is disabled, so that a small compressed blob
+// cannot exhaust the memory (a "zip bomb")
+const maxDecompressedSize = 512 << 20
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressDocument replaces a gzip-compressed blob with its decompressed
+// content, so that collectors can deliver the payloads as they get them. The
+// format and type are then guessed from the decompressed content.
+func decompressDocument(ctx context.Context, i *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	if !bytes.HasPrefix(i.Blob, gzipMagic) {
+		return nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(i.Blob))
+	if err != nil {
+		return fmt.Errorf("unable to decompress gzip document: %w", err)
+	}
+	defer r.Close()
+	limit := processor.MaxDocumentSize()
+	if limit == 0 {
+		limit = maxDecompressedSize
+	}
+	blob, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return fmt.Errorf("unable to decompress gzip document: %w", err)
+	}
+	if int64(len(blob)) > limit {
+		return processor.DocumentTooLargeError(i.SourceInformation.Source+" (decompressed)", limit)
+	}
+
+	logger.Debugf("decompressed gzip document %s (%d to %d bytes)", i.SourceInformation.Source, len(i.Blob), len(blob))
+	i.Blob = blob
+	i.SourceInformation.Decompressed = true
+	return nil
+}
@@ -17,30 +17,103 @@ package process
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/bundle"
+	"github.com/guacsec/guac/pkg/handler/processor/clearlydefined"
+	"github.com/guacsec/guac/pkg/handler/processor/csaf"
 	"github.com/guacsec/guac/pkg/handler/processor/cyclonedx"
+	"github.com/guacsec/guac/pkg/handler/processor/depsnapshot"
 	"github.com/guacsec/guac/pkg/handler/processor/dsse"
 	"github.com/guacsec/guac/pkg/handler/processor/guesser"
+	"github.com/guacsec/guac/pkg/handler/processor/intoto"
 	"github.com/guacsec/guac/pkg/handler/processor/ite6"
+	"github.com/guacsec/guac/pkg/handler/processor/jsonlines"
+	"github.com/guacsec/guac/pkg/handler/processor/lifecycle"
+	"github.com/guacsec/guac/pkg/handler/processor/lockfile"
+	"github.com/guacsec/guac/pkg/handler/processor/ociimage"
+	"github.com/guacsec/guac/pkg/handler/processor/openvex"
+	"github.com/guacsec/guac/pkg/handler/processor/osv"
+	"github.com/guacsec/guac/pkg/handler/processor/sarif"
+	"github.com/guacsec/guac/pkg/handler/processor/schema"
 	"github.com/guacsec/guac/pkg/handler/processor/scorecard"
 	"github.com/guacsec/guac/pkg/handler/processor/spdx"
+	"github.com/guacsec/guac/pkg/handler/processor/syft"
+	"github.com/guacsec/guac/pkg/handler/processor/trivy"
+	"github.com/guacsec/guac/pkg/ingestor/verifier"
+	"github.com/guacsec/guac/pkg/logging"
 )
 
 var (
 	documentProcessors = map[processor.DocumentType]processor.DocumentProcessor{}
+	// dsseVerifier checks the signatures of DSSE envelopes before they are
+	// unpacked. Verification is skipped if it is nil.
+	dsseVerifier verifier.Verifier
+	// documentResolver fetches the documents referenced by other documents.
+	// References are not followed if it is nil.
+	documentResolver = NewHTTPResolver(DefaultResolveTimeout, DefaultResolveMaxSize)
+	// resolveBudget is the total time spent fetching the documents
+	// referenced by a document tree, 0 for no limit
+	resolveBudget = DefaultResolveBudget
+	// schemaValidation controls how SBOMs not matching their schema are
+	// handled
+	schemaValidation = SchemaValidationWarn
 )
 
+// SchemaValidationMode controls how CycloneDX and SPDX JSON documents that do
+// not match the schema of their spec version are handled
+type SchemaValidationMode string
+
+const (
+	// SchemaValidationOff skips the schema validation
+	SchemaValidationOff SchemaValidationMode = "off"
+	// SchemaValidationWarn logs the violations and processes the document
+	SchemaValidationWarn SchemaValidationMode = "warn"
+	// SchemaValidationStrict rejects the document
+	SchemaValidationStrict SchemaValidationMode = "strict"
+)
+
+// ErrUnverifiedEnvelope is returned when none of the signatures of a DSSE
+// envelope could be verified
+var ErrUnverifiedEnvelope = errors.New("no verified signature found in DSSE envelope")
+
 func init() {
 	_ = RegisterDocumentProcessor(&ite6.ITE6Processor{}, processor.DocumentITE6Generic)
 	_ = RegisterDocumentProcessor(&ite6.ITE6Processor{}, processor.DocumentITE6SLSA)
 	_ = RegisterDocumentProcessor(&ite6.ITE6Processor{}, processor.DocumentITE6Vul)
+	_ = RegisterDocumentProcessor(&ite6.ITE6Processor{}, processor.DocumentITE6SameAs)
 	_ = RegisterDocumentProcessor(&dsse.DSSEProcessor{}, processor.DocumentDSSE)
+	_ = RegisterDocumentProcessor(&bundle.BundleProcessor{}, processor.DocumentSigstoreBundle)
 	_ = RegisterDocumentProcessor(&spdx.SPDXProcessor{}, processor.DocumentSPDX)
 	_ = RegisterDocumentProcessor(&scorecard.ScorecardProcessor{}, processor.DocumentScorecard)
 	_ = RegisterDocumentProcessor(&cyclonedx.CycloneDXProcessor{}, processor.DocumentCycloneDX)
+	_ = RegisterDocumentProcessor(&osv.OSVProcessor{}, processor.DocumentOSV)
+	_ = RegisterDocumentProcessor(&openvex.OpenVEXProcessor{}, processor.DocumentOpenVEX)
+	_ = RegisterDocumentProcessor(&csaf.CSAFProcessor{}, processor.DocumentCSAF)
+	_ = RegisterDocumentProcessor(&intoto.InTotoProcessor{}, processor.DocumentInTotoLink)
+	_ = RegisterDocumentProcessor(&intoto.InTotoProcessor{}, processor.DocumentInTotoLayout)
+	_ = RegisterDocumentProcessor(&syft.SyftProcessor{}, processor.DocumentSyft)
+	_ = RegisterDocumentProcessor(&trivy.TrivyProcessor{}, processor.DocumentTrivy)
+	_ = RegisterDocumentProcessor(&lifecycle.LifecycleProcessor{}, processor.DocumentLifecycle)
+	_ = RegisterDocumentProcessor(&clearlydefined.ClearlyDefinedProcessor{}, processor.DocumentClearlyDefined)
+	_ = RegisterDocumentProcessor(&sarif.SARIFProcessor{}, processor.DocumentSARIF)
+	_ = RegisterDocumentProcessor(&depsnapshot.DepSnapshotProcessor{}, processor.DocumentDepSnapshot)
+	_ = RegisterDocumentProcessor(&ociimage.OCIImageProcessor{}, processor.DocumentImageConfig)
+	_ = RegisterDocumentProcessor(&ociimage.OCIImageProcessor{}, processor.DocumentImageManifest)
+	_ = RegisterDocumentProcessor(&lockfile.LockfileProcessor{}, processor.DocumentNpmLock)
+	_ = RegisterDocumentProcessor(&lockfile.LockfileProcessor{}, processor.DocumentGoMod)
+	_ = RegisterDocumentProcessor(&lockfile.LockfileProcessor{}, processor.DocumentGoSum)
+	_ = RegisterDocumentProcessor(&lockfile.LockfileProcessor{}, processor.DocumentRequirements)
+	_ = RegisterDocumentProcessor(&lockfile.LockfileProcessor{}, processor.DocumentCargoLock)
+	_ = RegisterDocumentProcessor(&jsonlines.JsonLinesProcessor{}, processor.DocumentJsonLines)
 }
 
 func RegisterDocumentProcessor(p processor.DocumentProcessor, d processor.DocumentType) error {
@@ -51,8 +124,54 @@ func RegisterDocumentProcessor(p processor.DocumentProcessor, d processor.Docume
 	return nil
 }
 
+// DocumentTypes returns the types of documents that have a processor, sorted
+func DocumentTypes() []processor.DocumentType {
+	types := make([]processor.DocumentType, 0, len(documentProcessors))
+	for t := range documentProcessors {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// RegisterDSSEVerifier sets the verifier that checks the signatures of DSSE
+// envelopes. Once registered, envelopes without at least one verified
+// signature are rejected and their payload is not processed.
+func RegisterDSSEVerifier(v verifier.Verifier) error {
+	if dsseVerifier != nil {
+		return fmt.Errorf("the DSSE verifier is being overwritten: %s", v.Type())
+	}
+	dsseVerifier = v
+	return nil
+}
+
+// SetDocumentResolver sets the resolver used to fetch the documents
+// referenced by other documents. Passing nil disables following references.
+func SetDocumentResolver(r DocumentResolver) {
+	documentResolver = r
+}
+
+// SetResolveBudget sets the total time spent fetching the documents
+// referenced by a document tree. Once it is spent, the remaining references
+// are skipped and the documents already fetched are ingested, so that slow
+// remotes cannot stall the ingestion. 0 disables the limit.
+func SetResolveBudget(d time.Duration) {
+	resolveBudget = d
+}
+
+// SetSchemaValidation sets how SBOMs that do not match the schema of their
+// spec version are handled. Documents are only logged by default, since many
+// SBOMs produced by common tools violate the schema in harmless ways.
+func SetSchemaValidation(m SchemaValidationMode) {
+	schemaValidation = m
+}
+
 func Process(ctx context.Context, i *processor.Document) (processor.DocumentTree, error) {
-	node, err := processHelper(ctx, i)
+	tree := &resolution{
+		visited: map[string]bool{blobDigest(i.Blob): true},
+		budget:  resolveBudget,
+	}
+	node, err := processHelper(ctx, i, tree)
 	if err != nil {
 		return nil, err
 	}
@@ -60,28 +179,187 @@ func Process(ctx context.Context, i *processor.Document) (processor.DocumentTree
 	return processor.DocumentTree(node), nil
 }
 
-func processHelper(ctx context.Context, doc *processor.Document) (*processor.DocumentNode, error) {
+// resolution is the state of the resolution of the references of a document
+// tree
+type resolution struct {
+	// visited holds the URIs and digests of the documents that have been
+	// fetched, to break reference cycles
+	visited map[string]bool
+	// budget is the time left to fetch documents, unlimited if it was 0
+	// from the start
+	budget time.Duration
+	// exhausted is set once the budget is spent
+	exhausted bool
+}
+
+// processHelper processes doc and its sub-documents. The sub-documents are
+// either unpacked from doc or fetched from the references in doc. Processing
+// stops once ctx is done.
+func processHelper(ctx context.Context, doc *processor.Document, tree *resolution) (*processor.DocumentNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	ds, err := processDocument(ctx, doc)
 	if err != nil {
 		return nil, err
 	}
 
-	children := make([]*processor.DocumentNode, len(ds))
-	for i, d := range ds {
-		d.SourceInformation = doc.SourceInformation
-		n, err := processHelper(ctx, d)
-		if err != nil {
-			return nil, err
-		}
-		children[i] = n
+	children, err := processUnpacked(ctx, doc, ds, tree)
+	if err != nil {
+		return nil, err
 	}
+
+	referenced, err := processReferences(ctx, doc, tree)
+	if err != nil {
+		return nil, err
+	}
+	children = append(children, referenced...)
+
 	return &processor.DocumentNode{
 		Document: doc,
 		Children: children,
 	}, nil
 }
 
+// processUnpacked processes the sub-documents ds unpacked from doc. The lines
+// of JSON Lines documents are independent: those that cannot be processed
+// are logged and skipped, and doc only fails if all of them do.
+func processUnpacked(ctx context.Context, doc *processor.Document, ds []*processor.Document, tree *resolution) ([]*processor.DocumentNode, error) {
+	logger := logging.FromContext(ctx)
+	children := []*processor.DocumentNode{}
+	var firstErr error
+	for _, d := range ds {
+		line := d.SourceInformation.Line
+		d.SourceInformation = doc.SourceInformation
+		if line != 0 {
+			d.SourceInformation.Line = line
+		}
+		n, err := processHelper(ctx, d, tree)
+		if err != nil {
+			if doc.Type != processor.DocumentJsonLines || ctx.Err() != nil {
+				return nil, err
+			}
+			logger.Warnw("unable to process JSON Lines document line",
+				"source", doc.SourceInformation.Source,
+				"line", line,
+				"error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		children = append(children, n)
+	}
+	if len(children) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return children, nil
+}
+
+// processReferences fetches and processes the documents referenced by doc.
+// Documents that cannot be fetched or processed are logged and skipped, so
+// that doc can still be ingested. Once the resolution budget of the tree is
+// spent, the remaining references are skipped.
+func processReferences(ctx context.Context, doc *processor.Document, tree *resolution) ([]*processor.DocumentNode, error) {
+	logger := logging.FromContext(ctx)
+	if documentResolver == nil {
+		return nil, nil
+	}
+	p, ok := documentProcessors[doc.Type].(processor.DocumentReferencer)
+	if !ok {
+		return nil, nil
+	}
+	uris, err := p.ExternalReferences(doc)
+	if err != nil {
+		return nil, processor.MalformedDocumentError(fmt.Errorf("unable to get document references: %w", err))
+	}
+
+	children := []*processor.DocumentNode{}
+	for _, uri := range uris {
+		if tree.visited[uri] {
+			logger.Debugf("skipping already visited document reference %s", uri)
+			continue
+		}
+		tree.visited[uri] = true
+
+		blob, err := tree.resolve(ctx, uri)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Warnf("unable to resolve document reference %s: %v", uri, err)
+			continue
+		}
+		digest := blobDigest(blob)
+		if tree.visited[digest] {
+			logger.Debugf("skipping already visited document %s", uri)
+			continue
+		}
+		tree.visited[digest] = true
+
+		d := &processor.Document{
+			Blob:   blob,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: doc.SourceInformation.Collector,
+				Source:    uri,
+			},
+		}
+		n, err := processHelper(ctx, d, tree)
+		if err != nil {
+			logger.Warnf("unable to process referenced document %s: %v", uri, err)
+			continue
+		}
+		children = append(children, n)
+	}
+	return children, nil
+}
+
+// resolve fetches the document at uri with the resolver, within the budget
+// left, which is decreased by the time taken
+func (tree *resolution) resolve(ctx context.Context, uri string) ([]byte, error) {
+	if tree.exhausted {
+		return nil, &ResolveLimitError{URI: uri, Limit: ResolveLimitBudget, Err: context.DeadlineExceeded}
+	}
+	if tree.budget <= 0 {
+		return documentResolver.Resolve(ctx, uri)
+	}
+
+	resolveCtx, cancel := context.WithTimeout(ctx, tree.budget)
+	defer cancel()
+	start := time.Now()
+	blob, err := documentResolver.Resolve(resolveCtx, uri)
+	tree.budget -= time.Since(start)
+	if tree.budget <= 0 {
+		tree.exhausted = true
+	}
+	if err != nil && resolveCtx.Err() != nil && ctx.Err() == nil {
+		tree.exhausted = true
+		return nil, &ResolveLimitError{URI: uri, Limit: ResolveLimitBudget, Err: err}
+	}
+	return blob, err
+}
+
+func blobDigest(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// processDocument guesses the type of the document, validates it and
+// unpacks its sub-documents. The errors for documents that cannot be
+// processed have the kind processor.ErrUnsupportedFormat,
+// processor.ErrMalformedDocument or processor.ErrDocumentTooLarge; failures
+// to verify them have none, since the verifiers may fail transiently.
 func processDocument(ctx context.Context, i *processor.Document) ([]*processor.Document, error) {
+	if err := processor.CheckDocumentSize(i.SourceInformation.Source, int64(len(i.Blob))); err != nil {
+		return nil, err
+	}
+
+	if err := decompressDocument(ctx, i); err != nil {
+		return nil, processor.MalformedDocumentError(err)
+	}
+
 	if err := preProcessDocument(ctx, i); err != nil {
 		return nil, err
 	}
@@ -90,14 +368,22 @@ func processDocument(ctx context.Context, i *processor.Document) ([]*processor.D
 		return nil, err
 	}
 
+	if err := validateSBOMSchema(ctx, i); err != nil {
+		return nil, processor.MalformedDocumentError(err)
+	}
+
 	err := validateDocument(i)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := verifyDocument(ctx, i); err != nil {
+		return nil, fmt.Errorf("unable to verify document: %w", err)
+	}
+
 	ds, err := unpackDocument(i)
 	if err != nil {
-		return nil, fmt.Errorf("unable to unpack document: %w", err)
+		return nil, processor.MalformedDocumentError(fmt.Errorf("unable to unpack document: %w", err))
 	}
 
 	return ds, nil
@@ -106,11 +392,15 @@ func processDocument(ctx context.Context, i *processor.Document) ([]*processor.D
 func preProcessDocument(ctx context.Context, i *processor.Document) error {
 	docType, format, err := guesser.GuessDocument(ctx, i)
 	if err != nil {
-		return err
+		return processor.UnsupportedFormatError(err)
 	}
 
 	i.Type = docType
 	i.Format = format
+	if docType == processor.DocumentUnknown {
+		return processor.UnsupportedFormatError(fmt.Errorf("unable to detect the type of the document (format %s), the content matches none of the guessers: %s",
+			format, strings.Join(guesser.DocumentTypeGuessers(), ", ")))
+	}
 
 	return nil
 }
@@ -119,29 +409,96 @@ func validateFormat(i *processor.Document) error {
 	switch i.Format {
 	case processor.FormatJSON:
 		if !json.Valid(i.Blob) {
-			return fmt.Errorf("invalid JSON document")
+			return processor.MalformedDocumentError(fmt.Errorf("invalid JSON document"))
 		}
-	case processor.FormatUnknown:
+	case processor.FormatTagValue, processor.FormatJSONLines, processor.FormatUnknown:
 		return nil
 	default:
-		return fmt.Errorf("invalid document format type: %v", i.Format)
+		return processor.UnsupportedFormatError(fmt.Errorf("invalid document format type: %v", i.Format))
 	}
 	return nil
 }
 
+// validateSBOMSchema validates CycloneDX and SPDX JSON documents against the
+// schema of their spec version, following the schema validation mode
+func validateSBOMSchema(ctx context.Context, i *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	if schemaValidation == SchemaValidationOff || i.Format != processor.FormatJSON {
+		return nil
+	}
+	if i.Type != processor.DocumentCycloneDX && i.Type != processor.DocumentSPDX {
+		return nil
+	}
+
+	err := schema.ValidateSBOM(i)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, schema.ErrNoSchema):
+		logger.Debugf("skipping schema validation of %s: %v", i.SourceInformation.Source, err)
+		return nil
+	case schemaValidation == SchemaValidationStrict:
+		return fmt.Errorf("schema validation failed: %w", err)
+	default:
+		logger.Warnf("schema validation failed for %s: %v", i.SourceInformation.Source, err)
+		return nil
+	}
+}
+
 func validateDocument(i *processor.Document) error {
 	p, ok := documentProcessors[i.Type]
 	if !ok {
-		return fmt.Errorf("no document processor registered for type: %s", i.Type)
+		return processor.UnsupportedFormatError(fmt.Errorf("no document processor registered for type: %s", i.Type))
+	}
+
+	return processor.MalformedDocumentError(p.ValidateSchema(i))
+}
+
+func verifyDocument(ctx context.Context, i *processor.Document) error {
+	if dsseVerifier == nil {
+		return nil
 	}
 
-	return p.ValidateSchema(i)
+	var (
+		identities []verifier.Identity
+		err        error
+	)
+	switch i.Type {
+	case processor.DocumentDSSE:
+		identities, err = dsseVerifier.Verify(ctx, i.Blob)
+	case processor.DocumentSigstoreBundle:
+		identities, err = verifier.VerifyBundle(ctx, dsseVerifier, i.Blob)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	verified := false
+	for _, id := range identities {
+		if !id.Verified {
+			continue
+		}
+		if !verified {
+			i.SourceInformation.SignerIdentity = id.ID
+		}
+		verified = true
+		if id.RekorLogIndex != nil {
+			// Annotate the envelope, its payload inherits the source
+			// information when unpacked
+			i.SourceInformation.RekorLogIndex = id.RekorLogIndex
+		}
+	}
+	if !verified {
+		return ErrUnverifiedEnvelope
+	}
+	return nil
 }
 
 func unpackDocument(i *processor.Document) ([]*processor.Document, error) {
 	p, ok := documentProcessors[i.Type]
 	if !ok {
-		return nil, fmt.Errorf("no document processor registered for type: %s", i.Type)
+		return nil, processor.UnsupportedFormatError(fmt.Errorf("no document processor registered for type: %s", i.Type))
 	}
 	return p.Unpack(i)
 }
@@ -16,13 +16,23 @@
 package process
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/guacsec/guac/internal/testing/dochelper"
 	"github.com/guacsec/guac/internal/testing/simpledoc"
+	"github.com/guacsec/guac/internal/testing/testdata"
 	"github.com/guacsec/guac/pkg/handler/processor"
 	"github.com/guacsec/guac/pkg/handler/processor/guesser"
+	"github.com/guacsec/guac/pkg/ingestor/verifier"
 )
 
 func Test_SimpleDocProcessTest(t *testing.T) {
@@ -537,3 +547,550 @@ func Test_SimpleDocProcessTest(t *testing.T) {
 		})
 	}
 }
+
+type fakeVerifier struct {
+	identities []verifier.Identity
+	err        error
+}
+
+func (f *fakeVerifier) Verify(ctx context.Context, payloadBytes []byte) ([]verifier.Identity, error) {
+	return f.identities, f.err
+}
+
+func (f *fakeVerifier) Type() verifier.VerifierType {
+	return "fake"
+}
+
+func Test_verifyDocument(t *testing.T) {
+	logIndex := int64(42)
+	dsseDoc := &processor.Document{
+		Blob:   []byte(`{"payloadType": "https://in-toto.io/Statement/v0.1", "payload": "", "signatures": []}`),
+		Type:   processor.DocumentDSSE,
+		Format: processor.FormatJSON,
+	}
+	testCases := []struct {
+		name     string
+		doc      *processor.Document
+		verifier verifier.Verifier
+		wantErr  error
+		// wantLogIndex is the transparency log index the document must be
+		// annotated with
+		wantLogIndex *int64
+		// wantSigner is the signer identity the document must be annotated
+		// with
+		wantSigner string
+	}{{
+		name: "no verifier registered",
+		doc:  dsseDoc,
+	}, {
+		name:     "verified signature",
+		doc:      dsseDoc,
+		verifier: &fakeVerifier{identities: []verifier.Identity{{ID: "bad"}, {ID: "good", Verified: true}}},
+	}, {
+		name: "verified against transparency log",
+		doc: &processor.Document{
+			Blob:   dsseDoc.Blob,
+			Type:   processor.DocumentDSSE,
+			Format: processor.FormatJSON,
+		},
+		verifier:     &fakeVerifier{identities: []verifier.Identity{{ID: "good", Verified: true, RekorLogIndex: &logIndex}}},
+		wantLogIndex: &logIndex,
+		wantSigner:   "good",
+	}, {
+		name: "verified Sigstore bundle",
+		doc: &processor.Document{
+			Blob: []byte(`{
+				"mediaType": "application/vnd.dev.sigstore.bundle+json;version=0.2",
+				"verificationMaterial": {"tlogEntries": []},
+				"dsseEnvelope": {"payloadType": "application/vnd.in-toto+json", "payload": "e30=", "signatures": [{"sig": "c2ln"}]}
+			}`),
+			Type:   processor.DocumentSigstoreBundle,
+			Format: processor.FormatJSON,
+		},
+		verifier:     &fakeVerifier{identities: []verifier.Identity{{ID: "bad"}, {ID: "signer@example.com", Verified: true, RekorLogIndex: &logIndex}}},
+		wantLogIndex: &logIndex,
+		wantSigner:   "signer@example.com",
+	}, {
+		name:     "no verified signature",
+		doc:      dsseDoc,
+		verifier: &fakeVerifier{identities: []verifier.Identity{{ID: "bad"}}},
+		wantErr:  ErrUnverifiedEnvelope,
+	}, {
+		name:     "verifier error",
+		doc:      dsseDoc,
+		verifier: &fakeVerifier{err: errors.New("key not found")},
+		wantErr:  errors.New("key not found"),
+	}, {
+		name: "not a DSSE envelope",
+		doc: &processor.Document{
+			Blob:   []byte(`{}`),
+			Type:   simpledoc.SimpleDocType,
+			Format: processor.FormatJSON,
+		},
+		verifier: &fakeVerifier{},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			dsseVerifier = tt.verifier
+			defer func() { dsseVerifier = nil }()
+
+			err := verifyDocument(context.TODO(), tt.doc)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("verifyDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err.Error() != tt.wantErr.Error() {
+				t.Errorf("verifyDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tt.doc.SourceInformation.RekorLogIndex, tt.wantLogIndex) {
+				t.Errorf("verifyDocument() log index = %v, want %v", tt.doc.SourceInformation.RekorLogIndex, tt.wantLogIndex)
+			}
+			if tt.wantSigner != "" && tt.doc.SourceInformation.SignerIdentity != tt.wantSigner {
+				t.Errorf("verifyDocument() signer = %q, want %q", tt.doc.SourceInformation.SignerIdentity, tt.wantSigner)
+			}
+		})
+	}
+}
+
+type fakeResolver map[string][]byte
+
+func (f fakeResolver) Resolve(ctx context.Context, uri string) ([]byte, error) {
+	if b, ok := f[uri]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedReference, uri)
+}
+
+func spdxDocWithRefs(name string, uris ...string) []byte {
+	refs := []string{}
+	for i, uri := range uris {
+		refs = append(refs, fmt.Sprintf(`{"externalDocumentId": "DocumentRef-%d", "spdxDocument": %q, "checksum": {"algorithm": "SHA1", "checksumValue": "0000000000000000000000000000000000000000"}}`, i, uri))
+	}
+	return []byte(fmt.Sprintf(`{
+		"spdxVersion": "SPDX-2.2",
+		"dataLicense": "CC0-1.0",
+		"SPDXID": "SPDXRef-DOCUMENT",
+		"name": %q,
+		"documentNamespace": "https://example.com/%s",
+		"creationInfo": {"created": "2022-01-01T00:00:00Z", "creators": ["Tool: test"]},
+		"externalDocumentRefs": [%s]
+	}`, name, name, strings.Join(refs, ",")))
+}
+
+func Test_ProcessReferences(t *testing.T) {
+	source := processor.SourceInformation{Collector: "test", Source: "root"}
+	rootBlob := spdxDocWithRefs("root", "https://example.com/a", "https://example.com/missing")
+	aBlob := spdxDocWithRefs("a", "https://example.com/b", "https://example.com/root")
+	bBlob := spdxDocWithRefs("b", "https://example.com/a")
+	resolver := fakeResolver{
+		"https://example.com/a":    aBlob,
+		"https://example.com/b":    bBlob,
+		"https://example.com/root": rootBlob,
+	}
+	doc := processor.Document{
+		Blob:              rootBlob,
+		Type:              processor.DocumentSPDX,
+		Format:            processor.FormatJSON,
+		SourceInformation: source,
+	}
+
+	testCases := []struct {
+		name     string
+		resolver DocumentResolver
+		expected processor.DocumentTree
+	}{{
+		name:     "references disabled",
+		resolver: nil,
+		expected: dochelper.DocNode(&doc),
+	}, {
+		name:     "references followed once",
+		resolver: resolver,
+		expected: dochelper.DocNode(&doc,
+			dochelper.DocNode(&processor.Document{
+				Blob:              aBlob,
+				Type:              processor.DocumentSPDX,
+				Format:            processor.FormatJSON,
+				SourceInformation: processor.SourceInformation{Collector: "test", Source: "https://example.com/a"},
+			}, dochelper.DocNode(&processor.Document{
+				Blob:              bBlob,
+				Type:              processor.DocumentSPDX,
+				Format:            processor.FormatJSON,
+				SourceInformation: processor.SourceInformation{Collector: "test", Source: "https://example.com/b"},
+			}))),
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDocumentResolver(tt.resolver)
+			defer SetDocumentResolver(NewHTTPResolver(DefaultResolveTimeout, DefaultResolveMaxSize))
+
+			d := doc
+			// DocTreeEqual replaces the blobs of the expected documents
+			d.Blob = append([]byte{}, rootBlob...)
+			docTree, err := Process(context.TODO(), &d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !dochelper.DocTreeEqual(docTree, tt.expected) {
+				t.Errorf("got %v, expected %v", dochelper.StringTree(docTree), dochelper.StringTree(tt.expected))
+			}
+		})
+	}
+}
+
+// slowResolver is a fakeResolver taking delay to resolve each document
+type slowResolver struct {
+	fakeResolver
+	delay time.Duration
+}
+
+func (r slowResolver) Resolve(ctx context.Context, uri string) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(r.delay):
+		return r.fakeResolver.Resolve(ctx, uri)
+	}
+}
+
+func Test_ProcessReferencesBudget(t *testing.T) {
+	rootBlob := spdxDocWithRefs("root", "https://example.com/a")
+	aBlob := spdxDocWithRefs("a", "https://example.com/b")
+	bBlob := spdxDocWithRefs("b")
+	SetDocumentResolver(slowResolver{
+		fakeResolver: fakeResolver{
+			"https://example.com/a": aBlob,
+			"https://example.com/b": bBlob,
+		},
+		delay: 50 * time.Millisecond,
+	})
+	defer SetDocumentResolver(NewHTTPResolver(DefaultResolveTimeout, DefaultResolveMaxSize))
+	SetResolveBudget(80 * time.Millisecond)
+	defer SetResolveBudget(DefaultResolveBudget)
+
+	doc := processor.Document{
+		Blob:              rootBlob,
+		Type:              processor.DocumentSPDX,
+		Format:            processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{Collector: "test", Source: "root"},
+	}
+	// b cannot be fetched within the budget left after fetching a, the
+	// documents already fetched are kept
+	expected := dochelper.DocNode(&doc,
+		dochelper.DocNode(&processor.Document{
+			Blob:              aBlob,
+			Type:              processor.DocumentSPDX,
+			Format:            processor.FormatJSON,
+			SourceInformation: processor.SourceInformation{Collector: "test", Source: "https://example.com/a"},
+		}))
+
+	d := doc
+	d.Blob = append([]byte{}, rootBlob...)
+	docTree, err := Process(context.Background(), &d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dochelper.DocTreeEqual(docTree, expected) {
+		t.Errorf("got %v, expected %v", dochelper.StringTree(docTree), dochelper.StringTree(expected))
+	}
+}
+
+func Test_ProcessCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doc := processor.Document{
+		Blob:   testdata.SpdxExampleSmall,
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+	}
+	if _, err := Process(ctx, &doc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Process() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_ProcessErrorKinds(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name     string
+		doc      processor.Document
+		wantKind error
+	}{{
+		name:     "unknown document",
+		doc:      processor.Document{Blob: []byte("hello"), Type: processor.DocumentUnknown, Format: processor.FormatUnknown},
+		wantKind: processor.ErrUnsupportedFormat,
+	}, {
+		name:     "invalid JSON",
+		doc:      processor.Document{Blob: []byte("{"), Type: processor.DocumentSPDX, Format: processor.FormatJSON},
+		wantKind: processor.ErrMalformedDocument,
+	}, {
+		name:     "invalid schema",
+		doc:      processor.Document{Blob: testdata.OsvInvalid, Type: processor.DocumentOSV, Format: processor.FormatJSON},
+		wantKind: processor.ErrMalformedDocument,
+	}, {
+		name:     "unsupported format",
+		doc:      processor.Document{Blob: []byte("<bom/>"), Type: processor.DocumentCycloneDX, Format: processor.FormatXML},
+		wantKind: processor.ErrUnsupportedFormat,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(ctx, &tt.doc)
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("Process() error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func Test_ProcessMaxDocumentSize(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(testdata.SpdxExampleSmall); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	limit := int64(len(testdata.SpdxExampleSmall) - 1)
+	if int64(compressed.Len()) > limit {
+		t.Fatalf("the compressed document must be smaller than %d bytes", limit)
+	}
+
+	if err := processor.SetMaxDocumentSize(limit); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = processor.SetMaxDocumentSize(processor.DefaultMaxDocumentSize) }()
+
+	testCases := []struct {
+		name string
+		blob []byte
+	}{{
+		name: "document",
+		blob: testdata.SpdxExampleSmall,
+	}, {
+		name: "decompressed document",
+		blob: compressed.Bytes(),
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &processor.Document{
+				Blob:              tt.blob,
+				Type:              processor.DocumentUnknown,
+				Format:            processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{Source: "sbom.spdx.json"},
+			}
+			_, err := Process(context.Background(), doc)
+			if !errors.Is(err, processor.ErrDocumentTooLarge) {
+				t.Fatalf("Process() error = %v, want kind %v", err, processor.ErrDocumentTooLarge)
+			}
+			if !strings.Contains(err.Error(), "sbom.spdx.json") {
+				t.Errorf("Process() error = %v, want the source of the document", err)
+			}
+		})
+	}
+
+	if err := processor.SetMaxDocumentSize(0); err != nil {
+		t.Fatal(err)
+	}
+	doc := &processor.Document{Blob: testdata.SpdxExampleSmall, Type: processor.DocumentUnknown, Format: processor.FormatUnknown}
+	if _, err := Process(context.Background(), doc); err != nil {
+		t.Errorf("Process() without limit error = %v", err)
+	}
+}
+
+func Test_ProcessGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(testdata.SpdxExampleSmall); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		blob      []byte
+		expected  *processor.Document
+		expectErr bool
+	}{{
+		name: "gzip-compressed SBOM",
+		blob: compressed.Bytes(),
+		expected: &processor.Document{
+			Blob:   testdata.SpdxExampleSmall,
+			Type:   processor.DocumentSPDX,
+			Format: processor.FormatJSON,
+			SourceInformation: processor.SourceInformation{
+				Source:       "sbom.spdx.json.gz",
+				Decompressed: true,
+			},
+		},
+	}, {
+		name: "uncompressed SBOM",
+		blob: testdata.SpdxExampleSmall,
+		expected: &processor.Document{
+			Blob:   testdata.SpdxExampleSmall,
+			Type:   processor.DocumentSPDX,
+			Format: processor.FormatJSON,
+			SourceInformation: processor.SourceInformation{
+				Source: "sbom.spdx.json.gz",
+			},
+		},
+	}, {
+		name:      "corrupt gzip",
+		blob:      append([]byte{0x1f, 0x8b}, []byte("not gzip")...),
+		expectErr: true,
+	}, {
+		name:      "truncated gzip",
+		blob:      compressed.Bytes()[:compressed.Len()/2],
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := processor.Document{
+				Blob:              tt.blob,
+				Type:              processor.DocumentUnknown,
+				Format:            processor.FormatUnknown,
+				SourceInformation: processor.SourceInformation{Source: "sbom.spdx.json.gz"},
+			}
+			docTree, err := Process(context.Background(), &doc)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Process() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(docTree.Document, tt.expected) {
+				t.Errorf("Process() document = %+v, expected %+v", docTree.Document, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_validateSBOMSchema(t *testing.T) {
+	validCycloneDX := &processor.Document{
+		Blob:   testdata.CycloneDXExampleAlpine,
+		Type:   processor.DocumentCycloneDX,
+		Format: processor.FormatJSON,
+	}
+	// SBOMs produced by syft omit fields that SPDX 2.2 requires
+	invalidSPDX := &processor.Document{
+		Blob:   testdata.SpdxExampleAlpine,
+		Type:   processor.DocumentSPDX,
+		Format: processor.FormatJSON,
+	}
+	unsupportedVersion := &processor.Document{
+		Blob:   testdata.CycloneDXInvalidExample,
+		Type:   processor.DocumentCycloneDX,
+		Format: processor.FormatJSON,
+	}
+
+	testCases := []struct {
+		name    string
+		mode    SchemaValidationMode
+		doc     *processor.Document
+		wantErr bool
+	}{{
+		name: "valid document in strict mode",
+		mode: SchemaValidationStrict,
+		doc:  validCycloneDX,
+	}, {
+		name: "invalid document in warn mode",
+		mode: SchemaValidationWarn,
+		doc:  invalidSPDX,
+	}, {
+		name: "invalid document with validation off",
+		mode: SchemaValidationOff,
+		doc:  invalidSPDX,
+	}, {
+		name:    "invalid document in strict mode",
+		mode:    SchemaValidationStrict,
+		doc:     invalidSPDX,
+		wantErr: true,
+	}, {
+		name: "unsupported spec version in strict mode",
+		mode: SchemaValidationStrict,
+		doc:  unsupportedVersion,
+	}, {
+		name: "tag-value document in strict mode",
+		mode: SchemaValidationStrict,
+		doc: &processor.Document{
+			Blob:   testdata.SpdxTagValueExample,
+			Type:   processor.DocumentSPDX,
+			Format: processor.FormatTagValue,
+		},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSchemaValidation(tt.mode)
+			defer SetSchemaValidation(SchemaValidationWarn)
+
+			err := validateSBOMSchema(context.TODO(), tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSBOMSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ProcessJSONLines(t *testing.T) {
+	compact := func(blob []byte) string {
+		var b bytes.Buffer
+		if err := json.Compact(&b, blob); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return b.String()
+	}
+	osvLine, lifecycleLine := compact(testdata.OsvExample), compact(testdata.LifecycleExample)
+	source := processor.SourceInformation{Collector: "FileCollector", Source: "file:///attestations.intoto.jsonl"}
+
+	testCases := []struct {
+		name      string
+		blob      string
+		wantLines []int
+		wantTypes []processor.DocumentType
+		wantErr   bool
+	}{{
+		name:      "every line is processed",
+		blob:      osvLine + "\n" + lifecycleLine + "\n",
+		wantLines: []int{1, 2},
+		wantTypes: []processor.DocumentType{processor.DocumentOSV, processor.DocumentLifecycle},
+	}, {
+		name:      "malformed lines only fail themselves",
+		blob:      osvLine + "\n\n{\"truncated\": \n" + lifecycleLine + "\n{\"abc\": \"def\"}",
+		wantLines: []int{1, 4},
+		wantTypes: []processor.DocumentType{processor.DocumentOSV, processor.DocumentLifecycle},
+	}, {
+		name:    "every line failing fails the document",
+		blob:    "{\"abc\": \"def\"}\n{\"truncated\": \n{\"abc\": \"def\"}",
+		wantErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &processor.Document{
+				Blob:              []byte(tt.blob),
+				Type:              processor.DocumentUnknown,
+				Format:            processor.FormatUnknown,
+				SourceInformation: source,
+			}
+			docTree, err := Process(context.Background(), doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if docTree.Document.Type != processor.DocumentJsonLines || docTree.Document.Format != processor.FormatJSONLines {
+				t.Errorf("root document is %s %s, want JSON Lines", docTree.Document.Type, docTree.Document.Format)
+			}
+			gotLines, gotTypes := []int{}, []processor.DocumentType{}
+			for _, c := range docTree.Children {
+				gotLines = append(gotLines, c.Document.SourceInformation.Line)
+				gotTypes = append(gotTypes, c.Document.Type)
+				if c.Document.SourceInformation.Source != source.Source {
+					t.Errorf("line %d has source %s, want %s", c.Document.SourceInformation.Line, c.Document.SourceInformation.Source, source.Source)
+				}
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) || !reflect.DeepEqual(gotTypes, tt.wantTypes) {
+				t.Errorf("Process() children are lines %v of types %v, want lines %v of types %v", gotLines, gotTypes, tt.wantLines, tt.wantTypes)
+			}
+		})
+	}
+}
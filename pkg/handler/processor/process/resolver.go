@@ -0,0 +1,151 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+const (
+	// DefaultResolveTimeout is the timeout of each request of the default
+	// DocumentResolver
+	DefaultResolveTimeout = 30 * time.Second
+	// DefaultResolveMaxSize is the maximum size in bytes of the documents
+	// fetched by the default DocumentResolver
+	DefaultResolveMaxSize int64 = 64 << 20
+	// DefaultResolveBudget is the default total time spent fetching the
+	// documents referenced by a document tree, see SetResolveBudget
+	DefaultResolveBudget = 2 * time.Minute
+)
+
+// Limits of the resolution of references, reported by ResolveLimitError
+const (
+	ResolveLimitTimeout = "timeout"
+	ResolveLimitSize    = "size"
+	ResolveLimitBudget  = "budget"
+)
+
+// ErrUnsupportedReference is returned by a DocumentResolver that cannot fetch
+// documents from the given URI (e.g., because of its scheme)
+var ErrUnsupportedReference = errors.New("unsupported document reference")
+
+// ResolveLimitError is returned when fetching a referenced document exceeds
+// one of the limits: the timeout of the request, the maximum size of the
+// document or the time budget of the document tree.
+type ResolveLimitError struct {
+	URI   string
+	Limit string
+	Err   error
+}
+
+func (e *ResolveLimitError) Error() string {
+	return fmt.Sprintf("resolving %s exceeded the %s limit: %v", e.URI, e.Limit, e.Err)
+}
+
+func (e *ResolveLimitError) Unwrap() error {
+	return e.Err
+}
+
+// DocumentResolver fetches the documents referenced by other documents
+type DocumentResolver interface {
+	// Resolve returns the contents of the document at uri
+	Resolve(ctx context.Context, uri string) ([]byte, error)
+}
+
+type httpResolver struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// NewHTTPResolver returns a DocumentResolver that fetches HTTP(S) URIs,
+// failing requests that take longer than timeout and documents larger than
+// maxSize bytes (or MaxDocumentSize, if it is lower or maxSize is 0) with a
+// ResolveLimitError.
+func NewHTTPResolver(timeout time.Duration, maxSize int64) DocumentResolver {
+	return &httpResolver{
+		client:  &http.Client{Timeout: timeout},
+		maxSize: maxSize,
+	}
+}
+
+func (r *httpResolver) Resolve(ctx context.Context, uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedReference, uri)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, limitError(ctx, uri, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: %s", uri, resp.Status)
+	}
+
+	limit := processor.MaxDocumentSize()
+	if r.maxSize > 0 && (limit == 0 || r.maxSize < limit) {
+		limit = r.maxSize
+	}
+	if limit == 0 {
+		blob, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, limitError(ctx, uri, err)
+		}
+		return blob, nil
+	}
+	tooLarge := &ResolveLimitError{URI: uri, Limit: ResolveLimitSize, Err: processor.DocumentTooLargeError(uri, limit)}
+	if resp.ContentLength > limit {
+		return nil, tooLarge
+	}
+	blob, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, limitError(ctx, uri, err)
+	}
+	if int64(len(blob)) > limit {
+		return nil, tooLarge
+	}
+	return blob, nil
+}
+
+// limitError returns err as a ResolveLimitError if the request failed
+// because it timed out, rather than because ctx was canceled
+func limitError(ctx context.Context, uri string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &ResolveLimitError{URI: uri, Limit: ResolveLimitTimeout, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package process
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HTTPResolverLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		case "/large":
+			// no Content-Length, so that the limit applies while reading
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte(strings.Repeat("a", 64)))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name      string
+		path      string
+		wantBlob  string
+		wantLimit string
+	}{{
+		name:     "within limits",
+		path:     "/doc",
+		wantBlob: "ok",
+	}, {
+		name:      "too slow",
+		path:      "/slow",
+		wantLimit: ResolveLimitTimeout,
+	}, {
+		name:      "too large",
+		path:      "/large",
+		wantLimit: ResolveLimitSize,
+	}}
+
+	resolver := NewHTTPResolver(50*time.Millisecond, 16)
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := resolver.Resolve(context.Background(), server.URL+tt.path)
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(blob) != tt.wantBlob {
+					t.Errorf("got %q, expected %q", blob, tt.wantBlob)
+				}
+				return
+			}
+			var limitErr *ResolveLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected a ResolveLimitError, got %v", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("got limit %q, expected %q", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
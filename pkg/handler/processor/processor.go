@@ -28,8 +28,21 @@ type DocumentProcessor interface {
 	Unpack(i *Document) ([]*Document, error)
 }
 
+// DocumentReferencer is implemented by the DocumentProcessors of documents
+// that can reference other documents by URI (e.g., an SBOM referencing the
+// SBOMs of its components).
+type DocumentReferencer interface {
+	// ExternalReferences returns the URIs of the documents referenced by
+	// the document. Returns list of len=0 and nil error if there are none.
+	ExternalReferences(i *Document) ([]string, error)
+}
+
 // Document describes the input for a processor to run. This input can
 // come from a collector or from the processor itself (run recursively).
+//
+// Collectors that do not know what they collected set Type and Format to
+// DocumentUnknown and FormatUnknown; the processor then detects them from
+// the content of Blob.
 type Document struct {
 	Blob              []byte
 	Type              DocumentType
@@ -52,15 +65,35 @@ type DocumentType string
 
 // Document* is the enumerables of DocumentType
 const (
-	DocumentITE6SLSA    DocumentType = "SLSA"
-	DocumentITE6Generic DocumentType = "ITE6"
-	DocumentITE6Vul     DocumentType = "ITE6VUL"
-	DocumentDSSE        DocumentType = "DSSE"
-	DocumentSPDX        DocumentType = "SPDX"
-	DocumentJsonLines   DocumentType = "JSON_LINES"
-	DocumentScorecard   DocumentType = "SCORECARD"
-	DocumentCycloneDX   DocumentType = "CycloneDX"
-	DocumentUnknown     DocumentType = "UNKNOWN"
+	DocumentITE6SLSA       DocumentType = "SLSA"
+	DocumentITE6Generic    DocumentType = "ITE6"
+	DocumentITE6Vul        DocumentType = "ITE6VUL"
+	DocumentITE6SameAs     DocumentType = "ITE6SAMEAS"
+	DocumentDSSE           DocumentType = "DSSE"
+	DocumentSigstoreBundle DocumentType = "SIGSTORE_BUNDLE"
+	DocumentSPDX           DocumentType = "SPDX"
+	DocumentJsonLines      DocumentType = "JSON_LINES"
+	DocumentScorecard      DocumentType = "SCORECARD"
+	DocumentCycloneDX      DocumentType = "CycloneDX"
+	DocumentOSV            DocumentType = "OSV"
+	DocumentOpenVEX        DocumentType = "OPEN_VEX"
+	DocumentCSAF           DocumentType = "CSAF"
+	DocumentInTotoLink     DocumentType = "IN_TOTO_LINK"
+	DocumentInTotoLayout   DocumentType = "IN_TOTO_LAYOUT"
+	DocumentSyft           DocumentType = "SYFT"
+	DocumentTrivy          DocumentType = "TRIVY"
+	DocumentLifecycle      DocumentType = "LIFECYCLE"
+	DocumentClearlyDefined DocumentType = "CLEARLY_DEFINED"
+	DocumentSARIF          DocumentType = "SARIF"
+	DocumentDepSnapshot    DocumentType = "DEPENDENCY_SNAPSHOT"
+	DocumentImageConfig    DocumentType = "IMAGE_CONFIG"
+	DocumentImageManifest  DocumentType = "IMAGE_MANIFEST"
+	DocumentNpmLock        DocumentType = "NPM_LOCK"
+	DocumentGoMod          DocumentType = "GO_MOD"
+	DocumentGoSum          DocumentType = "GO_SUM"
+	DocumentRequirements   DocumentType = "PIP_REQUIREMENTS"
+	DocumentCargoLock      DocumentType = "CARGO_LOCK"
+	DocumentUnknown        DocumentType = "UNKNOWN"
 )
 
 // FormatType describes the document format for malform checks
@@ -71,6 +104,7 @@ const (
 	FormatJSON      FormatType = "JSON"
 	FormatJSONLines FormatType = "JSON_LINES"
 	FormatXML       FormatType = "XML"
+	FormatTagValue  FormatType = "TAG_VALUE"
 	FormatUnknown   FormatType = "UNKNOWN"
 )
 
@@ -80,4 +114,21 @@ type SourceInformation struct {
 	Collector string
 	// Source describes the source which the collector got this information
 	Source string
+	// RekorLogIndex is the index of the transparency log entry against which
+	// the document (or the envelope it was unpacked from) was verified. It is
+	// nil if the document was not checked against a transparency log.
+	RekorLogIndex *int64
+	// SignerIdentity is the verified identity (e.g. the email or workflow
+	// of a keyless signing certificate) that signed the document, or the
+	// envelope it was unpacked from. It is empty if no signature was
+	// verified.
+	SignerIdentity string
+	// Decompressed tells whether the document (or the document it was
+	// unpacked from) was delivered gzip-compressed, and decompressed by the
+	// processor
+	Decompressed bool
+	// Line is the line number, starting at 1, of the document in the JSON
+	// Lines document it was unpacked from (or of the document it was
+	// unpacked from). It is 0 for the other documents.
+	Line int
 }
@@ -0,0 +1,271 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Version is the version of SARIF supported
+const Version = "2.1.0"
+
+// Log is a SARIF log (https://docs.oasis-open.org/sarif/sarif/v2.1.0/), as
+// emitted by static analysis tools. Only the fields that are ingested are
+// decoded.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is a single invocation of a tool
+type Run struct {
+	Tool      Tool       `json:"tool"`
+	Results   []Result   `json:"results"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// VersionControlProvenance gives the repositories, and their revision,
+	// the analyzed files come from
+	VersionControlProvenance []VersionControlDetails `json:"versionControlProvenance,omitempty"`
+}
+
+type Tool struct {
+	Driver ToolComponent `json:"driver"`
+}
+
+type ToolComponent struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// Rule is a rule of the tool, a "reportingDescriptor" in SARIF
+type Rule struct {
+	ID                   string `json:"id"`
+	DefaultConfiguration *struct {
+		Level string `json:"level,omitempty"`
+	} `json:"defaultConfiguration,omitempty"`
+	Properties struct {
+		// SecuritySeverity is the CVSS-like score (0.0 to 10.0) of the
+		// security rules, as used by GitHub code scanning
+		SecuritySeverity json.Number `json:"security-severity,omitempty"`
+	} `json:"properties,omitempty"`
+}
+
+// Result is a finding of the tool
+type Result struct {
+	RuleID    string `json:"ruleId,omitempty"`
+	RuleIndex *int   `json:"ruleIndex,omitempty"`
+	Rule      *struct {
+		ID    string `json:"id,omitempty"`
+		Index *int   `json:"index,omitempty"`
+	} `json:"rule,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Message struct {
+		Text string `json:"text,omitempty"`
+	} `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	Fingerprints        map[string]string `json:"fingerprints,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *struct {
+		StartLine   int `json:"startLine,omitempty"`
+		StartColumn int `json:"startColumn,omitempty"`
+	} `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI   string `json:"uri,omitempty"`
+	Index *int   `json:"index,omitempty"`
+}
+
+// Artifact is a file analyzed by the run. Its hashes are keyed by algorithm
+// (e.g. `sha-256`).
+type Artifact struct {
+	Location ArtifactLocation  `json:"location"`
+	Hashes   map[string]string `json:"hashes,omitempty"`
+}
+
+type VersionControlDetails struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+// IsSARIFSchema returns whether the `$schema` URI of a document is one of the
+// SARIF schema, e.g. `https://json.schemastore.org/sarif-2.1.0.json` or
+// `https://docs.oasis-open.org/sarif/sarif/v2.1.0/os/schemas/sarif-schema-2.1.0.json`
+func IsSARIFSchema(uri string) bool {
+	return strings.Contains(strings.ToLower(uri), "sarif")
+}
+
+// Rule returns the rule of the tool that the result is for, or nil if the
+// tool does not describe it
+func (r Run) Rule(res Result) *Rule {
+	index := res.RuleIndex
+	if index == nil && res.Rule != nil {
+		index = res.Rule.Index
+	}
+	rules := r.Tool.Driver.Rules
+	if index != nil && *index >= 0 && *index < len(rules) {
+		return &rules[*index]
+	}
+	id := res.RuleID
+	if id == "" && res.Rule != nil {
+		id = res.Rule.ID
+	}
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// RuleID returns the id of the rule that the result is for
+func (r Run) RuleID(res Result) string {
+	if res.RuleID != "" {
+		return res.RuleID
+	}
+	if res.Rule != nil && res.Rule.ID != "" {
+		return res.Rule.ID
+	}
+	if rule := r.Rule(res); rule != nil {
+		return rule.ID
+	}
+	return ""
+}
+
+// Level returns the level of the result: its own, or the default level of
+// its rule. As specified by SARIF, results that are not failures have level
+// `none`, and failures `warning` when no level is given.
+func (r Run) Level(res Result) string {
+	if res.Kind != "" && res.Kind != "fail" {
+		return "none"
+	}
+	if res.Level != "" {
+		return res.Level
+	}
+	if rule := r.Rule(res); rule != nil && rule.DefaultConfiguration != nil && rule.DefaultConfiguration.Level != "" {
+		return rule.DefaultConfiguration.Level
+	}
+	return "warning"
+}
+
+// Severity returns the security severity of the result, from the score of
+// its rule, bucketed like GitHub code scanning does: `critical` (9.0 and
+// above), `high` (7.0 to 8.9), `medium` (4.0 to 6.9) or `low`. It returns ""
+// if the rule has no score.
+func (r Run) Severity(res Result) string {
+	rule := r.Rule(res)
+	if rule == nil || rule.Properties.SecuritySeverity == "" {
+		return ""
+	}
+	score, err := strconv.ParseFloat(string(rule.Properties.SecuritySeverity), 64)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case score >= 9.0:
+		return "critical"
+	case score >= 7.0:
+		return "high"
+	case score >= 4.0:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return ""
+}
+
+// Artifact returns the artifact of the run at the location, or nil if the
+// location does not reference one
+func (r Run) Artifact(loc ArtifactLocation) *Artifact {
+	if loc.Index != nil && *loc.Index >= 0 && *loc.Index < len(r.Artifacts) {
+		return &r.Artifacts[*loc.Index]
+	}
+	if loc.URI == "" {
+		return nil
+	}
+	for i := range r.Artifacts {
+		if r.Artifacts[i].Location.URI == loc.URI {
+			return &r.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// SARIFProcessor processes SARIF logs.
+// Currently only supports JSON documents
+type SARIFProcessor struct {
+}
+
+func (p *SARIFProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentSARIF {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSARIF, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var log Log
+		if err := json.Unmarshal(d.Blob, &log); err != nil {
+			return err
+		}
+		if !IsSARIFSchema(log.Schema) {
+			return fmt.Errorf("unexpected schema %q for a SARIF log", log.Schema)
+		}
+		if log.Version != Version {
+			return fmt.Errorf("unsupported SARIF version %q, expected %s", log.Version, Version)
+		}
+		if len(log.Runs) == 0 {
+			return fmt.Errorf("missing runs of SARIF log")
+		}
+		for i, run := range log.Runs {
+			if run.Tool.Driver.Name == "" {
+				return fmt.Errorf("missing tool name of run %d", i)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of SARIF document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *SARIFProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentSARIF {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSARIF, d.Type)
+	}
+
+	// SARIF logs don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
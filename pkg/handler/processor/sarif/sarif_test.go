@@ -0,0 +1,154 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sarif
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestSARIFProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "SARIF document",
+		doc: processor.Document{
+			Blob:              testdata.SARIFExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentSARIF,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.SARIFExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := SARIFProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("SARIFProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("SARIFProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSARIFProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blob      []byte
+		format    processor.FormatType
+		expectErr bool
+	}{{
+		name:   "valid SARIF document",
+		blob:   testdata.SARIFExample,
+		format: processor.FormatJSON,
+	}, {
+		name:      "run without tool name",
+		blob:      testdata.SARIFInvalid,
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "unsupported version",
+		blob:      []byte(`{"$schema": "https://json.schemastore.org/sarif-1.0.0.json", "version": "1.0.0", "runs": [{"tool": {"name": "old"}}]}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "not a SARIF schema",
+		blob:      []byte(`{"$schema": "http://cyclonedx.org/schema/bom-1.5.schema.json", "version": "2.1.0", "runs": [{"tool": {"driver": {"name": "x"}}}]}`),
+		format:    processor.FormatJSON,
+		expectErr: true,
+	}, {
+		name:      "unsupported format",
+		blob:      testdata.SARIFExample,
+		format:    processor.FormatXML,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := SARIFProcessor{}
+			err := d.ValidateSchema(&processor.Document{
+				Blob:   tt.blob,
+				Format: tt.format,
+				Type:   processor.DocumentSARIF,
+			})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("SARIFProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestRun_Results(t *testing.T) {
+	var log Log
+	if err := json.Unmarshal(testdata.SARIFExample, &log); err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		RuleID   string
+		Level    string
+		Severity string
+	}
+	got := []result{}
+	for _, run := range log.Runs {
+		for _, res := range run.Results {
+			got = append(got, result{run.RuleID(res), run.Level(res), run.Severity(res)})
+		}
+	}
+	want := []result{
+		// level of the rule, severity given as a string
+		{"go/sql-injection", "error", "high"},
+		// rule referenced by the rule object, severity given as a number
+		{"go/log-injection", "error", "high"},
+		// level of the result
+		{"go/unhandled-writable-file-close", "note", ""},
+		// no level given
+		{"BIN001", "warning", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got results %v, want %v", got, want)
+	}
+
+	run := Run{Tool: Tool{Driver: ToolComponent{Rules: []Rule{{ID: "r"}}}}}
+	if level := run.Level(Result{RuleID: "r", Kind: "pass", Level: "error"}); level != "none" {
+		t.Errorf("got level %q for a passing result, want none", level)
+	}
+	for score, want := range map[string]string{"9.8": "critical", "7.0": "high", "5": "medium", "0.1": "low", "0": "", "n/a": ""} {
+		run.Tool.Driver.Rules[0].Properties.SecuritySeverity = json.Number(score)
+		if got := run.Severity(Result{RuleID: "r"}); got != want {
+			t.Errorf("got severity %q for score %s, want %q", got, score, want)
+		}
+	}
+}
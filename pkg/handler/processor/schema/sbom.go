@@ -0,0 +1,227 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// maxReportedErrors is the number of violations included in the message of
+// a ValidationError
+const maxReportedErrors = 10
+
+// The schemas are the official CycloneDX and SPDX JSON schemas, unmodified:
+//   - cyclonedx-*: the schema/ folder of github.com/CycloneDX/cyclonedx-go
+//     v0.7.2, copied from github.com/CycloneDX/specification. For 1.2 and
+//     1.3, these are the strict variants (bom-1.x-strict.schema.json), which
+//     reject unknown properties like the 1.4 and 1.5 schemas do. The schemas
+//     reference the SPDX license ids (cyclonedx-spdx) and JSF signature
+//     (cyclonedx-jsf-0.82) schemas by their $id.
+//   - spdx-2.2: schemas/spdx-schema.json of github.com/spdx/spdx-spec v2.2.2
+//   - spdx-2.3: schemas/spdx-schema.json of github.com/spdx/spdx-spec v2.3, as
+//     vendored by github.com/CycloneDX/sbom-utility v0.16.0
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// sbomSchemas maps the document types to the schema file of each spec
+// version
+var sbomSchemas = map[processor.DocumentType]map[string]string{
+	processor.DocumentCycloneDX: {
+		"1.2": "schemas/cyclonedx-1.2.schema.json",
+		"1.3": "schemas/cyclonedx-1.3.schema.json",
+		"1.4": "schemas/cyclonedx-1.4.schema.json",
+		"1.5": "schemas/cyclonedx-1.5.schema.json",
+	},
+	processor.DocumentSPDX: {
+		"2.2": "schemas/spdx-2.2.schema.json",
+		"2.3": "schemas/spdx-2.3.schema.json",
+	},
+}
+
+// cycloneDXReferences are the schemas referenced by the CycloneDX schemas
+var cycloneDXReferences = []string{
+	"schemas/cyclonedx-spdx.schema.json",
+	"schemas/cyclonedx-jsf-0.82.schema.json",
+}
+
+var (
+	compiled   = map[string]*gojsonschema.Schema{}
+	compiledMu sync.Mutex
+)
+
+// ErrNoSchema is returned when there is no schema for the type or the spec
+// version of a document
+var ErrNoSchema = errors.New("no schema available")
+
+// FieldError is a violation of the schema by a value of the document
+type FieldError struct {
+	// Path is the JSON pointer to the value
+	Path   string
+	Reason string
+}
+
+func (e FieldError) String() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Reason)
+}
+
+// ValidationError is returned when a document does not match the schema of
+// its spec version
+type ValidationError struct {
+	DocumentType processor.DocumentType
+	SpecVersion  string
+	Errors       []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reported := e.Errors
+	if len(reported) > maxReportedErrors {
+		reported = reported[:maxReportedErrors]
+	}
+	msgs := make([]string, len(reported))
+	for i, fe := range reported {
+		msgs[i] = fe.String()
+	}
+	msg := fmt.Sprintf("%s %s document does not match its schema: %s", e.DocumentType, e.SpecVersion, strings.Join(msgs, "; "))
+	if more := len(e.Errors) - len(reported); more > 0 {
+		msg += fmt.Sprintf(" (and %d more errors)", more)
+	}
+	return msg
+}
+
+// ValidateSBOM validates a CycloneDX or SPDX JSON document against the schema
+// of the spec version it declares. It returns a *ValidationError listing the
+// violations if the document does not match, and ErrNoSchema if the type,
+// format or spec version of the document is not supported.
+func ValidateSBOM(d *processor.Document) error {
+	if d.Format != processor.FormatJSON {
+		return fmt.Errorf("%w for format %s", ErrNoSchema, d.Format)
+	}
+	var value interface{}
+	if err := json.Unmarshal(d.Blob, &value); err != nil {
+		return fmt.Errorf("unable to parse document: %w", err)
+	}
+	version, err := specVersion(d.Type, value)
+	if err != nil {
+		return err
+	}
+	s, err := sbomSchema(d.Type, version)
+	if err != nil {
+		return err
+	}
+	result, err := s.Validate(gojsonschema.NewBytesLoader(d.Blob))
+	if err != nil {
+		return fmt.Errorf("unable to validate document: %w", err)
+	}
+	if !result.Valid() {
+		return &ValidationError{DocumentType: d.Type, SpecVersion: version, Errors: fieldErrors(result.Errors())}
+	}
+	return nil
+}
+
+// fieldErrors converts the errors of gojsonschema, sorted by the JSON pointer
+// of the values
+func fieldErrors(errs []gojsonschema.ResultError) []FieldError {
+	fieldErrs := make([]FieldError, len(errs))
+	for i, e := range errs {
+		fieldErrs[i] = FieldError{Path: pointer(e.Context()), Reason: e.Description()}
+	}
+	sort.SliceStable(fieldErrs, func(i, j int) bool { return fieldErrs[i].Path < fieldErrs[j].Path })
+	return fieldErrs
+}
+
+// pointer returns the JSON pointer, as defined by RFC 6901, of the value at
+// the context
+func pointer(context *gojsonschema.JsonContext) string {
+	// the first token is the root of the document
+	tokens := strings.Split(context.String("\x00"), "\x00")[1:]
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// specVersion returns the spec version declared by the document, without the
+// "SPDX-" prefix for SPDX documents
+func specVersion(docType processor.DocumentType, value interface{}) (string, error) {
+	var field, prefix string
+	switch docType {
+	case processor.DocumentCycloneDX:
+		field = "specVersion"
+	case processor.DocumentSPDX:
+		field, prefix = "spdxVersion", "SPDX-"
+	default:
+		return "", fmt.Errorf("%w for document type %s", ErrNoSchema, docType)
+	}
+	root, _ := value.(map[string]interface{})
+	version, ok := root[field].(string)
+	if !ok {
+		return "", &ValidationError{
+			DocumentType: docType,
+			Errors:       []FieldError{{Reason: fmt.Sprintf("missing spec version %q", field)}},
+		}
+	}
+	return strings.TrimPrefix(version, prefix), nil
+}
+
+func sbomSchema(docType processor.DocumentType, version string) (*gojsonschema.Schema, error) {
+	file, ok := sbomSchemas[docType][version]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s version %s", ErrNoSchema, docType, version)
+	}
+
+	compiledMu.Lock()
+	defer compiledMu.Unlock()
+	if s, ok := compiled[file]; ok {
+		return s, nil
+	}
+	loader := gojsonschema.NewSchemaLoader()
+	if docType == processor.DocumentCycloneDX {
+		for _, ref := range cycloneDXReferences {
+			data, err := schemaFiles.ReadFile(ref)
+			if err != nil {
+				return nil, err
+			}
+			if err := loader.AddSchemas(gojsonschema.NewBytesLoader(data)); err != nil {
+				return nil, fmt.Errorf("unable to load %s: %w", ref, err)
+			}
+		}
+	}
+	data, err := schemaFiles.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s, err := loader.Compile(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile %s: %w", file, err)
+	}
+	compiled[file] = s
+	return s, nil
+}
@@ -0,0 +1,122 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+const spdx23Document = `{
+	"spdxVersion": "SPDX-2.3",
+	"dataLicense": "CC0-1.0",
+	"SPDXID": "SPDXRef-DOCUMENT",
+	"name": "hello",
+	"documentNamespace": "https://example.com/hello",
+	"creationInfo": {"created": "2022-01-01T00:00:00Z", "creators": ["Tool: test"]},
+	"packages": [{
+		"SPDXID": "SPDXRef-Package-hello",
+		"name": "hello",
+		"downloadLocation": "NOASSERTION",
+		"primaryPackagePurpose": "APPLICATION",
+		"externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:generic/hello@1.0"}]
+	}],
+	"relationships": [{"spdxElementId": "SPDXRef-DOCUMENT", "relatedSpdxElement": "SPDXRef-Package-hello", "relationshipType": "DESCRIBES"}]
+}`
+
+func TestValidateSBOM(t *testing.T) {
+	testCases := []struct {
+		name       string
+		doc        processor.Document
+		wantErr    error
+		wantErrMsg string
+	}{{
+		name: "CycloneDX 1.4",
+		doc:  processor.Document{Blob: testdata.CycloneDXExampleQuarkusDeps, Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+	}, {
+		name: "CycloneDX 1.5",
+		doc:  processor.Document{Blob: testdata.CycloneDXVulnExample, Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+	}, {
+		name: "SPDX 2.3",
+		doc:  processor.Document{Blob: []byte(spdx23Document), Type: processor.DocumentSPDX, Format: processor.FormatJSON},
+	}, {
+		name:       "SPDX 2.2 violating the schema",
+		doc:        processor.Document{Blob: testdata.SpdxExampleAlpine, Type: processor.DocumentSPDX, Format: processor.FormatJSON},
+		wantErrMsg: `/packages/0: copyrightText is required`,
+	}, {
+		name:       "CycloneDX with invalid component type",
+		doc:        processor.Document{Blob: []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1, "components": [{"type": "libary", "name": "a"}]}`), Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+		wantErrMsg: `/components/0/type: components.0.type must be one of the following`,
+	}, {
+		name: "CycloneDX 1.3 with component properties",
+		doc:  processor.Document{Blob: []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.3", "version": 1, "components": [{"type": "library", "name": "a", "version": "1", "properties": [{"name": "p", "value": "v"}]}]}`), Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+	}, {
+		name:       "CycloneDX 1.2 with component properties, added in 1.3",
+		doc:        processor.Document{Blob: []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.2", "version": 1, "components": [{"type": "library", "name": "a", "version": "1", "properties": [{"name": "p", "value": "v"}]}]}`), Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+		wantErrMsg: `/components/0: Additional property properties is not allowed`,
+	}, {
+		name:       "missing spec version",
+		doc:        processor.Document{Blob: []byte(`{"bomFormat": "CycloneDX"}`), Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+		wantErrMsg: `missing spec version "specVersion"`,
+	}, {
+		name:    "unsupported spec version",
+		doc:     processor.Document{Blob: testdata.CycloneDXInvalidExample, Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+		wantErr: ErrNoSchema,
+	}, {
+		name:    "tag-value document",
+		doc:     processor.Document{Blob: testdata.SpdxTagValueExample, Type: processor.DocumentSPDX, Format: processor.FormatTagValue},
+		wantErr: ErrNoSchema,
+	}, {
+		name:    "unsupported document type",
+		doc:     processor.Document{Blob: []byte(`{}`), Type: processor.DocumentOSV, Format: processor.FormatJSON},
+		wantErr: ErrNoSchema,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSBOM(&tt.doc)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ValidateSBOM() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantErrMsg != "":
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("ValidateSBOM() error = %v, want a validation error", err)
+				}
+				if !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("ValidateSBOM() error = %v, want it to contain %q", err, tt.wantErrMsg)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSBOMSchemasCompile(t *testing.T) {
+	for docType, versions := range sbomSchemas {
+		for version := range versions {
+			if _, err := sbomSchema(docType, version); err != nil {
+				t.Errorf("unable to compile the %s %s schema: %v", docType, version, err)
+			}
+		}
+	}
+}
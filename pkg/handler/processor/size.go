@@ -0,0 +1,78 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// DefaultMaxDocumentSize is the maximum size in bytes of a document, unless
+// set with SetMaxDocumentSize
+const DefaultMaxDocumentSize int64 = 512 << 20
+
+var maxDocumentSize = DefaultMaxDocumentSize
+
+// SetMaxDocumentSize sets the maximum size in bytes of the documents that
+// collectors read and the processor accepts, so that a single document
+// cannot exhaust the memory. 0 disables the limit.
+func SetMaxDocumentSize(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("the maximum document size must not be negative, got %d", n)
+	}
+	atomic.StoreInt64(&maxDocumentSize, n)
+	return nil
+}
+
+// MaxDocumentSize returns the maximum size in bytes of a document, or 0 if
+// there is no limit
+func MaxDocumentSize() int64 {
+	return atomic.LoadInt64(&maxDocumentSize)
+}
+
+// DocumentTooLargeError returns the error, of kind ErrDocumentTooLarge, for
+// the document from source exceeding the limit
+func DocumentTooLargeError(source string, limit int64) error {
+	return &Error{Kind: ErrDocumentTooLarge, Err: fmt.Errorf("document %s is larger than the maximum document size of %d bytes", source, limit)}
+}
+
+// CheckDocumentSize returns an error of kind ErrDocumentTooLarge if size is
+// larger than MaxDocumentSize, e.g. to skip a document before downloading it
+func CheckDocumentSize(source string, size int64) error {
+	if limit := MaxDocumentSize(); limit > 0 && size > limit {
+		return DocumentTooLargeError(source, limit)
+	}
+	return nil
+}
+
+// ReadDocument reads the document from source through r. It stops reading
+// after MaxDocumentSize bytes, failing with an error of kind
+// ErrDocumentTooLarge if there is more.
+func ReadDocument(r io.Reader, source string) ([]byte, error) {
+	limit := MaxDocumentSize()
+	if limit == 0 {
+		return io.ReadAll(r)
+	}
+	blob, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(blob)) > limit {
+		return nil, DocumentTooLargeError(source, limit)
+	}
+	return blob, nil
+}
@@ -16,31 +16,72 @@
 package spdx
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
 	spdx_json "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2_2"
+	"github.com/spdx/tools-golang/tvloader"
 )
 
 // SPDXProcessor processes SPDX documents.
-// Currently only supports JSON SPDX documents
+// Supports JSON and tag-value SPDX documents, versions 2.2 and 2.3.
 type SPDXProcessor struct {
 }
 
+// spdx23Tags are the tag-value tags introduced in SPDX 2.3, which the SPDX 2.2
+// tag-value loader rejects. They carry no information used by GUAC.
+var spdx23Tags = []string{
+	"PrimaryPackagePurpose:",
+	"ReleaseDate:",
+	"BuiltDate:",
+	"ValidUntilDate:",
+}
+
+// LoadDocument parses an SPDX 2.2 or 2.3 document from a JSON or tag-value
+// blob. SPDX 2.3 documents are loaded using the SPDX 2.2 model, dropping the
+// fields that were added in 2.3.
+func LoadDocument(blob []byte, format processor.FormatType) (*v2_2.Document, error) {
+	switch format {
+	case processor.FormatJSON:
+		return spdx_json.Load2_2(bytes.NewReader(blob))
+	case processor.FormatTagValue:
+		return tvloader.Load2_2(bytes.NewReader(dropSPDX23Tags(blob)))
+	}
+	return nil, fmt.Errorf("unable to support parsing of SPDX document format: %v", format)
+}
+
+func dropSPDX23Tags(blob []byte) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(blob)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		skip := false
+		for _, tag := range spdx23Tags {
+			if strings.HasPrefix(strings.TrimSpace(line), tag) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+	}
+	return out.Bytes()
+}
+
 func (p *SPDXProcessor) ValidateSchema(d *processor.Document) error {
 	if d.Type != processor.DocumentSPDX {
 		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSPDX, d.Type)
 	}
 
-	switch d.Format {
-	case processor.FormatJSON:
-		reader := bytes.NewReader(d.Blob)
-		_, err := spdx_json.Load2_2(reader)
-		return err
-	}
-
-	return fmt.Errorf("unable to support parsing of SPDX document format: %v", d.Format)
+	_, err := LoadDocument(d.Blob, d.Format)
+	return err
 }
 
 // Unpack takes in the document and tries to unpack it
@@ -56,3 +97,23 @@ func (p *SPDXProcessor) Unpack(d *processor.Document) ([]*processor.Document, er
 	// SPDX doesn't unpack into additional documents at the moment.
 	return []*processor.Document{}, nil
 }
+
+// ExternalReferences returns the URIs of the SPDX documents listed in
+// `externalDocumentRefs`
+func (p *SPDXProcessor) ExternalReferences(d *processor.Document) ([]string, error) {
+	if d.Type != processor.DocumentSPDX {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSPDX, d.Type)
+	}
+
+	spdxDoc, err := LoadDocument(d.Blob, d.Format)
+	if err != nil {
+		return nil, err
+	}
+	uris := []string{}
+	for _, ref := range spdxDoc.ExternalDocumentReferences {
+		if ref.URI != "" {
+			uris = append(uris, ref.URI)
+		}
+	}
+	return uris, nil
+}
@@ -102,6 +102,15 @@ func TestSPDXProcessor_ValidateSchema(t *testing.T) {
 			SourceInformation: processor.SourceInformation{},
 		},
 		expectErr: true,
+	}, {
+		name: "valid tag-value SPDX 2.3 document",
+		doc: processor.Document{
+			Blob:              testdata.SpdxTagValueExample,
+			Format:            processor.FormatTagValue,
+			Type:              processor.DocumentSPDX,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: false,
 	}, {
 		name: "invalid format supported",
 		doc: processor.Document{
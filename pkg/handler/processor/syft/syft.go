@@ -0,0 +1,191 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syft
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// DescriptorName is the name of the tool in the descriptor of Syft JSON
+// documents
+const DescriptorName = "syft"
+
+// SourceTypeImage is the type of the source of documents describing a
+// container image
+const SourceTypeImage = "image"
+
+// RelationshipDependencyOf is the type of the relationships where the parent
+// package is a dependency of the child package
+const RelationshipDependencyOf = "dependency-of"
+
+// Document is a Syft JSON document, as described by the schemas at
+// https://github.com/anchore/syft/tree/main/schema/json. Only the fields used
+// by GUAC are decoded.
+type Document struct {
+	Artifacts             []Package      `json:"artifacts"`
+	ArtifactRelationships []Relationship `json:"artifactRelationships"`
+	Source                Source         `json:"source"`
+	Descriptor            Descriptor     `json:"descriptor"`
+}
+
+// Package is a package found by Syft
+type Package struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	PURL    string `json:"purl"`
+	CPEs    []CPE  `json:"cpes"`
+}
+
+// CPE is a CPE of a package. Up to schema v14, it is given as a plain
+// string.
+type CPE struct {
+	CPE    string `json:"cpe"`
+	Source string `json:"source,omitempty"`
+}
+
+func (c *CPE) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.CPE); err == nil {
+		return nil
+	}
+	type cpe CPE
+	return json.Unmarshal(b, (*cpe)(c))
+}
+
+// Relationship links two packages, or a package and a file, by their IDs
+type Relationship struct {
+	Parent string `json:"parent"`
+	Child  string `json:"child"`
+	Type   string `json:"type"`
+}
+
+// Source is what Syft scanned
+type Source struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	// Up to schema v10, the metadata of the source is in the target field
+	Target   *ImageMetadata `json:"target,omitempty"`
+	Metadata *ImageMetadata `json:"metadata,omitempty"`
+}
+
+// ImageMetadata is the metadata of a container image source
+type ImageMetadata struct {
+	UserInput      string   `json:"userInput"`
+	ImageID        string   `json:"imageID"`
+	ManifestDigest string   `json:"manifestDigest"`
+	RepoDigests    []string `json:"repoDigests"`
+	Tags           []string `json:"tags"`
+}
+
+// Image returns the metadata of the source if it is a container image, nil
+// otherwise
+func (s Source) Image() *ImageMetadata {
+	if s.Type != SourceTypeImage {
+		return nil
+	}
+	if s.Metadata != nil {
+		return s.Metadata
+	}
+	return s.Target
+}
+
+// UnmarshalJSON ignores the target of sources that are not images, which is
+// a plain string (e.g. the path of a directory)
+func (s *Source) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Name     string          `json:"name"`
+		Version  string          `json:"version"`
+		Type     string          `json:"type"`
+		Target   json.RawMessage `json:"target"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Source{Name: raw.Name, Version: raw.Version, Type: raw.Type}
+	if raw.Type != SourceTypeImage {
+		return nil
+	}
+	for _, m := range []struct {
+		raw json.RawMessage
+		dst **ImageMetadata
+	}{{raw.Target, &s.Target}, {raw.Metadata, &s.Metadata}} {
+		if len(m.raw) == 0 || string(m.raw) == "null" {
+			continue
+		}
+		var image ImageMetadata
+		if err := json.Unmarshal(m.raw, &image); err != nil {
+			return err
+		}
+		*m.dst = &image
+	}
+	return nil
+}
+
+// Descriptor identifies the tool that generated the document
+type Descriptor struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SyftProcessor processes Syft JSON documents.
+// Currently only supports JSON documents
+type SyftProcessor struct {
+}
+
+func (p *SyftProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentSyft {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSyft, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var doc Document
+		if err := json.Unmarshal(d.Blob, &doc); err != nil {
+			return err
+		}
+		if doc.Descriptor.Name != DescriptorName {
+			return fmt.Errorf("missing required Syft fields")
+		}
+		for i, a := range doc.Artifacts {
+			if a.Name == "" {
+				return fmt.Errorf("artifact %d has no name", i)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of Syft document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *SyftProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentSyft {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSyft, d.Type)
+	}
+
+	// Syft documents don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
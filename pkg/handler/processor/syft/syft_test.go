@@ -0,0 +1,109 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syft
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestSyftProcessor_Unpack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expected  []*processor.Document
+		expectErr bool
+	}{{
+		name: "Syft document",
+		doc: processor.Document{
+			Blob:              testdata.SyftExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentSyft,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  []*processor.Document{},
+		expectErr: false,
+	}, {
+		name: "Incorrect type",
+		doc: processor.Document{
+			Blob:              testdata.SyftExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentUnknown,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expected:  nil,
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := SyftProcessor{}
+			actual, err := d.Unpack(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("SyftProcessor.Unpack() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("SyftProcessor.Unpack() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSyftProcessor_ValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name      string
+		doc       processor.Document
+		expectErr bool
+	}{{
+		name: "valid Syft document",
+		doc: processor.Document{
+			Blob:              testdata.SyftExample,
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentSyft,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: false,
+	}, {
+		name: "invalid Syft document",
+		doc: processor.Document{
+			Blob:              []byte(`{"descriptor": {"name": "grype"}, "artifacts": []}`),
+			Format:            processor.FormatJSON,
+			Type:              processor.DocumentSyft,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: true,
+	}, {
+		name: "invalid format supported",
+		doc: processor.Document{
+			Blob:              testdata.SyftExample,
+			Format:            processor.FormatUnknown,
+			Type:              processor.DocumentSyft,
+			SourceInformation: processor.SourceInformation{},
+		},
+		expectErr: true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := SyftProcessor{}
+			err := d.ValidateSchema(&tt.doc)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("SyftProcessor.ValidateSchema() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trivy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// SchemaVersion is the only version of the Trivy JSON report that is
+// supported
+const SchemaVersion = 2
+
+// ArtifactTypeContainerImage is the type of the reports of container images
+const ArtifactTypeContainerImage = "container_image"
+
+// Report is a Trivy JSON report, as produced by `trivy --format json`. Only
+// the fields used by GUAC are decoded.
+type Report struct {
+	SchemaVersion int      `json:"SchemaVersion"`
+	ArtifactName  string   `json:"ArtifactName"`
+	ArtifactType  string   `json:"ArtifactType"`
+	Metadata      Metadata `json:"Metadata"`
+	Results       []Result `json:"Results"`
+}
+
+// Metadata is the metadata of the scanned artifact
+type Metadata struct {
+	ImageID     string   `json:"ImageID,omitempty"`
+	RepoDigests []string `json:"RepoDigests,omitempty"`
+	RepoTags    []string `json:"RepoTags,omitempty"`
+}
+
+// Result holds the packages and vulnerabilities found in a target of the
+// artifact (e.g. the OS packages or a lock file)
+type Result struct {
+	Target          string          `json:"Target"`
+	Class           string          `json:"Class"`
+	Type            string          `json:"Type"`
+	Packages        []Package       `json:"Packages,omitempty"`
+	Vulnerabilities []Vulnerability `json:"Vulnerabilities,omitempty"`
+}
+
+// Package is a package found by Trivy. Packages are only listed with
+// `--list-all-pkgs`.
+type Package struct {
+	ID         string     `json:"ID"`
+	Name       string     `json:"Name"`
+	Version    string     `json:"Version"`
+	Identifier Identifier `json:"Identifier"`
+	DependsOn  []string   `json:"DependsOn,omitempty"`
+}
+
+// Identifier gives the software identifiers of a package
+type Identifier struct {
+	PURL string `json:"PURL,omitempty"`
+}
+
+// Vulnerability is a vulnerability detected in a package
+type Vulnerability struct {
+	VulnerabilityID  string     `json:"VulnerabilityID"`
+	PkgID            string     `json:"PkgID"`
+	PkgName          string     `json:"PkgName"`
+	PkgIdentifier    Identifier `json:"PkgIdentifier"`
+	InstalledVersion string     `json:"InstalledVersion"`
+	FixedVersion     string     `json:"FixedVersion,omitempty"`
+	Severity         string     `json:"Severity,omitempty"`
+}
+
+// TrivyProcessor processes Trivy JSON reports.
+// Currently only supports JSON documents
+type TrivyProcessor struct {
+}
+
+func (p *TrivyProcessor) ValidateSchema(d *processor.Document) error {
+	if d.Type != processor.DocumentTrivy {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentTrivy, d.Type)
+	}
+
+	switch d.Format {
+	case processor.FormatJSON:
+		var report Report
+		if err := json.Unmarshal(d.Blob, &report); err != nil {
+			return err
+		}
+		if report.SchemaVersion != SchemaVersion {
+			return fmt.Errorf("unsupported Trivy report schema version %d, expected %d", report.SchemaVersion, SchemaVersion)
+		}
+		if report.ArtifactName == "" {
+			return fmt.Errorf("missing required Trivy fields")
+		}
+		for _, r := range report.Results {
+			for i, v := range r.Vulnerabilities {
+				if v.VulnerabilityID == "" {
+					return fmt.Errorf("vulnerability %d of %s has no ID", i, r.Target)
+				}
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to support parsing of Trivy document format: %v", d.Format)
+}
+
+// Unpack takes in the document and tries to unpack it
+// if there is a valid decomposition of sub-documents.
+//
+// Returns empty list and nil error if nothing to unpack
+// Returns unpacked list and nil error if successfully unpacked
+func (p *TrivyProcessor) Unpack(d *processor.Document) ([]*processor.Document, error) {
+	if d.Type != processor.DocumentTrivy {
+		return nil, fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentTrivy, d.Type)
+	}
+
+	// Trivy reports don't unpack into additional documents.
+	return []*processor.Document{}, nil
+}
@@ -0,0 +1,195 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+	"golang.org/x/term"
+)
+
+// barWidth is the number of characters of the progress bar
+const barWidth = 30
+
+// Reporter tracks the documents going through the GUAC pipeline, to report
+// the progress of long ingestion runs.
+type Reporter struct {
+	mu        sync.Mutex
+	start     time.Time
+	total     int
+	inFlight  int
+	processed int
+	failed    int
+	// buffered returns the number of documents collected but not yet
+	// emitted, e.g. collector.BufferedDocuments
+	buffered func() int
+	now      func() time.Time
+}
+
+// Snapshot is the progress at a point in time
+type Snapshot struct {
+	// Seen is the number of documents collected so far, including those
+	// still buffered or being processed
+	Seen int
+	// Processed is the number of documents that went through the pipeline,
+	// successfully or not
+	Processed int
+	// Failed is the number of processed documents that failed
+	Failed int
+	// Pending is the number of seen documents that are not processed yet
+	Pending int
+	// Total is the expected number of documents, 0 if unknown
+	Total   int
+	Elapsed time.Duration
+	// Rate is the number of documents processed per second
+	Rate float64
+	// ETA is the estimated time left until Total documents are processed,
+	// 0 if unknown
+	ETA time.Duration
+}
+
+// NewReporter returns a reporter counting the documents passed to the
+// emitter returned by Wrap, together with the buffered ones, which may be nil.
+func NewReporter(buffered func() int) *Reporter {
+	if buffered == nil {
+		buffered = func() int { return 0 }
+	}
+	return &Reporter{start: time.Now(), buffered: buffered, now: time.Now}
+}
+
+// SetTotal sets the expected number of documents, enabling the ETA. It is
+// an estimate: once more documents are seen, the total is raised to match.
+func (r *Reporter) SetTotal(total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = total
+}
+
+// Wrap returns an emitter calling emitter and counting the documents it
+// processes and fails
+func (r *Reporter) Wrap(emitter func(*processor.Document) error) func(*processor.Document) error {
+	return func(d *processor.Document) error {
+		r.mu.Lock()
+		r.inFlight++
+		r.mu.Unlock()
+
+		err := emitter(d)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.inFlight--
+		r.processed++
+		if err != nil {
+			r.failed++
+		}
+		return err
+	}
+}
+
+// Snapshot returns the current progress
+func (r *Reporter) Snapshot() Snapshot {
+	buffered := r.buffered()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{
+		Processed: r.processed,
+		Failed:    r.failed,
+		Pending:   r.inFlight + buffered,
+		Elapsed:   r.now().Sub(r.start),
+	}
+	s.Seen = s.Processed + s.Pending
+	if r.total > 0 {
+		s.Total = r.total
+		if s.Seen > s.Total {
+			s.Total = s.Seen
+		}
+	}
+	if s.Elapsed > 0 {
+		s.Rate = float64(s.Processed) / s.Elapsed.Seconds()
+	}
+	if s.Total > 0 && s.Rate > 0 {
+		left := float64(s.Total-s.Processed) / s.Rate
+		s.ETA = time.Duration(left * float64(time.Second)).Round(time.Second)
+	}
+	return s
+}
+
+// Run reports the progress every interval until ctx is done, then reports it
+// one last time. On a terminal, a progress bar is drawn on out; otherwise,
+// the progress is logged.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration, out *os.File) {
+	report := r.logProgress
+	if term.IsTerminal(int(out.Fd())) {
+		report = func(ctx context.Context, s Snapshot) {
+			fmt.Fprintf(out, "\r\033[K%s", formatBar(s))
+		}
+		defer fmt.Fprintln(out)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			report(ctx, r.Snapshot())
+			return
+		case <-ticker.C:
+			report(ctx, r.Snapshot())
+		}
+	}
+}
+
+func (r *Reporter) logProgress(ctx context.Context, s Snapshot) {
+	logger := logging.FromContext(ctx)
+	keysAndValues := []interface{}{
+		"seen", s.Seen,
+		"processed", s.Processed,
+		"failed", s.Failed,
+		"pending", s.Pending,
+		"rate", fmt.Sprintf("%.1f/s", s.Rate),
+		"elapsed", s.Elapsed.Round(time.Second),
+	}
+	if s.Total > 0 {
+		keysAndValues = append(keysAndValues, "total", s.Total, "eta", s.ETA)
+	}
+	logger.Infow("ingestion progress", keysAndValues...)
+}
+
+// formatBar returns the line of the progress bar. Without a total, only the
+// counts are shown.
+func formatBar(s Snapshot) string {
+	var sb strings.Builder
+	if s.Total > 0 {
+		done := barWidth * s.Processed / s.Total
+		fmt.Fprintf(&sb, "[%s%s] %d/%d documents (%d%%)",
+			strings.Repeat("=", done), strings.Repeat(" ", barWidth-done),
+			s.Processed, s.Total, 100*s.Processed/s.Total)
+	} else {
+		fmt.Fprintf(&sb, "%d documents", s.Processed)
+	}
+	fmt.Fprintf(&sb, ", %d pending, %d failed, %.1f/s", s.Pending, s.Failed, s.Rate)
+	if s.ETA > 0 {
+		fmt.Fprintf(&sb, ", ETA %s", s.ETA)
+	}
+	return sb.String()
+}
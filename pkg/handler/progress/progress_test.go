@@ -0,0 +1,93 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestReporter_Snapshot(t *testing.T) {
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	buffered := 3
+
+	r := NewReporter(func() int { return buffered })
+	r.start = start
+	r.now = func() time.Time { return now }
+
+	emit := r.Wrap(func(d *processor.Document) error {
+		if string(d.Blob) == "bad" {
+			return errors.New("unable to process doc")
+		}
+		return nil
+	})
+	for _, blob := range []string{"a", "bad", "b", "c"} {
+		_ = emit(&processor.Document{Blob: []byte(blob)})
+	}
+	now = start.Add(2 * time.Second)
+
+	testCases := []struct {
+		name  string
+		total int
+		want  Snapshot
+	}{{
+		name: "unknown total",
+		want: Snapshot{Seen: 7, Processed: 4, Failed: 1, Pending: 3, Elapsed: 2 * time.Second, Rate: 2},
+	}, {
+		name:  "known total",
+		total: 10,
+		want:  Snapshot{Seen: 7, Processed: 4, Failed: 1, Pending: 3, Total: 10, Elapsed: 2 * time.Second, Rate: 2, ETA: 3 * time.Second},
+	}, {
+		name:  "total exceeded",
+		total: 5,
+		want:  Snapshot{Seen: 7, Processed: 4, Failed: 1, Pending: 3, Total: 7, Elapsed: 2 * time.Second, Rate: 2, ETA: 2 * time.Second},
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r.SetTotal(tt.total)
+			if got := r.Snapshot(); got != tt.want {
+				t.Errorf("Snapshot() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatBar(t *testing.T) {
+	testCases := []struct {
+		name     string
+		snapshot Snapshot
+		want     string
+	}{{
+		name:     "unknown total",
+		snapshot: Snapshot{Processed: 4, Failed: 1, Pending: 3, Rate: 2},
+		want:     "4 documents, 3 pending, 1 failed, 2.0/s",
+	}, {
+		name:     "known total",
+		snapshot: Snapshot{Processed: 5, Failed: 1, Pending: 3, Total: 10, Rate: 2, ETA: 3 * time.Second},
+		want:     "[===============               ] 5/10 documents (50%), 3 pending, 1 failed, 2.0/s, ETA 3s",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBar(tt.snapshot); got != tt.want {
+				t.Errorf("formatBar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
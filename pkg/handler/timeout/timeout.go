@@ -0,0 +1,79 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Error is returned when a stage of the ingestion pipeline does not complete
+// within its timeout. It matches `context.DeadlineExceeded` with `errors.Is`.
+type Error struct {
+	Stage   string
+	Timeout time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.Stage, e.Timeout)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// Run calls f with a context that is canceled after timeout, and returns an
+// *Error if f has not returned by then. A timeout of 0 disables it.
+//
+// f should stop once its context is done, but Go cannot interrupt a function
+// that ignores it (e.g. stuck in a parser loop or in a database driver). In
+// that case f is abandoned: it keeps running in the background and its result
+// is discarded, so that the caller can move on to the next document.
+func Run[T any](ctx context.Context, stage string, timeout time.Duration, f func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return f(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	// buffered, so that an abandoned f does not block forever on send
+	done := make(chan result, 1)
+	go func() {
+		v, err := f(ctx)
+		done <- result{value: v, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() == context.DeadlineExceeded {
+			var zero T
+			return zero, &Error{Stage: stage, Timeout: timeout}
+		}
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		if ctx.Err() == context.DeadlineExceeded {
+			return zero, &Error{Stage: stage, Timeout: timeout}
+		}
+		return zero, ctx.Err()
+	}
+}
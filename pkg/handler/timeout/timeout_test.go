@@ -0,0 +1,108 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	errStage := errors.New("stage failed")
+	// blocks until the test ends, ignoring its context
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		f           func(context.Context) (int, error)
+		want        int
+		wantErr     error
+		wantTimeout bool
+	}{{
+		name:    "completes in time",
+		timeout: time.Second,
+		f:       func(context.Context) (int, error) { return 42, nil },
+		want:    42,
+	}, {
+		name:    "fails in time",
+		timeout: time.Second,
+		f:       func(context.Context) (int, error) { return 0, errStage },
+		wantErr: errStage,
+	}, {
+		name:    "no timeout",
+		timeout: 0,
+		f: func(ctx context.Context) (int, error) {
+			if _, ok := ctx.Deadline(); ok {
+				return 0, errors.New("unexpected deadline")
+			}
+			return 42, nil
+		},
+		want: 42,
+	}, {
+		name:    "stops on cancellation",
+		timeout: 10 * time.Millisecond,
+		f: func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+		wantTimeout: true,
+	}, {
+		name:    "ignores cancellation",
+		timeout: 10 * time.Millisecond,
+		f: func(ctx context.Context) (int, error) {
+			<-stuck
+			return 42, nil
+		},
+		wantTimeout: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Run(context.Background(), "process", tt.timeout, tt.f)
+			if tt.wantTimeout {
+				var timeoutErr *Error
+				if !errors.As(err, &timeoutErr) || timeoutErr.Stage != "process" || timeoutErr.Timeout != tt.timeout {
+					t.Fatalf("Run() error = %v, want timeout", err)
+				}
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Run() error = %v, does not match context.DeadlineExceeded", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Run(ctx, "ingest", time.Second, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
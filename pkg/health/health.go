@@ -0,0 +1,83 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health serves the liveness and readiness probes of the server
+// modes, e.g. for Kubernetes.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// HealthzPath is the path of the liveness probe
+	HealthzPath = "/healthz"
+	// ReadyzPath is the path of the readiness probe
+	ReadyzPath = "/readyz"
+
+	// CheckTimeout bounds the time taken by the readiness check
+	CheckTimeout = 5 * time.Second
+)
+
+// Check returns an error if a dependency of the server, such as the
+// database, is not available
+type Check func(ctx context.Context) error
+
+// Register serves the probes on mux. The liveness probe succeeds as long as
+// the process serves requests. The readiness probe runs ready and responds
+// 503 Service Unavailable with the error if it fails or does not return
+// within CheckTimeout.
+func Register(mux *http.ServeMux, ready Check) {
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle(ReadyzPath, ReadyHandler(ready))
+}
+
+// ReadyHandler returns the handler of the readiness probe
+func ReadyHandler(ready Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), CheckTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := runCheck(ctx, ready); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "not ready: %v\n", err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// runCheck returns the error of check, or of ctx if it is done first. Checks
+// that ignore ctx are abandoned, not canceled.
+func runCheck(ctx context.Context, check Check) error {
+	// buffered, so that an abandoned check does not block forever on send
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
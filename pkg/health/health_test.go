@@ -0,0 +1,84 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		ready    Check
+		expired  bool
+		wantCode int
+		wantBody string
+	}{{
+		name:     "healthy",
+		path:     HealthzPath,
+		ready:    func(ctx context.Context) error { return errors.New("unreachable") },
+		wantCode: http.StatusOK,
+		wantBody: "ok",
+	}, {
+		name:     "ready",
+		path:     ReadyzPath,
+		ready:    func(ctx context.Context) error { return nil },
+		wantCode: http.StatusOK,
+		wantBody: "ok",
+	}, {
+		name:     "database unreachable",
+		path:     ReadyzPath,
+		ready:    func(ctx context.Context) error { return errors.New("connection refused") },
+		wantCode: http.StatusServiceUnavailable,
+		wantBody: "not ready: connection refused",
+	}, {
+		name: "check timing out",
+		path: ReadyzPath,
+		ready: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		expired:  true,
+		wantCode: http.StatusServiceUnavailable,
+		wantBody: "not ready: context deadline exceeded",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			Register(mux, tt.ready)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.expired {
+				ctx, cancel := context.WithTimeout(req.Context(), 0)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantCode)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("GET %s body = %q, want %q", tt.path, got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enricher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/guacsec/guac/pkg/assembler"
+)
+
+// Enricher adds properties to the nodes of the graphs before they are
+// stored, e.g. the owning team of the packages looked up by purl in an
+// internal service. This overlays organizational context on the nodes
+// without changing the parsers.
+type Enricher interface {
+	// Enrich returns the properties to add to the node, or nil to leave it
+	// unchanged. Property names must be alphanumeric (underscores allowed)
+	// and not start with a digit; values must be storable by the database
+	// (scalars or lists of scalars). Properties set by the parsers cannot be
+	// overwritten. An error fails the ingestion of the document.
+	Enrich(ctx context.Context, node assembler.GuacNode) (map[string]interface{}, error)
+}
+
+var (
+	enrichers = map[string]Enricher{}
+)
+
+// RegisterEnricher registers an enricher under name. Like the other
+// registries, it must be called before ingestion starts. Enrichers run in the
+// order of their names, so later ones see the properties added by earlier
+// ones.
+func RegisterEnricher(e Enricher, name string) error {
+	if _, ok := enrichers[name]; ok {
+		return fmt.Errorf("the enricher is being overwritten: %s", name)
+	}
+	enrichers[name] = e
+	return nil
+}
+
+// EnrichGraph returns g with the properties returned by the registered
+// enrichers added to its nodes. The endpoints of the edges are not enriched:
+// the nodes of the graph carry the added properties to the database.
+func EnrichGraph(ctx context.Context, g assembler.Graph) (assembler.Graph, error) {
+	if len(enrichers) == 0 {
+		return g, nil
+	}
+	names := make([]string, 0, len(enrichers))
+	for name := range enrichers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := make([]assembler.GuacNode, len(g.Nodes))
+	for i, n := range g.Nodes {
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return g, err
+			}
+			props, err := enrichers[name].Enrich(ctx, n)
+			if err != nil {
+				return g, fmt.Errorf("enricher %s failed for %s node: %w", name, n.Type(), err)
+			}
+			if n, err = assembler.WithProperties(n, props); err != nil {
+				return g, fmt.Errorf("enricher %s: %w", name, err)
+			}
+		}
+		nodes[i] = n
+	}
+	return assembler.Graph{Nodes: nodes, Edges: g.Edges}, nil
+}
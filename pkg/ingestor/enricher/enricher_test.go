@@ -0,0 +1,129 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enricher
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/assembler"
+)
+
+// ownerEnricher sets the team owning the packages listed in owners
+type ownerEnricher map[string]string
+
+func (e ownerEnricher) Enrich(ctx context.Context, node assembler.GuacNode) (map[string]interface{}, error) {
+	pkg, ok := node.(assembler.PackageNode)
+	if !ok || e[pkg.Purl] == "" {
+		return nil, nil
+	}
+	return map[string]interface{}{"team": e[pkg.Purl]}, nil
+}
+
+// tierEnricher sets the tier of the nodes that have a team
+type tierEnricher struct{}
+
+func (tierEnricher) Enrich(ctx context.Context, node assembler.GuacNode) (map[string]interface{}, error) {
+	if _, ok := node.Properties()["team"]; !ok {
+		return nil, nil
+	}
+	return map[string]interface{}{"tier": 1}, nil
+}
+
+type failingEnricher struct{}
+
+func (failingEnricher) Enrich(ctx context.Context, node assembler.GuacNode) (map[string]interface{}, error) {
+	return nil, errors.New("service unavailable")
+}
+
+func TestEnrichGraph(t *testing.T) {
+	app := assembler.PackageNode{Name: "app", Purl: "pkg:golang/app@v1"}
+	lib := assembler.PackageNode{Name: "lib", Purl: "pkg:golang/lib@v1"}
+	graph := assembler.Graph{
+		Nodes: []assembler.GuacNode{app, lib},
+		Edges: []assembler.GuacEdge{assembler.DependsOnEdge{PackageNode: app, PackageDependency: lib}},
+	}
+
+	testCases := []struct {
+		name      string
+		enrichers map[string]Enricher
+		wantProps []map[string]interface{}
+		wantErr   string
+	}{{
+		name:      "no enrichers",
+		wantProps: []map[string]interface{}{app.Properties(), lib.Properties()},
+	}, {
+		name: "enrichers run in the order of their names",
+		enrichers: map[string]Enricher{
+			"b-tier":  tierEnricher{},
+			"a-owner": ownerEnricher{"pkg:golang/app@v1": "payments"},
+		},
+		wantProps: []map[string]interface{}{
+			{"name": "app", "purl": "pkg:golang/app@v1", "team": "payments", "tier": 1},
+			lib.Properties(),
+		},
+	}, {
+		name:      "failing enricher",
+		enrichers: map[string]Enricher{"owner": failingEnricher{}},
+		wantErr:   "enricher owner failed for Package node: service unavailable",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			enrichers = map[string]Enricher{}
+			defer func() { enrichers = map[string]Enricher{} }()
+			for name, e := range tt.enrichers {
+				if err := RegisterEnricher(e, name); err != nil {
+					t.Fatalf("RegisterEnricher() unexpected error: %v", err)
+				}
+			}
+
+			got, err := EnrichGraph(context.Background(), graph)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("EnrichGraph() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EnrichGraph() unexpected error: %v", err)
+			}
+			gotProps := []map[string]interface{}{}
+			for _, n := range got.Nodes {
+				gotProps = append(gotProps, n.Properties())
+			}
+			if !reflect.DeepEqual(gotProps, tt.wantProps) {
+				t.Errorf("EnrichGraph() nodes = %v, want %v", gotProps, tt.wantProps)
+			}
+			if !reflect.DeepEqual(got.Edges, graph.Edges) {
+				t.Errorf("EnrichGraph() edges = %v, want %v", got.Edges, graph.Edges)
+			}
+		})
+	}
+}
+
+func TestRegisterEnricher(t *testing.T) {
+	enrichers = map[string]Enricher{}
+	defer func() { enrichers = map[string]Enricher{} }()
+	if err := RegisterEnricher(tierEnricher{}, "tier"); err != nil {
+		t.Fatalf("RegisterEnricher() unexpected error: %v", err)
+	}
+	if err := RegisterEnricher(tierEnricher{}, "tier"); err == nil {
+		t.Errorf("RegisterEnricher() expected an error registering the same name twice")
+	}
+}
@@ -26,7 +26,8 @@ type inmemory struct {
 	collector map[string]*key.Key
 }
 
-func newInmemoryProvider() *inmemory {
+// NewInmemoryProvider initializes a key provider that keeps the keys in memory
+func NewInmemoryProvider() *inmemory {
 	return &inmemory{
 		collector: map[string]*key.Key{},
 	}
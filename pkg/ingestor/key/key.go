@@ -121,24 +121,14 @@ func Retrieve(ctx context.Context, id string, providerType KeyProviderType) (*Ke
 // It takes in a PEM-encoded byte slice and converts it to a wrapped Key type
 // returns a nil error when successful
 func Store(ctx context.Context, id string, pemBytes []byte, providerType KeyProviderType) error {
-	key, err := cryptoutils.UnmarshalPEMToPublicKey(pemBytes)
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pemBytes)
 	if err != nil {
 		return err
 	}
-	keyHash, err := dsse.SHA256KeyID(key)
+	foundKey, err := NewKey(pub)
 	if err != nil {
 		return err
 	}
-	keyType, KeyScheme, err := getKeyInfo(key)
-	if err != nil {
-		return err
-	}
-	foundKey := &Key{
-		Hash:   keyHash,
-		Type:   keyType,
-		Val:    key,
-		Scheme: KeyScheme,
-	}
 	if provider, ok := keyProviders[providerType]; ok {
 		err := provider.StoreKey(ctx, id, foundKey)
 		if err != nil {
@@ -164,6 +154,24 @@ func Delete(ctx context.Context, id string, providerType KeyProviderType) error
 	return nil
 }
 
+// NewKey wraps the public key, filling in its hash, type and scheme
+func NewKey(pub crypto.PublicKey) (*Key, error) {
+	keyHash, err := dsse.SHA256KeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	keyType, keyScheme, err := getKeyInfo(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Hash:   keyHash,
+		Type:   keyType,
+		Val:    pub,
+		Scheme: keyScheme,
+	}, nil
+}
+
 func getKeyInfo(pub crypto.PublicKey) (KeyType, KeyScheme, error) {
 	switch pub.(type) {
 	case *rsa.PublicKey:
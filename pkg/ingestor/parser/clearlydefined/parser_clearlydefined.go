@@ -0,0 +1,153 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The ClearlyDefined parser parses the definitions of components curated by
+// https://clearlydefined.io (see `clearlydefined.Definition`).
+//
+// The package node is found by mapping the coordinates of the component to
+// its purl, without qualifiers. It is linked to a license node for its
+// declared license via a "DeclaredLicense" edge, and to a license node for
+// each license found in its files via "DiscoveredLicense" edges. License
+// expressions are normalized, and compound ones are linked to their operands
+// (see `common.NewLicense`). The
+// copyrights and the score of the definition are stored in a metadata node
+// of type "clearlydefined".
+package clearlydefined
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/clearlydefined"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+// MetadataType is the type of the metadata nodes of the definitions
+const MetadataType = "clearlydefined"
+
+type clearlyDefinedParser struct {
+	metadata   []assembler.MetadataForEdge
+	declared   []assembler.DeclaredLicenseEdge
+	discovered []assembler.DiscoveredLicenseEdge
+	licenses   []common.License
+}
+
+// NewClearlyDefinedParser initializes the clearlyDefinedParser
+func NewClearlyDefinedParser() common.DocumentParser {
+	return &clearlyDefinedParser{
+		metadata:   []assembler.MetadataForEdge{},
+		declared:   []assembler.DeclaredLicenseEdge{},
+		discovered: []assembler.DiscoveredLicenseEdge{},
+		licenses:   []common.License{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *clearlyDefinedParser) Parse(ctx context.Context, doc *processor.Document) error {
+	if doc.Type != processor.DocumentClearlyDefined {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentClearlyDefined, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of ClearlyDefined document format: %v", doc.Format)
+	}
+
+	var def clearlydefined.Definition
+	if err := json.Unmarshal(doc.Blob, &def); err != nil {
+		return err
+	}
+	purl, err := def.Coordinates.Purl()
+	if err != nil {
+		return err
+	}
+	pkg := assembler.PackageNode{Purl: common.NormalizePurl(purl)}
+
+	licensed := def.Licensed
+	if common.HasLicense(licensed.Declared) {
+		l := common.NewLicense(licensed.Declared, doc.SourceInformation)
+		p.licenses = append(p.licenses, l)
+		p.declared = append(p.declared, l.Declared(pkg))
+	}
+	for _, expression := range licensed.Facets.Core.Discovered.Expressions {
+		if !common.HasLicense(expression) {
+			continue
+		}
+		l := common.NewLicense(expression, doc.SourceInformation)
+		p.licenses = append(p.licenses, l)
+		p.discovered = append(p.discovered, l.Discovered(pkg))
+	}
+
+	details := map[string]interface{}{
+		"score": def.Scores.Effective,
+	}
+	if parties := licensed.Facets.Core.Attribution.Parties; len(parties) > 0 {
+		details["copyrights"] = parties
+	}
+	if licensed.Declared != "" {
+		details["declared_license"] = licensed.Declared
+	}
+	if def.Described.ReleaseDate != "" {
+		details["release_date"] = def.Described.ReleaseDate
+	}
+	if loc := def.Described.SourceLocation; loc != nil && loc.URL != "" {
+		details["source_location"] = loc.URL
+	}
+	p.metadata = append(p.metadata, assembler.MetadataForEdge{
+		MetadataNode: assembler.MetadataNode{
+			MetadataType: MetadataType,
+			ID:           def.Coordinates.String(),
+			Details:      details,
+		},
+		ForPackage: pkg,
+	})
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *clearlyDefinedParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, e := range p.metadata {
+		nodes = append(nodes, e.ForPackage, e.MetadataNode)
+	}
+	for _, l := range p.licenses {
+		nodes = append(nodes, l.Nodes()...)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *clearlyDefinedParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, e := range p.metadata {
+		edges = append(edges, e)
+	}
+	for _, e := range p.declared {
+		edges = append(edges, e)
+	}
+	for _, e := range p.discovered {
+		edges = append(edges, e)
+	}
+	for _, l := range p.licenses {
+		edges = append(edges, l.Edges()...)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *clearlyDefinedParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
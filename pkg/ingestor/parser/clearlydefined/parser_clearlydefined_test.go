@@ -0,0 +1,154 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clearlydefined
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_clearlyDefinedParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	lodash := assembler.PackageNode{
+		Purl: "pkg:npm/lodash@4.17.21",
+	}
+	metadata := assembler.MetadataNode{
+		MetadataType: "clearlydefined",
+		ID:           "npm/npmjs/-/lodash/4.17.21",
+		Details: map[string]interface{}{
+			"score": 87,
+			"copyrights": []string{
+				"Copyright OpenJS Foundation and other contributors <https://openjsf.org/>",
+				"Copyright Jeremy Ashkenas, DocumentCloud and Investigative Reporters & Editors",
+			},
+			"declared_license": "MIT",
+			"release_date":     "2021-02-20",
+			"source_location":  "https://github.com/lodash/lodash/tree/c6e281b878b315c7a10d90f9c2af4cdb112d9625",
+		},
+	}
+	mit := assembler.LicenseNode{
+		Expression: "MIT",
+		NodeData:   *assembler.NewObjectMetadata(srcInfo),
+	}
+	cc0 := assembler.LicenseNode{
+		Expression: "CC0-1.0",
+		NodeData:   *assembler.NewObjectMetadata(srcInfo),
+	}
+
+	scoped := assembler.PackageNode{
+		Purl: "pkg:npm/%40babel/core@7.0.0",
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "ClearlyDefined document",
+		doc: &processor.Document{
+			Blob:              testdata.ClearlyDefinedExample,
+			Type:              processor.DocumentClearlyDefined,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{lodash, metadata, mit, cc0, mit},
+		wantEdges: []assembler.GuacEdge{
+			assembler.MetadataForEdge{MetadataNode: metadata, ForPackage: lodash},
+			assembler.DeclaredLicenseEdge{PackageNode: lodash, LicenseNode: mit},
+			assembler.DiscoveredLicenseEdge{PackageNode: lodash, LicenseNode: cc0},
+			assembler.DiscoveredLicenseEdge{PackageNode: lodash, LicenseNode: mit},
+		},
+	}, {
+		name: "unknown declared license",
+		doc: &processor.Document{
+			Blob:              []byte(`{"coordinates": {"type": "npm", "provider": "npmjs", "namespace": "@babel", "name": "core", "revision": "7.0.0"}, "licensed": {"declared": "NOASSERTION"}}`),
+			Type:              processor.DocumentClearlyDefined,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			scoped,
+			assembler.MetadataNode{
+				MetadataType: "clearlydefined",
+				ID:           "npm/npmjs/@babel/core/7.0.0",
+				Details: map[string]interface{}{
+					"score":            0,
+					"declared_license": "NOASSERTION",
+				},
+			},
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.MetadataForEdge{
+				MetadataNode: assembler.MetadataNode{
+					MetadataType: "clearlydefined",
+					ID:           "npm/npmjs/@babel/core/7.0.0",
+					Details: map[string]interface{}{
+						"score":            0,
+						"declared_license": "NOASSERTION",
+					},
+				},
+				ForPackage: scoped,
+			},
+		},
+	}, {
+		name: "unsupported coordinates",
+		doc: &processor.Document{
+			Blob:              []byte(`{"coordinates": {"type": "conda", "provider": "conda-forge", "name": "numpy"}, "licensed": {"declared": "BSD-3-Clause"}}`),
+			Type:              processor.DocumentClearlyDefined,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.ClearlyDefinedExample,
+			Type:              processor.DocumentClearlyDefined,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewClearlyDefinedParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clearlyDefinedParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("clearlyDefinedParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("clearlyDefinedParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+)
+
+// NormalizeDigest returns the digest as `<algorithm>:<value>`, spelling the
+// algorithm like in-toto digest sets do (e.g. `SHA-256` and `SHA256` become
+// `sha256`, `SHA3-256` becomes `sha3_256`) and lowercasing the hex value.
+// Artifact nodes are identified by their digest only, so this lets the
+// subject of an attestation and a file or package of an SBOM with the same
+// checksum merge into the same node.
+func NormalizeDigest(algorithm string, value string) string {
+	algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+	// SHA-1 and SHA-2 algorithms are spelled with a dash by CycloneDX only
+	if rest := strings.TrimPrefix(algorithm, "sha-"); rest != algorithm && isDigits(rest) {
+		algorithm = "sha" + rest
+	}
+	algorithm = strings.ReplaceAll(algorithm, "-", "_")
+	return algorithm + ":" + strings.ToLower(strings.TrimSpace(value))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
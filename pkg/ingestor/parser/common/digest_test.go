@@ -0,0 +1,63 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+)
+
+func TestNormalizeDigest(t *testing.T) {
+	testCases := []struct {
+		algorithm string
+		value     string
+		want      string
+	}{{
+		algorithm: "sha256",
+		value:     "ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef",
+		want:      "sha256:ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef",
+	}, {
+		algorithm: "SHA256",
+		value:     "AD91970864102A59765E20CE16216EFC9D6AD381471F7ACCCECEAB7D905703EF",
+		want:      "sha256:ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef",
+	}, {
+		algorithm: "SHA-256",
+		value:     " ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef ",
+		want:      "sha256:ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef",
+	}, {
+		algorithm: "SHA-1",
+		value:     "d6525c840a62b398424a78d792f457477135d0cf",
+		want:      "sha1:d6525c840a62b398424a78d792f457477135d0cf",
+	}, {
+		algorithm: "SHA3-256",
+		value:     "abc",
+		want:      "sha3_256:abc",
+	}, {
+		algorithm: "sha512_256",
+		value:     "abc",
+		want:      "sha512_256:abc",
+	}, {
+		algorithm: "BLAKE2b-256",
+		value:     "abc",
+		want:      "blake2b_256:abc",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			if got := NormalizeDigest(tt.algorithm, tt.value); got != tt.want {
+				t.Errorf("NormalizeDigest(%q, %q) = %q, want %q", tt.algorithm, tt.value, got, tt.want)
+			}
+		})
+	}
+}
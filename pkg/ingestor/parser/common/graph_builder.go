@@ -41,6 +41,8 @@ func (b *GraphBuilder) CreateAssemblerInput(ctx context.Context, foundIdentities
 		Nodes: b.docParser.CreateNodes(ctx),
 		Edges: b.docParser.CreateEdges(ctx, foundIdentities),
 	}
+	// link the packages to their CPEs, for the advisories that only know them
+	assemblerinput.Edges = append(assemblerinput.Edges, assembler.CPEEdges(assemblerinput)...)
 	return assemblerinput
 }
 
@@ -0,0 +1,390 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// Operators of SPDX license expressions, by increasing precedence
+const (
+	LicenseOr   = "OR"
+	LicenseAnd  = "AND"
+	LicenseWith = "WITH"
+)
+
+// deprecatedLicenses maps the deprecated SPDX license ids, lowercased, to
+// the expressions replacing them
+var deprecatedLicenses = map[string]string{
+	"agpl-1.0":                         "AGPL-1.0-only",
+	"agpl-3.0":                         "AGPL-3.0-only",
+	"bsd-2-clause-freebsd":             "BSD-2-Clause",
+	"bsd-2-clause-netbsd":              "BSD-2-Clause",
+	"bzip2-1.0.5":                      "bzip2-1.0.6",
+	"ecos-2.0":                         "GPL-2.0-or-later WITH eCos-exception-2.0",
+	"gfdl-1.1":                         "GFDL-1.1-only",
+	"gfdl-1.2":                         "GFDL-1.2-only",
+	"gfdl-1.3":                         "GFDL-1.3-only",
+	"gpl-1.0":                          "GPL-1.0-only",
+	"gpl-1.0+":                         "GPL-1.0-or-later",
+	"gpl-2.0":                          "GPL-2.0-only",
+	"gpl-2.0+":                         "GPL-2.0-or-later",
+	"gpl-2.0-with-autoconf-exception":  "GPL-2.0-only WITH Autoconf-exception-2.0",
+	"gpl-2.0-with-bison-exception":     "GPL-2.0-or-later WITH Bison-exception-2.2",
+	"gpl-2.0-with-classpath-exception": "GPL-2.0-only WITH Classpath-exception-2.0",
+	"gpl-2.0-with-font-exception":      "GPL-2.0-only WITH Font-exception-2.0",
+	"gpl-2.0-with-gcc-exception":       "GPL-2.0-only WITH GCC-exception-2.0",
+	"gpl-3.0":                          "GPL-3.0-only",
+	"gpl-3.0+":                         "GPL-3.0-or-later",
+	"gpl-3.0-with-autoconf-exception":  "GPL-3.0-only WITH Autoconf-exception-3.0",
+	"gpl-3.0-with-gcc-exception":       "GPL-3.0-only WITH GCC-exception-3.1",
+	"lgpl-2.0":                         "LGPL-2.0-only",
+	"lgpl-2.0+":                        "LGPL-2.0-or-later",
+	"lgpl-2.1":                         "LGPL-2.1-only",
+	"lgpl-2.1+":                        "LGPL-2.1-or-later",
+	"lgpl-3.0":                         "LGPL-3.0-only",
+	"lgpl-3.0+":                        "LGPL-3.0-or-later",
+	"nunit":                            "zlib-acknowledgement",
+	"standardml-nj":                    "SMLNJ",
+	"wxwindows":                        "GPL-2.0-or-later WITH WxWindows-exception-3.1",
+}
+
+// spdxLicenses are the commonly used SPDX license and exception ids, whose
+// case is normalized. SPDX ids are case insensitive, other ids are kept as
+// written.
+var spdxLicenses = caseInsensitive(
+	"0BSD", "AFL-3.0", "AGPL-1.0-only", "AGPL-1.0-or-later", "AGPL-3.0-only",
+	"AGPL-3.0-or-later", "Apache-1.0", "Apache-1.1", "Apache-2.0",
+	"Artistic-1.0", "Artistic-2.0", "BlueOak-1.0.0", "BSD-1-Clause",
+	"BSD-2-Clause", "BSD-2-Clause-Patent", "BSD-3-Clause",
+	"BSD-3-Clause-Clear", "BSD-4-Clause", "BSL-1.0", "bzip2-1.0.6",
+	"CC-BY-3.0", "CC-BY-4.0", "CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC0-1.0",
+	"CDDL-1.0", "CDDL-1.1", "CPL-1.0", "curl", "ECL-2.0", "EPL-1.0",
+	"EPL-2.0", "EUPL-1.1", "EUPL-1.2", "GFDL-1.1-only", "GFDL-1.1-or-later",
+	"GFDL-1.2-only", "GFDL-1.2-or-later", "GFDL-1.3-only", "GFDL-1.3-or-later",
+	"GPL-1.0-only", "GPL-1.0-or-later", "GPL-2.0-only", "GPL-2.0-or-later",
+	"GPL-3.0-only", "GPL-3.0-or-later", "ICU", "IJG", "ISC", "LGPL-2.0-only",
+	"LGPL-2.0-or-later", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only",
+	"LGPL-3.0-or-later", "Libpng", "libpng-2.0", "MIT", "MIT-0", "MPL-1.0",
+	"MPL-1.1", "MPL-2.0", "MPL-2.0-no-copyleft-exception", "MS-PL", "MS-RL",
+	"NCSA", "ODbL-1.0", "OFL-1.1", "OpenSSL", "OSL-3.0", "PHP-3.01",
+	"PostgreSQL", "PSF-2.0", "Python-2.0", "Ruby", "SMLNJ", "Unicode-DFS-2016",
+	"Unlicense", "UPL-1.0", "Vim", "W3C", "WTFPL", "X11", "Zlib",
+	"zlib-acknowledgement", "ZPL-2.1",
+	// exceptions
+	"Autoconf-exception-2.0", "Autoconf-exception-3.0", "Bison-exception-2.2",
+	"Classpath-exception-2.0", "eCos-exception-2.0", "Font-exception-2.0",
+	"GCC-exception-2.0", "GCC-exception-3.1", "LLVM-exception",
+	"OpenJDK-assembly-exception-1.0", "WxWindows-exception-3.1",
+)
+
+func caseInsensitive(ids ...string) map[string]string {
+	m := make(map[string]string, len(ids))
+	for _, id := range ids {
+		m[strings.ToLower(id)] = id
+	}
+	return m
+}
+
+// LicenseExpression is a parsed SPDX license expression: either a single
+// license (or exception) id, or an operator applied to its operands.
+type LicenseExpression struct {
+	// ID is the license id, e.g. "MIT" or "LicenseRef-custom", when the
+	// expression is a single license
+	ID string
+	// Operator is LicenseAnd, LicenseOr or LicenseWith for compound
+	// expressions. The operands of WITH are the license and the exception.
+	Operator string
+	Operands []*LicenseExpression
+}
+
+// String returns the expression in its canonical form, only parenthesizing
+// the OR expressions that are operands of AND
+func (e *LicenseExpression) String() string {
+	if e.Operator == "" {
+		return e.ID
+	}
+	parts := make([]string, len(e.Operands))
+	for i, o := range e.Operands {
+		parts[i] = o.String()
+		if e.Operator == LicenseAnd && o.Operator == LicenseOr {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+e.Operator+" ")
+}
+
+// HasLicense returns whether the license field of a document holds a license
+// expression, rather than being empty, NOASSERTION or NONE
+func HasLicense(expression string) bool {
+	switch strings.ToUpper(strings.TrimSpace(expression)) {
+	case "", "NOASSERTION", "NONE":
+		return false
+	}
+	return true
+}
+
+// ParseLicenseExpression parses the SPDX license expression and normalizes
+// it, so that the same license written differently by different tools maps to
+// the same license node:
+//
+//   - the case of known SPDX ids is normalized (e.g. `apache-2.0` becomes
+//     `Apache-2.0`), and the operators are uppercased,
+//   - deprecated ids are replaced with their current expression (e.g.
+//     `GPL-2.0+` becomes `GPL-2.0-or-later`),
+//   - nested AND (or OR) expressions are flattened, and their operands are
+//     deduplicated and sorted, since their order does not matter.
+func ParseLicenseExpression(expression string) (*LicenseExpression, error) {
+	p := &licenseParser{
+		tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)),
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("invalid license expression %q: %w", expression, err)
+	}
+	if tok := p.next(); tok != "" {
+		return nil, fmt.Errorf("invalid license expression %q: unexpected %q", expression, tok)
+	}
+	return e, nil
+}
+
+type licenseParser struct {
+	tokens []string
+}
+
+func (p *licenseParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *licenseParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+func (p *licenseParser) or() (*LicenseExpression, error) {
+	return p.compound(LicenseOr, p.and)
+}
+
+func (p *licenseParser) and() (*LicenseExpression, error) {
+	return p.compound(LicenseAnd, p.license)
+}
+
+// compound parses the operands given by operand, separated by operator
+func (p *licenseParser) compound(operator string, operand func() (*LicenseExpression, error)) (*LicenseExpression, error) {
+	e, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*LicenseExpression{e}
+	for strings.EqualFold(p.peek(), operator) {
+		p.next()
+		e, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, e)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return newCompoundLicense(operator, operands), nil
+}
+
+// newCompoundLicense flattens the operands with the same operator, then
+// sorts and deduplicates them
+func newCompoundLicense(operator string, operands []*LicenseExpression) *LicenseExpression {
+	flat := map[string]*LicenseExpression{}
+	for _, o := range operands {
+		if o.Operator == operator {
+			for _, nested := range o.Operands {
+				flat[nested.String()] = nested
+			}
+		} else {
+			flat[o.String()] = o
+		}
+	}
+	if len(flat) == 1 {
+		for _, o := range flat {
+			return o
+		}
+	}
+	e := &LicenseExpression{Operator: operator}
+	for _, key := range sortedLicenseKeys(flat) {
+		e.Operands = append(e.Operands, flat[key])
+	}
+	return e
+}
+
+func sortedLicenseKeys(m map[string]*LicenseExpression) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// license parses a parenthesized expression, or a license id optionally
+// followed by WITH and an exception
+func (p *licenseParser) license() (*LicenseExpression, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	case !isLicenseID(tok):
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+
+	e, err := normalizeLicenseID(tok)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(p.peek(), LicenseWith) {
+		return e, nil
+	}
+	p.next()
+	exception := p.next()
+	if !isLicenseID(exception) {
+		return nil, fmt.Errorf("missing exception after WITH")
+	}
+	if e.Operator != "" {
+		return nil, fmt.Errorf("%q cannot have an exception", tok)
+	}
+	return &LicenseExpression{
+		Operator: LicenseWith,
+		Operands: []*LicenseExpression{e, {ID: canonicalLicenseID(exception)}},
+	}, nil
+}
+
+// normalizeLicenseID returns the expression of the license id, replacing
+// deprecated ids
+func normalizeLicenseID(id string) (*LicenseExpression, error) {
+	if replacement, ok := deprecatedLicenses[strings.ToLower(id)]; ok {
+		return ParseLicenseExpression(replacement)
+	}
+	return &LicenseExpression{ID: canonicalLicenseID(id)}, nil
+}
+
+// canonicalLicenseID returns the id with the case of the SPDX license list,
+// keeping the `+` suffix ("or later") of licenses that have no -or-later id
+func canonicalLicenseID(id string) string {
+	plus := ""
+	if strings.HasSuffix(id, "+") {
+		id, plus = strings.TrimSuffix(id, "+"), "+"
+	}
+	if canonical, ok := spdxLicenses[strings.ToLower(id)]; ok {
+		return canonical + plus
+	}
+	return id + plus
+}
+
+// isLicenseID returns whether the token is a license (or exception) id,
+// rather than an operator or a parenthesis
+func isLicenseID(tok string) bool {
+	switch strings.ToUpper(tok) {
+	case "", "(", ")", LicenseAnd, LicenseOr, LicenseWith:
+		return false
+	}
+	return true
+}
+
+// License is the license node of a license expression, together with the
+// nodes of its operands, recursively, and the edges linking them
+type License struct {
+	Node  assembler.LicenseNode
+	nodes []assembler.GuacNode
+	edges []assembler.GuacEdge
+}
+
+// NewLicense returns the license given by the SPDX license expression, as
+// normalized by ParseLicenseExpression. Expressions that cannot be parsed are
+// kept as written, in a single license node.
+func NewLicense(expression string, info processor.SourceInformation) License {
+	e, err := ParseLicenseExpression(expression)
+	if err != nil {
+		n := assembler.LicenseNode{
+			Expression: strings.TrimSpace(expression),
+			NodeData:   *assembler.NewObjectMetadata(info),
+		}
+		return License{Node: n, nodes: []assembler.GuacNode{n}, edges: []assembler.GuacEdge{}}
+	}
+	l := License{nodes: []assembler.GuacNode{}, edges: []assembler.GuacEdge{}}
+	l.Node = l.add(e, info, map[string]bool{})
+	return l
+}
+
+// add adds the nodes and edges of the expression, skipping the
+// subexpressions already seen, and returns its node
+func (l *License) add(e *LicenseExpression, info processor.SourceInformation, seen map[string]bool) assembler.LicenseNode {
+	n := assembler.LicenseNode{
+		Expression: e.String(),
+		Operator:   e.Operator,
+		NodeData:   *assembler.NewObjectMetadata(info),
+	}
+	if seen[n.Expression] {
+		return n
+	}
+	seen[n.Expression] = true
+	l.nodes = append(l.nodes, n)
+	for _, o := range e.Operands {
+		l.edges = append(l.edges, assembler.LicenseOperandEdge{
+			LicenseNode: n,
+			OperandNode: l.add(o, info, seen),
+		})
+	}
+	return n
+}
+
+// Nodes returns the node of the license and of all its operands
+func (l License) Nodes() []assembler.GuacNode {
+	return l.nodes
+}
+
+// Edges returns the edges from the compound expressions to their operands
+func (l License) Edges() []assembler.GuacEdge {
+	return l.edges
+}
+
+// Declared returns the edge from the package declaring the license
+func (l License) Declared(pkg assembler.PackageNode) assembler.DeclaredLicenseEdge {
+	return assembler.DeclaredLicenseEdge{PackageNode: pkg, LicenseNode: l.Node}
+}
+
+// Discovered returns the edge from the package whose files contain the
+// license
+func (l License) Discovered(pkg assembler.PackageNode) assembler.DiscoveredLicenseEdge {
+	return assembler.DiscoveredLicenseEdge{PackageNode: pkg, LicenseNode: l.Node}
+}
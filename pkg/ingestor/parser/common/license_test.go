@@ -0,0 +1,191 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+func TestParseLicenseExpression(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expressions []string
+		normalized  string
+		wantErr     bool
+	}{{
+		name:        "single license",
+		expressions: []string{"MIT", "mit", " MIT "},
+		normalized:  "MIT",
+	}, {
+		name:        "unknown ids are kept as written",
+		expressions: []string{"LicenseRef-Custom"},
+		normalized:  "LicenseRef-Custom",
+	}, {
+		name: "operands are sorted",
+		expressions: []string{
+			"MIT OR Apache-2.0",
+			"Apache-2.0 OR MIT",
+			"apache-2.0 or mit",
+			"(MIT OR Apache-2.0)",
+			"MIT OR Apache-2.0 OR MIT",
+		},
+		normalized: "Apache-2.0 OR MIT",
+	}, {
+		name: "nested operators are flattened",
+		expressions: []string{
+			"MIT AND (BSD-3-Clause AND Zlib)",
+			"(MIT AND Zlib) AND BSD-3-Clause",
+		},
+		normalized: "BSD-3-Clause AND MIT AND Zlib",
+	}, {
+		name: "AND has precedence over OR",
+		expressions: []string{
+			"MIT OR Apache-2.0 AND BSD-3-Clause",
+			"(BSD-3-Clause AND Apache-2.0) OR MIT",
+		},
+		normalized: "Apache-2.0 AND BSD-3-Clause OR MIT",
+	}, {
+		name: "OR operands of AND are parenthesized",
+		expressions: []string{
+			"(MIT OR Apache-2.0) AND BSD-3-Clause",
+			"BSD-3-Clause AND (Apache-2.0 OR MIT)",
+		},
+		normalized: "(Apache-2.0 OR MIT) AND BSD-3-Clause",
+	}, {
+		name: "exception",
+		expressions: []string{
+			"GPL-2.0-only WITH Classpath-exception-2.0",
+			"(GPL-2.0 WITH classpath-exception-2.0)",
+			"GPL-2.0-with-classpath-exception",
+		},
+		normalized: "GPL-2.0-only WITH Classpath-exception-2.0",
+	}, {
+		name:        "deprecated or later",
+		expressions: []string{"GPL-2.0+", "GPL-2.0-or-later", "gpl-2.0+"},
+		normalized:  "GPL-2.0-or-later",
+	}, {
+		name:        "or later suffix",
+		expressions: []string{"apache-1.1+"},
+		normalized:  "Apache-1.1+",
+	}, {
+		name:        "deprecated ids in compound expressions",
+		expressions: []string{"LGPL-2.1 OR wxWindows"},
+		normalized:  "GPL-2.0-or-later WITH WxWindows-exception-3.1 OR LGPL-2.1-only",
+	}, {
+		name:        "missing operand",
+		expressions: []string{"MIT OR"},
+		wantErr:     true,
+	}, {
+		name:        "missing parenthesis",
+		expressions: []string{"(MIT OR Apache-2.0"},
+		wantErr:     true,
+	}, {
+		name:        "missing exception",
+		expressions: []string{"GPL-2.0-only WITH"},
+		wantErr:     true,
+	}, {
+		name:        "exception of a compound license",
+		expressions: []string{"wxWindows WITH Classpath-exception-2.0"},
+		wantErr:     true,
+	}, {
+		name:        "missing operator",
+		expressions: []string{"MIT Apache-2.0"},
+		wantErr:     true,
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, expression := range tt.expressions {
+				e, err := ParseLicenseExpression(expression)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ParseLicenseExpression(%q) error = %v, wantErr %v", expression, err, tt.wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if got := e.String(); got != tt.normalized {
+					t.Errorf("ParseLicenseExpression(%q) = %q, want %q", expression, got, tt.normalized)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLicense(t *testing.T) {
+	info := processor.SourceInformation{Collector: "TestCollector", Source: "TestSource"}
+	node := func(expression, operator string) assembler.LicenseNode {
+		return assembler.LicenseNode{
+			Expression: expression,
+			Operator:   operator,
+			NodeData:   *assembler.NewObjectMetadata(info),
+		}
+	}
+	gpl := node("GPL-2.0-only", "")
+	classpath := node("Classpath-exception-2.0", "")
+	withClasspath := node("GPL-2.0-only WITH Classpath-exception-2.0", LicenseWith)
+	or := node("GPL-2.0-only OR GPL-2.0-only WITH Classpath-exception-2.0", LicenseOr)
+
+	l := NewLicense("GPL-2.0 OR (GPL-2.0 WITH Classpath-exception-2.0)", info)
+	if l.Node != or {
+		t.Errorf("got license node %v, want %v", l.Node, or)
+	}
+	wantNodes := []assembler.GuacNode{or, gpl, withClasspath, classpath}
+	if got := l.Nodes(); len(got) != len(wantNodes) {
+		t.Errorf("got nodes %v, want %v", got, wantNodes)
+	} else {
+		for i := range got {
+			if got[i] != wantNodes[i] {
+				t.Errorf("got node %v, want %v", got[i], wantNodes[i])
+			}
+		}
+	}
+	wantEdges := []assembler.GuacEdge{
+		assembler.LicenseOperandEdge{LicenseNode: or, OperandNode: gpl},
+		assembler.LicenseOperandEdge{LicenseNode: withClasspath, OperandNode: gpl},
+		assembler.LicenseOperandEdge{LicenseNode: withClasspath, OperandNode: classpath},
+		assembler.LicenseOperandEdge{LicenseNode: or, OperandNode: withClasspath},
+	}
+	if got := l.Edges(); len(got) != len(wantEdges) {
+		t.Errorf("got edges %v, want %v", got, wantEdges)
+	} else {
+		for i := range got {
+			if got[i] != wantEdges[i] {
+				t.Errorf("got edge %v, want %v", got[i], wantEdges[i])
+			}
+		}
+	}
+
+	invalid := NewLicense("MIT Apache-2.0", info)
+	if invalid.Node != node("MIT Apache-2.0", "") || len(invalid.Nodes()) != 1 || len(invalid.Edges()) != 0 {
+		t.Errorf("got license %v for an invalid expression, want a single node with the expression", invalid)
+	}
+}
+
+func TestHasLicense(t *testing.T) {
+	for expression, want := range map[string]bool{
+		"":            false,
+		"NOASSERTION": false,
+		"NONE":        false,
+		"noassertion": false,
+		"MIT":         true,
+	} {
+		if got := HasLicense(expression); got != want {
+			t.Errorf("HasLicense(%q) = %v, want %v", expression, got, want)
+		}
+	}
+}
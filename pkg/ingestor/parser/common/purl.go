@@ -0,0 +1,262 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// lowercasedPurlParts lists, for each purl type, whether its namespace and
+// its name are case insensitive according to the purl spec, and so are
+// lowercased when normalizing
+var lowercasedPurlParts = map[string]struct{ namespace, name bool }{
+	"alpm":      {namespace: true, name: true},
+	"apk":       {namespace: true, name: true},
+	"bitbucket": {namespace: true, name: true},
+	"composer":  {namespace: true, name: true},
+	"deb":       {namespace: true, name: true},
+	"github":    {namespace: true, name: true},
+	"hex":       {namespace: true, name: true},
+	"npm":       {name: true},
+	"oci":       {name: true},
+	"pypi":      {name: true},
+	"rpm":       {namespace: true},
+}
+
+// defaultPurlQualifiers are the qualifiers that have the default value of
+// their purl type, and so carry no information
+var defaultPurlQualifiers = map[string]map[string]string{
+	"maven": {"type": "jar"},
+}
+
+// NormalizePurl returns the canonical form of the purl, so that the same
+// package written differently by different tools maps to the same package
+// node:
+//
+//   - the type and the qualifier keys are lowercased,
+//   - the namespace and the name are lowercased for the types where the purl
+//     spec defines them as case insensitive (e.g. deb or npm, but not golang
+//     or maven), and underscores in PyPI names become dashes,
+//   - qualifiers are sorted by key, and empty or default ones (e.g.
+//     type=jar for maven) are dropped,
+//   - each component is percent-decoded and re-encoded consistently, and
+//     empty, "." and ".." segments are dropped from the subpath.
+//
+// Strings that are not purls are returned unchanged. The results are cached,
+// see SetPurlCacheSize.
+func NormalizePurl(purl string) string {
+	cache := purls
+	if cache == nil {
+		return normalizePurl(purl)
+	}
+	if normalized, ok := cache.get(purl); ok {
+		return normalized
+	}
+	normalized := normalizePurl(purl)
+	cache.add(purl, normalized)
+	return normalized
+}
+
+func normalizePurl(purl string) string {
+	rest, ok := cutPrefixFold(strings.TrimSpace(purl), "pkg:")
+	if !ok {
+		return purl
+	}
+	rest = strings.TrimLeft(rest, "/")
+
+	rest, subpath, _ := strings.Cut(rest, "#")
+	rest, qualifiers, _ := strings.Cut(rest, "?")
+	typ, rest, ok := strings.Cut(rest, "/")
+	if !ok || typ == "" {
+		return purl
+	}
+	typ = strings.ToLower(typ)
+
+	var version string
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest, version = rest[:i], unescapePurl(rest[i+1:])
+	}
+	// Segments cannot contain slashes, so encoded slashes (written by some
+	// tools in golang namespaces) are separators too
+	segments := []string{}
+	for _, s := range strings.Split(unescapePurl(rest), "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return purl
+	}
+	name := segments[len(segments)-1]
+	namespace := segments[:len(segments)-1]
+
+	lower := lowercasedPurlParts[typ]
+	if lower.namespace {
+		for i := range namespace {
+			namespace[i] = strings.ToLower(namespace[i])
+		}
+	}
+	if lower.name {
+		name = strings.ToLower(name)
+	}
+	switch typ {
+	case "pypi":
+		name = strings.ReplaceAll(name, "_", "-")
+	case "oci":
+		version = strings.ToLower(version)
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:")
+	b.WriteString(typ)
+	b.WriteString("/")
+	for _, s := range namespace {
+		b.WriteString(escapePurl(s, ":"))
+		b.WriteString("/")
+	}
+	b.WriteString(escapePurl(name, ":"))
+	if version != "" {
+		b.WriteString("@")
+		b.WriteString(escapePurl(version, ":/"))
+	}
+	if q := normalizeQualifiers(typ, qualifiers); q != "" {
+		b.WriteString("?")
+		b.WriteString(q)
+	}
+	if s := normalizeSubpath(subpath); s != "" {
+		b.WriteString("#")
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// PurlName splits the purl into the package it names, without version nor
+// qualifiers, and its version, both normalized. Different versions of a
+// package (e.g. built for different architectures) have the same name. The
+// name of a string that is not a purl is the string itself.
+func PurlName(purl string) (name string, version string) {
+	normalized := NormalizePurl(purl)
+	rest, ok := cutPrefixFold(normalized, "pkg:")
+	if !ok {
+		return purl, ""
+	}
+	rest, subpath, _ := strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest, version = rest[:i], unescapePurl(rest[i+1:])
+	}
+	name = "pkg:" + rest
+	if subpath != "" {
+		name += "#" + subpath
+	}
+	return name, version
+}
+
+// OCIPurl returns the purl of the container image given by a repository
+// digest (e.g. `index.docker.io/library/alpine@sha256:65a2...`), or the
+// empty string if it is not pinned by digest. The repository is kept in the
+// repository_url qualifier, unless it is only the name of the image.
+func OCIPurl(repoDigest string) string {
+	repository, digest, ok := strings.Cut(repoDigest, "@")
+	if !ok || repository == "" || digest == "" {
+		return ""
+	}
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	purl := "pkg:oci/" + name + "@" + digest
+	if name != repository {
+		purl += "?repository_url=" + repository
+	}
+	return NormalizePurl(purl)
+}
+
+func normalizeQualifiers(typ string, qualifiers string) string {
+	values := map[string]string{}
+	for _, kv := range strings.Split(qualifiers, "&") {
+		k, v, _ := strings.Cut(kv, "=")
+		k = strings.ToLower(unescapePurl(k))
+		v = unescapePurl(v)
+		if k == "" || v == "" || defaultPurlQualifiers[typ][k] == v {
+			continue
+		}
+		values[k] = v
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = escapePurl(k, "") + "=" + escapePurl(values[k], ":/@?=")
+	}
+	return strings.Join(pairs, "&")
+}
+
+func normalizeSubpath(subpath string) string {
+	segments := []string{}
+	for _, s := range strings.Split(subpath, "/") {
+		s = unescapePurl(s)
+		if s == "" || s == "." || s == ".." {
+			continue
+		}
+		segments = append(segments, escapePurl(s, ":"))
+	}
+	return strings.Join(segments, "/")
+}
+
+// unescapePurl percent-decodes s. Invalid escapes are kept as they are. Unlike
+// in URL queries, "+" is not decoded to a space.
+func unescapePurl(s string) string {
+	u, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return u
+}
+
+// escapePurl percent-encodes the bytes of s other than the unreserved
+// characters of RFC 3986, "+" and those in allowed
+func escapePurl(s string, allowed string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreservedPurl(c) || strings.IndexByte(allowed, c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
+
+func isUnreservedPurl(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~' || c == '+'
+}
+
+// cutPrefixFold is like strings.CutPrefix, ignoring case
+func cutPrefixFold(s string, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
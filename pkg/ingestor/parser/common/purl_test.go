@@ -0,0 +1,184 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "testing"
+
+func TestNormalizePurl(t *testing.T) {
+	testCases := []struct {
+		name       string
+		purls      []string
+		normalized string
+	}{{
+		name: "golang qualifier ordering and empty qualifiers",
+		purls: []string{
+			"pkg:golang/github.com/Sirupsen/logrus@v1.9.0?type=module&goos=linux",
+			"pkg:golang/github.com/Sirupsen/logrus@v1.9.0?goos=linux&type=module",
+			"PKG:GoLang/github.com/Sirupsen/logrus@v1.9.0?GOOS=linux&type=module&goarch=",
+			"pkg:golang/github.com%2FSirupsen/logrus@v1.9.0?goos=linux&type=module",
+		},
+		normalized: "pkg:golang/github.com/Sirupsen/logrus@v1.9.0?goos=linux&type=module",
+	}, {
+		name: "maven default type",
+		purls: []string{
+			"pkg:maven/org.apache.commons/commons-text@1.9",
+			"pkg:maven/org.apache.commons/commons-text@1.9?type=jar",
+			"pkg:Maven/org.apache.commons/commons-text@1.9?type=jar&classifier=",
+		},
+		normalized: "pkg:maven/org.apache.commons/commons-text@1.9",
+	}, {
+		name: "maven non default type",
+		purls: []string{
+			"pkg:maven/org.apache.commons/commons-text@1.9?type=pom",
+		},
+		normalized: "pkg:maven/org.apache.commons/commons-text@1.9?type=pom",
+	}, {
+		name: "deb case and encoding",
+		purls: []string{
+			"pkg:deb/debian/libstdc++6@10.2.1-6?arch=amd64&distro=debian-11",
+			"pkg:deb/Debian/LibStdC++6@10.2.1-6?distro=debian-11&arch=amd64",
+			"pkg:deb/debian/libstdc%2B%2B6@10.2.1-6?arch=amd64&distro=debian-11",
+		},
+		normalized: "pkg:deb/debian/libstdc++6@10.2.1-6?arch=amd64&distro=debian-11",
+	}, {
+		name: "alpine qualifier ordering",
+		purls: []string{
+			"pkg:alpine/alpine-baselayout@3.2.0-r22?arch=x86_64&upstream=alpine-baselayout&distro=alpine-3.16.2",
+			"pkg:alpine/alpine-baselayout@3.2.0-r22?distro=alpine-3.16.2&arch=x86_64&upstream=alpine-baselayout",
+		},
+		normalized: "pkg:alpine/alpine-baselayout@3.2.0-r22?arch=x86_64&distro=alpine-3.16.2&upstream=alpine-baselayout",
+	}, {
+		name: "npm scope and name",
+		purls: []string{
+			"pkg:npm/%40angular/animation@12.3.1",
+			"pkg:npm/@angular/Animation@12.3.1",
+		},
+		normalized: "pkg:npm/%40angular/animation@12.3.1",
+	}, {
+		name: "pypi name",
+		purls: []string{
+			"pkg:pypi/django_allauth@0.51.0",
+			"pkg:pypi/Django-Allauth@0.51.0",
+		},
+		normalized: "pkg:pypi/django-allauth@0.51.0",
+	}, {
+		name: "github",
+		purls: []string{
+			"pkg:github/Package-URL/purl-spec@244fd47e07d1004",
+			"pkg://github/package-url/purl-spec@244fd47e07d1004",
+		},
+		normalized: "pkg:github/package-url/purl-spec@244fd47e07d1004",
+	}, {
+		name: "oci digest and repository",
+		purls: []string{
+			"pkg:oci/debian@sha256%3A244FD47E07D10?repository_url=ghcr.io/debian&tag=bullseye",
+			"pkg:oci/Debian@sha256:244fd47e07d10?tag=bullseye&repository_url=ghcr.io%2Fdebian",
+		},
+		normalized: "pkg:oci/debian@sha256:244fd47e07d10?repository_url=ghcr.io/debian&tag=bullseye",
+	}, {
+		name: "subpath",
+		purls: []string{
+			"pkg:golang/google.golang.org/genproto#googleapis/api/annotations",
+			"pkg:golang/google.golang.org/genproto#/googleapis/./api/annotations/",
+		},
+		normalized: "pkg:golang/google.golang.org/genproto#googleapis/api/annotations",
+	}, {
+		name: "not a purl",
+		purls: []string{
+			"alpine-baselayout",
+		},
+		normalized: "alpine-baselayout",
+	}, {
+		name: "purl without name",
+		purls: []string{
+			"pkg:maven",
+		},
+		normalized: "pkg:maven",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, purl := range tt.purls {
+				if got := NormalizePurl(purl); got != tt.normalized {
+					t.Errorf("NormalizePurl(%q) = %q, want %q", purl, got, tt.normalized)
+				}
+				if got := NormalizePurl(tt.normalized); got != tt.normalized {
+					t.Errorf("NormalizePurl(%q) = %q, want it unchanged", tt.normalized, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOCIPurl(t *testing.T) {
+	testCases := []struct {
+		name       string
+		repoDigest string
+		want       string
+	}{{
+		name:       "repository with registry",
+		repoDigest: "index.docker.io/library/alpine@sha256:65a2763f593ae85fab3b5406dc9e80f744ec5b449f269b699b5efd37a07ad32e",
+		want:       "pkg:oci/alpine@sha256:65a2763f593ae85fab3b5406dc9e80f744ec5b449f269b699b5efd37a07ad32e?repository_url=index.docker.io/library/alpine",
+	}, {
+		name:       "image name only",
+		repoDigest: "Alpine@SHA256:65A2763F",
+		want:       "pkg:oci/alpine@sha256:65a2763f",
+	}, {
+		name:       "not pinned by digest",
+		repoDigest: "alpine:3.16.2",
+		want:       "",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OCIPurl(tt.repoDigest); got != tt.want {
+				t.Errorf("OCIPurl(%q) = %q, want %q", tt.repoDigest, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurlName(t *testing.T) {
+	testCases := []struct {
+		purl        string
+		wantName    string
+		wantVersion string
+	}{{
+		purl:        "pkg:npm/%40babel/Core@7.20.0",
+		wantName:    "pkg:npm/%40babel/core",
+		wantVersion: "7.20.0",
+	}, {
+		purl:        "pkg:deb/debian/libc6@2.31-13?arch=amd64&distro=debian-11",
+		wantName:    "pkg:deb/debian/libc6",
+		wantVersion: "2.31-13",
+	}, {
+		purl:        "pkg:golang/github.com/google/go-cmp@v0.5.9#cmp/internal",
+		wantName:    "pkg:golang/github.com/google/go-cmp#cmp/internal",
+		wantVersion: "v0.5.9",
+	}, {
+		purl:     "pkg:generic/openssl",
+		wantName: "pkg:generic/openssl",
+	}, {
+		purl:     "not a purl",
+		wantName: "not a purl",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.purl, func(t *testing.T) {
+			name, version := PurlName(tt.purl)
+			if name != tt.wantName || version != tt.wantVersion {
+				t.Errorf("PurlName(%q) = %q, %q, want %q, %q", tt.purl, name, version, tt.wantName, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/guacsec/guac/pkg/metrics"
+)
+
+// DefaultPurlCacheSize is the default number of purls whose normalized form
+// is remembered by NormalizePurl
+const DefaultPurlCacheSize = 50000
+
+// purls is the cache of NormalizePurl, shared by all the parsers, nil when
+// disabled
+var purls = newPurlCache(DefaultPurlCacheSize)
+
+// SetPurlCacheSize sets the number of purls whose normalized form is
+// remembered, so that the packages found in many documents are only
+// normalized once. The least recently normalized purls are forgotten first.
+// 0 disables the cache. It must be called before the documents are parsed.
+func SetPurlCacheSize(size int) {
+	if size <= 0 {
+		purls = nil
+		return
+	}
+	purls = newPurlCache(size)
+}
+
+// purlCache maps purls to their normalized form. Normalizing is pure, so
+// entries never go stale. It is safe for concurrent use.
+type purlCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the purlCacheEntry values, most recently used first
+	order *list.List
+}
+
+type purlCacheEntry struct {
+	purl       string
+	normalized string
+}
+
+func newPurlCache(size int) *purlCache {
+	return &purlCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// get returns the normalized form of purl, if it is remembered, counting the
+// hit or miss
+func (c *purlCache) get(purl string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[purl]; ok {
+		c.order.MoveToFront(el)
+		metrics.PurlCacheHits.Inc()
+		return el.Value.(*purlCacheEntry).normalized, true
+	}
+	metrics.PurlCacheMisses.Inc()
+	return "", false
+}
+
+// add remembers the normalized form of purl, forgetting the least recently
+// used purls beyond the size of the cache
+func (c *purlCache) add(purl string, normalized string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[purl]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[purl] = c.order.PushFront(&purlCacheEntry{purl: purl, normalized: normalized})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*purlCacheEntry).purl)
+	}
+}
+
+// len returns the number of purls remembered
+func (c *purlCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
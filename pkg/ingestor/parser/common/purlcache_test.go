@@ -0,0 +1,70 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/guacsec/guac/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPurlCache(t *testing.T) {
+	c := newPurlCache(2)
+	c.add("a", "A")
+	c.add("b", "B")
+	if n, ok := c.get("a"); !ok || n != "A" {
+		t.Fatalf("get(a) = %q, %v, want the cached normalized purl", n, ok)
+	}
+	if _, ok := c.get("c"); ok {
+		t.Errorf("expected a purl not added to be missing")
+	}
+
+	// getting a made b the least recently used
+	c.add("c", "C")
+	if c.len() != 2 {
+		t.Errorf("cache holds %d purls, want 2", c.len())
+	}
+	if _, ok := c.get("b"); ok {
+		t.Errorf("expected the least recently used purl to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("expected the recently used purl to be cached")
+	}
+}
+
+func TestNormalizePurl_Cache(t *testing.T) {
+	defer SetPurlCacheSize(DefaultPurlCacheSize)
+
+	purl := "pkg:NPM/%40Angular/Core@1.0.0?b=2&a=1"
+	want := normalizePurl(purl)
+	for _, size := range []int{0, 1, DefaultPurlCacheSize} {
+		SetPurlCacheSize(size)
+		hits := testutil.ToFloat64(metrics.PurlCacheHits)
+		for i := 0; i < 3; i++ {
+			if got := NormalizePurl(purl); got != want {
+				t.Errorf("NormalizePurl() with cache size %d = %s, want %s", size, got, want)
+			}
+		}
+		wantHits := 2.0
+		if size == 0 {
+			wantHits = 0
+		}
+		if got := testutil.ToFloat64(metrics.PurlCacheHits) - hits; got != wantHits {
+			t.Errorf("cache size %d counted %v hits, want %v", size, got, wantHits)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+)
+
+// vcsPrefixes are the prefixes naming the version control system of VCS
+// locators, as used by SPDX download locations, pip and SLSA materials
+var vcsPrefixes = []string{"git+", "hg+", "svn+", "bzr+"}
+
+// VCSReference is a reference to a source repository, and possibly to one
+// of its revisions
+type VCSReference struct {
+	// VCS is the version control system, e.g. "git"
+	VCS string
+	// Repo is the normalized URL of the repository
+	Repo string
+	// Revision is the commit, tag or branch, if any
+	Revision string
+}
+
+// ParseVCS parses a VCS locator, `<vcs>+<url>[@<revision>][#<subpath>]`
+// (e.g. `git+https://github.com/guacsec/guac@v0.1.0`), or a `git://` URL.
+// Other URLs are only taken as repositories of defaultVCS, when it is not
+// empty, e.g. for the references that a document explicitly marks as VCS.
+//
+// The URL of the repository is normalized so that the references written by
+// different tools match: the scheme and the host are lowercased, and the
+// credentials, the trailing slash and the `.git` suffix are dropped.
+func ParseVCS(locator string, defaultVCS string) (VCSReference, bool) {
+	locator = strings.TrimSpace(locator)
+	ref := VCSReference{}
+	for _, prefix := range vcsPrefixes {
+		if len(locator) > len(prefix) && strings.EqualFold(locator[:len(prefix)], prefix) {
+			ref.VCS = strings.TrimSuffix(prefix, "+")
+			locator = locator[len(prefix):]
+			break
+		}
+	}
+	scheme, rest, ok := strings.Cut(locator, "://")
+	if !ok || scheme == "" {
+		return VCSReference{}, false
+	}
+	scheme = strings.ToLower(scheme)
+	if ref.VCS == "" {
+		switch {
+		case scheme == "git":
+			ref.VCS = "git"
+		case defaultVCS != "":
+			ref.VCS = defaultVCS
+		default:
+			return VCSReference{}, false
+		}
+	}
+
+	rest, _, _ = strings.Cut(rest, "#")
+	host, path, _ := strings.Cut(rest, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.LastIndex(path, "@"); i >= 0 {
+		path, ref.Revision = path[:i], path[i+1:]
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	if host == "" {
+		return VCSReference{}, false
+	}
+	ref.Repo = scheme + "://" + strings.ToLower(host)
+	if path != "" {
+		ref.Repo += "/" + path
+	}
+	return ref, true
+}
+
+// CommitSha returns the revision, lowercased, if it is the id of a commit
+// (a full sha1 or sha256 hash), or "" for tags, branches and other refs
+func (r VCSReference) CommitSha() string {
+	if len(r.Revision) != 40 && len(r.Revision) != 64 {
+		return ""
+	}
+	for _, c := range strings.ToLower(r.Revision) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ""
+		}
+	}
+	return strings.ToLower(r.Revision)
+}
+
+// BuildSource is the source a package or an artifact is built from: a
+// repository and, when the revision is known, a commit of it
+type BuildSource struct {
+	Source assembler.SourceNode
+	Commit *assembler.CommitNode
+}
+
+// NewBuildSource returns the source given by ref. The commit is sha when it
+// is not empty (e.g. the digest of a SLSA material), otherwise the revision
+// of ref if it is a commit.
+func NewBuildSource(ref VCSReference, sha string, info processor.SourceInformation) BuildSource {
+	b := BuildSource{Source: assembler.SourceNode{
+		URL:      ref.Repo,
+		VCS:      ref.VCS,
+		NodeData: *assembler.NewObjectMetadata(info),
+	}}
+	if sha == "" {
+		sha = ref.CommitSha()
+	}
+	if sha != "" {
+		b.Commit = &assembler.CommitNode{
+			VCS:        ref.VCS,
+			Sha:        strings.ToLower(sha),
+			Repository: ref.Repo,
+			NodeData:   *assembler.NewObjectMetadata(info),
+		}
+	}
+	return b
+}
+
+// Nodes returns the nodes of the repository and of the commit
+func (b BuildSource) Nodes() []assembler.GuacNode {
+	nodes := []assembler.GuacNode{b.Source}
+	if b.Commit != nil {
+		nodes = append(nodes, *b.Commit)
+	}
+	return nodes
+}
+
+// Edges returns the edge from the repository to the commit, if any
+func (b BuildSource) Edges() []assembler.GuacEdge {
+	if b.Commit == nil {
+		return []assembler.GuacEdge{}
+	}
+	return []assembler.GuacEdge{assembler.HasCommitEdge{SourceNode: b.Source, CommitNode: *b.Commit}}
+}
+
+// BuiltFrom returns the edge from the package or artifact n to the commit,
+// or to the repository when the commit is unknown
+func (b BuildSource) BuiltFrom(n assembler.GuacNode) assembler.GuacEdge {
+	e := assembler.BuiltFromSourceEdge{}
+	switch n := n.(type) {
+	case assembler.ArtifactNode:
+		e.ArtifactNode = n
+	case assembler.PackageNode:
+		e.PackageNode = n
+	}
+	if b.Commit != nil {
+		e.CommitNode = *b.Commit
+	} else {
+		e.SourceNode = b.Source
+	}
+	return e
+}
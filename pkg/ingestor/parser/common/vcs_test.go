@@ -0,0 +1,90 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+)
+
+func TestParseVCS(t *testing.T) {
+	testCases := []struct {
+		name       string
+		locator    string
+		defaultVCS string
+		want       VCSReference
+		wantOK     bool
+		wantSha    string
+	}{{
+		name:    "git with commit",
+		locator: "git+https://github.com/guacsec/guac@D6525C840A62B398424A78D792F457477135D0CF",
+		want: VCSReference{
+			VCS:      "git",
+			Repo:     "https://github.com/guacsec/guac",
+			Revision: "D6525C840A62B398424A78D792F457477135D0CF",
+		},
+		wantOK:  true,
+		wantSha: "d6525c840a62b398424a78d792f457477135d0cf",
+	}, {
+		name:    "git with tag and subpath",
+		locator: "git+ssh://git@GitHub.com/guacsec/guac.git@v0.1.0#pkg/assembler",
+		want: VCSReference{
+			VCS:      "git",
+			Repo:     "ssh://github.com/guacsec/guac",
+			Revision: "v0.1.0",
+		},
+		wantOK: true,
+	}, {
+		name:    "git scheme",
+		locator: "git://git.kernel.org/pub/scm/git/git.git",
+		want:    VCSReference{VCS: "git", Repo: "git://git.kernel.org/pub/scm/git/git"},
+		wantOK:  true,
+	}, {
+		name:    "mercurial",
+		locator: "hg+https://hg.mozilla.org/mozilla-central/",
+		want:    VCSReference{VCS: "hg", Repo: "https://hg.mozilla.org/mozilla-central"},
+		wantOK:  true,
+	}, {
+		name:       "plain url with default",
+		locator:    "https://github.com/quarkusio/quarkus",
+		defaultVCS: "git",
+		want:       VCSReference{VCS: "git", Repo: "https://github.com/quarkusio/quarkus"},
+		wantOK:     true,
+	}, {
+		name:    "plain url without default",
+		locator: "https://github.com/quarkusio/quarkus",
+	}, {
+		name:       "spdx noassertion",
+		locator:    "NOASSERTION",
+		defaultVCS: "git",
+	}, {
+		name:    "missing host",
+		locator: "git+file:///tmp/repo",
+	}}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseVCS(tt.locator, tt.defaultVCS)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseVCS(%q) ok = %v, want %v", tt.locator, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVCS(%q) = %+v, want %+v", tt.locator, got, tt.want)
+			}
+			if sha := got.CommitSha(); sha != tt.wantSha {
+				t.Errorf("CommitSha() = %q, want %q", sha, tt.wantSha)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The CSAF parser parses VEX documents in the CSAF format
+// (https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html).
+//
+// For each vulnerability, a vulnerability node is generated for its CVE (or
+// its first other identifier if it has no CVE), and one for each of its
+// other identifiers (e.g., a GHSA), linked via "AliasOf" edges.
+//
+// The products of the product tree identified by a purl become package
+// nodes. Each product listed in the product status of a vulnerability is
+// linked from the vulnerability node via a "VexStatement" edge, the CSAF
+// product status being mapped to the VEX status:
+//
+//	known_not_affected                            -> not_affected
+//	known_affected, first_affected, last_affected -> affected
+//	fixed, first_fixed                            -> fixed
+//	under_investigation                           -> under_investigation
+//
+// The justification is the label of the flag of the product, the impact
+// statement the details of its "impact" threat and the action statement the
+// details of its remediations.
+//
+// Products only identified by a CPE cannot be linked to a package node.
+// Those known to be affected by a vulnerability are linked from the
+// vulnerability node to a CPE node via an "Affects" edge instead, which
+// reaches the packages that SBOMs give with the same CPE.
+package csaf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/csaf"
+	"github.com/guacsec/guac/pkg/handler/processor/openvex"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type csafParser struct {
+	vulns      []assembler.VulnerabilityNode
+	aliases    []assembler.AliasOfEdge
+	statements []assembler.VexStatementEdge
+	affects    []assembler.CPEAffectsEdge
+}
+
+// NewCSAFParser initializes the csafParser
+func NewCSAFParser() common.DocumentParser {
+	return &csafParser{
+		vulns:      []assembler.VulnerabilityNode{},
+		aliases:    []assembler.AliasOfEdge{},
+		statements: []assembler.VexStatementEdge{},
+		affects:    []assembler.CPEAffectsEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *csafParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentCSAF {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentCSAF, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of CSAF document format: %v", doc.Format)
+	}
+
+	var csafDoc csaf.Document
+	if err := json.Unmarshal(doc.Blob, &csafDoc); err != nil {
+		return err
+	}
+
+	purls, cpes := productIdentifiers(csafDoc.ProductTree)
+	for _, v := range csafDoc.Vulnerabilities {
+		ids := vulnerabilityIDs(v)
+		if len(ids) == 0 {
+			logger.Debugf("skipping vulnerability of CSAF document %s without identifier", csafDoc.Document.Tracking.ID)
+			continue
+		}
+		vuln := assembler.VulnerabilityNode{
+			ID:       ids[0],
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		p.vulns = append(p.vulns, vuln)
+		for _, id := range ids[1:] {
+			p.aliases = append(p.aliases, assembler.AliasOfEdge{
+				VulnerabilityNode: vuln,
+				AliasNode: assembler.VulnerabilityNode{
+					ID:       id,
+					NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+				},
+			})
+		}
+
+		for _, ps := range productStatuses(v.ProductStatus) {
+			for _, productID := range ps.products {
+				purl, ok := purls[productID]
+				if !ok {
+					if cpe, ok := cpes[productID]; ok && ps.status == openvex.StatusAffected {
+						p.affects = append(p.affects, assembler.CPEAffectsEdge{
+							VulnerabilityNode: vuln,
+							CPENode:           assembler.CPENode{CPE: cpe},
+						})
+						continue
+					}
+					logger.Debugf("skipping product %s of VEX statement for %s, no purl", productID, vuln.ID)
+					continue
+				}
+				p.statements = append(p.statements, assembler.VexStatementEdge{
+					VulnerabilityNode: vuln,
+					PackageNode:       assembler.PackageNode{Purl: purl},
+					Status:            string(ps.status),
+					Justification:     justification(v, productID),
+					ImpactStatement:   impactStatement(v, productID),
+					ActionStatement:   actionStatement(v, productID),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// productIdentifiers maps the ids of the products of the tree to their purl
+// and to their CPE
+func productIdentifiers(tree csaf.ProductTree) (map[string]string, map[string]string) {
+	purls := map[string]string{}
+	cpes := map[string]string{}
+	add := func(p csaf.FullProduct) {
+		if p.ProductIdentificationHelper == nil {
+			return
+		}
+		if p.ProductIdentificationHelper.Purl != "" {
+			purls[p.ProductID] = common.NormalizePurl(p.ProductIdentificationHelper.Purl)
+		}
+		if p.ProductIdentificationHelper.CPE != "" {
+			cpes[p.ProductID] = p.ProductIdentificationHelper.CPE
+		}
+	}
+	var walk func(branches []csaf.Branch)
+	walk = func(branches []csaf.Branch) {
+		for _, b := range branches {
+			if b.Product != nil {
+				add(*b.Product)
+			}
+			walk(b.Branches)
+		}
+	}
+	walk(tree.Branches)
+	for _, p := range tree.FullProductNames {
+		add(p)
+	}
+	for _, r := range tree.Relationships {
+		add(r.FullProductName)
+	}
+	return purls, cpes
+}
+
+// vulnerabilityIDs returns the CVE of the vulnerability followed by its other
+// identifiers
+func vulnerabilityIDs(v csaf.Vulnerability) []string {
+	ids := []string{}
+	if v.CVE != "" {
+		ids = append(ids, v.CVE)
+	}
+	for _, id := range v.IDs {
+		if id.Text != "" && id.Text != v.CVE {
+			ids = append(ids, id.Text)
+		}
+	}
+	return ids
+}
+
+type productStatus struct {
+	status   openvex.Status
+	products []string
+}
+
+func productStatuses(s csaf.ProductStatus) []productStatus {
+	return []productStatus{
+		{openvex.StatusNotAffected, s.KnownNotAffected},
+		{openvex.StatusAffected, s.KnownAffected},
+		{openvex.StatusAffected, s.FirstAffected},
+		{openvex.StatusAffected, s.LastAffected},
+		{openvex.StatusFixed, s.Fixed},
+		{openvex.StatusFixed, s.FirstFixed},
+		{openvex.StatusUnderInvestigation, s.UnderInvestigation},
+	}
+}
+
+func justification(v csaf.Vulnerability, productID string) string {
+	for _, f := range v.Flags {
+		if contains(f.ProductIDs, productID) {
+			return f.Label
+		}
+	}
+	return ""
+}
+
+func impactStatement(v csaf.Vulnerability, productID string) string {
+	for _, t := range v.Threats {
+		if t.Category == "impact" && contains(t.ProductIDs, productID) {
+			return t.Details
+		}
+	}
+	return ""
+}
+
+func actionStatement(v csaf.Vulnerability, productID string) string {
+	details := []string{}
+	for _, r := range v.Remediations {
+		if contains(r.ProductIDs, productID) {
+			details = append(details, r.Details)
+		}
+	}
+	return strings.Join(details, "\n")
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *csafParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, v := range p.vulns {
+		nodes = append(nodes, v)
+	}
+	for _, a := range p.aliases {
+		nodes = append(nodes, a.AliasNode)
+	}
+	for _, s := range p.statements {
+		nodes = append(nodes, s.PackageNode)
+	}
+	for _, a := range p.affects {
+		nodes = append(nodes, a.CPENode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *csafParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, a := range p.aliases {
+		edges = append(edges, a)
+	}
+	for _, s := range p.statements {
+		edges = append(edges, s)
+	}
+	for _, a := range p.affects {
+		edges = append(edges, a)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *csafParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -17,13 +17,14 @@ package cyclonedx
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	cdx "github.com/CycloneDX/cyclonedx-go"
 	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/handler/processor"
+	cdx_processor "github.com/guacsec/guac/pkg/handler/processor/cyclonedx"
+	"github.com/guacsec/guac/pkg/handler/processor/openvex"
 	"github.com/guacsec/guac/pkg/ingestor/parser/common"
 )
 
@@ -31,17 +32,28 @@ type cyclonedxParser struct {
 	doc           *processor.Document
 	rootComponent component
 	pkgMap        map[string]*component
+	vulnerable    []assembler.VulnerableToEdge
+	statements    []assembler.VexStatementEdge
+	vulnMap       map[string]assembler.VulnerabilityNode
 }
 
 type component struct {
 	curPackage  assembler.PackageNode
 	depPackages []*component
+	// sources are the repositories given by the VCS references of the
+	// component
+	sources []common.BuildSource
+	// licenses are the licenses declared by the component
+	licenses []common.License
 }
 
 func NewCycloneDXParser() common.DocumentParser {
 	return &cyclonedxParser{
 		rootComponent: component{},
 		pkgMap:        map[string]*component{},
+		vulnerable:    []assembler.VulnerableToEdge{},
+		statements:    []assembler.VexStatementEdge{},
+		vulnMap:       map[string]assembler.VulnerabilityNode{},
 	}
 }
 
@@ -51,9 +63,39 @@ func (c *cyclonedxParser) CreateNodes(ctx context.Context) []assembler.GuacNode
 	for _, p := range c.rootComponent.depPackages {
 		nodes = append(nodes, p.curPackage)
 	}
+	for _, src := range c.rootComponent.sources {
+		nodes = append(nodes, src.Nodes()...)
+	}
+	for _, p := range c.rootComponent.depPackages {
+		for _, src := range p.sources {
+			nodes = append(nodes, src.Nodes()...)
+		}
+	}
+	for _, v := range c.vulnMap {
+		nodes = append(nodes, v)
+	}
+	for _, l := range c.uniqueLicenses() {
+		nodes = append(nodes, l.Nodes()...)
+	}
 	return nodes
 }
 
+// uniqueLicenses returns the licenses of all the components, once each even
+// when declared by several components
+func (c *cyclonedxParser) uniqueLicenses() []common.License {
+	unique := []common.License{}
+	seen := map[string]bool{}
+	for _, p := range append([]*component{&c.rootComponent}, c.rootComponent.depPackages...) {
+		for _, l := range p.licenses {
+			if !seen[l.Node.Expression] {
+				seen[l.Node.Expression] = true
+				unique = append(unique, l)
+			}
+		}
+	}
+	return unique
+}
+
 func addEdges(curPkg component, edges *[]assembler.GuacEdge) {
 	// this could happen if we image purl creation fails for rootPackage
 	// we need better solution to support different image name formats in SBOM
@@ -66,6 +108,29 @@ func addEdges(curPkg component, edges *[]assembler.GuacEdge) {
 	}
 }
 
+// addSourceEdges links the package of the component to the repositories it
+// is built from
+func addSourceEdges(curPkg component, edges *[]assembler.GuacEdge) {
+	if curPkg.curPackage.Name == "" {
+		return
+	}
+	for _, src := range curPkg.sources {
+		*edges = append(*edges, src.BuiltFrom(curPkg.curPackage))
+		*edges = append(*edges, src.Edges()...)
+	}
+}
+
+// addLicenseEdges links the package of the component to the licenses it
+// declares
+func addLicenseEdges(curPkg component, edges *[]assembler.GuacEdge) {
+	if curPkg.curPackage.Name == "" {
+		return
+	}
+	for _, l := range curPkg.licenses {
+		*edges = append(*edges, l.Declared(curPkg.curPackage))
+	}
+}
+
 // Parse breaks out the document into the graph components
 func (c *cyclonedxParser) Parse(ctx context.Context, doc *processor.Document) error {
 	c.doc = doc
@@ -75,6 +140,7 @@ func (c *cyclonedxParser) Parse(ctx context.Context, doc *processor.Document) er
 	}
 	c.addRootPackage(cdxBom)
 	c.addPackages(cdxBom)
+	c.addVulnerabilities(cdxBom)
 
 	return nil
 }
@@ -87,24 +153,39 @@ func (c *cyclonedxParser) GetIdentities(ctx context.Context) []assembler.Identit
 func (c *cyclonedxParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
 	edges := []assembler.GuacEdge{}
 	addEdges(c.rootComponent, &edges)
+	addSourceEdges(c.rootComponent, &edges)
+	addLicenseEdges(c.rootComponent, &edges)
+	for _, p := range c.rootComponent.depPackages {
+		addSourceEdges(*p, &edges)
+		addLicenseEdges(*p, &edges)
+	}
+	for _, l := range c.uniqueLicenses() {
+		edges = append(edges, l.Edges()...)
+	}
+	for _, v := range c.vulnerable {
+		edges = append(edges, v)
+	}
+	for _, s := range c.statements {
+		edges = append(edges, s)
+	}
 	return edges
 }
 
 func (c *cyclonedxParser) addRootPackage(cdxBom *cdx.BOM) {
 	// oci purl: pkg:oci/debian@sha256%3A244fd47e07d10?repository_url=ghcr.io/debian&tag=bullseye
-	if cdxBom.Metadata.Component != nil {
+	if cdxBom.Metadata != nil && cdxBom.Metadata.Component != nil {
 		rootPackage := assembler.PackageNode{}
 		rootPackage.Name = cdxBom.Metadata.Component.Name
 		// rootPackage.CPEs = nil
 		rootPackage.NodeData = *assembler.NewObjectMetadata(c.doc.SourceInformation)
 		if cdxBom.Metadata.Component.PackageURL != "" {
-			rootPackage.Purl = cdxBom.Metadata.Component.PackageURL
+			rootPackage.Purl = common.NormalizePurl(cdxBom.Metadata.Component.PackageURL)
 			rootPackage.Version = cdxBom.Metadata.Component.Version
 			rootPackage.Tags = []string{string(cdxBom.Metadata.Component.Type)}
 		} else {
 			splitImage := strings.Split(cdxBom.Metadata.Component.Name, "/")
 			if len(splitImage) == 3 {
-				rootPackage.Purl = "pkg:oci/" + splitImage[2] + "?repository_url=" + splitImage[0] + "/" + splitImage[1]
+				rootPackage.Purl = common.NormalizePurl("pkg:oci/" + splitImage[2] + "?repository_url=" + splitImage[0] + "/" + splitImage[1])
 				rootPackage.Version = cdxBom.Metadata.Component.Version
 				rootPackage.Digest = append(rootPackage.Digest, cdxBom.Metadata.Component.Version)
 				rootPackage.Tags = []string{"CONTAINER"}
@@ -113,11 +194,16 @@ func (c *cyclonedxParser) addRootPackage(cdxBom *cdx.BOM) {
 		c.rootComponent = component{
 			curPackage:  rootPackage,
 			depPackages: []*component{},
+			sources:     c.getSources(cdxBom.Metadata.Component),
+			licenses:    c.getLicenses(cdxBom.Metadata.Component),
 		}
 	}
 }
 
 func (c *cyclonedxParser) addPackages(cdxBom *cdx.BOM) {
+	if cdxBom.Components == nil {
+		return
+	}
 	for _, comp := range *cdxBom.Components {
 		// skipping over the "operating-system" type as it does not contain
 		// the required purl for package node. Currently there is no use-case
@@ -126,7 +212,7 @@ func (c *cyclonedxParser) addPackages(cdxBom *cdx.BOM) {
 			curPkg := assembler.PackageNode{
 				Name: comp.Name,
 				// Digest: []string{comp.Version},
-				Purl:     comp.PackageURL,
+				Purl:     common.NormalizePurl(comp.PackageURL),
 				Version:  comp.Version,
 				NodeData: *assembler.NewObjectMetadata(c.doc.SourceInformation),
 			}
@@ -136,6 +222,8 @@ func (c *cyclonedxParser) addPackages(cdxBom *cdx.BOM) {
 			parentPkg := component{
 				curPackage:  curPkg,
 				depPackages: []*component{},
+				sources:     c.getSources(&comp),
+				licenses:    c.getLicenses(&comp),
 			}
 			c.rootComponent.depPackages = append(c.rootComponent.depPackages, &parentPkg)
 			c.pkgMap[comp.BOMRef] = &parentPkg
@@ -158,10 +246,141 @@ func (c *cyclonedxParser) addPackages(cdxBom *cdx.BOM) {
 	}
 }
 
-func parseCycloneDXBOM(d []byte) (*cdx.BOM, error) {
-	bom := cdx.BOM{}
-	if err := json.Unmarshal(d, &bom); err != nil {
-		return nil, err
+// getSources returns the repositories given by the VCS external references of
+// the component
+func (c *cyclonedxParser) getSources(comp *cdx.Component) []common.BuildSource {
+	if comp.ExternalReferences == nil {
+		return nil
+	}
+	sources := []common.BuildSource{}
+	for _, ref := range *comp.ExternalReferences {
+		if ref.Type != cdx.ERTypeVCS {
+			continue
+		}
+		if vcs, ok := common.ParseVCS(ref.URL, "git"); ok {
+			sources = append(sources, common.NewBuildSource(vcs, "", c.doc.SourceInformation))
+		}
 	}
-	return &bom, nil
+	return sources
+}
+
+// getLicenses returns the licenses of the component, given by their SPDX id,
+// their name or an SPDX license expression. Each is declared on its own.
+func (c *cyclonedxParser) getLicenses(comp *cdx.Component) []common.License {
+	if comp.Licenses == nil {
+		return nil
+	}
+	licenses := []common.License{}
+	for _, choice := range *comp.Licenses {
+		expression := choice.Expression
+		if choice.License != nil {
+			expression = choice.License.ID
+			if expression == "" {
+				expression = choice.License.Name
+			}
+		}
+		if common.HasLicense(expression) {
+			licenses = append(licenses, common.NewLicense(expression, c.doc.SourceInformation))
+		}
+	}
+	return licenses
+}
+
+// addVulnerabilities links the vulnerabilities found in the BOM to the
+// components they affect. References to unknown components are ignored.
+//
+// Vulnerabilities with an analysis (embedded VEX, CycloneDX 1.4+) are linked
+// via a "VexStatement" edge, so that they reconcile with the statements of
+// standalone VEX documents, the analysis state being mapped to the VEX
+// status:
+//
+//	not_affected, false_positive          -> not_affected
+//	exploitable                           -> affected
+//	resolved, resolved_with_pedigree      -> fixed
+//	in_triage                             -> under_investigation
+//
+// The justification is kept as is, the impact statement is the detail of the
+// analysis and the action statement its responses. Unless the analysis
+// states that the component is not affected or fixed, the vulnerability is
+// also linked via a "VulnerableTo" edge, as it is without an analysis.
+func (c *cyclonedxParser) addVulnerabilities(cdxBom *cdx.BOM) {
+	if cdxBom.Vulnerabilities == nil {
+		return
+	}
+	rootRef := ""
+	if cdxBom.Metadata != nil && cdxBom.Metadata.Component != nil {
+		rootRef = cdxBom.Metadata.Component.BOMRef
+	}
+	for _, vuln := range *cdxBom.Vulnerabilities {
+		if vuln.ID == "" || vuln.Affects == nil {
+			continue
+		}
+		vulnNode := assembler.VulnerabilityNode{
+			ID:       vuln.ID,
+			NodeData: *assembler.NewObjectMetadata(c.doc.SourceInformation),
+		}
+		status, hasStatus := vexStatus(vuln.Analysis)
+		for _, affects := range *vuln.Affects {
+			affected, found := c.pkgMap[affects.Ref]
+			if !found && affects.Ref != "" && affects.Ref == rootRef {
+				affected, found = &c.rootComponent, true
+			}
+			if !found {
+				continue
+			}
+			c.vulnMap[vuln.ID] = vulnNode
+			if hasStatus {
+				c.statements = append(c.statements, assembler.VexStatementEdge{
+					VulnerabilityNode: vulnNode,
+					PackageNode:       affected.curPackage,
+					Status:            string(status),
+					Justification:     string(vuln.Analysis.Justification),
+					ImpactStatement:   vuln.Analysis.Detail,
+					ActionStatement:   actionStatement(vuln.Analysis),
+				})
+			}
+			if status == openvex.StatusNotAffected || status == openvex.StatusFixed {
+				continue
+			}
+			c.vulnerable = append(c.vulnerable, assembler.VulnerableToEdge{
+				PackageNode:       affected.curPackage,
+				VulnerabilityNode: vulnNode,
+			})
+		}
+	}
+}
+
+// vexStatus maps the state of the analysis to a VEX status. It returns false
+// without analysis, or if its state is unknown.
+func vexStatus(analysis *cdx.VulnerabilityAnalysis) (openvex.Status, bool) {
+	if analysis == nil {
+		return "", false
+	}
+	switch analysis.State {
+	case cdx.IASNotAffected, cdx.IASFalsePositive:
+		return openvex.StatusNotAffected, true
+	case cdx.IASExploitable:
+		return openvex.StatusAffected, true
+	case cdx.IASResolved, cdx.IASResolvedWithPedigree:
+		return openvex.StatusFixed, true
+	case cdx.IASInTriage:
+		return openvex.StatusUnderInvestigation, true
+	default:
+		return "", false
+	}
+}
+
+func actionStatement(analysis *cdx.VulnerabilityAnalysis) string {
+	if analysis.Response == nil {
+		return ""
+	}
+	responses := make([]string, len(*analysis.Response))
+	for i, r := range *analysis.Response {
+		responses[i] = string(r)
+	}
+	return strings.Join(responses, ", ")
+}
+
+func parseCycloneDXBOM(d []byte) (*cdx.BOM, error) {
+	return cdx_processor.DecodeBOM(d)
 }
@@ -61,6 +61,34 @@ func Test_cyclonedxParser(t *testing.T) {
 		wantNodes: testdata.CycloneDXQuarkusNodes,
 		wantEdges: testdata.CyloneDXQuarkusEdges,
 		wantErr:   false,
+	}, {
+		name: "valid CycloneDX 1.5 document with vulnerabilities",
+		doc: &processor.Document{
+			Blob:   testdata.CycloneDXVulnExample,
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentCycloneDX,
+			SourceInformation: processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		},
+		wantNodes: testdata.CycloneDXVulnNodes,
+		wantEdges: testdata.CycloneDXVulnEdges,
+		wantErr:   false,
+	}, {
+		name: "valid CycloneDX 1.5 document with vulnerability analyses",
+		doc: &processor.Document{
+			Blob:   testdata.CycloneDXVEXExample,
+			Format: processor.FormatJSON,
+			Type:   processor.DocumentCycloneDX,
+			SourceInformation: processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		},
+		wantNodes: testdata.CycloneDXVEXNodes,
+		wantEdges: testdata.CycloneDXVEXEdges,
+		wantErr:   false,
 	},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,163 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The dependency snapshot parser parses the snapshots of GitHub's dependency
+// submission API (see `depsnapshot.Snapshot`), as sent by the dependency
+// submission actions, so that the dependency graph GitHub shows can be fed
+// to GUAC without generating an SBOM.
+//
+// Each resolved dependency with a purl becomes a package node; the others
+// are skipped. The dependencies of each package become "DependsOn" edges,
+// with the path of the manifest they were found in and the name of the
+// detector as properties. The snapshot does not name the repository, so the
+// direct dependencies of the manifests are not linked to it.
+package depsnapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/depsnapshot"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type depSnapshotParser struct {
+	packages []assembler.PackageNode
+	depends  []assembler.DependsOnEdge
+}
+
+// NewDepSnapshotParser initializes the depSnapshotParser
+func NewDepSnapshotParser() common.DocumentParser {
+	return &depSnapshotParser{
+		packages: []assembler.PackageNode{},
+		depends:  []assembler.DependsOnEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *depSnapshotParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentDepSnapshot {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentDepSnapshot, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of dependency snapshot format: %v", doc.Format)
+	}
+
+	var snapshot depsnapshot.Snapshot
+	if err := json.Unmarshal(doc.Blob, &snapshot); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	pkg := func(name string, purl string) assembler.PackageNode {
+		purl = common.NormalizePurl(purl)
+		_, version := common.PurlName(purl)
+		n := assembler.PackageNode{
+			Name:     name,
+			Version:  version,
+			Purl:     purl,
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		if !seen[purl] {
+			seen[purl] = true
+			p.packages = append(p.packages, n)
+		}
+		return n
+	}
+
+	for _, key := range sortedKeys(snapshot.Manifests) {
+		m := snapshot.Manifests[key]
+		path := m.Path(key)
+		byPurl := map[string]assembler.PackageNode{}
+		for _, name := range sortedKeys(m.Resolved) {
+			if purl := m.Resolved[name].PackageURL; purl != "" {
+				byPurl[purl] = pkg(name, purl)
+			}
+		}
+
+		edges := map[[2]string]bool{}
+		for _, name := range sortedKeys(m.Resolved) {
+			dep := m.Resolved[name]
+			if dep.PackageURL == "" {
+				logger.Debugf("skipping dependency %s of manifest %s, no purl", name, path)
+				continue
+			}
+			dependent := byPurl[dep.PackageURL]
+			for _, ref := range dep.Dependencies {
+				purl, ok := m.Lookup(ref)
+				if !ok {
+					logger.Debugf("skipping dependency %s of %s in manifest %s, not resolved", ref, name, path)
+					continue
+				}
+				dependency, ok := byPurl[purl]
+				if !ok {
+					dependency = pkg("", purl)
+				}
+				if edges[[2]string{dependent.Purl, dependency.Purl}] {
+					continue
+				}
+				edges[[2]string{dependent.Purl, dependency.Purl}] = true
+				p.depends = append(p.depends, assembler.DependsOnEdge{
+					PackageNode:       dependent,
+					PackageDependency: dependency,
+					Manifest:          path,
+					Detector:          snapshot.Detector.Name,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in order, so that the graph does
+// not depend on the iteration order of the maps of the snapshot
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *depSnapshotParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, pkg := range p.packages {
+		nodes = append(nodes, pkg)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *depSnapshotParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, d := range p.depends {
+		edges = append(edges, d)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *depSnapshotParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
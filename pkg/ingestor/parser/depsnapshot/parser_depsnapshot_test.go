@@ -0,0 +1,119 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsnapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_depSnapshotParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	pkg := func(name, version, purl string) assembler.PackageNode {
+		return assembler.PackageNode{
+			Name:     name,
+			Version:  version,
+			Purl:     purl,
+			NodeData: *assembler.NewObjectMetadata(srcInfo),
+		}
+	}
+	cobra := pkg("github.com/spf13/cobra", "v1.6.1", "pkg:golang/github.com/spf13/cobra@v1.6.1")
+	pflag := pkg("github.com/spf13/pflag", "v1.0.5", "pkg:golang/github.com/spf13/pflag@v1.0.5")
+	core := pkg("@actions/core", "1.1.9", "pkg:npm/%40actions/core@1.1.9")
+	httpClient := pkg("@actions/http-client", "1.0.7", "pkg:npm/%40actions/http-client@1.0.7")
+	jest := pkg("jest", "29.7.0", "pkg:npm/jest@29.7.0")
+	tunnel := pkg("tunnel", "0.0.6", "pkg:npm/tunnel@0.0.6")
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "dependency snapshot",
+		doc: &processor.Document{
+			Blob:              testdata.DepSnapshotExample,
+			Type:              processor.DocumentDepSnapshot,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{cobra, pflag, core, httpClient, jest, tunnel},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DependsOnEdge{PackageNode: cobra, PackageDependency: pflag, Manifest: "go.mod", Detector: "component-detection"},
+			assembler.DependsOnEdge{PackageNode: core, PackageDependency: httpClient, Manifest: "web/package-lock.json", Detector: "component-detection"},
+			assembler.DependsOnEdge{PackageNode: httpClient, PackageDependency: tunnel, Manifest: "web/package-lock.json", Detector: "component-detection"},
+		},
+	}, {
+		name: "dependency only given by purl",
+		doc: &processor.Document{
+			Blob: []byte(`{"sha": "ce58745", "ref": "refs/heads/main", "detector": {"name": "gradle"}, "manifests": {"build.gradle": {
+				"resolved": {"guava": {"package_url": "pkg:maven/com.google.guava/guava@32.1.2-jre", "dependencies": ["pkg:maven/com.google.guava/failureaccess@1.0.1"]}}
+			}}}`),
+			Type:              processor.DocumentDepSnapshot,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			pkg("guava", "32.1.2-jre", "pkg:maven/com.google.guava/guava@32.1.2-jre"),
+			pkg("", "1.0.1", "pkg:maven/com.google.guava/failureaccess@1.0.1"),
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DependsOnEdge{
+				PackageNode:       pkg("guava", "32.1.2-jre", "pkg:maven/com.google.guava/guava@32.1.2-jre"),
+				PackageDependency: pkg("", "1.0.1", "pkg:maven/com.google.guava/failureaccess@1.0.1"),
+				Manifest:          "build.gradle",
+				Detector:          "gradle",
+			},
+		},
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.DepSnapshotExample,
+			Type:              processor.DocumentDepSnapshot,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewDepSnapshotParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("depSnapshotParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("depSnapshotParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("depSnapshotParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
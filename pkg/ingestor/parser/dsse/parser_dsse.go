@@ -32,7 +32,8 @@ type dsseParser struct {
 	identities []assembler.IdentityNode
 }
 
-// NewDSSEParser initializes the dsseParser
+// NewDSSEParser initializes the dsseParser, which parses the identities that
+// signed DSSE envelopes, standalone or embedded in Sigstore bundles
 func NewDSSEParser() common.DocumentParser {
 	return &dsseParser{
 		identities: []assembler.IdentityNode{},
@@ -0,0 +1,235 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The in-toto parser parses in-toto links and layouts
+// (https://github.com/in-toto/docs/blob/master/in-toto-spec.md).
+//
+// A link becomes a step node for each functionary that signed it, linked to
+// the artifacts it used via "Material" edges and to the artifacts it created
+// via "Product" edges. Since the products of a step are the materials of the
+// next one, these edges reconstruct the whole supply chain.
+//
+// A layout becomes a layout node, linked via "ExpectsStep" edges to a step
+// node for each step and each functionary allowed to perform it. The step
+// nodes are the same as the ones created from the links signed by these
+// functionaries, so the steps without a link are the ones missing for the
+// layout to be verified.
+package intoto
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/intoto"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+const algorithmSHA256 string = "sha256"
+
+type inTotoParser struct {
+	steps     []assembler.StepNode
+	materials []assembler.MaterialEdge
+	products  []assembler.ProductEdge
+	layouts   []assembler.LayoutNode
+	expected  []assembler.ExpectsStepEdge
+}
+
+// NewInTotoParser initializes the inTotoParser
+func NewInTotoParser() common.DocumentParser {
+	return &inTotoParser{
+		steps:     []assembler.StepNode{},
+		materials: []assembler.MaterialEdge{},
+		products:  []assembler.ProductEdge{},
+		layouts:   []assembler.LayoutNode{},
+		expected:  []assembler.ExpectsStepEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *inTotoParser) Parse(ctx context.Context, doc *processor.Document) error {
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of in-toto document format: %v", doc.Format)
+	}
+
+	switch doc.Type {
+	case processor.DocumentInTotoLink:
+		return p.parseLink(doc)
+	case processor.DocumentInTotoLayout:
+		return p.parseLayout(doc)
+	}
+	return fmt.Errorf("expected in-toto document type, actual document type: %v", doc.Type)
+}
+
+func (p *inTotoParser) parseLink(doc *processor.Document) error {
+	link, err := intoto.ParseLink(doc.Blob)
+	if err != nil {
+		return err
+	}
+	materials, err := intoto.ArtifactDigests(link.Signed.Materials)
+	if err != nil {
+		return fmt.Errorf("invalid materials: %w", err)
+	}
+	products, err := intoto.ArtifactDigests(link.Signed.Products)
+	if err != nil {
+		return fmt.Errorf("invalid products: %w", err)
+	}
+
+	keyIDs := []string{}
+	for _, s := range link.Signatures {
+		keyIDs = append(keyIDs, s.KeyID)
+	}
+	if len(keyIDs) == 0 {
+		// Unsigned links can still be matched by layouts without keys
+		keyIDs = append(keyIDs, "")
+	}
+
+	for _, keyID := range keyIDs {
+		step := assembler.StepNode{
+			Name:     link.Signed.Name,
+			KeyID:    keyID,
+			Command:  link.Signed.Command,
+			Link:     blobDigest(doc.Blob),
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		p.steps = append(p.steps, step)
+		for _, a := range artifacts(materials, doc.SourceInformation) {
+			p.materials = append(p.materials, assembler.MaterialEdge{StepNode: step, ArtifactNode: a})
+		}
+		for _, a := range artifacts(products, doc.SourceInformation) {
+			p.products = append(p.products, assembler.ProductEdge{StepNode: step, ArtifactNode: a})
+		}
+	}
+	return nil
+}
+
+func (p *inTotoParser) parseLayout(doc *processor.Document) error {
+	layout, err := intoto.ParseLayout(doc.Blob)
+	if err != nil {
+		return err
+	}
+
+	node := assembler.LayoutNode{
+		Digest:   blobDigest(doc.Blob),
+		Expires:  layout.Signed.Expires,
+		Readme:   layout.Signed.Readme,
+		Steps:    []string{},
+		NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+	}
+	for _, s := range layout.Signed.Steps {
+		node.Steps = append(node.Steps, s.Name)
+	}
+	p.layouts = append(p.layouts, node)
+
+	for _, s := range layout.Signed.Steps {
+		keyIDs := s.PubKeys
+		if len(keyIDs) == 0 {
+			keyIDs = []string{""}
+		}
+		threshold := s.Threshold
+		if threshold < 1 {
+			threshold = 1
+		}
+		for _, keyID := range keyIDs {
+			p.expected = append(p.expected, assembler.ExpectsStepEdge{
+				LayoutNode: node,
+				StepNode: assembler.StepNode{
+					Name:     s.Name,
+					KeyID:    keyID,
+					NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+				},
+				Threshold: threshold,
+			})
+		}
+	}
+	return nil
+}
+
+// artifacts returns an artifact node for each digest of each artifact, in a
+// stable order
+func artifacts(digests map[string]map[string]string, srcInfo processor.SourceInformation) []assembler.ArtifactNode {
+	paths := make([]string, 0, len(digests))
+	for path := range digests {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	nodes := []assembler.ArtifactNode{}
+	for _, path := range paths {
+		algs := make([]string, 0, len(digests[path]))
+		for alg := range digests[path] {
+			algs = append(algs, alg)
+		}
+		sort.Strings(algs)
+		for _, alg := range algs {
+			nodes = append(nodes, assembler.ArtifactNode{
+				Name:     path,
+				Digest:   common.NormalizeDigest(alg, digests[path][alg]),
+				NodeData: *assembler.NewObjectMetadata(srcInfo),
+			})
+		}
+	}
+	return nodes
+}
+
+func blobDigest(blob []byte) string {
+	h := sha256.Sum256(blob)
+	return algorithmSHA256 + ":" + hex.EncodeToString(h[:])
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *inTotoParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, s := range p.steps {
+		nodes = append(nodes, s)
+	}
+	for _, m := range p.materials {
+		nodes = append(nodes, m.ArtifactNode)
+	}
+	for _, pr := range p.products {
+		nodes = append(nodes, pr.ArtifactNode)
+	}
+	for _, l := range p.layouts {
+		nodes = append(nodes, l)
+	}
+	for _, e := range p.expected {
+		nodes = append(nodes, e.StepNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *inTotoParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, m := range p.materials {
+		edges = append(edges, m)
+	}
+	for _, pr := range p.products {
+		edges = append(edges, pr)
+	}
+	for _, e := range p.expected {
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *inTotoParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
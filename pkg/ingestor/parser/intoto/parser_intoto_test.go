@@ -0,0 +1,148 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_inTotoParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	developer := "776a00e29f3559e0141b3b096f696abc6cfb0c657ab40f441132b345b08453f5"
+	packager := "0c6c50a1b9d2a8f2e1d2b9e0b0b6c1a0f2e9d2b3a6c4e5f7a8b9c0d1e2f3a4b5"
+
+	pkgStep := assembler.StepNode{
+		Name:     "package",
+		KeyID:    packager,
+		Command:  []string{"tar", "--exclude", ".git", "-zcvf", "foo.tar.gz", "foo.py"},
+		Link:     "sha256:c8e19cd270d357c45f1e8247f56da9f6f846e63c388b2c17a67c3c81c9fd6d41",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	source := assembler.ArtifactNode{
+		Name:     "foo.py",
+		Digest:   "sha256:74dc3727c6e89308b39e4dfedf787e37841198b1fa165a27c013544a60502549",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	tarball := assembler.ArtifactNode{
+		Name:     "foo.tar.gz",
+		Digest:   "sha256:52947cb78b91ad01fe81cd6aef42d1f6817e92b9e6936c1e5aabb7c98514f355",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+
+	layout := assembler.LayoutNode{
+		Digest:   "sha256:8d1f1b0423f7f6bcd42e8b6e0f606961fe001ebd82381e4ac9ebd767ac6d3765",
+		Expires:  "2030-11-18T16:06:36Z",
+		Readme:   "Demo supply chain: write the code, then package it",
+		Steps:    []string{"write-code", "package"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	expectedWriteCode := assembler.StepNode{Name: "write-code", KeyID: developer, NodeData: *assembler.NewObjectMetadata(srcInfo)}
+	expectedPackage := assembler.StepNode{Name: "package", KeyID: packager, NodeData: *assembler.NewObjectMetadata(srcInfo)}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "in-toto link",
+		doc: &processor.Document{
+			Blob:              testdata.InTotoPackageLinkExample,
+			Type:              processor.DocumentInTotoLink,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{pkgStep, source, tarball},
+		wantEdges: []assembler.GuacEdge{
+			assembler.MaterialEdge{StepNode: pkgStep, ArtifactNode: source},
+			assembler.ProductEdge{StepNode: pkgStep, ArtifactNode: tarball},
+		},
+	}, {
+		name: "unsigned in-toto link",
+		doc: &processor.Document{
+			Blob:              []byte(`{"signed": {"_type": "link", "name": "test", "materials": {}, "products": {}}, "signatures": []}`),
+			Type:              processor.DocumentInTotoLink,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{assembler.StepNode{
+			Name:     "test",
+			Link:     "sha256:c14f101b3f4313d9c523d6136ae89e6fb07e2aa2413017173a853d43fce3a1d8",
+			NodeData: *assembler.NewObjectMetadata(srcInfo),
+		}},
+		wantEdges: []assembler.GuacEdge{},
+	}, {
+		name: "in-toto layout",
+		doc: &processor.Document{
+			Blob:              testdata.InTotoLayoutExample,
+			Type:              processor.DocumentInTotoLayout,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{layout, expectedWriteCode, expectedPackage},
+		wantEdges: []assembler.GuacEdge{
+			assembler.ExpectsStepEdge{LayoutNode: layout, StepNode: expectedWriteCode, Threshold: 1},
+			assembler.ExpectsStepEdge{LayoutNode: layout, StepNode: expectedPackage, Threshold: 1},
+		},
+	}, {
+		name: "layout given as link",
+		doc: &processor.Document{
+			Blob:              testdata.InTotoLayoutExample,
+			Type:              processor.DocumentInTotoLink,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.InTotoPackageLinkExample,
+			Type:              processor.DocumentInTotoLink,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewInTotoParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inTotoParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("inTotoParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("inTotoParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
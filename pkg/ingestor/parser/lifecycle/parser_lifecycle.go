@@ -0,0 +1,113 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The lifecycle parser parses the release cycles of a product, as published
+// by https://endoflife.date, wrapped with the purls of the packages
+// distributing the product (see `lifecycle.Document`).
+//
+// A lifecycle node is generated for each cycle of each purl, storing the
+// dates of its end of life and of active support. The package node of the
+// purl, without version, is linked to it via a "HasLifecycle" edge. Since
+// cycles are usually per major (or minor) version, the versions of the
+// package are only matched to their cycle when querying, with
+// `lifecycle.MatchCycle`.
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/lifecycle"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+type lifecycleParser struct {
+	lifecycles []assembler.HasLifecycleEdge
+}
+
+// NewLifecycleParser initializes the lifecycleParser
+func NewLifecycleParser() common.DocumentParser {
+	return &lifecycleParser{
+		lifecycles: []assembler.HasLifecycleEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *lifecycleParser) Parse(ctx context.Context, doc *processor.Document) error {
+	if doc.Type != processor.DocumentLifecycle {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentLifecycle, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of lifecycle document format: %v", doc.Format)
+	}
+
+	var lc lifecycle.Document
+	if err := json.Unmarshal(doc.Blob, &lc); err != nil {
+		return err
+	}
+
+	for _, purl := range lc.Purls {
+		purl, _ = lifecycle.BasePurl(common.NormalizePurl(purl))
+		for _, c := range lc.Cycles {
+			p.lifecycles = append(p.lifecycles, assembler.HasLifecycleEdge{
+				PackageNode: assembler.PackageNode{Purl: purl},
+				LifecycleNode: assembler.LifecycleNode{
+					Purl:        purl,
+					Cycle:       c.Cycle,
+					Product:     lc.Product,
+					ReleaseDate: c.ReleaseDate,
+					EOL:         c.EOL.Date,
+					EOLReached:  c.EOL.Reached,
+					Support:     c.Support.Date,
+					LTS:         c.LTS.Date != "" || c.LTS.Reached,
+					Latest:      c.Latest,
+					NodeData:    *assembler.NewObjectMetadata(doc.SourceInformation),
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *lifecycleParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	seen := map[string]bool{}
+	for _, e := range p.lifecycles {
+		if !seen[e.PackageNode.Purl] {
+			seen[e.PackageNode.Purl] = true
+			nodes = append(nodes, e.PackageNode)
+		}
+		nodes = append(nodes, e.LifecycleNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *lifecycleParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, e := range p.lifecycles {
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *lifecycleParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
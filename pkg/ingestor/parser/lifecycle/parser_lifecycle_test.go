@@ -0,0 +1,155 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_lifecycleParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	django := assembler.PackageNode{
+		Purl: "pkg:pypi/django",
+	}
+	cycles := []assembler.LifecycleNode{{
+		Purl:        "pkg:pypi/django",
+		Cycle:       "5.0",
+		Product:     "django",
+		ReleaseDate: "2023-12-04",
+		EOL:         "2025-04-30",
+		Support:     "2024-08-31",
+		Latest:      "5.0.1",
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}, {
+		Purl:        "pkg:pypi/django",
+		Cycle:       "4.2",
+		Product:     "django",
+		ReleaseDate: "2023-04-03",
+		EOL:         "2026-04-30",
+		Support:     "2023-12-31",
+		LTS:         true,
+		Latest:      "4.2.9",
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}, {
+		Purl:        "pkg:pypi/django",
+		Cycle:       "3.2",
+		Product:     "django",
+		ReleaseDate: "2021-04-06",
+		EOL:         "2024-04-30",
+		Support:     "2021-12-07",
+		LTS:         true,
+		Latest:      "3.2.23",
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}, {
+		Purl:        "pkg:pypi/django",
+		Cycle:       "1.0",
+		Product:     "django",
+		ReleaseDate: "2008-09-03",
+		EOLReached:  true,
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}}
+	wantNodes := []assembler.GuacNode{django}
+	wantEdges := []assembler.GuacEdge{}
+	for _, c := range cycles {
+		wantNodes = append(wantNodes, c)
+		wantEdges = append(wantEdges, assembler.HasLifecycleEdge{PackageNode: django, LifecycleNode: c})
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "lifecycle document",
+		doc: &processor.Document{
+			Blob:              testdata.LifecycleExample,
+			Type:              processor.DocumentLifecycle,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: wantNodes,
+		wantEdges: wantEdges,
+	}, {
+		name: "versioned purl",
+		doc: &processor.Document{
+			Blob:              []byte(`{"purls": ["pkg:PyPI/django@4.2.1"], "cycles": [{"cycle": "1.0", "releaseDate": "2008-09-03", "eol": true}]}`),
+			Type:              processor.DocumentLifecycle,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			django,
+			assembler.LifecycleNode{
+				Purl:        "pkg:pypi/django",
+				Cycle:       "1.0",
+				ReleaseDate: "2008-09-03",
+				EOLReached:  true,
+				NodeData:    *assembler.NewObjectMetadata(srcInfo),
+			},
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.HasLifecycleEdge{
+				PackageNode: django,
+				LifecycleNode: assembler.LifecycleNode{
+					Purl:        "pkg:pypi/django",
+					Cycle:       "1.0",
+					ReleaseDate: "2008-09-03",
+					EOLReached:  true,
+					NodeData:    *assembler.NewObjectMetadata(srcInfo),
+				},
+			},
+		},
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.LifecycleExample,
+			Type:              processor.DocumentLifecycle,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewLifecycleParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lifecycleParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("lifecycleParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("lifecycleParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The lockfile parser parses the lockfiles of package managers (see
+// `lockfile.TypeOf` for the supported files), so that the dependencies of
+// projects that do not generate SBOMs can be fed to GUAC.
+//
+// Each package of the lockfile becomes a package node, with the purl type of
+// its ecosystem. When the lockfile names the project (package-lock.json,
+// go.mod, and Cargo.lock with a single workspace member), the project is a
+// package node too, linked to its dependencies via "DependsOn" edges whose
+// relationship is "direct", or "indirect" for the requirements go.mod marks
+// as such. The dependencies between packages, when the lockfile gives them,
+// become "DependsOn" edges without relationship. All the edges have the name
+// of the lockfile as manifest.
+package lockfile
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/lockfile"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+// purlTypes maps the types of lockfiles to the purl type of their packages
+var purlTypes = map[processor.DocumentType]string{
+	processor.DocumentNpmLock:      "npm",
+	processor.DocumentGoMod:        "golang",
+	processor.DocumentGoSum:        "golang",
+	processor.DocumentRequirements: "pypi",
+	processor.DocumentCargoLock:    "cargo",
+}
+
+type lockfileParser struct {
+	packages []assembler.PackageNode
+	depends  []assembler.DependsOnEdge
+}
+
+// NewLockfileParser initializes the lockfileParser
+func NewLockfileParser() common.DocumentParser {
+	return &lockfileParser{
+		packages: []assembler.PackageNode{},
+		depends:  []assembler.DependsOnEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *lockfileParser) Parse(ctx context.Context, doc *processor.Document) error {
+	purlType, ok := purlTypes[doc.Type]
+	if !ok {
+		return fmt.Errorf("expected a lockfile document type, actual document type: %v", doc.Type)
+	}
+	lock, err := lockfile.Parse(doc.Type, doc.Blob)
+	if err != nil {
+		return err
+	}
+
+	pkg := func(name, version string) assembler.PackageNode {
+		purl := "pkg:" + purlType + "/" + name
+		if version != "" {
+			purl += "@" + version
+		}
+		return assembler.PackageNode{
+			Name:     name,
+			Version:  version,
+			Purl:     common.NormalizePurl(purl),
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+	}
+	manifest := ""
+	if doc.SourceInformation.Source != "" {
+		manifest = path.Base(doc.SourceInformation.Source)
+	}
+	depend := func(from assembler.PackageNode, dep lockfile.Dependency, relationship string) {
+		p.depends = append(p.depends, assembler.DependsOnEdge{
+			PackageNode:       from,
+			PackageDependency: pkg(dep.Name, dep.Version),
+			Manifest:          manifest,
+			Relationship:      relationship,
+		})
+	}
+
+	if lock.Root != nil {
+		root := pkg(lock.Root.Name, lock.Root.Version)
+		p.packages = append(p.packages, root)
+		for _, dep := range lock.Root.Dependencies {
+			relationship := "direct"
+			if dep.Indirect {
+				relationship = "indirect"
+			}
+			depend(root, dep, relationship)
+		}
+	}
+	for _, lp := range lock.Packages {
+		n := pkg(lp.Name, lp.Version)
+		p.packages = append(p.packages, n)
+		for _, dep := range lp.Dependencies {
+			depend(n, dep, "")
+		}
+	}
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *lockfileParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, pkg := range p.packages {
+		nodes = append(nodes, pkg)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *lockfileParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, d := range p.depends {
+		edges = append(edges, d)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *lockfileParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
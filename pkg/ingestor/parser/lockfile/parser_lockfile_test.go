@@ -0,0 +1,155 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lockfile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_lockfileParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "FileCollector",
+		Source:    "file:///src/app/lockfile",
+	}
+	pkg := func(name, version, purl string) assembler.PackageNode {
+		return assembler.PackageNode{
+			Name:     name,
+			Version:  version,
+			Purl:     purl,
+			NodeData: *assembler.NewObjectMetadata(srcInfo),
+		}
+	}
+	dependsOn := func(from, to assembler.PackageNode, relationship string) assembler.DependsOnEdge {
+		return assembler.DependsOnEdge{PackageNode: from, PackageDependency: to, Manifest: "lockfile", Relationship: relationship}
+	}
+
+	app := pkg("github.com/example/app", "", "pkg:golang/github.com/example/app")
+	cobra := pkg("github.com/spf13/cobra", "v1.6.1", "pkg:golang/github.com/spf13/cobra@v1.6.1")
+	text := pkg("golang.org/x/text", "v0.3.8", "pkg:golang/golang.org/x/text@v0.3.8")
+	pflag := pkg("github.com/spf13/pflag", "v1.0.5", "pkg:golang/github.com/spf13/pflag@v1.0.5")
+
+	certifi := pkg("certifi", "2023.7.22", "pkg:pypi/certifi@2023.7.22")
+	charset := pkg("charset-normalizer", "3.2.0", "pkg:pypi/charset-normalizer@3.2.0")
+	idna := pkg("idna", "3.4", "pkg:pypi/idna@3.4")
+	pyyaml := pkg("pyyaml", "6.0.1", "pkg:pypi/pyyaml@6.0.1")
+	requests := pkg("requests", "2.31.0", "pkg:pypi/requests@2.31.0")
+	urllib3 := pkg("urllib3", "2.0.4", "pkg:pypi/urllib3@2.0.4")
+
+	web := pkg("web", "1.0.0", "pkg:npm/web@1.0.0")
+	core := pkg("@actions/core", "1.10.0", "pkg:npm/%40actions/core@1.10.0")
+	httpClient := pkg("@actions/http-client", "2.1.0", "pkg:npm/%40actions/http-client@2.1.0")
+	tunnel := pkg("tunnel", "0.0.6", "pkg:npm/tunnel@0.0.6")
+	uuid8 := pkg("uuid", "8.3.2", "pkg:npm/uuid@8.3.2")
+	ms := pkg("ms", "2.1.3", "pkg:npm/ms@2.1.3")
+	uuid9 := pkg("uuid", "9.0.0", "pkg:npm/uuid@9.0.0")
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "go.mod",
+		doc: &processor.Document{
+			Blob:              testdata.GoModExample,
+			Type:              processor.DocumentGoMod,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{app, cobra, text, pflag},
+		wantEdges: []assembler.GuacEdge{
+			dependsOn(app, cobra, "direct"),
+			dependsOn(app, text, "indirect"),
+			dependsOn(app, pflag, "indirect"),
+		},
+	}, {
+		name: "requirements.txt",
+		doc: &processor.Document{
+			Blob:              testdata.RequirementsExample,
+			Type:              processor.DocumentRequirements,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{certifi, charset, idna, pyyaml, requests, urllib3},
+		wantEdges: []assembler.GuacEdge{
+			dependsOn(requests, certifi, ""),
+			dependsOn(requests, charset, ""),
+			dependsOn(requests, idna, ""),
+			dependsOn(requests, urllib3, ""),
+		},
+	}, {
+		name: "package-lock.json",
+		doc: &processor.Document{
+			Blob:              testdata.NpmLockExample,
+			Type:              processor.DocumentNpmLock,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{web, core, httpClient, tunnel, uuid8, ms, uuid9},
+		wantEdges: []assembler.GuacEdge{
+			dependsOn(web, core, "direct"),
+			dependsOn(web, ms, "direct"),
+			dependsOn(web, uuid9, "direct"),
+			dependsOn(core, httpClient, ""),
+			dependsOn(core, uuid8, ""),
+			dependsOn(httpClient, tunnel, ""),
+		},
+	}, {
+		name: "invalid lockfile",
+		doc: &processor.Document{
+			Blob:              []byte("go 1.20\n"),
+			Type:              processor.DocumentGoMod,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "not a lockfile",
+		doc: &processor.Document{
+			Blob:              testdata.GoModExample,
+			Type:              processor.DocumentOSV,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewLockfileParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lockfileParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("lockfileParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("lockfileParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
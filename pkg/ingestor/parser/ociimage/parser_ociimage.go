@@ -0,0 +1,268 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The OCI image parser parses the configurations and manifests of container
+// images, so that the graph captures how images are composed independently
+// of any SBOM.
+//
+// Each image becomes an artifact node, identified by the digest of the
+// document: the image ID for a configuration, the image digest for a
+// manifest. Its layers become artifact nodes too, linked by "HasLayer" edges
+// giving their position and, for configurations, the instruction that
+// created them. A manifest is linked to its configuration by a "HasConfig"
+// edge.
+//
+// The base image is linked by a "DerivedFrom" edge. It is given by the
+// `org.opencontainers.image.base.*` annotations of manifests or labels of
+// configurations, or else guessed from the history of configurations: the
+// base image ends with its CMD or ENTRYPOINT instruction, and is then only
+// known by its top layer, so the edge points to the layer node.
+package ociimage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/ociimage"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+// Ways the base image of an image is detected, given by the "detection"
+// property of the DerivedFrom edges
+const (
+	detectionAnnotation = "annotation"
+	detectionLabel      = "label"
+	detectionHistory    = "history"
+)
+
+type ociImageParser struct {
+	nodes []assembler.GuacNode
+	edges []assembler.GuacEdge
+	// digests of the artifacts already in nodes
+	seen map[string]bool
+}
+
+// NewOCIImageParser initializes the ociImageParser
+func NewOCIImageParser() common.DocumentParser {
+	return &ociImageParser{
+		nodes: []assembler.GuacNode{},
+		edges: []assembler.GuacEdge{},
+		seen:  map[string]bool{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *ociImageParser) Parse(ctx context.Context, doc *processor.Document) error {
+	if doc.Type != processor.DocumentImageConfig && doc.Type != processor.DocumentImageManifest {
+		return fmt.Errorf("expected document type: %v or %v, actual document type: %v", processor.DocumentImageConfig, processor.DocumentImageManifest, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of image document format: %v", doc.Format)
+	}
+
+	sum := sha256.Sum256(doc.Blob)
+	image := p.artifact(doc, doc.SourceInformation.Source, "sha256:"+hex.EncodeToString(sum[:]), "CONTAINER")
+
+	if doc.Type == processor.DocumentImageConfig {
+		config, err := ociimage.ParseConfig(doc.Blob)
+		if err != nil {
+			return err
+		}
+		p.parseConfig(doc, image, config)
+		return nil
+	}
+	manifest, err := ociimage.ParseManifest(doc.Blob)
+	if err != nil {
+		return err
+	}
+	p.parseManifest(doc, image, manifest)
+	return nil
+}
+
+func (p *ociImageParser) parseConfig(doc *processor.Document, image assembler.ArtifactNode, config *v1.ConfigFile) {
+	createdBy := layerHistory(config.History, len(config.RootFS.DiffIDs))
+	layers := make([]assembler.ArtifactNode, len(config.RootFS.DiffIDs))
+	for i, diffID := range config.RootFS.DiffIDs {
+		layers[i] = p.artifact(doc, "", diffID.String(), "LAYER")
+		edge := assembler.HasLayerEdge{ImageNode: image, LayerNode: layers[i], Index: i}
+		if createdBy != nil {
+			edge.CreatedBy = createdBy[i]
+		}
+		p.edges = append(p.edges, edge)
+	}
+
+	if p.addBase(doc, image, config.Config.Labels, detectionLabel) {
+		return
+	}
+	if n := baseLayers(config.History); n > 0 && n <= len(layers) && createdBy != nil {
+		p.edges = append(p.edges, assembler.DerivedFromEdge{
+			ImageNode:    image,
+			BaseArtifact: layers[n-1],
+			Detection:    detectionHistory,
+		})
+	}
+}
+
+func (p *ociImageParser) parseManifest(doc *processor.Document, image assembler.ArtifactNode, manifest *v1.Manifest) {
+	config := p.artifact(doc, "", manifest.Config.Digest.String(), "IMAGE_CONFIG")
+	p.edges = append(p.edges, assembler.HasConfigEdge{ImageNode: image, ConfigNode: config})
+	for i, l := range manifest.Layers {
+		layer := p.artifact(doc, "", l.Digest.String(), "LAYER")
+		p.edges = append(p.edges, assembler.HasLayerEdge{ImageNode: image, LayerNode: layer, Index: i})
+	}
+	p.addBase(doc, image, manifest.Annotations, detectionAnnotation)
+}
+
+// addBase adds the DerivedFrom edge to the base image named by the
+// annotations (or labels), and returns whether there is one. The base image
+// is an artifact node when its digest is given, a package node otherwise.
+func (p *ociImageParser) addBase(doc *processor.Document, image assembler.ArtifactNode, annotations map[string]string, detection string) bool {
+	name, digest := annotations[ociimage.BaseNameAnnotation], annotations[ociimage.BaseDigestAnnotation]
+	switch {
+	case digest != "":
+		base := p.artifact(doc, name, digest, "CONTAINER")
+		p.edges = append(p.edges, assembler.DerivedFromEdge{ImageNode: image, BaseArtifact: base, Detection: detection})
+	case name != "":
+		base := assembler.PackageNode{
+			Name:     name,
+			Purl:     imagePurl(name),
+			Tags:     []string{"CONTAINER"},
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		p.nodes = append(p.nodes, base)
+		p.edges = append(p.edges, assembler.DerivedFromEdge{ImageNode: image, BasePackage: base, Detection: detection})
+	default:
+		return false
+	}
+	return true
+}
+
+// artifact returns the artifact node with the digest, adding it to the
+// nodes unless it already is
+func (p *ociImageParser) artifact(doc *processor.Document, name string, digest string, tag string) assembler.ArtifactNode {
+	a := assembler.ArtifactNode{
+		Name:     name,
+		Digest:   digest,
+		Tags:     []string{tag},
+		NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+	}
+	if !p.seen[strings.ToLower(digest)] {
+		p.seen[strings.ToLower(digest)] = true
+		p.nodes = append(p.nodes, a)
+	}
+	return a
+}
+
+// layerHistory returns the instruction that created each of the n layers of
+// an image, or nil if the history does not match the layers
+func layerHistory(history []v1.History, n int) []string {
+	createdBy := []string{}
+	for _, h := range history {
+		if !h.EmptyLayer {
+			createdBy = append(createdBy, h.CreatedBy)
+		}
+	}
+	if len(createdBy) != n {
+		return nil
+	}
+	return createdBy
+}
+
+// baseLayers returns the number of layers of the base image, found in the
+// history as those created up to the last CMD or ENTRYPOINT instruction
+// followed by other instructions of a later build, or 0 if there is none.
+// The instructions of a build share their creation time, unless it was
+// reset for a reproducible build.
+func baseLayers(history []v1.History) int {
+	end := -1
+	for i := 0; i < len(history)-1; i++ {
+		if !isFinalInstruction(history[i].CreatedBy) {
+			continue
+		}
+		created, next := history[i].Created.Time, history[i+1].Created.Time
+		if !created.IsZero() && created.Equal(next) {
+			continue
+		}
+		end = i
+	}
+	n := 0
+	for _, h := range history[:end+1] {
+		if !h.EmptyLayer {
+			n++
+		}
+	}
+	return n
+}
+
+// isFinalInstruction returns whether the instruction usually ends the
+// Dockerfile of a base image
+func isFinalInstruction(createdBy string) bool {
+	instruction := strings.TrimSpace(strings.TrimPrefix(createdBy, "/bin/sh -c #(nop)"))
+	for _, prefix := range []string{"CMD", "ENTRYPOINT"} {
+		if !strings.HasPrefix(instruction, prefix) {
+			continue
+		}
+		if rest := instruction[len(prefix):]; rest == "" || rest[0] == ' ' || rest[0] == '[' {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePurl returns the purl of the image given by name, with its tag as
+// qualifier (e.g. `docker.io/library/alpine:3.17`)
+func imagePurl(name string) string {
+	if purl := common.OCIPurl(name); purl != "" {
+		return purl
+	}
+	repository, tag := name, ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repository, tag = name[:i], name[i+1:]
+	}
+	short := repository[strings.LastIndex(repository, "/")+1:]
+	qualifiers := []string{}
+	if short != repository {
+		qualifiers = append(qualifiers, "repository_url="+repository)
+	}
+	if tag != "" {
+		qualifiers = append(qualifiers, "tag="+tag)
+	}
+	purl := "pkg:oci/" + short
+	if len(qualifiers) > 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+	return common.NormalizePurl(purl)
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *ociImageParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	return p.nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *ociImageParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	return p.edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *ociImageParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,241 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ociimage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_ociImageParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	artifact := func(name, digest, tag string) assembler.ArtifactNode {
+		return assembler.ArtifactNode{
+			Name:     name,
+			Digest:   digest,
+			Tags:     []string{tag},
+			NodeData: *assembler.NewObjectMetadata(srcInfo),
+		}
+	}
+	config := artifact("", "sha256:d13e0ce4c60e0d05869f5253e8a44363ce5aca77e414f9615cf04c8a13e361c5", "IMAGE_CONFIG")
+	configImage := artifact("TestSource", config.Digest, "CONTAINER")
+	manifestImage := artifact("TestSource", "sha256:4e8fd9fcf912fa10b82e2ab771b0348993d6d3bdf5cda53b1ef66d87a15f6d90", "CONTAINER")
+	alpine := artifact("docker.io/library/alpine:3.17", "sha256:f271e74b17ced29b915d351685fd4644785c6d1559dd1f2d4189a5e851ef753a", "CONTAINER")
+	diffIDs := []assembler.ArtifactNode{
+		artifact("", "sha256:fee41e23023b7df172580c51185aeca914a95be6637c53c942c262ead88a64da", "LAYER"),
+		artifact("", "sha256:499e63565d42d6554772aa0b0f961f0f21e7a311ba745ab199b7506c25dacbf8", "LAYER"),
+		artifact("", "sha256:305a9086e5c65a8237ae4f566e1a83132d63cb493fbeaf5263d49911e6944c32", "LAYER"),
+	}
+	layers := []assembler.ArtifactNode{
+		artifact("", "sha256:38681bc9bcc4bdf2f582c18e7b86705651591ba8dfd9e93ef316896ce7859e0b", "LAYER"),
+		artifact("", "sha256:481a7f2e024dbf4eef5e606d9ef92ee8e2191768025db89b0e654f6a8239eda9", "LAYER"),
+		artifact("", "sha256:1d2681e41135b13a3ed6d042bd47327489f3b64613dc2ec2a722a05e0b68953c", "LAYER"),
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "image configuration, base image from history",
+		doc: &processor.Document{
+			Blob:              testdata.OCIImageConfigExample,
+			Type:              processor.DocumentImageConfig,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{configImage, diffIDs[0], diffIDs[1], diffIDs[2]},
+		wantEdges: []assembler.GuacEdge{
+			assembler.HasLayerEdge{ImageNode: configImage, LayerNode: diffIDs[0], Index: 0,
+				CreatedBy: "/bin/sh -c #(nop) ADD file:e4d600fc4c9c293efe360be7b30ee96579925d1b4634c94332e2ec73f7d8eca1 in / "},
+			assembler.HasLayerEdge{ImageNode: configImage, LayerNode: diffIDs[1], Index: 1,
+				CreatedBy: "RUN /bin/sh -c apk add --no-cache ca-certificates # buildkit"},
+			assembler.HasLayerEdge{ImageNode: configImage, LayerNode: diffIDs[2], Index: 2,
+				CreatedBy: "COPY server /app/server # buildkit"},
+			assembler.DerivedFromEdge{ImageNode: configImage, BaseArtifact: diffIDs[0], Detection: "history"},
+		},
+	}, {
+		name: "image manifest, base image from annotations",
+		doc: &processor.Document{
+			Blob:              testdata.OCIImageManifestExample,
+			Type:              processor.DocumentImageManifest,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{manifestImage, config, layers[0], layers[1], layers[2], alpine},
+		wantEdges: []assembler.GuacEdge{
+			assembler.HasConfigEdge{ImageNode: manifestImage, ConfigNode: config},
+			assembler.HasLayerEdge{ImageNode: manifestImage, LayerNode: layers[0], Index: 0},
+			assembler.HasLayerEdge{ImageNode: manifestImage, LayerNode: layers[1], Index: 1},
+			assembler.HasLayerEdge{ImageNode: manifestImage, LayerNode: layers[2], Index: 2},
+			assembler.DerivedFromEdge{ImageNode: manifestImage, BaseArtifact: alpine, Detection: "annotation"},
+		},
+	}, {
+		name: "image configuration, base image name from labels",
+		doc: &processor.Document{
+			Blob: []byte(`{"os": "linux", "rootfs": {"type": "layers", "diff_ids": []},
+				"config": {"Labels": {"org.opencontainers.image.base.name": "ghcr.io/guacsec/base:v1"}}}`),
+			Type:              processor.DocumentImageConfig,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			artifact("TestSource", "sha256:00483665a55ad38769b942cb6cc48b5cf2d4c61e7e8e030b34ae4dc2f10a92d1", "CONTAINER"),
+			assembler.PackageNode{
+				Name:     "ghcr.io/guacsec/base:v1",
+				Purl:     "pkg:oci/base?repository_url=ghcr.io/guacsec/base&tag=v1",
+				Tags:     []string{"CONTAINER"},
+				NodeData: *assembler.NewObjectMetadata(srcInfo),
+			},
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DerivedFromEdge{
+				ImageNode: artifact("TestSource", "sha256:00483665a55ad38769b942cb6cc48b5cf2d4c61e7e8e030b34ae4dc2f10a92d1", "CONTAINER"),
+				BasePackage: assembler.PackageNode{
+					Name:     "ghcr.io/guacsec/base:v1",
+					Purl:     "pkg:oci/base?repository_url=ghcr.io/guacsec/base&tag=v1",
+					Tags:     []string{"CONTAINER"},
+					NodeData: *assembler.NewObjectMetadata(srcInfo),
+				},
+				Detection: "label",
+			},
+		},
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.OCIImageConfigExample,
+			Type:              processor.DocumentImageConfig,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOCIImageParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ociImageParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("ociImageParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("ociImageParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
+
+func Test_baseLayers(t *testing.T) {
+	base := v1.Time{Time: time.Date(2023, 1, 9, 0, 0, 0, 0, time.UTC)}
+	build := v1.Time{Time: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name    string
+		history []v1.History
+		want    int
+	}{{
+		name: "no base image",
+		history: []v1.History{
+			{Created: build, CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+			{Created: build, CreatedBy: "/bin/sh -c #(nop)  CMD [\"/bin/sh\"]", EmptyLayer: true},
+		},
+		want: 0,
+	}, {
+		name: "base image ending with ENTRYPOINT",
+		history: []v1.History{
+			{Created: base, CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+			{Created: base, CreatedBy: "RUN /bin/sh -c apt-get update # buildkit"},
+			{Created: base, CreatedBy: "ENTRYPOINT [\"/docker-entrypoint.sh\"]", EmptyLayer: true},
+			{Created: build, CreatedBy: "COPY app /app # buildkit"},
+		},
+		want: 2,
+	}, {
+		name: "CMD in the middle of a build",
+		history: []v1.History{
+			{Created: base, CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+			{Created: base, CreatedBy: "/bin/sh -c #(nop)  CMD [\"/bin/sh\"]", EmptyLayer: true},
+			{Created: build, CreatedBy: "RUN /bin/sh -c apk add curl # buildkit"},
+			{Created: build, CreatedBy: "CMD [\"/app\"]", EmptyLayer: true},
+			{Created: build, CreatedBy: "LABEL version=1", EmptyLayer: true},
+		},
+		want: 1,
+	}, {
+		name: "base of a base image",
+		history: []v1.History{
+			{CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+			{CreatedBy: "/bin/sh -c #(nop)  CMD [\"/bin/sh\"]", EmptyLayer: true},
+			{CreatedBy: "RUN /bin/sh -c apk add python3"},
+			{CreatedBy: "CMD [\"python3\"]", EmptyLayer: true},
+			{CreatedBy: "COPY app.py /app.py"},
+		},
+		want: 2,
+	}, {
+		name: "CMDLINE is not CMD",
+		history: []v1.History{
+			{Created: base, CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+			{Created: base, CreatedBy: "CMDLINE", EmptyLayer: true},
+			{Created: build, CreatedBy: "COPY app /app"},
+		},
+		want: 0,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := baseLayers(tt.history); got != tt.want {
+				t.Errorf("baseLayers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_imagePurl(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "alpine", want: "pkg:oci/alpine"},
+		{name: "alpine:3.17", want: "pkg:oci/alpine?tag=3.17"},
+		{name: "localhost:5000/app", want: "pkg:oci/app?repository_url=localhost:5000/app"},
+		{name: "docker.io/library/alpine:3.17", want: "pkg:oci/alpine?repository_url=docker.io/library/alpine&tag=3.17"},
+		{
+			name: "docker.io/library/alpine@sha256:f271e74b17ced29b915d351685fd4644785c6d1559dd1f2d4189a5e851ef753a",
+			want: "pkg:oci/alpine@sha256:f271e74b17ced29b915d351685fd4644785c6d1559dd1f2d4189a5e851ef753a?repository_url=docker.io/library/alpine",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imagePurl(tt.name); got != tt.want {
+				t.Errorf("imagePurl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
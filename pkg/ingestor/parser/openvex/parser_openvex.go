@@ -0,0 +1,144 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The OpenVEX parser parses VEX documents in the OpenVEX format
+// (https://github.com/openvex/spec), both in the current format and in the
+// v0.0.1 format, where vulnerabilities and products are plain strings.
+//
+// For each statement, a vulnerability node is generated for the
+// vulnerability name, and one for each of its aliases, linked via "AliasOf"
+// edges. Each product identified by a purl becomes a package node, linked
+// from the vulnerability node via a "VexStatement" edge storing the status,
+// justification, impact and action statements. Products that are not
+// identified by a purl are skipped, and so are subcomponents: the statement
+// is about the product as a whole.
+package openvex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/openvex"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type openVEXParser struct {
+	vulns      []assembler.VulnerabilityNode
+	aliases    []assembler.AliasOfEdge
+	statements []assembler.VexStatementEdge
+}
+
+// NewOpenVEXParser initializes the openVEXParser
+func NewOpenVEXParser() common.DocumentParser {
+	return &openVEXParser{
+		vulns:      []assembler.VulnerabilityNode{},
+		aliases:    []assembler.AliasOfEdge{},
+		statements: []assembler.VexStatementEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *openVEXParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentOpenVEX {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOpenVEX, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of OpenVEX document format: %v", doc.Format)
+	}
+
+	var vex openvex.Document
+	if err := json.Unmarshal(doc.Blob, &vex); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, s := range vex.Statements {
+		if !s.Status.Valid() {
+			return fmt.Errorf("invalid status %q for vulnerability %s", s.Status, s.Vulnerability.Name)
+		}
+		vuln := assembler.VulnerabilityNode{
+			ID:       s.Vulnerability.Name,
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		if !seen[vuln.ID] {
+			seen[vuln.ID] = true
+			p.vulns = append(p.vulns, vuln)
+			for _, a := range s.Vulnerability.Aliases {
+				p.aliases = append(p.aliases, assembler.AliasOfEdge{
+					VulnerabilityNode: vuln,
+					AliasNode: assembler.VulnerabilityNode{
+						ID:       a,
+						NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+					},
+				})
+			}
+		}
+
+		for _, product := range s.Products {
+			purl := common.NormalizePurl(product.Purl())
+			if purl == "" {
+				logger.Debugf("skipping product %s of VEX statement for %s, no purl", product.ID, vuln.ID)
+				continue
+			}
+			p.statements = append(p.statements, assembler.VexStatementEdge{
+				VulnerabilityNode: vuln,
+				PackageNode:       assembler.PackageNode{Purl: purl},
+				Status:            string(s.Status),
+				Justification:     s.Justification,
+				ImpactStatement:   s.ImpactStatement,
+				ActionStatement:   s.ActionStatement,
+			})
+		}
+	}
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *openVEXParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, v := range p.vulns {
+		nodes = append(nodes, v)
+	}
+	for _, a := range p.aliases {
+		nodes = append(nodes, a.AliasNode)
+	}
+	for _, s := range p.statements {
+		nodes = append(nodes, s.PackageNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *openVEXParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, a := range p.aliases {
+		edges = append(edges, a)
+	}
+	for _, s := range p.statements {
+		edges = append(edges, s)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *openVEXParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,133 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openvex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_openVEXParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	semverVuln := assembler.VulnerabilityNode{
+		ID:       "CVE-2022-25883",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	alias := assembler.VulnerabilityNode{
+		ID:       "GHSA-c2qf-rxjj-qqgw",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	otherVuln := assembler.VulnerabilityNode{
+		ID:       "CVE-2023-1234",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	app := assembler.PackageNode{Purl: "pkg:npm/app@1.0.0"}
+	lib := assembler.PackageNode{Purl: "pkg:npm/lib@2.0.0"}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "OpenVEX document",
+		doc: &processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Type:              processor.DocumentOpenVEX,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{semverVuln, otherVuln, alias, app, app},
+		wantEdges: []assembler.GuacEdge{
+			assembler.AliasOfEdge{VulnerabilityNode: semverVuln, AliasNode: alias},
+			assembler.VexStatementEdge{
+				VulnerabilityNode: semverVuln,
+				PackageNode:       app,
+				Status:            "not_affected",
+				Justification:     "vulnerable_code_not_in_execute_path",
+				ImpactStatement:   "The app never parses untrusted version ranges.",
+			},
+			assembler.VexStatementEdge{
+				VulnerabilityNode: otherVuln,
+				PackageNode:       app,
+				Status:            "affected",
+				ActionStatement:   "Upgrade to 1.0.1",
+			},
+		},
+	}, {
+		name: "OpenVEX v0.0.1 document",
+		doc: &processor.Document{
+			Blob:              testdata.OpenVEXLegacyExample,
+			Type:              processor.DocumentOpenVEX,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{otherVuln, lib},
+		wantEdges: []assembler.GuacEdge{
+			assembler.VexStatementEdge{
+				VulnerabilityNode: otherVuln,
+				PackageNode:       lib,
+				Status:            "fixed",
+			},
+		},
+	}, {
+		name: "invalid status",
+		doc: &processor.Document{
+			Blob:              testdata.OpenVEXInvalid,
+			Type:              processor.DocumentOpenVEX,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.OpenVEXExample,
+			Type:              processor.DocumentOpenVEX,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOpenVEXParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openVEXParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("openVEXParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("openVEXParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
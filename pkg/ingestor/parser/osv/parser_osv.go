@@ -0,0 +1,188 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The OSV parser parses vulnerability entries in the OSV format
+// (https://ossf.github.io/osv-schema/), as found in the OSV database and its
+// mirrors.
+//
+// A vulnerability node is generated for the OSV id of the entry and for each
+// of its aliases (e.g., the CVE of a GHSA advisory). The alias nodes are
+// linked to the entry node via "AliasOf" edges.
+//
+// For each affected package, a package node is generated with the purl of
+// the package (derived from the ecosystem and name when the entry has no
+// purl). The entry node is linked to the package node via an "Affects" edge
+// storing the affected version ranges and versions, so that queries can
+// decide whether a specific version of the package is impacted.
+package osv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/osv"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+// ecosystemPurlTypes maps the OSV ecosystems to their purl type (and
+// namespace, for ecosystems where it is fixed)
+var ecosystemPurlTypes = map[string]string{
+	"Alpine":    "apk/alpine",
+	"crates.io": "cargo",
+	"Debian":    "deb/debian",
+	"Go":        "golang",
+	"Hex":       "hex",
+	"Maven":     "maven",
+	"npm":       "npm",
+	"NuGet":     "nuget",
+	"Packagist": "composer",
+	"Pub":       "pub",
+	"PyPI":      "pypi",
+	"RubyGems":  "gem",
+}
+
+type osvParser struct {
+	vuln    assembler.VulnerabilityNode
+	aliases []assembler.VulnerabilityNode
+	affects []assembler.AffectsEdge
+}
+
+// NewOSVParser initializes the osvParser
+func NewOSVParser() common.DocumentParser {
+	return &osvParser{
+		aliases: []assembler.VulnerabilityNode{},
+		affects: []assembler.AffectsEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *osvParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentOSV {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentOSV, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of OSV document format: %v", doc.Format)
+	}
+
+	var entry osv.Entry
+	if err := json.Unmarshal(doc.Blob, &entry); err != nil {
+		return err
+	}
+
+	p.vuln = assembler.VulnerabilityNode{
+		ID:       entry.ID,
+		NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+	}
+	for _, alias := range entry.Aliases {
+		p.aliases = append(p.aliases, assembler.VulnerabilityNode{
+			ID:       alias,
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		})
+	}
+
+	if entry.Withdrawn != "" {
+		logger.Debugf("OSV entry %s was withdrawn, not linking affected packages", entry.ID)
+		return nil
+	}
+	for _, affected := range entry.Affected {
+		purl := packagePurl(affected.Package)
+		if purl == "" {
+			logger.Debugf("skipping package %s of OSV entry %s, unknown ecosystem %q",
+				affected.Package.Name, entry.ID, affected.Package.Ecosystem)
+			continue
+		}
+		ranges := []string{}
+		for _, r := range affected.Ranges {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			ranges = append(ranges, string(b))
+		}
+		p.affects = append(p.affects, assembler.AffectsEdge{
+			VulnerabilityNode: p.vuln,
+			PackageNode: assembler.PackageNode{
+				Name: affected.Package.Name,
+				Purl: purl,
+			},
+			Ranges:   ranges,
+			Versions: affected.Versions,
+		})
+	}
+	return nil
+}
+
+// packagePurl returns the purl of the package, without version, or the empty
+// string if it cannot be derived
+func packagePurl(pkg osv.Package) string {
+	if pkg.Purl != "" {
+		return common.NormalizePurl(pkg.Purl)
+	}
+	if pkg.Name == "" {
+		return ""
+	}
+	// some ecosystems include a release, e.g. `Debian:11`
+	ecosystem, _, _ := strings.Cut(pkg.Ecosystem, ":")
+	purlType, ok := ecosystemPurlTypes[ecosystem]
+	if !ok {
+		return ""
+	}
+	name := pkg.Name
+	switch purlType {
+	case "maven":
+		name = strings.Replace(name, ":", "/", 1)
+	case "npm":
+		name = strings.Replace(name, "@", "%40", 1)
+	case "pypi":
+		name = strings.ToLower(name)
+	}
+	return common.NormalizePurl("pkg:" + purlType + "/" + name)
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *osvParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{p.vuln}
+	for _, a := range p.aliases {
+		nodes = append(nodes, a)
+	}
+	for _, e := range p.affects {
+		nodes = append(nodes, e.PackageNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *osvParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, a := range p.aliases {
+		edges = append(edges, assembler.AliasOfEdge{VulnerabilityNode: p.vuln, AliasNode: a})
+	}
+	for _, e := range p.affects {
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *osvParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,152 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/osv"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_osvParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	vuln := assembler.VulnerabilityNode{
+		ID:       "GHSA-c2qf-rxjj-qqgw",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	alias := assembler.VulnerabilityNode{
+		ID:       "CVE-2022-25883",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	npmSemver := assembler.PackageNode{
+		Name: "semver",
+		Purl: "pkg:npm/semver",
+	}
+	goSemver := assembler.PackageNode{
+		Name: "github.com/npm/node-semver",
+		Purl: "pkg:golang/github.com/npm/node-semver",
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "OSV entry",
+		doc: &processor.Document{
+			Blob:              testdata.OsvExample,
+			Type:              processor.DocumentOSV,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{vuln, alias, npmSemver, goSemver},
+		wantEdges: []assembler.GuacEdge{
+			assembler.AliasOfEdge{VulnerabilityNode: vuln, AliasNode: alias},
+			assembler.AffectsEdge{
+				VulnerabilityNode: vuln,
+				PackageNode:       npmSemver,
+				Ranges: []string{
+					`{"type":"SEMVER","events":[{"introduced":"7.0.0"},{"fixed":"7.5.2"}]}`,
+					`{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"5.7.2"}]}`,
+				},
+			},
+			assembler.AffectsEdge{
+				VulnerabilityNode: vuln,
+				PackageNode:       goSemver,
+				Ranges:            []string{},
+				Versions:          []string{"v6.3.0"},
+			},
+		},
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.OsvExample,
+			Type:              processor.DocumentOSV,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOSVParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("osvParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("osvParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("osvParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
+
+func Test_packagePurl(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  osv.Package
+		want string
+	}{{
+		name: "purl given",
+		pkg:  osv.Package{Ecosystem: "npm", Name: "semver", Purl: "pkg:npm/semver"},
+		want: "pkg:npm/semver",
+	}, {
+		name: "scoped npm package",
+		pkg:  osv.Package{Ecosystem: "npm", Name: "@babel/core"},
+		want: "pkg:npm/%40babel/core",
+	}, {
+		name: "maven package",
+		pkg:  osv.Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"},
+		want: "pkg:maven/org.apache.logging.log4j/log4j-core",
+	}, {
+		name: "pypi package",
+		pkg:  osv.Package{Ecosystem: "PyPI", Name: "Django"},
+		want: "pkg:pypi/django",
+	}, {
+		name: "ecosystem with release",
+		pkg:  osv.Package{Ecosystem: "Debian:11", Name: "openssl"},
+		want: "pkg:deb/debian/openssl",
+	}, {
+		name: "unknown ecosystem",
+		pkg:  osv.Package{Ecosystem: "OSS-Fuzz", Name: "openssl"},
+		want: "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := packagePurl(tt.pkg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packagePurl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,25 +18,64 @@ package parser
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/clearlydefined"
 	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/ingestor/parser/csaf"
 	"github.com/guacsec/guac/pkg/ingestor/parser/cyclonedx"
+	"github.com/guacsec/guac/pkg/ingestor/parser/depsnapshot"
 	"github.com/guacsec/guac/pkg/ingestor/parser/dsse"
+	"github.com/guacsec/guac/pkg/ingestor/parser/intoto"
+	"github.com/guacsec/guac/pkg/ingestor/parser/jsonlines"
+	"github.com/guacsec/guac/pkg/ingestor/parser/lifecycle"
+	"github.com/guacsec/guac/pkg/ingestor/parser/lockfile"
+	"github.com/guacsec/guac/pkg/ingestor/parser/ociimage"
+	"github.com/guacsec/guac/pkg/ingestor/parser/openvex"
+	"github.com/guacsec/guac/pkg/ingestor/parser/osv"
+	"github.com/guacsec/guac/pkg/ingestor/parser/sameas"
+	"github.com/guacsec/guac/pkg/ingestor/parser/sarif"
 	"github.com/guacsec/guac/pkg/ingestor/parser/scorecard"
 	"github.com/guacsec/guac/pkg/ingestor/parser/slsa"
 	"github.com/guacsec/guac/pkg/ingestor/parser/spdx"
+	"github.com/guacsec/guac/pkg/ingestor/parser/syft"
+	"github.com/guacsec/guac/pkg/ingestor/parser/trivy"
 	certify_vuln "github.com/guacsec/guac/pkg/ingestor/parser/vuln"
 )
 
 func init() {
 	_ = RegisterDocumentParser(dsse.NewDSSEParser, processor.DocumentDSSE)
+	_ = RegisterDocumentParser(dsse.NewDSSEParser, processor.DocumentSigstoreBundle)
 	_ = RegisterDocumentParser(slsa.NewSLSAParser, processor.DocumentITE6SLSA)
 	_ = RegisterDocumentParser(certify_vuln.NewVulnCertificationParser, processor.DocumentITE6Vul)
-	_ = RegisterDocumentParser(spdx.NewSpdxParser, processor.DocumentSPDX)
-	_ = RegisterDocumentParser(cyclonedx.NewCycloneDXParser, processor.DocumentCycloneDX)
+	_ = RegisterDocumentParser(sameas.NewSameAsParser, processor.DocumentITE6SameAs)
+	_ = RegisterVersionedDocumentParser(spdx.NewSpdxParser, processor.DocumentSPDX, processor.FormatJSON, "2.1", "2.2", "2.3")
+	_ = RegisterVersionedDocumentParser(spdx.NewSpdxParser, processor.DocumentSPDX, processor.FormatTagValue, "2.1", "2.2", "2.3")
+	_ = RegisterSpecVersionDetector(spdxSpecVersion, processor.DocumentSPDX)
+	_ = RegisterVersionedDocumentParser(cyclonedx.NewCycloneDXParser, processor.DocumentCycloneDX, processor.FormatJSON, "1.2", "1.3", "1.4", "1.5")
+	_ = RegisterSpecVersionDetector(cycloneDXSpecVersion, processor.DocumentCycloneDX)
 	_ = RegisterDocumentParser(scorecard.NewScorecardParser, processor.DocumentScorecard)
+	_ = RegisterDocumentParser(osv.NewOSVParser, processor.DocumentOSV)
+	_ = RegisterDocumentParser(openvex.NewOpenVEXParser, processor.DocumentOpenVEX)
+	_ = RegisterDocumentParser(csaf.NewCSAFParser, processor.DocumentCSAF)
+	_ = RegisterDocumentParser(intoto.NewInTotoParser, processor.DocumentInTotoLink)
+	_ = RegisterDocumentParser(intoto.NewInTotoParser, processor.DocumentInTotoLayout)
+	_ = RegisterDocumentParser(syft.NewSyftParser, processor.DocumentSyft)
+	_ = RegisterDocumentParser(trivy.NewTrivyParser, processor.DocumentTrivy)
+	_ = RegisterDocumentParser(lifecycle.NewLifecycleParser, processor.DocumentLifecycle)
+	_ = RegisterDocumentParser(clearlydefined.NewClearlyDefinedParser, processor.DocumentClearlyDefined)
+	_ = RegisterDocumentParser(sarif.NewSARIFParser, processor.DocumentSARIF)
+	_ = RegisterDocumentParser(depsnapshot.NewDepSnapshotParser, processor.DocumentDepSnapshot)
+	_ = RegisterDocumentParser(ociimage.NewOCIImageParser, processor.DocumentImageConfig)
+	_ = RegisterDocumentParser(ociimage.NewOCIImageParser, processor.DocumentImageManifest)
+	_ = RegisterDocumentParser(lockfile.NewLockfileParser, processor.DocumentNpmLock)
+	_ = RegisterDocumentParser(lockfile.NewLockfileParser, processor.DocumentGoMod)
+	_ = RegisterDocumentParser(lockfile.NewLockfileParser, processor.DocumentGoSum)
+	_ = RegisterDocumentParser(lockfile.NewLockfileParser, processor.DocumentRequirements)
+	_ = RegisterDocumentParser(lockfile.NewLockfileParser, processor.DocumentCargoLock)
+	_ = RegisterDocumentParser(jsonlines.NewJsonLinesParser, processor.DocumentJsonLines)
 }
 
 var (
@@ -46,6 +85,49 @@ var (
 type docTreeBuilder struct {
 	identities    []assembler.IdentityNode
 	graphBuilders []*common.GraphBuilder
+	// failures holds the errors of the sub-documents that could not be
+	// parsed
+	failures []*DocumentError
+}
+
+// DocumentError is the error of a document of a tree that could not be
+// parsed
+type DocumentError struct {
+	Type   processor.DocumentType
+	Source string
+	Err    error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("%s document from %s: %v", e.Type, e.Source, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned by ParseDocumentTree when some sub-documents of
+// the tree could not be parsed. The graphs of the other documents are still
+// returned.
+type PartialError struct {
+	// Failures are the errors of the sub-documents that were skipped,
+	// together with their own sub-documents
+	Failures []*DocumentError
+	// Parsed is the number of documents that were parsed
+	Parsed int
+}
+
+// Is makes errors.Is(err, processor.ErrPartial) hold for partial errors
+func (e *PartialError) Is(target error) bool {
+	return target == processor.ErrPartial
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("unable to parse %d sub-documents (%d documents parsed): %s", len(e.Failures), e.Parsed, strings.Join(msgs, "; "))
 }
 
 func newDocTreeBuilder() *docTreeBuilder {
@@ -55,8 +137,11 @@ func newDocTreeBuilder() *docTreeBuilder {
 	}
 }
 
+// RegisterDocumentParser registers the parser of all the documents of type
+// d, whatever their format and spec version. See
+// RegisterVersionedDocumentParser for parsers supporting specific ones.
 func RegisterDocumentParser(p func() common.DocumentParser, d processor.DocumentType) error {
-	if _, ok := documentParser[d]; ok {
+	if _, ok := documentParser[d]; ok || hasVersionedParsers(d) {
 		return fmt.Errorf("the document parser is being overwritten: %s", d)
 	}
 	documentParser[d] = p
@@ -64,53 +149,99 @@ func RegisterDocumentParser(p func() common.DocumentParser, d processor.Document
 }
 
 // ParseDocumentTree takes the DocumentTree and create graph inputs (nodes and edges) per document node
+//
+// A sub-document that cannot be parsed is skipped, together with its own
+// sub-documents, and the graphs of the other documents are returned with a
+// *PartialError listing the failures. If the root document cannot be parsed,
+// or ctx is done, no graph is returned.
 func ParseDocumentTree(ctx context.Context, docTree processor.DocumentTree) ([]assembler.AssemblerInput, error) {
 	assemblerinputs := []assembler.AssemblerInput{}
 	docTreeBuilder := newDocTreeBuilder()
-	err := docTreeBuilder.parse(ctx, docTree)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	root, err := parseHelper(ctx, docTree.Document)
 	if err != nil {
 		return nil, err
 	}
+	docTreeBuilder.add(root)
+	for _, c := range docTree.Children {
+		if err := docTreeBuilder.parse(ctx, c); err != nil {
+			return nil, err
+		}
+	}
 	for _, builder := range docTreeBuilder.graphBuilders {
 		assemblerinput := builder.CreateAssemblerInput(ctx, docTreeBuilder.identities)
 		assemblerinputs = append(assemblerinputs, assemblerinput)
 	}
 
+	if len(docTreeBuilder.failures) > 0 {
+		return assemblerinputs, &PartialError{
+			Failures: docTreeBuilder.failures,
+			Parsed:   len(docTreeBuilder.graphBuilders),
+		}
+	}
 	return assemblerinputs, nil
 }
 
-func (t *docTreeBuilder) parse(ctx context.Context, root processor.DocumentTree) error {
-	builder, err := parseHelper(ctx, root.Document)
-	if err != nil {
-		return err
-	}
-
+func (t *docTreeBuilder) add(builder *common.GraphBuilder) {
 	t.graphBuilders = append(t.graphBuilders, builder)
 	t.identities = append(t.identities, builder.GetIdentities()...)
+}
 
-	if len(root.Children) == 0 {
+// parse parses the sub-document root and its own sub-documents. Documents
+// that fail to parse are recorded in failures; only the error of ctx is
+// returned.
+func (t *docTreeBuilder) parse(ctx context.Context, root processor.DocumentTree) error {
+	// stop between documents once the caller gave up, e.g. on timeout
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	builder, err := parseHelper(ctx, root.Document)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		t.failures = append(t.failures, &DocumentError{
+			Type:   root.Document.Type,
+			Source: root.Document.SourceInformation.Source,
+			Err:    err,
+		})
 		return nil
 	}
+	t.add(builder)
 
 	for _, c := range root.Children {
-		err := t.parse(ctx, c)
-		if err != nil {
+		if err := t.parse(ctx, c); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// parseHelper parses the document with the parser registered for its type,
+// or for its type, format and spec version. Documents without parser fail
+// with an error of kind processor.ErrUnsupportedFormat, and those the parser
+// rejects with one of kind processor.ErrMalformedDocument.
 func parseHelper(ctx context.Context, doc *processor.Document) (*common.GraphBuilder, error) {
 	pFunc, ok := documentParser[doc.Type]
 	if !ok {
-		return nil, fmt.Errorf("no document parser registered for type: %s", doc.Type)
+		if !hasVersionedParsers(doc.Type) {
+			return nil, processor.UnsupportedFormatError(fmt.Errorf("no document parser registered for type: %s", doc.Type))
+		}
+		var err error
+		if pFunc, err = versionedParser(doc); err != nil {
+			return nil, err
+		}
 	}
 
 	p := pFunc()
 	err := p.Parse(ctx, doc)
 	if err != nil {
-		return nil, err
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, err
+		}
+		return nil, processor.MalformedDocumentError(err)
 	}
 
 	graphBuilder := common.NewGenericGraphBuilder(p, p.GetIdentities(ctx))
@@ -17,6 +17,7 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/guacsec/guac/internal/testing/mockverifier"
@@ -61,7 +62,7 @@ var (
 
 	spdxGraphInput = []assembler.AssemblerInput{{
 		Nodes: testdata.SpdxNodes,
-		Edges: testdata.SpdxEdges,
+		Edges: append(append([]assembler.GuacEdge{}, testdata.SpdxEdges...), testdata.SpdxCPEEdges...),
 	}}
 )
 
@@ -116,3 +117,168 @@ func compare(t *testing.T, gotEdges, wantEdges []assembler.GuacEdge, gotNodes, w
 		t.Errorf("ParseDocumentTree() = %v, want %v", gotNodes, wantNodes)
 	}
 }
+
+func TestParseDocumentTree_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(logging.WithLogger(context.Background()))
+	cancel()
+	if _, err := ParseDocumentTree(ctx, processor.DocumentTree(&dsseDocTree)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseDocumentTree() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParseDocumentTree_PartialFailure(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	node := func(blob []byte, source string, children ...*processor.DocumentNode) *processor.DocumentNode {
+		return &processor.DocumentNode{
+			Document: &processor.Document{
+				Blob:              blob,
+				Type:              processor.DocumentOpenVEX,
+				Format:            processor.FormatJSON,
+				SourceInformation: processor.SourceInformation{Source: source},
+			},
+			Children: children,
+		}
+	}
+
+	tree := node(testdata.OpenVEXExample, "root",
+		node(testdata.OpenVEXInvalid, "bad", node(testdata.OpenVEXExample, "skipped")),
+		node(testdata.OpenVEXLegacyExample, "good"))
+	got, err := ParseDocumentTree(ctx, processor.DocumentTree(tree))
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("ParseDocumentTree() error = %v, want a PartialError", err)
+	}
+	if !errors.Is(err, processor.ErrPartial) || !errors.Is(partial.Failures[0], processor.ErrMalformedDocument) {
+		t.Errorf("ParseDocumentTree() error = %v, want a partial error with a malformed document", err)
+	}
+	if len(partial.Failures) != 1 || partial.Failures[0].Source != "bad" || partial.Parsed != 2 {
+		t.Errorf("ParseDocumentTree() failures = %v, want only the bad document", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("ParseDocumentTree() returned %d graphs, want 2", len(got))
+	}
+
+	got, err = ParseDocumentTree(ctx, processor.DocumentTree(node(testdata.OpenVEXInvalid, "bad root", node(testdata.OpenVEXExample, "child"))))
+	if err == nil || errors.As(err, &partial) || got != nil {
+		t.Errorf("ParseDocumentTree() = %v, %v, want no graph and an error for a bad root", got, err)
+	}
+	if !errors.Is(err, processor.ErrMalformedDocument) {
+		t.Errorf("ParseDocumentTree() error = %v, want a malformed document", err)
+	}
+}
+
+func TestParseDocumentTree_MergeArtifacts(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	spdxDoc := processor.Document{
+		Blob:   testdata.SpdxExampleCurl,
+		Format: processor.FormatJSON,
+		Type:   processor.DocumentSPDX,
+		SourceInformation: processor.SourceInformation{
+			Collector: "TestCollector",
+			Source:    "TestSource",
+		},
+	}
+	// the SBOM describes the tarball that is the subject of the provenance
+	digest := "sha256:ad91970864102a59765e20ce16216efc9d6ad381471f7accceceab7d905703ef"
+
+	backend := assembler.NewMemoryBackend()
+	for _, doc := range []*processor.Document{&spdxDoc, &testdata.Ite6SLSAV1Doc} {
+		graphs, err := ParseDocumentTree(ctx, &processor.DocumentNode{Document: doc, Children: []*processor.DocumentNode{}})
+		if err != nil {
+			t.Fatalf("ParseDocumentTree() error = %v", err)
+		}
+		for _, g := range graphs {
+			if err := backend.StoreNodes(g.Nodes); err != nil {
+				t.Fatalf("StoreNodes() error = %v", err)
+			}
+			if err := backend.StoreEdges(g.Edges); err != nil {
+				t.Fatalf("StoreEdges() error = %v", err)
+			}
+		}
+	}
+
+	var artifacts []assembler.GuacNode
+	for _, n := range backend.Graph().Nodes {
+		if n.Type() == assembler.NodeTypeArtifact && n.Properties()["digest"] == digest {
+			artifacts = append(artifacts, n)
+		}
+	}
+	if len(artifacts) != 1 {
+		t.Fatalf("got %d artifact nodes with digest %s, want 1: %v", len(artifacts), digest, artifacts)
+	}
+	// the artifact is both contained in the package of the SBOM and built by
+	// the builder of the provenance
+	if got := backend.ReverseNeighbors(artifacts[0], assembler.EdgeTypeContains); len(got) == 0 {
+		t.Errorf("artifact %s is not linked to the SBOM package", digest)
+	}
+	if got := backend.Neighbors(artifacts[0], assembler.EdgeTypeBuiltBy); len(got) == 0 {
+		t.Errorf("artifact %s is not linked to the provenance builder", digest)
+	}
+}
+
+func TestParseDocumentTree_InTotoSupplyChain(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	docs := []*processor.Document{{
+		Blob:   testdata.InTotoLayoutExample,
+		Format: processor.FormatJSON,
+		Type:   processor.DocumentInTotoLayout,
+	}, {
+		Blob:   testdata.InTotoWriteCodeLinkExample,
+		Format: processor.FormatJSON,
+		Type:   processor.DocumentInTotoLink,
+	}, {
+		Blob:   testdata.InTotoPackageLinkExample,
+		Format: processor.FormatJSON,
+		Type:   processor.DocumentInTotoLink,
+	}}
+
+	backend := assembler.NewMemoryBackend()
+	for _, doc := range docs {
+		graphs, err := ParseDocumentTree(ctx, &processor.DocumentNode{Document: doc, Children: []*processor.DocumentNode{}})
+		if err != nil {
+			t.Fatalf("ParseDocumentTree() error = %v", err)
+		}
+		for _, g := range graphs {
+			if err := backend.StoreNodes(g.Nodes); err != nil {
+				t.Fatalf("StoreNodes() error = %v", err)
+			}
+			if err := backend.StoreEdges(g.Edges); err != nil {
+				t.Fatalf("StoreEdges() error = %v", err)
+			}
+		}
+	}
+
+	layout, ok := backend.GetNode(assembler.NodeTypeLayout, map[string]interface{}{
+		"digest": "sha256:8d1f1b0423f7f6bcd42e8b6e0f606961fe001ebd82381e4ac9ebd767ac6d3765",
+	})
+	if !ok {
+		t.Fatalf("layout node not found")
+	}
+	// every step expected by the layout has been merged with its link
+	steps := backend.Neighbors(layout, assembler.EdgeTypeExpectsStep)
+	if len(steps) != 2 {
+		t.Fatalf("got %d expected steps, want 2", len(steps))
+	}
+	for _, s := range steps {
+		props, _ := backend.NodeProperties(s)
+		if props["link"] == nil {
+			t.Errorf("step %v has no link", props["name"])
+		}
+	}
+
+	// the product of write-code is the material of package
+	source, ok := backend.GetNode(assembler.NodeTypeArtifact, map[string]interface{}{
+		"digest": "sha256:74dc3727c6e89308b39e4dfedf787e37841198b1fa165a27c013544a60502549",
+	})
+	if !ok {
+		t.Fatalf("artifact node not found")
+	}
+	producers := backend.ReverseNeighbors(source, assembler.EdgeTypeProduct)
+	consumers := backend.ReverseNeighbors(source, assembler.EdgeTypeMaterial)
+	if len(producers) != 1 || producers[0].(assembler.StepNode).Name != "write-code" {
+		t.Errorf("artifact is produced by %v, want write-code", producers)
+	}
+	if len(consumers) != 1 || consumers[0].(assembler.StepNode).Name != "package" {
+		t.Errorf("artifact is a material of %v, want package", consumers)
+	}
+}
@@ -0,0 +1,198 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The same-as parser parses the in-toto attestations with the predicate type
+// "https://in-toto.io/attestation/same-as/v0.1", which claim that their
+// subjects identify the same software as the resources listed in the
+// predicate, under other identity schemes:
+//
+//	"predicate": {
+//	  "sameAs": [{"uri": "git+https://github.com/org/repo@refs/tags/v1", "digest": {"sha1": "..."}}],
+//	  "justification": "built from the tagged commit"
+//	}
+//
+// Subjects and resources named by a purl become package nodes, the others
+// an artifact node per digest; resources with neither are skipped. Each
+// subject is linked to each resource via a "SameAs" edge carrying the
+// justification. An attestation node is also generated for the document, and
+// linked to the subjects via "Attestation" edges.
+package sameas
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/in-toto/in-toto-golang/in_toto"
+)
+
+const (
+	algorithmSHA256 string = "sha256"
+	attestationType string = "SAME_AS"
+)
+
+// statement is an in-toto statement with the same-as predicate
+type statement struct {
+	in_toto.StatementHeader
+	Predicate struct {
+		SameAs        []resource `json:"sameAs"`
+		Justification string     `json:"justification"`
+	} `json:"predicate"`
+}
+
+// resource is an in-toto resource descriptor, identified by its name or uri
+// and its digests
+type resource struct {
+	Name   string            `json:"name"`
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+type sameAsParser struct {
+	doc           *processor.Document
+	subjects      []assembler.GuacNode
+	equivalents   []assembler.GuacNode
+	justification string
+	attestation   assembler.AttestationNode
+}
+
+// NewSameAsParser initializes the sameAsParser
+func NewSameAsParser() common.DocumentParser {
+	return &sameAsParser{
+		subjects:    []assembler.GuacNode{},
+		equivalents: []assembler.GuacNode{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (s *sameAsParser) Parse(ctx context.Context, doc *processor.Document) error {
+	if doc.Type != processor.DocumentITE6SameAs {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentITE6SameAs, doc.Type)
+	}
+	s.doc = doc
+	st := statement{}
+	if err := json.Unmarshal(doc.Blob, &st); err != nil {
+		return fmt.Errorf("failed to parse same-as predicate: %w", err)
+	}
+	for _, sub := range st.Subject {
+		s.subjects = append(s.subjects, s.identify(resource{Name: sub.Name, Digest: sub.Digest})...)
+	}
+	for _, r := range st.Predicate.SameAs {
+		s.equivalents = append(s.equivalents, s.identify(r)...)
+	}
+	s.justification = st.Predicate.Justification
+
+	h := sha256.Sum256(doc.Blob)
+	s.attestation = assembler.AttestationNode{
+		FilePath:        doc.SourceInformation.Source,
+		Digest:          algorithmSHA256 + ":" + hex.EncodeToString(h[:]),
+		AttestationType: attestationType,
+		Payload:         map[string]interface{}{"justification": st.Predicate.Justification},
+		NodeData:        *assembler.NewObjectMetadata(doc.SourceInformation),
+	}
+	return nil
+}
+
+// identify returns the nodes identifying the resource: a package node if it
+// is named by a purl, otherwise an artifact node per digest
+func (s *sameAsParser) identify(r resource) []assembler.GuacNode {
+	name := r.Name
+	if name == "" {
+		name = r.URI
+	}
+	algorithms := make([]string, 0, len(r.Digest))
+	for alg := range r.Digest {
+		algorithms = append(algorithms, alg)
+	}
+	sort.Strings(algorithms)
+	digests := []string{}
+	for _, alg := range algorithms {
+		digests = append(digests, common.NormalizeDigest(alg, strings.Trim(r.Digest[alg], "'")))
+	}
+
+	if strings.HasPrefix(name, "pkg:") {
+		pkg := assembler.PackageNode{
+			Purl:     common.NormalizePurl(name),
+			NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation),
+		}
+		if len(digests) > 0 {
+			pkg.Digest = digests
+		}
+		return []assembler.GuacNode{pkg}
+	}
+	nodes := []assembler.GuacNode{}
+	for _, d := range digests {
+		nodes = append(nodes, assembler.ArtifactNode{
+			Name: name, Digest: d, NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+	}
+	return nodes
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (s *sameAsParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	nodes = append(nodes, s.subjects...)
+	nodes = append(nodes, s.equivalents...)
+	nodes = append(nodes, s.attestation)
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (s *sameAsParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, i := range foundIdentities {
+		edges = append(edges, assembler.IdentityForEdge{IdentityNode: i, AttestationNode: s.attestation})
+	}
+	for _, sub := range s.subjects {
+		att := assembler.AttestationForEdge{AttestationNode: s.attestation}
+		switch sub := sub.(type) {
+		case assembler.ArtifactNode:
+			att.ForArtifact = sub
+		case assembler.PackageNode:
+			att.ForPackage = sub
+		}
+		edges = append(edges, att)
+
+		for _, eq := range s.equivalents {
+			e := assembler.SameAsEdge{Justification: s.justification}
+			switch sub := sub.(type) {
+			case assembler.ArtifactNode:
+				e.ArtifactNode = sub
+			case assembler.PackageNode:
+				e.PackageNode = sub
+			}
+			switch eq := eq.(type) {
+			case assembler.ArtifactNode:
+				e.ArtifactEquivalent = eq
+			case assembler.PackageNode:
+				e.PackageEquivalent = eq
+			}
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (s *sameAsParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,115 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sameas
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_sameAsParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	image := assembler.ArtifactNode{
+		Name:     "ghcr.io/guacsec/guac",
+		Digest:   "sha256:1ab2f6c6d5e2a8e1b3c6f1a7e0c3c5a8c9e4f2d1b0a7c6e5d4f3a2b1c0d9e8f7",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	imagePurl := assembler.PackageNode{
+		Purl:     "pkg:oci/guac@sha256:1ab2f6c6d5e2a8e1b3c6f1a7e0c3c5a8c9e4f2d1b0a7c6e5d4f3a2b1c0d9e8f7?repository_url=ghcr.io/guacsec/guac",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	commit := assembler.ArtifactNode{
+		Name:     "git+https://github.com/guacsec/guac@refs/tags/v0.1.0",
+		Digest:   "sha1:5f8ebd1b5d9b6f2b1d3e4c2a7a3c0e9a8d1b2c3d",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	module := assembler.PackageNode{
+		Purl:     "pkg:golang/github.com/guacsec/guac@v0.1.0",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	h := sha256.Sum256(testdata.ITE6SameAsExample)
+	att := assembler.AttestationNode{
+		FilePath:        "TestSource",
+		Digest:          "sha256:" + hex.EncodeToString(h[:]),
+		AttestationType: "SAME_AS",
+		Payload:         map[string]interface{}{"justification": "built by the release workflow from the tagged commit"},
+		NodeData:        *assembler.NewObjectMetadata(srcInfo),
+	}
+	justification := "built by the release workflow from the tagged commit"
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "same-as attestation",
+		doc: &processor.Document{
+			Blob:              testdata.ITE6SameAsExample,
+			Type:              processor.DocumentITE6SameAs,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{image, imagePurl, commit, module, att},
+		wantEdges: []assembler.GuacEdge{
+			assembler.AttestationForEdge{AttestationNode: att, ForArtifact: image},
+			assembler.AttestationForEdge{AttestationNode: att, ForPackage: imagePurl},
+			assembler.SameAsEdge{ArtifactNode: image, ArtifactEquivalent: commit, Justification: justification},
+			assembler.SameAsEdge{ArtifactNode: image, PackageEquivalent: module, Justification: justification},
+			assembler.SameAsEdge{PackageNode: imagePurl, ArtifactEquivalent: commit, Justification: justification},
+			assembler.SameAsEdge{PackageNode: imagePurl, PackageEquivalent: module, Justification: justification},
+		},
+	}, {
+		name: "wrong document type",
+		doc: &processor.Document{
+			Blob:              testdata.ITE6SameAsExample,
+			Type:              processor.DocumentITE6Generic,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSameAsParser()
+			err := s.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sameAsParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := s.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("sameAsParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := s.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("sameAsParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
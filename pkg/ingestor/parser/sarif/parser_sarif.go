@@ -0,0 +1,222 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The SARIF parser parses the results of static analysis tools, as SARIF
+// logs (see `sarif.Log`).
+//
+// A finding node is generated for each result, with the id of its rule, its
+// level and security severity, and its location. It is linked via a
+// "HasFinding" edge to what was analyzed: the artifact of its location, when
+// the run gives its SHA-256 digest (e.g. for the scans of binaries),
+// otherwise the commit (or the repository, when the revision is not a
+// commit) given by the version control provenance of the run. Results that
+// cannot be linked to either are skipped.
+package sarif
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/sarif"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type sarifParser struct {
+	sources   []common.BuildSource
+	artifacts []assembler.ArtifactNode
+	findings  []assembler.HasFindingEdge
+}
+
+// NewSARIFParser initializes the sarifParser
+func NewSARIFParser() common.DocumentParser {
+	return &sarifParser{
+		sources:   []common.BuildSource{},
+		artifacts: []assembler.ArtifactNode{},
+		findings:  []assembler.HasFindingEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *sarifParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+	if doc.Type != processor.DocumentSARIF {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSARIF, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of SARIF document format: %v", doc.Format)
+	}
+
+	var log sarif.Log
+	if err := json.Unmarshal(doc.Blob, &log); err != nil {
+		return err
+	}
+
+	results, skipped := 0, 0
+	seenArtifacts := map[string]bool{}
+	for _, run := range log.Runs {
+		tool := run.Tool.Driver.Name
+		var source *common.BuildSource
+		if len(run.VersionControlProvenance) > 0 {
+			vcp := run.VersionControlProvenance[0]
+			if ref, ok := common.ParseVCS(vcp.RepositoryURI, "git"); ok {
+				ref.Revision = vcp.RevisionID
+				s := common.NewBuildSource(ref, "", doc.SourceInformation)
+				source = &s
+				p.sources = append(p.sources, s)
+			}
+		}
+
+		for _, res := range run.Results {
+			results++
+			uri, location, artifact := resultLocation(run, res)
+			finding := assembler.FindingNode{
+				Tool:        tool,
+				RuleID:      run.RuleID(res),
+				Location:    location,
+				Level:       run.Level(res),
+				Severity:    run.Severity(res),
+				Message:     res.Message.Text,
+				Fingerprint: fingerprint(res),
+				NodeData:    *assembler.NewObjectMetadata(doc.SourceInformation),
+			}
+			e := assembler.HasFindingEdge{}
+			switch {
+			case artifact != "":
+				a := assembler.ArtifactNode{
+					Name:     uri,
+					Digest:   artifact,
+					NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+				}
+				if !seenArtifacts[a.Digest] {
+					seenArtifacts[a.Digest] = true
+					p.artifacts = append(p.artifacts, a)
+				}
+				e.ArtifactNode = a
+				finding.Subject = a.Digest
+			case source != nil && source.Commit != nil:
+				e.CommitNode = *source.Commit
+				finding.Subject = source.Commit.Sha
+			case source != nil:
+				e.SourceNode = source.Source
+				finding.Subject = source.Source.URL
+			default:
+				skipped++
+				logger.Debugf("skipping result %s of %s at %s, the analyzed repository or artifact is unknown", finding.RuleID, tool, location)
+				continue
+			}
+			e.FindingNode = finding
+			p.findings = append(p.findings, e)
+		}
+	}
+
+	if results > 0 && skipped == results {
+		return fmt.Errorf("none of the %d results of the SARIF log identifies the analyzed repository (versionControlProvenance) or artifact (hashes)", results)
+	}
+	if skipped > 0 {
+		logger.Warnf("skipped %d of the %d results of %s, the analyzed repository or artifact is unknown", skipped, results, doc.SourceInformation.Source)
+	}
+	return nil
+}
+
+// resultLocation returns the URI of the file the result is located in, its
+// location with the line and column when known (e.g. `main.go:17:14`), and
+// the digest of the file if the run gives its SHA-256 hash
+func resultLocation(run sarif.Run, res sarif.Result) (string, string, string) {
+	for _, loc := range res.Locations {
+		pl := loc.PhysicalLocation
+		if pl == nil {
+			continue
+		}
+		uri := pl.ArtifactLocation.URI
+		var digest string
+		if a := run.Artifact(pl.ArtifactLocation); a != nil {
+			if uri == "" {
+				uri = a.Location.URI
+			}
+			for _, alg := range []string{"sha-256", "sha256"} {
+				if h, ok := a.Hashes[alg]; ok && h != "" {
+					digest = "sha256:" + strings.ToLower(h)
+					break
+				}
+			}
+		}
+		location := uri
+		if r := pl.Region; r != nil && r.StartLine > 0 {
+			location += ":" + strconv.Itoa(r.StartLine)
+			if r.StartColumn > 0 {
+				location += ":" + strconv.Itoa(r.StartColumn)
+			}
+		}
+		return uri, location, digest
+	}
+	return "", "", ""
+}
+
+// fingerprint returns the fingerprint of the result tracking it across runs,
+// preferring the stable fingerprints over the partial ones. When there are
+// several, the one whose name sorts first is returned.
+func fingerprint(res sarif.Result) string {
+	for _, fps := range []map[string]string{res.Fingerprints, res.PartialFingerprints} {
+		if len(fps) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(fps))
+		for name := range fps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fps[names[0]]
+	}
+	return ""
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *sarifParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	for _, s := range p.sources {
+		nodes = append(nodes, s.Nodes()...)
+	}
+	for _, a := range p.artifacts {
+		nodes = append(nodes, a)
+	}
+	for _, e := range p.findings {
+		nodes = append(nodes, e.FindingNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *sarifParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, s := range p.sources {
+		edges = append(edges, s.Edges()...)
+	}
+	for _, e := range p.findings {
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *sarifParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,186 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sarif
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_sarifParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	repo := assembler.SourceNode{
+		URL:      "https://github.com/example/app",
+		VCS:      "git",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	commit := assembler.CommitNode{
+		VCS:        "git",
+		Sha:        "d6525c840a62b398424a78d792f457477135d0cf",
+		Repository: "https://github.com/example/app",
+		NodeData:   *assembler.NewObjectMetadata(srcInfo),
+	}
+	binary := assembler.ArtifactNode{
+		Name:     "dist/app-linux-amd64",
+		Digest:   "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	sqlInjection := assembler.FindingNode{
+		Subject:     commit.Sha,
+		Tool:        "CodeQL",
+		RuleID:      "go/sql-injection",
+		Location:    "pkg/store/users.go:42:23",
+		Level:       "error",
+		Severity:    "high",
+		Message:     "This query depends on a user-provided value.",
+		Fingerprint: "5a8d1f2f2b0e9c1b:1",
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}
+	logInjection := assembler.FindingNode{
+		Subject:  commit.Sha,
+		Tool:     "CodeQL",
+		RuleID:   "go/log-injection",
+		Location: "cmd/server/main.go:17:14",
+		Level:    "error",
+		Severity: "high",
+		Message:  "This log entry depends on a user-provided value.",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	fileClose := assembler.FindingNode{
+		Subject:  commit.Sha,
+		Tool:     "CodeQL",
+		RuleID:   "go/unhandled-writable-file-close",
+		Location: "cmd/server/main.go:64",
+		Level:    "note",
+		Message:  "File handle may be writable as a result of data flow from a call to OpenFile and closing it may result in data loss upon failure, which is not handled explicitly.",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	stackProtector := assembler.FindingNode{
+		Subject:     binary.Digest,
+		Tool:        "binscan",
+		RuleID:      "BIN001",
+		Location:    "dist/app-linux-amd64",
+		Level:       "warning",
+		Message:     "The binary is not compiled with -fstack-protector.",
+		Fingerprint: "c0ffee",
+		NodeData:    *assembler.NewObjectMetadata(srcInfo),
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "SARIF document",
+		doc: &processor.Document{
+			Blob:              testdata.SARIFExample,
+			Type:              processor.DocumentSARIF,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{repo, commit, binary, sqlInjection, logInjection, fileClose, stackProtector},
+		wantEdges: []assembler.GuacEdge{
+			assembler.HasCommitEdge{SourceNode: repo, CommitNode: commit},
+			assembler.HasFindingEdge{CommitNode: commit, FindingNode: sqlInjection},
+			assembler.HasFindingEdge{CommitNode: commit, FindingNode: logInjection},
+			assembler.HasFindingEdge{CommitNode: commit, FindingNode: fileClose},
+			assembler.HasFindingEdge{ArtifactNode: binary, FindingNode: stackProtector},
+		},
+	}, {
+		name: "revision is not a commit",
+		doc: &processor.Document{
+			Blob: []byte(`{"version": "2.1.0", "runs": [{
+				"tool": {"driver": {"name": "semgrep"}},
+				"versionControlProvenance": [{"repositoryUri": "https://github.com/example/app", "revisionId": "main"}],
+				"results": [{"ruleId": "go.lang.security.audit.xss", "level": "warning", "message": {"text": "xss"}}]
+			}]}`),
+			Type:              processor.DocumentSARIF,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			repo,
+			assembler.FindingNode{
+				Subject:  repo.URL,
+				Tool:     "semgrep",
+				RuleID:   "go.lang.security.audit.xss",
+				Level:    "warning",
+				Message:  "xss",
+				NodeData: *assembler.NewObjectMetadata(srcInfo),
+			},
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.HasFindingEdge{
+				SourceNode: repo,
+				FindingNode: assembler.FindingNode{
+					Subject:  repo.URL,
+					Tool:     "semgrep",
+					RuleID:   "go.lang.security.audit.xss",
+					Level:    "warning",
+					Message:  "xss",
+					NodeData: *assembler.NewObjectMetadata(srcInfo),
+				},
+			},
+		},
+	}, {
+		name: "no result can be linked",
+		doc: &processor.Document{
+			Blob:              []byte(`{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "semgrep"}}, "results": [{"ruleId": "r", "message": {"text": "m"}}]}]}`),
+			Type:              processor.DocumentSARIF,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.SARIFExample,
+			Type:              processor.DocumentSARIF,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSARIFParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sarifParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("sarifParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("sarifParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -34,7 +34,7 @@ type scorecardParser struct {
 	artifactNodes []assembler.ArtifactNode
 }
 
-// NewSLSAParser initializes the slsaParser
+// NewScorecardParser initializes the scorecardParser
 func NewScorecardParser() common.DocumentParser {
 	return &scorecardParser{
 		scorecardNodes: []assembler.MetadataNode{},
@@ -56,7 +56,7 @@ func (p *scorecardParser) Parse(ctx context.Context, doc *processor.Document) er
 			return err
 		}
 		p.scorecardNodes = append(p.scorecardNodes, getMetadataNode(&scorecard))
-		p.artifactNodes = append(p.artifactNodes, getArtifactNode(&scorecard))
+		p.artifactNodes = append(p.artifactNodes, getArtifactNode(&scorecard, doc.SourceInformation))
 		return nil
 	}
 	return fmt.Errorf("unable to support parsing of Scorecard document format: %v", doc.Format)
@@ -97,6 +97,10 @@ func metadataId(s *sc.JSONScorecardResultV2) string {
 	return fmt.Sprintf("%v:%v", s.Repo.Name, s.Repo.Commit)
 }
 
+// getMetadataNode returns the scorecard node. Each check score is stored as a
+// separate property, named after the check with dashes replaced by
+// underscores (e.g., `Branch_Protection`), so that repositories can be
+// filtered on individual checks.
 func getMetadataNode(s *sc.JSONScorecardResultV2) assembler.MetadataNode {
 	mnNode := assembler.MetadataNode{
 		MetadataType: "scorecard",
@@ -112,14 +116,16 @@ func getMetadataNode(s *sc.JSONScorecardResultV2) assembler.MetadataNode {
 	mnNode.Details["scorecard_version"] = s.Scorecard.Version
 	mnNode.Details["scorecard_commit"] = hashToDigest(s.Scorecard.Commit)
 	mnNode.Details["score"] = float64(s.AggregateScore)
+	mnNode.Details["date"] = s.Date
 
 	return mnNode
 }
 
-func getArtifactNode(s *sc.JSONScorecardResultV2) assembler.ArtifactNode {
+func getArtifactNode(s *sc.JSONScorecardResultV2, srcInfo processor.SourceInformation) assembler.ArtifactNode {
 	return assembler.ArtifactNode{
-		Name:   sourceUri(s.Repo.Name),
-		Digest: hashToDigest(s.Repo.Commit),
+		Name:     sourceUri(s.Repo.Name),
+		Digest:   hashToDigest(s.Repo.Commit),
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
 	}
 }
 
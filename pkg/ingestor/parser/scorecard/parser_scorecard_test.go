@@ -37,10 +37,13 @@ func Test_scorecardParser(t *testing.T) {
 	}{{
 		name: "testing",
 		doc: &processor.Document{
-			Blob:              testdata.ScorecardExample,
-			Type:              processor.DocumentScorecard,
-			Format:            processor.FormatJSON,
-			SourceInformation: processor.SourceInformation{},
+			Blob:   testdata.ScorecardExample,
+			Type:   processor.DocumentScorecard,
+			Format: processor.FormatJSON,
+			SourceInformation: processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
 		},
 		wantNodes: []assembler.GuacNode{
 			assembler.MetadataNode{
@@ -52,6 +55,7 @@ func Test_scorecardParser(t *testing.T) {
 					"scorecard_version":   "v4.7.0",
 					"scorecard_commit":    "sha1:7cd6406aef0b80a819402e631919293d5eb6adcf",
 					"score":               8.9,
+					"date":                "2022-10-06",
 					"Binary_Artifacts":    10,
 					"CI_Tests":            10,
 					"Code_Review":         7,
@@ -66,6 +70,10 @@ func Test_scorecardParser(t *testing.T) {
 			assembler.ArtifactNode{
 				Name:   "git+https://github.com/kubernetes/kubernetes",
 				Digest: "sha1:5835544ca568b757a8ecae5c153f317e5736700e",
+				NodeData: *assembler.NewObjectMetadata(processor.SourceInformation{
+					Collector: "TestCollector",
+					Source:    "TestSource",
+				}),
 			},
 		},
 		wantEdges: []assembler.GuacEdge{
@@ -79,6 +87,7 @@ func Test_scorecardParser(t *testing.T) {
 						"scorecard_version":   "v4.7.0",
 						"scorecard_commit":    "sha1:7cd6406aef0b80a819402e631919293d5eb6adcf",
 						"score":               8.9,
+						"date":                "2022-10-06",
 						"Binary_Artifacts":    10,
 						"CI_Tests":            10,
 						"Code_Review":         7,
@@ -93,6 +102,10 @@ func Test_scorecardParser(t *testing.T) {
 				ForArtifact: assembler.ArtifactNode{
 					Name:   "git+https://github.com/kubernetes/kubernetes",
 					Digest: "sha1:5835544ca568b757a8ecae5c153f317e5736700e",
+					NodeData: *assembler.NewObjectMetadata(processor.SourceInformation{
+						Collector: "TestCollector",
+						Source:    "TestSource",
+					}),
 				},
 			},
 		},
@@ -30,7 +30,8 @@ import (
 )
 
 const (
-	algorithmSHA256 string = "sha256"
+	algorithmSHA256     string = "sha256"
+	slsaV1PredicateType string = "https://slsa.dev/provenance/v1"
 )
 
 type slsaParser struct {
@@ -39,6 +40,7 @@ type slsaParser struct {
 	dependencies []assembler.ArtifactNode
 	attestations []assembler.AttestationNode
 	builders     []assembler.BuilderNode
+	sources      []common.BuildSource
 }
 
 // NewSLSAParser initializes the slsaParser
@@ -48,6 +50,7 @@ func NewSLSAParser() common.DocumentParser {
 		dependencies: []assembler.ArtifactNode{},
 		attestations: []assembler.AttestationNode{},
 		builders:     []assembler.BuilderNode{},
+		sources:      []common.BuildSource{},
 	}
 }
 
@@ -65,25 +68,40 @@ func (s *slsaParser) Parse(ctx context.Context, doc *processor.Document) error {
 	return nil
 }
 
-func (s *slsaParser) getSubject(statement *in_toto.ProvenanceStatement) {
+func (s *slsaParser) getSubject(statement *provenance) {
 	// append artifact node for the subjects
-	for _, sub := range statement.Subject {
+	for _, sub := range statement.subjects {
 		for alg, ds := range sub.Digest {
 			s.subjects = append(s.subjects, assembler.ArtifactNode{
-				Name: sub.Name, Digest: alg + ":" + strings.Trim(ds, "'"), NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+				Name: sub.Name, Digest: common.NormalizeDigest(alg, strings.Trim(ds, "'")), NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
 		}
 	}
 }
 
-func (s *slsaParser) getDependency(statement *in_toto.ProvenanceStatement) {
+func (s *slsaParser) getDependency(statement *provenance) {
 	// append dependency nodes for the materials
-	for _, mat := range statement.Predicate.Materials {
-		for alg, ds := range mat.Digest {
-
+	for _, mat := range statement.materials {
+		if ref, ok := common.ParseVCS(mat.uri, ""); ok {
+			s.sources = append(s.sources, common.NewBuildSource(ref, materialCommit(mat.digest), s.doc.SourceInformation))
+		}
+		for alg, ds := range mat.digest {
 			s.dependencies = append(s.dependencies, assembler.ArtifactNode{
-				Name: mat.URI, Digest: alg + ":" + strings.Trim(ds, "'"), NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+				Name: mat.uri, Digest: common.NormalizeDigest(alg, strings.Trim(ds, "'")), NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+		}
+	}
+}
+
+// materialCommit returns the commit of a VCS material, given by its sha1 or
+// gitCommit digest
+func materialCommit(digest map[string]string) string {
+	for _, alg := range []string{"gitCommit", "sha1"} {
+		for a, ds := range digest {
+			if strings.EqualFold(a, alg) {
+				return strings.Trim(ds, "'")
+			}
 		}
 	}
+	return ""
 }
 
 func (s *slsaParser) getAttestation(blob []byte) {
@@ -92,18 +110,101 @@ func (s *slsaParser) getAttestation(blob []byte) {
 		FilePath: s.doc.SourceInformation.Source, Digest: algorithmSHA256 + ":" + hex.EncodeToString(h[:]), NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
 }
 
-func (s *slsaParser) getBuilder(statement *in_toto.ProvenanceStatement) {
+func (s *slsaParser) getBuilder(statement *provenance) {
 	// append builder node for builder
 	s.builders = append(s.builders, assembler.BuilderNode{
-		BuilderType: statement.Predicate.BuildType, BuilderId: statement.Predicate.Builder.ID, NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+		BuilderType: statement.buildType, BuilderId: statement.builderID, NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+	// the Tekton tasks that ran the build are builders of the subjects too
+	for _, t := range statement.tasks {
+		s.builders = append(s.builders, assembler.BuilderNode{
+			BuilderType: tektonTaskType + t.kind, BuilderId: t.id, NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation)})
+	}
+}
+
+// provenance holds the parts of a SLSA provenance statement used by GUAC,
+// independently of the version of the predicate
+type provenance struct {
+	subjects  []in_toto.Subject
+	buildType string
+	builderID string
+	materials []material
+	// tasks that ran the build, for Tekton Chains
+	tasks []task
+}
+
+type material struct {
+	uri    string
+	digest map[string]string
+}
+
+// slsaV1Predicate is the subset of the SLSA v1.0 provenance predicate that is
+// used by GUAC. See https://slsa.dev/provenance/v1
+type slsaV1Predicate struct {
+	BuildDefinition struct {
+		BuildType            string                     `json:"buildType"`
+		ResolvedDependencies []slsaV1ResourceDescriptor `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+	} `json:"runDetails"`
+}
+
+type slsaV1ResourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
 }
 
-func parseSlsaPredicate(p []byte) (*in_toto.ProvenanceStatement, error) {
-	predicate := in_toto.ProvenanceStatement{}
-	if err := json.Unmarshal(p, &predicate); err != nil {
+// parseSlsaPredicate parses SLSA v1.0 provenance, and falls back to the
+// v0.2 format for older predicate types
+func parseSlsaPredicate(p []byte) (*provenance, error) {
+	header := in_toto.StatementHeader{}
+	if err := json.Unmarshal(p, &header); err != nil {
 		return nil, err
 	}
-	return &predicate, nil
+
+	if header.PredicateType == slsaV1PredicateType {
+		statement := struct {
+			Predicate slsaV1Predicate `json:"predicate"`
+		}{}
+		if err := json.Unmarshal(p, &statement); err != nil {
+			return nil, err
+		}
+		prov := &provenance{
+			subjects:  header.Subject,
+			buildType: statement.Predicate.BuildDefinition.BuildType,
+			builderID: statement.Predicate.RunDetails.Builder.ID,
+		}
+		for _, dep := range statement.Predicate.BuildDefinition.ResolvedDependencies {
+			uri := dep.URI
+			if uri == "" {
+				uri = dep.Name
+			}
+			prov.materials = append(prov.materials, material{uri: uri, digest: dep.Digest})
+		}
+		return prov, nil
+	}
+
+	statement := in_toto.ProvenanceStatement{}
+	if err := json.Unmarshal(p, &statement); err != nil {
+		return nil, err
+	}
+	prov := &provenance{
+		subjects:  statement.Subject,
+		buildType: statement.Predicate.BuildType,
+		builderID: statement.Predicate.Builder.ID,
+	}
+	for _, mat := range statement.Predicate.Materials {
+		prov.materials = append(prov.materials, material{uri: mat.URI, digest: mat.Digest})
+	}
+	if isTektonBuild(prov) {
+		if err := parseTekton(p, prov); err != nil {
+			return nil, err
+		}
+	}
+	return prov, nil
 }
 
 // CreateNodes creates the GuacNode for the graph inputs
@@ -121,6 +222,9 @@ func (s *slsaParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
 	for _, b := range s.builders {
 		nodes = append(nodes, b)
 	}
+	for _, src := range s.sources {
+		nodes = append(nodes, src.Nodes()...)
+	}
 	return nodes
 }
 
@@ -140,8 +244,14 @@ func (s *slsaParser) CreateEdges(ctx context.Context, foundIdentities []assemble
 			edges = append(edges, assembler.AttestationForEdge{AttestationNode: a, ForArtifact: sub})
 		}
 		for _, d := range s.dependencies {
-			edges = append(edges, assembler.DependsOnEdge{ArtifactNode: sub, ArtifactDependency: d})
+			edges = append(edges, assembler.BuiltFromEdge{ArtifactNode: sub, MaterialNode: d})
 		}
+		for _, src := range s.sources {
+			edges = append(edges, src.BuiltFrom(sub))
+		}
+	}
+	for _, src := range s.sources {
+		edges = append(edges, src.Edges()...)
 	}
 	return edges
 }
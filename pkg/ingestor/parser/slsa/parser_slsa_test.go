@@ -17,12 +17,15 @@ package slsa
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"reflect"
 	"testing"
 
 	"github.com/guacsec/guac/internal/testing/testdata"
 	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
 	"github.com/guacsec/guac/pkg/logging"
 )
 
@@ -40,6 +43,12 @@ func Test_slsaParser(t *testing.T) {
 		wantNodes: testdata.SlsaNodes,
 		wantEdges: testdata.SlsaEdges,
 		wantErr:   false,
+	}, {
+		name:      "slsa v1.0 provenance",
+		doc:       &testdata.Ite6SLSAV1Doc,
+		wantNodes: testdata.SlsaV1Nodes,
+		wantEdges: testdata.SlsaV1Edges,
+		wantErr:   false,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -61,3 +70,108 @@ func Test_slsaParser(t *testing.T) {
 		})
 	}
 }
+
+func Test_slsaParser_Tekton(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	taskRun := []byte(`{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject": [{"name": "registry.example.com/guac/app", "digest": {"sha256": "5b0d2b8a3f4a4b1e8b7e4f0f2d2b8b5d1b2e0c4e6f8a0b2c4d6e8f0a2b4c6d8e"}}],
+		"predicate": {
+			"builder": {"id": "https://tekton.dev/chains/v2"},
+			"buildType": "tekton.dev/v1beta1/TaskRun",
+			"invocation": {"configSource": {"uri": "git+https://github.com/guacsec/tasks.git", "digest": {"sha1": "0123456789abcdef0123456789abcdef01234567"}, "entryPoint": "build"}},
+			"buildConfig": {"steps": [
+				{"entryPoint": "go build ./...", "environment": {"container": "build", "image": "docker.io/library/golang@sha256:4cf39e1b1fa2d7d2a0eb5fe3c2bbb3a5e0e0f1b6c8e9a4d2f3b7c1a8e5d6f9b0"}},
+				{"entryPoint": "echo done", "environment": {"container": "unpinned", "image": "docker.io/library/alpine:3.17"}}
+			]}
+		}
+	}`)
+	artifact := func(name, digest string) assembler.ArtifactNode {
+		return assembler.ArtifactNode{Name: name, Digest: digest, NodeData: *assembler.NewObjectMetadata(srcInfo)}
+	}
+	builder := func(builderType, id string) assembler.BuilderNode {
+		return assembler.BuilderNode{BuilderType: builderType, BuilderId: id, NodeData: *assembler.NewObjectMetadata(srcInfo)}
+	}
+	attestation := func(blob []byte) assembler.AttestationNode {
+		h := sha256.Sum256(blob)
+		return assembler.AttestationNode{FilePath: "TestSource", Digest: "sha256:" + hex.EncodeToString(h[:]), NodeData: *assembler.NewObjectMetadata(srcInfo)}
+	}
+	source := func(uri, sha string) common.BuildSource {
+		ref, _ := common.ParseVCS(uri, "")
+		return common.NewBuildSource(ref, sha, srcInfo)
+	}
+	subject := artifact("registry.example.com/guac/app", "sha256:5b0d2b8a3f4a4b1e8b7e4f0f2d2b8b5d1b2e0c4e6f8a0b2c4d6e8f0a2b4c6d8e")
+
+	tests := []struct {
+		name         string
+		blob         []byte
+		dependencies []assembler.ArtifactNode
+		builders     []assembler.BuilderNode
+		source       common.BuildSource
+	}{{
+		name: "PipelineRun",
+		blob: testdata.TektonPipelineRunExample,
+		dependencies: []assembler.ArtifactNode{
+			artifact("git+https://github.com/guacsec/example.git", "sha1:a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"),
+			artifact("oci://gcr.io/tekton-releases/github.com/tektoncd/pipeline/cmd/git-init", "sha256:28ff94e63e4058afc3f15b4c11c08cf3b54fa91faa646a4bbac90380cd7158df"),
+			artifact("gcr.io/tekton-releases/catalog/upstream/git-clone@sha256:2aa77e1ac3c8f4e6b1b4f0a9b3e4d5c6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2", "sha256:2aa77e1ac3c8f4e6b1b4f0a9b3e4d5c6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2"),
+			artifact("quay.io/buildah/stable@sha256:8d6a1bd53f0ea31bd7ae7e6b3c2b1ae8dc3b6f1a9a0c5e0f6b3c8d2e1f0a9b8c", "sha256:8d6a1bd53f0ea31bd7ae7e6b3c2b1ae8dc3b6f1a9a0c5e0f6b3c8d2e1f0a9b8c"),
+		},
+		builders: []assembler.BuilderNode{
+			builder("tekton.dev/v1beta1/PipelineRun", "https://tekton.dev/chains/v2"),
+			builder("tekton.dev/Task", "gcr.io/tekton-releases/catalog/upstream/git-clone@sha256:2aa77e1ac3c8f4e6b1b4f0a9b3e4d5c6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2#git-clone"),
+			builder("tekton.dev/ClusterTask", "buildah"),
+		},
+		source: source("git+https://github.com/guacsec/example.git", "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"),
+	}, {
+		name: "TaskRun",
+		blob: taskRun,
+		dependencies: []assembler.ArtifactNode{
+			artifact("git+https://github.com/guacsec/tasks.git", "sha1:0123456789abcdef0123456789abcdef01234567"),
+			artifact("docker.io/library/golang@sha256:4cf39e1b1fa2d7d2a0eb5fe3c2bbb3a5e0e0f1b6c8e9a4d2f3b7c1a8e5d6f9b0", "sha256:4cf39e1b1fa2d7d2a0eb5fe3c2bbb3a5e0e0f1b6c8e9a4d2f3b7c1a8e5d6f9b0"),
+		},
+		builders: []assembler.BuilderNode{
+			builder("tekton.dev/v1beta1/TaskRun", "https://tekton.dev/chains/v2"),
+			builder("tekton.dev/Task", "git+https://github.com/guacsec/tasks.git#build"),
+		},
+		source: source("git+https://github.com/guacsec/tasks.git", "0123456789abcdef0123456789abcdef01234567"),
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att := attestation(tt.blob)
+			wantNodes := []assembler.GuacNode{subject, att}
+			wantEdges := []assembler.GuacEdge{}
+			for _, d := range tt.dependencies {
+				wantNodes = append(wantNodes, d)
+			}
+			for _, b := range tt.builders {
+				wantNodes = append(wantNodes, b)
+				wantEdges = append(wantEdges, assembler.BuiltByEdge{ArtifactNode: subject, BuilderNode: b})
+			}
+			wantEdges = append(wantEdges, assembler.AttestationForEdge{AttestationNode: att, ForArtifact: subject})
+			for _, d := range tt.dependencies {
+				wantEdges = append(wantEdges, assembler.BuiltFromEdge{ArtifactNode: subject, MaterialNode: d})
+			}
+			wantNodes = append(wantNodes, tt.source.Nodes()...)
+			wantEdges = append(wantEdges, tt.source.BuiltFrom(subject))
+			wantEdges = append(wantEdges, tt.source.Edges()...)
+
+			s := NewSLSAParser()
+			doc := &processor.Document{Blob: tt.blob, Type: processor.DocumentITE6SLSA, Format: processor.FormatJSON, SourceInformation: srcInfo}
+			if err := s.Parse(ctx, doc); err != nil {
+				t.Fatalf("slsa.Parse() error = %v", err)
+			}
+			if nodes := s.CreateNodes(ctx); !reflect.DeepEqual(nodes, wantNodes) {
+				t.Errorf("slsa.CreateNodes() = %v, want %v", nodes, wantNodes)
+			}
+			if edges := s.CreateEdges(ctx, nil); !reflect.DeepEqual(edges, wantEdges) {
+				t.Errorf("slsa.CreateEdges() = %v, want %v", edges, wantEdges)
+			}
+		})
+	}
+}
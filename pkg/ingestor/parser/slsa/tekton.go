@@ -0,0 +1,169 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+const (
+	// tektonBuilderPrefix prefixes the builder IDs of the provenance
+	// generated by Tekton Chains (e.g. `https://tekton.dev/chains/v2`)
+	tektonBuilderPrefix = "https://tekton.dev/chains"
+	// tektonTaskType prefixes the kind of task in the type of the builder
+	// nodes of the Tekton tasks (e.g. `tekton.dev/ClusterTask`)
+	tektonTaskType = "tekton.dev/"
+)
+
+// tektonPredicate is the subset of the SLSA v0.2 provenance predicate of
+// Tekton Chains that is specific to Tekton: the build config lists the steps
+// of a TaskRun, or the tasks of a PipelineRun with their own steps, and the
+// invocation references the task run by a TaskRun.
+// See https://tekton.dev/docs/chains/slsa-provenance/
+type tektonPredicate struct {
+	Invocation struct {
+		ConfigSource struct {
+			URI        string            `json:"uri"`
+			Digest     map[string]string `json:"digest"`
+			EntryPoint string            `json:"entryPoint"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+	BuildConfig struct {
+		Steps []tektonStep `json:"steps"`
+		Tasks []tektonTask `json:"tasks"`
+	} `json:"buildConfig"`
+}
+
+type tektonTask struct {
+	Name string `json:"name"`
+	Ref  struct {
+		Name   string `json:"name"`
+		Kind   string `json:"kind"`
+		Bundle string `json:"bundle"`
+	} `json:"ref"`
+	Steps []tektonStep `json:"steps"`
+}
+
+type tektonStep struct {
+	EntryPoint  string `json:"entryPoint"`
+	Environment struct {
+		Container string `json:"container"`
+		Image     string `json:"image"`
+	} `json:"environment"`
+}
+
+// task is a Tekton task that ran part of the build. Kind is `Task` or
+// `ClusterTask`, and ID references its definition: its name, prefixed by
+// the bundle or the URI it was resolved from.
+type task struct {
+	kind string
+	id   string
+}
+
+// isTektonBuild returns whether the provenance was generated by Tekton
+// Chains
+func isTektonBuild(prov *provenance) bool {
+	return strings.HasPrefix(prov.builderID, tektonBuilderPrefix)
+}
+
+// parseTekton adds the tasks of the Tekton build to the provenance, and the
+// images of their steps and their bundles to its materials, unless they
+// already are
+func parseTekton(p []byte, prov *provenance) error {
+	statement := struct {
+		Predicate tektonPredicate `json:"predicate"`
+	}{}
+	if err := json.Unmarshal(p, &statement); err != nil {
+		return err
+	}
+	predicate := statement.Predicate
+
+	// Chains records some of the step images in the materials too, as
+	// `oci://<repository>` with their digest
+	seen := map[string]bool{}
+	for _, mat := range prov.materials {
+		for alg, ds := range mat.digest {
+			seen[common.NormalizeDigest(alg, ds)] = true
+		}
+	}
+	addMaterial := func(ref string, digest map[string]string) {
+		if digest == nil {
+			digest = imageDigest(ref)
+		}
+		for alg, ds := range digest {
+			if seen[common.NormalizeDigest(alg, ds)] {
+				return
+			}
+			seen[common.NormalizeDigest(alg, ds)] = true
+		}
+		if len(digest) > 0 {
+			prov.materials = append(prov.materials, material{uri: ref, digest: digest})
+		}
+	}
+	addSteps := func(steps []tektonStep) {
+		for _, step := range steps {
+			addMaterial(step.Environment.Image, nil)
+		}
+	}
+
+	// A TaskRun references its task by the invocation, a PipelineRun by
+	// the ref of each of its tasks
+	if src := predicate.Invocation.ConfigSource; src.EntryPoint != "" && len(predicate.BuildConfig.Tasks) == 0 {
+		id := src.EntryPoint
+		if src.URI != "" {
+			id = src.URI + "#" + src.EntryPoint
+			addMaterial(src.URI, src.Digest)
+		}
+		prov.tasks = append(prov.tasks, task{kind: "Task", id: id})
+	}
+	addSteps(predicate.BuildConfig.Steps)
+	for _, t := range predicate.BuildConfig.Tasks {
+		name := t.Ref.Name
+		if name == "" {
+			// embedded task spec
+			name = t.Name
+		}
+		kind := t.Ref.Kind
+		if kind == "" {
+			kind = "Task"
+		}
+		id := name
+		if t.Ref.Bundle != "" {
+			id = t.Ref.Bundle + "#" + name
+			addMaterial(t.Ref.Bundle, nil)
+		}
+		prov.tasks = append(prov.tasks, task{kind: kind, id: id})
+		addSteps(t.Steps)
+	}
+	return nil
+}
+
+// imageDigest returns the digest of the image reference (e.g.
+// `gcr.io/distroless/base@sha256:...`), or nil if it is not pinned
+func imageDigest(ref string) map[string]string {
+	_, digest, ok := strings.Cut(ref, "@")
+	if !ok {
+		return nil
+	}
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || value == "" {
+		return nil
+	}
+	return map[string]string{alg: value}
+}
@@ -16,17 +16,17 @@
 package spdx
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/guacsec/guac/pkg/assembler"
 	"github.com/guacsec/guac/pkg/handler/processor"
+	spdx_processor "github.com/guacsec/guac/pkg/handler/processor/spdx"
 	"github.com/guacsec/guac/pkg/ingestor/parser/common"
 	"github.com/guacsec/guac/pkg/logging"
-	spdx_json "github.com/spdx/tools-golang/json"
 	spdx_common "github.com/spdx/tools-golang/spdx/common"
 	"github.com/spdx/tools-golang/spdx/v2_2"
 )
@@ -35,19 +35,29 @@ type spdxParser struct {
 	doc      *processor.Document
 	packages map[string][]assembler.PackageNode
 	files    map[string][]assembler.ArtifactNode
+	// packageArtifacts are the artifacts described by the checksums of
+	// a package
+	packageArtifacts map[string][]assembler.ArtifactNode
+	// sources are the repositories and commits the packages are built from
+	sources map[string][]common.BuildSource
+	// licenses are the licenses declared by the packages
+	licenses map[string]common.License
 	spdxDoc  *v2_2.Document
 }
 
 func NewSpdxParser() common.DocumentParser {
 	return &spdxParser{
-		packages: map[string][]assembler.PackageNode{},
-		files:    map[string][]assembler.ArtifactNode{},
+		packages:         map[string][]assembler.PackageNode{},
+		files:            map[string][]assembler.ArtifactNode{},
+		packageArtifacts: map[string][]assembler.ArtifactNode{},
+		sources:          map[string][]common.BuildSource{},
+		licenses:         map[string]common.License{},
 	}
 }
 
 func (s *spdxParser) Parse(ctx context.Context, doc *processor.Document) error {
 	s.doc = doc
-	spdxDoc, err := parseSpdxBlob(doc.Blob)
+	spdxDoc, err := parseSpdxBlob(doc.Blob, doc.Format)
 	if err != nil {
 		return fmt.Errorf("failed to parse SPDX document: %w", err)
 	}
@@ -63,18 +73,18 @@ func (s *spdxParser) getTopLevelPackage() {
 	splitImage := strings.Split(s.spdxDoc.DocumentName, "/")
 	if len(splitImage) == 3 {
 		topPackage := assembler.PackageNode{}
-		topPackage.Purl = "pkg:oci/" + splitImage[2] + "?repository_url=" + splitImage[0] + "/" + splitImage[1]
+		topPackage.Purl = common.NormalizePurl("pkg:oci/" + splitImage[2] + "?repository_url=" + splitImage[0] + "/" + splitImage[1])
 		topPackage.Name = s.spdxDoc.DocumentName
 		topPackage.Tags = []string{"CONTAINER"}
 		topPackage.NodeData = *assembler.NewObjectMetadata(s.doc.SourceInformation)
-		s.packages[string(s.spdxDoc.SPDXIdentifier)] = append(s.packages[string(s.spdxDoc.SPDXIdentifier)], topPackage)
+		s.packages[spdxRef(s.spdxDoc.SPDXIdentifier)] = append(s.packages[spdxRef(s.spdxDoc.SPDXIdentifier)], topPackage)
 	} else if len(splitImage) == 2 {
 		topPackage := assembler.PackageNode{}
-		topPackage.Purl = "pkg:oci/" + splitImage[1] + "?repository_url=" + splitImage[0]
+		topPackage.Purl = common.NormalizePurl("pkg:oci/" + splitImage[1] + "?repository_url=" + splitImage[0])
 		topPackage.Name = s.spdxDoc.DocumentName
 		topPackage.Tags = []string{"CONTAINER"}
 		topPackage.NodeData = *assembler.NewObjectMetadata(s.doc.SourceInformation)
-		s.packages[string(s.spdxDoc.SPDXIdentifier)] = append(s.packages[string(s.spdxDoc.SPDXIdentifier)], topPackage)
+		s.packages[spdxRef(s.spdxDoc.SPDXIdentifier)] = append(s.packages[spdxRef(s.spdxDoc.SPDXIdentifier)], topPackage)
 	}
 }
 
@@ -85,19 +95,60 @@ func (s *spdxParser) getPackages() {
 		currentPackage.Name = pac.PackageName
 		currentPackage.NodeData = *assembler.NewObjectMetadata(s.doc.SourceInformation)
 		currentPackage.Version = pac.PackageVersion
+		if ref, ok := common.ParseVCS(pac.PackageDownloadLocation, ""); ok {
+			s.addSource(pac.PackageSPDXIdentifier, ref)
+		}
 		for _, ext := range pac.PackageExternalReferences {
 			if strings.HasPrefix(ext.RefType, "cpe") {
 				currentPackage.CPEs = append(currentPackage.CPEs, ext.Locator)
 			} else if ext.RefType == spdx_common.TypePackageManagerPURL {
-				currentPackage.Purl = ext.Locator
+				currentPackage.Purl = common.NormalizePurl(ext.Locator)
+			} else if strings.EqualFold(ext.RefType, "vcs") {
+				if ref, ok := common.ParseVCS(ext.Locator, "git"); ok {
+					s.addSource(pac.PackageSPDXIdentifier, ref)
+				}
 			}
 		}
 		for _, checksum := range pac.PackageChecksums {
-			currentPackage.Digest = append(currentPackage.Digest, strings.ToLower(string(checksum.Algorithm))+":"+checksum.Value)
+			digest := common.NormalizeDigest(string(checksum.Algorithm), checksum.Value)
+			currentPackage.Digest = append(currentPackage.Digest, digest)
+			packageArtifact := assembler.ArtifactNode{
+				Name:     pac.PackageFileName,
+				Digest:   digest,
+				NodeData: *assembler.NewObjectMetadata(s.doc.SourceInformation),
+			}
+			if packageArtifact.Name == "" {
+				packageArtifact.Name = pac.PackageName
+			}
+			s.packageArtifacts[spdxRef(pac.PackageSPDXIdentifier)] = append(s.packageArtifacts[spdxRef(pac.PackageSPDXIdentifier)], packageArtifact)
 		}
 		currentPackage.Tags = getPackageTags(currentPackage)
-		s.packages[string(pac.PackageSPDXIdentifier)] = append(s.packages[string(pac.PackageSPDXIdentifier)], currentPackage)
+		if common.HasLicense(pac.PackageLicenseDeclared) {
+			s.licenses[spdxRef(pac.PackageSPDXIdentifier)] = common.NewLicense(pac.PackageLicenseDeclared, s.doc.SourceInformation)
+		}
+		s.packages[spdxRef(pac.PackageSPDXIdentifier)] = append(s.packages[spdxRef(pac.PackageSPDXIdentifier)], currentPackage)
+	}
+}
+
+// addSource records that the package is built from ref, unless the same
+// repository and revision were already given by another reference
+func (s *spdxParser) addSource(id spdx_common.ElementID, ref common.VCSReference) {
+	src := common.NewBuildSource(ref, "", s.doc.SourceInformation)
+	for _, existing := range s.sources[spdxRef(id)] {
+		if reflect.DeepEqual(existing, src) {
+			return
+		}
 	}
+	s.sources[spdxRef(id)] = append(s.sources[spdxRef(id)], src)
+}
+
+// spdxRef returns the element ID with its "SPDXRef-" prefix. The JSON loader
+// keeps the prefix while the tag-value one strips it.
+func spdxRef(id spdx_common.ElementID) string {
+	if strings.HasPrefix(string(id), "SPDXRef-") {
+		return string(id)
+	}
+	return "SPDXRef-" + string(id)
 }
 
 func getPackageTags(p assembler.PackageNode) []string {
@@ -112,10 +163,10 @@ func (s *spdxParser) getFiles() {
 		currentFile := assembler.ArtifactNode{}
 		for _, checksum := range file.Checksums {
 			currentFile.Name = file.FileName
-			currentFile.Digest = strings.ToLower(string(checksum.Algorithm)) + ":" + checksum.Value
+			currentFile.Digest = common.NormalizeDigest(string(checksum.Algorithm), checksum.Value)
 			currentFile.Tags = getTags(file)
 			currentFile.NodeData = *assembler.NewObjectMetadata(s.doc.SourceInformation)
-			s.files[string(file.FileSPDXIdentifier)] = append(s.files[string(file.FileSPDXIdentifier)], currentFile)
+			s.files[spdxRef(file.FileSPDXIdentifier)] = append(s.files[spdxRef(file.FileSPDXIdentifier)], currentFile)
 		}
 	}
 }
@@ -124,9 +175,11 @@ func getTags(f *v2_2.File) []string {
 	return f.FileTypes
 }
 
-func parseSpdxBlob(p []byte) (*v2_2.Document, error) {
-	reader := bytes.NewReader(p)
-	spdx, err := spdx_json.Load2_2(reader)
+func parseSpdxBlob(p []byte, format processor.FormatType) (*v2_2.Document, error) {
+	if format == processor.FormatUnknown {
+		format = processor.FormatJSON
+	}
+	spdx, err := spdx_processor.LoadDocument(p, format)
 	if err != nil {
 		return nil, err
 	}
@@ -145,9 +198,36 @@ func (s *spdxParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
 			nodes = append(nodes, fileNode)
 		}
 	}
+	for _, artifactNodes := range s.packageArtifacts {
+		for _, artifactNode := range artifactNodes {
+			nodes = append(nodes, artifactNode)
+		}
+	}
+	for _, sources := range s.sources {
+		for _, src := range sources {
+			nodes = append(nodes, src.Nodes()...)
+		}
+	}
+	for _, l := range uniqueLicenses(s.licenses) {
+		nodes = append(nodes, l.Nodes()...)
+	}
 	return nodes
 }
 
+// uniqueLicenses returns the licenses, once each even when declared by
+// several packages
+func uniqueLicenses(licenses map[string]common.License) []common.License {
+	unique := []common.License{}
+	seen := map[string]bool{}
+	for _, l := range licenses {
+		if !seen[l.Node.Expression] {
+			seen[l.Node.Expression] = true
+			unique = append(unique, l)
+		}
+	}
+	return unique
+}
+
 func (s *spdxParser) getPackageElement(elementID string) []assembler.PackageNode {
 	if packNode, ok := s.packages[string(elementID)]; ok {
 		return packNode
@@ -170,11 +250,42 @@ func (s *spdxParser) CreateEdges(ctx context.Context, foundIdentities []assemble
 	if toplevel != nil {
 		edges = append(edges, createTopLevelEdges(toplevel[0], s.packages, s.files)...)
 	}
+	// packages contain the artifacts identified by their checksums
+	for id, artifactNodes := range s.packageArtifacts {
+		for _, packNode := range s.packages[id] {
+			for _, artifactNode := range artifactNodes {
+				edges = append(edges, assembler.ContainsEdge{
+					PackageNode:       packNode,
+					ContainedArtifact: artifactNode,
+				})
+			}
+		}
+	}
+	// packages are built from the repositories given by their download
+	// location or VCS references
+	for id, sources := range s.sources {
+		for _, src := range sources {
+			for _, packNode := range s.packages[id] {
+				edges = append(edges, src.BuiltFrom(packNode))
+			}
+			edges = append(edges, src.Edges()...)
+		}
+	}
+	// packages declare their licenses, whose compound expressions are
+	// linked to their operands
+	for id, l := range s.licenses {
+		for _, packNode := range s.packages[id] {
+			edges = append(edges, l.Declared(packNode))
+		}
+	}
+	for _, l := range uniqueLicenses(s.licenses) {
+		edges = append(edges, l.Edges()...)
+	}
 	for _, rel := range s.spdxDoc.Relationships {
-		foundPackNodes := s.getPackageElement("SPDXRef-" + string(rel.RefA.ElementRefID))
-		foundFileNodes := s.getFileElement("SPDXRef-" + string(rel.RefA.ElementRefID))
-		relatedPackNodes := s.getPackageElement("SPDXRef-" + string(rel.RefB.ElementRefID))
-		relatedFileNodes := s.getFileElement("SPDXRef-" + string(rel.RefB.ElementRefID))
+		foundPackNodes := s.getPackageElement(spdxRef(rel.RefA.ElementRefID))
+		foundFileNodes := s.getFileElement(spdxRef(rel.RefA.ElementRefID))
+		relatedPackNodes := s.getPackageElement(spdxRef(rel.RefB.ElementRefID))
+		relatedFileNodes := s.getFileElement(spdxRef(rel.RefB.ElementRefID))
 		for _, packNode := range foundPackNodes {
 			createdEdge, err := getEdge(packNode, rel.Relationship, relatedPackNodes, relatedFileNodes)
 			if err != nil {
@@ -245,6 +356,10 @@ func getEdgeByType(relationship string, foundNode assembler.GuacNode, relatedNod
 		return getContainsEdge(foundNode, relatedNode)
 	case spdx_common.TypeRelationshipDependsOn:
 		return getDependsOnEdge(foundNode, relatedNode), nil
+	case spdx_common.TypeRelationshipGeneratedFrom:
+		return getGeneratedFromEdge(foundNode, relatedNode), nil
+	case spdx_common.TypeRelationshipStaticLink:
+		return getStaticLinkEdge(foundNode, relatedNode), nil
 	}
 	return nil, nil
 }
@@ -280,6 +395,38 @@ func getDependsOnEdge(foundNode assembler.GuacNode, relatedNode assembler.GuacNo
 	return e
 }
 
+func getGeneratedFromEdge(foundNode assembler.GuacNode, relatedNode assembler.GuacNode) assembler.GuacEdge {
+	e := assembler.GeneratedFromEdge{}
+	if foundNode.Type() == "Package" {
+		e.PackageNode = foundNode.(assembler.PackageNode)
+	} else {
+		e.ArtifactNode = foundNode.(assembler.ArtifactNode)
+	}
+
+	if relatedNode.Type() == "Package" {
+		e.PackageSource = relatedNode.(assembler.PackageNode)
+	} else {
+		e.ArtifactSource = relatedNode.(assembler.ArtifactNode)
+	}
+	return e
+}
+
+func getStaticLinkEdge(foundNode assembler.GuacNode, relatedNode assembler.GuacNode) assembler.GuacEdge {
+	e := assembler.StaticLinkEdge{}
+	if foundNode.Type() == "Package" {
+		e.PackageNode = foundNode.(assembler.PackageNode)
+	} else {
+		e.ArtifactNode = foundNode.(assembler.ArtifactNode)
+	}
+
+	if relatedNode.Type() == "Package" {
+		e.LinkedPackage = relatedNode.(assembler.PackageNode)
+	} else {
+		e.LinkedArtifact = relatedNode.(assembler.ArtifactNode)
+	}
+	return e
+}
+
 func (s *spdxParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
 	return nil
 }
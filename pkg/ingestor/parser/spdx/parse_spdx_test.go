@@ -47,6 +47,20 @@ func Test_spdxParser(t *testing.T) {
 		wantNodes: testdata.SpdxNodes,
 		wantEdges: testdata.SpdxEdges,
 		wantErr:   false,
+	}, {
+		name: "SPDX 2.3 tag-value document with relationships",
+		doc: &processor.Document{
+			Blob:   testdata.SpdxTagValueExample,
+			Format: processor.FormatTagValue,
+			Type:   processor.DocumentSPDX,
+			SourceInformation: processor.SourceInformation{
+				Collector: "TestCollector",
+				Source:    "TestSource",
+			},
+		},
+		wantNodes: testdata.SpdxTagValueNodes,
+		wantEdges: testdata.SpdxTagValueEdges,
+		wantErr:   false,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
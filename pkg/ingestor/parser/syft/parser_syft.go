@@ -0,0 +1,162 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Syft parser parses the native JSON documents of Syft
+// (https://github.com/anchore/syft), without going through a lossy
+// conversion to SPDX or CycloneDX.
+//
+// Each package found by Syft with a purl becomes a package node; packages
+// without a purl (e.g. unidentified binaries) are skipped. When the scanned
+// source is a container image pinned by digest, it becomes an OCI package
+// node depending on all the packages. "dependency-of" relationships between
+// packages become "DependsOn" edges.
+package syft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/syft"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type syftParser struct {
+	root     *assembler.PackageNode
+	packages []assembler.PackageNode
+	depends  []assembler.DependsOnEdge
+}
+
+// NewSyftParser initializes the syftParser
+func NewSyftParser() common.DocumentParser {
+	return &syftParser{
+		packages: []assembler.PackageNode{},
+		depends:  []assembler.DependsOnEdge{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *syftParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentSyft {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentSyft, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of Syft document format: %v", doc.Format)
+	}
+
+	var sbom syft.Document
+	if err := json.Unmarshal(doc.Blob, &sbom); err != nil {
+		return err
+	}
+
+	p.root = rootPackage(sbom.Source, doc.SourceInformation)
+
+	byID := map[string]assembler.PackageNode{}
+	for _, a := range sbom.Artifacts {
+		purl := common.NormalizePurl(a.PURL)
+		if purl == "" {
+			logger.Debugf("skipping Syft package %s, no purl", a.Name)
+			continue
+		}
+		pkg := assembler.PackageNode{
+			Name:     a.Name,
+			Version:  a.Version,
+			Purl:     purl,
+			NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+		}
+		for _, c := range a.CPEs {
+			pkg.CPEs = append(pkg.CPEs, c.CPE)
+		}
+		p.packages = append(p.packages, pkg)
+		byID[a.ID] = pkg
+		if p.root != nil {
+			p.depends = append(p.depends, assembler.DependsOnEdge{PackageNode: *p.root, PackageDependency: pkg})
+		}
+	}
+
+	for _, r := range sbom.ArtifactRelationships {
+		if r.Type != syft.RelationshipDependencyOf {
+			continue
+		}
+		// the parent is a dependency of the child, relationships with
+		// files or skipped packages are ignored
+		dependency, okParent := byID[r.Parent]
+		dependent, okChild := byID[r.Child]
+		if okParent && okChild {
+			p.depends = append(p.depends, assembler.DependsOnEdge{PackageNode: dependent, PackageDependency: dependency})
+		}
+	}
+	return nil
+}
+
+// rootPackage returns the package node of the container image that was
+// scanned, or nil if the source is not an image pinned by digest
+func rootPackage(source syft.Source, srcInfo processor.SourceInformation) *assembler.PackageNode {
+	image := source.Image()
+	if image == nil {
+		return nil
+	}
+	for _, repoDigest := range image.RepoDigests {
+		purl := common.OCIPurl(repoDigest)
+		if purl == "" {
+			continue
+		}
+		root := assembler.PackageNode{
+			Name:     source.Name,
+			Purl:     purl,
+			Tags:     []string{"CONTAINER"},
+			NodeData: *assembler.NewObjectMetadata(srcInfo),
+		}
+		if root.Name == "" {
+			root.Name = image.UserInput
+		}
+		if image.ManifestDigest != "" {
+			root.Digest = []string{image.ManifestDigest}
+		}
+		return &root
+	}
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *syftParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	if p.root != nil {
+		nodes = append(nodes, *p.root)
+	}
+	for _, pkg := range p.packages {
+		nodes = append(nodes, pkg)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *syftParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, d := range p.depends {
+		edges = append(edges, d)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *syftParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,158 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syft
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_syftParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	image := assembler.PackageNode{
+		Name:     "alpine",
+		Purl:     "pkg:oci/alpine@sha256:65a2763f593ae85fab3b5406dc9e80f744ec5b449f269b699b5efd37a07ad32e?repository_url=index.docker.io/library/alpine",
+		Digest:   []string{"sha256:2a3a2c4c3bd8e5d4a6ac16ef10fbd8c4ce9c8ee1bd7b38bd2d0a6a7c0e7c1d1e"},
+		Tags:     []string{"CONTAINER"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	busybox := assembler.PackageNode{
+		Name:     "busybox",
+		Version:  "1.35.0-r17",
+		Purl:     "pkg:apk/alpine/busybox@1.35.0-r17?arch=x86_64&distro=alpine-3.16.2",
+		CPEs:     []string{"cpe:2.3:a:busybox:busybox:1.35.0-r17:*:*:*:*:*:*:*"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	musl := assembler.PackageNode{
+		Name:     "musl",
+		Version:  "1.2.3-r0",
+		Purl:     "pkg:apk/alpine/musl@1.2.3-r0?arch=x86_64&distro=alpine-3.16.2",
+		CPEs:     []string{"cpe:2.3:a:musl-libc:musl:1.2.3-r0:*:*:*:*:*:*:*"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	zlib := assembler.PackageNode{
+		Name:     "zlib",
+		Version:  "1.2.12-r1",
+		Purl:     "pkg:apk/alpine/zlib@1.2.12-r1?arch=x86_64&distro=alpine-3.16.2",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	legacy := assembler.PackageNode{
+		Name:     "lib",
+		Version:  "2.0.0",
+		Purl:     "pkg:npm/lib@2.0.0",
+		CPEs:     []string{"cpe:2.3:a:lib:lib:2.0.0:*:*:*:*:*:*:*"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	legacyImage := assembler.PackageNode{
+		Name:     "example.com/app:1.0",
+		Purl:     "pkg:oci/app@sha256:1111111111111111111111111111111111111111111111111111111111111111?repository_url=example.com/app",
+		Tags:     []string{"CONTAINER"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "image document",
+		doc: &processor.Document{
+			Blob:              testdata.SyftExample,
+			Type:              processor.DocumentSyft,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{image, busybox, musl, zlib},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: busybox},
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: musl},
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: zlib},
+			assembler.DependsOnEdge{PackageNode: busybox, PackageDependency: musl},
+			assembler.DependsOnEdge{PackageNode: zlib, PackageDependency: musl},
+		},
+	}, {
+		name: "legacy image document",
+		doc: &processor.Document{
+			Blob: []byte(`{
+				"descriptor": {"name": "syft"},
+				"artifacts": [{"id": "1", "name": "lib", "version": "2.0.0", "purl": "pkg:npm/lib@2.0.0",
+					"cpes": ["cpe:2.3:a:lib:lib:2.0.0:*:*:*:*:*:*:*"]}],
+				"source": {"type": "image", "target": {"userInput": "example.com/app:1.0",
+					"repoDigests": ["example.com/app@sha256:1111111111111111111111111111111111111111111111111111111111111111"]}}
+			}`),
+			Type:              processor.DocumentSyft,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{legacyImage, legacy},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DependsOnEdge{PackageNode: legacyImage, PackageDependency: legacy},
+		},
+	}, {
+		name: "directory document",
+		doc: &processor.Document{
+			Blob: []byte(`{
+				"descriptor": {"name": "syft"},
+				"artifacts": [{"id": "1", "name": "lib", "version": "2.0.0", "purl": "pkg:npm/lib@2.0.0",
+					"cpes": ["cpe:2.3:a:lib:lib:2.0.0:*:*:*:*:*:*:*"]}],
+				"source": {"type": "directory", "target": "/src"}
+			}`),
+			Type:              processor.DocumentSyft,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{legacy},
+		wantEdges: []assembler.GuacEdge{},
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.SyftExample,
+			Type:              processor.DocumentSyft,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSyftParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syftParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("syftParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("syftParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Trivy parser parses the native JSON reports of Trivy
+// (https://github.com/aquasecurity/trivy), without going through a lossy
+// conversion to SPDX or CycloneDX.
+//
+// Each package of the report with a purl becomes a package node (packages
+// are only listed with `--list-all-pkgs`), linked to its dependencies via
+// "DependsOn" edges. Each detected vulnerability becomes a vulnerability node
+// linked to the vulnerable package via an "Affects" edge storing the
+// installed version. Packages and vulnerabilities without a purl are
+// skipped. When the scanned artifact is a container image pinned by digest,
+// it becomes an OCI package node depending on all the packages.
+package trivy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/trivy"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+type trivyParser struct {
+	root     *assembler.PackageNode
+	packages []assembler.PackageNode
+	depends  []assembler.DependsOnEdge
+	vulns    []assembler.VulnerabilityNode
+	affects  []assembler.AffectsEdge
+
+	// purls of the packages the root already depends on
+	rootDeps map[string]bool
+}
+
+// NewTrivyParser initializes the trivyParser
+func NewTrivyParser() common.DocumentParser {
+	return &trivyParser{
+		packages: []assembler.PackageNode{},
+		depends:  []assembler.DependsOnEdge{},
+		vulns:    []assembler.VulnerabilityNode{},
+		affects:  []assembler.AffectsEdge{},
+		rootDeps: map[string]bool{},
+	}
+}
+
+// Parse breaks out the document into the graph components
+func (p *trivyParser) Parse(ctx context.Context, doc *processor.Document) error {
+	logger := logging.FromContext(ctx)
+
+	if doc.Type != processor.DocumentTrivy {
+		return fmt.Errorf("expected document type: %v, actual document type: %v", processor.DocumentTrivy, doc.Type)
+	}
+	if doc.Format != processor.FormatJSON {
+		return fmt.Errorf("unable to support parsing of Trivy document format: %v", doc.Format)
+	}
+
+	var report trivy.Report
+	if err := json.Unmarshal(doc.Blob, &report); err != nil {
+		return err
+	}
+	if report.SchemaVersion != trivy.SchemaVersion {
+		return fmt.Errorf("unsupported Trivy report schema version %d, expected %d", report.SchemaVersion, trivy.SchemaVersion)
+	}
+
+	p.root = rootPackage(report, doc.SourceInformation)
+
+	seenVulns := map[string]bool{}
+	for _, r := range report.Results {
+		// package IDs are only unique within a result
+		byID := map[string]assembler.PackageNode{}
+		for _, pkg := range r.Packages {
+			purl := common.NormalizePurl(pkg.Identifier.PURL)
+			if purl == "" {
+				logger.Debugf("skipping Trivy package %s of %s, no purl", pkg.ID, r.Target)
+				continue
+			}
+			node := assembler.PackageNode{
+				Name:     pkg.Name,
+				Version:  pkg.Version,
+				Purl:     purl,
+				NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+			}
+			p.packages = append(p.packages, node)
+			p.addRootDependency(node)
+			byID[pkg.ID] = node
+		}
+		for _, pkg := range r.Packages {
+			dependent, ok := byID[pkg.ID]
+			if !ok {
+				continue
+			}
+			for _, id := range pkg.DependsOn {
+				if dependency, ok := byID[id]; ok {
+					p.depends = append(p.depends, assembler.DependsOnEdge{PackageNode: dependent, PackageDependency: dependency})
+				}
+			}
+		}
+
+		for _, v := range r.Vulnerabilities {
+			node, ok := byID[v.PkgID]
+			if purl := common.NormalizePurl(v.PkgIdentifier.PURL); purl != "" {
+				node = assembler.PackageNode{
+					Name:     v.PkgName,
+					Version:  v.InstalledVersion,
+					Purl:     purl,
+					NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+				}
+			} else if !ok {
+				logger.Debugf("skipping %s of Trivy package %s, no purl", v.VulnerabilityID, v.PkgName)
+				continue
+			}
+			p.addRootDependency(node)
+
+			vuln := assembler.VulnerabilityNode{
+				ID:       v.VulnerabilityID,
+				NodeData: *assembler.NewObjectMetadata(doc.SourceInformation),
+			}
+			if !seenVulns[vuln.ID] {
+				seenVulns[vuln.ID] = true
+				p.vulns = append(p.vulns, vuln)
+			}
+			p.affects = append(p.affects, assembler.AffectsEdge{
+				VulnerabilityNode: vuln,
+				PackageNode:       node,
+				Versions:          []string{v.InstalledVersion},
+			})
+		}
+	}
+	return nil
+}
+
+// addRootDependency makes the root depend on the package, once
+func (p *trivyParser) addRootDependency(pkg assembler.PackageNode) {
+	if p.root == nil || p.rootDeps[pkg.Purl] {
+		return
+	}
+	p.rootDeps[pkg.Purl] = true
+	p.depends = append(p.depends, assembler.DependsOnEdge{PackageNode: *p.root, PackageDependency: pkg})
+}
+
+// rootPackage returns the package node of the container image that was
+// scanned, or nil if the artifact is not an image pinned by digest
+func rootPackage(report trivy.Report, srcInfo processor.SourceInformation) *assembler.PackageNode {
+	if report.ArtifactType != trivy.ArtifactTypeContainerImage {
+		return nil
+	}
+	for _, repoDigest := range report.Metadata.RepoDigests {
+		if purl := common.OCIPurl(repoDigest); purl != "" {
+			return &assembler.PackageNode{
+				Name:     report.ArtifactName,
+				Purl:     purl,
+				Tags:     []string{"CONTAINER"},
+				NodeData: *assembler.NewObjectMetadata(srcInfo),
+			}
+		}
+	}
+	return nil
+}
+
+// CreateNodes creates the GuacNode for the graph inputs
+func (p *trivyParser) CreateNodes(ctx context.Context) []assembler.GuacNode {
+	nodes := []assembler.GuacNode{}
+	if p.root != nil {
+		nodes = append(nodes, *p.root)
+	}
+	for _, pkg := range p.packages {
+		nodes = append(nodes, pkg)
+	}
+	for _, v := range p.vulns {
+		nodes = append(nodes, v)
+	}
+	for _, a := range p.affects {
+		nodes = append(nodes, a.PackageNode)
+	}
+	return nodes
+}
+
+// CreateEdges creates the GuacEdges that form the relationship for the graph inputs
+func (p *trivyParser) CreateEdges(ctx context.Context, foundIdentities []assembler.IdentityNode) []assembler.GuacEdge {
+	edges := []assembler.GuacEdge{}
+	for _, d := range p.depends {
+		edges = append(edges, d)
+	}
+	for _, a := range p.affects {
+		edges = append(edges, a)
+	}
+	return edges
+}
+
+// GetIdentities gets the identity node from the document if they exist
+func (p *trivyParser) GetIdentities(ctx context.Context) []assembler.IdentityNode {
+	return nil
+}
@@ -0,0 +1,162 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trivy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/assembler"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func Test_trivyParser(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	srcInfo := processor.SourceInformation{
+		Collector: "TestCollector",
+		Source:    "TestSource",
+	}
+	image := assembler.PackageNode{
+		Name:     "alpine:3.16.2",
+		Purl:     "pkg:oci/alpine@sha256:65a2763f593ae85fab3b5406dc9e80f744ec5b449f269b699b5efd37a07ad32e?repository_url=index.docker.io/library/alpine",
+		Tags:     []string{"CONTAINER"},
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	busybox := assembler.PackageNode{
+		Name:     "busybox",
+		Version:  "1.35.0-r17",
+		Purl:     "pkg:apk/alpine/busybox@1.35.0-r17?arch=x86_64&distro=3.16.2",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	musl := assembler.PackageNode{
+		Name:     "musl",
+		Version:  "1.2.3-r0",
+		Purl:     "pkg:apk/alpine/musl@1.2.3-r0?arch=x86_64&distro=3.16.2",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	zlib := assembler.PackageNode{
+		Name:     "zlib",
+		Version:  "1.2.12-r1",
+		Purl:     "pkg:apk/alpine/zlib@1.2.12-r1?arch=x86_64&distro=3.16.2",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	semver := assembler.PackageNode{
+		Name:     "semver",
+		Version:  "7.3.7",
+		Purl:     "pkg:npm/semver@7.3.7",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	zlibVuln := assembler.VulnerabilityNode{
+		ID:       "CVE-2022-37434",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	busyboxVuln := assembler.VulnerabilityNode{
+		ID:       "CVE-2022-30065",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+	semverVuln := assembler.VulnerabilityNode{
+		ID:       "GHSA-c2qf-rxjj-qqgw",
+		NodeData: *assembler.NewObjectMetadata(srcInfo),
+	}
+
+	tests := []struct {
+		name      string
+		doc       *processor.Document
+		wantNodes []assembler.GuacNode
+		wantEdges []assembler.GuacEdge
+		wantErr   bool
+	}{{
+		name: "image report",
+		doc: &processor.Document{
+			Blob:              testdata.TrivyExample,
+			Type:              processor.DocumentTrivy,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{
+			image, busybox, musl, zlib, zlibVuln, busyboxVuln, semverVuln,
+			zlib, busybox, semver,
+		},
+		wantEdges: []assembler.GuacEdge{
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: busybox},
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: musl},
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: zlib},
+			assembler.DependsOnEdge{PackageNode: image, PackageDependency: semver},
+			assembler.DependsOnEdge{PackageNode: busybox, PackageDependency: musl},
+			assembler.DependsOnEdge{PackageNode: zlib, PackageDependency: musl},
+			assembler.AffectsEdge{VulnerabilityNode: zlibVuln, PackageNode: zlib, Versions: []string{"1.2.12-r1"}},
+			assembler.AffectsEdge{VulnerabilityNode: busyboxVuln, PackageNode: busybox, Versions: []string{"1.35.0-r17"}},
+			assembler.AffectsEdge{VulnerabilityNode: semverVuln, PackageNode: semver, Versions: []string{"7.3.7"}},
+		},
+	}, {
+		name: "filesystem report",
+		doc: &processor.Document{
+			Blob: []byte(`{
+				"SchemaVersion": 2,
+				"ArtifactName": ".",
+				"ArtifactType": "filesystem",
+				"Results": [{"Target": "package-lock.json", "Vulnerabilities": [{
+					"VulnerabilityID": "GHSA-c2qf-rxjj-qqgw", "PkgName": "semver",
+					"PkgIdentifier": {"PURL": "pkg:npm/semver@7.3.7"}, "InstalledVersion": "7.3.7"}]}]
+			}`),
+			Type:              processor.DocumentTrivy,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantNodes: []assembler.GuacNode{semverVuln, semver},
+		wantEdges: []assembler.GuacEdge{
+			assembler.AffectsEdge{VulnerabilityNode: semverVuln, PackageNode: semver, Versions: []string{"7.3.7"}},
+		},
+	}, {
+		name: "unsupported schema version",
+		doc: &processor.Document{
+			Blob:              []byte(`{"SchemaVersion": 1, "ArtifactName": ".", "ArtifactType": "filesystem"}`),
+			Type:              processor.DocumentTrivy,
+			Format:            processor.FormatJSON,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}, {
+		name: "wrong format",
+		doc: &processor.Document{
+			Blob:              testdata.TrivyExample,
+			Type:              processor.DocumentTrivy,
+			Format:            processor.FormatUnknown,
+			SourceInformation: srcInfo,
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTrivyParser()
+			err := p.Parse(ctx, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("trivyParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if nodes := p.CreateNodes(ctx); !testdata.GuacNodeSliceEqual(nodes, tt.wantNodes) {
+				t.Errorf("trivyParser.CreateNodes() = %v, want %v", nodes, tt.wantNodes)
+			}
+			if edges := p.CreateEdges(ctx, nil); !testdata.GuacEdgeSliceEqual(edges, tt.wantEdges) {
+				t.Errorf("trivyParser.CreateEdges() = %v, want %v", edges, tt.wantEdges)
+			}
+		})
+	}
+}
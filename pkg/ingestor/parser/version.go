@@ -0,0 +1,185 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/common"
+)
+
+// SpecVersionDetector returns the version of the specification a document
+// follows, e.g. `2.3` for an SPDX 2.3 document
+type SpecVersionDetector func(doc *processor.Document) (string, error)
+
+// parserKey identifies the documents a versioned parser supports
+type parserKey struct {
+	docType     processor.DocumentType
+	format      processor.FormatType
+	specVersion string
+}
+
+var (
+	versionedParsers     = map[parserKey]func() common.DocumentParser{}
+	specVersionDetectors = map[processor.DocumentType]SpecVersionDetector{}
+)
+
+// RegisterVersionedDocumentParser registers the parser of the documents of
+// type d in format f following one of the given spec versions. The documents
+// of a type with versioned parsers are dispatched on their spec version, as
+// returned by the SpecVersionDetector registered for the type, so a type
+// cannot also have a parser registered with RegisterDocumentParser.
+func RegisterVersionedDocumentParser(p func() common.DocumentParser, d processor.DocumentType, f processor.FormatType, versions ...string) error {
+	if _, ok := documentParser[d]; ok {
+		return fmt.Errorf("the document parser is being overwritten: %s", d)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no spec version given for the document parser: %s", d)
+	}
+	for _, v := range versions {
+		if _, ok := versionedParsers[parserKey{d, f, v}]; ok {
+			return fmt.Errorf("the document parser is being overwritten: %s %s %s", d, f, v)
+		}
+	}
+	for _, v := range versions {
+		versionedParsers[parserKey{d, f, v}] = p
+	}
+	return nil
+}
+
+// RegisterSpecVersionDetector registers the detector of the spec version of
+// the documents of type d, needed to dispatch them to their versioned parser
+func RegisterSpecVersionDetector(detector SpecVersionDetector, d processor.DocumentType) error {
+	if _, ok := specVersionDetectors[d]; ok {
+		return fmt.Errorf("the spec version detector is being overwritten: %s", d)
+	}
+	specVersionDetectors[d] = detector
+	return nil
+}
+
+// hasVersionedParsers returns whether parsers were registered for specific
+// spec versions of the document type
+func hasVersionedParsers(d processor.DocumentType) bool {
+	for key := range versionedParsers {
+		if key.docType == d {
+			return true
+		}
+	}
+	return false
+}
+
+// versionedParser returns the parser registered for the format and spec
+// version of the document. The error lists the supported formats or
+// versions when there is none.
+func versionedParser(doc *processor.Document) (func() common.DocumentParser, error) {
+	formats := map[processor.FormatType][]string{}
+	for key := range versionedParsers {
+		if key.docType == doc.Type {
+			formats[key.format] = append(formats[key.format], key.specVersion)
+		}
+	}
+	versions, ok := formats[doc.Format]
+	if !ok {
+		supported := []string{}
+		for f := range formats {
+			supported = append(supported, string(f))
+		}
+		sort.Strings(supported)
+		return nil, processor.UnsupportedFormatError(fmt.Errorf("no document parser registered for %s documents in %s format, supported formats: %s",
+			doc.Type, doc.Format, strings.Join(supported, ", ")))
+	}
+
+	detector, ok := specVersionDetectors[doc.Type]
+	if !ok {
+		return nil, processor.UnsupportedFormatError(fmt.Errorf("no spec version detector registered for type: %s", doc.Type))
+	}
+	version, err := detector(doc)
+	if err != nil {
+		return nil, processor.MalformedDocumentError(fmt.Errorf("unable to detect the spec version of the %s document: %w", doc.Type, err))
+	}
+	p, ok := versionedParsers[parserKey{doc.Type, doc.Format, version}]
+	if !ok {
+		sort.Strings(versions)
+		return nil, processor.UnsupportedFormatError(fmt.Errorf("unsupported %s spec version %q in %s format, supported versions: %s",
+			doc.Type, version, doc.Format, strings.Join(versions, ", ")))
+	}
+	return p, nil
+}
+
+// spdxSpecVersion returns the version of SPDX documents, without the `SPDX-`
+// prefix
+func spdxSpecVersion(doc *processor.Document) (string, error) {
+	var version string
+	switch doc.Format {
+	case processor.FormatJSON:
+		var header struct {
+			SPDXVersion string `json:"spdxVersion"`
+		}
+		if err := json.Unmarshal(doc.Blob, &header); err != nil {
+			return "", err
+		}
+		version = header.SPDXVersion
+	case processor.FormatTagValue:
+		scanner := bufio.NewScanner(bytes.NewReader(doc.Blob))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(doc.Blob)+1)
+		for scanner.Scan() {
+			if v, ok := cutPrefix(strings.TrimSpace(scanner.Text()), "SPDXVersion:"); ok {
+				version = strings.TrimSpace(v)
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported format: %s", doc.Format)
+	}
+	if version == "" {
+		return "", fmt.Errorf("missing SPDX version")
+	}
+	return strings.TrimPrefix(version, "SPDX-"), nil
+}
+
+// cycloneDXSpecVersion returns the version of CycloneDX documents
+func cycloneDXSpecVersion(doc *processor.Document) (string, error) {
+	if doc.Format != processor.FormatJSON {
+		return "", fmt.Errorf("unsupported format: %s", doc.Format)
+	}
+	var header struct {
+		SpecVersion string `json:"specVersion"`
+	}
+	if err := json.Unmarshal(doc.Blob, &header); err != nil {
+		return "", err
+	}
+	if header.SpecVersion == "" {
+		return "", fmt.Errorf("missing CycloneDX spec version")
+	}
+	return header.SpecVersion, nil
+}
+
+// cutPrefix is strings.CutPrefix, which needs Go 1.20
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
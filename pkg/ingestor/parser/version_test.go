@@ -0,0 +1,137 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/internal/testing/testdata"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/ingestor/parser/osv"
+)
+
+func Test_versionedParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      processor.Document
+		wantErr  string
+		wantKind error
+	}{{
+		name: "SPDX 2.2 JSON",
+		doc:  processor.Document{Blob: testdata.SpdxExampleAlpine, Type: processor.DocumentSPDX, Format: processor.FormatJSON},
+	}, {
+		name: "SPDX 2.3 tag-value",
+		doc:  processor.Document{Blob: testdata.SpdxTagValueExample, Type: processor.DocumentSPDX, Format: processor.FormatTagValue},
+	}, {
+		name: "CycloneDX 1.5 JSON",
+		doc:  processor.Document{Blob: testdata.CycloneDXVulnExample, Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+	}, {
+		name:     "unsupported SPDX version",
+		doc:      processor.Document{Blob: []byte(`{"spdxVersion": "SPDX-3.0"}`), Type: processor.DocumentSPDX, Format: processor.FormatJSON},
+		wantErr:  `unsupported SPDX spec version "3.0" in JSON format, supported versions: 2.1, 2.2, 2.3`,
+		wantKind: processor.ErrUnsupportedFormat,
+	}, {
+		name:     "unsupported CycloneDX version",
+		doc:      processor.Document{Blob: []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.6"}`), Type: processor.DocumentCycloneDX, Format: processor.FormatJSON},
+		wantErr:  `unsupported CycloneDX spec version "1.6" in JSON format, supported versions: 1.2, 1.3, 1.4, 1.5`,
+		wantKind: processor.ErrUnsupportedFormat,
+	}, {
+		name:     "unsupported format",
+		doc:      processor.Document{Blob: []byte(`<bom/>`), Type: processor.DocumentCycloneDX, Format: processor.FormatXML},
+		wantErr:  "no document parser registered for CycloneDX documents in XML format, supported formats: JSON",
+		wantKind: processor.ErrUnsupportedFormat,
+	}, {
+		name:     "missing version",
+		doc:      processor.Document{Blob: []byte("DocumentName: test\n"), Type: processor.DocumentSPDX, Format: processor.FormatTagValue},
+		wantErr:  "unable to detect the spec version of the SPDX document: missing SPDX version",
+		wantKind: processor.ErrMalformedDocument,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := versionedParser(&tt.doc)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("versionedParser() error = %v, want %s", err, tt.wantErr)
+				}
+				if !errors.Is(err, tt.wantKind) {
+					t.Errorf("versionedParser() error = %v, want kind %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil || p == nil {
+				t.Fatalf("versionedParser() error = %v, want a parser", err)
+			}
+		})
+	}
+}
+
+func TestRegisterVersionedDocumentParser(t *testing.T) {
+	const testType processor.DocumentType = "TEST_VERSIONED"
+	t.Cleanup(func() {
+		for key := range versionedParsers {
+			if key.docType == testType {
+				delete(versionedParsers, key)
+			}
+		}
+	})
+
+	if err := RegisterVersionedDocumentParser(osv.NewOSVParser, testType, processor.FormatJSON, "1.0", "1.1"); err != nil {
+		t.Fatalf("RegisterVersionedDocumentParser() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		register func() error
+		wantErr  string
+	}{{
+		name: "overwritten version",
+		register: func() error {
+			return RegisterVersionedDocumentParser(osv.NewOSVParser, testType, processor.FormatJSON, "1.2", "1.1")
+		},
+		wantErr: "the document parser is being overwritten: TEST_VERSIONED JSON 1.1",
+	}, {
+		name: "no version",
+		register: func() error {
+			return RegisterVersionedDocumentParser(osv.NewOSVParser, testType, processor.FormatXML)
+		},
+		wantErr: "no spec version given for the document parser: TEST_VERSIONED",
+	}, {
+		name: "type with an unversioned parser",
+		register: func() error {
+			return RegisterVersionedDocumentParser(osv.NewOSVParser, processor.DocumentOSV, processor.FormatJSON, "1.0")
+		},
+		wantErr: "the document parser is being overwritten: OSV",
+	}, {
+		name: "unversioned parser for a type with versioned parsers",
+		register: func() error {
+			return RegisterDocumentParser(osv.NewOSVParser, testType)
+		},
+		wantErr: "the document parser is being overwritten: TEST_VERSIONED",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.register()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("register error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+	// the failed registration of 1.2 registered none of its versions
+	if _, ok := versionedParsers[parserKey{testType, processor.FormatJSON, "1.2"}]; ok {
+		t.Errorf("RegisterVersionedDocumentParser() registered a version despite failing")
+	}
+}
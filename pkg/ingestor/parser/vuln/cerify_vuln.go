@@ -78,9 +78,9 @@ func (c *vulnCertificationParser) Parse(ctx context.Context, doc *processor.Docu
 func (c *vulnCertificationParser) getSubject(statement *attestation_vuln.VulnerabilityStatement) {
 	currentPackage := assembler.PackageNode{}
 	for _, sub := range statement.StatementHeader.Subject {
-		currentPackage.Purl = sub.Name
+		currentPackage.Purl = common.NormalizePurl(sub.Name)
 		for alg, ds := range sub.Digest {
-			currentPackage.Digest = append(currentPackage.Digest, strings.ToLower(alg+":"+strings.Trim(ds, "'")))
+			currentPackage.Digest = append(currentPackage.Digest, common.NormalizeDigest(alg, strings.Trim(ds, "'")))
 		}
 		c.packageNode = append(c.packageNode, currentPackage)
 	}
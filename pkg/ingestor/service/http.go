@@ -0,0 +1,220 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+const (
+	// CollectorHTTP is the collector recorded in the source information of
+	// the documents uploaded to the HTTP ingestion endpoint
+	CollectorHTTP = "HTTPIngest"
+
+	// MaxUploadSize is the maximum size in bytes of the body of an
+	// ingestion request
+	MaxUploadSize = 64 << 20
+)
+
+// ErrInvalidDocument wraps the errors returned by an IngestFunc for documents
+// that cannot be processed or parsed, as opposed to documents that could not
+// be written to the graph or failed transiently, which may be submitted
+// again.
+var ErrInvalidDocument = errors.New("invalid document")
+
+var errTooLarge = fmt.Errorf("request body larger than %d bytes", MaxUploadSize)
+
+// IngestFunc runs a document through the GUAC pipeline and returns the number
+// of nodes and edges written to the graph.
+type IngestFunc func(d *processor.Document) (nodes int, edges int, err error)
+
+// DocumentResult is the result of ingesting one of the uploaded documents
+type DocumentResult struct {
+	Source string `json:"source"`
+	Nodes  int    `json:"nodes"`
+	Edges  int    `json:"edges"`
+	Error  string `json:"error,omitempty"`
+}
+
+// IngestResponse is the body of the responses of the HTTP ingestion endpoint
+type IngestResponse struct {
+	Documents []DocumentResult `json:"documents"`
+}
+
+type httpHandler struct {
+	ctx     context.Context
+	ingest  IngestFunc
+	maxSize int64
+	// mu ensures that documents from concurrent requests are ingested one at
+	// a time, as the GUAC pipeline is not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewHTTPHandler returns an HTTP handler ingesting the documents of POST
+// requests with ingest. The document is either the raw body of the request,
+// whose source can be given with the `source` query parameter, or each file
+// of a `multipart/form-data` body. Their type and format are guessed by the
+// processor.
+//
+// The response lists the number of nodes and edges written for each document,
+// or the error that prevented it. Its status is 400 if a document is invalid
+// and 500 if one could not be written.
+func NewHTTPHandler(ctx context.Context, ingest IngestFunc) http.Handler {
+	return &httpHandler{ctx: ctx, ingest: ingest, maxSize: MaxUploadSize}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(h.ctx)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	docs, err := h.readDocuments(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, "invalid request body: "+err.Error(), status)
+		return
+	}
+	if len(docs) == 0 {
+		http.Error(w, "no document in request body", http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	resp := IngestResponse{Documents: []DocumentResult{}}
+	for _, d := range docs {
+		result := DocumentResult{Source: d.SourceInformation.Source}
+		nodes, edges, err := h.emit(d)
+		switch {
+		case err == nil:
+			result.Nodes = nodes
+			result.Edges = edges
+		case errors.Is(err, ErrInvalidDocument):
+			result.Error = err.Error()
+			if status == http.StatusOK {
+				status = http.StatusBadRequest
+			}
+		default:
+			result.Error = err.Error()
+			status = http.StatusInternalServerError
+		}
+		if err != nil {
+			logger.Errorf("unable to ingest document %+v: %v", d.SourceInformation, err)
+		}
+		resp.Documents = append(resp.Documents, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *httpHandler) emit(d *processor.Document) (int, int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ingest(d)
+}
+
+// readDocuments returns the documents uploaded in the body of the request
+func (h *httpHandler) readDocuments(r *http.Request) ([]*processor.Document, error) {
+	body := &limitedReader{r: r.Body, remaining: h.maxSize}
+	newDocument := func(blob []byte, source string) *processor.Document {
+		return &processor.Document{
+			Blob:   blob,
+			Type:   processor.DocumentUnknown,
+			Format: processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{
+				Collector: CollectorHTTP,
+				Source:    source,
+			},
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		blob, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) == 0 {
+			return nil, nil
+		}
+		source := r.URL.Query().Get("source")
+		if source == "" {
+			source = "upload"
+		}
+		return []*processor.Document{newDocument(blob, source)}, nil
+	}
+
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, errors.New("missing multipart boundary")
+	}
+	var docs []*processor.Document
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// form fields that are not files are ignored
+		if part.FileName() == "" {
+			continue
+		}
+		blob, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, newDocument(blob, part.FileName()))
+	}
+}
+
+// limitedReader returns errTooLarge once more than remaining bytes are read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, errTooLarge
+	}
+	return n, err
+}
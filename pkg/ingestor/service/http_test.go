@@ -0,0 +1,187 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/logging"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+
+	// the fake pipeline writes one node per line of the document
+	var ingested []*processor.Document
+	ingest := func(d *processor.Document) (int, int, error) {
+		switch string(d.Blob) {
+		case "bad":
+			return 0, 0, fmt.Errorf("%w: unable to process doc", ErrInvalidDocument)
+		case "unavailable":
+			return 0, 0, errors.New("unable to assemble graphs")
+		}
+		ingested = append(ingested, d)
+		lines := strings.Count(string(d.Blob), "\n") + 1
+		return lines, lines - 1, nil
+	}
+
+	multipartBody := func(files map[string]string) (string, *bytes.Buffer) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		_ = mw.WriteField("comment", "ignored")
+		for _, name := range []string{"a.json", "b.json"} {
+			if content, ok := files[name]; ok {
+				fw, _ := mw.CreateFormFile("file", name)
+				_, _ = fw.Write([]byte(content))
+			}
+		}
+		mw.Close()
+		return mw.FormDataContentType(), body
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		body        *bytes.Buffer
+		maxSize     int64
+		wantStatus  int
+		wantResults []DocumentResult
+		wantSources []string
+	}{{
+		name:        "raw body",
+		method:      http.MethodPost,
+		url:         "/ingest?source=ci%2Fsbom.json",
+		contentType: "application/json",
+		body:        bytes.NewBufferString("{\n}"),
+		wantStatus:  http.StatusOK,
+		wantResults: []DocumentResult{{Source: "ci/sbom.json", Nodes: 2, Edges: 1}},
+		wantSources: []string{"ci/sbom.json"},
+	}, {
+		name:        "raw body without source",
+		method:      http.MethodPost,
+		url:         "/ingest",
+		body:        bytes.NewBufferString("{}"),
+		wantStatus:  http.StatusOK,
+		wantResults: []DocumentResult{{Source: "upload", Nodes: 1}},
+		wantSources: []string{"upload"},
+	}, {
+		name:        "multipart files",
+		method:      http.MethodPost,
+		url:         "/ingest",
+		wantStatus:  http.StatusOK,
+		wantResults: []DocumentResult{{Source: "a.json", Nodes: 1}, {Source: "b.json", Nodes: 3, Edges: 2}},
+		wantSources: []string{"a.json", "b.json"},
+	}, {
+		name:        "invalid document",
+		method:      http.MethodPost,
+		url:         "/ingest",
+		body:        bytes.NewBufferString("bad"),
+		wantStatus:  http.StatusBadRequest,
+		wantResults: []DocumentResult{{Source: "upload", Error: "invalid document: unable to process doc"}},
+	}, {
+		name:        "write failure",
+		method:      http.MethodPost,
+		url:         "/ingest",
+		body:        bytes.NewBufferString("unavailable"),
+		wantStatus:  http.StatusInternalServerError,
+		wantResults: []DocumentResult{{Source: "upload", Error: "unable to assemble graphs"}},
+	}, {
+		name:       "empty body",
+		method:     http.MethodPost,
+		url:        "/ingest",
+		body:       &bytes.Buffer{},
+		wantStatus: http.StatusBadRequest,
+	}, {
+		name:       "body too large",
+		method:     http.MethodPost,
+		url:        "/ingest",
+		body:       bytes.NewBufferString("12345"),
+		maxSize:    4,
+		wantStatus: http.StatusRequestEntityTooLarge,
+	}, {
+		name:        "body at the size limit",
+		method:      http.MethodPost,
+		url:         "/ingest",
+		body:        bytes.NewBufferString("1234"),
+		maxSize:     4,
+		wantStatus:  http.StatusOK,
+		wantResults: []DocumentResult{{Source: "upload", Nodes: 1}},
+		wantSources: []string{"upload"},
+	}, {
+		name:       "wrong method",
+		method:     http.MethodGet,
+		url:        "/ingest",
+		body:       &bytes.Buffer{},
+		wantStatus: http.StatusMethodNotAllowed,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ingested = nil
+			body, contentType := tt.body, tt.contentType
+			if body == nil {
+				contentType, body = multipartBody(map[string]string{"a.json": "{}", "b.json": "{\n\n}"})
+			}
+			req := httptest.NewRequest(tt.method, tt.url, body)
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			h := NewHTTPHandler(ctx, ingest)
+			if tt.maxSize > 0 {
+				h.(*httpHandler).maxSize = tt.maxSize
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantResults == nil {
+				return
+			}
+			var resp IngestResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if !reflect.DeepEqual(resp.Documents, tt.wantResults) {
+				t.Errorf("got results %+v, want %+v", resp.Documents, tt.wantResults)
+			}
+			var sources []string
+			for _, d := range ingested {
+				if d.SourceInformation.Collector != CollectorHTTP || d.Type != processor.DocumentUnknown || d.Format != processor.FormatUnknown {
+					t.Errorf("unexpected document %+v", d)
+				}
+				sources = append(sources, d.SourceInformation.Source)
+			}
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("ingested documents from %v, want %v", sources, tt.wantSources)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	pb "github.com/guacsec/guac/pkg/ingestor/service/proto"
+	"github.com/guacsec/guac/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageOverhead bounds the size of the fields of a document message other
+// than its blob, e.g. its source
+const messageOverhead = 1 << 20
+
+// MaxMessageSize returns the maximum size in bytes of the messages of the
+// IngestorService, so that the documents up to processor.MaxDocumentSize are
+// not rejected by gRPC, whose default limit is 4 MiB. Without a maximum
+// document size, it is the largest size gRPC supports.
+func MaxMessageSize() int {
+	limit := processor.MaxDocumentSize()
+	if limit == 0 || limit > math.MaxInt32-messageOverhead {
+		return math.MaxInt32
+	}
+	return int(limit) + messageOverhead
+}
+
+// ServerOptions returns the options of the gRPC server of the
+// IngestorService. A message larger than the server accepts aborts the whole
+// stream, so the limit must be at least the one of the clients.
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.MaxRecvMsgSize(MaxMessageSize())}
+}
+
+type ingestorServer struct {
+	pb.UnimplementedIngestorServiceServer
+	ctx     context.Context
+	emitter collector.Emitter
+	// mu ensures that documents from concurrent streams are emitted one at
+	// a time, as the GUAC pipeline is not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewIngestorServer returns the server side of the IngestorService, which
+// passes every received document to emitter. The emitter is expected to run
+// the document through the GUAC pipeline.
+func NewIngestorServer(ctx context.Context, emitter collector.Emitter) pb.IngestorServiceServer {
+	return &ingestorServer{
+		ctx:     ctx,
+		emitter: emitter,
+	}
+}
+
+// Ingest emits each document of the stream. Documents that fail to be emitted
+// are logged and counted in the returned summary, the stream is only aborted
+// if the documents cannot be received.
+func (s *ingestorServer) Ingest(stream pb.IngestorService_IngestServer) error {
+	logger := logging.FromContext(s.ctx)
+	summary := &pb.IngestSummary{}
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(summary)
+		}
+		if err != nil {
+			return err
+		}
+
+		summary.Received++
+		d := DocumentFromProto(msg)
+		if err := s.emit(d); err != nil {
+			summary.Failed++
+			logger.Errorf("unable to ingest document %+v: %v", d.SourceInformation, err)
+		}
+	}
+}
+
+func (s *ingestorServer) emit(d *processor.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.emitter(d)
+}
+
+// Client sends documents to a remote IngestorService.
+type Client struct {
+	client pb.IngestorServiceClient
+}
+
+// NewClient creates a client for the IngestorService served on conn
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		client: pb.NewIngestorServiceClient(conn),
+	}
+}
+
+// Ingest streams the documents from docChan to the ingestor until docChan is
+// closed, and returns the summary of the ingestion. It can be used directly
+// with the channel returned by `collector.CollectDocuments`.
+//
+// Documents larger than MaxMessageSize are logged and counted as failed
+// without being sent, as they would abort the stream.
+//
+// When the ingestor is unavailable, the stream is opened again following the
+// collector.ReconnectPolicy, starting with the document that failed to be
+// sent. The documents sent before on the broken stream are not sent again,
+// as whether the ingestor received them is unknown. Once the retry window is
+// exceeded, or on other errors, the remaining documents of docChan are
+// discarded, so that the collectors sending them are not blocked.
+func (c *Client) Ingest(ctx context.Context, docChan <-chan *processor.Document) (*pb.IngestSummary, error) {
+	logger := logging.FromContext(ctx)
+	limit := MaxMessageSize()
+	total := &pb.IngestSummary{}
+	// pending is the message that failed to be sent on a broken stream
+	var pending *pb.Document
+	next := func() *pb.Document {
+		if pending != nil {
+			return pending
+		}
+		for d := range docChan {
+			msg := DocumentToProto(d)
+			if size := proto.Size(msg); size > limit {
+				total.Received++
+				total.Failed++
+				logger.Errorf("unable to send document %s: its message of %d bytes is larger than the maximum of %d bytes", d.SourceInformation.Source, size, limit)
+				continue
+			}
+			return msg
+		}
+		return nil
+	}
+
+	reconnector := collector.NewReconnector()
+	for {
+		sent, err := c.stream(ctx, limit, next, &pending, total)
+		if err == nil {
+			return total, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			drain(docChan)
+			return nil, err
+		}
+		if sent > 0 {
+			reconnector.Reset()
+			logger.Warnf("ingestion stream broke, %d documents sent on it may not have been ingested", sent)
+		}
+		logger.Warnf("ingestor unavailable (attempt %d): %v", reconnector.Attempt()+1, err)
+		if err := reconnector.Wait(ctx, err); err != nil {
+			drain(docChan)
+			return nil, fmt.Errorf("unable to send documents: %w", err)
+		}
+	}
+}
+
+// stream sends the messages returned by next on a new stream until it
+// returns nil, and adds the summary of the stream to total. When the stream
+// breaks, the message being sent is left in pending, and the number of
+// messages sent before is returned with the error, whose gRPC status is kept.
+func (c *Client) stream(ctx context.Context, limit int, next func() *pb.Document, pending **pb.Document, total *pb.IngestSummary) (int, error) {
+	stream, err := c.client.Ingest(ctx, grpc.MaxCallSendMsgSize(limit))
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for msg := next(); msg != nil; msg = next() {
+		*pending = msg
+		if err := stream.Send(msg); err != nil {
+			// the actual error is returned by CloseAndRecv
+			if errors.Is(err, io.EOF) {
+				if _, err = stream.CloseAndRecv(); err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			return sent, err
+		}
+		*pending = nil
+		sent++
+	}
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		return sent, err
+	}
+	total.Received += summary.Received
+	total.Failed += summary.Failed
+	return sent, nil
+}
+
+// drain discards the documents of docChan in the background, until it is
+// closed
+func drain(docChan <-chan *processor.Document) {
+	go func() {
+		for range docChan {
+		}
+	}()
+}
+
+// DocumentToProto converts a document to its protobuf message
+func DocumentToProto(d *processor.Document) *pb.Document {
+	return &pb.Document{
+		Blob:   d.Blob,
+		Type:   string(d.Type),
+		Format: string(d.Format),
+		SourceInformation: &pb.SourceInformation{
+			Collector: d.SourceInformation.Collector,
+			Source:    d.SourceInformation.Source,
+		},
+	}
+}
+
+// DocumentFromProto converts a protobuf message to a document. Missing type
+// and format are set to unknown, so that they are guessed by the processor.
+func DocumentFromProto(m *pb.Document) *processor.Document {
+	d := &processor.Document{
+		Blob:   m.GetBlob(),
+		Type:   processor.DocumentType(m.GetType()),
+		Format: processor.FormatType(m.GetFormat()),
+		SourceInformation: processor.SourceInformation{
+			Collector: m.GetSourceInformation().GetCollector(),
+			Source:    m.GetSourceInformation().GetSource(),
+		},
+	}
+	if d.Type == "" {
+		d.Type = processor.DocumentUnknown
+	}
+	if d.Format == "" {
+		d.Format = processor.FormatUnknown
+	}
+	return d
+}
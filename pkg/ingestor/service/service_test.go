@@ -0,0 +1,266 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/collector"
+	"github.com/guacsec/guac/pkg/handler/processor"
+	pb "github.com/guacsec/guac/pkg/ingestor/service/proto"
+	"github.com/guacsec/guac/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestIngest(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+
+	docs := []*processor.Document{{
+		Blob:   []byte(`{"a": 1}`),
+		Type:   processor.DocumentSPDX,
+		Format: processor.FormatJSON,
+		SourceInformation: processor.SourceInformation{
+			Collector: "file",
+			Source:    "file:///a.json",
+		},
+	}, {
+		Blob:   []byte(`bad`),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+		SourceInformation: processor.SourceInformation{
+			Collector: "file",
+			Source:    "file:///bad",
+		},
+	}, {
+		Blob:   []byte(`{"c": 3}`),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+		SourceInformation: processor.SourceInformation{
+			Collector: "file",
+			Source:    "file:///c.json",
+		},
+	}}
+
+	var emitted []*processor.Document
+	emitter := func(d *processor.Document) error {
+		if string(d.Blob) == "bad" {
+			return errors.New("unable to process document")
+		}
+		emitted = append(emitted, d)
+		return nil
+	}
+
+	conn := serve(ctx, t, emitter)
+
+	docChan := make(chan *processor.Document, len(docs))
+	for _, d := range docs {
+		docChan <- d
+	}
+	close(docChan)
+
+	summary, err := NewClient(conn).Ingest(ctx, docChan)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if summary.GetReceived() != 3 || summary.GetFailed() != 1 {
+		t.Errorf("Ingest() received %d documents with %d failures, want 3 with 1 failure", summary.GetReceived(), summary.GetFailed())
+	}
+	want := []*processor.Document{docs[0], docs[2]}
+	if !reflect.DeepEqual(emitted, want) {
+		t.Errorf("emitted %v, want %v", emitted, want)
+	}
+}
+
+func TestIngest_LargeDocuments(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	if err := processor.SetMaxDocumentSize(8 << 20); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = processor.SetMaxDocumentSize(processor.DefaultMaxDocumentSize)
+	}()
+
+	doc := func(source string, size int) *processor.Document {
+		return &processor.Document{
+			Blob:              bytes.Repeat([]byte("a"), size),
+			Type:              processor.DocumentUnknown,
+			Format:            processor.FormatUnknown,
+			SourceInformation: processor.SourceInformation{Collector: "file", Source: source},
+		}
+	}
+	// larger than the 4 MiB default limit of gRPC, but not than the
+	// maximum document size, and larger than both
+	docs := []*processor.Document{doc("file:///large", 5<<20), doc("file:///too-large", 10<<20), doc("file:///small", 10)}
+
+	var emitted []string
+	conn := serve(ctx, t, func(d *processor.Document) error {
+		emitted = append(emitted, d.SourceInformation.Source)
+		return nil
+	})
+
+	docChan := make(chan *processor.Document, len(docs))
+	for _, d := range docs {
+		docChan <- d
+	}
+	close(docChan)
+
+	summary, err := NewClient(conn).Ingest(ctx, docChan)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if summary.GetReceived() != 3 || summary.GetFailed() != 1 {
+		t.Errorf("Ingest() received %d documents with %d failures, want 3 with 1 failure", summary.GetReceived(), summary.GetFailed())
+	}
+	if want := []string{"file:///large", "file:///small"}; !reflect.DeepEqual(emitted, want) {
+		t.Errorf("emitted %v, want %v", emitted, want)
+	}
+}
+
+func TestIngest_DrainsAfterFailure(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	conn := serve(ctx, t, func(d *processor.Document) error { return nil })
+	// the stream cannot be opened on a closed connection
+	conn.Close()
+
+	docChan := make(chan *processor.Document)
+	if _, err := NewClient(conn).Ingest(ctx, docChan); err == nil {
+		t.Fatalf("Ingest() succeeded on a closed connection")
+	}
+	sent := make(chan struct{})
+	go func() {
+		docChan <- &processor.Document{Blob: []byte("{}")}
+		close(docChan)
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Ingest() stopped draining the documents")
+	}
+}
+
+func TestIngest_Reconnects(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	if err := collector.SetReconnectPolicy(collector.ReconnectPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = collector.SetReconnectPolicy(collector.DefaultReconnectPolicy)
+	}()
+
+	var emitted []string
+	// the ingestor cannot be reached on the first two connection attempts
+	conn := serveAfter(ctx, t, func(d *processor.Document) error {
+		emitted = append(emitted, d.SourceInformation.Source)
+		return nil
+	}, 2)
+
+	docChan := make(chan *processor.Document, 2)
+	docChan <- &processor.Document{Blob: []byte("{}"), SourceInformation: processor.SourceInformation{Source: "a"}}
+	docChan <- &processor.Document{Blob: []byte("{}"), SourceInformation: processor.SourceInformation{Source: "b"}}
+	close(docChan)
+
+	summary, err := NewClient(conn).Ingest(ctx, docChan)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if summary.GetReceived() != 2 || summary.GetFailed() != 0 {
+		t.Errorf("Ingest() received %d documents with %d failures, want 2 without failures", summary.GetReceived(), summary.GetFailed())
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(emitted, want) {
+		t.Errorf("emitted %v, want %v", emitted, want)
+	}
+}
+
+func TestIngest_ReconnectWindow(t *testing.T) {
+	ctx := logging.WithLogger(context.Background())
+	if err := collector.SetReconnectPolicy(collector.ReconnectPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond, MaxRetryWindow: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = collector.SetReconnectPolicy(collector.DefaultReconnectPolicy)
+	}()
+
+	conn := serveAfter(ctx, t, func(d *processor.Document) error { return nil }, math.MaxInt32)
+	docChan := make(chan *processor.Document, 1)
+	docChan <- &processor.Document{Blob: []byte("{}")}
+	close(docChan)
+
+	_, err := NewClient(conn).Ingest(ctx, docChan)
+	if err == nil || !strings.Contains(err.Error(), "giving up reconnecting") {
+		t.Errorf("Ingest() error = %v, want the retry window to be exceeded", err)
+	}
+}
+
+// serve serves the IngestorService with emitter and returns a connection to it
+func serve(ctx context.Context, t *testing.T, emitter func(*processor.Document) error) *grpc.ClientConn {
+	return serveAfter(ctx, t, emitter, 0)
+}
+
+// serveAfter is like serve, but the first failures attempts to connect to
+// the service fail
+func serveAfter(ctx context.Context, t *testing.T, emitter func(*processor.Document) error, failures int32) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(ServerOptions()...)
+	pb.RegisterIngestorServiceServer(server, NewIngestorServer(ctx, emitter))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	var attempts int32
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			if atomic.AddInt32(&attempts, 1) <= failures {
+				return nil, errors.New("connection refused")
+			}
+			return listener.Dial()
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond},
+			MinConnectTimeout: time.Second,
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestDocumentFromProto(t *testing.T) {
+	got := DocumentFromProto(&pb.Document{Blob: []byte("blob")})
+	want := &processor.Document{
+		Blob:   []byte("blob"),
+		Type:   processor.DocumentUnknown,
+		Format: processor.FormatUnknown,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DocumentFromProto() = %v, want %v", got, want)
+	}
+}
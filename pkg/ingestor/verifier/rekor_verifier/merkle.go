@@ -0,0 +1,135 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor_verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// RFC 6962 domain separation prefixes of the Merkle tree hashes
+const (
+	leafHashPrefix = 0
+	nodeHashPrefix = 1
+)
+
+func hashLeaf(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafHashPrefix})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func hashChildren(l, r []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodeHashPrefix})
+	h.Write(l)
+	h.Write(r)
+	return h.Sum(nil)
+}
+
+// verifyInclusion checks that the leaf at index is included in the tree of
+// the given size and root hash, following the RFC 6962 audit path algorithm
+func verifyInclusion(index, size uint64, leafHash []byte, proof [][]byte, root []byte) error {
+	if index >= size {
+		return fmt.Errorf("index %d is beyond tree size %d", index, size)
+	}
+	// The audit path is made of the siblings below the point where the paths
+	// to the leaf and to the last leaf of the tree diverge (inner), then of
+	// the left siblings on the right border of the tree (border)
+	inner := bits.Len64(index ^ (size - 1))
+	border := bits.OnesCount64(index >> uint(inner))
+	if len(proof) != inner+border {
+		return fmt.Errorf("wrong proof size %d, want %d", len(proof), inner+border)
+	}
+
+	h := leafHash
+	for i, sibling := range proof[:inner] {
+		if (index>>uint(i))&1 == 0 {
+			h = hashChildren(h, sibling)
+		} else {
+			h = hashChildren(sibling, h)
+		}
+	}
+	for _, sibling := range proof[inner:] {
+		h = hashChildren(sibling, h)
+	}
+
+	if !bytes.Equal(h, root) {
+		return errors.New("calculated root hash does not match the root hash of the proof")
+	}
+	return nil
+}
+
+// checkpoint is the signed tree head of the log, in the signed note format
+// (https://github.com/transparency-dev/formats/blob/main/log/README.md)
+type checkpoint struct {
+	origin   string
+	size     uint64
+	rootHash []byte
+}
+
+// verifyCheckpoint checks that one of the signatures of the note was made by
+// the log key and returns the tree head it signs
+func verifyCheckpoint(note string, logVerifier signature.Verifier) (*checkpoint, error) {
+	sep := strings.Index(note, "\n\n")
+	if sep < 0 {
+		return nil, errors.New("malformed checkpoint: missing signatures")
+	}
+	text, sigLines := note[:sep+1], strings.Split(strings.TrimSpace(note[sep+2:]), "\n")
+
+	verified := false
+	for _, line := range sigLines {
+		// Each signature line is "— <name> <base64(key hint || signature)>"
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(raw) <= 4 {
+			continue
+		}
+		if logVerifier.VerifySignature(bytes.NewReader(raw[4:]), strings.NewReader(text)) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("checkpoint is not signed by the log key")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 3 {
+		return nil, errors.New("malformed checkpoint: missing tree head")
+	}
+	size, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed checkpoint size: %w", err)
+	}
+	rootHash, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed checkpoint root hash: %w", err)
+	}
+	return &checkpoint{origin: lines[0], size: size, rootHash: rootHash}, nil
+}
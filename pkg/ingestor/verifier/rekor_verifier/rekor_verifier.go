@@ -0,0 +1,532 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor_verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guacsec/guac/pkg/handler/processor/bundle"
+	"github.com/guacsec/guac/pkg/ingestor/key"
+	"github.com/guacsec/guac/pkg/ingestor/verifier"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	sig_dsse "github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+const (
+	// DefaultRekorURL is the public Rekor instance of Sigstore
+	DefaultRekorURL = "https://rekor.sigstore.dev"
+	// DefaultTimeout is the timeout of each request to Rekor
+	DefaultTimeout = 30 * time.Second
+
+	// maxCachedEnvelopes bounds the number of verification results kept, so
+	// that the process and ingest stages look each envelope up only once
+	maxCachedEnvelopes = 1000
+)
+
+type rekorVerifier struct {
+	url    string
+	client *http.Client
+
+	logVerifier   signature.Verifier
+	logID         string
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+
+	mu    sync.Mutex
+	cache map[string][]verifier.Identity
+}
+
+// NewRekorVerifier initializes a verifier of DSSE envelopes signed with
+// keyless Sigstore. rekorKeyPEM is the public key of the Rekor log at
+// rekorURL and fulcioPEM holds the Fulcio root and intermediate
+// certificates that the signing certificates must chain to.
+func NewRekorVerifier(rekorURL string, rekorKeyPEM []byte, fulcioPEM []byte) (*rekorVerifier, error) {
+	rekorKey, err := cryptoutils.UnmarshalPEMToPublicKey(rekorKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Rekor public key: %w", err)
+	}
+	logVerifier, err := signature.LoadVerifier(rekorKey, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load Rekor public key: %w", err)
+	}
+	der, err := cryptoutils.MarshalPublicKeyToDER(rekorKey)
+	if err != nil {
+		return nil, err
+	}
+	logID := sha256.Sum256(der)
+
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM(fulcioPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Fulcio certificates: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no Fulcio certificate found")
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+
+	return &rekorVerifier{
+		url:           strings.TrimSuffix(rekorURL, "/"),
+		client:        &http.Client{Timeout: DefaultTimeout},
+		logVerifier:   logVerifier,
+		logID:         hex.EncodeToString(logID[:]),
+		roots:         roots,
+		intermediates: intermediates,
+		cache:         map[string][]verifier.Identity{},
+	}, nil
+}
+
+// Verify looks up the Rekor entries of the envelope payload and returns the
+// identities of the first entry that is included in the log and whose
+// signing certificates chain to the Fulcio roots. An error is returned if no
+// such entry exists.
+func (r *rekorVerifier) Verify(ctx context.Context, payloadBytes []byte) ([]verifier.Identity, error) {
+	digest := sha256.Sum256(payloadBytes)
+	cacheKey := hex.EncodeToString(digest[:])
+	r.mu.Lock()
+	identities, ok := r.cache[cacheKey]
+	r.mu.Unlock()
+	if ok {
+		return identities, nil
+	}
+
+	envelope := dsse.Envelope{}
+	if err := json.Unmarshal(payloadBytes, &envelope); err != nil {
+		return nil, err
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode envelope payload: %w", err)
+	}
+	payloadDigest := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(payloadDigest[:])
+
+	uuids, err := r.searchIndex(ctx, payloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search Rekor: %w", err)
+	}
+	if len(uuids) == 0 {
+		return nil, fmt.Errorf("no Rekor entry found for payload sha256:%s", payloadHash)
+	}
+
+	var errs []string
+	for _, uuid := range uuids {
+		e, err := r.getEntry(ctx, uuid)
+		if err == nil {
+			identities, err = r.verifyEntry(e, payloadHash, payloadBytes)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", uuid, err))
+			continue
+		}
+
+		r.mu.Lock()
+		if len(r.cache) >= maxCachedEnvelopes {
+			r.cache = map[string][]verifier.Identity{}
+		}
+		r.cache[cacheKey] = identities
+		r.mu.Unlock()
+		return identities, nil
+	}
+	return nil, fmt.Errorf("no valid Rekor entry found for payload sha256:%s: %s", payloadHash, strings.Join(errs, "; "))
+}
+
+// VerifyBundle verifies the DSSE envelope of a Sigstore bundle against the
+// Rekor entries embedded in the bundle, without contacting Rekor. The
+// identities of the first valid entry are returned.
+func (r *rekorVerifier) VerifyBundle(ctx context.Context, bundleBytes []byte) ([]verifier.Identity, error) {
+	b, err := bundle.ParseBundle(bundleBytes)
+	if err != nil {
+		return nil, err
+	}
+	_, payload, err := b.Envelope()
+	if err != nil {
+		return nil, err
+	}
+	payloadDigest := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(payloadDigest[:])
+
+	if len(b.VerificationMaterial.TlogEntries) == 0 {
+		return nil, errors.New("bundle has no Rekor entry")
+	}
+	var errs []string
+	for _, t := range b.VerificationMaterial.TlogEntries {
+		identities, err := r.verifyEntry(bundleLogEntry(t), payloadHash, b.DSSEEnvelope)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("entry %d: %v", t.LogIndex, err))
+			continue
+		}
+		return identities, nil
+	}
+	return nil, fmt.Errorf("no valid Rekor entry found in bundle for payload sha256:%s: %s", payloadHash, strings.Join(errs, "; "))
+}
+
+// Type returns the type of the verifier
+func (r *rekorVerifier) Type() verifier.VerifierType {
+	return "rekor"
+}
+
+// logEntry is an entry of the Rekor log, as returned by the
+// /api/v1/log/entries/{uuid} endpoint
+type logEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		InclusionProof *struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+	} `json:"verification"`
+}
+
+// bundleLogEntry converts a transparency log entry of a Sigstore bundle to
+// the encoding of the Rekor API, which hex encodes the hashes
+func bundleLogEntry(t bundle.TlogEntry) *logEntry {
+	e := &logEntry{
+		Body:           base64.StdEncoding.EncodeToString(t.CanonicalizedBody),
+		IntegratedTime: int64(t.IntegratedTime),
+		LogID:          hex.EncodeToString(t.LogID.KeyID),
+		LogIndex:       int64(t.LogIndex),
+	}
+	if t.InclusionPromise != nil {
+		e.Verification.SignedEntryTimestamp = base64.StdEncoding.EncodeToString(t.InclusionPromise.SignedEntryTimestamp)
+	}
+	if p := t.InclusionProof; p != nil {
+		hashes := make([]string, len(p.Hashes))
+		for i, h := range p.Hashes {
+			hashes[i] = hex.EncodeToString(h)
+		}
+		e.Verification.InclusionProof = &struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		}{
+			Checkpoint: p.Checkpoint.Envelope,
+			Hashes:     hashes,
+			LogIndex:   int64(p.LogIndex),
+			RootHash:   hex.EncodeToString(p.RootHash),
+			TreeSize:   int64(p.TreeSize),
+		}
+	}
+	return e
+}
+
+func (r *rekorVerifier) searchIndex(ctx context.Context, payloadHash string) ([]string, error) {
+	query, err := json.Marshal(map[string]string{"hash": "sha256:" + payloadHash})
+	if err != nil {
+		return nil, err
+	}
+	var uuids []string
+	err = r.do(ctx, http.MethodPost, "/api/v1/index/retrieve", bytes.NewReader(query), &uuids)
+	return uuids, err
+}
+
+func (r *rekorVerifier) getEntry(ctx context.Context, uuid string) (*logEntry, error) {
+	entries := map[string]logEntry{}
+	if err := r.do(ctx, http.MethodGet, "/api/v1/log/entries/"+uuid, nil, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		return &e, nil
+	}
+	return nil, errors.New("entry not found")
+}
+
+func (r *rekorVerifier) do(ctx context.Context, method string, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// verifyEntry checks that the entry was signed by the log and is included in
+// it, that it records the payload, and that its signing certificates chain to
+// the Fulcio roots and sign the envelope
+func (r *rekorVerifier) verifyEntry(e *logEntry, payloadHash string, envelope []byte) ([]verifier.Identity, error) {
+	if e.LogID != r.logID {
+		return nil, fmt.Errorf("entry is from an unknown log %s", e.LogID)
+	}
+	if err := r.verifySET(e); err != nil {
+		return nil, fmt.Errorf("invalid signed entry timestamp: %w", err)
+	}
+	if err := r.verifyInclusionProof(e); err != nil {
+		return nil, fmt.Errorf("invalid inclusion proof: %w", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(e.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode entry body: %w", err)
+	}
+	entryHash, certPEMs, err := parseEntryBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if entryHash != payloadHash {
+		return nil, fmt.Errorf("entry records payload sha256:%s", entryHash)
+	}
+	if len(certPEMs) == 0 {
+		return nil, errors.New("entry has no signing certificate")
+	}
+
+	logIndex := e.LogIndex
+	identities := []verifier.Identity{}
+	for _, certPEM := range certPEMs {
+		cert, err := r.verifyCertificate(certPEM, time.Unix(e.IntegratedTime, 0))
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(cert.PublicKey, envelope); err != nil {
+			return nil, fmt.Errorf("envelope signature does not match certificate of %s: %w", certIdentity(cert), err)
+		}
+		k, err := key.NewKey(cert.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, verifier.Identity{
+			ID:            certIdentity(cert),
+			Key:           *k,
+			Verified:      true,
+			RekorLogIndex: &logIndex,
+		})
+	}
+	return identities, nil
+}
+
+// verifySET checks the promise of inclusion signed by the log, made over the
+// canonical JSON of the entry
+func (r *rekorVerifier) verifySET(e *logEntry) error {
+	set, err := base64.StdEncoding.DecodeString(e.Verification.SignedEntryTimestamp)
+	if err != nil {
+		return err
+	}
+	// The fields are in lexicographic order, which makes the encoding
+	// canonical
+	canonical, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{e.Body, e.IntegratedTime, e.LogID, e.LogIndex})
+	if err != nil {
+		return err
+	}
+	return r.logVerifier.VerifySignature(bytes.NewReader(set), bytes.NewReader(canonical))
+}
+
+// verifyInclusionProof checks the Merkle audit path from the entry to the
+// root hash of the tree head signed by the log
+func (r *rekorVerifier) verifyInclusionProof(e *logEntry) error {
+	p := e.Verification.InclusionProof
+	if p == nil {
+		return errors.New("entry has no inclusion proof")
+	}
+	body, err := base64.StdEncoding.DecodeString(e.Body)
+	if err != nil {
+		return err
+	}
+	rootHash, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return fmt.Errorf("malformed root hash: %w", err)
+	}
+	hashes := make([][]byte, len(p.Hashes))
+	for i, h := range p.Hashes {
+		if hashes[i], err = hex.DecodeString(h); err != nil {
+			return fmt.Errorf("malformed proof hash: %w", err)
+		}
+	}
+	if p.LogIndex < 0 || p.TreeSize < 0 {
+		return errors.New("negative log index or tree size")
+	}
+	if err := verifyInclusion(uint64(p.LogIndex), uint64(p.TreeSize), hashLeaf(body), hashes, rootHash); err != nil {
+		return err
+	}
+
+	cp, err := verifyCheckpoint(p.Checkpoint, r.logVerifier)
+	if err != nil {
+		return err
+	}
+	if cp.size != uint64(p.TreeSize) || !bytes.Equal(cp.rootHash, rootHash) {
+		return errors.New("checkpoint does not match the tree of the proof")
+	}
+	return nil
+}
+
+// verifyCertificate parses the signing certificate and checks that it chains
+// to the Fulcio roots and was valid when the entry was logged. Fulcio
+// certificates only live for a few minutes, so the integration time of the
+// entry is used rather than the current time.
+func (r *rekorVerifier) verifyCertificate(certPEM []byte, at time.Time) (*x509.Certificate, error) {
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return nil, errors.New("entry is not signed with a Fulcio certificate")
+	}
+	cert := certs[0]
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         r.roots,
+		Intermediates: r.intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("certificate of %s does not chain to the Fulcio roots: %w", certIdentity(cert), err)
+	}
+	return cert, nil
+}
+
+// certIdentity returns the subject alternative name Fulcio puts the OIDC
+// identity of the signer in
+func certIdentity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.String()
+}
+
+// parseEntryBody returns the payload hash and the PEM encoded signing
+// certificates recorded in a dsse or intoto entry
+func parseEntryBody(body []byte) (string, [][]byte, error) {
+	entry := struct {
+		APIVersion string          `json:"apiVersion"`
+		Kind       string          `json:"kind"`
+		Spec       json.RawMessage `json:"spec"`
+	}{}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return "", nil, fmt.Errorf("unable to parse entry body: %w", err)
+	}
+
+	type hash struct {
+		Value string `json:"value"`
+	}
+	var (
+		payloadHash string
+		encoded     []string
+	)
+	switch {
+	case entry.Kind == "dsse":
+		spec := struct {
+			PayloadHash hash `json:"payloadHash"`
+			Signatures  []struct {
+				Verifier string `json:"verifier"`
+			} `json:"signatures"`
+		}{}
+		if err := json.Unmarshal(entry.Spec, &spec); err != nil {
+			return "", nil, err
+		}
+		payloadHash = spec.PayloadHash.Value
+		for _, s := range spec.Signatures {
+			encoded = append(encoded, s.Verifier)
+		}
+	case entry.Kind == "intoto" && entry.APIVersion == "0.0.1":
+		spec := struct {
+			PublicKey string `json:"publicKey"`
+			Content   struct {
+				PayloadHash hash `json:"payloadHash"`
+			} `json:"content"`
+		}{}
+		if err := json.Unmarshal(entry.Spec, &spec); err != nil {
+			return "", nil, err
+		}
+		payloadHash = spec.Content.PayloadHash.Value
+		encoded = append(encoded, spec.PublicKey)
+	case entry.Kind == "intoto":
+		spec := struct {
+			Content struct {
+				PayloadHash hash `json:"payloadHash"`
+				Envelope    struct {
+					Signatures []struct {
+						PublicKey string `json:"publicKey"`
+					} `json:"signatures"`
+				} `json:"envelope"`
+			} `json:"content"`
+		}{}
+		if err := json.Unmarshal(entry.Spec, &spec); err != nil {
+			return "", nil, err
+		}
+		payloadHash = spec.Content.PayloadHash.Value
+		for _, s := range spec.Content.Envelope.Signatures {
+			encoded = append(encoded, s.PublicKey)
+		}
+	default:
+		return "", nil, fmt.Errorf("unsupported entry kind %s %s", entry.Kind, entry.APIVersion)
+	}
+
+	certPEMs := make([][]byte, 0, len(encoded))
+	for _, e := range encoded {
+		if e == "" {
+			continue
+		}
+		certPEM, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to decode signing certificate: %w", err)
+		}
+		certPEMs = append(certPEMs, certPEM)
+	}
+	return payloadHash, certPEMs, nil
+}
+
+func verifySignature(k crypto.PublicKey, envelope []byte) error {
+	vfr, err := signature.LoadVerifier(k, crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("could not load verifier: %w", err)
+	}
+	return sig_dsse.WrapVerifier(vfr).VerifySignature(bytes.NewReader(envelope), nil)
+}
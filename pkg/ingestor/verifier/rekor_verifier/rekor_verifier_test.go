@@ -0,0 +1,518 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor_verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guacsec/guac/pkg/logging"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	sig_dsse "github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+var integratedTime = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// rfc6962Root computes the Merkle tree hash of the leaf hashes
+func rfc6962Root(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return hashChildren(rfc6962Root(leaves[:k]), rfc6962Root(leaves[k:]))
+}
+
+// rfc6962Path computes the audit path of the m-th leaf
+func rfc6962Path(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(rfc6962Path(m, leaves[:k]), rfc6962Root(leaves[k:]))
+	}
+	return append(rfc6962Path(m-k, leaves[k:]), rfc6962Root(leaves[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = hashLeaf([]byte{byte(i)})
+		}
+		root := rfc6962Root(leaves)
+		for i := 0; i < size; i++ {
+			proof := rfc6962Path(i, leaves)
+			if err := verifyInclusion(uint64(i), uint64(size), leaves[i], proof, root); err != nil {
+				t.Errorf("size %d, index %d: unexpected error: %v", size, i, err)
+			}
+			if err := verifyInclusion(uint64(i), uint64(size), hashLeaf([]byte("other")), proof, root); err == nil {
+				t.Errorf("size %d, index %d: expected error for wrong leaf", size, i)
+			}
+		}
+		if err := verifyInclusion(uint64(size), uint64(size), leaves[0], nil, root); err == nil {
+			t.Errorf("size %d: expected error for index beyond tree size", size)
+		}
+	}
+}
+
+// fixture is a Rekor log holding the entry of a keyless signed envelope
+type fixture struct {
+	rekorKey    *ecdsa.PrivateKey
+	rekorKeyPEM []byte
+	fulcioKey   *ecdsa.PrivateKey
+	fulcioCA    *x509.Certificate
+	fulcioPEM   []byte
+	envelope    []byte
+	uuids       []string
+	entry       logEntry
+}
+
+func newCA(t *testing.T) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio.test"},
+		NotBefore:             integratedTime.Add(-time.Hour),
+		NotAfter:              integratedTime.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+// newSigningCert issues a short-lived Fulcio-like certificate for the email
+func newSigningCert(t *testing.T, caKey *ecdsa.PrivateKey, ca *x509.Certificate, email string) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:   big.NewInt(2),
+		NotBefore:      integratedTime.Add(-5 * time.Minute),
+		NotAfter:       integratedTime.Add(5 * time.Minute),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses: []string{email},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, certPEM
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{}
+	var err error
+	f.rekorKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.rekorKeyPEM, err = cryptoutils.MarshalPublicKeyToPEM(&f.rekorKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.fulcioKey, f.fulcioCA = newCA(t)
+	f.fulcioPEM, err = cryptoutils.MarshalCertificateToPEM(f.fulcioCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingKey, certPEM := newSigningCert(t, f.fulcioKey, f.fulcioCA, "signer@example.com")
+	payload := []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`)
+	f.envelope = sign(t, signingKey, payload)
+	f.setEntry(t, payload, certPEM)
+	return f
+}
+
+func sign(t *testing.T, k *ecdsa.PrivateKey, payload []byte) []byte {
+	signer, err := signature.LoadECDSASigner(k, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := sig_dsse.WrapSigner(signer, in_toto.PayloadType).SignMessage(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+// setEntry logs a dsse entry for the payload as the 4th of 5 entries, with a
+// valid signed entry timestamp and inclusion proof
+func (f *fixture) setEntry(t *testing.T, payload []byte, certPEM []byte) {
+	payloadHash := sha256.Sum256(payload)
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]interface{}{
+			"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+			"signatures": []map[string]string{{
+				"signature": "c2ln",
+				"verifier":  base64.StdEncoding.EncodeToString(certPEM),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const logIndex, treeSize = 3, 5
+	leaves := make([][]byte, treeSize)
+	for i := range leaves {
+		leaves[i] = hashLeaf([]byte{byte(i)})
+	}
+	leaves[logIndex] = hashLeaf(body)
+	root := rfc6962Root(leaves)
+	hashes := []string{}
+	for _, h := range rfc6962Path(logIndex, leaves) {
+		hashes = append(hashes, hex.EncodeToString(h))
+	}
+
+	der, err := cryptoutils.MarshalPublicKeyToDER(&f.rekorKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256(der)
+
+	e := logEntry{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integratedTime.Unix(),
+		LogID:          hex.EncodeToString(logID[:]),
+		LogIndex:       logIndex,
+	}
+	e.Verification.InclusionProof = &struct {
+		Checkpoint string   `json:"checkpoint"`
+		Hashes     []string `json:"hashes"`
+		LogIndex   int64    `json:"logIndex"`
+		RootHash   string   `json:"rootHash"`
+		TreeSize   int64    `json:"treeSize"`
+	}{
+		Checkpoint: f.checkpoint(t, f.rekorKey, treeSize, root),
+		Hashes:     hashes,
+		LogIndex:   logIndex,
+		RootHash:   hex.EncodeToString(root),
+		TreeSize:   treeSize,
+	}
+	canonical, err := json.Marshal(map[string]interface{}{
+		"body":           e.Body,
+		"integratedTime": e.IntegratedTime,
+		"logID":          e.LogID,
+		"logIndex":       e.LogIndex,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Verification.SignedEntryTimestamp = base64.StdEncoding.EncodeToString(f.signWith(t, f.rekorKey, canonical))
+
+	f.entry = e
+	f.uuids = []string{"entry-uuid"}
+}
+
+func (f *fixture) signWith(t *testing.T, k *ecdsa.PrivateKey, msg []byte) []byte {
+	signer, err := signature.LoadECDSASigner(k, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.SignMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func (f *fixture) checkpoint(t *testing.T, k *ecdsa.PrivateKey, size int, root []byte) string {
+	text := "rekor.test - 1\n" + big.NewInt(int64(size)).String() + "\n" + base64.StdEncoding.EncodeToString(root) + "\n"
+	sig := append([]byte{0, 0, 0, 0}, f.signWith(t, k, []byte(text))...)
+	return text + "\n— rekor.test " + base64.StdEncoding.EncodeToString(sig) + "\n"
+}
+
+func (f *fixture) serve(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/index/retrieve":
+			_ = json.NewEncoder(w).Encode(f.uuids)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/log/entries/"):
+			uuid := strings.TrimPrefix(r.URL.Path, "/api/v1/log/entries/")
+			_ = json.NewEncoder(w).Encode(map[string]logEntry{uuid: f.entry})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestRekorVerifier_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, f *fixture)
+		wantErr string
+	}{{
+		name: "valid keyless envelope",
+	}, {
+		name: "no entry in the log",
+		tamper: func(t *testing.T, f *fixture) {
+			f.uuids = []string{}
+		},
+		wantErr: "no Rekor entry found",
+	}, {
+		name: "entry from another log",
+		tamper: func(t *testing.T, f *fixture) {
+			f.entry.LogID = strings.Repeat("0", 64)
+		},
+		wantErr: "unknown log",
+	}, {
+		name: "tampered signed entry timestamp",
+		tamper: func(t *testing.T, f *fixture) {
+			f.entry.LogIndex++
+		},
+		wantErr: "invalid signed entry timestamp",
+	}, {
+		name: "tampered inclusion proof",
+		tamper: func(t *testing.T, f *fixture) {
+			f.entry.Verification.InclusionProof.Hashes[0] = strings.Repeat("0", 64)
+		},
+		wantErr: "invalid inclusion proof",
+	}, {
+		name: "checkpoint not signed by the log",
+		tamper: func(t *testing.T, f *fixture) {
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := f.entry.Verification.InclusionProof
+			root, _ := hex.DecodeString(p.RootHash)
+			p.Checkpoint = f.checkpoint(t, other, int(p.TreeSize), root)
+		},
+		wantErr: "checkpoint is not signed by the log key",
+	}, {
+		name: "certificate from another CA",
+		tamper: func(t *testing.T, f *fixture) {
+			caKey, ca := newCA(t)
+			signingKey, certPEM := newSigningCert(t, caKey, ca, "mallory@example.com")
+			payload := []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`)
+			f.envelope = sign(t, signingKey, payload)
+			f.setEntry(t, payload, certPEM)
+		},
+		wantErr: "does not chain to the Fulcio roots",
+	}, {
+		name: "envelope signed by another key",
+		tamper: func(t *testing.T, f *fixture) {
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.envelope = sign(t, other, []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`))
+		},
+		wantErr: "envelope signature does not match certificate",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.WithLogger(context.Background())
+			f := newFixture(t)
+			if tt.tamper != nil {
+				tt.tamper(t, f)
+			}
+			s := f.serve(t)
+
+			v, err := NewRekorVerifier(s.URL, f.rekorKeyPEM, f.fulcioPEM)
+			if err != nil {
+				t.Fatalf("NewRekorVerifier() error = %v", err)
+			}
+			got, err := v.Verify(ctx, f.envelope)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() unexpected error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Verify() returned %d identities, want 1", len(got))
+			}
+			if got[0].ID != "signer@example.com" || !got[0].Verified {
+				t.Errorf("Verify() identity = %+v", got[0])
+			}
+			if got[0].RekorLogIndex == nil || *got[0].RekorLogIndex != 3 {
+				t.Errorf("Verify() log index = %v, want 3", got[0].RekorLogIndex)
+			}
+		})
+	}
+}
+
+// bundle embeds the envelope and the log entry of the fixture in a Sigstore
+// bundle, encoding the entry like protobuf JSON does
+func (f *fixture) bundle(t *testing.T) []byte {
+	decode := func(s string, hexEncoded bool) []byte {
+		var b []byte
+		var err error
+		if hexEncoded {
+			b, err = hex.DecodeString(s)
+		} else {
+			b, err = base64.StdEncoding.DecodeString(s)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	p := f.entry.Verification.InclusionProof
+	hashes := [][]byte{}
+	for _, h := range p.Hashes {
+		hashes = append(hashes, decode(h, true))
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"mediaType": "application/vnd.dev.sigstore.bundle+json;version=0.2",
+		"verificationMaterial": map[string]interface{}{
+			"tlogEntries": []map[string]interface{}{{
+				"logIndex":         strconv.FormatInt(f.entry.LogIndex, 10),
+				"logId":            map[string][]byte{"keyId": decode(f.entry.LogID, true)},
+				"kindVersion":      map[string]string{"kind": "dsse", "version": "0.0.1"},
+				"integratedTime":   strconv.FormatInt(f.entry.IntegratedTime, 10),
+				"inclusionPromise": map[string][]byte{"signedEntryTimestamp": decode(f.entry.Verification.SignedEntryTimestamp, false)},
+				"inclusionProof": map[string]interface{}{
+					"logIndex":   strconv.FormatInt(p.LogIndex, 10),
+					"rootHash":   decode(p.RootHash, true),
+					"treeSize":   strconv.FormatInt(p.TreeSize, 10),
+					"hashes":     hashes,
+					"checkpoint": map[string]string{"envelope": p.Checkpoint},
+				},
+				"canonicalizedBody": decode(f.entry.Body, false),
+			}},
+		},
+		"dsseEnvelope": json.RawMessage(f.envelope),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRekorVerifier_VerifyBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, f *fixture)
+		wantErr string
+	}{{
+		name: "valid bundle",
+	}, {
+		name: "tampered signed entry timestamp",
+		tamper: func(t *testing.T, f *fixture) {
+			f.entry.IntegratedTime++
+		},
+		wantErr: "invalid signed entry timestamp",
+	}, {
+		name: "entry of another payload",
+		tamper: func(t *testing.T, f *fixture) {
+			signingKey, certPEM := newSigningCert(t, f.fulcioKey, f.fulcioCA, "signer@example.com")
+			f.envelope = sign(t, signingKey, []byte(`{"_type":"https://in-toto.io/Statement/v1"}`))
+			f.setEntry(t, []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`), certPEM)
+		},
+		wantErr: "entry records payload",
+	}, {
+		name: "envelope signed by another key",
+		tamper: func(t *testing.T, f *fixture) {
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.envelope = sign(t, other, []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`))
+		},
+		wantErr: "envelope signature does not match certificate",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.WithLogger(context.Background())
+			f := newFixture(t)
+			if tt.tamper != nil {
+				tt.tamper(t, f)
+			}
+
+			// The bundle is verified offline, Rekor is never contacted
+			v, err := NewRekorVerifier("http://127.0.0.1:0", f.rekorKeyPEM, f.fulcioPEM)
+			if err != nil {
+				t.Fatalf("NewRekorVerifier() error = %v", err)
+			}
+			got, err := v.VerifyBundle(ctx, f.bundle(t))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifyBundle() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyBundle() unexpected error = %v", err)
+			}
+			if len(got) != 1 || got[0].ID != "signer@example.com" || !got[0].Verified {
+				t.Fatalf("VerifyBundle() identities = %+v", got)
+			}
+			if got[0].RekorLogIndex == nil || *got[0].RekorLogIndex != 3 {
+				t.Errorf("VerifyBundle() log index = %v, want 3", got[0].RekorLogIndex)
+			}
+		})
+	}
+}
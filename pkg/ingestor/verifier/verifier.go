@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/guacsec/guac/pkg/handler/processor"
+	"github.com/guacsec/guac/pkg/handler/processor/bundle"
 	"github.com/guacsec/guac/pkg/ingestor/key"
 )
 
@@ -42,6 +43,13 @@ type Verifier interface {
 	Type() VerifierType
 }
 
+// BundleVerifier is implemented by the verifiers that can check a Sigstore
+// bundle offline, against the transparency log entry and the signing
+// certificate it embeds, instead of looking them up.
+type BundleVerifier interface {
+	VerifyBundle(ctx context.Context, bundleBytes []byte) ([]Identity, error)
+}
+
 // Identity struct elements might be nil/empty if the key is invalid or the
 // ID of the identity can't be determined. Verified indicates that the
 // identity has been verified, usually based on signature matching the key.
@@ -51,10 +59,16 @@ type Identity struct {
 	ID       string
 	Key      key.Key
 	Verified bool
+	// RekorLogIndex is the index of the transparency log entry the identity
+	// was verified against. It is nil if no transparency log was checked.
+	RekorLogIndex *int64
 }
 
 var (
 	verifierProviders = map[VerifierType]Verifier{}
+	// dsseVerifierTypes are the verifiers of DSSE envelopes, in order of
+	// preference
+	dsseVerifierTypes = []VerifierType{"rekor", "sigstore"}
 )
 
 // RegisterVerifier registers the providers that are available for verification
@@ -70,9 +84,30 @@ func RegisterVerifier(k Verifier, providerType VerifierType) error {
 func VerifyIdentity(ctx context.Context, doc *processor.Document) ([]Identity, error) {
 	switch doc.Type {
 	case processor.DocumentDSSE:
-		if verifier, ok := verifierProviders["sigstore"]; ok {
-			return verifier.Verify(ctx, doc.Blob)
+		for _, t := range dsseVerifierTypes {
+			if verifier, ok := verifierProviders[t]; ok {
+				return verifier.Verify(ctx, doc.Blob)
+			}
+		}
+	case processor.DocumentSigstoreBundle:
+		for _, t := range dsseVerifierTypes {
+			if verifier, ok := verifierProviders[t]; ok {
+				return VerifyBundle(ctx, verifier, doc.Blob)
+			}
 		}
 	}
 	return nil, fmt.Errorf("failed verification for document type: %s", doc.Type)
 }
+
+// VerifyBundle verifies the DSSE envelope of a Sigstore bundle with v, using
+// the verification material of the bundle if v is a BundleVerifier
+func VerifyBundle(ctx context.Context, v Verifier, bundleBytes []byte) ([]Identity, error) {
+	if bv, ok := v.(BundleVerifier); ok {
+		return bv.VerifyBundle(ctx, bundleBytes)
+	}
+	b, err := bundle.ParseBundle(bundleBytes)
+	if err != nil {
+		return nil, err
+	}
+	return v.Verify(ctx, b.DSSEEnvelope)
+}
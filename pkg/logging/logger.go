@@ -17,16 +17,139 @@ package logging
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.SugaredLogger
+// Format is the encoding of the log lines
+type Format string
+
+const (
+	// ConsoleFormat writes human-readable log lines. This is the default.
+	ConsoleFormat Format = "console"
+	// JSONFormat writes each log line as a JSON object, with the structured
+	// fields as keys.
+	JSONFormat Format = "json"
+
+	// FormatEnv is the environment variable used to select the Format of the
+	// default logger (e.g., GUAC_LOG_FORMAT=json)
+	FormatEnv = "GUAC_LOG_FORMAT"
+)
+
+// Level is the minimum severity of the log lines written by a logger
+type Level string
+
+const (
+	DebugLevel Level = "debug"
+	InfoLevel  Level = "info"
+	WarnLevel  Level = "warn"
+	ErrorLevel Level = "error"
+
+	// DefaultLevel is the level of the default logger, until SetLevel is
+	// called
+	DefaultLevel = InfoLevel
+)
+
+// Levels are the valid levels, from the most to the least verbose
+var Levels = []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel}
+
+var (
+	logger *zap.SugaredLogger
+	// level is the level of the default logger, changed by SetLevel
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+)
 
 type loggerKey struct{}
 
 func init() {
-	zapLogger, _ := zap.NewProduction()
+	logger = newLogger(formatFromEnv(), level)
+}
+
+// ParseLevel returns the level with the given name, case insensitively
+func ParseLevel(name string) (Level, error) {
+	for _, l := range Levels {
+		if strings.EqualFold(name, string(l)) {
+			return l, nil
+		}
+	}
+	names := make([]string, len(Levels))
+	for i, l := range Levels {
+		names[i] = string(l)
+	}
+	return "", fmt.Errorf("unknown log level %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+func (l Level) zapLevel() zapcore.Level {
+	switch l {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// SetLevel sets the level of the default logger, which is shared by the
+// contexts returned by WithLogger without options, including those created
+// before the call.
+func SetLevel(l Level) error {
+	l, err := ParseLevel(string(l))
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l.zapLevel())
+	return nil
+}
+
+// GetLevel returns the level of the default logger
+func GetLevel() Level {
+	switch level.Level() {
+	case zapcore.DebugLevel:
+		return DebugLevel
+	case zapcore.WarnLevel:
+		return WarnLevel
+	case zapcore.ErrorLevel:
+		return ErrorLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// NewLogger returns a logger writing the log lines of at least the given
+// level to stderr, in the given format
+func NewLogger(format Format, l Level) (*zap.SugaredLogger, error) {
+	l, err := ParseLevel(string(l))
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(format, zap.NewAtomicLevelAt(l.zapLevel())), nil
+}
+
+func formatFromEnv() Format {
+	if Format(strings.ToLower(os.Getenv(FormatEnv))) == JSONFormat {
+		return JSONFormat
+	}
+	return ConsoleFormat
+}
+
+func newLogger(format Format, level zap.AtomicLevel) *zap.SugaredLogger {
+	config := zap.NewProductionConfig()
+	config.Level = level
+	if format != JSONFormat {
+		config.Encoding = string(ConsoleFormat)
+		config.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	zapLogger, err := config.Build()
+	if err != nil {
+		zapLogger = zap.NewNop()
+	}
 
 	// flushes buffer, if any
 	defer func() {
@@ -34,11 +157,51 @@ func init() {
 		_ = zapLogger.Sync()
 	}()
 
-	logger = zapLogger.Sugar()
+	return zapLogger.Sugar()
+}
+
+type loggerOptions struct {
+	format Format
+	level  Level
 }
 
-func WithLogger(ctx context.Context) context.Context {
-	return context.WithValue(ctx, loggerKey{}, logger)
+// Option configures the logger attached by WithLogger
+type Option func(*loggerOptions)
+
+// WithFormat selects the Format of the logger, overriding FormatEnv
+func WithFormat(format Format) Option {
+	return func(o *loggerOptions) {
+		o.format = format
+	}
+}
+
+// WithLogger returns a context holding a logger. Without options, the logger
+// uses the Format given by the FormatEnv environment variable, defaulting to
+// ConsoleFormat.
+// WithLevel creates a logger with its own level, instead of the level of the
+// default logger. Invalid levels are ignored.
+func WithLevel(level Level) Option {
+	return func(o *loggerOptions) {
+		o.level = level
+	}
+}
+
+func WithLogger(ctx context.Context, opts ...Option) context.Context {
+	o := loggerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.format == "" && o.level == "" {
+		return context.WithValue(ctx, loggerKey{}, logger)
+	}
+	if o.format == "" {
+		o.format = formatFromEnv()
+	}
+	l := level
+	if parsed, err := ParseLevel(string(o.level)); err == nil {
+		l = zap.NewAtomicLevelAt(parsed.zapLevel())
+	}
+	return context.WithValue(ctx, loggerKey{}, newLogger(o.format, l))
 }
 
 func FromContext(ctx context.Context) *zap.SugaredLogger {
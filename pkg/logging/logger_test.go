@@ -0,0 +1,101 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_formatFromEnv(t *testing.T) {
+	tests := []struct {
+		env  string
+		want Format
+	}{
+		{env: "", want: ConsoleFormat},
+		{env: "console", want: ConsoleFormat},
+		{env: "json", want: JSONFormat},
+		{env: "JSON", want: JSONFormat},
+		{env: "xml", want: ConsoleFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(FormatEnv, tt.env)
+			if got := formatFromEnv(); got != tt.want {
+				t.Errorf("formatFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	if FromContext(WithLogger(context.Background())) != logger {
+		t.Errorf("WithLogger() without options should use the default logger")
+	}
+	if FromContext(WithLogger(context.Background(), WithFormat(JSONFormat))) == logger {
+		t.Errorf("WithLogger() with a format should create a new logger")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{name: "debug", want: DebugLevel},
+		{name: "WARN", want: WarnLevel},
+		{name: "error", want: ErrorLevel},
+		{name: "verbose", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer func() { _ = SetLevel(DefaultLevel) }()
+	// contexts created before the call use the new level too
+	ctx := WithLogger(context.Background())
+	if err := SetLevel("ERROR"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if GetLevel() != ErrorLevel {
+		t.Errorf("GetLevel() = %v, want %v", GetLevel(), ErrorLevel)
+	}
+	if FromContext(ctx).Desugar().Core().Enabled(zapcore.WarnLevel) {
+		t.Errorf("default logger writes warnings at the error level")
+	}
+	if err := SetLevel("verbose"); err == nil {
+		t.Errorf("SetLevel() accepted an unknown level")
+	}
+
+	own := FromContext(WithLogger(context.Background(), WithLevel(DebugLevel)))
+	if !own.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("WithLevel() logger does not write debug lines")
+	}
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics defines the Prometheus metrics of the ingestion pipeline.
+// They are registered with the default Prometheus registry and are exported
+// by `promhttp.Handler()`.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics of the ingestion pipeline
+var (
+	// DocumentsCollected counts the documents emitted by each collector
+	DocumentsCollected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "guac_documents_collected_total",
+		Help: "Number of documents emitted by the collectors.",
+	}, []string{"collector"})
+
+	// DocumentsProcessed counts the documents that went through the whole
+	// pipeline, by format
+	DocumentsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "guac_documents_processed_total",
+		Help: "Number of documents processed, ingested and assembled.",
+	}, []string{"format"})
+
+	// DocumentsFiltered counts the documents skipped by the filters of the
+	// pipeline, by type
+	DocumentsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "guac_documents_filtered_total",
+		Help: "Number of documents skipped by the document filters.",
+	}, []string{"type"})
+
+	// DocumentsDeduplicated counts the documents skipped because a document
+	// with the same content was already stored, by type
+	DocumentsDeduplicated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "guac_documents_deduplicated_total",
+		Help: "Number of documents skipped as duplicates of documents already stored.",
+	}, []string{"type"})
+
+	// ParseFailures counts the documents that could not be processed or
+	// parsed, by format
+	ParseFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "guac_parse_failures_total",
+		Help: "Number of documents that failed processing or parsing.",
+	}, []string{"format"})
+
+	// AssemblerWriteSeconds measures the time taken to write the graph of a
+	// document to the database
+	AssemblerWriteSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "guac_assembler_write_duration_seconds",
+		Help:    "Latency of the writes of the assembler to the database.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// NodesStored counts the nodes written to the database
+	NodesStored = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_nodes_stored_total",
+		Help: "Number of nodes written to the database.",
+	})
+
+	// EdgesStored counts the edges written to the database
+	EdgesStored = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_edges_stored_total",
+		Help: "Number of edges written to the database.",
+	})
+
+	// EdgeCacheHits counts the edges not written to Neo4j because an
+	// identical edge was written recently. The hit rate is
+	// hits / (hits + misses).
+	EdgeCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_edge_cache_hits_total",
+		Help: "Number of edge writes skipped as identical to a recent write.",
+	})
+
+	// EdgeCacheMisses counts the edges looked up in the edge cache and
+	// written to Neo4j
+	EdgeCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_edge_cache_misses_total",
+		Help: "Number of edge writes not found in the edge cache.",
+	})
+
+	// PurlCacheHits counts the purls whose normalized form was found in the
+	// purl cache. The hit rate is hits / (hits + misses).
+	PurlCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_purl_cache_hits_total",
+		Help: "Number of purls whose normalized form was cached.",
+	})
+
+	// PurlCacheMisses counts the purls normalized because they were not
+	// found in the purl cache
+	PurlCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "guac_purl_cache_misses_total",
+		Help: "Number of purls normalized as not found in the purl cache.",
+	})
+)
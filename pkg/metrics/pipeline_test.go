@@ -0,0 +1,50 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	DocumentsCollected.WithLabelValues("FileCollector").Inc()
+	AssemblerWriteSeconds.Observe(0.2)
+	NodesStored.Add(3)
+	if got := testutil.ToFloat64(NodesStored); got != 3 {
+		t.Errorf("NodesStored = %v, want 3", got)
+	}
+
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	for _, want := range []string{
+		`guac_documents_collected_total{collector="FileCollector"} 1`,
+		`guac_assembler_write_duration_seconds_bucket{le="0.25"} 1`,
+		`guac_nodes_stored_total 3`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metric %q missing from body:\n%s", want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStartDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "process")
+	if span.IsRecording() {
+		t.Fatalf("Start() returned a recording span with tracing disabled")
+	}
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		t.Errorf("SpanFromContext() returned a valid span with tracing disabled")
+	}
+	// methods of the no-op spans do nothing
+	span.SetAttributes(attribute.String("source", "file:///sbom.json"))
+	RecordError(span, errors.New("parse error"))
+	span.End()
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ctx, root := Start(context.Background(), "document", attribute.String("source", "file:///sbom.json"))
+	_, child := Start(ctx, "ingest", attribute.String("format", "SPDX"))
+	RecordError(child, errors.New("parse error"))
+	RecordError(child, nil)
+	child.End()
+	root.SetAttributes(attribute.Int("nodes", 3))
+	root.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	gotChild, gotRoot := spans[0], spans[1]
+	if gotRoot.Parent().IsValid() {
+		t.Errorf("root span has a parent: %v", gotRoot.Parent())
+	}
+	if gotChild.SpanContext().TraceID() != gotRoot.SpanContext().TraceID() || gotChild.Parent().SpanID() != gotRoot.SpanContext().SpanID() {
+		t.Errorf("child span is not linked to its parent")
+	}
+	if attrs := gotRoot.Attributes(); len(attrs) != 2 || attrs[1] != attribute.Int("nodes", 3) {
+		t.Errorf("unexpected root attributes: %v", attrs)
+	}
+	if gotChild.Status().Code != codes.Error || gotChild.Status().Description != "parse error" {
+		t.Errorf("got child status %+v, want error", gotChild.Status())
+	}
+	if events := gotChild.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("error not recorded on the child span: %+v", events)
+	}
+	if gotRoot.Status().Code != codes.Unset {
+		t.Errorf("got root status %+v, want unset", gotRoot.Status())
+	}
+}
+
+func TestSetup(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*coltracepb.ExportTraceServiceRequest
+		paths    []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("unable to read request: %v", err)
+		}
+		r := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, r); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		paths = append(paths, req.URL.Path)
+	}))
+	defer server.Close()
+
+	shutdown, err := Setup(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	ctx, root := Start(context.Background(), "document", attribute.String("source", "file:///sbom.json"))
+	_, child := Start(ctx, "assemble", attribute.Int("nodes", 42))
+	RecordError(child, errors.New("write failed"))
+	child.End()
+	root.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 || paths[0] != "/v1/traces" {
+		t.Fatalf("got %d requests to %v, want one to /v1/traces", len(requests), paths)
+	}
+	resource := requests[0].ResourceSpans[0]
+	if name := resource.Resource.Attributes[0]; name.Key != "service.name" || name.Value.GetStringValue() != serviceName {
+		t.Errorf("got resource attribute %v, want the service name", name)
+	}
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	assemble := spans[0]
+	if assemble.Name != "assemble" || string(assemble.ParentSpanId) != string(spans[1].SpanId) {
+		t.Errorf("unexpected span: %v", assemble)
+	}
+	if v := assemble.Attributes[0].Value.GetIntValue(); v != 42 {
+		t.Errorf("got nodes attribute %v, want 42", assemble.Attributes[0])
+	}
+	if assemble.Status.Code != tracepb.Status_STATUS_CODE_ERROR || assemble.Status.Message != "write failed" {
+		t.Errorf("got status %v, want error", assemble.Status)
+	}
+	if _, span := Start(context.Background(), "after shutdown"); span.IsRecording() {
+		t.Errorf("spans are still recorded after shutdown")
+	}
+}
+
+func TestSetupInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", "http://%zz"} {
+		if _, err := Setup(context.Background(), endpoint); err == nil {
+			t.Errorf("Setup(%q) succeeded, want error", endpoint)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+)
+
+// CompareDebian compares Debian package versions (`[epoch:]upstream[-revision]`)
+// as dpkg does: the epoch first, numerically, then the upstream version and
+// the revision, where `~` sorts before anything, even the end of the version
+// (`1.0~rc1` is lower than `1.0`).
+func CompareDebian(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitDebian(a)
+	bEpoch, bUpstream, bRevision := splitDebian(b)
+	if c := compareDigits(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := compareDpkg(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareDpkg(aRevision, bRevision)
+}
+
+// splitDebian returns the epoch (0 if missing), upstream version and
+// revision of a Debian version
+func splitDebian(v string) (epoch, upstream, revision string) {
+	v = strings.TrimSpace(v)
+	epoch = "0"
+	if i := strings.Index(v, ":"); i >= 0 && isNumber(v[:i]) {
+		epoch, v = v[:i], v[i+1:]
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// compareDpkg compares the upstream versions or revisions of Debian versions
+// following `verrevcmp` of dpkg: alternating non-digit parts, compared
+// character by character, and digit parts, compared numerically
+func compareDpkg(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			if c := compareInts(dpkgOrder(a), dpkgOrder(b)); c != 0 {
+				return c
+			}
+			a, b = rest(a), rest(b)
+		}
+		var aDigits, bDigits string
+		aDigits, a = digitPrefix(a)
+		bDigits, b = digitPrefix(b)
+		if c := compareDigits(aDigits, bDigits); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// dpkgOrder returns the weight of the first character of s: `~` sorts first,
+// then the end of the string and digits, then letters and finally the other
+// characters
+func dpkgOrder(s string) int {
+	if s == "" {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -1
+	}
+	return int(c) + 256
+}
+
+// rest returns s without its first character, if any
+func rest(s string) string {
+	if s == "" {
+		return s
+	}
+	return s[1:]
+}
+
+// digitPrefix splits s into its leading digits and the rest
+func digitPrefix(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
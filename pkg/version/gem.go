@@ -0,0 +1,77 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+)
+
+// CompareGem compares RubyGems versions like `Gem::Version`: the version is
+// split into numbers and letters, numbers being compared numerically and
+// higher than letters, so that versions with letters are pre-releases
+// (`1.0.0.pre` and `1.0.0.rc1` are lower than `1.0.0`). Missing segments count
+// as 0.
+func CompareGem(a, b string) int {
+	x, y := gemSegments(a), gemSegments(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		s, t := "0", "0"
+		if i < len(x) {
+			s = x[i]
+		}
+		if i < len(y) {
+			t = y[i]
+		}
+		sIsNum, tIsNum := isNumber(s), isNumber(t)
+		switch {
+		case sIsNum && tIsNum:
+			if c := compareDigits(s, t); c != 0 {
+				return c
+			}
+		case sIsNum:
+			return 1
+		case tIsNum:
+			return -1
+		default:
+			if c := strings.Compare(s, t); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// gemSegments splits the version into its segments, a `-` standing for a
+// `.pre.` segment as in RubyGems
+func gemSegments(v string) []string {
+	v = strings.ReplaceAll(strings.TrimSpace(v), "-", ".pre.")
+	segments := []string{}
+	for _, part := range strings.Split(v, ".") {
+		for part != "" {
+			var segment string
+			if isDigit(part[0]) {
+				segment, part = digitPrefix(part)
+			} else {
+				i := 0
+				for i < len(part) && !isDigit(part[i]) {
+					i++
+				}
+				segment, part = part[:i], part[i:]
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
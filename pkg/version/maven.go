@@ -0,0 +1,143 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+)
+
+// mavenQualifiers are the well-known qualifiers of Maven versions, lowest
+// first. Other qualifiers are higher and compared lexically.
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// mavenAliases are the alternative spellings of the well-known qualifiers
+var mavenAliases = map[string]string{
+	"cr":      "rc",
+	"ga":      "",
+	"final":   "",
+	"release": "",
+}
+
+// CompareMaven compares Maven versions like Maven's `ComparableVersion`:
+// numbers are compared numerically and are higher than qualifiers, which are
+// ordered `alpha < beta < milestone < rc < snapshot < (release) < sp`, then
+// lexically. Trailing zeros and release qualifiers are ignored (`1.0`, `1`
+// and `1.0.0-ga` are equal) and `a1`, `b1`, `m1` stand for `alpha-1`,
+// `beta-1`, `milestone-1`.
+func CompareMaven(a, b string) int {
+	aItems := mavenItems(a)
+	bItems := mavenItems(b)
+	for i := 0; i < len(aItems) || i < len(bItems); i++ {
+		// a missing item is a 0 next to a number, a release otherwise
+		var x, y string
+		if i < len(aItems) {
+			x = aItems[i]
+		}
+		if i < len(bItems) {
+			y = bItems[i]
+		}
+		if i >= len(aItems) && isNumber(y) {
+			x = "0"
+		}
+		if i >= len(bItems) && isNumber(x) {
+			y = "0"
+		}
+		if c := compareMavenItems(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// mavenItems splits the version into its numbers and qualifiers, lower-cased
+// and with aliases resolved, dropping the zeros before qualifiers and the
+// trailing zeros and releases
+func mavenItems(v string) []string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	items := []string{}
+	start := 0
+	for i := 0; i <= len(v); i++ {
+		// items are separated by `.`, `-` and transitions between digits and
+		// letters
+		if i < len(v) && v[i] != '.' && v[i] != '-' && (i == start || isDigit(v[i]) == isDigit(v[i-1])) {
+			continue
+		}
+		item := v[start:i]
+		if isNumber(item) {
+			item = strings.TrimLeft(item, "0")
+			if item == "" {
+				item = "0"
+			}
+		} else if i < len(v) && isDigit(v[i]) && len(item) == 1 {
+			// a single letter followed by a number, e.g. `1.0a1`
+			switch item {
+			case "a":
+				item = "alpha"
+			case "b":
+				item = "beta"
+			case "m":
+				item = "milestone"
+			}
+		}
+		if alias, ok := mavenAliases[item]; ok {
+			item = alias
+		}
+		if !isNumber(item) {
+			// zeros before a qualifier are ignored, `1.0-rc1` is `1-rc1`
+			for len(items) > 0 && items[len(items)-1] == "0" {
+				items = items[:len(items)-1]
+			}
+		}
+		items = append(items, item)
+		start = i
+		if i < len(v) && (v[i] == '.' || v[i] == '-') {
+			start = i + 1
+		}
+	}
+	for len(items) > 0 && (items[len(items)-1] == "0" || items[len(items)-1] == "") {
+		items = items[:len(items)-1]
+	}
+	return items
+}
+
+// compareMavenItems compares two items of Maven versions
+func compareMavenItems(x, y string) int {
+	xIsNum, yIsNum := isNumber(x), isNumber(y)
+	switch {
+	case xIsNum && yIsNum:
+		return compareDigits(x, y)
+	case xIsNum:
+		return 1
+	case yIsNum:
+		return -1
+	}
+	xRank, yRank := mavenQualifierRank(x), mavenQualifierRank(y)
+	if xRank != yRank {
+		return compareInts(xRank, yRank)
+	}
+	return strings.Compare(x, y)
+}
+
+// mavenQualifierRank returns the position of the qualifier among the
+// well-known ones, unknown qualifiers being higher than all of them
+func mavenQualifierRank(q string) int {
+	for i, known := range mavenQualifiers {
+		if q == known {
+			return i
+		}
+	}
+	return len(mavenQualifiers)
+}
@@ -0,0 +1,174 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pep440Pattern matches the versions allowed by PEP 440, with the variations
+// accepted by pip, see https://peps.python.org/pep-0440/#appendix-b-parsing-version-strings-with-regular-expressions
+var pep440Pattern = regexp.MustCompile(`^v?(?:(?:(?P<epoch>[0-9]+)!)?(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?P<pre>[-_\.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_\.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?P<post>(?:-(?P<post_n1>[0-9]+))|(?:[-_\.]?(?P<post_l>post|rev|r)[-_\.]?(?P<post_n2>[0-9]+)?))?` +
+	`(?P<dev>[-_\.]?(?P<dev_l>dev)[-_\.]?(?P<dev_n>[0-9]+)?)?)` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_\.][a-z0-9]+)*))?$`)
+
+// pep440Pre maps the spellings of pre-release phases to their rank
+var pep440Pre = map[string]int{
+	"a": 0, "alpha": 0,
+	"b": 1, "beta": 1,
+	"c": 2, "rc": 2, "pre": 2, "preview": 2,
+}
+
+// pep440Final is the rank of final releases among the pre-release phases
+const pep440Final = 3
+
+// pep440Version is a parsed PEP 440 version. The numbers are strings of
+// digits, empty when missing.
+type pep440Version struct {
+	epoch   string
+	release []string
+	// pre-release phase and number, phase -1 when it is not a pre-release
+	prePhase int
+	pre      string
+	post     string
+	dev      string
+	local    []string
+}
+
+// ComparePEP440 compares Python package versions following PEP 440: the
+// epoch (`1!`) first, then the release, where trailing zeros are ignored,
+// then development releases (`.dev1`) are lower than pre-releases (`a1`,
+// `b1`, `rc1`), which are lower than the final release, which is lower than
+// post-releases (`.post1`), and finally local versions (`+local`). Versions
+// that do not follow PEP 440 are compared as semantic versions.
+func ComparePEP440(a, b string) int {
+	x, xOK := parsePEP440(a)
+	y, yOK := parsePEP440(b)
+	if !xOK || !yOK {
+		return CompareSemver(a, b)
+	}
+	if c := compareDigits(x.epoch, y.epoch); c != 0 {
+		return c
+	}
+	if c := compareDotted(x.release, y.release); c != 0 {
+		return c
+	}
+	if c := compareInts(x.preKey(), y.preKey()); c != 0 {
+		return c
+	}
+	if x.prePhase >= 0 && x.prePhase == y.prePhase {
+		if c := compareDigits(x.pre, y.pre); c != 0 {
+			return c
+		}
+	}
+	if c := compareOptional(x.post, y.post, -1); c != 0 {
+		return c
+	}
+	if c := compareOptional(x.dev, y.dev, 1); c != 0 {
+		return c
+	}
+	return compareLocal(x.local, y.local)
+}
+
+// parsePEP440 parses the version, or returns false if it does not follow
+// PEP 440
+func parsePEP440(v string) (pep440Version, bool) {
+	m := pep440Pattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return pep440Version{}, false
+	}
+	group := func(name string) string {
+		return m[pep440Pattern.SubexpIndex(name)]
+	}
+	parsed := pep440Version{epoch: group("epoch"), release: strings.Split(group("release"), "."), prePhase: -1}
+	if parsed.epoch == "" {
+		parsed.epoch = "0"
+	}
+	if group("pre") != "" {
+		parsed.prePhase = pep440Pre[group("pre_l")]
+		parsed.pre = numberOrZero(group("pre_n"))
+	}
+	if group("post") != "" {
+		parsed.post = numberOrZero(group("post_n1") + group("post_n2"))
+	}
+	if group("dev") != "" {
+		parsed.dev = numberOrZero(group("dev_n"))
+	}
+	if local := group("local"); local != "" {
+		parsed.local = strings.FieldsFunc(local, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+	return parsed, true
+}
+
+// preKey orders the versions by pre-release phase: development releases of
+// the final release (`1.0.dev1`) first, then the phases, then the final
+// release and its post-releases
+func (v pep440Version) preKey() int {
+	switch {
+	case v.prePhase >= 0:
+		return v.prePhase
+	case v.post == "" && v.dev != "":
+		return -1
+	}
+	return pep440Final
+}
+
+// compareOptional compares numbers that may be missing, a missing number
+// being lower than all others when missing is negative, and higher otherwise
+func compareOptional(a, b string, missing int) int {
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return missing
+	case b == "":
+		return -missing
+	}
+	return compareDigits(a, b)
+}
+
+// compareLocal compares the segments of local versions: numeric segments are
+// higher than the others, and a version without local segments is lower
+func compareLocal(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		xIsNum, yIsNum := isNumber(a[i]), isNumber(b[i])
+		switch {
+		case xIsNum && yIsNum:
+			if c := compareDigits(a[i], b[i]); c != 0 {
+				return c
+			}
+		case xIsNum:
+			return 1
+		case yIsNum:
+			return -1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+func numberOrZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
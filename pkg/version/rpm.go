@@ -0,0 +1,140 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+)
+
+// CompareRPM compares RPM package versions (`[epoch:]version[-release]`) as
+// rpm does: the epoch first, numerically, then the version and, when both
+// have one, the release. `~` sorts before anything, even the end of the
+// version (`1.0~rc1` is lower than `1.0`), and `^` after the end of the
+// version but before anything else (`1.0^git1` is between `1.0` and `1.0.1`).
+func CompareRPM(a, b string) int {
+	aEpoch, aVersion, aRelease := splitRPM(a)
+	bEpoch, bVersion, bRelease := splitRPM(b)
+	if c := compareDigits(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := rpmvercmp(aVersion, bVersion); c != 0 {
+		return c
+	}
+	if aRelease == "" || bRelease == "" {
+		return 0
+	}
+	return rpmvercmp(aRelease, bRelease)
+}
+
+// splitRPM returns the epoch (0 if missing), version and release of an RPM
+// version
+func splitRPM(v string) (epoch, version, release string) {
+	v = strings.TrimSpace(v)
+	epoch = "0"
+	if i := strings.Index(v, ":"); i >= 0 && isNumber(v[:i]) {
+		epoch, v = v[:i], v[i+1:]
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// rpmvercmp compares versions or releases following `rpmvercmp` of rpm:
+// alphanumeric segments are compared one by one, the separators between them
+// being ignored, numeric segments numerically and higher than alphabetic ones
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, isRPMSeparator)
+		b = strings.TrimLeftFunc(b, isRPMSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		var aSegment, bSegment string
+		numeric := isDigit(a[0])
+		if numeric {
+			aSegment, a = digitPrefix(a)
+			bSegment, b = digitPrefix(b)
+		} else {
+			aSegment, a = letterPrefix(a)
+			bSegment, b = letterPrefix(b)
+		}
+		// segments of different kinds: numeric ones are higher
+		if bSegment == "" {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			if c := compareDigits(aSegment, bSegment); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(aSegment, bSegment); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// isRPMSeparator returns whether rpm ignores the character between segments
+func isRPMSeparator(r rune) bool {
+	return r > 0x7f || !(isDigit(byte(r)) || isLetter(byte(r)) || r == '~' || r == '^')
+}
+
+// letterPrefix splits s into its leading letters and the rest
+func letterPrefix(s string) (string, string) {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
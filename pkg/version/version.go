@@ -0,0 +1,182 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package version compares package versions following the semantics of
+// their ecosystem, given by the type of their purl (e.g. Debian epochs, the
+// RPM tilde, PEP 440 pre-releases), for instance to decide whether a version
+// is in a range of versions affected by a vulnerability.
+package version
+
+import (
+	"strings"
+	"sync"
+)
+
+// Comparator compares two versions of a package, returning a negative
+// number, zero or a positive number when a is lower than, equal to or
+// greater than b. Comparators must accept any string: versions that do not
+// follow the rules of the ecosystem are still ordered, if arbitrarily.
+type Comparator func(a, b string) int
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]Comparator{
+		"deb":   CompareDebian,
+		"gem":   CompareGem,
+		"maven": CompareMaven,
+		"pypi":  ComparePEP440,
+		"rpm":   CompareRPM,
+	}
+)
+
+// RegisterComparator sets the comparator of the versions of the packages of
+// the purl type, replacing the existing one if any
+func RegisterComparator(purlType string, c Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	comparators[strings.ToLower(purlType)] = c
+}
+
+// ComparatorFor returns the comparator of the versions of the packages of
+// the purl type. Types without a registered comparator (e.g. npm, cargo,
+// golang) use semantic versioning.
+func ComparatorFor(purlType string) Comparator {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	if c, ok := comparators[strings.ToLower(purlType)]; ok {
+		return c
+	}
+	return CompareSemver
+}
+
+// Compare compares two versions of a package of the purl type, see
+// `Comparator`
+func Compare(purlType string, a, b string) int {
+	return ComparatorFor(purlType)(a, b)
+}
+
+// CompareSemver compares semantic versions (https://semver.org). A leading
+// `v` and the build metadata are ignored, missing components count as 0
+// (`1.2` is `1.2.0`), and a version with a pre-release is lower than the
+// version without it.
+func CompareSemver(a, b string) int {
+	aCore, aPre := splitSemver(a)
+	bCore, bPre := splitSemver(b)
+	if c := compareDotted(aCore, bCore); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == nil && bPre == nil:
+		return 0
+	case aPre == nil:
+		return 1
+	case bPre == nil:
+		return -1
+	}
+	return compareDotted(aPre, bPre)
+}
+
+// splitSemver returns the dot-separated components of the version and of its
+// pre-release
+func splitSemver(v string) (core []string, pre []string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, p, hasPre := strings.Cut(v, "-")
+	core = strings.Split(v, ".")
+	if hasPre {
+		pre = strings.Split(p, ".")
+	}
+	return core, pre
+}
+
+// compareDotted compares lists of components, numeric components being
+// compared numerically and lower than the others, which are compared
+// lexically. Missing components count as 0 when the other is numeric, and
+// are lower otherwise.
+func compareDotted(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		xIsNum, yIsNum := isNumber(x), isNumber(y)
+		if x == "" && yIsNum {
+			x, xIsNum = "0", true
+		}
+		if y == "" && xIsNum {
+			y, yIsNum = "0", true
+		}
+		switch {
+		case xIsNum && yIsNum:
+			if c := compareDigits(x, y); c != 0 {
+				return c
+			}
+		case xIsNum:
+			return -1
+		case yIsNum:
+			return 1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// isNumber returns whether the string is a non-empty string of digits
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareDigits compares two strings of digits numerically, whatever their
+// length
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInts(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
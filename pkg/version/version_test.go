@@ -0,0 +1,155 @@
+//
+// Copyright 2022 The GUAC Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		purlType string
+		a, b     string
+		want     int
+	}{
+		// semantic versioning, the default
+		{"npm", "1.2.3", "1.2.3", 0},
+		{"npm", "1.2.3", "1.10.0", -1},
+		{"npm", "v1.2", "1.2.0", 0},
+		{"npm", "1.0.0-rc.1", "1.0.0", -1},
+		{"npm", "1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"npm", "1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"npm", "1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"npm", "1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"npm", "1.0.0+build.1", "1.0.0+build.2", 0},
+		{"golang", "v0.0.0-20220101000000-abcdef", "v0.1.0", -1},
+		{"cargo", "18446744073709551616.0.0", "18446744073709551615.0.0", 1},
+		{"unknown", "2.0", "10.0", -1},
+
+		// Debian
+		{"deb", "1.0", "1.0", 0},
+		{"deb", "1:1.0", "2.0", 1},
+		{"deb", "0:2.0", "2.0", 0},
+		{"deb", "1.0~rc1", "1.0", -1},
+		{"deb", "1.0~~", "1.0~", -1},
+		{"deb", "1.0", "1.0+dfsg", -1},
+		{"deb", "1.0+dfsg-1", "1.0-1", 1},
+		{"deb", "1.0-1", "1.0-2", -1},
+		{"deb", "1.0-2", "1.0-10", -1},
+		{"deb", "1.0-1ubuntu1", "1.0-1", 1},
+		{"deb", "2.30-1~bpo10+1", "2.30-1", -1},
+		{"deb", "1.0a", "1.0+", -1},
+		{"deb", "1.2.3-4", "1.2.10-1", -1},
+
+		// RPM
+		{"rpm", "1.0", "1.0", 0},
+		{"rpm", "1:1.0", "2.0", 1},
+		{"rpm", "1.0~rc1", "1.0", -1},
+		{"rpm", "1.0~rc1", "1.0~rc2", -1},
+		{"rpm", "1.0^git1", "1.0", 1},
+		{"rpm", "1.0^git1", "1.0.1", -1},
+		{"rpm", "1.0^git1", "1.0~rc1", 1},
+		{"rpm", "1.0-1.el8", "1.0-2.el8", -1},
+		{"rpm", "1.0-1", "1.0", 0},
+		{"rpm", "1.0a", "1.0", 1},
+		{"rpm", "1.0a", "1.0.1", -1},
+		{"rpm", "1.010", "1.9", 1},
+		{"rpm", "1_0", "1.0", 0},
+
+		// Maven
+		{"maven", "1.0", "1", 0},
+		{"maven", "1.0.0-ga", "1", 0},
+		{"maven", "1.0-final", "1.0", 0},
+		{"maven", "1.0-alpha-1", "1.0-beta", -1},
+		{"maven", "1.0a1", "1.0-alpha-1", 0},
+		{"maven", "1.0-beta", "1.0-milestone-1", -1},
+		{"maven", "1.0-M1", "1.0-RC1", -1},
+		{"maven", "1.0-RC1", "1.0-CR1", 0},
+		{"maven", "1.0-RC1", "1.0-SNAPSHOT", -1},
+		{"maven", "1.0-SNAPSHOT", "1.0", -1},
+		{"maven", "1.0", "1.0-sp1", -1},
+		{"maven", "1.0-sp1", "1.0-foo", -1},
+		{"maven", "1.0-rc1", "1-rc1", 0},
+		{"maven", "1.0.1", "1.0-sp1", 1},
+		{"maven", "1.9", "1.10", -1},
+		{"maven", "2.14.1", "2.15.0", -1},
+
+		// PEP 440
+		{"pypi", "1.0", "1.0.0", 0},
+		{"pypi", "1!0.1", "2.0", 1},
+		{"pypi", "1.0.dev1", "1.0a1", -1},
+		{"pypi", "1.0a1", "1.0a2.dev1", -1},
+		{"pypi", "1.0a2.dev1", "1.0a2", -1},
+		{"pypi", "1.0a2", "1.0b1", -1},
+		{"pypi", "1.0b1", "1.0rc1", -1},
+		{"pypi", "1.0rc1", "1.0c1", 0},
+		{"pypi", "1.0-alpha.1", "1.0a1", 0},
+		{"pypi", "1.0rc1", "1.0", -1},
+		{"pypi", "1.0", "1.0.post1.dev1", -1},
+		{"pypi", "1.0.post1.dev1", "1.0.post1", -1},
+		{"pypi", "1.0-1", "1.0.post1", 0},
+		{"pypi", "1.0.post1", "1.1.dev1", -1},
+		{"pypi", "1.0", "1.0+local", -1},
+		{"pypi", "1.0+abc", "1.0+1", -1},
+		{"pypi", "1.0+1.2", "1.0+1", 1},
+		{"pypi", "V1.0", "1.0", 0},
+		{"pypi", "1.10", "1.9", 1},
+
+		// RubyGems
+		{"gem", "1.0", "1.0.0", 0},
+		{"gem", "1.0.0.pre", "1.0.0", -1},
+		{"gem", "1.0.0.rc1", "1.0.0.rc2", -1},
+		{"gem", "1.0.0.a", "1.0.0.b", -1},
+		{"gem", "1.0.0-1", "1.0.0", -1},
+		{"gem", "1.0.0.rc1", "1.0.0.1", -1},
+		{"gem", "1.10", "1.9", 1},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.purlType+"/"+tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := sign(Compare(tt.purlType, tt.a, tt.b)); got != tt.want {
+				t.Errorf("Compare(%q, %q, %q) = %d, expected %d", tt.purlType, tt.a, tt.b, got, tt.want)
+			}
+			if got := sign(Compare(tt.purlType, tt.b, tt.a)); got != -tt.want {
+				t.Errorf("Compare(%q, %q, %q) = %d, expected %d", tt.purlType, tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterComparator(t *testing.T) {
+	if got := Compare("apk", "1.0-r1", "1.0"); got >= 0 {
+		t.Fatalf("got %d for the default comparator, expected 1.0-r1 to be a pre-release of 1.0", got)
+	}
+	RegisterComparator("apk", CompareDebian)
+	defer func() {
+		comparatorsMu.Lock()
+		delete(comparators, "apk")
+		comparatorsMu.Unlock()
+	}()
+	if got := Compare("APK", "1.0-r1", "1.0"); got <= 0 {
+		t.Errorf("got %d for the registered comparator, expected 1.0-r1 to be greater than 1.0", got)
+	}
+}
+
+func sign(c int) int {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	}
+	return 0
+}